@@ -0,0 +1,76 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"strings"
+)
+
+// Exit codes of the CLI modes (--once, validate-config, export and report), so that wrapper scripts and
+// CI can tell failed checks from a broken setup. They are documented in the README.
+const (
+	exitOK = 0
+	// exitCheckFailed is returned when a check failed (--once) or the configuration has
+	// problems (validate-config).
+	exitCheckFailed = 1
+	// exitConfigError is returned when the command line or the configuration cannot be used,
+	// e.g. an invalid flag or a configuration file that cannot be read or parsed.
+	exitConfigError = 2
+	// exitInfraError is returned when the command could not do its work, e.g. a remote
+	// configuration, secrets backend or running watchdog that cannot be reached, or a report
+	// that cannot be written.
+	exitInfraError = 3
+)
+
+// exitOutcomes names the exit codes in failure summaries.
+var exitOutcomes = map[int]string{
+	exitOK:          "ok",
+	exitCheckFailed: "check_failed",
+	exitConfigError: "config_error",
+	exitInfraError:  "infra_error",
+}
+
+// failureSummary is printed instead of the usual report, with --output json, by a CLI mode that
+// fails before it has one: {"ok": false, "exit_code": 2, "outcome": "config_error", "error": "..."}.
+type failureSummary struct {
+	OK       bool   `json:"ok"`
+	ExitCode int    `json:"exit_code"`
+	Outcome  string `json:"outcome"`
+	Error    string `json:"error"`
+}
+
+// writeFailureSummary writes the failure summary of err to w if output is json, and returns
+// code, the exit code.
+func writeFailureSummary(w io.Writer, output string, code int, err error) int {
+	if output == "json" {
+		encoder := json.NewEncoder(w)
+		encoder.SetIndent("", "  ")
+		_ = encoder.Encode(failureSummary{ExitCode: code, Outcome: exitOutcomes[code], Error: err.Error()})
+	}
+	return code
+}
+
+// summaryError returns the error of a failure summary from a log message and its attributes,
+// e.g. "Invalid --proxy-url err=...".
+func summaryError(msg string, args ...any) error {
+	var b strings.Builder
+	b.WriteString(msg)
+	for i := 0; i+1 < len(args); i += 2 {
+		fmt.Fprintf(&b, " %v=%v", args[i], args[i+1])
+	}
+	return errors.New(b.String())
+}
+
+// loadExitCode returns the exit code of a failure to load a configuration: exitInfraError if the
+// server holding it (a remote configuration or an OIDC issuer) could not be reached,
+// exitConfigError otherwise.
+func loadExitCode(err error) int {
+	var netErr net.Error
+	if errors.As(err, &netErr) {
+		return exitInfraError
+	}
+	return exitConfigError
+}
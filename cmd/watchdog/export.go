@@ -0,0 +1,95 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"strings"
+	"time"
+
+	"watchdog.onebusaway.org/internal/app"
+	"watchdog.onebusaway.org/internal/httpclient"
+)
+
+// runExport implements `watchdog export overview`, which writes the fleet overview of a running
+// watchdog (see app.Overview), one row per server, as CSV or JSON for spreadsheets. It reads
+// the overview from the status API, with the API_AUTH_TOKEN, or the API_AUTH_USER and
+// API_AUTH_PASS, credentials if the API requires them. It returns the exit code (see exit.go):
+// exitConfigError on a usage error, exitInfraError if the overview cannot be read or written.
+func runExport(args []string, stdout, stderr io.Writer) int {
+	if len(args) == 0 || args[0] != "overview" {
+		fmt.Fprintln(stderr, "usage: watchdog export overview [--url <watchdog URL>] [--output csv|json]")
+		return exitConfigError
+	}
+	flags := flag.NewFlagSet("export overview", flag.ContinueOnError)
+	flags.SetOutput(stderr)
+	baseURL := flags.String("url", "http://localhost:4000", "URL of the running watchdog")
+	format := flags.String("output", "csv", "Output format (csv|json)")
+	flags.StringVar(format, "format", "csv", "Deprecated alias of --output")
+	timeout := flags.Duration("timeout", 30*time.Second, "Timeout of the request to the watchdog")
+	if err := flags.Parse(args[1:]); err != nil {
+		return exitConfigError
+	}
+	if *format != "csv" && *format != "json" {
+		fmt.Fprintf(stderr, "invalid --output %q: expected csv or json\n", *format)
+		return exitConfigError
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), *timeout)
+	defer cancel()
+	client := httpclient.New(httpclient.Options{Timeouts: httpclient.Timeouts{API: *timeout}}).API
+	rows, err := fetchOverview(ctx, client, *baseURL)
+	if err != nil {
+		fmt.Fprintln(stderr, "Error exporting the overview:", err)
+		return writeFailureSummary(stdout, *format, exitInfraError, err)
+	}
+	if *format == "csv" {
+		err = app.WriteOverviewCSV(stdout, rows)
+	} else {
+		encoder := json.NewEncoder(stdout)
+		encoder.SetIndent("", "  ")
+		err = encoder.Encode(rows)
+	}
+	if err != nil {
+		fmt.Fprintln(stderr, "Error writing the overview:", err)
+		return exitInfraError
+	}
+	return exitOK
+}
+
+// fetchOverview reads the fleet overview from GET /v1/overview of the watchdog at baseURL.
+func fetchOverview(ctx context.Context, client *http.Client, baseURL string) ([]app.OverviewRow, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, strings.TrimSuffix(baseURL, "/")+"/v1/overview", nil)
+	if err != nil {
+		return nil, fmt.Errorf("invalid --url: %w", err)
+	}
+	if token := os.Getenv("API_AUTH_TOKEN"); token != "" {
+		req.Header.Set("Authorization", "Bearer "+token)
+	} else if user := os.Getenv("API_AUTH_USER"); user != "" {
+		req.SetBasicAuth(user, os.Getenv("API_AUTH_PASS"))
+	}
+	resp, err := client.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+	switch resp.StatusCode {
+	case http.StatusOK:
+	case http.StatusUnauthorized, http.StatusForbidden:
+		return nil, errors.New("the status API rejected the credentials, set API_AUTH_TOKEN or API_AUTH_USER and API_AUTH_PASS")
+	default:
+		return nil, fmt.Errorf("the status API returned status: %d", resp.StatusCode)
+	}
+	var body struct {
+		Servers []app.OverviewRow `json:"servers"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&body); err != nil {
+		return nil, fmt.Errorf("failed to decode the overview: %w", err)
+	}
+	return body.Servers, nil
+}
@@ -2,18 +2,42 @@ package main
 
 import (
 	"context"
+	"crypto/rand"
+	"crypto/tls"
+	"errors"
 	"flag"
 	"fmt"
 	"log/slog"
 	"net/http"
+	"net/mail"
 	"os"
+	"os/signal"
+	"slices"
+	"strings"
+	"syscall"
 	"time"
 
 	"github.com/getsentry/sentry-go"
+	"github.com/prometheus/client_golang/prometheus"
+	"watchdog.onebusaway.org/internal/alert"
 	"watchdog.onebusaway.org/internal/app"
+	"watchdog.onebusaway.org/internal/auth"
 	"watchdog.onebusaway.org/internal/config"
+	"watchdog.onebusaway.org/internal/httpclient"
+	"watchdog.onebusaway.org/internal/i18n"
+	"watchdog.onebusaway.org/internal/leader"
+	"watchdog.onebusaway.org/internal/logbuffer"
+	"watchdog.onebusaway.org/internal/logsample"
+	"watchdog.onebusaway.org/internal/metrics"
 	"watchdog.onebusaway.org/internal/models"
+	"watchdog.onebusaway.org/internal/redisclient"
 	"watchdog.onebusaway.org/internal/report"
+	"watchdog.onebusaway.org/internal/scheduler"
+	"watchdog.onebusaway.org/internal/secrets"
+	"watchdog.onebusaway.org/internal/shutdown"
+	"watchdog.onebusaway.org/internal/telemetry"
+	"watchdog.onebusaway.org/internal/tlscert"
+	"watchdog.onebusaway.org/internal/updatecheck"
 )
 
 // Declare a string containing the application version number. Later in the book we'll
@@ -21,12 +45,27 @@ import (
 // number as a hard-coded global constant.
 const version = "1.0.0"
 
+// serverLogRecords is the number of log records of each server kept for diagnostic snapshots.
+const serverLogRecords = 200
+
 func main() {
-	// Initialize a structured logger for the application
-	// This logger will be used throughout the application for logging messages.
-	// It can be configured to log to different outputs (e.g., console, file)
-	logger := slog.New(slog.NewTextHandler(os.Stdout, nil))
-	logger.Info("Starting OneBusAway Watchdog", "version", version)
+	// `watchdog export overview` prints the fleet overview of a running watchdog and exits.
+	if len(os.Args) > 1 && os.Args[1] == "export" {
+		os.Exit(runExport(os.Args[2:], os.Stdout, os.Stderr))
+	}
+	// `watchdog validate-config` checks a configuration file and exits.
+	if len(os.Args) > 1 && os.Args[1] == "validate-config" {
+		os.Exit(runValidateConfig(os.Args[2:], os.Stdout, os.Stderr))
+	}
+	// `watchdog report` prints the uptime and SLA report of the servers from the check history and exits.
+	if len(os.Args) > 1 && os.Args[1] == "report" {
+		os.Exit(runReport(os.Args[2:], os.Stdout, os.Stderr))
+	}
+	// `watchdog import-regions` writes a configuration skeleton from the OneBusAway regions API and exits.
+	if len(os.Args) > 1 && os.Args[1] == "import-regions" {
+		os.Exit(runImportRegions(os.Args[2:], os.Stdout, os.Stderr))
+	}
+
 	// Load environment variables for configuration
 	configAuthUser := os.Getenv("CONFIG_AUTH_USER")
 	configAuthPass := os.Getenv("CONFIG_AUTH_PASS")
@@ -36,23 +75,358 @@ func main() {
 	var cfg config.Config
 
 	flag.IntVar(&cfg.Port, "port", 4000, "API server port")
+	flag.StringVar(&cfg.TLSCertFile, "tls-cert", "", "PEM certificate (with intermediates) to serve HTTPS with, along with --tls-key (empty = plain HTTP)")
+	flag.StringVar(&cfg.TLSKeyFile, "tls-key", "", "PEM private key of --tls-cert")
+	flag.Func("tls-reload-schedule", "Schedule for reloading --tls-cert and --tls-key when they are rotated (interval or cron expression, default disabled)", scheduleFlag(&cfg.TLSReloadSchedule))
+	flag.Float64Var(&cfg.ColdStartReadyFraction, "cold-start-ready-fraction", 1, "Fraction of the servers whose GTFS bundle must be loaded on startup before metrics are served and the watchdog is ready; the other bundles are downloaded in the background")
+	flag.DurationVar(&cfg.ColdStartTimeout, "cold-start-timeout", 0, "Longest wait on startup for --cold-start-ready-fraction of the GTFS bundles, after which the watchdog is ready anyway (0 = no limit)")
+	flag.DurationVar(&cfg.ShutdownTimeout, "shutdown-timeout", 25*time.Second, "How long to wait on SIGINT or SIGTERM for the checks, GTFS downloads and HTTP requests in flight before exiting")
 	flag.StringVar(&cfg.Env, "env", "development", "Environment (development|staging|production)")
 	flag.IntVar(&cfg.FetchInterval, "fetch-interval", 30, "Interval (in seconds) at which the application fetches data from realtime APIs and updates Prometheus metrics")
+	flag.DurationVar(&cfg.FeedStaleAfter, "feed-stale-after", 10*time.Minute, "How long after its last successful fetch a GTFS-RT feed counts as stale in watchdog_feeds_stale")
+	flag.Int64Var(&cfg.BundleDownloadRateLimit, "bundle-download-rate-limit", 0, "Maximum bandwidth (in bytes per second) for a single GTFS bundle download (0 = unlimited)")
+	flag.Int64Var(&cfg.BundleDownloadGlobalRateLimit, "bundle-download-global-rate-limit", 0, "Maximum combined bandwidth (in bytes per second) for all concurrent GTFS bundle downloads (0 = unlimited)")
+	flag.DurationVar(&cfg.ConnectTimeout, "connect-timeout", httpclient.DefaultTimeouts.Connect, "Timeout of establishing the TCP connections of outgoing requests")
+	flag.DurationVar(&cfg.TLSHandshakeTimeout, "tls-handshake-timeout", httpclient.DefaultTimeouts.TLSHandshake, "Timeout of the TLS handshake of the connections of outgoing requests")
+	flag.DurationVar(&cfg.APITimeout, "api-timeout", httpclient.DefaultTimeouts.API, "Timeout of OBA API calls and other outgoing requests")
+	flag.DurationVar(&cfg.RealtimeTimeout, "realtime-timeout", httpclient.DefaultTimeouts.Realtime, "Timeout of GTFS-RT feed requests")
+	flag.DurationVar(&cfg.BundleDownloadTimeout, "bundle-download-timeout", httpclient.DefaultTimeouts.Bundle, "Timeout of GTFS bundle downloads, ignored for throttled downloads")
+	flag.Float64Var(&cfg.OutboundRateLimit, "outbound-rate-limit", 0, "Maximum requests per second sent to each OBA API or GTFS-RT host; servers can override it with rate_limit (0 = unlimited)")
+	flag.Float64Var(&cfg.OutboundGlobalRateLimit, "outbound-global-rate-limit", 0, "Maximum requests per second sent to all hosts combined (0 = unlimited)")
+	flag.StringVar(&cfg.ProxyURL, "proxy-url", "", "HTTP(S) proxy of outgoing requests; servers can override it with proxy_url (empty = HTTP_PROXY, HTTPS_PROXY and NO_PROXY environment variables)")
+	flag.Func("ca-cert-files", "Comma-separated PEM files of CA certificates trusted for outgoing requests besides the system ones; servers can add their own with ca_cert_files", func(s string) error {
+		cfg.CACertFiles = strings.Split(s, ",")
+		return nil
+	})
+	flag.StringVar(&cfg.BundleCacheDir, "bundle-cache-dir", "", "Directory where downloaded GTFS bundles are cached across restarts (empty = disabled)")
+	flag.StringVar(&cfg.LifecycleFile, "lifecycle-file", "", "File where the first-seen and last-seen times of servers, routes and GTFS-RT feeds are kept across restarts (empty = kept in memory only)")
+	flag.StringVar(&cfg.HistoryDB, "history-db", "", "SQLite database where the result of every check run is recorded and served at /v1/servers/:id/history (empty = disabled)")
+	flag.DurationVar(&cfg.HistoryRetention, "history-retention", 31*24*time.Hour, "How long the check results of --history-db are kept")
+	// Schedules accept an interval ("@every 1h") or a cron expression ("0 3 * * *"),
+	// optionally prefixed with a time zone ("CRON_TZ=America/Los_Angeles 0 3 * * *").
+	cfg.BundleRefreshSchedule = scheduler.Every(24 * time.Hour)
+	cfg.LocalBundleWatchSchedule = scheduler.Every(10 * time.Second)
+	cfg.ConfigRefreshSchedule = scheduler.Every(time.Minute)
+	cfg.VehicleCleanupSchedule = scheduler.Every(15 * time.Minute)
+	cfg.AgencyDigestSchedule = scheduler.Every(24 * time.Hour)
+	cfg.ServiceCalendarRefreshSchedule = scheduler.Every(time.Hour)
+	cfg.ReportProblemSchedule = scheduler.Every(6 * time.Hour)
+	cfg.ExecCheckSchedule = scheduler.Every(5 * time.Minute)
+	cfg.SentryRetrySchedule = scheduler.Every(time.Minute)
+	cfg.SecretsRefreshSchedule = scheduler.Every(15 * time.Minute)
+	cfg.SharedStoreSyncSchedule = scheduler.Every(30 * time.Second)
+	cfg.UpdateCheckSchedule = scheduler.Every(24 * time.Hour)
+	flag.Func("fetch-schedule", "Schedule for collecting metrics, overrides --fetch-interval (interval or cron expression)", scheduleFlag(&cfg.FetchSchedule))
+	flag.Func("bundle-refresh-schedule", "Schedule for refreshing GTFS bundles (interval or cron expression, default \"@every 24h\")", scheduleFlag(&cfg.BundleRefreshSchedule))
+	flag.Func("local-bundle-watch-schedule", "Schedule for checking the GTFS bundles read from local files (gtfs_url set to a path or file:// URL) for changes (interval or cron expression, default \"@every 10s\")", scheduleFlag(&cfg.LocalBundleWatchSchedule))
+	flag.Func("config-refresh-schedule", "Schedule for reloading a remote config (interval or cron expression, default \"@every 1m\")", scheduleFlag(&cfg.ConfigRefreshSchedule))
+	flag.IntVar(&cfg.ConfigStaleIntervals, "config-stale-intervals", 5, "Scheduled reloads of a remote config that can fail in a row before /v1/readyz reports the config_url as stale")
+	flag.Func("agency-digest-schedule", "Schedule for sending data-quality findings to agency contacts (interval or cron expression, default \"@every 24h\")", scheduleFlag(&cfg.AgencyDigestSchedule))
+	flag.Func("service-calendar-refresh-schedule", "Schedule for reloading the reduced service calendars of servers (interval or cron expression, default \"@every 1h\")", scheduleFlag(&cfg.ServiceCalendarRefreshSchedule))
+	flag.Func("report-problem-schedule", "Schedule for submitting test problem reports to the OBA APIs of servers with a report_problem_stop_id (interval or cron expression, default \"@every 6h\")", scheduleFlag(&cfg.ReportProblemSchedule))
+	flag.Func("exec-check-schedule", "Schedule for running the custom exec_checks of servers (interval or cron expression, default \"@every 5m\")", scheduleFlag(&cfg.ExecCheckSchedule))
+	flag.BoolVar(&cfg.AllowExecChecks, "allow-exec-checks", false, "Run the exec_checks of servers; without it, configurations and servers added through the admin API that define exec checks are rejected")
+	flag.Func("secrets-refresh-schedule", "Schedule for resolving the secret references of the config again, to pick up rotated secrets (interval or cron expression, default \"@every 15m\")", scheduleFlag(&cfg.SecretsRefreshSchedule))
+	flag.StringVar(&cfg.ErrorReporter, "error-reporter", "sentry", "Where reported errors are sent: sentry (SENTRY_DSN), rollbar (ROLLBAR_ACCESS_TOKEN), log (the logs of the watchdog) or none")
+	flag.DurationVar(&cfg.SentryMinInterval, "sentry-min-interval", 10*time.Minute, "Interval between two reports to Sentry of the same error of the same server once --sentry-burst reports were sent (0 = no throttling)")
+	flag.IntVar(&cfg.SentryBurst, "sentry-burst", 3, "Reports to Sentry of the same error of the same server sent at once before --sentry-min-interval applies")
+	flag.Func("sentry-retry-schedule", "Schedule for sending the events that could not be delivered to Sentry again (interval or cron expression, default \"@every 1m\")", scheduleFlag(&cfg.SentryRetrySchedule))
+	flag.Func("shared-store-sync-schedule", "Schedule for syncing the entries published by the other instances to the shared stores (interval or cron expression, default \"@every 30s\")", scheduleFlag(&cfg.SharedStoreSyncSchedule))
+	flag.BoolVar(&cfg.UpdateCheck, "update-check", false, "Check the GitHub releases of --update-check-repo for a newer version on startup and on --update-check-schedule, exported as watchdog_update_available")
+	flag.StringVar(&cfg.UpdateCheckRepo, "update-check-repo", updatecheck.DefaultRepo, "GitHub repository (owner/name) whose releases --update-check compares the running version with")
+	flag.Func("update-check-schedule", "Schedule for checking for a newer version with --update-check (interval or cron expression, default \"@every 24h\")", scheduleFlag(&cfg.UpdateCheckSchedule))
+	flag.Func("vehicle-cleanup-schedule", "Schedule for removing stale vehicle data (interval or cron expression, default \"@every 15m\")", scheduleFlag(&cfg.VehicleCleanupSchedule))
+	flag.Int64Var(&cfg.MaxBundleSize, "max-bundle-size", 1<<30, "Maximum size (in bytes) of a GTFS bundle download; larger bundles are rejected (0 = unlimited)")
+	flag.StringVar(&cfg.BundleChangeWebhookURL, "bundle-change-webhook-url", "", "URL that receives a JSON POST whenever a server's GTFS bundle changes (empty = disabled)")
+	flag.StringVar(&cfg.LeaderElection, "leader-election", "", "Lock the replicas elect a leader with, which alone runs the checks: kubernetes (a Lease) or redis (empty = no election)")
+	flag.StringVar(&cfg.LeaderElectionName, "leader-election-name", "watchdog-leader", "Name of the Kubernetes Lease or Redis key of the leader election")
+	flag.StringVar(&cfg.LeaderElectionNamespace, "leader-election-namespace", "", "Namespace of the Kubernetes Lease of the leader election (empty = namespace of the pod)")
+	flag.StringVar(&cfg.LeaderElectionIdentity, "leader-election-identity", "", "Name of this replica in the leader election (empty = host name, the pod name in Kubernetes)")
+	flag.DurationVar(&cfg.LeaderElectionTTL, "leader-election-ttl", 15*time.Second, "How long the leader holds the lock without renewing it; a standby takes over after at most this long")
+	flag.StringVar(&cfg.RedisAddr, "redis-addr", "", "Redis server (host:port, or a redis:// or rediss:// URL) of the redis leader election and shared stores")
+	flag.StringVar(&cfg.SharedStore, "shared-store", "", "Backend the instances share their GTFS, bounding box and backoff stores through: redis (empty = not shared)")
+	flag.StringVar(&cfg.SharedStorePrefix, "shared-store-prefix", "watchdog:", "Prefix of the keys of the shared stores")
+	flag.StringVar(&cfg.ProbePrimaryURL, "probe-primary-url", "", "URL of the primary watchdog this instance is a secondary probe agent of, which it pushes its ping results to after every collection cycle (empty = not an agent)")
+	flag.StringVar(&cfg.VantagePoint, "vantage-point", "", "Name of this probe agent in the results of the primary, e.g. its region (empty = host name)")
+	flag.DurationVar(&cfg.ProbeResultMaxAge, "probe-result-max-age", 5*time.Minute, "How long the primary takes the last ping results of a probe agent into account")
+	flag.StringVar(&cfg.PushGatewayURL, "push-gateway-url", "", "Prometheus Pushgateway URL to push metrics to after every collection cycle (empty = disabled)")
+	flag.StringVar(&cfg.PushGatewayJob, "push-gateway-job", "watchdog", "Job name used when pushing metrics to the Pushgateway")
+	flag.StringVar(&cfg.OTLPEndpoint, "otlp-endpoint", "", "OTLP/HTTP endpoint of an OpenTelemetry collector to export metrics and traces to, e.g. http://localhost:4318 (empty = disabled)")
+	flag.StringVar(&cfg.SlackWebhookURL, "slack-webhook-url", "", "Slack incoming webhook URL that alerts are posted to (empty = disabled)")
+	flag.StringVar(&cfg.AlertLocale, "alert-locale", i18n.DefaultLocale, "Default language of alert notifications (en, es, fr); servers can override it with alerts.locale")
+	flag.StringVar(&cfg.AlertWebhookURL, "alert-webhook-url", "", "URL that receives a JSON POST whenever an alert check changes state (empty = disabled)")
+	flag.Func("alert-webhook-template", "Path to a Go text/template file rendering the body of alert webhook requests", func(path string) error {
+		tmpl, err := alert.ParseWebhookTemplate(path)
+		if err != nil {
+			return err
+		}
+		cfg.AlertWebhookTemplate = tmpl
+		return nil
+	})
+	flag.Func("alert-rules-file", "JSON file of alert rules on the exported metrics or the check results, with absolute or rate-of-change conditions and durations", func(path string) error {
+		rules, err := alert.LoadRules(path)
+		if err != nil {
+			return err
+		}
+		for _, rule := range rules {
+			// Exec checks are named by the servers, so any "exec:" name is accepted.
+			if rule.Check != "" && !slices.Contains(metrics.CheckNames, rule.Check) && !strings.HasPrefix(rule.Check, "exec:") {
+				return fmt.Errorf("rule %q has unknown check %q (expected one of %s, or exec:<name>)", rule.Name, rule.Check, strings.Join(metrics.CheckNames, ", "))
+			}
+		}
+		cfg.AlertRules = rules
+		return nil
+	})
+	flag.StringVar(&cfg.SMTPAddr, "smtp-addr", "", "SMTP server (host:port) alert emails and emails to agency contacts are sent through (empty = no emails)")
+	flag.StringVar(&cfg.SMTPFrom, "smtp-from", "watchdog@localhost", "Sender address of emails")
+	flag.StringVar(&cfg.SMTPUsername, "smtp-username", "", "Username to authenticate to the SMTP server with (empty = no authentication)")
+	flag.StringVar(&cfg.SMTPTLS, "smtp-tls", alert.SMTPTLSAuto, "Security of SMTP connections: auto (STARTTLS when supported), starttls (STARTTLS required) or tls (implicit TLS, usually on port 465)")
+	flag.StringVar(&cfg.SMTPAuth, "smtp-auth", alert.SMTPAuthPlain, "SMTP authentication mechanism used with --smtp-username: plain or cram-md5")
+	flag.Func("alert-email-to", "Comma-separated addresses alerts are emailed to (requires --smtp-addr); servers can override them with alerts.email_to", func(s string) error {
+		for _, address := range strings.Split(s, ",") {
+			if address = strings.TrimSpace(address); address != "" {
+				cfg.AlertEmailTo = append(cfg.AlertEmailTo, address)
+			}
+		}
+		return nil
+	})
+	flag.DurationVar(&cfg.AlertEmailDigestWindow, "alert-email-digest-window", 0, "Batch the alert emails over this window into a single email per recipients (0 = one email per alert)")
+	flag.DurationVar(&cfg.AlertCooldown, "alert-cooldown", time.Hour, "Minimum time between two alert notifications for the same server and check")
+	flag.StringVar(&cfg.AuthTokensFile, "auth-tokens-file", "", "JSON file of admin API tokens and their roles (empty = admin API disabled)")
+	flag.StringVar(&cfg.APIAuthUser, "api-auth-user", os.Getenv("API_AUTH_USER"), "Basic auth user required on /metrics and the status API, with the API_AUTH_PASS password (empty = no basic auth)")
+	flag.StringVar(&cfg.AuditLogFile, "audit-log", "", "File that admin API requests are appended to (empty = application log)")
+	flag.StringVar(&cfg.OIDCIssuerURL, "oidc-issuer-url", "", "OpenID Connect issuer users log in with to the dashboard and status API (empty = disabled)")
+	flag.StringVar(&cfg.OIDCClientID, "oidc-client-id", "", "Client ID of the watchdog at the OpenID Connect provider")
+	flag.StringVar(&cfg.OIDCRedirectURL, "oidc-redirect-url", "", "Public URL of the /auth/callback endpoint, e.g. https://watchdog.example.org/auth/callback")
+	flag.StringVar(&cfg.OIDCRolesClaim, "oidc-roles-claim", "groups", "ID token claim mapped to roles with --oidc-role-mapping")
+	flag.Func("oidc-role-mapping", "Comma-separated <claim value>=<role> pairs, e.g. watchdog-admins=admin,transit-ops=operator", func(s string) error {
+		mapping, err := auth.ParseRoleMapping(s)
+		if err != nil {
+			return err
+		}
+		cfg.OIDCRoleMapping = mapping
+		return nil
+	})
+	cfg.OIDCDefaultRole = auth.RoleViewer
+	flag.Func("oidc-default-role", "Role of OIDC users without a mapped claim value: viewer, operator, admin or none to deny them (default viewer)", func(s string) error {
+		if s == "none" {
+			cfg.OIDCDefaultRole = auth.RoleNone
+			return nil
+		}
+		role, err := auth.ParseRole(s)
+		cfg.OIDCDefaultRole = role
+		return err
+	})
+	flag.DurationVar(&cfg.OIDCSessionTTL, "oidc-session-ttl", 12*time.Hour, "How long an OIDC login lasts")
+	flag.BoolVar(&cfg.StatusPage, "status-page", false, "Serve a public status page at /status")
+	flag.StringVar(&cfg.StatusPageTitle, "status-page-title", "OneBusAway Status", "Title of the public status page")
+	cfg.StatusPageChecks = []string{metrics.CheckServerPing, metrics.CheckObaAPI, metrics.CheckRealtimeFeed}
+	flag.Func("status-page-checks", "Comma-separated checks the public status page reports on (default server_ping,oba_api,gtfs_rt_feed)", func(s string) error {
+		checks, err := parseCheckNames(s)
+		cfg.StatusPageChecks = checks
+		return err
+	})
+	cfg.ReadinessRequired = []string{"sentry"}
+	flag.Func("readiness-required", fmt.Sprintf("Comma-separated external dependencies whose failure makes /v1/readyz answer 503, among %s, or none; the others are only reported (default sentry)", strings.Join(app.ReadinessDependencies, ", ")), func(s string) error {
+		dependencies, err := parseReadinessDependencies(s)
+		cfg.ReadinessRequired = dependencies
+		return err
+	})
+	flag.IntVar(&cfg.StatusPageDays, "status-page-days", 90, fmt.Sprintf("Days of uptime history shown on the public status page (at most %d)", metrics.HistoryRetentionDays))
+	flag.Float64Var(&cfg.RouteMismatchThreshold, "route-mismatch-threshold", 0.05, "Share of an agency's routes missing from or extra in the OBA API above which the discrepancy is reported to Sentry")
+	flag.IntVar(&cfg.CircuitBreakerThreshold, "circuit-breaker-threshold", 5, "Consecutive ping failures after which a server's checks are skipped for the circuit breaker cooldown (0 = disabled)")
+	flag.DurationVar(&cfg.CircuitBreakerCooldown, "circuit-breaker-cooldown", 10*time.Minute, "How long the checks of a server are skipped once its circuit breaker opens")
+	flag.BoolVar(&cfg.IncidentFeed, "incident-feed", false, "Serve an Atom feed of incidents (alerts firing and resolving) at /v1/incidents.atom")
+	flag.DurationVar(&cfg.OTLPExportInterval, "otlp-export-interval", 30*time.Second, "Interval at which metrics and traces are exported to the OTLP endpoint")
 
 	var (
 		configFile = flag.String("config-file", "", "Path to a local JSON configuration file")
-		configURL  = flag.String("config-url", "", "URL to a remote JSON configuration file")
+		configURL  = flag.String("config-url", "", "URL to a remote JSON configuration file (http(s)://, or s3://<bucket>/<key> and gs://<bucket>/<object> read with the ambient cloud credentials)")
+		// When set, the alert checks are written as Prometheus alerting rules and the watchdog exits.
+		exportAlertRules = flag.String("export-alert-rules", "", "Write the alert checks of the loaded config as a Prometheus alerting rules file to this path and exit")
+		// When set, every check runs once, the report is printed and the watchdog exits.
+		once       = flag.Bool("once", false, "Run every check of the configured servers once, print a report and exit with status 1 if a check failed, 2 on a configuration error and 3 on an infrastructure error")
+		onceFormat = flag.String("once-format", "text", "Deprecated alias of --output")
+		// Repetitive warnings and errors are sampled to keep the log volume of long outages down.
+		logSampleFirst  = flag.Int("log-sample-first", 5, "Similar warnings and errors (same message and server) logged before the next ones are only summed up once per --log-sample-window (0 = log every record)")
+		logSampleWindow = flag.Duration("log-sample-window", time.Hour, "Period of the summaries of suppressed log records; similar records are logged again after a window without any")
 	)
+	flag.StringVar(onceFormat, "output", "text", "Format of the --once report, and of the failure summary printed instead when it cannot run (text|json)")
 	// Parse command line flags
 	flag.Parse()
 
+	// Initialize a structured logger for the application
+	// This logger will be used throughout the application for logging messages.
+	// It can be configured to log to different outputs (e.g., console, file)
+	// The last records of each server are also kept for diagnostic snapshots.
+	// With --once, stdout is left to the report.
+	serverLogs := logbuffer.New(serverLogRecords)
+	logOutput := os.Stdout
+	if *once {
+		logOutput = os.Stderr
+	}
+	var logSampler *logsample.Sampler
+	if *logSampleFirst > 0 && !*once {
+		logSampler = logsample.New(*logSampleFirst, *logSampleWindow)
+	}
+	logger := slog.New(logsample.NewHandler(logbuffer.NewHandler(slog.NewTextHandler(logOutput, nil), serverLogs), logSampler))
+	logger.Info("Starting OneBusAway Watchdog", "version", version)
+
+	// fail logs a startup error and exits with code (see exit.go), also printing the failure
+	// summary with --once --output json.
+	fail := func(code int, msg string, args ...any) {
+		logger.Error(msg, args...)
+		if *once {
+			writeFailureSummary(os.Stdout, *onceFormat, code, summaryError(msg, args...))
+		}
+		os.Exit(code)
+	}
+
+	// The PagerDuty routing key and the webhook signing secret are secrets, so they are read from the environment rather than a flag.
+	cfg.PagerDutyRoutingKey = os.Getenv("PAGERDUTY_ROUTING_KEY")
+	cfg.AlertWebhookSecret = os.Getenv("ALERT_WEBHOOK_SECRET")
+	cfg.SMTPPassword = os.Getenv("SMTP_PASSWORD")
+	cfg.APIAuthToken = os.Getenv("API_AUTH_TOKEN")
+	cfg.APIAuthPassword = os.Getenv("API_AUTH_PASS")
+	cfg.OIDCClientSecret = os.Getenv("OIDC_CLIENT_SECRET")
+	cfg.OIDCSessionSecret = os.Getenv("OIDC_SESSION_SECRET")
+	cfg.ProbeToken = os.Getenv("PROBE_TOKEN")
+	cfg.RedisPassword = os.Getenv("REDIS_PASSWORD")
+	cfg.RollbarAccessToken = os.Getenv("ROLLBAR_ACCESS_TOKEN")
+
 	// Validate that only one configuration source is specified
 	// Either a config file or a remote config URL can be specified, but not both.
 	err := config.ValidateConfigFlags(configFile, configURL)
 	if err != nil {
-		logger.Error("Error validating config flags", "err", err)
 		flag.Usage()
-		os.Exit(1)
+		fail(exitConfigError, "Error validating config flags", "err", err)
+	}
+
+	if *onceFormat != "text" && *onceFormat != "json" {
+		fail(exitConfigError, "Invalid --output, expected text or json", "output", *onceFormat)
+	}
+
+	if cfg.ColdStartReadyFraction <= 0 || cfg.ColdStartReadyFraction > 1 {
+		fail(exitConfigError, "Invalid --cold-start-ready-fraction, expected a fraction in (0, 1]", "fraction", cfg.ColdStartReadyFraction)
+	}
+
+	if cfg.StatusPageDays < 1 || cfg.StatusPageDays > metrics.HistoryRetentionDays {
+		fail(exitConfigError, "Invalid --status-page-days", "days", cfg.StatusPageDays, "max", metrics.HistoryRetentionDays)
+	}
+
+	if cfg.SMTPTLS != alert.SMTPTLSAuto && cfg.SMTPTLS != alert.SMTPTLSStartTLS && cfg.SMTPTLS != alert.SMTPTLSImplicit {
+		fail(exitConfigError, "Invalid --smtp-tls, expected auto, starttls or tls", "smtp_tls", cfg.SMTPTLS)
+	}
+	if cfg.SMTPAuth != alert.SMTPAuthPlain && cfg.SMTPAuth != alert.SMTPAuthCRAMMD5 {
+		fail(exitConfigError, "Invalid --smtp-auth, expected plain or cram-md5", "smtp_auth", cfg.SMTPAuth)
+	}
+	for _, address := range cfg.AlertEmailTo {
+		if _, err := mail.ParseAddress(address); err != nil {
+			fail(exitConfigError, "Invalid --alert-email-to address", "address", address, "error", err)
+		}
+	}
+	if len(cfg.AlertEmailTo) > 0 && cfg.SMTPAddr == "" {
+		fail(exitConfigError, "--alert-email-to requires --smtp-addr")
+	}
+	if cfg.AlertEmailDigestWindow < 0 {
+		fail(exitConfigError, "Invalid --alert-email-digest-window, expected a positive duration or 0", "window", cfg.AlertEmailDigestWindow)
+	}
+
+	if cfg.FeedStaleAfter <= 0 {
+		fail(exitConfigError, "Invalid --feed-stale-after, expected a positive duration", "feed_stale_after", cfg.FeedStaleAfter)
+	}
+
+	for _, timeout := range []struct {
+		flag  string
+		value time.Duration
+	}{
+		{"connect-timeout", cfg.ConnectTimeout},
+		{"tls-handshake-timeout", cfg.TLSHandshakeTimeout},
+		{"api-timeout", cfg.APITimeout},
+		{"realtime-timeout", cfg.RealtimeTimeout},
+		{"bundle-download-timeout", cfg.BundleDownloadTimeout},
+	} {
+		if timeout.value <= 0 {
+			fail(exitConfigError, "Invalid --"+timeout.flag+", expected a positive duration", "timeout", timeout.value)
+		}
+	}
+
+	if owner, name, ok := strings.Cut(cfg.UpdateCheckRepo, "/"); cfg.UpdateCheck && (!ok || owner == "" || name == "" || strings.Contains(name, "/")) {
+		fail(exitConfigError, "Invalid --update-check-repo, expected owner/name", "repo", cfg.UpdateCheckRepo)
+	}
+
+	switch cfg.ErrorReporter {
+	case "sentry", "log", "none":
+	case "rollbar":
+		if cfg.RollbarAccessToken == "" {
+			fail(exitConfigError, "--error-reporter rollbar requires the ROLLBAR_ACCESS_TOKEN environment variable")
+		}
+	default:
+		fail(exitConfigError, "Invalid --error-reporter, expected sentry, rollbar, log or none", "error_reporter", cfg.ErrorReporter)
+	}
+	if cfg.SentryMinInterval < 0 {
+		fail(exitConfigError, "Invalid --sentry-min-interval, expected a positive duration or 0", "interval", cfg.SentryMinInterval)
+	}
+	if cfg.SentryBurst < 1 {
+		fail(exitConfigError, "Invalid --sentry-burst, expected at least 1", "burst", cfg.SentryBurst)
+	}
+
+	if cfg.HistoryDB != "" && cfg.HistoryRetention <= 0 {
+		fail(exitConfigError, "Invalid --history-retention, expected a positive duration", "retention", cfg.HistoryRetention)
+	}
+
+	if cfg.ProbePrimaryURL != "" {
+		if cfg.ProbeToken == "" {
+			fail(exitConfigError, "--probe-primary-url requires the PROBE_TOKEN environment variable shared with the primary")
+		}
+		cfg.ProbePrimaryURL = strings.TrimSuffix(cfg.ProbePrimaryURL, "/")
+		if cfg.VantagePoint == "" {
+			if cfg.VantagePoint, err = os.Hostname(); err != nil {
+				fail(exitConfigError, "Cannot determine the host name, set --vantage-point", "error", err)
+			}
+		}
+	}
+
+	switch cfg.LeaderElection {
+	case "", "kubernetes":
+	case "redis":
+		if cfg.RedisAddr == "" {
+			fail(exitConfigError, "--leader-election redis requires --redis-addr")
+		}
+	default:
+		fail(exitConfigError, "Invalid --leader-election, expected kubernetes or redis", "leader_election", cfg.LeaderElection)
+	}
+	switch cfg.SharedStore {
+	case "":
+	case "redis":
+		if cfg.RedisAddr == "" {
+			fail(exitConfigError, "--shared-store redis requires --redis-addr")
+		}
+	default:
+		fail(exitConfigError, "Invalid --shared-store, expected redis", "shared_store", cfg.SharedStore)
+	}
+	if cfg.LeaderElection == "redis" || cfg.SharedStore == "redis" {
+		if cfg.Redis, err = redisclient.New(cfg.RedisAddr, cfg.RedisPassword); err != nil {
+			fail(exitConfigError, "Invalid --redis-addr", "error", err)
+		}
+		defer cfg.Redis.Close()
+	}
+	if cfg.LeaderElection != "" && cfg.LeaderElectionTTL < 3*time.Second {
+		fail(exitConfigError, "Invalid --leader-election-ttl, expected at least 3s", "ttl", cfg.LeaderElectionTTL)
+	}
+	// Load the TLS certificate up front, so that a wrong path fails before the cold start.
+	var certs *tlscert.Reloader
+	if cfg.TLSCertFile != "" || cfg.TLSKeyFile != "" {
+		if cfg.TLSCertFile == "" || cfg.TLSKeyFile == "" {
+			fail(exitConfigError, "Both --tls-cert and --tls-key are required to serve HTTPS")
+		}
+		if certs, err = tlscert.New(cfg.TLSCertFile, cfg.TLSKeyFile); err != nil {
+			fail(exitConfigError, "Error loading TLS certificate", "err", err)
+		}
+	}
+
+	if !i18n.Supported(cfg.AlertLocale) {
+		logger.Warn("Unsupported alert locale, using the default", "locale", cfg.AlertLocale, "default", i18n.DefaultLocale, "supported", i18n.Locales())
 	}
 
 	// At this point, we are sure that all command line flags have been parsed
@@ -61,16 +435,69 @@ func main() {
 	// Create a context for the application
 	// This context will be used to manage the application's lifecycle and cancel operations when needed.
 	// It allows us to gracefully shut down the application and clean up resources.
-	// we will use it to cancel and clean up routines when the application is shutting down.
-	ctx, cancel := context.WithCancel(context.Background())
-	defer cancel()
+	// On SIGINT or SIGTERM, the coordinator stops the scheduled routines, waits (up to
+	// --shutdown-timeout) for the checks and GTFS downloads in flight, then cancels the context.
+	// A second signal exits right away.
+	coordinator, ctx := shutdown.New(context.Background())
+	shutdownDeadline := make(chan time.Time, 1)
+	drained := make(chan struct{})
+	signals := make(chan os.Signal, 1)
+	signal.Notify(signals, syscall.SIGINT, syscall.SIGTERM)
+	go func() {
+		sig := <-signals
+		signal.Stop(signals)
+		shutdownDeadline <- time.Now().Add(cfg.ShutdownTimeout)
+		logger.Info("Shutting down, waiting for the work in flight", "signal", sig.String(), "timeout", cfg.ShutdownTimeout)
+		if !coordinator.Shutdown(cfg.ShutdownTimeout) {
+			logger.Warn("Shutdown timeout reached, canceling the work in flight")
+		}
+		close(drained)
+	}()
 
-	// Create a new HTTP client with a connection pool
-	// This client will be reused across the application to avoid creating new connections for each request.
+	// Create the HTTP clients with a shared connection pool
+	// They are reused across the application to avoid creating new connections for each request.
 	// This is particularly useful for polling APIs like GTFS-RT endpoints.
-	// It can be configured with timeouts, retries, etc.
-	// Using a pooled client allows for better performance and resource management.
-	client := app.NewPooledClient()
+	// Each type of request (OBA API calls, GTFS-RT polls, bundle downloads) has its own timeout,
+	// and servers can override the timeouts of the requests to their hosts.
+	// A proxy and additional CA certificates can be configured for all requests, and
+	// overridden per server.
+	clientOptions, err := httpClientOptions(httpclient.Timeouts{
+		Connect:      cfg.ConnectTimeout,
+		TLSHandshake: cfg.TLSHandshakeTimeout,
+		API:          cfg.APITimeout,
+		Realtime:     cfg.RealtimeTimeout,
+		Bundle:       cfg.BundleDownloadTimeout,
+	}, cfg.ProxyURL, cfg.CACertFiles)
+	if err != nil {
+		fail(exitConfigError, "Invalid HTTP client settings", "err", err)
+	}
+	clients := httpclient.New(clientOptions)
+	clients.SetServers(cfg.GetServers)
+	client := clients.API
+	// Errors are reported to Sentry or Rollbar through the shared transport too, with its proxy
+	// and CAs, but without the rate limits of the agencies' servers wrapped around it below.
+	clients.WrapTransport(report.SendThrough)
+
+	// Rate limit outgoing requests so that concurrent checks cannot overload small agencies' servers.
+	rateLimiter := httpclient.NewRateLimiter(cfg.OutboundRateLimit, cfg.OutboundGlobalRateLimit)
+	rateLimiter.SetServers(cfg.GetServers)
+	clients.WrapTransport(rateLimiter.Transport)
+
+	// If an OpenTelemetry collector is configured, export all Prometheus metrics to it
+	// and trace outgoing HTTP requests. The exports go through the shared transport too.
+	if cfg.OTLPEndpoint != "" {
+		otlpHeaders, err := telemetry.ParseHeaders(os.Getenv("OTEL_EXPORTER_OTLP_HEADERS"))
+		if err != nil {
+			fail(exitConfigError, "Error parsing OTEL_EXPORTER_OTLP_HEADERS", "err", err)
+		}
+		exporter := telemetry.NewExporter(client, cfg.OTLPEndpoint, otlpHeaders, map[string]string{
+			"service.name":           "watchdog",
+			"service.version":        version,
+			"deployment.environment": cfg.Env,
+		}, prometheus.DefaultGatherer, logger)
+		clients.WrapTransport(exporter.Transport)
+		go exporter.Run(ctx, cfg.OTLPExportInterval)
+	}
 
 	// Load the configuration from the specified source
 	// If a config file is specified, load it from disk.
@@ -78,21 +505,48 @@ func main() {
 	var servers []models.ObaServer
 	if *configFile != "" {
 		servers, err = config.LoadConfigFromFile(*configFile)
+		cfg.ConfigFile = *configFile
 	} else if *configURL != "" {
 		servers, err = config.LoadConfigFromURL(ctx, client, *configURL, configAuthUser, configAuthPass, 20)
+		cfg.ConfigURL = *configURL
+		cfg.SetLastRefresh(config.RefreshStatus{At: time.Now(), Err: err})
+	}
+
+	// Exec checks run commands on this host, so they are only accepted when enabled.
+	if err == nil && !cfg.AllowExecChecks {
+		err = config.DisallowExecChecks(servers)
+	}
+
+	// Replace the secret references (vault://, awssm://, gcpsm://) of the API keys by their values.
+	secretResolver := secrets.NewResolver(client)
+	if err == nil {
+		err = config.ResolveSecrets(ctx, secretResolver, servers)
 	}
 
 	if err != nil {
-		logger.Error("Error loading configuration", "err", err)
-		os.Exit(1)
+		fail(loadExitCode(err), "Error loading configuration", "err", err)
 	}
 
 	if len(servers) == 0 {
-		logger.Error("Error: No servers found in configuration.")
-		os.Exit(1)
+		fail(exitConfigError, "Error: No servers found in configuration.")
 	}
 
+	if *exportAlertRules != "" {
+		if err := writeAlertRules(*exportAlertRules, servers, &cfg); err != nil {
+			fail(exitInfraError, "Error exporting alert rules", "err", err)
+		}
+		logger.Info("Exported alert rules", "path", *exportAlertRules)
+		os.Exit(0)
+	}
+
+	// Derive feed URLs that are not in the config from the OBA instances' data sources.
+	servers = config.ResolveDataSources(ctx, client, servers, logger)
+	config.WarnDuplicateServers(servers, logger)
+
 	cfg.UpdateConfig(servers)
+	// Entries of type "url" are only checked in the collection cycles; the rest of the startup
+	// (bundle downloads, cold start) is about the OBA servers.
+	servers = cfg.GetServers()
 
 	// At this point, we have successfully loaded the configuration
 	// and have a list of OBA servers to work with.
@@ -102,7 +556,50 @@ func main() {
 	// and the required dependencies.
 	// this New() function is critical in understanding how we structure the application take a look at it.
 	// and also take a look at service file in each package to see the dependencies and the exposed methods and function.
-	app := app.New(&cfg, logger, client, version)
+	app := app.New(&cfg, logger, clients, version)
+	defer app.History.Close()
+	app.Logs = serverLogs
+	app.ConfigService.Secrets = secretResolver
+
+	// Enable the admin API if tokens are configured. Every admin request is audited,
+	// to a dedicated file if one is given.
+	if cfg.AuthTokensFile != "" {
+		tokens, err := auth.LoadTokenFile(cfg.AuthTokensFile)
+		if err != nil {
+			fail(exitConfigError, "Error loading admin API tokens", "err", err)
+		}
+		app.Authenticator = auth.Authenticators{tokens}
+	}
+
+	// Require credentials on /metrics and the status API, for deployments reachable from the
+	// internet that should not expose the monitored servers.
+	if cfg.APIAuthToken != "" || cfg.APIAuthUser != "" {
+		apiAuth, err := auth.NewStaticAuthenticator(cfg.APIAuthToken, cfg.APIAuthUser, cfg.APIAuthPassword)
+		if err != nil {
+			fail(exitConfigError, "Error setting up API authentication", "err", err)
+		}
+		app.APIAuth = apiAuth
+	}
+
+	// Let users log in with OIDC. Their sessions (and ID tokens) are accepted wherever
+	// admin API tokens are.
+	if cfg.OIDCIssuerURL != "" {
+		oidcProvider, err := newOIDC(ctx, &cfg, client, logger)
+		if err != nil {
+			fail(loadExitCode(err), "Error setting up OIDC login", "err", err)
+		}
+		app.OIDC = oidcProvider
+		authenticators, _ := app.Authenticator.(auth.Authenticators)
+		app.Authenticator = append(authenticators, oidcProvider)
+	}
+	if cfg.AuditLogFile != "" {
+		auditFile, err := os.OpenFile(cfg.AuditLogFile, os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0o600)
+		if err != nil {
+			fail(exitInfraError, "Error opening audit log", "err", err)
+		}
+		defer auditFile.Close()
+		app.AuditLogger = slog.New(slog.NewJSONHandler(auditFile, nil))
+	}
 
 	// Initialize Sentry for error reporting
 	// This will allow us to capture and report errors that occur during the application's execution.
@@ -112,29 +609,116 @@ func main() {
 	// Note: you should have (SENTRY_DSN) environment variable set to your Sentry DSN.
 	// you can read in sentry documentation how to set it up.
 	// Link to official documentation: https://docs.sentry.io/concepts/key-terms/dsn-explainer/
-	report.SetupSentry()
-	defer report.FlushSentry()
-	report.ConfigureScope(cfg.Env, version)
+	//
+	// Deployments that cannot send data to Sentry report errors to Rollbar, to the logs, or
+	// nowhere instead (--error-reporter).
+	switch cfg.ErrorReporter {
+	case "sentry":
+		report.SetupSentry()
+		report.ConfigureScope(cfg.Env, version)
+	case "rollbar":
+		report.SetReporter(report.NewRollbarReporter(report.RollbarOptions{AccessToken: cfg.RollbarAccessToken, Environment: cfg.Env, CodeVersion: version}))
+	case "log":
+		report.SetReporter(report.NewLogReporter(logger))
+	case "none":
+		report.SetReporter(report.NoopReporter{})
+	}
+	defer report.Flush()
+	report.SetThrottle(cfg.SentryMinInterval, cfg.SentryBurst)
 
 	// From here we set up all dependencies and we are ready to start business logic.
 
-	// On startup, download GTFS static bundles for all configured servers
-	app.GtfsService.DownloadGTFSBundles(ctx, servers, 20)
+	// With leader election, only the leader runs the checks; the standbys serve the metrics and
+	// status of the last time they led, flagged by watchdog_leader, and take over when the
+	// leader stops renewing its lock. The first election is held before the cold start, so
+	// that a standby does not check the servers on startup.
+	if cfg.LeaderElection != "" && !*once {
+		elector, err := newElector(&cfg, logger)
+		if err != nil {
+			fail(exitConfigError, "Error setting up leader election", "err", err)
+		}
+		app.Leader = elector
+		elector.Elect(ctx)
+		go elector.Run(ctx)
+	} else {
+		leader.LeaderGauge.Set(1)
+	}
+
+	// With --once, run every check once and exit with the result instead of monitoring.
+	if *once {
+		os.Exit(runOnce(ctx, app, servers, *onceFormat, os.Stdout, logger))
+	}
+
+	// On startup, restore GTFS static bundles from the disk cache (if configured),
+	// then download GTFS static bundles and run the first checks for all configured servers,
+	// one priority tier at a time so the most important servers are monitored first.
+	// Startup goes on once --cold-start-ready-fraction of the servers have a bundle (restored
+	// from the cache or downloaded), or after --cold-start-timeout, and the rest of the cold
+	// start runs in the background; its progress is in the status API.
+	// With shared stores, the bundles already downloaded by the other instances are synced
+	// first, so that the cold start only checks them for changes.
+	app.GtfsService.LoadCachedGTFSBundles(servers)
+	if cfg.SharedStore != "" {
+		app.SyncSharedStores(ctx)
+	}
+	go app.ColdStart(ctx, servers, 20)
+	progress := app.Bootstrap.Wait(ctx)
+	logger.Info("Cold start ready", "loaded", progress.Loaded, "failed", progress.Failed, "servers", progress.Total, "complete", progress.Complete)
 
 	// This function starts the metrics collection process
 	// it intialize a routine the run every FetchInterval seconds (30 seconds by default)
 	// and collects metrics from all configured OBA servers.
 	app.StartMetricsCollection(ctx)
 
-	// Cron job to download GTFS bundles for all servers every 24 hours
-	go app.GtfsService.RefreshGTFSBundles(ctx, servers, 24*time.Hour, 5)
+	// Cron job to download GTFS bundles for all servers (every 24 hours by default)
+	go app.GtfsService.RefreshGTFSBundles(ctx, app.ConfigService.Config.GetServers, cfg.BundleRefreshSchedule, 5)
+
+	// Cron job to reload the GTFS bundles read from local files when they change (every 10 seconds by default)
+	go app.GtfsService.WatchLocalGTFSBundles(ctx, app.ConfigService.Config.GetServers, cfg.LocalBundleWatchSchedule, 5)
+
+	// Cron job to reload the reduced service calendars of servers (every hour by default)
+	go app.GtfsService.RefreshServiceReductions(ctx, app.ConfigService.Config.GetServers, cfg.ServiceCalendarRefreshSchedule)
+
+	// Cron job to check that the OBA APIs accept problem reports (every 6 hours by default)
+	go app.RunReportProblemChecks(ctx, cfg.ReportProblemSchedule)
+
+	// Cron job to run the custom exec checks of servers (every 5 minutes by default)
+	go app.RunExecChecks(ctx, cfg.ExecCheckSchedule)
+
+	// Cron job to resolve the secret references of the config again, for rotated secrets (every 15 minutes by default)
+	go app.ConfigService.RefreshSecrets(ctx, cfg.SecretsRefreshSchedule)
+
+	// Cron job to send the events buffered while Sentry was unavailable again (every minute by default)
+	go report.RetryBufferedEvents(ctx, cfg.SentryRetrySchedule)
+
+	// Check for a newer release of the watchdog now, then daily by default, if enabled
+	if cfg.UpdateCheck {
+		checker := &updatecheck.Checker{Repo: cfg.UpdateCheckRepo, Current: version, Client: client, Logger: logger}
+		go checker.Run(ctx, cfg.UpdateCheckSchedule)
+	}
+
+	// Cron job to send the data-quality findings of servers to their agencies (every 24 hours by default)
+	go app.AgencyDigest.Run(ctx, cfg.AgencyDigestSchedule)
+
+	// Cron job to send the batched alert emails (every --alert-email-digest-window, if set)
+	go app.EmailAlerts.Run(ctx, cfg.AlertEmailDigestWindow)
+
+	// Log the summaries of the suppressed repetitive warnings and errors (every minute)
+	if logSampler != nil {
+		go logSampler.Run(ctx)
+	}
+
+	// Cron job to sync the entries published by the other instances to the shared stores (every 30 seconds by default)
+	if cfg.SharedStore != "" {
+		go app.RunSharedStoreSync(ctx, cfg.SharedStoreSyncSchedule)
+	}
 
 	// Cron job to delete the data of vehicles that has not sent updates for 1 hour
-	go app.MetricsService.VehicleLastSeen.ClearRoutine(ctx, 15*time.Minute, time.Hour)
+	go app.MetricsService.VehicleLastSeen.ClearRoutine(ctx, cfg.VehicleCleanupSchedule, time.Hour)
 
-	// If a remote URL is specified, refresh the configuration every minute
+	// If a remote URL is specified, refresh the configuration (every minute by default)
 	if *configURL != "" {
-		go app.ConfigService.RefreshConfig(ctx, *configURL, configAuthUser, configAuthPass, time.Minute, 20)
+		go app.ConfigService.RefreshConfig(ctx, *configURL, configAuthUser, configAuthPass, cfg.ConfigRefreshSchedule, 20)
 	}
 
 	// Start the HTTP server to serve the API and metrics endpoints
@@ -154,10 +738,176 @@ func main() {
 		ErrorLog:     slog.NewLogLogger(logger.Handler(), slog.LevelError),
 	}
 
-	logger.Info("starting server", "addr", srv.Addr, "env", cfg.Env)
-	err = srv.ListenAndServe()
+	// Once the work in flight is drained, stop accepting connections and wait for the requests
+	// in flight until the end of the shutdown timeout. The server keeps serving /metrics and
+	// the status API while checks drain.
+	serverClosed := make(chan struct{})
+	go func() {
+		defer close(serverClosed)
+		<-drained
+		shutdownCtx, cancelShutdown := context.WithDeadline(context.Background(), <-shutdownDeadline)
+		defer cancelShutdown()
+		if err := srv.Shutdown(shutdownCtx); err != nil {
+			logger.Warn("HTTP server shutdown incomplete", "err", err)
+		}
+	}()
+
+	// Serve HTTPS (and HTTP/2) directly if a certificate is configured, reloading it on rotation.
+	if certs != nil {
+		if cfg.TLSReloadSchedule != nil {
+			go certs.Watch(ctx, cfg.TLSReloadSchedule, logger)
+		}
+		srv.TLSConfig = certs.TLSConfig()
+		logger.Info("starting server", "addr", srv.Addr, "env", cfg.Env, "tls", true)
+		err = srv.ListenAndServeTLS("", "")
+	} else {
+		logger.Info("starting server", "addr", srv.Addr, "env", cfg.Env)
+		err = srv.ListenAndServe()
+	}
+	if errors.Is(err, http.ErrServerClosed) {
+		<-serverClosed
+		// Returning runs the deferred calls, which flush the reported errors in flight.
+		logger.Info("Shutdown complete")
+		return
+	}
 	report.ReportError(err, sentry.LevelFatal)
-	report.FlushSentry()
+	report.Flush()
 	logger.Error(err.Error())
 	os.Exit(1)
 }
+
+// httpClientOptions returns the options of the HTTP clients with the given timeouts, the proxy
+// of --proxy-url (empty = the environment) and the CA certificates of --ca-cert-files.
+func httpClientOptions(timeouts httpclient.Timeouts, proxyURL string, caCertFiles []string) (httpclient.Options, error) {
+	options := httpclient.Options{Timeouts: timeouts}
+	if proxyURL != "" {
+		proxy, err := httpclient.ParseProxyURL(proxyURL)
+		if err != nil {
+			return options, fmt.Errorf("invalid --proxy-url: %w", err)
+		}
+		options.Proxy = proxy
+	}
+	if len(caCertFiles) > 0 {
+		pool, err := httpclient.LoadCertPool(caCertFiles)
+		if err != nil {
+			return options, fmt.Errorf("invalid --ca-cert-files: %w", err)
+		}
+		options.TLSConfig = &tls.Config{RootCAs: pool, MinVersion: tls.VersionTLS12}
+	}
+	return options, nil
+}
+
+// parseCheckNames parses a comma-separated list of check names (see metrics.CheckNames).
+func parseCheckNames(s string) ([]string, error) {
+	var checks []string
+	for _, check := range strings.Split(s, ",") {
+		check = strings.TrimSpace(check)
+		if check == "" {
+			continue
+		}
+		if !slices.Contains(metrics.CheckNames, check) {
+			return nil, fmt.Errorf("unknown check %q (expected one of %s)", check, strings.Join(metrics.CheckNames, ", "))
+		}
+		checks = append(checks, check)
+	}
+	if len(checks) == 0 {
+		return nil, errors.New("no checks given")
+	}
+	return checks, nil
+}
+
+// parseReadinessDependencies parses a comma-separated list of the external dependencies of the
+// readiness probe (see app.ReadinessDependencies); "none" is the empty list.
+func parseReadinessDependencies(s string) ([]string, error) {
+	if strings.TrimSpace(s) == "none" {
+		return nil, nil
+	}
+	var dependencies []string
+	for _, dependency := range strings.Split(s, ",") {
+		dependency = strings.TrimSpace(dependency)
+		if dependency == "" {
+			continue
+		}
+		if !slices.Contains(app.ReadinessDependencies, dependency) {
+			return nil, fmt.Errorf("unknown dependency %q (expected one of %s, or none)", dependency, strings.Join(app.ReadinessDependencies, ", "))
+		}
+		dependencies = append(dependencies, dependency)
+	}
+	return dependencies, nil
+}
+
+// scheduleFlag returns a flag.Func handler that parses a schedule specification into dst.
+func scheduleFlag(dst *scheduler.Schedule) func(string) error {
+	return func(spec string) error {
+		schedule, err := scheduler.Parse(spec)
+		if err != nil {
+			return err
+		}
+		*dst = schedule
+		return nil
+	}
+}
+
+// writeAlertRules writes the alert checks for servers as a Prometheus alerting rules file.
+// Checks counting consecutive failures are converted to durations using the collection
+// interval, i.e. the fetch interval or the time between two runs of the fetch schedule.
+func writeAlertRules(path string, servers []models.ObaServer, cfg *config.Config) error {
+	interval := time.Duration(cfg.FetchInterval) * time.Second
+	if cfg.FetchSchedule != nil {
+		next := cfg.FetchSchedule.Next(time.Now())
+		interval = cfg.FetchSchedule.Next(next).Sub(next)
+	}
+
+	f, err := os.Create(path)
+	if err != nil {
+		return err
+	}
+	if err := alert.WritePrometheusRules(f, servers, interval); err != nil {
+		f.Close()
+		return err
+	}
+	return f.Close()
+}
+
+// newOIDC sets up OIDC login from the configuration. Without OIDC_SESSION_SECRET, sessions
+// are signed with a random secret and end when the watchdog restarts.
+func newOIDC(ctx context.Context, cfg *config.Config, client *http.Client, logger *slog.Logger) (*auth.OIDC, error) {
+	secret := []byte(cfg.OIDCSessionSecret)
+	if len(secret) == 0 {
+		logger.Warn("OIDC_SESSION_SECRET is not set, users will have to log in again after a restart")
+		secret = make([]byte, 32)
+		if _, err := rand.Read(secret); err != nil {
+			return nil, err
+		}
+	}
+	return auth.NewOIDC(ctx, auth.OIDCConfig{
+		IssuerURL:     cfg.OIDCIssuerURL,
+		ClientID:      cfg.OIDCClientID,
+		ClientSecret:  cfg.OIDCClientSecret,
+		RedirectURL:   cfg.OIDCRedirectURL,
+		RolesClaim:    cfg.OIDCRolesClaim,
+		RoleMapping:   cfg.OIDCRoleMapping,
+		DefaultRole:   cfg.OIDCDefaultRole,
+		SessionTTL:    cfg.OIDCSessionTTL,
+		SessionSecret: secret,
+	}, client)
+}
+
+// newElector sets up the leader election of --leader-election. The identity of the replica
+// defaults to the host name, which is the pod name in Kubernetes.
+func newElector(cfg *config.Config, logger *slog.Logger) (*leader.Elector, error) {
+	identity := cfg.LeaderElectionIdentity
+	if identity == "" {
+		hostname, err := os.Hostname()
+		if err != nil {
+			return nil, fmt.Errorf("cannot determine the host name, set --leader-election-identity: %w", err)
+		}
+		identity = hostname
+	}
+	logger = logger.With("component", "leader_election")
+	if cfg.LeaderElection == "kubernetes" {
+		return leader.NewKubernetesElector(cfg.LeaderElectionName, cfg.LeaderElectionNamespace, identity, cfg.LeaderElectionTTL, logger)
+	}
+	lock := &leader.RedisLock{Client: cfg.Redis, Key: cfg.LeaderElectionName}
+	return leader.NewElector(lock, identity, cfg.LeaderElectionTTL, logger), nil
+}
@@ -0,0 +1,41 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"io"
+	"log/slog"
+
+	"watchdog.onebusaway.org/internal/app"
+	"watchdog.onebusaway.org/internal/models"
+	"watchdog.onebusaway.org/internal/report"
+)
+
+// runOnce implements --once: it runs every check of the servers once, writes the report in
+// format (text or json) to out, and returns the exit code (see exit.go): exitCheckFailed if a
+// check failed, exitInfraError if the report cannot be written.
+//
+// Alert notifications are disabled, since the run reports through its exit code, e.g. to
+// fail a CI job. Errors are still reported, to Sentry if configured (see --error-reporter).
+func runOnce(ctx context.Context, application *app.Application, servers []models.ObaServer, format string, out io.Writer, logger *slog.Logger) int {
+	application.Alerts = nil
+	checkReport := application.RunChecksOnce(ctx, servers, 3)
+	report.Flush()
+
+	var err error
+	if format == "json" {
+		encoder := json.NewEncoder(out)
+		encoder.SetIndent("", "  ")
+		err = encoder.Encode(checkReport)
+	} else {
+		err = checkReport.WriteText(out)
+	}
+	if err != nil {
+		logger.Error("Error writing the check report", "err", err)
+		return exitInfraError
+	}
+	if !checkReport.OK {
+		return exitCheckFailed
+	}
+	return exitOK
+}
@@ -0,0 +1,75 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"time"
+
+	"watchdog.onebusaway.org/internal/config"
+	"watchdog.onebusaway.org/internal/httpclient"
+	"watchdog.onebusaway.org/internal/models"
+)
+
+// runImportRegions implements `watchdog import-regions`, which writes a configuration skeleton
+// with a server for each region of the OneBusAway regions API (see config.RegionServers), to
+// set up the monitoring of many regions at once. With --merge, the skeleton is merged into an
+// existing configuration file (see config.MergeServers), which is left as is: the merged
+// configuration is written to stdout like the skeleton. It returns the exit code (see exit.go):
+// exitConfigError on a usage error or if the configuration to merge with cannot be read,
+// exitInfraError if the regions cannot be fetched or the configuration cannot be written.
+func runImportRegions(args []string, stdout, stderr io.Writer) int {
+	flags := flag.NewFlagSet("import-regions", flag.ContinueOnError)
+	flags.SetOutput(stderr)
+	regionsURL := flags.String("regions-url", config.DefaultRegionsURL, "URL of the OneBusAway regions API")
+	mergeFile := flags.String("merge", "", "Configuration file to merge the regions into (empty = write the regions only)")
+	includeInactive := flags.Bool("include-inactive", false, "Also import the regions that are not active")
+	includeExperimental := flags.Bool("include-experimental", false, "Also import the experimental regions")
+	timeout := flags.Duration("timeout", 30*time.Second, "Timeout of the request to the regions API")
+	if err := flags.Parse(args); err != nil {
+		return exitConfigError
+	}
+	if flags.NArg() > 0 {
+		fmt.Fprintln(stderr, "usage: watchdog import-regions [--regions-url <url>] [--merge <config file>] [--include-inactive] [--include-experimental]")
+		return exitConfigError
+	}
+
+	var existing []models.ObaServer
+	if *mergeFile != "" {
+		// #nosec G304 - the file is chosen by the user running the command
+		data, err := os.ReadFile(*mergeFile)
+		if err != nil {
+			fmt.Fprintln(stderr, "Error reading the configuration:", err)
+			return exitConfigError
+		}
+		if existing, err = config.ParseServers(data); err != nil {
+			fmt.Fprintln(stderr, "Error parsing the configuration:", err)
+			return exitConfigError
+		}
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), *timeout)
+	defer cancel()
+	client := httpclient.New(httpclient.Options{Timeouts: httpclient.Timeouts{API: *timeout}}).API
+	regions, err := config.FetchRegions(ctx, client, *regionsURL)
+	if err != nil {
+		fmt.Fprintln(stderr, "Error importing the regions:", err)
+		return exitInfraError
+	}
+	servers, added := config.MergeServers(existing, config.RegionServers(regions, *includeInactive, *includeExperimental))
+	if servers == nil {
+		servers = []models.ObaServer{}
+	}
+
+	encoder := json.NewEncoder(stdout)
+	encoder.SetIndent("", "  ")
+	if err := encoder.Encode(servers); err != nil {
+		fmt.Fprintln(stderr, "Error writing the configuration:", err)
+		return exitInfraError
+	}
+	fmt.Fprintf(stderr, "Imported %d of %d regions; fill in the API keys and GTFS and GTFS-RT feeds of the new servers, or their oba_data_sources_url\n", added, len(regions))
+	return exitOK
+}
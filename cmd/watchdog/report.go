@@ -0,0 +1,79 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"time"
+
+	"watchdog.onebusaway.org/internal/history"
+)
+
+// runReport implements `watchdog report`, which writes the uptime and SLA report of the servers
+// over a period (see history.Store.Report) from the check history database of a watchdog, as
+// JSON or CSV. The database can be read while the watchdog writes to it. It returns the exit
+// code (see exit.go): exitConfigError on a usage error or a missing database, exitInfraError if
+// the report cannot be computed or written.
+func runReport(args []string, stdout, stderr io.Writer) int {
+	flags := flag.NewFlagSet("report", flag.ContinueOnError)
+	flags.SetOutput(stderr)
+	path := flags.String("history-db", "", "Check history database of the watchdog (its --history-db)")
+	from := flags.String("from", "", "Start of the period, an RFC 3339 time or a YYYY-MM-DD date (default 30 days before the end)")
+	to := flags.String("to", "", "End of the period, an RFC 3339 time or a YYYY-MM-DD date, included (default now)")
+	serverID := flags.Int("server-id", 0, "Server to report on (0 = every server with results)")
+	freshnessSLO := flags.Duration("freshness-slo", history.DefaultFreshnessSLO, "Largest age of the newest vehicle position of a GTFS-RT feed that meets the freshness SLO")
+	expirationDays := flags.Float64("expiration-days", history.DefaultExpirationDays, "Days before the end of a bundle under which it counts as an expiration incident")
+	format := flags.String("output", "json", "Output format (csv|json)")
+	if err := flags.Parse(args); err != nil {
+		return exitConfigError
+	}
+	if *format != "csv" && *format != "json" {
+		fmt.Fprintf(stderr, "invalid --output %q: expected csv or json\n", *format)
+		return exitConfigError
+	}
+	if *path == "" {
+		fmt.Fprintln(stderr, "usage: watchdog report --history-db <path> [--from <date>] [--to <date>] [--output csv|json]")
+		return exitConfigError
+	}
+	if _, err := os.Stat(*path); err != nil {
+		fmt.Fprintln(stderr, "Error reading the check history:", err)
+		return writeFailureSummary(stdout, *format, exitConfigError, err)
+	}
+
+	opts := history.ReportOptions{FreshnessSLO: *freshnessSLO, ExpirationDays: *expirationDays}
+	var err error
+	if opts.From, opts.To, err = history.ParseReportRange(*from, *to, time.Now()); err != nil {
+		fmt.Fprintln(stderr, "Invalid period:", err)
+		return writeFailureSummary(stdout, *format, exitConfigError, err)
+	}
+	if *serverID != 0 {
+		opts.ServerIDs = []int{*serverID}
+	}
+
+	store, err := history.Open(*path, 0)
+	if err != nil {
+		fmt.Fprintln(stderr, "Error reading the check history:", err)
+		return writeFailureSummary(stdout, *format, exitInfraError, err)
+	}
+	defer store.Close()
+	report, err := store.Report(context.Background(), opts)
+	if err != nil {
+		fmt.Fprintln(stderr, "Error computing the report:", err)
+		return writeFailureSummary(stdout, *format, exitInfraError, err)
+	}
+	if *format == "csv" {
+		err = history.WriteReportCSV(stdout, report)
+	} else {
+		encoder := json.NewEncoder(stdout)
+		encoder.SetIndent("", "  ")
+		err = encoder.Encode(report)
+	}
+	if err != nil {
+		fmt.Fprintln(stderr, "Error writing the report:", err)
+		return exitInfraError
+	}
+	return exitOK
+}
@@ -0,0 +1,138 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+	"time"
+
+	"watchdog.onebusaway.org/internal/config"
+	"watchdog.onebusaway.org/internal/httpclient"
+	"watchdog.onebusaway.org/internal/models"
+	"watchdog.onebusaway.org/internal/secrets"
+)
+
+// validationReport is the result of `watchdog validate-config`, as printed with --format json.
+type validationReport struct {
+	Valid  bool                     `json:"valid"`
+	Errors []config.ValidationError `json:"errors"`
+}
+
+// runValidateConfig implements `watchdog validate-config`, which checks a configuration file
+// before it is deployed: its JSON (including unknown fields), the environment variables of its
+// placeholders (see config.ExpandEnv), the required fields, the URL syntax and the uniqueness
+// of server ids (see config.ValidateServers), that it has no exec checks unless
+// --allow-exec-checks is given, and, with --probe, that its secret references
+// can be resolved (see config.ResolveSecrets) and its URLs can be reached (see
+// config.ProbeServers). It prints every problem found and returns the exit code (see exit.go):
+// exitCheckFailed if the file has problems, exitConfigError on a usage error or if the file
+// cannot be read, exitInfraError if the report cannot be written.
+func runValidateConfig(args []string, stdout, stderr io.Writer) int {
+	flags := flag.NewFlagSet("validate-config", flag.ContinueOnError)
+	flags.SetOutput(stderr)
+	configFile := flags.String("config-file", "", "Path to the configuration file to validate")
+	allowExecChecks := flags.Bool("allow-exec-checks", false, "Accept the exec_checks of servers, as the watchdog does with --allow-exec-checks")
+	probe := flags.Bool("probe", false, "Also resolve the secret references and request the URLs of every server to check that they can be reached")
+	format := flags.String("output", "text", "Output format (text|json)")
+	flags.StringVar(format, "format", "text", "Deprecated alias of --output")
+	timeout := flags.Duration("timeout", 10*time.Second, "Timeout of each request with --probe")
+	proxyURL := flags.String("proxy-url", "", "HTTP(S) proxy of the requests with --probe, as the watchdog does with --proxy-url")
+	var caCertFiles []string
+	flags.Func("ca-cert-files", "Comma-separated PEM files of CA certificates trusted by the requests with --probe, as the watchdog does with --ca-cert-files", func(s string) error {
+		caCertFiles = strings.Split(s, ",")
+		return nil
+	})
+	if err := flags.Parse(args); err != nil {
+		return exitConfigError
+	}
+	if *configFile == "" || flags.NArg() > 0 {
+		fmt.Fprintln(stderr, "usage: watchdog validate-config --config-file <file> [--probe] [--output text|json]")
+		return exitConfigError
+	}
+	if *format != "text" && *format != "json" {
+		fmt.Fprintf(stderr, "invalid --output %q: expected text or json\n", *format)
+		return exitConfigError
+	}
+	clientOptions, err := httpClientOptions(httpclient.Timeouts{API: *timeout}, *proxyURL, caCertFiles)
+	if err != nil {
+		fmt.Fprintln(stderr, err)
+		return exitConfigError
+	}
+
+	report := validationReport{Errors: []config.ValidationError{}}
+	// #nosec G304 - the file is chosen by the user running the command
+	data, err := os.ReadFile(*configFile)
+	if err != nil {
+		fmt.Fprintln(stderr, "Error reading the configuration:", err)
+		return writeFailureSummary(stdout, *format, exitConfigError, err)
+	}
+	servers, err := config.ParseServers(data)
+	if err != nil {
+		report.Errors = append(report.Errors, config.ValidationError{Index: -1, Message: err.Error()})
+	} else {
+		var unset config.ValidationErrors
+		if errors.As(config.ExpandEnv(servers), &unset) {
+			report.Errors = append(report.Errors, unset...)
+		}
+		report.Errors = append(report.Errors, config.ValidateServers(servers)...)
+		var execChecks config.ValidationErrors
+		if !*allowExecChecks && errors.As(config.DisallowExecChecks(servers), &execChecks) {
+			report.Errors = append(report.Errors, execChecks...)
+		}
+		if *probe {
+			// The requests go through the same transport as the watchdog's, with the proxy and
+			// CAs of the servers.
+			clients := httpclient.New(clientOptions)
+			clients.SetServers(func() []models.ObaServer { return servers })
+			client := clients.API
+			var unresolved config.ValidationErrors
+			if errors.As(config.ResolveSecrets(context.Background(), secrets.NewResolver(client), servers), &unresolved) {
+				report.Errors = append(report.Errors, unresolved...)
+			}
+			report.Errors = append(report.Errors, config.ProbeServers(context.Background(), client, servers)...)
+		}
+	}
+	report.Valid = len(report.Errors) == 0
+
+	if *format == "json" {
+		encoder := json.NewEncoder(stdout)
+		encoder.SetIndent("", "  ")
+		err = encoder.Encode(report)
+	} else {
+		err = writeValidationText(stdout, *configFile, len(servers), report)
+	}
+	if err != nil {
+		fmt.Fprintln(stderr, "Error writing the report:", err)
+		return exitInfraError
+	}
+	if !report.Valid {
+		return exitCheckFailed
+	}
+	return exitOK
+}
+
+// writeValidationText writes the problems of the configuration in file, one per line, then a
+// summary line.
+func writeValidationText(w io.Writer, file string, servers int, report validationReport) error {
+	for _, problem := range report.Errors {
+		if _, err := fmt.Fprintf(w, "%s: %s\n", file, problem.Error()); err != nil {
+			return err
+		}
+	}
+	var err error
+	if report.Valid {
+		_, err = fmt.Fprintf(w, "%s is valid (%d servers)\n", file, servers)
+	} else {
+		problems := "problems"
+		if len(report.Errors) == 1 {
+			problems = "problem"
+		}
+		_, err = fmt.Fprintf(w, "%s has %d %s\n", file, len(report.Errors), problems)
+	}
+	return err
+}
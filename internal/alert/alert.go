@@ -0,0 +1,367 @@
+package alert
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"strconv"
+	"sync"
+	"time"
+
+	"watchdog.onebusaway.org/internal/i18n"
+	"watchdog.onebusaway.org/internal/models"
+)
+
+// Status is the lifecycle state reported in a notification.
+type Status string
+
+const (
+	// StatusFiring means the check breached its threshold.
+	StatusFiring Status = "firing"
+	// StatusResolved means a previously firing check recovered.
+	StatusResolved Status = "resolved"
+)
+
+// Alert is a single notification sent to the notifiers.
+type Alert struct {
+	Server models.ObaServer
+	Check  string
+	// Locale is the language the alert is written in (see package i18n).
+	Locale string
+	Title  string
+	// Severity is the check's severity in paging systems ("critical", "error" or "warning").
+	Severity string
+	Status   Status
+	// Value is the last observed value and Threshold the threshold it was compared to.
+	Value     float64
+	Threshold float64
+	// Description explains Value, e.g. "3 consecutive failed pings".
+	Description string
+	// Repeat reports whether this is a reminder for a firing check that was already notified,
+	// rather than a change of state.
+	Repeat bool
+	// StartsAt is when the check started firing; At is when this notification was created.
+	StartsAt time.Time
+	At       time.Time
+	// Vantages are the results of the vantage points of a vantage-dependent check, the primary
+	// first, or nil without probe agents (see Manager.SetVantages).
+	Vantages []VantageResult
+}
+
+// Notifier delivers alerts to an external system (Slack, ...).
+type Notifier interface {
+	// Name identifies the notifier in logs and metrics.
+	Name() string
+	// Notify delivers a single alert.
+	Notify(ctx context.Context, alert Alert) error
+}
+
+// alertState is the state of one (server, check) pair.
+type alertState struct {
+	firing   bool
+	startsAt time.Time
+	// notifiedFiring reports whether the current firing episode was notified,
+	// so recoveries of silenced (e.g. cooling down) episodes are not announced either.
+	notifiedFiring bool
+	lastNotified   time.Time
+	// consecutiveFailures backs the checks counted by ObserveResult.
+	consecutiveFailures int
+}
+
+type stateKey struct {
+	serverID int
+	check    string
+}
+
+// Manager evaluates check observations against thresholds and notifies on state transitions.
+//
+// For each (server, check) pair it keeps whether the check is firing:
+//   - When an observation breaches the threshold, a firing notification is sent, unless one was
+//     sent for the same pair within the cooldown (so a flapping check does not spam the channel).
+//   - While the check keeps firing, the notification is repeated once per cooldown as a reminder.
+//   - When the check recovers, a resolved notification is sent if its firing was notified.
+//
+// Thresholds and cooldowns can be overridden per server and per check (models.AlertConfig).
+// Alerts are written in the server's `alerts.locale`, or in the manager's default locale.
+// A nil *Manager is valid and ignores all observations.
+type Manager struct {
+	notifiers []Notifier
+	cooldown  time.Duration
+	locale    string
+	logger    *slog.Logger
+	now       func() time.Time
+
+	mu     sync.Mutex
+	states map[stateKey]*alertState
+	// history records the values thresholds are suggested from.
+	history *valueHistory
+	// reducedService reports whether a planned service reduction of a server is in effect
+	// (see SetServiceReductions).
+	reducedService func(serverID int, at time.Time) bool
+	// vantages returns the latest results of the probe agents for a server (see SetVantages).
+	vantages func(serverID int, at time.Time) []VantageResult
+	// maintenance reports whether a server is in a maintenance window (see SetMaintenance).
+	maintenance func(server models.ObaServer, at time.Time) bool
+}
+
+// NewManager creates a Manager sending to the given notifiers, with a default cooldown
+// between two notifications for the same server and check, and a default locale.
+// Returns nil (alerting disabled) if there are no notifiers.
+func NewManager(notifiers []Notifier, cooldown time.Duration, locale string, logger *slog.Logger) *Manager {
+	if len(notifiers) == 0 {
+		return nil
+	}
+	return &Manager{
+		notifiers: notifiers,
+		cooldown:  cooldown,
+		locale:    i18n.Match(locale),
+		logger:    logger,
+		now:       time.Now,
+		states:    make(map[stateKey]*alertState),
+		history:   newValueHistory(),
+	}
+}
+
+// Observe records the latest value of a check for a server and sends notifications
+// if the check started firing, is still firing past its cooldown, or recovered.
+func (m *Manager) Observe(server models.ObaServer, check string, value float64) {
+	if m == nil {
+		return
+	}
+	definition, ok := checks[check]
+	if !ok {
+		m.logger.Error("Unknown alert check", "check", check)
+		return
+	}
+	if definition.ServiceDependent && m.serviceReduced(server.ID) {
+		m.logger.Debug("Ignoring alert check during a planned service reduction", "server_id", server.ID, "check", check)
+		return
+	}
+
+	override := server.Alerts.Check(check)
+	threshold := definition.DefaultThreshold
+	if override.Threshold != nil {
+		threshold = *override.Threshold
+	}
+	cooldown := m.cooldown
+	if override.Cooldown != nil {
+		cooldown = override.Cooldown.Std()
+	}
+	firing := definition.Firing(value, threshold)
+	var vantages []VantageResult
+	if definition.VantageDependent {
+		vantages = m.vantageResults(server.ID, firing)
+		if firing && !downQuorum(vantages) {
+			m.logger.Info("Ignoring alert check failing from a minority of vantage points", "server_id", server.ID, "check", check, "vantages", formatVantages(m.locale, vantages))
+			AlertsSuppressedCounter.WithLabelValues(check, "vantage_quorum").Inc()
+			return
+		}
+	}
+	if definition.SuggestPercentile > 0 {
+		m.history.record(server.ID, check, check, "", threshold, definition.SuggestPercentile, value, m.now())
+	}
+
+	serverLabel := strconv.Itoa(server.ID)
+	if firing {
+		AlertFiringGauge.WithLabelValues(serverLabel, check).Set(1)
+	} else {
+		AlertFiringGauge.WithLabelValues(serverLabel, check).Set(0)
+	}
+
+	m.evaluate(observation{
+		server:    server,
+		check:     check,
+		key:       check,
+		firing:    firing,
+		value:     value,
+		threshold: threshold,
+		cooldown:  cooldown,
+		muted:     override.Disabled,
+		severity:  definition.Severity,
+		title:     definition.title,
+		describe:  func(locale string) string { return definition.describe(locale, value) },
+		vantages:  vantages,
+	})
+}
+
+// SetServiceReductions sets how the manager learns about the planned service reductions of
+// servers. During a reduction, the observations of service-dependent checks and rules (such as
+// CheckVehiclesDropped) are ignored: they neither fire nor resolve, and their values are not
+// used for threshold suggestions.
+func (m *Manager) SetServiceReductions(reduced func(serverID int, at time.Time) bool) {
+	if m == nil {
+		return
+	}
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.reducedService = reduced
+}
+
+// serviceReduced reports whether a planned service reduction of a server is in effect.
+func (m *Manager) serviceReduced(serverID int) bool {
+	m.mu.Lock()
+	reduced := m.reducedService
+	m.mu.Unlock()
+	return reduced != nil && reduced(serverID, m.now())
+}
+
+// SetMaintenance sets how the manager learns about the maintenance windows of servers. During a
+// window, the checks and rules of the server keep their state, but no notification is sent:
+// a check that started firing during the window is notified once the window ends, if it is
+// still firing then. Suppressed notifications are counted in watchdog_alerts_suppressed_total
+// with reason="maintenance".
+func (m *Manager) SetMaintenance(inMaintenance func(server models.ObaServer, at time.Time) bool) {
+	if m == nil {
+		return
+	}
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.maintenance = inMaintenance
+}
+
+// inMaintenance reports whether a server is in a maintenance window.
+func (m *Manager) inMaintenance(server models.ObaServer) bool {
+	m.mu.Lock()
+	inMaintenance := m.maintenance
+	m.mu.Unlock()
+	return inMaintenance != nil && inMaintenance(server, m.now())
+}
+
+// observation is an evaluation of a check (or of a rule, see observeRule) for a server.
+type observation struct {
+	server models.ObaServer
+	check  string
+	// key identifies the state of the observation, e.g. the check, or a rule and a series.
+	key       string
+	firing    bool
+	value     float64
+	threshold float64
+	cooldown  time.Duration
+	// muted reports whether the check is disabled for the server.
+	muted    bool
+	severity string
+	// title and describe write the title and description of notifications in a locale.
+	title    func(locale string) string
+	describe func(locale string) string
+	// vantages are the results of the vantage points of the check, if it depends on them.
+	vantages []VantageResult
+}
+
+// evaluate updates the state of an observation and sends a notification if the check started
+// firing, is still firing past its cooldown, or recovered.
+func (m *Manager) evaluate(o observation) {
+	server, firing, cooldown := o.server, o.firing, o.cooldown
+	maintenance := m.inMaintenance(server)
+	m.mu.Lock()
+	state := m.state(server.ID, o.key)
+	now := m.now()
+	var status Status
+	switch {
+	case firing && !state.firing:
+		state.firing, state.startsAt, state.notifiedFiring = true, now, false
+		if state.lastNotified.IsZero() || now.Sub(state.lastNotified) >= cooldown {
+			status = StatusFiring
+		}
+	case firing && state.firing:
+		if now.Sub(state.lastNotified) >= cooldown {
+			status = StatusFiring
+		}
+	case !firing && state.firing:
+		state.firing = false
+		if state.notifiedFiring {
+			status = StatusResolved
+		}
+	}
+	muted := server.Alerts != nil && server.Alerts.Disabled || o.muted
+	if status == "" || muted {
+		m.mu.Unlock()
+		return
+	}
+	if maintenance {
+		m.mu.Unlock()
+		AlertsSuppressedCounter.WithLabelValues(o.check, "maintenance").Inc()
+		m.logger.Info("Suppressing alert notification during a maintenance window", "server_id", server.ID, "check", o.check, "status", status)
+		return
+	}
+	repeat := status == StatusFiring && state.notifiedFiring
+	if status == StatusFiring {
+		state.notifiedFiring = true
+	}
+	state.lastNotified = now
+	locale := m.locale
+	if server.Alerts != nil && server.Alerts.Locale != "" {
+		locale = i18n.Match(server.Alerts.Locale)
+	}
+	alert := Alert{
+		Server:      server,
+		Check:       o.check,
+		Locale:      locale,
+		Title:       o.title(locale),
+		Severity:    o.severity,
+		Status:      status,
+		Value:       o.value,
+		Threshold:   o.threshold,
+		Description: o.describe(locale),
+		Repeat:      repeat,
+		StartsAt:    state.startsAt,
+		At:          now,
+		Vantages:    o.vantages,
+	}
+	m.mu.Unlock()
+
+	m.notify(alert)
+}
+
+// ObserveResult records the outcome of one run of a check that alerts on consecutive
+// failures (such as CheckAPIDown): the number of failures in a row is counted per server
+// and passed to Observe.
+func (m *Manager) ObserveResult(server models.ObaServer, check string, ok bool) {
+	if m == nil {
+		return
+	}
+	m.mu.Lock()
+	state := m.state(server.ID, check)
+	if ok {
+		state.consecutiveFailures = 0
+	} else {
+		state.consecutiveFailures++
+	}
+	failures := state.consecutiveFailures
+	m.mu.Unlock()
+
+	m.Observe(server, check, float64(failures))
+}
+
+// state returns the state of a (server, check) pair, creating it if needed. m.mu must be held.
+func (m *Manager) state(serverID int, check string) *alertState {
+	key := stateKey{serverID: serverID, check: check}
+	state, ok := m.states[key]
+	if !ok {
+		state = &alertState{}
+		m.states[key] = state
+	}
+	return state
+}
+
+// notify sends the alert to every notifier. A failing notifier does not prevent the others
+// from being notified; failures are logged and counted.
+func (m *Manager) notify(alert Alert) {
+	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+	defer cancel()
+
+	for _, notifier := range m.notifiers {
+		err := notifier.Notify(ctx, alert)
+		if err != nil {
+			AlertNotificationsCounter.WithLabelValues(notifier.Name(), string(alert.Status), "failure").Inc()
+			m.logger.Error("Failed to send alert notification", "notifier", notifier.Name(), "server_id", alert.Server.ID, "check", alert.Check, "status", alert.Status, "error", err)
+			continue
+		}
+		AlertNotificationsCounter.WithLabelValues(notifier.Name(), string(alert.Status), "success").Inc()
+		m.logger.Info("Sent alert notification", "notifier", notifier.Name(), "server_id", alert.Server.ID, "check", alert.Check, "status", alert.Status)
+	}
+}
+
+// String returns a one-line summary of the alert, used by text-based notifiers and logs.
+func (a Alert) String() string {
+	return fmt.Sprintf("[%s] %s: %s (server %d): %s", a.Status, a.Title, a.Server.Name, a.Server.ID, a.Description)
+}
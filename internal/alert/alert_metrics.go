@@ -0,0 +1,23 @@
+package alert
+
+import (
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promauto"
+)
+
+var (
+	AlertFiringGauge = promauto.NewGaugeVec(prometheus.GaugeOpts{
+		Name: "watchdog_alert_firing",
+		Help: "Whether an alert check is currently breaching its threshold for a server (1 = firing, 0 = ok)",
+	}, []string{"server_id", "check"})
+
+	AlertNotificationsCounter = promauto.NewCounterVec(prometheus.CounterOpts{
+		Name: "watchdog_alert_notifications_total",
+		Help: "Total number of alert notifications sent, by notifier, alert status (firing, resolved) and result (success, failure)",
+	}, []string{"notifier", "status", "result"})
+
+	AlertsSuppressedCounter = promauto.NewCounterVec(prometheus.CounterOpts{
+		Name: "watchdog_alerts_suppressed_total",
+		Help: "Alert observations or notifications of a check ignored, by check and reason (vantage_quorum: firing observation failing from a minority of the vantage points, maintenance: notification of a server in a maintenance window)",
+	}, []string{"check", "reason"})
+)
@@ -0,0 +1,262 @@
+package alert
+
+import (
+	"context"
+	"encoding/json"
+	"io"
+	"log/slog"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	"watchdog.onebusaway.org/internal/models"
+)
+
+type fakeNotifier struct {
+	alerts []Alert
+}
+
+func (n *fakeNotifier) Name() string { return "fake" }
+
+func (n *fakeNotifier) Notify(_ context.Context, alert Alert) error {
+	n.alerts = append(n.alerts, alert)
+	return nil
+}
+
+func newTestManager(t *testing.T, cooldown time.Duration) (*Manager, *fakeNotifier, *time.Time) {
+	t.Helper()
+	notifier := &fakeNotifier{}
+	m := NewManager([]Notifier{notifier}, cooldown, "en", slog.New(slog.NewTextHandler(io.Discard, nil)))
+	now := time.Date(2025, 1, 1, 12, 0, 0, 0, time.UTC)
+	m.now = func() time.Time { return now }
+	return m, notifier, &now
+}
+
+func TestNewManagerWithoutNotifiers(t *testing.T) {
+	m := NewManager(nil, time.Hour, "en", slog.New(slog.NewTextHandler(io.Discard, nil)))
+	if m != nil {
+		t.Fatal("expected a nil manager without notifiers")
+	}
+	// A nil manager ignores observations.
+	m.Observe(models.ObaServer{ID: 1}, CheckAPIDown, 10)
+	m.ObserveResult(models.ObaServer{ID: 1}, CheckAPIDown, false)
+}
+
+func TestManagerFiringAndResolved(t *testing.T) {
+	m, notifier, now := newTestManager(t, time.Hour)
+	server := models.ObaServer{ID: 1, Name: "Test Server"}
+
+	m.ObserveResult(server, CheckAPIDown, false)
+	if len(notifier.alerts) != 0 {
+		t.Fatalf("expected no alert below the threshold, got %d", len(notifier.alerts))
+	}
+
+	m.ObserveResult(server, CheckAPIDown, false)
+	if len(notifier.alerts) != 1 || notifier.alerts[0].Status != StatusFiring {
+		t.Fatalf("expected a firing alert, got %+v", notifier.alerts)
+	}
+	if notifier.alerts[0].Value != 2 || notifier.alerts[0].Description != "2 consecutive failed pings" {
+		t.Errorf("unexpected alert %+v", notifier.alerts[0])
+	}
+
+	// Still firing within the cooldown: no reminder.
+	*now = now.Add(30 * time.Minute)
+	m.ObserveResult(server, CheckAPIDown, false)
+	if len(notifier.alerts) != 1 {
+		t.Fatalf("expected no reminder within the cooldown, got %d alerts", len(notifier.alerts))
+	}
+
+	// Still firing past the cooldown: reminder.
+	*now = now.Add(31 * time.Minute)
+	m.ObserveResult(server, CheckAPIDown, false)
+	if len(notifier.alerts) != 2 || notifier.alerts[1].Status != StatusFiring {
+		t.Fatalf("expected a reminder, got %+v", notifier.alerts)
+	}
+	if notifier.alerts[0].Repeat || !notifier.alerts[1].Repeat {
+		t.Errorf("expected only the reminder to be a repeat, got %v and %v", notifier.alerts[0].Repeat, notifier.alerts[1].Repeat)
+	}
+
+	m.ObserveResult(server, CheckAPIDown, true)
+	if len(notifier.alerts) != 3 || notifier.alerts[2].Status != StatusResolved {
+		t.Fatalf("expected a resolved alert, got %+v", notifier.alerts)
+	}
+	if !notifier.alerts[2].StartsAt.Equal(notifier.alerts[0].At) {
+		t.Errorf("expected the resolved alert to start when the check started firing, got %v", notifier.alerts[2].StartsAt)
+	}
+}
+
+func TestManagerFlappingRespectsCooldown(t *testing.T) {
+	m, notifier, now := newTestManager(t, time.Hour)
+	server := models.ObaServer{ID: 1}
+
+	m.Observe(server, CheckBundleExpiration, 3)
+	*now = now.Add(time.Minute)
+	m.Observe(server, CheckBundleExpiration, 10)
+	*now = now.Add(time.Minute)
+	// Fires again within the cooldown: not notified, and neither is its recovery.
+	m.Observe(server, CheckBundleExpiration, 3)
+	*now = now.Add(time.Minute)
+	m.Observe(server, CheckBundleExpiration, 10)
+
+	if len(notifier.alerts) != 2 {
+		t.Fatalf("expected one firing and one resolved alert, got %+v", notifier.alerts)
+	}
+	if notifier.alerts[0].Status != StatusFiring || notifier.alerts[1].Status != StatusResolved {
+		t.Errorf("unexpected statuses %s, %s", notifier.alerts[0].Status, notifier.alerts[1].Status)
+	}
+}
+
+func TestManagerServerOverrides(t *testing.T) {
+	m, notifier, now := newTestManager(t, time.Hour)
+
+	var config models.AlertConfig
+	err := json.Unmarshal([]byte(`{
+		"checks": {
+			"bundle_download": {"threshold": 5, "cooldown": "10m"},
+			"bundle_expiration": {"disabled": true}
+		}
+	}`), &config)
+	if err != nil {
+		t.Fatalf("failed to parse alert config: %v", err)
+	}
+	server := models.ObaServer{ID: 1, Alerts: &config}
+
+	m.Observe(server, CheckBundleDownload, 3)
+	if len(notifier.alerts) != 0 {
+		t.Fatalf("expected the threshold override to apply, got %+v", notifier.alerts)
+	}
+	m.Observe(server, CheckBundleDownload, 5)
+	*now = now.Add(11 * time.Minute)
+	m.Observe(server, CheckBundleDownload, 6)
+	if len(notifier.alerts) != 2 || notifier.alerts[0].Threshold != 5 {
+		t.Fatalf("expected a firing alert and a reminder after the cooldown override, got %+v", notifier.alerts)
+	}
+
+	m.Observe(server, CheckBundleExpiration, 1)
+	if len(notifier.alerts) != 2 {
+		t.Errorf("expected the disabled check to be muted, got %+v", notifier.alerts)
+	}
+
+	muted := models.ObaServer{ID: 2, Alerts: &models.AlertConfig{Disabled: true}}
+	m.Observe(muted, CheckBundleExpiration, 1)
+	if len(notifier.alerts) != 2 {
+		t.Errorf("expected the disabled server to be muted, got %+v", notifier.alerts)
+	}
+}
+
+func TestManagerLocale(t *testing.T) {
+	m, notifier, _ := newTestManager(t, time.Hour)
+
+	m.Observe(models.ObaServer{ID: 1}, CheckBundleExpiration, 3)
+	m.Observe(models.ObaServer{ID: 2, Alerts: &models.AlertConfig{Locale: "fr-CA"}}, CheckBundleExpiration, 3)
+
+	if len(notifier.alerts) != 2 {
+		t.Fatalf("expected 2 alerts, got %d", len(notifier.alerts))
+	}
+	if got := notifier.alerts[0]; got.Locale != "en" || got.Title != "GTFS bundle expiring soon" {
+		t.Errorf("expected an English alert, got %+v", got)
+	}
+	if got := notifier.alerts[1]; got.Locale != "fr" || got.Title != "Le bundle GTFS expire bientôt" ||
+		got.Description != "première date de fin de service dans 3 jours" {
+		t.Errorf("expected a French alert, got %+v", got)
+	}
+}
+
+func TestManagerIgnoresServiceDependentChecksDuringServiceReductions(t *testing.T) {
+	m, notifier, now := newTestManager(t, time.Hour)
+	server := models.ObaServer{ID: 1, Name: "Test Server"}
+	reductionEnd := now.Add(2 * time.Hour)
+	m.SetServiceReductions(func(serverID int, at time.Time) bool {
+		return serverID == server.ID && at.Before(reductionEnd)
+	})
+
+	m.Observe(server, CheckVehiclesDropped, 0.1)
+	if len(notifier.alerts) != 0 {
+		t.Fatalf("expected no alert during a service reduction, got %+v", notifier.alerts)
+	}
+	// Other checks still alert.
+	m.Observe(server, CheckAPIDown, 2)
+	if len(notifier.alerts) != 1 || notifier.alerts[0].Check != CheckAPIDown {
+		t.Fatalf("expected an api_down alert, got %+v", notifier.alerts)
+	}
+
+	*now = reductionEnd
+	m.Observe(server, CheckVehiclesDropped, 0.1)
+	if len(notifier.alerts) != 2 || notifier.alerts[1].Check != CheckVehiclesDropped {
+		t.Fatalf("expected a vehicles_dropped alert after the service reduction, got %+v", notifier.alerts)
+	}
+}
+
+func TestManagerSuppressesNotificationsDuringMaintenance(t *testing.T) {
+	m, notifier, now := newTestManager(t, time.Hour)
+	server := models.ObaServer{ID: 1, Name: "Test Server"}
+	maintenanceEnd := now.Add(30 * time.Minute)
+	m.SetMaintenance(func(s models.ObaServer, at time.Time) bool {
+		return s.ID == server.ID && at.Before(maintenanceEnd)
+	})
+
+	before := testutil.ToFloat64(AlertsSuppressedCounter.WithLabelValues(CheckAPIDown, "maintenance"))
+	m.Observe(server, CheckAPIDown, 2)
+	if len(notifier.alerts) != 0 {
+		t.Fatalf("expected no alert during maintenance, got %+v", notifier.alerts)
+	}
+	if got := testutil.ToFloat64(AlertsSuppressedCounter.WithLabelValues(CheckAPIDown, "maintenance")) - before; got != 1 {
+		t.Errorf("expected 1 suppressed notification, got %v", got)
+	}
+	// Other servers still alert.
+	m.Observe(models.ObaServer{ID: 2}, CheckAPIDown, 2)
+	if len(notifier.alerts) != 1 || notifier.alerts[0].Server.ID != 2 {
+		t.Fatalf("expected an alert for server 2, got %+v", notifier.alerts)
+	}
+
+	// Still firing once the window ends: notified then.
+	*now = maintenanceEnd
+	m.Observe(server, CheckAPIDown, 3)
+	if len(notifier.alerts) != 2 || notifier.alerts[1].Server.ID != server.ID || notifier.alerts[1].Status != StatusFiring {
+		t.Fatalf("expected a firing alert after maintenance, got %+v", notifier.alerts)
+	}
+	if !notifier.alerts[1].StartsAt.Equal(maintenanceEnd.Add(-30 * time.Minute)) {
+		t.Errorf("expected the alert to start when the check started firing, got %v", notifier.alerts[1].StartsAt)
+	}
+}
+
+func TestManagerVantageQuorum(t *testing.T) {
+	m, notifier, _ := newTestManager(t, time.Hour)
+	server := models.ObaServer{ID: 1, Name: "Test"}
+	remote := []VantageResult{{Vantage: "eu-west", OK: true}, {Vantage: "us-east", OK: true}}
+	m.SetVantages(func(int, time.Time) []VantageResult { return remote })
+
+	// Only the primary fails: no majority.
+	m.ObserveResult(server, CheckAPIDown, false)
+	m.ObserveResult(server, CheckAPIDown, false)
+	if len(notifier.alerts) != 0 {
+		t.Fatalf("expected no alert without a majority of vantage points, got %+v", notifier.alerts)
+	}
+	if got := testutil.ToFloat64(AlertsSuppressedCounter.WithLabelValues(CheckAPIDown, "vantage_quorum")); got < 1 {
+		t.Errorf("expected the ignored observation to be counted, got %v", got)
+	}
+
+	remote[1] = VantageResult{Vantage: "us-east", OK: false, Error: "timeout"}
+	m.ObserveResult(server, CheckAPIDown, false)
+	if len(notifier.alerts) != 1 {
+		t.Fatalf("expected an alert once a majority agrees, got %+v", notifier.alerts)
+	}
+	want := []VantageResult{{Vantage: PrimaryVantage}, {Vantage: "eu-west", OK: true}, {Vantage: "us-east", Error: "timeout"}}
+	if !reflect.DeepEqual(notifier.alerts[0].Vantages, want) {
+		t.Errorf("expected the alert to list the vantage points, got %+v", notifier.alerts[0].Vantages)
+	}
+	if message := newSlackMessage(notifier.alerts[0]); !strings.Contains(message.Attachments[0].Fields[3].Value, "us-east: down (timeout)") {
+		t.Errorf("expected the vantage points in the Slack message, got %+v", message.Attachments[0].Fields)
+	}
+
+	// Without agent results the primary decides alone.
+	other := models.ObaServer{ID: 2, Name: "Other"}
+	m.SetVantages(func(int, time.Time) []VantageResult { return nil })
+	m.ObserveResult(other, CheckAPIDown, false)
+	m.ObserveResult(other, CheckAPIDown, false)
+	if len(notifier.alerts) != 2 || notifier.alerts[1].Server.ID != other.ID || notifier.alerts[1].Vantages != nil {
+		t.Errorf("expected the primary alone to fire, got %+v", notifier.alerts)
+	}
+}
@@ -0,0 +1,161 @@
+package alert
+
+import (
+	"strconv"
+	"time"
+
+	"watchdog.onebusaway.org/internal/i18n"
+)
+
+// Names of the checks that can raise alerts. They are used as keys in the per-server
+// `alerts.checks` config and as the `check` label of the alert metrics.
+const (
+	// CheckAPIDown fires when the OBA API fails to answer a number of consecutive pings.
+	CheckAPIDown = "api_down"
+	// CheckBundleDownload fires when the GTFS static bundle fails to download a number of consecutive times.
+	CheckBundleDownload = "bundle_download"
+	// CheckBundleExpiration fires when the bundle's earliest service end date is less than a number of days away.
+	CheckBundleExpiration = "bundle_expiration"
+	// CheckVehiclesDropped fires when the OBA API returns a smaller share of the vehicles of the GTFS-RT feed than a ratio.
+	CheckVehiclesDropped = "vehicles_dropped"
+	// CheckBundleSizeDrop fires when a new GTFS static bundle is smaller than a ratio of the median size of the previous ones.
+	CheckBundleSizeDrop = "bundle_size_drop"
+	// CheckURLDown fires when the uptime checks of an entry of type "url" fail a number of consecutive times.
+	CheckURLDown = "url_down"
+)
+
+// checkDefinition describes how an observed value of a check is compared to its threshold.
+type checkDefinition struct {
+	// TitleKey is the i18n key of the human readable summary used in notifications.
+	TitleKey string
+	// DefaultThreshold is used unless a server overrides it.
+	DefaultThreshold float64
+	// Firing reports whether value breaches threshold.
+	Firing func(value, threshold float64) bool
+	// DescriptionKey is the i18n key of the message explaining an observed value,
+	// e.g. "3 consecutive failed pings". The message is formatted with the value.
+	DescriptionKey string
+	// Severity is the severity of the alert in paging systems: "critical", "error" or "warning".
+	Severity string
+	// AlertName is the name of the equivalent Prometheus alerting rule.
+	AlertName string
+	// Rule returns the PromQL expression equivalent to the check for the given series selector
+	// and threshold, and how long it must hold before firing, given the metrics collection interval.
+	Rule func(selector string, threshold float64, interval time.Duration) (expr string, forDuration time.Duration)
+	// RuleDescription is the description annotation of the Prometheus rule ($value is the expression value).
+	RuleDescription string
+	// SuggestPercentile is the percentile of the observed values suggested as threshold (see
+	// Manager.SuggestThresholds), or 0 if the values do not tell what the threshold should be,
+	// e.g. for counts of consecutive failures that are 0 most of the time.
+	SuggestPercentile float64
+	// ServiceDependent reports whether the values of the check depend on the service running,
+	// so that it does not alert during the planned service reductions of a server.
+	ServiceDependent bool
+	// VantageDependent reports whether the check is a ping that the probe agents also make, so
+	// that it only fires when a majority of the vantage points agree (see Manager.SetVantages).
+	VantageDependent bool
+}
+
+func atLeast(value, threshold float64) bool { return value >= threshold }
+func below(value, threshold float64) bool   { return value < threshold }
+
+// checks lists every check that can raise alerts.
+var checks = map[string]checkDefinition{
+	CheckAPIDown: {
+		TitleKey:         "alert.api_down.title",
+		DefaultThreshold: 2,
+		Firing:           atLeast,
+		DescriptionKey:   "alert.api_down.description",
+		Severity:         "critical",
+		AlertName:        "WatchdogAPIDown",
+		// oba_api_status is 0 after each failed ping, so N consecutive failures are N-1
+		// collection intervals of oba_api_status == 0.
+		Rule: func(selector string, threshold float64, interval time.Duration) (string, time.Duration) {
+			pings := max(threshold-1, 0)
+			return "oba_api_status" + selector + " == 0", time.Duration(pings * float64(interval))
+		},
+		RuleDescription:  "The OBA API of server {{ $labels.server_id }} is not answering pings.",
+		VantageDependent: true,
+	},
+	CheckBundleDownload: {
+		TitleKey:         "alert.bundle_download.title",
+		DefaultThreshold: 3,
+		Firing:           atLeast,
+		DescriptionKey:   "alert.bundle_download.description",
+		Severity:         "error",
+		AlertName:        "WatchdogBundleDownloadFailing",
+		Rule: func(selector string, threshold float64, _ time.Duration) (string, time.Duration) {
+			return "gtfs_bundle_download_consecutive_failures" + selector + " >= " + formatThreshold(threshold), 0
+		},
+		RuleDescription: "The GTFS bundle of server {{ $labels.server_id }} failed to download {{ $value }} times in a row.",
+	},
+	CheckBundleExpiration: {
+		TitleKey:         "alert.bundle_expiration.title",
+		DefaultThreshold: 7,
+		Firing:           below,
+		DescriptionKey:   "alert.bundle_expiration.description",
+		Severity:         "warning",
+		AlertName:        "WatchdogBundleExpiringSoon",
+		Rule: func(selector string, threshold float64, _ time.Duration) (string, time.Duration) {
+			return "gtfs_bundle_days_until_earliest_expiration" + selector + " < " + formatThreshold(threshold), 0
+		},
+		RuleDescription: "The earliest service end date of the GTFS bundle of server {{ $labels.server_id }} is in {{ $value }} days.",
+	},
+	CheckBundleSizeDrop: {
+		TitleKey:         "alert.bundle_size_drop.title",
+		DefaultThreshold: 0.5,
+		Firing:           below,
+		DescriptionKey:   "alert.bundle_size_drop.description",
+		Severity:         "error",
+		AlertName:        "WatchdogBundleSizeDrop",
+		Rule: func(selector string, threshold float64, _ time.Duration) (string, time.Duration) {
+			return "gtfs_bundle_size_ratio" + selector + " < " + formatThreshold(threshold), 0
+		},
+		RuleDescription: "The GTFS bundle of server {{ $labels.server_id }} is {{ $value }} of the median size of its previous bundles, likely a truncated publish.",
+	},
+	CheckVehiclesDropped: {
+		TitleKey:         "alert.vehicles_dropped.title",
+		DefaultThreshold: 0.8,
+		Firing:           below,
+		DescriptionKey:   "alert.vehicles_dropped.description",
+		Severity:         "warning",
+		AlertName:        "WatchdogVehiclesDropped",
+		Rule: func(selector string, threshold float64, _ time.Duration) (string, time.Duration) {
+			return "vehicle_count_match_ratio" + selector + " < " + formatThreshold(threshold), 0
+		},
+		RuleDescription:   "The OBA API of server {{ $labels.server_id }} returns {{ $value }} of the vehicles of the GTFS-RT feed.",
+		SuggestPercentile: 1,
+		ServiceDependent:  true,
+	},
+	CheckURLDown: {
+		TitleKey:         "alert.url_down.title",
+		DefaultThreshold: 2,
+		Firing:           atLeast,
+		DescriptionKey:   "alert.url_down.description",
+		Severity:         "critical",
+		AlertName:        "WatchdogURLDown",
+		// Like oba_api_status, url_check_up is 0 after each failed run of the checks.
+		Rule: func(selector string, threshold float64, interval time.Duration) (string, time.Duration) {
+			runs := max(threshold-1, 0)
+			return "url_check_up" + selector + " == 0", time.Duration(runs * float64(interval))
+		},
+		RuleDescription: "The service {{ $labels.url }} (entry {{ $labels.server_id }}) is failing its uptime checks.",
+	},
+}
+
+// checkNames lists the checks in a stable order.
+var checkNames = []string{CheckAPIDown, CheckBundleDownload, CheckBundleExpiration, CheckBundleSizeDrop, CheckVehiclesDropped, CheckURLDown}
+
+// title returns the summary of the check in the given locale.
+func (d checkDefinition) title(locale string) string {
+	return i18n.T(locale, d.TitleKey)
+}
+
+// describe explains an observed value of the check in the given locale.
+func (d checkDefinition) describe(locale string, value float64) string {
+	return i18n.T(locale, d.DescriptionKey, value)
+}
+
+func formatThreshold(threshold float64) string {
+	return strconv.FormatFloat(threshold, 'f', -1, 64)
+}
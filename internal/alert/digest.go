@@ -0,0 +1,201 @@
+package alert
+
+import (
+	"bytes"
+	"cmp"
+	"context"
+	"encoding/json"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"slices"
+	"strings"
+	"sync"
+	"time"
+
+	"watchdog.onebusaway.org/internal/i18n"
+	"watchdog.onebusaway.org/internal/models"
+	"watchdog.onebusaway.org/internal/scheduler"
+)
+
+// Finding is a data-quality problem found in the data of a server during a digest period.
+type Finding struct {
+	Check string
+	// Message is the error of the last failed run of the check.
+	Message string
+	// Count is the number of failed runs, FirstSeen and LastSeen the first and last of them.
+	Count     int
+	FirstSeen time.Time
+	LastSeen  time.Time
+	// Resolved reports whether the check passed after its last failure.
+	Resolved bool
+}
+
+// digestEntry holds the findings of a server since the last digest.
+type digestEntry struct {
+	server   models.ObaServer
+	since    time.Time
+	findings map[string]*Finding
+}
+
+// AgencyDigest batches the data-quality findings of each server and sends them to the agency
+// contact of the server (models.AgencyContact) once per digest period, so that agencies hear
+// about problems in their data without being notified on every collection cycle.
+//
+// A digest lists each check that failed during the period once, with its last error, how
+// many runs failed and whether it passed since. Servers without findings get no digest.
+// Digests are written in the server's `alerts.locale`, or in the default locale.
+// A nil *AgencyDigest is valid and ignores all findings.
+type AgencyDigest struct {
+	client *http.Client
+	// mailer sends digests by email; nil leaves email contacts out.
+	mailer *Mailer
+	locale string
+	logger *slog.Logger
+	now    func() time.Time
+
+	mu      sync.Mutex
+	pending map[int]*digestEntry
+}
+
+// NewAgencyDigest creates an AgencyDigest posting to Slack with client and sending emails
+// with mailer (nil = no emails), in a default locale.
+func NewAgencyDigest(client *http.Client, mailer *Mailer, locale string, logger *slog.Logger) *AgencyDigest {
+	return &AgencyDigest{
+		client:  client,
+		mailer:  mailer,
+		locale:  i18n.Match(locale),
+		logger:  logger,
+		now:     time.Now,
+		pending: make(map[int]*digestEntry),
+	}
+}
+
+// Record adds the result of a run of a data-quality check to the next digest of the server:
+// a failure is a finding, and a success marks the finding of the check as resolved.
+// Results of servers without an agency contact are ignored.
+func (d *AgencyDigest) Record(server models.ObaServer, check string, err error) {
+	if d == nil || server.AgencyContact == nil {
+		return
+	}
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	now := d.now()
+	entry := d.pending[server.ID]
+	if err == nil {
+		if entry != nil && entry.findings[check] != nil {
+			entry.findings[check].Resolved = true
+		}
+		return
+	}
+	if entry == nil {
+		entry = &digestEntry{since: now, findings: make(map[string]*Finding)}
+		d.pending[server.ID] = entry
+	}
+	entry.server = server
+	finding := entry.findings[check]
+	if finding == nil {
+		finding = &Finding{Check: check, FirstSeen: now}
+		entry.findings[check] = finding
+	}
+	finding.Message = err.Error()
+	finding.Count++
+	finding.LastSeen = now
+	finding.Resolved = false
+}
+
+// Flush sends the pending digests and starts a new period. Digests that fail to be delivered
+// are logged and counted, not retried: the findings that persist are in the next one.
+func (d *AgencyDigest) Flush(ctx context.Context) {
+	if d == nil {
+		return
+	}
+	d.mu.Lock()
+	pending := d.pending
+	d.pending = make(map[int]*digestEntry)
+	d.mu.Unlock()
+
+	for _, entry := range pending {
+		findings := make([]Finding, 0, len(entry.findings))
+		for _, finding := range entry.findings {
+			findings = append(findings, *finding)
+		}
+		slices.SortFunc(findings, func(a, b Finding) int { return cmp.Compare(a.Check, b.Check) })
+		d.send(ctx, entry.server, entry.since, findings)
+	}
+}
+
+// Run flushes the digests on schedule until ctx is canceled.
+func (d *AgencyDigest) Run(ctx context.Context, schedule scheduler.Schedule) {
+	if d == nil {
+		return
+	}
+	scheduler.Run(ctx, "alert_digest", schedule, func() { d.Flush(ctx) })
+}
+
+// send delivers the digest of a server to its agency contact.
+func (d *AgencyDigest) send(ctx context.Context, server models.ObaServer, since time.Time, findings []Finding) {
+	locale := d.locale
+	if server.Alerts != nil && server.Alerts.Locale != "" {
+		locale = i18n.Match(server.Alerts.Locale)
+	}
+	subject, body := formatDigest(locale, server, since, findings)
+	contact := server.AgencyContact
+
+	deliver := func(notifier string, fn func() error) {
+		if err := fn(); err != nil {
+			AlertNotificationsCounter.WithLabelValues(notifier, "digest", "failure").Inc()
+			d.logger.Error("Failed to send agency digest", "notifier", notifier, "server_id", server.ID, "findings", len(findings), "error", err)
+			return
+		}
+		AlertNotificationsCounter.WithLabelValues(notifier, "digest", "success").Inc()
+		d.logger.Info("Sent agency digest", "notifier", notifier, "server_id", server.ID, "findings", len(findings))
+	}
+	if contact.SlackWebhookURL != "" {
+		deliver("slack", func() error {
+			return d.postSlack(ctx, contact.SlackWebhookURL, slackMessage{Text: "*" + subject + "*\n" + body})
+		})
+	}
+	if contact.Email != "" && d.mailer != nil {
+		deliver("email", func() error { return d.mailer.Send([]string{contact.Email}, subject, body) })
+	}
+}
+
+// formatDigest writes the subject and the plain text body of a digest in a locale.
+func formatDigest(locale string, server models.ObaServer, since time.Time, findings []Finding) (string, string) {
+	var body strings.Builder
+	body.WriteString(i18n.T(locale, "digest.intro", server.Name, since.UTC().Format("2006-01-02 15:04 MST")))
+	body.WriteString("\n")
+	for _, finding := range findings {
+		line := i18n.T(locale, "digest.finding", finding.Check, finding.Message, finding.Count)
+		if finding.Resolved {
+			line += " " + i18n.T(locale, "digest.resolved")
+		}
+		body.WriteString("\n• " + line)
+	}
+	return i18n.T(locale, "digest.subject", server.Name), body.String()
+}
+
+// postSlack posts a message to a Slack incoming webhook.
+func (d *AgencyDigest) postSlack(ctx context.Context, webhookURL string, message slackMessage) error {
+	data, err := json.Marshal(message)
+	if err != nil {
+		return fmt.Errorf("failed to encode Slack message: %w", err)
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, webhookURL, bytes.NewReader(data))
+	if err != nil {
+		return fmt.Errorf("failed to create Slack request: %w", err)
+	}
+	req.Header.Set("Content-Type", "application/json")
+
+	resp, err := d.client.Do(req)
+	if err != nil {
+		return fmt.Errorf("failed to post to Slack: %w", err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("slack webhook returned status: %d", resp.StatusCode)
+	}
+	return nil
+}
@@ -0,0 +1,77 @@
+package alert
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"io"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"net/smtp"
+	"strings"
+	"testing"
+	"time"
+
+	"watchdog.onebusaway.org/internal/models"
+)
+
+func TestAgencyDigest(t *testing.T) {
+	var messages []slackMessage
+	slack := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var message slackMessage
+		if err := json.NewDecoder(r.Body).Decode(&message); err != nil {
+			t.Errorf("failed to decode Slack message: %v", err)
+		}
+		messages = append(messages, message)
+	}))
+	defer slack.Close()
+
+	var emails []string
+	mailer := NewMailer("smtp.example.com:587", "watchdog@example.com", "", "", MailerOptions{})
+	mailer.send = func(addr string, _ smtp.Auth, from string, to []string, msg []byte) error {
+		emails = append(emails, strings.Join(to, ",")+"\n"+string(msg))
+		return nil
+	}
+
+	digest := NewAgencyDigest(slack.Client(), mailer, "en", slog.New(slog.NewTextHandler(io.Discard, nil)))
+	digest.now = func() time.Time { return time.Date(2025, 3, 1, 8, 0, 0, 0, time.UTC) }
+
+	agency := models.ObaServer{ID: 1, Name: "Metro", AgencyContact: &models.AgencyContact{Email: "gtfs@metro.example", SlackWebhookURL: slack.URL}}
+	internal := models.ObaServer{ID: 2, Name: "Internal"}
+
+	digest.Record(agency, "stops_match", errors.New("1 of 10 sampled stops are missing"))
+	digest.Record(agency, "stops_match", errors.New("2 of 10 sampled stops are missing"))
+	digest.Record(agency, "routes_match", errors.New("routes differ"))
+	digest.Record(agency, "routes_match", nil)
+	digest.Record(internal, "stops_match", errors.New("ignored"))
+	digest.Flush(context.Background())
+
+	if len(messages) != 1 || len(emails) != 1 {
+		t.Fatalf("expected one Slack message and one email, got %d and %d", len(messages), len(emails))
+	}
+	for _, want := range []string{
+		"Data quality report for Metro",
+		"since 2025-03-01 08:00 UTC",
+		"routes_match: routes differ (failed 1 times) (resolved)",
+		"stops_match: 2 of 10 sampled stops are missing (failed 2 times)",
+	} {
+		if !strings.Contains(messages[0].Text, want) {
+			t.Errorf("expected the Slack digest to contain %q, got %q", want, messages[0].Text)
+		}
+	}
+	if !strings.HasPrefix(emails[0], "gtfs@metro.example\n") || !strings.Contains(emails[0], "Subject: Data quality report for Metro") {
+		t.Errorf("unexpected email %q", emails[0])
+	}
+
+	// Findings are sent once; a period without findings sends nothing.
+	digest.Flush(context.Background())
+	if len(messages) != 1 || len(emails) != 1 {
+		t.Errorf("expected no digest without new findings, got %d messages and %d emails", len(messages), len(emails))
+	}
+
+	// A nil digest ignores findings.
+	var disabled *AgencyDigest
+	disabled.Record(agency, "stops_match", errors.New("ignored"))
+	disabled.Flush(context.Background())
+}
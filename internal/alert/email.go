@@ -0,0 +1,162 @@
+package alert
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"slices"
+	"strings"
+	"sync"
+	"time"
+
+	"watchdog.onebusaway.org/internal/i18n"
+	"watchdog.onebusaway.org/internal/scheduler"
+)
+
+// EmailNotifier emails alerts, for agencies without Slack or PagerDuty.
+//
+// Each server can send its alerts to its own recipients with `alerts.email_to`; other servers
+// use the default recipients. Alerts for servers without any recipient are skipped.
+//
+// In digest mode, alerts are not sent as they come but batched by recipients, and each batch
+// is sent as a single email when the digest is flushed (see Run), so that a wave of failures
+// does not flood the inboxes.
+type EmailNotifier struct {
+	mailer *Mailer
+	to     []string
+	digest bool
+	logger *slog.Logger
+
+	mu sync.Mutex
+	// pending holds the alerts waiting for the next digest, keyed by their recipients.
+	pending map[string]*emailBatch
+}
+
+// emailBatch is the alerts of a digest to the same recipients, in the order they came.
+type emailBatch struct {
+	to     []string
+	since  time.Time
+	alerts []Alert
+}
+
+// NewEmailNotifier creates an email notifier sending through mailer to default recipients
+// (may be empty if every server that should alert sets its own), batching alerts in digests
+// if digest is set.
+func NewEmailNotifier(mailer *Mailer, to []string, digest bool, logger *slog.Logger) *EmailNotifier {
+	return &EmailNotifier{mailer: mailer, to: to, digest: digest, logger: logger, pending: make(map[string]*emailBatch)}
+}
+
+func (n *EmailNotifier) Name() string {
+	return "email"
+}
+
+func (n *EmailNotifier) Notify(ctx context.Context, alert Alert) error {
+	to := n.to
+	if alert.Server.Alerts != nil && len(alert.Server.Alerts.EmailTo) > 0 {
+		to = alert.Server.Alerts.EmailTo
+	}
+	if len(to) == 0 {
+		return nil
+	}
+	if !n.digest {
+		subject, body := formatEmail(alert)
+		return n.mailer.Send(to, subject, body)
+	}
+
+	n.mu.Lock()
+	defer n.mu.Unlock()
+	key := strings.Join(to, ",")
+	batch := n.pending[key]
+	if batch == nil {
+		batch = &emailBatch{to: to, since: alert.At}
+		n.pending[key] = batch
+	}
+	batch.alerts = append(batch.alerts, alert)
+	return nil
+}
+
+// Flush sends the pending digests. Digests that fail to be delivered are logged and counted
+// with status "digest", not retried: the alerts still firing are notified again after the
+// cooldown.
+func (n *EmailNotifier) Flush() {
+	if n == nil {
+		return
+	}
+	n.mu.Lock()
+	pending := n.pending
+	n.pending = make(map[string]*emailBatch)
+	n.mu.Unlock()
+
+	keys := make([]string, 0, len(pending))
+	for key := range pending {
+		keys = append(keys, key)
+	}
+	slices.Sort(keys)
+	for _, key := range keys {
+		batch := pending[key]
+		subject, body := formatEmailDigest(batch.since, batch.alerts)
+		if err := n.mailer.Send(batch.to, subject, body); err != nil {
+			AlertNotificationsCounter.WithLabelValues(n.Name(), "digest", "failure").Inc()
+			n.logger.Error("Failed to send alert digest", "notifier", n.Name(), "recipients", len(batch.to), "alerts", len(batch.alerts), "error", err)
+			continue
+		}
+		AlertNotificationsCounter.WithLabelValues(n.Name(), "digest", "success").Inc()
+		n.logger.Info("Sent alert digest", "notifier", n.Name(), "recipients", len(batch.to), "alerts", len(batch.alerts))
+	}
+}
+
+// Run flushes the digests every window until ctx is canceled, then sends those still pending.
+// It returns immediately if the notifier is not in digest mode.
+func (n *EmailNotifier) Run(ctx context.Context, window time.Duration) {
+	if n == nil || !n.digest {
+		return
+	}
+	scheduler.Run(ctx, "alert_email_digest", scheduler.Every(window), n.Flush)
+	n.Flush()
+}
+
+// formatEmail writes the subject and the plain text body of an alert in its locale.
+func formatEmail(alert Alert) (string, string) {
+	return emailSummary(alert), emailDetails(alert)
+}
+
+// formatEmailDigest writes the subject and the plain text body of a digest of alerts, in the
+// locale of the first one.
+func formatEmailDigest(since time.Time, alerts []Alert) (string, string) {
+	locale := alerts[0].Locale
+	var body strings.Builder
+	body.WriteString(i18n.T(locale, "alert.email.digest_intro", since.UTC().Format("2006-01-02 15:04 MST")))
+	for _, alert := range alerts {
+		body.WriteString("\n\n" + emailSummary(alert) + "\n" + emailDetails(alert))
+	}
+	return i18n.T(locale, "alert.email.digest_subject", len(alerts)), body.String()
+}
+
+// emailSummary is the one-line summary of an alert, e.g. "[firing] OBA API is down — Metro".
+func emailSummary(alert Alert) string {
+	status := i18n.T(alert.Locale, "alert.status."+string(alert.Status))
+	return fmt.Sprintf("[%s] %s — %s", status, alert.Title, alert.Server.Name)
+}
+
+// emailDetails lists the fields of an alert, one per line, as in the Slack attachment.
+func emailDetails(alert Alert) string {
+	var details strings.Builder
+	field := func(key, value string) {
+		fmt.Fprintf(&details, "%s: %s\n", i18n.T(alert.Locale, key), value)
+	}
+	field("alert.field.server", fmt.Sprintf("%s (id %d)", alert.Server.Name, alert.Server.ID))
+	field("alert.field.check", alert.Check)
+	field("alert.field.details", alert.Description)
+	if alert.Server.ObaBaseURL != "" {
+		field("alert.field.oba", alert.Server.ObaBaseURL)
+	}
+	if len(alert.Vantages) > 0 {
+		field("alert.field.vantages", formatVantages(alert.Locale, alert.Vantages))
+	}
+	if alert.Status == StatusFiring {
+		field("alert.field.threshold", formatThreshold(alert.Threshold))
+	} else {
+		field("alert.field.firing_since", alert.StartsAt.UTC().Format("2006-01-02 15:04 MST"))
+	}
+	return strings.TrimSuffix(details.String(), "\n")
+}
@@ -0,0 +1,178 @@
+package alert
+
+import (
+	"bufio"
+	"context"
+	"io"
+	"log/slog"
+	"mime"
+	"net"
+	"net/mail"
+	"net/smtp"
+	"strings"
+	"testing"
+	"time"
+
+	"watchdog.onebusaway.org/internal/models"
+)
+
+// sentEmail is an email captured by a test mailer.
+type sentEmail struct {
+	to  []string
+	msg string
+}
+
+func newTestMailer(sent *[]sentEmail) *Mailer {
+	mailer := NewMailer("smtp.example.com:587", "watchdog@example.com", "", "", MailerOptions{})
+	mailer.send = func(_ string, _ smtp.Auth, _ string, to []string, msg []byte) error {
+		*sent = append(*sent, sentEmail{to: to, msg: string(msg)})
+		return nil
+	}
+	return mailer
+}
+
+// subject returns the decoded subject of a captured email.
+func (e sentEmail) subject(t *testing.T) string {
+	msg, err := mail.ReadMessage(strings.NewReader(e.msg))
+	if err != nil {
+		t.Fatalf("failed to parse email: %v", err)
+	}
+	subject, err := new(mime.WordDecoder).DecodeHeader(msg.Header.Get("Subject"))
+	if err != nil {
+		t.Fatalf("failed to decode subject: %v", err)
+	}
+	return subject
+}
+
+func TestEmailNotifier(t *testing.T) {
+	var sent []sentEmail
+	notifier := NewEmailNotifier(newTestMailer(&sent), []string{"ops@example.com"}, false, slog.New(slog.NewTextHandler(io.Discard, nil)))
+
+	metro := models.ObaServer{ID: 1, Name: "Metro", ObaBaseURL: "https://oba.metro.example"}
+	agency := models.ObaServer{ID: 2, Name: "Agency", Alerts: &models.AlertConfig{EmailTo: []string{"gtfs@agency.example"}, Locale: "es"}}
+	at := time.Date(2025, 3, 1, 8, 0, 0, 0, time.UTC)
+
+	if err := notifier.Notify(context.Background(), Alert{Server: metro, Check: "api_down", Locale: "en", Title: "OBA API is down", Status: StatusFiring, Threshold: 3, Description: "3 consecutive failed pings", At: at}); err != nil {
+		t.Fatalf("Notify failed: %v", err)
+	}
+	if err := notifier.Notify(context.Background(), Alert{Server: agency, Check: "api_down", Locale: "es", Title: "La API de OBA no responde", Status: StatusResolved, StartsAt: at, At: at}); err != nil {
+		t.Fatalf("Notify failed: %v", err)
+	}
+	if len(sent) != 2 {
+		t.Fatalf("expected 2 emails, got %d", len(sent))
+	}
+	if strings.Join(sent[0].to, ",") != "ops@example.com" {
+		t.Errorf("expected the default recipients, got %v", sent[0].to)
+	}
+	if subject := sent[0].subject(t); subject != "[firing] OBA API is down — Metro" {
+		t.Errorf("unexpected subject %q", subject)
+	}
+	for _, want := range []string{"Server: Metro (id 1)", "OBA: https://oba.metro.example", "Threshold: 3"} {
+		if !strings.Contains(sent[0].msg, want) {
+			t.Errorf("expected the email to contain %q, got %q", want, sent[0].msg)
+		}
+	}
+	if strings.Join(sent[1].to, ",") != "gtfs@agency.example" {
+		t.Errorf("expected the recipients of the server, got %v", sent[1].to)
+	}
+	if !strings.Contains(sent[1].msg, "Activa desde: 2025-03-01 08:00 UTC") {
+		t.Errorf("expected the email in the locale of the server, got %q", sent[1].msg)
+	}
+
+	// Without any recipient, alerts are skipped.
+	silent := NewEmailNotifier(newTestMailer(&sent), nil, false, slog.New(slog.NewTextHandler(io.Discard, nil)))
+	if err := silent.Notify(context.Background(), Alert{Server: metro, Status: StatusFiring}); err != nil || len(sent) != 2 {
+		t.Errorf("expected no email without recipients, got %d emails and error %v", len(sent), err)
+	}
+}
+
+func TestEmailNotifierDigest(t *testing.T) {
+	var sent []sentEmail
+	notifier := NewEmailNotifier(newTestMailer(&sent), []string{"ops@example.com"}, true, slog.New(slog.NewTextHandler(io.Discard, nil)))
+
+	at := time.Date(2025, 3, 1, 8, 0, 0, 0, time.UTC)
+	metro := models.ObaServer{ID: 1, Name: "Metro"}
+	ferry := models.ObaServer{ID: 2, Name: "Ferry"}
+	agency := models.ObaServer{ID: 3, Name: "Agency", Alerts: &models.AlertConfig{EmailTo: []string{"gtfs@agency.example"}}}
+	for _, server := range []models.ObaServer{metro, ferry, agency} {
+		alert := Alert{Server: server, Check: "api_down", Locale: "en", Title: "OBA API is down", Status: StatusFiring, At: at}
+		if err := notifier.Notify(context.Background(), alert); err != nil {
+			t.Fatalf("Notify failed: %v", err)
+		}
+	}
+	if len(sent) != 0 {
+		t.Fatalf("expected alerts to wait for the digest, got %d emails", len(sent))
+	}
+
+	notifier.Flush()
+	if len(sent) != 2 {
+		t.Fatalf("expected one digest per recipients, got %d emails", len(sent))
+	}
+	if strings.Join(sent[1].to, ",") != "ops@example.com" {
+		t.Fatalf("expected the second digest to go to the default recipients, got %v", sent[1].to)
+	}
+	if subject := sent[1].subject(t); subject != "2 OneBusAway watchdog alerts" {
+		t.Errorf("unexpected digest subject %q", subject)
+	}
+	for _, want := range []string{"since 2025-03-01 08:00 UTC", "[firing] OBA API is down — Metro", "[firing] OBA API is down — Ferry"} {
+		if !strings.Contains(sent[1].msg, want) {
+			t.Errorf("expected the digest to contain %q, got %q", want, sent[1].msg)
+		}
+	}
+
+	// Alerts are sent once; a window without alerts sends nothing.
+	notifier.Flush()
+	if len(sent) != 2 {
+		t.Errorf("expected no digest without new alerts, got %d emails", len(sent))
+	}
+
+	// Pending alerts are sent when the notifier stops.
+	if err := notifier.Notify(context.Background(), Alert{Server: metro, Locale: "en", Status: StatusResolved, At: at}); err != nil {
+		t.Fatalf("Notify failed: %v", err)
+	}
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	notifier.Run(ctx, time.Hour)
+	if len(sent) != 3 {
+		t.Errorf("expected the pending alerts to be sent on stop, got %d emails", len(sent))
+	}
+}
+
+func TestMailerRequiresStartTLS(t *testing.T) {
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer listener.Close()
+	// The server does not advertise STARTTLS.
+	go func() {
+		conn, err := listener.Accept()
+		if err != nil {
+			return
+		}
+		defer conn.Close()
+		reader := bufio.NewReader(conn)
+		io.WriteString(conn, "220 smtp.example.com ESMTP\r\n")
+		for {
+			line, err := reader.ReadString('\n')
+			if err != nil {
+				return
+			}
+			switch {
+			case strings.HasPrefix(line, "EHLO"):
+				io.WriteString(conn, "250-smtp.example.com\r\n250 AUTH PLAIN\r\n")
+			case strings.HasPrefix(line, "QUIT"):
+				io.WriteString(conn, "221 bye\r\n")
+				return
+			default:
+				io.WriteString(conn, "250 OK\r\n")
+			}
+		}
+	}()
+
+	mailer := NewMailer(listener.Addr().String(), "watchdog@example.com", "user", "secret", MailerOptions{TLS: SMTPTLSStartTLS})
+	err = mailer.Send([]string{"ops@example.com"}, "subject", "body")
+	if err == nil || !strings.Contains(err.Error(), "does not support STARTTLS") {
+		t.Errorf("expected the email to be refused without STARTTLS, got %v", err)
+	}
+}
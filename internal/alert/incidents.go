@@ -0,0 +1,52 @@
+package alert
+
+import (
+	"context"
+	"sync"
+)
+
+// IncidentLog is a Notifier that keeps the latest incident events, i.e. the alerts that open an
+// incident (a check starts firing) or resolve it, for the incident feed. Reminders of a check
+// that keeps firing are not recorded, since they are not events of the incident.
+// Events are kept in memory, so the log starts over when the watchdog restarts.
+// It is safe for concurrent use.
+type IncidentLog struct {
+	limit int
+
+	mu     sync.RWMutex
+	events []Alert
+}
+
+// NewIncidentLog creates an IncidentLog keeping the last limit events.
+func NewIncidentLog(limit int) *IncidentLog {
+	return &IncidentLog{limit: limit}
+}
+
+func (l *IncidentLog) Name() string {
+	return "incident_log"
+}
+
+// Notify records the alert if it opens or resolves an incident.
+func (l *IncidentLog) Notify(_ context.Context, alert Alert) error {
+	if alert.Repeat {
+		return nil
+	}
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	l.events = append(l.events, alert)
+	if len(l.events) > l.limit {
+		l.events = l.events[len(l.events)-l.limit:]
+	}
+	return nil
+}
+
+// Events returns the recorded events, newest first.
+func (l *IncidentLog) Events() []Alert {
+	l.mu.RLock()
+	defer l.mu.RUnlock()
+	events := make([]Alert, len(l.events))
+	for i, event := range l.events {
+		events[len(events)-1-i] = event
+	}
+	return events
+}
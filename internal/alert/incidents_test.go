@@ -0,0 +1,28 @@
+package alert
+
+import (
+	"context"
+	"testing"
+
+	"watchdog.onebusaway.org/internal/models"
+)
+
+func TestIncidentLog(t *testing.T) {
+	l := NewIncidentLog(2)
+	ctx := context.Background()
+	server := models.ObaServer{ID: 1}
+
+	_ = l.Notify(ctx, Alert{Server: server, Check: CheckAPIDown, Status: StatusFiring})
+	_ = l.Notify(ctx, Alert{Server: server, Check: CheckAPIDown, Status: StatusFiring, Repeat: true})
+	_ = l.Notify(ctx, Alert{Server: server, Check: CheckAPIDown, Status: StatusResolved})
+	events := l.Events()
+	if len(events) != 2 || events[0].Status != StatusResolved || events[1].Status != StatusFiring {
+		t.Fatalf("expected the opening and resolution of the incident, newest first, got %+v", events)
+	}
+
+	_ = l.Notify(ctx, Alert{Server: server, Check: CheckBundleExpiration, Status: StatusFiring})
+	events = l.Events()
+	if len(events) != 2 || events[0].Check != CheckBundleExpiration || events[1].Status != StatusResolved {
+		t.Errorf("expected only the last 2 events to be kept, got %+v", events)
+	}
+}
@@ -0,0 +1,152 @@
+package alert
+
+import (
+	"crypto/tls"
+	"errors"
+	"fmt"
+	"mime"
+	"net"
+	"net/smtp"
+	"strings"
+	"time"
+)
+
+// SMTP connection security modes (--smtp-tls).
+const (
+	// SMTPTLSAuto upgrades connections with STARTTLS when the server supports it.
+	SMTPTLSAuto = "auto"
+	// SMTPTLSStartTLS requires STARTTLS, and fails with servers that do not support it.
+	SMTPTLSStartTLS = "starttls"
+	// SMTPTLSImplicit connects over TLS from the start, usually on port 465.
+	SMTPTLSImplicit = "tls"
+)
+
+// SMTP authentication mechanisms (--smtp-auth).
+const (
+	SMTPAuthPlain   = "plain"
+	SMTPAuthCRAMMD5 = "cram-md5"
+)
+
+// smtpDialTimeout bounds the connection to the SMTP server in the starttls and tls modes.
+const smtpDialTimeout = 30 * time.Second
+
+// MailerOptions are the connection settings of a Mailer. The zero value uses SMTPTLSAuto and
+// SMTPAuthPlain.
+type MailerOptions struct {
+	// TLS is the connection security, one of the SMTPTLS* modes.
+	TLS string
+	// Auth is the authentication mechanism used with a username, one of the SMTPAuth* mechanisms.
+	Auth string
+}
+
+// Mailer sends plain text emails through an SMTP server. By default connections are upgraded
+// with STARTTLS when the server supports it, and PLAIN credentials are only sent over TLS or to
+// localhost; see MailerOptions to require TLS or use CRAM-MD5.
+type Mailer struct {
+	addr string
+	from string
+	auth smtp.Auth
+	// send is smtp.SendMail, or sendTLS when TLS is required; replaced in tests.
+	send func(addr string, auth smtp.Auth, from string, to []string, msg []byte) error
+}
+
+// NewMailer creates a Mailer sending from an address through the SMTP server at addr
+// ("host:port"). Returns nil (email disabled) if addr is empty. Credentials are optional.
+func NewMailer(addr, from, username, password string, opts MailerOptions) *Mailer {
+	if addr == "" {
+		return nil
+	}
+	m := &Mailer{addr: addr, from: from, send: smtp.SendMail}
+	if opts.TLS == SMTPTLSStartTLS || opts.TLS == SMTPTLSImplicit {
+		implicit := opts.TLS == SMTPTLSImplicit
+		m.send = func(addr string, auth smtp.Auth, from string, to []string, msg []byte) error {
+			return sendTLS(addr, implicit, auth, from, to, msg)
+		}
+	}
+	if username != "" {
+		host, _, _ := net.SplitHostPort(addr)
+		if opts.Auth == SMTPAuthCRAMMD5 {
+			m.auth = smtp.CRAMMD5Auth(username, password)
+		} else {
+			m.auth = smtp.PlainAuth("", username, password, host)
+		}
+	}
+	return m
+}
+
+// Send sends an email to the recipients.
+func (m *Mailer) Send(to []string, subject, body string) error {
+	var msg strings.Builder
+	fmt.Fprintf(&msg, "From: %s\r\n", m.from)
+	fmt.Fprintf(&msg, "To: %s\r\n", strings.Join(to, ", "))
+	fmt.Fprintf(&msg, "Subject: %s\r\n", mime.QEncoding.Encode("utf-8", subject))
+	fmt.Fprintf(&msg, "Date: %s\r\n", time.Now().Format(time.RFC1123Z))
+	msg.WriteString("MIME-Version: 1.0\r\n")
+	msg.WriteString("Content-Type: text/plain; charset=utf-8\r\n\r\n")
+	msg.WriteString(strings.ReplaceAll(body, "\n", "\r\n"))
+
+	if err := m.send(m.addr, m.auth, m.from, to, []byte(msg.String())); err != nil {
+		return fmt.Errorf("failed to send email: %w", err)
+	}
+	return nil
+}
+
+// sendTLS is smtp.SendMail with TLS required: the connection is either over TLS from the start
+// (implicit) or upgraded with STARTTLS, and the email is not sent if the server does not
+// support it.
+func sendTLS(addr string, implicit bool, auth smtp.Auth, from string, to []string, msg []byte) error {
+	host, _, err := net.SplitHostPort(addr)
+	if err != nil {
+		return err
+	}
+	tlsConfig := &tls.Config{ServerName: host, MinVersion: tls.VersionTLS12}
+	dialer := &net.Dialer{Timeout: smtpDialTimeout}
+	var conn net.Conn
+	if implicit {
+		conn, err = tls.DialWithDialer(dialer, "tcp", addr, tlsConfig)
+	} else {
+		conn, err = dialer.Dial("tcp", addr)
+	}
+	if err != nil {
+		return err
+	}
+	client, err := smtp.NewClient(conn, host)
+	if err != nil {
+		conn.Close()
+		return err
+	}
+	defer client.Close()
+
+	if !implicit {
+		if ok, _ := client.Extension("STARTTLS"); !ok {
+			return errors.New("the SMTP server does not support STARTTLS")
+		}
+		if err := client.StartTLS(tlsConfig); err != nil {
+			return err
+		}
+	}
+	if auth != nil {
+		if err := client.Auth(auth); err != nil {
+			return err
+		}
+	}
+	if err := client.Mail(from); err != nil {
+		return err
+	}
+	for _, recipient := range to {
+		if err := client.Rcpt(recipient); err != nil {
+			return err
+		}
+	}
+	w, err := client.Data()
+	if err != nil {
+		return err
+	}
+	if _, err := w.Write(msg); err != nil {
+		return err
+	}
+	if err := w.Close(); err != nil {
+		return err
+	}
+	return client.Quit()
+}
@@ -0,0 +1,150 @@
+package alert
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"time"
+
+	"watchdog.onebusaway.org/internal/config"
+)
+
+// PagerDutyEventsURL is the PagerDuty Events API v2 endpoint.
+const PagerDutyEventsURL = "https://events.pagerduty.com/v2/enqueue"
+
+// PagerDutyNotifier triggers and resolves PagerDuty incidents through the Events API v2.
+//
+// Every (server, check) pair maps to one incident through its dedup key, so reminders of a
+// firing check are grouped into the open incident, and the recovery of the check resolves it.
+// Each server can page its own service with `alerts.pagerduty_routing_key`; other servers use
+// the default routing key. Alerts for servers without any routing key are skipped.
+type PagerDutyNotifier struct {
+	routingKey string
+	client     *http.Client
+	maxRetries int
+	eventsURL  string
+}
+
+// NewPagerDutyNotifier creates a PagerDuty notifier with a default routing (integration) key,
+// which may be empty if every server that should page sets its own.
+// Events rejected with a 429 or 5xx response, or not delivered at all, are retried up to
+// maxRetries times with exponential backoff.
+func NewPagerDutyNotifier(routingKey string, client *http.Client, maxRetries int) *PagerDutyNotifier {
+	return &PagerDutyNotifier{
+		routingKey: routingKey,
+		client:     client,
+		maxRetries: maxRetries,
+		eventsURL:  PagerDutyEventsURL,
+	}
+}
+
+func (n *PagerDutyNotifier) Name() string {
+	return "pagerduty"
+}
+
+// pagerDutyEvent is an Events API v2 event.
+type pagerDutyEvent struct {
+	RoutingKey  string            `json:"routing_key"`
+	EventAction string            `json:"event_action"`
+	DedupKey    string            `json:"dedup_key"`
+	Payload     *pagerDutyPayload `json:"payload,omitempty"`
+}
+
+type pagerDutyPayload struct {
+	Summary       string                 `json:"summary"`
+	Source        string                 `json:"source"`
+	Severity      string                 `json:"severity"`
+	Timestamp     string                 `json:"timestamp"`
+	Component     string                 `json:"component"`
+	Group         string                 `json:"group"`
+	Class         string                 `json:"class"`
+	CustomDetails map[string]interface{} `json:"custom_details"`
+}
+
+// pagerDutyDedupKey identifies the incident of a (server, check) pair.
+func pagerDutyDedupKey(alert Alert) string {
+	return fmt.Sprintf("onebusaway-watchdog/%d/%s", alert.Server.ID, alert.Check)
+}
+
+// newPagerDutyEvent converts an alert into a trigger event, or a resolve event for recoveries.
+func newPagerDutyEvent(routingKey string, alert Alert) pagerDutyEvent {
+	event := pagerDutyEvent{
+		RoutingKey: routingKey,
+		DedupKey:   pagerDutyDedupKey(alert),
+	}
+	if alert.Status == StatusResolved {
+		event.EventAction = "resolve"
+		return event
+	}
+
+	source := alert.Server.ObaBaseURL
+	if source == "" {
+		source = alert.Server.Name
+	}
+	event.EventAction = "trigger"
+	event.Payload = &pagerDutyPayload{
+		Summary:   fmt.Sprintf("%s: %s (%s)", alert.Title, alert.Server.Name, alert.Description),
+		Source:    source,
+		Severity:  alert.Severity,
+		Timestamp: alert.At.UTC().Format(time.RFC3339),
+		Component: alert.Server.Name,
+		Group:     "onebusaway-watchdog",
+		Class:     alert.Check,
+		CustomDetails: map[string]interface{}{
+			"server_id": alert.Server.ID,
+			"value":     alert.Value,
+			"threshold": alert.Threshold,
+			"starts_at": alert.StartsAt.UTC().Format(time.RFC3339),
+		},
+	}
+	if len(alert.Vantages) > 0 {
+		event.Payload.CustomDetails["vantages"] = alert.Vantages
+	}
+	return event
+}
+
+func (n *PagerDutyNotifier) Notify(ctx context.Context, alert Alert) error {
+	routingKey := n.routingKey
+	if alert.Server.Alerts != nil && alert.Server.Alerts.PagerDutyRoutingKey != "" {
+		routingKey = alert.Server.Alerts.PagerDutyRoutingKey
+	}
+	if routingKey == "" {
+		return nil
+	}
+
+	body, err := json.Marshal(newPagerDutyEvent(routingKey, alert))
+	if err != nil {
+		return fmt.Errorf("failed to encode PagerDuty event: %w", err)
+	}
+
+	err = config.RetryWithBackoff(ctx, n.maxRetries, func() error {
+		return n.post(ctx, body)
+	})
+	if err != nil {
+		return fmt.Errorf("failed to send PagerDuty event: %w", err)
+	}
+	return nil
+}
+
+func (n *PagerDutyNotifier) post(ctx context.Context, body []byte) error {
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, n.eventsURL, bytes.NewReader(body))
+	if err != nil {
+		return fmt.Errorf("failed to create PagerDuty request: %w", err)
+	}
+	req.Header.Set("Content-Type", "application/json")
+	resp, err := n.client.Do(req)
+	if err != nil {
+		return fmt.Errorf("failed to post to PagerDuty: %w", err)
+	}
+	resp.Body.Close()
+	switch {
+	case resp.StatusCode >= 200 && resp.StatusCode <= 299:
+		return nil
+	case resp.StatusCode == http.StatusTooManyRequests || resp.StatusCode >= 500:
+		return fmt.Errorf("PagerDuty responded with status %d", resp.StatusCode)
+	default:
+		return &config.PermanentError{Err: fmt.Errorf("PagerDuty responded with status %d", resp.StatusCode)}
+	}
+}
@@ -0,0 +1,100 @@
+package alert
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"watchdog.onebusaway.org/internal/models"
+)
+
+func TestPagerDutyNotifier(t *testing.T) {
+	var events []pagerDutyEvent
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var event pagerDutyEvent
+		if err := json.NewDecoder(r.Body).Decode(&event); err != nil {
+			t.Errorf("failed to decode PagerDuty event: %v", err)
+		}
+		events = append(events, event)
+		w.WriteHeader(http.StatusAccepted)
+	}))
+	defer server.Close()
+
+	notifier := NewPagerDutyNotifier("default-key", server.Client(), 0)
+	notifier.eventsURL = server.URL
+
+	alert := Alert{
+		Server:      models.ObaServer{ID: 7, Name: "Test Server", ObaBaseURL: "https://oba.example.com"},
+		Check:       CheckAPIDown,
+		Title:       "OBA API is down",
+		Severity:    "critical",
+		Status:      StatusFiring,
+		Value:       2,
+		Threshold:   2,
+		Description: "2 consecutive failed pings",
+		StartsAt:    time.Now(),
+		At:          time.Now(),
+	}
+	if err := notifier.Notify(context.Background(), alert); err != nil {
+		t.Fatalf("Notify failed: %v", err)
+	}
+
+	alert.Status = StatusResolved
+	alert.Server.Alerts = &models.AlertConfig{PagerDutyRoutingKey: "agency-key"}
+	if err := notifier.Notify(context.Background(), alert); err != nil {
+		t.Fatalf("Notify failed: %v", err)
+	}
+
+	if len(events) != 2 {
+		t.Fatalf("expected 2 events, got %d", len(events))
+	}
+	trigger, resolve := events[0], events[1]
+	if trigger.EventAction != "trigger" || trigger.RoutingKey != "default-key" || trigger.Payload == nil ||
+		trigger.Payload.Severity != "critical" || trigger.Payload.Source != "https://oba.example.com" {
+		t.Errorf("unexpected trigger event %+v", trigger)
+	}
+	if resolve.EventAction != "resolve" || resolve.RoutingKey != "agency-key" || resolve.Payload != nil {
+		t.Errorf("unexpected resolve event %+v", resolve)
+	}
+	if trigger.DedupKey != "onebusaway-watchdog/7/api_down" || resolve.DedupKey != trigger.DedupKey {
+		t.Errorf("expected both events to share the dedup key, got %q and %q", trigger.DedupKey, resolve.DedupKey)
+	}
+
+	// Without any routing key, the alert is skipped.
+	if err := NewPagerDutyNotifier("", server.Client(), 0).Notify(context.Background(), Alert{}); err != nil {
+		t.Errorf("expected no error without a routing key, got %v", err)
+	}
+	if len(events) != 2 {
+		t.Errorf("expected no request without a routing key, got %d events", len(events))
+	}
+}
+
+func TestPagerDutyNotifierRetries(t *testing.T) {
+	statuses := []int{http.StatusServiceUnavailable, http.StatusAccepted}
+	attempts := 0
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(statuses[min(attempts, len(statuses)-1)])
+		attempts++
+	}))
+	defer server.Close()
+
+	notifier := NewPagerDutyNotifier("key", server.Client(), 2)
+	notifier.eventsURL = server.URL
+	if err := notifier.Notify(context.Background(), Alert{Status: StatusFiring}); err != nil {
+		t.Fatalf("expected the event to be delivered after a retry, got %v", err)
+	}
+	if attempts != 2 {
+		t.Errorf("expected 2 attempts, got %d", attempts)
+	}
+
+	statuses, attempts = []int{http.StatusBadRequest}, 0
+	if err := notifier.Notify(context.Background(), Alert{Status: StatusFiring}); err == nil {
+		t.Fatal("expected an error for an invalid event")
+	}
+	if attempts != 1 {
+		t.Errorf("expected an invalid event not to be retried, got %d attempts", attempts)
+	}
+}
@@ -0,0 +1,127 @@
+package alert
+
+import (
+	"fmt"
+	"io"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"gopkg.in/yaml.v3"
+	"watchdog.onebusaway.org/internal/i18n"
+	"watchdog.onebusaway.org/internal/models"
+)
+
+// PrometheusRuleGroupName is the name of the rule group written by WritePrometheusRules.
+const PrometheusRuleGroupName = "onebusaway-watchdog"
+
+// prometheusRuleFile is the layout of a Prometheus rule file
+// (https://prometheus.io/docs/prometheus/latest/configuration/alerting_rules/).
+type prometheusRuleFile struct {
+	Groups []prometheusRuleGroup `yaml:"groups"`
+}
+
+type prometheusRuleGroup struct {
+	Name  string           `yaml:"name"`
+	Rules []prometheusRule `yaml:"rules"`
+}
+
+type prometheusRule struct {
+	Alert       string            `yaml:"alert"`
+	Expr        string            `yaml:"expr"`
+	For         string            `yaml:"for,omitempty"`
+	Labels      map[string]string `yaml:"labels"`
+	Annotations map[string]string `yaml:"annotations"`
+}
+
+// prometheusRules translates the alert checks into Prometheus alerting rules, applying the
+// same thresholds and per-server overrides the Manager uses:
+//   - One rule per check covers every server using the default threshold. Servers with an
+//     overridden threshold, or with the check muted, are excluded from it with a server_id matcher.
+//   - Each server with an overridden threshold gets its own rule, selecting only its series.
+//   - Muted servers and checks get no rule.
+//   - Service-dependent checks do not fire during the planned service reductions of a server.
+//
+// interval is the metrics collection interval, used to express consecutive failures of checks
+// that run once per collection cycle as a `for` duration.
+//
+// Cooldowns are not part of the rules: they correspond to Alertmanager's `repeat_interval`.
+// Summaries are in English; Alertmanager notification templates can localize them.
+func prometheusRules(servers []models.ObaServer, interval time.Duration) []prometheusRule {
+	servers = append([]models.ObaServer(nil), servers...)
+	sort.Slice(servers, func(i, j int) bool { return servers[i].ID < servers[j].ID })
+
+	var rules []prometheusRule
+	for _, check := range checkNames {
+		definition := checks[check]
+
+		var excluded []string
+		var overrides []prometheusRule
+		for _, server := range servers {
+			override := server.Alerts.Check(check)
+			serverID := strconv.Itoa(server.ID)
+			if server.Alerts != nil && server.Alerts.Disabled || override.Disabled {
+				excluded = append(excluded, serverID)
+				continue
+			}
+			if override.Threshold == nil || *override.Threshold == definition.DefaultThreshold {
+				continue
+			}
+			excluded = append(excluded, serverID)
+			selector := fmt.Sprintf(`{server_id="%s"}`, serverID)
+			overrides = append(overrides, newPrometheusRule(check, definition, selector, *override.Threshold, interval))
+		}
+
+		selector := ""
+		if len(excluded) > 0 {
+			quoted := make([]string, len(excluded))
+			for i, id := range excluded {
+				quoted[i] = regexp.QuoteMeta(id)
+			}
+			selector = fmt.Sprintf(`{server_id!~"%s"}`, strings.Join(quoted, "|"))
+		}
+		rules = append(rules, newPrometheusRule(check, definition, selector, definition.DefaultThreshold, interval))
+		rules = append(rules, overrides...)
+	}
+	return rules
+}
+
+func newPrometheusRule(check string, definition checkDefinition, selector string, threshold float64, interval time.Duration) prometheusRule {
+	expr, forDuration := definition.Rule(selector, threshold, interval)
+	if definition.ServiceDependent {
+		expr += " unless on(server_id) gtfs_service_reduction_active == 1"
+	}
+	rule := prometheusRule{
+		Alert:  definition.AlertName,
+		Expr:   expr,
+		Labels: map[string]string{"check": check, "severity": definition.Severity},
+		Annotations: map[string]string{
+			"summary":     definition.title(i18n.DefaultLocale),
+			"description": definition.RuleDescription,
+		},
+	}
+	// Prometheus durations do not accept fractions of a second.
+	if forDuration = forDuration.Round(time.Second); forDuration > 0 {
+		rule.For = forDuration.String()
+	}
+	return rule
+}
+
+// WritePrometheusRules writes the alert checks, with the per-server overrides of servers,
+// as a Prometheus alerting rules file (YAML) to w. See prometheusRules for how checks map to rules.
+func WritePrometheusRules(w io.Writer, servers []models.ObaServer, interval time.Duration) error {
+	file := prometheusRuleFile{
+		Groups: []prometheusRuleGroup{{
+			Name:  PrometheusRuleGroupName,
+			Rules: prometheusRules(servers, interval),
+		}},
+	}
+	encoder := yaml.NewEncoder(w)
+	encoder.SetIndent(2)
+	if err := encoder.Encode(file); err != nil {
+		return fmt.Errorf("failed to encode Prometheus rules: %w", err)
+	}
+	return encoder.Close()
+}
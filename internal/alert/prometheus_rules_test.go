@@ -0,0 +1,70 @@
+package alert
+
+import (
+	"bytes"
+	"testing"
+	"time"
+
+	"gopkg.in/yaml.v3"
+	"watchdog.onebusaway.org/internal/models"
+)
+
+func TestWritePrometheusRules(t *testing.T) {
+	threshold := 4.0
+	servers := []models.ObaServer{
+		{ID: 3},
+		{ID: 2, Alerts: &models.AlertConfig{Disabled: true}},
+		{ID: 1, Alerts: &models.AlertConfig{Checks: map[string]models.AlertCheckConfig{
+			CheckAPIDown:        {Threshold: &threshold},
+			CheckBundleDownload: {Disabled: true},
+		}}},
+	}
+
+	var buf bytes.Buffer
+	if err := WritePrometheusRules(&buf, servers, 30*time.Second); err != nil {
+		t.Fatalf("WritePrometheusRules failed: %v", err)
+	}
+
+	var file prometheusRuleFile
+	if err := yaml.Unmarshal(buf.Bytes(), &file); err != nil {
+		t.Fatalf("failed to parse rules YAML: %v\n%s", err, buf.String())
+	}
+	if len(file.Groups) != 1 || file.Groups[0].Name != PrometheusRuleGroupName {
+		t.Fatalf("unexpected groups %+v", file.Groups)
+	}
+
+	expected := []struct {
+		alert, expr, forDuration string
+	}{
+		{"WatchdogAPIDown", `oba_api_status{server_id!~"1|2"} == 0`, "30s"},
+		{"WatchdogAPIDown", `oba_api_status{server_id="1"} == 0`, "1m30s"},
+		{"WatchdogBundleDownloadFailing", `gtfs_bundle_download_consecutive_failures{server_id!~"1|2"} >= 3`, ""},
+		{"WatchdogBundleExpiringSoon", `gtfs_bundle_days_until_earliest_expiration{server_id!~"2"} < 7`, ""},
+		{"WatchdogBundleSizeDrop", `gtfs_bundle_size_ratio{server_id!~"2"} < 0.5`, ""},
+		{"WatchdogVehiclesDropped", `vehicle_count_match_ratio{server_id!~"2"} < 0.8 unless on(server_id) gtfs_service_reduction_active == 1`, ""},
+		{"WatchdogURLDown", `url_check_up{server_id!~"2"} == 0`, "30s"},
+	}
+	rules := file.Groups[0].Rules
+	if len(rules) != len(expected) {
+		t.Fatalf("expected %d rules, got %d:\n%s", len(expected), len(rules), buf.String())
+	}
+	for i, want := range expected {
+		got := rules[i]
+		if got.Alert != want.alert || got.Expr != want.expr || got.For != want.forDuration {
+			t.Errorf("rule %d: got %s %q for %q, want %s %q for %q", i, got.Alert, got.Expr, got.For, want.alert, want.expr, want.forDuration)
+		}
+		if got.Labels["check"] == "" || got.Annotations["summary"] == "" {
+			t.Errorf("rule %d: missing check label or summary: %+v", i, got)
+		}
+	}
+}
+
+func TestPrometheusRulesWithoutOverrides(t *testing.T) {
+	rules := prometheusRules([]models.ObaServer{{ID: 1}}, time.Minute)
+	if len(rules) != len(checkNames) {
+		t.Fatalf("expected one rule per check, got %d", len(rules))
+	}
+	if rules[0].Expr != "oba_api_status == 0" || rules[0].For != "1m0s" {
+		t.Errorf("unexpected api_down rule %+v", rules[0])
+	}
+}
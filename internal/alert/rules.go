@@ -0,0 +1,565 @@
+package alert
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"slices"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	dto "github.com/prometheus/client_model/go"
+	"watchdog.onebusaway.org/internal/i18n"
+	"watchdog.onebusaway.org/internal/models"
+)
+
+// Conditions of alert rules (models.AlertRule). Absolute conditions compare the last value of
+// a series; rate-of-change conditions compare it to the values of the rule's window.
+const (
+	// ConditionAbove fires when the value is above the threshold.
+	ConditionAbove = "above"
+	// ConditionBelow fires when the value is below the threshold.
+	ConditionBelow = "below"
+	// ConditionIncreaseAbove fires when the value rose by more than the threshold from the lowest value of the window.
+	ConditionIncreaseAbove = "increase_above"
+	// ConditionDecreaseAbove fires when the value fell by more than the threshold from the highest value of the window.
+	ConditionDecreaseAbove = "decrease_above"
+	// ConditionRisePercent fires when the value rose by more than threshold percent from the lowest value of the window.
+	ConditionRisePercent = "rise_percent"
+	// ConditionDropPercent fires when the value fell by more than threshold percent from the highest value of the window.
+	ConditionDropPercent = "drop_percent"
+	// ConditionChanged fires when the value changed at all within the window; the threshold is not used.
+	ConditionChanged = "changed"
+)
+
+// RuleConditions lists the conditions of alert rules.
+var RuleConditions = []string{
+	ConditionAbove,
+	ConditionBelow,
+	ConditionIncreaseAbove,
+	ConditionDecreaseAbove,
+	ConditionRisePercent,
+	ConditionDropPercent,
+	ConditionChanged,
+}
+
+// Aggregates of alert rules (models.AlertRule.Aggregate), combining the series of a metric.
+const (
+	AggregateSum   = "sum"
+	AggregateAvg   = "avg"
+	AggregateMin   = "min"
+	AggregateMax   = "max"
+	AggregateCount = "count"
+)
+
+// RuleAggregates lists the aggregates of alert rules.
+var RuleAggregates = []string{AggregateSum, AggregateAvg, AggregateMin, AggregateMax, AggregateCount}
+
+// aggregateServerID is the server_id label of the watchdog_alert_firing series of aggregate rules.
+const aggregateServerID = "all"
+
+// isRateOfChange reports whether a condition looks at the values of a window.
+func isRateOfChange(condition string) bool {
+	return condition != ConditionAbove && condition != ConditionBelow
+}
+
+// LoadRules reads a JSON array of alert rules from a file and validates them.
+func LoadRules(path string) ([]models.AlertRule, error) {
+	// #nosec G304 -- the path is given by the operator on the command line.
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read alert rules: %w", err)
+	}
+	var rules []models.AlertRule
+	if err := json.Unmarshal(data, &rules); err != nil {
+		return nil, fmt.Errorf("failed to parse alert rules %s: %w", path, err)
+	}
+	if err := ValidateRules(rules); err != nil {
+		return nil, fmt.Errorf("invalid alert rules %s: %w", path, err)
+	}
+	return rules, nil
+}
+
+// ValidateRules checks that rules have a unique name, a metric or a check and a known
+// condition, and that rate-of-change rules have a window. Check names are not validated here.
+func ValidateRules(rules []models.AlertRule) error {
+	names := make(map[string]bool)
+	for i, rule := range rules {
+		switch {
+		case rule.Name == "":
+			return fmt.Errorf("rule %d has no name", i+1)
+		case names[rule.Name]:
+			return fmt.Errorf("duplicate rule name %q", rule.Name)
+		case checks[rule.Name].TitleKey != "":
+			return fmt.Errorf("rule name %q is the name of a built-in check", rule.Name)
+		case rule.Metric == "" && rule.Check == "":
+			return fmt.Errorf("rule %q has no metric or check", rule.Name)
+		case rule.Metric != "" && rule.Check != "":
+			return fmt.Errorf("rule %q has both a metric and a check", rule.Name)
+		case rule.For.Std() < 0:
+			return fmt.Errorf("rule %q has a negative for duration", rule.Name)
+		case !contains(RuleConditions, rule.Condition):
+			return fmt.Errorf("rule %q has unknown condition %q (expected one of %s)", rule.Name, rule.Condition, strings.Join(RuleConditions, ", "))
+		case isRateOfChange(rule.Condition) && rule.Window.Std() <= 0:
+			return fmt.Errorf("rule %q needs a window for condition %q", rule.Name, rule.Condition)
+		case rule.Severity != "" && !contains([]string{"critical", "error", "warning"}, rule.Severity):
+			return fmt.Errorf("rule %q has unknown severity %q", rule.Name, rule.Severity)
+		case rule.Aggregate != "" && !contains(RuleAggregates, rule.Aggregate):
+			return fmt.Errorf("rule %q has unknown aggregate %q (expected one of %s)", rule.Name, rule.Aggregate, strings.Join(RuleAggregates, ", "))
+		case rule.Aggregate == "" && rule.Alerts != nil:
+			return fmt.Errorf("rule %q sets alerts, which only aggregate rules support (servers route the alerts of the other rules)", rule.Name)
+		case rule.Aggregate != "" && rule.ServiceDependent:
+			return fmt.Errorf("rule %q is service dependent, which aggregate rules do not support (they belong to no server)", rule.Name)
+		}
+		names[rule.Name] = true
+	}
+	return nil
+}
+
+func contains(values []string, value string) bool {
+	for _, v := range values {
+		if v == value {
+			return true
+		}
+	}
+	return false
+}
+
+// sample is a value of a series at a point in time.
+type sample struct {
+	at    time.Time
+	value float64
+}
+
+// series is the recent values of a metric with a set of labels.
+type series struct {
+	metric string
+	labels map[string]string
+	// aggregate is set on the series of the aggregate of a rule (see RuleAggregates).
+	aggregate string
+	// samples are in chronological order.
+	samples []sample
+}
+
+// String formats the series as a PromQL selector, e.g. `vehicle_count_api{server_id="1"}`,
+// or as an aggregation, e.g. `count(oba_api_status)`.
+func (s *series) String() string {
+	if s.aggregate != "" {
+		return s.aggregate + "(" + (&series{metric: s.metric, labels: s.labels}).String() + ")"
+	}
+	names := make([]string, 0, len(s.labels))
+	for name := range s.labels {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	pairs := make([]string, len(names))
+	for i, name := range names {
+		pairs[i] = fmt.Sprintf("%s=%q", name, s.labels[name])
+	}
+	if len(pairs) == 0 {
+		return s.metric
+	}
+	return s.metric + "{" + strings.Join(pairs, ",") + "}"
+}
+
+// evaluate applies the condition of a rule to the series at now. value is the value compared
+// to the threshold: the last value, or its change over the window.
+func (s *series) evaluate(rule models.AlertRule, threshold float64, now time.Time) (value float64, firing bool) {
+	last := s.samples[len(s.samples)-1].value
+	if !isRateOfChange(rule.Condition) {
+		if rule.Condition == ConditionAbove {
+			return last, last > threshold
+		}
+		return last, last < threshold
+	}
+
+	lowest, highest := last, last
+	for _, sample := range s.samples {
+		if now.Sub(sample.at) > rule.Window.Std() {
+			continue
+		}
+		lowest, highest = min(lowest, sample.value), max(highest, sample.value)
+	}
+	switch rule.Condition {
+	case ConditionIncreaseAbove:
+		value = last - lowest
+	case ConditionDecreaseAbove:
+		value = highest - last
+	case ConditionRisePercent:
+		if lowest > 0 {
+			value = (last - lowest) / lowest * 100
+		}
+	case ConditionDropPercent:
+		if highest > 0 {
+			value = (highest - last) / highest * 100
+		}
+	case ConditionChanged:
+		if lowest != highest {
+			return 1, true
+		}
+		return 0, false
+	}
+	return value, value > threshold
+}
+
+// RuleEvaluator evaluates alert rules (models.AlertRule) over the recent values of the metrics
+// exported by the watchdog. After every collection cycle, Evaluate records the current value of
+// every series of the metrics used by the rules, then evaluates each rule on each series and
+// passes the result to the Manager, which notifies like for the built-in checks.
+//
+// Rules on a check rather than a metric are evaluated over the values recorded with RecordCheck
+// as the checks run, one series per server, e.g. `gtfs_rt_feed{server_id="1"}`.
+//
+// A rule with a `for` duration fires once its condition has held on every evaluation for that
+// long, so that a brief breach does not notify; it resolves as soon as the condition stops
+// holding.
+//
+// Aggregate rules instead combine the series of all servers into a single series per rule,
+// e.g. the number of servers down (the sum of `watchdog_alert_firing{check="api_down"}`). Their
+// alerts are attributed to no server, and are routed with the rule's own `alerts` settings.
+//
+// A series is attributed to a server by its `server_id` label, or by its `server` label (the
+// agency ID used by the OBA API metrics); series of no configured server are ignored. Servers
+// can mute rules, or override their threshold and cooldown, in `alerts.checks` like checks.
+//
+// Values are kept for the longest window of the rules, in memory.
+type RuleEvaluator struct {
+	rules     []models.AlertRule
+	manager   *Manager
+	retention time.Duration
+
+	mu     sync.Mutex
+	series map[string]*series
+	// checks holds the series of the values of the checks used by the rules (see RecordCheck).
+	checks map[string]*series
+	// aggregates holds the series of the aggregate rules, by rule name.
+	aggregates map[string]*series
+	// pending holds since when the condition of a rule with a `for` duration holds for a
+	// series, by rule name and series.
+	pending map[string]time.Time
+}
+
+// NewRuleEvaluator creates a RuleEvaluator notifying through manager.
+// Returns nil (rules disabled) if there are no rules.
+func NewRuleEvaluator(rules []models.AlertRule, manager *Manager) *RuleEvaluator {
+	if len(rules) == 0 {
+		return nil
+	}
+	e := &RuleEvaluator{
+		rules:      rules,
+		manager:    manager,
+		series:     make(map[string]*series),
+		checks:     make(map[string]*series),
+		aggregates: make(map[string]*series),
+		pending:    make(map[string]time.Time),
+	}
+	for _, rule := range rules {
+		e.retention = max(e.retention, rule.Window.Std())
+	}
+	return e
+}
+
+// RecordCheck records a value of a check of a server, for the rules on the check. Values of
+// checks without rules are ignored. A nil *RuleEvaluator does nothing.
+func (e *RuleEvaluator) RecordCheck(serverID int, check string, value float64, at time.Time) {
+	if e == nil || !slices.ContainsFunc(e.rules, func(rule models.AlertRule) bool { return rule.Check == check }) {
+		return
+	}
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	s := &series{metric: check, labels: map[string]string{"server_id": strconv.Itoa(serverID)}}
+	key := s.String()
+	if existing, ok := e.checks[key]; ok {
+		s = existing
+	} else {
+		e.checks[key] = s
+	}
+	s.samples = append(s.samples, sample{at: at, value: value})
+	s.prune(at, e.retention)
+}
+
+// source returns the series a rule is evaluated on, and the name of their metric or check.
+func (e *RuleEvaluator) source(rule models.AlertRule) (map[string]*series, string) {
+	if rule.Check != "" {
+		return e.checks, rule.Check
+	}
+	return e.series, rule.Metric
+}
+
+// held applies the `for` duration of a rule to the result of its condition on a series
+// (identified by key): it reports whether the condition has held for long enough.
+func (e *RuleEvaluator) held(rule models.AlertRule, key string, firing bool, now time.Time, touched map[string]bool) bool {
+	pendingKey := rule.Name + " " + key
+	touched[pendingKey] = true
+	if !firing {
+		delete(e.pending, pendingKey)
+		return false
+	}
+	since, ok := e.pending[pendingKey]
+	if !ok {
+		since = now
+		e.pending[pendingKey] = since
+	}
+	return now.Sub(since) >= rule.For.Std()
+}
+
+// Evaluate records the current values of the metrics of the rules from gatherer, and evaluates
+// the rules for servers. A nil *RuleEvaluator does nothing.
+func (e *RuleEvaluator) Evaluate(gatherer prometheus.Gatherer, servers []models.ObaServer, now time.Time) error {
+	if e == nil {
+		return nil
+	}
+	families, err := gatherer.Gather()
+	if err != nil {
+		return fmt.Errorf("failed to gather metrics for alert rules: %w", err)
+	}
+
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	e.record(families, now)
+
+	byID := make(map[string]models.ObaServer, len(servers))
+	byAgency := make(map[string]models.ObaServer, len(servers))
+	for _, server := range servers {
+		byID[strconv.Itoa(server.ID)] = server
+		if server.AgencyID != "" {
+			byAgency[server.AgencyID] = server
+		}
+	}
+
+	// The series of the checks of servers that are no longer configured are forgotten.
+	for key, s := range e.checks {
+		if _, ok := byID[s.labels["server_id"]]; !ok {
+			delete(e.checks, key)
+		}
+	}
+
+	touched := make(map[string]bool)
+	for _, rule := range e.rules {
+		if rule.Aggregate != "" {
+			e.evaluateAggregate(rule, now, touched)
+			continue
+		}
+		// A rule fires for a server if it fires for any of the server's series.
+		firingServers := make(map[int]bool)
+		source, name := e.source(rule)
+		for key, s := range source {
+			if s.metric != name || !matchLabels(s.labels, rule.Labels) {
+				continue
+			}
+			server, ok := byID[s.labels["server_id"]]
+			if !ok {
+				if server, ok = byAgency[s.labels["server"]]; !ok {
+					continue
+				}
+			}
+			firing := e.manager.observeRule(server, rule, key, s, now, func(firing bool) bool {
+				return e.held(rule, key, firing, now, touched)
+			})
+			firingServers[server.ID] = firingServers[server.ID] || firing
+		}
+		for serverID, firing := range firingServers {
+			value := 0.0
+			if firing {
+				value = 1
+			}
+			AlertFiringGauge.WithLabelValues(strconv.Itoa(serverID), rule.Name).Set(value)
+		}
+	}
+	// Series that were not evaluated, e.g. of removed servers, start over if they come back.
+	for key := range e.pending {
+		if !touched[key] {
+			delete(e.pending, key)
+		}
+	}
+	return nil
+}
+
+// evaluateAggregate appends the aggregate of the current series of the metric of a rule to the
+// rule's aggregate series, and evaluates the rule on it. Avg, min and max are not evaluated
+// while the metric has no series.
+func (e *RuleEvaluator) evaluateAggregate(rule models.AlertRule, now time.Time, touched map[string]bool) {
+	var values []float64
+	source, name := e.source(rule)
+	for _, s := range source {
+		if s.metric == name && matchLabels(s.labels, rule.Labels) {
+			values = append(values, s.samples[len(s.samples)-1].value)
+		}
+	}
+	value := float64(len(values))
+	switch rule.Aggregate {
+	case AggregateSum, AggregateAvg:
+		value = 0
+		for _, v := range values {
+			value += v
+		}
+		if rule.Aggregate == AggregateAvg && len(values) > 0 {
+			value /= float64(len(values))
+		}
+	case AggregateMin, AggregateMax:
+		for i, v := range values {
+			if i == 0 || rule.Aggregate == AggregateMin && v < value || rule.Aggregate == AggregateMax && v > value {
+				value = v
+			}
+		}
+	}
+	if len(values) == 0 && rule.Aggregate != AggregateSum && rule.Aggregate != AggregateCount {
+		return
+	}
+
+	s, ok := e.aggregates[rule.Name]
+	if !ok {
+		s = &series{metric: rule.Metric, labels: rule.Labels, aggregate: rule.Aggregate}
+		e.aggregates[rule.Name] = s
+	}
+	s.samples = append(s.samples, sample{at: now, value: value})
+	s.prune(now, e.retention)
+
+	all := models.ObaServer{Name: "all servers", Alerts: rule.Alerts}
+	firing := 0.0
+	held := func(firing bool) bool { return e.held(rule, "", firing, now, touched) }
+	if e.manager.observeRule(all, rule, "", s, now, held) {
+		firing = 1
+	}
+	AlertFiringGauge.WithLabelValues(aggregateServerID, rule.Name).Set(firing)
+}
+
+// prune forgets the samples older than retention, keeping at least the last one.
+func (s *series) prune(now time.Time, retention time.Duration) {
+	i := 0
+	for i < len(s.samples)-1 && now.Sub(s.samples[i].at) > retention {
+		i++
+	}
+	s.samples = s.samples[i:]
+}
+
+// record appends the values of the metrics of the rules, and forgets values older than the
+// retention and series that are no longer exported.
+func (e *RuleEvaluator) record(families []*dto.MetricFamily, now time.Time) {
+	metrics := make(map[string]bool, len(e.rules))
+	for _, rule := range e.rules {
+		if rule.Metric != "" {
+			metrics[rule.Metric] = true
+		}
+	}
+
+	seen := make(map[string]bool)
+	for _, family := range families {
+		if !metrics[family.GetName()] {
+			continue
+		}
+		for _, metric := range family.GetMetric() {
+			var value float64
+			switch {
+			case metric.GetGauge() != nil:
+				value = metric.GetGauge().GetValue()
+			case metric.GetCounter() != nil:
+				value = metric.GetCounter().GetValue()
+			case metric.GetUntyped() != nil:
+				value = metric.GetUntyped().GetValue()
+			default:
+				continue
+			}
+			s := &series{metric: family.GetName(), labels: make(map[string]string, len(metric.GetLabel()))}
+			for _, label := range metric.GetLabel() {
+				s.labels[label.GetName()] = label.GetValue()
+			}
+			key := s.String()
+			if existing, ok := e.series[key]; ok {
+				s = existing
+			} else {
+				e.series[key] = s
+			}
+			s.samples = append(s.samples, sample{at: now, value: value})
+			seen[key] = true
+		}
+	}
+
+	for key, s := range e.series {
+		if !seen[key] {
+			delete(e.series, key)
+			continue
+		}
+		s.prune(now, e.retention)
+	}
+}
+
+// matchLabels reports whether labels have the values of matchers.
+func matchLabels(labels, matchers map[string]string) bool {
+	for name, value := range matchers {
+		if labels[name] != value {
+			return false
+		}
+	}
+	return true
+}
+
+// observeRule evaluates a rule on a series of a server (identified by key) and sends
+// notifications like Observe. held applies the `for` duration of the rule to the result of its
+// condition. It returns whether the rule is firing for the series.
+func (m *Manager) observeRule(server models.ObaServer, rule models.AlertRule, key string, s *series, now time.Time, held func(firing bool) bool) bool {
+	if m == nil || rule.ServiceDependent && m.serviceReduced(server.ID) {
+		return false
+	}
+	override := server.Alerts.Check(rule.Name)
+	threshold := rule.Threshold
+	if override.Threshold != nil {
+		threshold = *override.Threshold
+	}
+	cooldown := m.cooldown
+	if override.Cooldown != nil {
+		cooldown = override.Cooldown.Std()
+	}
+	severity := rule.Severity
+	if severity == "" {
+		severity = "warning"
+	}
+	title := rule.Title
+	if title == "" {
+		title = rule.Name
+	}
+	value, firing := s.evaluate(rule, threshold, now)
+	firing = held(firing)
+	switch rule.Condition {
+	case ConditionAbove:
+		m.history.record(server.ID, rule.Name+" "+key, rule.Name, s.String(), threshold, 99, value, now)
+	case ConditionBelow:
+		m.history.record(server.ID, rule.Name+" "+key, rule.Name, s.String(), threshold, 1, value, now)
+	}
+
+	m.evaluate(observation{
+		server:    server,
+		check:     rule.Name,
+		key:       rule.Name + " " + key,
+		firing:    firing,
+		value:     value,
+		threshold: threshold,
+		cooldown:  cooldown,
+		muted:     override.Disabled,
+		severity:  severity,
+		title:     func(string) string { return title },
+		describe: func(locale string) string {
+			return describeRule(locale, rule, s.String(), value, threshold)
+		},
+	})
+	return firing
+}
+
+// describeRule explains the value of a rule for a series in the given locale.
+func describeRule(locale string, rule models.AlertRule, series string, value, threshold float64) string {
+	window := rule.Window.Std().String()
+	switch rule.Condition {
+	case ConditionAbove, ConditionBelow:
+		return i18n.T(locale, "alert.rule."+rule.Condition, series, formatThreshold(value), formatThreshold(threshold))
+	case ConditionRisePercent, ConditionDropPercent:
+		return i18n.T(locale, "alert.rule."+rule.Condition, series, strconv.FormatFloat(value, 'f', 0, 64)+"%", window)
+	case ConditionChanged:
+		return i18n.T(locale, "alert.rule."+rule.Condition, series, window)
+	default:
+		return i18n.T(locale, "alert.rule."+rule.Condition, series, formatThreshold(value), window)
+	}
+}
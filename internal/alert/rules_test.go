@@ -0,0 +1,210 @@
+package alert
+
+import (
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"watchdog.onebusaway.org/internal/models"
+)
+
+func TestLoadRules(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "rules.json")
+	content := `[{"name": "vehicles_drop", "metric": "vehicle_count_api", "condition": "drop_percent", "threshold": 50, "window": "10m"}]`
+	if err := os.WriteFile(path, []byte(content), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	rules, err := LoadRules(path)
+	if err != nil || len(rules) != 1 || rules[0].Window.Std() != 10*time.Minute {
+		t.Fatalf("unexpected rules %+v, %v", rules, err)
+	}
+
+	invalid := map[string][]models.AlertRule{
+		"has no name":       {{Metric: "m", Condition: ConditionAbove}},
+		"duplicate":         {{Name: "a", Metric: "m", Condition: ConditionAbove}, {Name: "a", Metric: "m", Condition: ConditionAbove}},
+		"built-in check":    {{Name: CheckAPIDown, Metric: "m", Condition: ConditionAbove}},
+		"has no metric":     {{Name: "a", Condition: ConditionAbove}},
+		"both a metric":     {{Name: "a", Metric: "m", Check: "gtfs_rt_feed", Condition: ConditionAbove}},
+		"negative for":      {{Name: "a", Check: "gtfs_rt_feed", Condition: ConditionAbove, For: models.Duration(-time.Minute)}},
+		"unknown condition": {{Name: "a", Metric: "m", Condition: "derivative"}},
+		"needs a window":    {{Name: "a", Metric: "m", Condition: ConditionChanged}},
+		"unknown severity":  {{Name: "a", Metric: "m", Condition: ConditionAbove, Severity: "page"}},
+		"unknown aggregate": {{Name: "a", Metric: "m", Condition: ConditionAbove, Aggregate: "median"}},
+		"only aggregate":    {{Name: "a", Metric: "m", Condition: ConditionAbove, Alerts: &models.AlertConfig{}}},
+	}
+	for want, rules := range invalid {
+		if err := ValidateRules(rules); err == nil || !strings.Contains(err.Error(), want) {
+			t.Errorf("expected an error containing %q, got %v", want, err)
+		}
+	}
+}
+
+func TestRuleEvaluator(t *testing.T) {
+	m, notifier, now := newTestManager(t, time.Hour)
+	registry := prometheus.NewRegistry()
+	vehicles := prometheus.NewGaugeVec(prometheus.GaugeOpts{Name: "test_vehicles"}, []string{"server_id"})
+	agencies := prometheus.NewGaugeVec(prometheus.GaugeOpts{Name: "test_agencies"}, []string{"server"})
+	registry.MustRegister(vehicles, agencies)
+
+	servers := []models.ObaServer{{ID: 1, Name: "Server 1", AgencyID: "unitrans"}, {ID: 2, Name: "Server 2"}}
+	e := NewRuleEvaluator([]models.AlertRule{
+		{Name: "vehicles_drop", Title: "Vehicle count dropped", Metric: "test_vehicles", Condition: ConditionDropPercent, Threshold: 50, Window: models.Duration(10 * time.Minute)},
+		{Name: "agencies_changed", Metric: "test_agencies", Condition: ConditionChanged, Window: models.Duration(time.Hour)},
+	}, m)
+
+	evaluate := func() {
+		t.Helper()
+		if err := e.Evaluate(registry, servers, *now); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	vehicles.WithLabelValues("1").Set(100)
+	vehicles.WithLabelValues("2").Set(100)
+	agencies.WithLabelValues("unitrans").Set(3)
+	evaluate()
+
+	// Server 1 loses 60% of its vehicles in 5 minutes; server 2 loses 40%.
+	*now = now.Add(5 * time.Minute)
+	vehicles.WithLabelValues("1").Set(40)
+	vehicles.WithLabelValues("2").Set(60)
+	evaluate()
+	if len(notifier.alerts) != 1 {
+		t.Fatalf("expected one alert, got %+v", notifier.alerts)
+	}
+	got := notifier.alerts[0]
+	if got.Server.ID != 1 || got.Check != "vehicles_drop" || got.Title != "Vehicle count dropped" || got.Value != 60 || got.Severity != "warning" {
+		t.Errorf("unexpected alert %+v", got)
+	}
+	if got.Description != `test_vehicles{server_id="1"} dropped by 60% within 10m0s` {
+		t.Errorf("unexpected description %q", got.Description)
+	}
+
+	// 100 leaves the window: the drop resolves although the count stays low.
+	*now = now.Add(6 * time.Minute)
+	evaluate()
+	if len(notifier.alerts) != 2 || notifier.alerts[1].Status != StatusResolved {
+		t.Fatalf("expected the drop to resolve, got %+v", notifier.alerts)
+	}
+
+	// Series with a server label are attributed by agency ID.
+	agencies.WithLabelValues("unitrans").Set(4)
+	evaluate()
+	if len(notifier.alerts) != 3 || notifier.alerts[2].Check != "agencies_changed" || notifier.alerts[2].Server.ID != 1 {
+		t.Fatalf("expected the agencies count change to fire, got %+v", notifier.alerts)
+	}
+
+	// Rules can be muted per server like checks.
+	servers[1].Alerts = &models.AlertConfig{Checks: map[string]models.AlertCheckConfig{"vehicles_drop": {Disabled: true}}}
+	vehicles.WithLabelValues("2").Set(0)
+	*now = now.Add(time.Minute)
+	evaluate()
+	if len(notifier.alerts) != 3 {
+		t.Errorf("expected the muted rule not to notify, got %+v", notifier.alerts[3:])
+	}
+}
+
+func TestAggregateRule(t *testing.T) {
+	m, notifier, now := newTestManager(t, time.Hour)
+	registry := prometheus.NewRegistry()
+	firing := prometheus.NewGaugeVec(prometheus.GaugeOpts{Name: "test_firing"}, []string{"server_id", "check"})
+	registry.MustRegister(firing)
+
+	routing := &models.AlertConfig{SlackWebhookURL: "https://hooks.slack.com/services/infra"}
+	e := NewRuleEvaluator([]models.AlertRule{{
+		Name:      "servers_down",
+		Metric:    "test_firing",
+		Labels:    map[string]string{"check": CheckAPIDown},
+		Aggregate: AggregateSum,
+		Condition: ConditionAbove,
+		Threshold: 2,
+		Severity:  "critical",
+		Alerts:    routing,
+	}}, m)
+	servers := []models.ObaServer{{ID: 1}, {ID: 2}, {ID: 3}}
+
+	for i, server := range servers {
+		firing.WithLabelValues(strconv.Itoa(server.ID), CheckAPIDown).Set(float64(i % 2))
+		firing.WithLabelValues(strconv.Itoa(server.ID), CheckBundleExpiration).Set(1)
+	}
+	if err := e.Evaluate(registry, servers, *now); err != nil {
+		t.Fatal(err)
+	}
+	if len(notifier.alerts) != 0 {
+		t.Fatalf("expected no alert with one server down, got %+v", notifier.alerts)
+	}
+
+	for _, server := range servers {
+		firing.WithLabelValues(strconv.Itoa(server.ID), CheckAPIDown).Set(1)
+	}
+	*now = now.Add(time.Minute)
+	if err := e.Evaluate(registry, servers, *now); err != nil {
+		t.Fatal(err)
+	}
+	if len(notifier.alerts) != 1 {
+		t.Fatalf("expected a single alert for all servers, got %+v", notifier.alerts)
+	}
+	got := notifier.alerts[0]
+	if got.Server.ID != 0 || got.Server.Alerts != routing || got.Value != 3 || got.Severity != "critical" {
+		t.Errorf("unexpected alert %+v", got)
+	}
+	if got.Description != `sum(test_firing{check="api_down"}) is 3, above 2` {
+		t.Errorf("unexpected description %q", got.Description)
+	}
+}
+
+func TestCheckRuleWithDuration(t *testing.T) {
+	m, notifier, now := newTestManager(t, time.Hour)
+	registry := prometheus.NewRegistry()
+	e := NewRuleEvaluator([]models.AlertRule{{
+		Name:      "feed_stale",
+		Check:     "gtfs_rt_feed",
+		Condition: ConditionAbove,
+		Threshold: 300,
+		For:       models.Duration(10 * time.Minute),
+	}}, m)
+	servers := []models.ObaServer{{ID: 1, Name: "Server 1"}, {ID: 2, Name: "Server 2"}}
+
+	step := func(age1, age2 float64) {
+		t.Helper()
+		e.RecordCheck(1, "gtfs_rt_feed", age1, *now)
+		e.RecordCheck(2, "gtfs_rt_feed", age2, *now)
+		e.RecordCheck(1, "server_ping", 1, *now)
+		if err := e.Evaluate(registry, servers, *now); err != nil {
+			t.Fatal(err)
+		}
+		*now = now.Add(5 * time.Minute)
+	}
+
+	// The feed of server 1 is stale for 10 minutes before the rule fires; that of server 2 is
+	// stale for 5 minutes only.
+	step(400, 400)
+	step(420, 100)
+	if len(notifier.alerts) != 0 {
+		t.Fatalf("expected no alert before the duration, got %+v", notifier.alerts)
+	}
+	step(450, 400)
+	if len(notifier.alerts) != 1 {
+		t.Fatalf("expected one alert, got %+v", notifier.alerts)
+	}
+	got := notifier.alerts[0]
+	if got.Server.ID != 1 || got.Check != "feed_stale" || got.Value != 450 {
+		t.Errorf("unexpected alert %+v", got)
+	}
+	if got.Description != `gtfs_rt_feed{server_id="1"} is 450, above 300` {
+		t.Errorf("unexpected description %q", got.Description)
+	}
+
+	// The rule resolves as soon as the condition stops holding.
+	step(30, 400)
+	if len(notifier.alerts) != 2 || notifier.alerts[1].Status != StatusResolved {
+		t.Fatalf("expected the rule to resolve, got %+v", notifier.alerts)
+	}
+	if len(e.checks) != 2 {
+		t.Errorf("expected only the series of the checks with rules, got %d", len(e.checks))
+	}
+}
@@ -0,0 +1,111 @@
+package alert
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+
+	"watchdog.onebusaway.org/internal/i18n"
+)
+
+// SlackNotifier posts alerts to a Slack incoming webhook.
+//
+// Each server can route its alerts to its own channel with `alerts.slack_webhook_url`;
+// other servers use the default webhook. Alerts for servers without any webhook are skipped.
+type SlackNotifier struct {
+	webhookURL string
+	client     *http.Client
+}
+
+// NewSlackNotifier creates a Slack notifier with a default webhook URL (may be empty
+// if every server that should alert sets its own).
+func NewSlackNotifier(webhookURL string, client *http.Client) *SlackNotifier {
+	return &SlackNotifier{webhookURL: webhookURL, client: client}
+}
+
+func (n *SlackNotifier) Name() string {
+	return "slack"
+}
+
+// slackMessage is the subset of the Slack message payload used for alerts.
+type slackMessage struct {
+	Text        string            `json:"text"`
+	Attachments []slackAttachment `json:"attachments,omitempty"`
+}
+
+type slackAttachment struct {
+	Color  string       `json:"color"`
+	Fields []slackField `json:"fields"`
+	Ts     int64        `json:"ts"`
+}
+
+type slackField struct {
+	Title string `json:"title"`
+	Value string `json:"value"`
+	Short bool   `json:"short"`
+}
+
+// newSlackMessage formats an alert in its locale: a one-line summary with an emoji for the status,
+// and a colored attachment with the details.
+func newSlackMessage(alert Alert) slackMessage {
+	emoji, color := ":rotating_light:", "danger"
+	if alert.Status == StatusResolved {
+		emoji, color = ":white_check_mark:", "good"
+	}
+	status := i18n.T(alert.Locale, "alert.status."+string(alert.Status))
+	text := fmt.Sprintf("%s *[%s] %s* — %s", emoji, status, alert.Title, alert.Server.Name)
+
+	fields := []slackField{
+		{Title: i18n.T(alert.Locale, "alert.field.server"), Value: fmt.Sprintf("%s (id %d)", alert.Server.Name, alert.Server.ID), Short: true},
+		{Title: i18n.T(alert.Locale, "alert.field.check"), Value: alert.Check, Short: true},
+		{Title: i18n.T(alert.Locale, "alert.field.details"), Value: alert.Description, Short: false},
+	}
+	if alert.Server.ObaBaseURL != "" {
+		fields = append(fields, slackField{Title: i18n.T(alert.Locale, "alert.field.oba"), Value: alert.Server.ObaBaseURL, Short: true})
+	}
+	if len(alert.Vantages) > 0 {
+		fields = append(fields, slackField{Title: i18n.T(alert.Locale, "alert.field.vantages"), Value: formatVantages(alert.Locale, alert.Vantages), Short: false})
+	}
+	if alert.Status == StatusFiring {
+		fields = append(fields, slackField{Title: i18n.T(alert.Locale, "alert.field.threshold"), Value: formatThreshold(alert.Threshold), Short: true})
+	} else {
+		fields = append(fields, slackField{Title: i18n.T(alert.Locale, "alert.field.firing_since"), Value: alert.StartsAt.UTC().Format("2006-01-02 15:04 MST"), Short: true})
+	}
+
+	return slackMessage{
+		Text:        text,
+		Attachments: []slackAttachment{{Color: color, Fields: fields, Ts: alert.At.Unix()}},
+	}
+}
+
+func (n *SlackNotifier) Notify(ctx context.Context, alert Alert) error {
+	webhookURL := n.webhookURL
+	if alert.Server.Alerts != nil && alert.Server.Alerts.SlackWebhookURL != "" {
+		webhookURL = alert.Server.Alerts.SlackWebhookURL
+	}
+	if webhookURL == "" {
+		return nil
+	}
+
+	body, err := json.Marshal(newSlackMessage(alert))
+	if err != nil {
+		return fmt.Errorf("failed to encode Slack message: %w", err)
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, webhookURL, bytes.NewReader(body))
+	if err != nil {
+		return fmt.Errorf("failed to create Slack request: %w", err)
+	}
+	req.Header.Set("Content-Type", "application/json")
+
+	resp, err := n.client.Do(req)
+	if err != nil {
+		return fmt.Errorf("failed to post to Slack: %w", err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("slack webhook returned status: %d", resp.StatusCode)
+	}
+	return nil
+}
@@ -0,0 +1,86 @@
+package alert
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"watchdog.onebusaway.org/internal/models"
+)
+
+func TestSlackNotifier(t *testing.T) {
+	var received []string
+	var message slackMessage
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		received = append(received, r.URL.Path)
+		if err := json.NewDecoder(r.Body).Decode(&message); err != nil {
+			t.Errorf("failed to decode Slack message: %v", err)
+		}
+		w.WriteHeader(http.StatusOK)
+	}))
+	defer server.Close()
+
+	alert := Alert{
+		Server:      models.ObaServer{ID: 1, Name: "Test Server"},
+		Check:       CheckAPIDown,
+		Title:       checks[CheckAPIDown].title("en"),
+		Status:      StatusFiring,
+		Value:       2,
+		Threshold:   2,
+		Description: "2 consecutive failed pings",
+		At:          time.Now(),
+	}
+
+	notifier := NewSlackNotifier(server.URL+"/default", server.Client())
+	if err := notifier.Notify(context.Background(), alert); err != nil {
+		t.Fatalf("Notify failed: %v", err)
+	}
+	if !strings.Contains(message.Text, "OBA API is down") || len(message.Attachments) != 1 || message.Attachments[0].Color != "danger" {
+		t.Errorf("unexpected Slack message %+v", message)
+	}
+
+	alert.Server.Alerts = &models.AlertConfig{SlackWebhookURL: server.URL + "/agency"}
+	alert.Status = StatusResolved
+	if err := notifier.Notify(context.Background(), alert); err != nil {
+		t.Fatalf("Notify failed: %v", err)
+	}
+	if message.Attachments[0].Color != "good" {
+		t.Errorf("expected a resolved message, got %+v", message)
+	}
+
+	alert.Locale = "es"
+	if err := notifier.Notify(context.Background(), alert); err != nil {
+		t.Fatalf("Notify failed: %v", err)
+	}
+	if !strings.Contains(message.Text, "[resuelta]") || message.Attachments[0].Fields[0].Title != "Servidor" {
+		t.Errorf("expected a Spanish message, got %+v", message)
+	}
+
+	if len(received) != 3 || received[0] != "/default" || received[1] != "/agency" {
+		t.Errorf("expected the server's webhook to override the default, got %v", received)
+	}
+
+	// Without any webhook, the alert is skipped.
+	if err := NewSlackNotifier("", server.Client()).Notify(context.Background(), Alert{}); err != nil {
+		t.Errorf("expected no error without a webhook, got %v", err)
+	}
+	if len(received) != 3 {
+		t.Errorf("expected no request without a webhook, got %v", received)
+	}
+}
+
+func TestSlackNotifierError(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusForbidden)
+	}))
+	defer server.Close()
+
+	err := NewSlackNotifier(server.URL, server.Client()).Notify(context.Background(), Alert{Status: StatusFiring})
+	if err == nil {
+		t.Fatal("expected an error for a non-200 response")
+	}
+}
@@ -0,0 +1,168 @@
+package alert
+
+import (
+	"cmp"
+	"fmt"
+	"math"
+	"slices"
+	"strconv"
+	"sync"
+	"time"
+)
+
+const (
+	// SuggestionWindow is the history of observed values threshold suggestions are based on.
+	SuggestionWindow = 30 * 24 * time.Hour
+	// suggestionSampleInterval is the minimum time between two recorded values of a series, so
+	// that a month of history stays small whatever the collection interval.
+	suggestionSampleInterval = 10 * time.Minute
+	// minSuggestionSamples is the number of values (a day) needed before suggesting a threshold.
+	minSuggestionSamples = 144
+	// minSuggestionChange is the relative difference from the current threshold below which
+	// no suggestion is made.
+	minSuggestionChange = 0.05
+)
+
+// ThresholdSuggestion is a threshold adjustment suggested from the history of a check's
+// values, for a human to review and apply to the configuration.
+type ThresholdSuggestion struct {
+	ServerID int
+	Check    string
+	// Series is the series of an alert rule, empty for built-in checks.
+	Series    string
+	Current   float64
+	Suggested float64
+	// Percentile of the recorded values the suggestion is, and the number of values.
+	Percentile float64
+	Samples    int
+	// Since is when the oldest value was recorded.
+	Since time.Time
+	// Reason summarizes the suggestion, e.g. "vehicles_dropped threshold 0.8 → 0.93 based on
+	// p1 of the last 30 days".
+	Reason string
+}
+
+// valueSeries is the recorded history of the values of a check (or rule series) of a server.
+type valueSeries struct {
+	serverID   int
+	check      string
+	series     string
+	threshold  float64
+	percentile float64
+	samples    []valueSample
+}
+
+type valueSample struct {
+	at    time.Time
+	value float64
+}
+
+// valueHistory keeps the values of the checks that thresholds can be suggested for, sampled
+// every suggestionSampleInterval over SuggestionWindow. It is kept in memory, so suggestions
+// need a day of uptime after a restart.
+type valueHistory struct {
+	mu     sync.Mutex
+	series map[stateKey]*valueSeries
+}
+
+func newValueHistory() *valueHistory {
+	return &valueHistory{series: make(map[stateKey]*valueSeries)}
+}
+
+// record adds a value of a series of a server (identified by key), unless a value was
+// recorded within suggestionSampleInterval, and forgets values older than SuggestionWindow.
+// percentile is the share of values (in percent) at which a threshold is suggested.
+func (h *valueHistory) record(serverID int, key, check, series string, threshold, percentile, value float64, at time.Time) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	k := stateKey{serverID: serverID, check: key}
+	s := h.series[k]
+	if s == nil {
+		s = &valueSeries{serverID: serverID, check: check, series: series}
+		h.series[k] = s
+	}
+	s.threshold, s.percentile = threshold, percentile
+	if n := len(s.samples); n > 0 && at.Sub(s.samples[n-1].at) < suggestionSampleInterval {
+		return
+	}
+	s.samples = append(s.samples, valueSample{at: at, value: value})
+	expired := 0
+	for expired < len(s.samples) && at.Sub(s.samples[expired].at) > SuggestionWindow {
+		expired++
+	}
+	s.samples = s.samples[expired:]
+}
+
+// suggest returns the thresholds that differ from the percentile of their recorded values.
+func (h *valueHistory) suggest(now time.Time) []ThresholdSuggestion {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	var suggestions []ThresholdSuggestion
+	for _, s := range h.series {
+		var values []float64
+		var since time.Time
+		for _, sample := range s.samples {
+			if now.Sub(sample.at) > SuggestionWindow {
+				continue
+			}
+			if since.IsZero() {
+				since = sample.at
+			}
+			values = append(values, sample.value)
+		}
+		if len(values) < minSuggestionSamples {
+			continue
+		}
+		suggested := roundSuggestion(percentile(values, s.percentile))
+		if math.Abs(suggested-s.threshold) <= minSuggestionChange*math.Abs(s.threshold) {
+			continue
+		}
+		days := int(math.Ceil(now.Sub(since).Hours() / 24))
+		suggestions = append(suggestions, ThresholdSuggestion{
+			ServerID:   s.serverID,
+			Check:      s.check,
+			Series:     s.series,
+			Current:    s.threshold,
+			Suggested:  suggested,
+			Percentile: s.percentile,
+			Samples:    len(values),
+			Since:      since,
+			Reason: fmt.Sprintf("%s threshold %s → %s based on p%s of the last %d days",
+				s.check, formatThreshold(s.threshold), formatThreshold(suggested), strconv.FormatFloat(s.percentile, 'f', -1, 64), days),
+		})
+	}
+	slices.SortFunc(suggestions, func(a, b ThresholdSuggestion) int {
+		return cmp.Or(cmp.Compare(a.ServerID, b.ServerID), cmp.Compare(a.Check, b.Check), cmp.Compare(a.Series, b.Series))
+	})
+	return suggestions
+}
+
+// percentile returns the nearest-rank p-th percentile of values.
+func percentile(values []float64, p float64) float64 {
+	sorted := slices.Clone(values)
+	slices.Sort(sorted)
+	rank := int(math.Ceil(p / 100 * float64(len(sorted))))
+	return sorted[min(max(rank-1, 0), len(sorted)-1)]
+}
+
+// roundSuggestion keeps two decimals, enough for ratios and counts alike.
+func roundSuggestion(v float64) float64 {
+	return math.Round(v*100) / 100
+}
+
+// SuggestThresholds analyzes the recorded values of the checks with a threshold and returns
+// the thresholds that no longer fit them: for a check firing below its threshold, the 1st
+// percentile of its values over SuggestionWindow (the value it only drops under in unusual
+// conditions), and for a check firing above, the 99th percentile. Built-in checks are
+// analyzed if they have a meaningful distribution (see checkDefinition.SuggestPercentile),
+// as are alert rules with an absolute condition.
+// Suggestions are only returned once a day of values is recorded, and when they differ from
+// the current threshold by more than 5%. A nil *Manager returns no suggestions.
+func (m *Manager) SuggestThresholds() []ThresholdSuggestion {
+	if m == nil {
+		return nil
+	}
+	return m.history.suggest(m.now())
+}
@@ -0,0 +1,82 @@
+package alert
+
+import (
+	"testing"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"watchdog.onebusaway.org/internal/models"
+)
+
+func TestSuggestThresholds(t *testing.T) {
+	m, _, now := newTestManager(t, time.Hour)
+	server := models.ObaServer{ID: 1}
+
+	// Two days of vehicle count ratios between 0.9 and 1, observed every 5 minutes.
+	for i := range 2 * 24 * 12 {
+		m.Observe(server, CheckVehiclesDropped, 0.9+float64(i%11)/100)
+		// Checks without a meaningful distribution are not analyzed.
+		m.ObserveResult(server, CheckAPIDown, true)
+		*now = now.Add(5 * time.Minute)
+	}
+
+	suggestions := m.SuggestThresholds()
+	if len(suggestions) != 1 {
+		t.Fatalf("expected one suggestion, got %+v", suggestions)
+	}
+	got := suggestions[0]
+	if got.ServerID != 1 || got.Check != CheckVehiclesDropped || got.Current != 0.8 || got.Suggested != 0.9 || got.Samples != 2*24*6 {
+		t.Errorf("unexpected suggestion %+v", got)
+	}
+	if got.Reason != "vehicles_dropped threshold 0.8 → 0.9 based on p1 of the last 2 days" {
+		t.Errorf("unexpected reason %q", got.Reason)
+	}
+
+	// A threshold that fits the values is not reported.
+	threshold := 0.88
+	server.Alerts = &models.AlertConfig{Checks: map[string]models.AlertCheckConfig{CheckVehiclesDropped: {Threshold: &threshold}}}
+	m.Observe(server, CheckVehiclesDropped, 1)
+	if suggestions := m.SuggestThresholds(); len(suggestions) != 0 {
+		t.Errorf("expected no suggestion for a fitting threshold, got %+v", suggestions)
+	}
+
+	// Values past the window are forgotten.
+	*now = now.Add(SuggestionWindow)
+	m.Observe(server, CheckVehiclesDropped, 0.5)
+	if suggestions := m.SuggestThresholds(); len(suggestions) != 0 {
+		t.Errorf("expected no suggestion without enough recent values, got %+v", suggestions)
+	}
+
+	var nilManager *Manager
+	if suggestions := nilManager.SuggestThresholds(); suggestions != nil {
+		t.Errorf("expected no suggestions from a nil manager, got %+v", suggestions)
+	}
+}
+
+func TestSuggestRuleThresholds(t *testing.T) {
+	m, _, now := newTestManager(t, time.Hour)
+	registry := prometheus.NewRegistry()
+	delay := prometheus.NewGaugeVec(prometheus.GaugeOpts{Name: "test_delay"}, []string{"server_id"})
+	registry.MustRegister(delay)
+	servers := []models.ObaServer{{ID: 1}}
+	e := NewRuleEvaluator([]models.AlertRule{
+		{Name: "delay_high", Metric: "test_delay", Condition: ConditionAbove, Threshold: 300},
+		{Name: "delay_jump", Metric: "test_delay", Condition: ConditionIncreaseAbove, Threshold: 60, Window: models.Duration(time.Hour)},
+	}, m)
+
+	for i := range 200 {
+		delay.WithLabelValues("1").Set(float64(i % 100))
+		if err := e.Evaluate(registry, servers, *now); err != nil {
+			t.Fatal(err)
+		}
+		*now = now.Add(10 * time.Minute)
+	}
+
+	suggestions := m.SuggestThresholds()
+	if len(suggestions) != 1 {
+		t.Fatalf("expected a suggestion for the absolute rule only, got %+v", suggestions)
+	}
+	if got := suggestions[0]; got.Check != "delay_high" || got.Series != `test_delay{server_id="1"}` || got.Suggested != 98 || got.Percentile != 99 {
+		t.Errorf("unexpected suggestion %+v", got)
+	}
+}
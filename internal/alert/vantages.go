@@ -0,0 +1,83 @@
+package alert
+
+import (
+	"fmt"
+	"strings"
+	"time"
+
+	"watchdog.onebusaway.org/internal/i18n"
+)
+
+// PrimaryVantage names the watchdog running the checks among the vantage points of an alert.
+const PrimaryVantage = "primary"
+
+// VantageResult is the latest ping of a server from a vantage point: the primary watchdog, or
+// one of its probe agents (see package vantage).
+type VantageResult struct {
+	Vantage string `json:"vantage"`
+	OK      bool   `json:"ok"`
+	Error   string `json:"error,omitempty"`
+}
+
+// SetVantages sets how the manager learns about the latest pings of servers by the probe
+// agents. The vantage-dependent checks (such as CheckAPIDown) of a server then only fire when
+// a majority of the vantage points, the primary included, fail to reach it: a failure seen by
+// a minority is more likely a network issue between them and the server, and is ignored like
+// the observations during a planned service reduction. Their notifications list the results
+// of every vantage point.
+func (m *Manager) SetVantages(results func(serverID int, at time.Time) []VantageResult) {
+	if m == nil {
+		return
+	}
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.vantages = results
+}
+
+// vantageResults returns the results of the primary, whose check is firing or not, and of the
+// probe agents for a server, or nil without results from agents.
+func (m *Manager) vantageResults(serverID int, firing bool) []VantageResult {
+	m.mu.Lock()
+	vantages := m.vantages
+	m.mu.Unlock()
+	if vantages == nil {
+		return nil
+	}
+	remote := vantages(serverID, m.now())
+	if len(remote) == 0 {
+		return nil
+	}
+	return append([]VantageResult{{Vantage: PrimaryVantage, OK: !firing}}, remote...)
+}
+
+// downQuorum reports whether a strict majority of the vantage points failed to reach the
+// server. Without results from agents, the primary decides alone.
+func downQuorum(results []VantageResult) bool {
+	if len(results) == 0 {
+		return true
+	}
+	down := 0
+	for _, result := range results {
+		if !result.OK {
+			down++
+		}
+	}
+	return 2*down > len(results)
+}
+
+// formatVantages lists the results of the vantage points in a locale, e.g.
+// "primary: down, eu-west: up".
+func formatVantages(locale string, results []VantageResult) string {
+	parts := make([]string, len(results))
+	for i, result := range results {
+		status := i18n.T(locale, "health.up")
+		if !result.OK {
+			status = i18n.T(locale, "health.down")
+		}
+		parts[i] = fmt.Sprintf("%s: %s", result.Vantage, status)
+		if result.Error != "" {
+			parts[i] += " (" + result.Error + ")"
+		}
+	}
+	return strings.Join(parts, ", ")
+}
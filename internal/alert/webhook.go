@@ -0,0 +1,204 @@
+package alert
+
+import (
+	"bytes"
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"os"
+	"strconv"
+	"text/template"
+	"time"
+
+	"watchdog.onebusaway.org/internal/config"
+)
+
+const (
+	// WebhookEventType is the `event` field of the default webhook payload.
+	WebhookEventType = "check.state_changed"
+	// WebhookSignatureHeader carries the HMAC-SHA256 signature of a webhook request, as "sha256=<hex>".
+	WebhookSignatureHeader = "X-Watchdog-Signature"
+	// WebhookTimestampHeader carries the Unix time at which a webhook request was signed.
+	WebhookTimestampHeader = "X-Watchdog-Timestamp"
+)
+
+// Check states reported in webhook payloads.
+const (
+	StateHealthy   = "healthy"
+	StateUnhealthy = "unhealthy"
+)
+
+// WebhookPayload describes a change of state of a check. It is the default JSON body of
+// webhook requests, and the data passed to custom body templates.
+type WebhookPayload struct {
+	Event         string    `json:"event"`
+	ServerID      int       `json:"server_id"`
+	ServerName    string    `json:"server_name"`
+	ObaBaseURL    string    `json:"oba_base_url"`
+	Check         string    `json:"check"`
+	Title         string    `json:"title"`
+	Description   string    `json:"description"`
+	Severity      string    `json:"severity"`
+	Status        Status    `json:"status"`
+	PreviousState string    `json:"previous_state"`
+	State         string    `json:"state"`
+	Value         float64   `json:"value"`
+	Threshold     float64   `json:"threshold"`
+	StartsAt      time.Time `json:"starts_at"`
+	At            time.Time `json:"at"`
+	// Vantages are the results of the vantage points of the check, if it depends on them.
+	Vantages []VantageResult `json:"vantages,omitempty"`
+}
+
+func newWebhookPayload(alert Alert) WebhookPayload {
+	previous, state := StateHealthy, StateUnhealthy
+	if alert.Status == StatusResolved {
+		previous, state = StateUnhealthy, StateHealthy
+	}
+	return WebhookPayload{
+		Event:         WebhookEventType,
+		ServerID:      alert.Server.ID,
+		ServerName:    alert.Server.Name,
+		ObaBaseURL:    alert.Server.ObaBaseURL,
+		Check:         alert.Check,
+		Title:         alert.Title,
+		Description:   alert.Description,
+		Severity:      alert.Severity,
+		Status:        alert.Status,
+		PreviousState: previous,
+		State:         state,
+		Value:         alert.Value,
+		Threshold:     alert.Threshold,
+		StartsAt:      alert.StartsAt.UTC(),
+		At:            alert.At.UTC(),
+		Vantages:      alert.Vantages,
+	}
+}
+
+// WebhookNotifier posts a payload to a URL whenever a check changes state (healthy → unhealthy
+// when it starts firing, unhealthy → healthy when it recovers). Reminders of a check that keeps
+// firing are not sent, since they are not a change of state.
+//
+// Each server can post to its own URL with `alerts.webhook_url`; other servers use the default URL.
+// When a secret is set, requests are signed (see sign).
+type WebhookNotifier struct {
+	url        string
+	secret     []byte
+	body       *template.Template
+	client     *http.Client
+	maxRetries int
+	now        func() time.Time
+}
+
+// NewWebhookNotifier creates a webhook notifier posting to url (may be empty if every server that
+// should notify sets its own). If body is nil, the payload is the JSON encoding of WebhookPayload;
+// otherwise it is the output of the template executed with the WebhookPayload.
+// Failed deliveries are retried up to maxRetries times with exponential backoff.
+func NewWebhookNotifier(url string, secret string, body *template.Template, client *http.Client, maxRetries int) *WebhookNotifier {
+	return &WebhookNotifier{
+		url:        url,
+		secret:     []byte(secret),
+		body:       body,
+		client:     client,
+		maxRetries: maxRetries,
+		now:        time.Now,
+	}
+}
+
+// ParseWebhookTemplate reads a webhook body template from a file. In addition to the text/template
+// builtins, templates can use `json` to encode a value as JSON, e.g. `{{ json .Description }}`.
+func ParseWebhookTemplate(path string) (*template.Template, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read webhook template %s: %w", path, err)
+	}
+	tmpl, err := template.New("webhook").Funcs(template.FuncMap{"json": templateJSON}).Parse(string(data))
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse webhook template %s: %w", path, err)
+	}
+	return tmpl, nil
+}
+
+func templateJSON(v interface{}) (string, error) {
+	data, err := json.Marshal(v)
+	return string(data), err
+}
+
+func (n *WebhookNotifier) Name() string {
+	return "webhook"
+}
+
+func (n *WebhookNotifier) Notify(ctx context.Context, alert Alert) error {
+	url := n.url
+	if alert.Server.Alerts != nil && alert.Server.Alerts.WebhookURL != "" {
+		url = alert.Server.Alerts.WebhookURL
+	}
+	if url == "" || alert.Repeat {
+		return nil
+	}
+
+	body, err := n.render(newWebhookPayload(alert))
+	if err != nil {
+		return err
+	}
+	err = config.RetryWithBackoff(ctx, n.maxRetries, func() error {
+		return n.post(ctx, url, body)
+	})
+	if err != nil {
+		return fmt.Errorf("failed to deliver webhook: %w", err)
+	}
+	return nil
+}
+
+// render builds the request body of a payload.
+func (n *WebhookNotifier) render(payload WebhookPayload) ([]byte, error) {
+	if n.body == nil {
+		body, err := json.Marshal(payload)
+		if err != nil {
+			return nil, fmt.Errorf("failed to encode webhook payload: %w", err)
+		}
+		return body, nil
+	}
+	var buf bytes.Buffer
+	if err := n.body.Execute(&buf, payload); err != nil {
+		return nil, fmt.Errorf("failed to render webhook template: %w", err)
+	}
+	return buf.Bytes(), nil
+}
+
+func (n *WebhookNotifier) post(ctx context.Context, url string, body []byte) error {
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, url, bytes.NewReader(body))
+	if err != nil {
+		return &config.PermanentError{Err: fmt.Errorf("failed to create webhook request: %w", err)}
+	}
+	req.Header.Set("Content-Type", "application/json")
+	if len(n.secret) > 0 {
+		timestamp := strconv.FormatInt(n.now().Unix(), 10)
+		req.Header.Set(WebhookTimestampHeader, timestamp)
+		req.Header.Set(WebhookSignatureHeader, "sha256="+sign(n.secret, timestamp, body))
+	}
+
+	resp, err := n.client.Do(req)
+	if err != nil {
+		return err
+	}
+	resp.Body.Close()
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		return fmt.Errorf("webhook responded with status %d", resp.StatusCode)
+	}
+	return nil
+}
+
+// sign returns the hex-encoded HMAC-SHA256 of "<timestamp>.<body>" with the secret.
+// Including the timestamp lets receivers reject replayed requests.
+func sign(secret []byte, timestamp string, body []byte) string {
+	mac := hmac.New(sha256.New, secret)
+	mac.Write([]byte(timestamp))
+	mac.Write([]byte("."))
+	mac.Write(body)
+	return hex.EncodeToString(mac.Sum(nil))
+}
@@ -0,0 +1,144 @@
+package alert
+
+import (
+	"context"
+	"crypto/hmac"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"watchdog.onebusaway.org/internal/models"
+)
+
+type webhookRequest struct {
+	body      []byte
+	signature string
+	timestamp string
+}
+
+func newWebhookServer(t *testing.T, requests *[]webhookRequest) *httptest.Server {
+	t.Helper()
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, err := io.ReadAll(r.Body)
+		if err != nil {
+			t.Errorf("failed to read webhook body: %v", err)
+		}
+		*requests = append(*requests, webhookRequest{
+			body:      body,
+			signature: r.Header.Get(WebhookSignatureHeader),
+			timestamp: r.Header.Get(WebhookTimestampHeader),
+		})
+		w.WriteHeader(http.StatusNoContent)
+	}))
+	t.Cleanup(server.Close)
+	return server
+}
+
+func testAlert() Alert {
+	startsAt := time.Date(2025, 1, 1, 12, 0, 0, 0, time.UTC)
+	return Alert{
+		Server:      models.ObaServer{ID: 3, Name: "Test Server"},
+		Check:       CheckBundleExpiration,
+		Title:       "GTFS bundle expiring soon",
+		Severity:    "warning",
+		Status:      StatusFiring,
+		Value:       5,
+		Threshold:   7,
+		Description: "earliest service end date in 5 days",
+		StartsAt:    startsAt,
+		At:          startsAt,
+	}
+}
+
+func TestWebhookNotifier(t *testing.T) {
+	var requests []webhookRequest
+	server := newWebhookServer(t, &requests)
+
+	notifier := NewWebhookNotifier(server.URL, "s3cret", nil, server.Client(), 0)
+	notifier.now = func() time.Time { return time.Unix(1735732800, 0) }
+
+	alert := testAlert()
+	if err := notifier.Notify(context.Background(), alert); err != nil {
+		t.Fatalf("Notify failed: %v", err)
+	}
+
+	// Reminders are not state changes.
+	alert.Repeat = true
+	if err := notifier.Notify(context.Background(), alert); err != nil {
+		t.Fatalf("Notify failed: %v", err)
+	}
+
+	alert.Repeat = false
+	alert.Status = StatusResolved
+	if err := notifier.Notify(context.Background(), alert); err != nil {
+		t.Fatalf("Notify failed: %v", err)
+	}
+
+	if len(requests) != 2 {
+		t.Fatalf("expected 2 webhook requests, got %d", len(requests))
+	}
+
+	var firing, resolved WebhookPayload
+	if err := json.Unmarshal(requests[0].body, &firing); err != nil {
+		t.Fatalf("failed to decode payload: %v", err)
+	}
+	if err := json.Unmarshal(requests[1].body, &resolved); err != nil {
+		t.Fatalf("failed to decode payload: %v", err)
+	}
+	if firing.Event != WebhookEventType || firing.ServerID != 3 || firing.Check != CheckBundleExpiration ||
+		firing.PreviousState != StateHealthy || firing.State != StateUnhealthy || firing.Value != 5 {
+		t.Errorf("unexpected firing payload %+v", firing)
+	}
+	if resolved.PreviousState != StateUnhealthy || resolved.State != StateHealthy || resolved.Status != StatusResolved {
+		t.Errorf("unexpected resolved payload %+v", resolved)
+	}
+
+	request := requests[0]
+	if request.timestamp != "1735732800" {
+		t.Errorf("unexpected timestamp header %q", request.timestamp)
+	}
+	want := "sha256=" + sign([]byte("s3cret"), request.timestamp, request.body)
+	if !hmac.Equal([]byte(request.signature), []byte(want)) {
+		t.Errorf("unexpected signature %q, want %q", request.signature, want)
+	}
+}
+
+func TestWebhookNotifierTemplate(t *testing.T) {
+	var requests []webhookRequest
+	server := newWebhookServer(t, &requests)
+
+	path := filepath.Join(t.TempDir(), "webhook.tmpl")
+	template := `{"text": {{ json (printf "%s is %s: %s" .ServerName .State .Description) }}}`
+	if err := os.WriteFile(path, []byte(template), 0o600); err != nil {
+		t.Fatalf("failed to write template: %v", err)
+	}
+	tmpl, err := ParseWebhookTemplate(path)
+	if err != nil {
+		t.Fatalf("ParseWebhookTemplate failed: %v", err)
+	}
+
+	alert := testAlert()
+	alert.Server.Alerts = &models.AlertConfig{WebhookURL: server.URL}
+	if err := NewWebhookNotifier("", "", tmpl, server.Client(), 0).Notify(context.Background(), alert); err != nil {
+		t.Fatalf("Notify failed: %v", err)
+	}
+
+	if len(requests) != 1 {
+		t.Fatalf("expected a request to the server's webhook URL, got %d", len(requests))
+	}
+	if got := string(requests[0].body); got != `{"text": "Test Server is unhealthy: earliest service end date in 5 days"}` {
+		t.Errorf("unexpected templated body %s", got)
+	}
+	if requests[0].signature != "" {
+		t.Errorf("expected an unsigned request without a secret, got %q", requests[0].signature)
+	}
+
+	if _, err := ParseWebhookTemplate(filepath.Join(t.TempDir(), "missing.tmpl")); err == nil {
+		t.Error("expected an error for a missing template")
+	}
+}
@@ -0,0 +1,221 @@
+package app
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/julienschmidt/httprouter"
+	"watchdog.onebusaway.org/internal/auth"
+	"watchdog.onebusaway.org/internal/config"
+	"watchdog.onebusaway.org/internal/middleware"
+	"watchdog.onebusaway.org/internal/models"
+	"watchdog.onebusaway.org/internal/shutdown"
+)
+
+// requireRole wraps an admin handler so that only callers with at least the given role reach it
+// (see middleware.RequireRole). Requests are written to app.AuditLogger.
+func (app *Application) requireRole(role auth.Role, handler http.HandlerFunc) http.Handler {
+	return middleware.RequireRole(app.Authenticator, role, app.AuditLogger, handler)
+}
+
+// protect wraps a read-only handler of the dashboard or the status API. These are public unless
+// OIDC login or API credentials are enabled. With OIDC, they require a viewer (or the API
+// credentials), and browsers without a session are sent to the login page rather than getting
+// a 401.
+func (app *Application) protect(handler http.HandlerFunc) http.Handler {
+	if app.OIDC == nil {
+		return app.requireAPIAuth(handler)
+	}
+	authenticator := app.Authenticator
+	if app.APIAuth != nil {
+		authenticator = auth.Authenticators{app.APIAuth, app.Authenticator}
+	}
+	protected := middleware.RequireRole(authenticator, auth.RoleViewer, app.AuditLogger, handler)
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method == http.MethodGet && strings.Contains(r.Header.Get("Accept"), "text/html") {
+			if principal, _ := authenticator.Authenticate(r); principal == nil {
+				http.Redirect(w, r, auth.LoginURL(r.URL.RequestURI()), http.StatusFound)
+				return
+			}
+		}
+		protected.ServeHTTP(w, r)
+	})
+}
+
+// requireAPIAuth wraps a handler with the API credentials check, if enabled (see app.APIAuth).
+func (app *Application) requireAPIAuth(handler http.Handler) http.Handler {
+	if app.APIAuth == nil {
+		return handler
+	}
+	return middleware.RequireAuthentication(app.APIAuth, app.APIAuth.Challenge(), handler)
+}
+
+// writeJSON writes v as a JSON response with the given status.
+func (app *Application) writeJSON(w http.ResponseWriter, status int, v any) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	if err := json.NewEncoder(w).Encode(v); err != nil {
+		app.Logger.Warn("failed to write response", "error", err)
+	}
+}
+
+// whoamiHandler returns the authenticated caller and its role, so operators can check
+// which access a token grants.
+func (app *Application) whoamiHandler(w http.ResponseWriter, r *http.Request) {
+	principal := auth.PrincipalFromContext(r.Context())
+	app.writeJSON(w, http.StatusOK, map[string]string{
+		"name":        principal.Name,
+		"role":        principal.Role.String(),
+		"auth_method": principal.Method,
+	})
+}
+
+// refreshBundlesHandler starts a GTFS bundle refresh, without waiting for the next scheduled one,
+// for all servers or for the server given by the `server_id` query parameter.
+// The refresh runs in the background under ctx (the application context), so it is not canceled
+// when the response is sent; the handler responds with 202 Accepted, or with 503 Service
+// Unavailable once the watchdog is shutting down.
+func (app *Application) refreshBundlesHandler(ctx context.Context) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		servers := app.ConfigService.Config.GetServers()
+		if id := r.URL.Query().Get("server_id"); id != "" {
+			serverID, err := strconv.Atoi(id)
+			if err != nil {
+				app.writeJSON(w, http.StatusBadRequest, map[string]string{"error": "invalid server_id"})
+				return
+			}
+			var selected []models.ObaServer
+			for _, server := range servers {
+				if server.ID == serverID {
+					selected = append(selected, server)
+				}
+			}
+			if len(selected) == 0 {
+				app.writeJSON(w, http.StatusNotFound, map[string]string{"error": "unknown server_id"})
+				return
+			}
+			servers = selected
+		}
+
+		if !shutdown.Go(ctx, func() { app.GtfsService.DownloadGTFSBundles(ctx, servers, 5) }) {
+			app.writeJSON(w, http.StatusServiceUnavailable, map[string]string{"error": "shutting down"})
+			return
+		}
+		app.writeJSON(w, http.StatusAccepted, map[string]int{"servers": len(servers)})
+	}
+}
+
+// maxServerBodySize bounds the size of a server definition posted to the admin API.
+const maxServerBodySize = 1 << 20
+
+// addServerHandler adds the server given in the request body (a JSON object in the format of
+// the configuration file) to the monitored servers. Its GTFS bundle is downloaded right away in
+// the background under ctx; realtime polling starts with the next collection cycle, and the
+// server is included in later scheduled bundle refreshes. Entries of type "url" are checked
+// from the next collection cycle on. A definition that fails config.ValidateServers is
+// rejected with the problems found, and so is one with exec checks unless they are enabled
+// (see config.DisallowExecChecks).
+//
+// When the configuration was loaded from a file, the server is also written to it. Otherwise
+// (e.g. a remote configuration) the change is kept in memory only, and is lost when the
+// remote configuration next changes. The `persisted` field of the response tells which applies.
+// A server whose OBA base URL or GTFS URL is already configured for another server is still
+// added, with the duplicates listed in the `warnings` field of the response.
+func (app *Application) addServerHandler(ctx context.Context) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		var server models.ObaServer
+		if err := json.NewDecoder(http.MaxBytesReader(w, r.Body, maxServerBodySize)).Decode(&server); err != nil {
+			app.writeJSON(w, http.StatusBadRequest, map[string]string{"error": "invalid server definition"})
+			return
+		}
+		if problems := config.ValidateServers([]models.ObaServer{server}); len(problems) > 0 {
+			app.writeJSON(w, http.StatusBadRequest, map[string]any{"error": "invalid server definition", "problems": problems})
+			return
+		}
+		if !app.ConfigService.Config.AllowExecChecks {
+			if err := config.DisallowExecChecks([]models.ObaServer{server}); err != nil {
+				app.writeJSON(w, http.StatusBadRequest, map[string]any{"error": "invalid server definition", "problems": err})
+				return
+			}
+		}
+
+		// Resolve the secret references and derive the feed settings the definition leaves empty,
+		// as is done for configured servers. The configuration file keeps the definition as posted.
+		resolved := []models.ObaServer{server}
+		if err := config.ResolveSecrets(r.Context(), app.ConfigService.Secrets, resolved); err != nil {
+			app.writeJSON(w, http.StatusBadRequest, map[string]any{"error": "invalid server definition", "problems": err})
+			return
+		}
+		if !server.IsURLTarget() {
+			resolved = config.ResolveDataSources(ctx, app.ConfigService.Client, resolved, app.Logger)
+		}
+
+		cfg := app.ConfigService.Config
+		if err := cfg.AddServer(resolved[0]); err != nil {
+			app.writeJSON(w, http.StatusConflict, map[string]string{"error": err.Error()})
+			return
+		}
+		persisted := cfg.ConfigFile != ""
+		if persisted {
+			if err := config.AddServerToFile(cfg.ConfigFile, server); err != nil && !errors.Is(err, config.ErrServerExists) {
+				cfg.RemoveServer(server.ID)
+				app.Logger.Error("failed to persist added server", "server_id", server.ID, "error", err)
+				app.writeJSON(w, http.StatusInternalServerError, map[string]string{"error": "failed to update the configuration file"})
+				return
+			}
+		}
+		// While shutting down, the bundle is downloaded on the next start instead.
+		if !server.IsURLTarget() {
+			shutdown.Go(ctx, func() { app.GtfsService.DownloadGTFSBundles(ctx, resolved, 5) })
+		}
+
+		app.Logger.Info("Added server", "server_id", server.ID, "server_name", server.Name, "persisted", persisted)
+		response := map[string]any{"id": server.ID, "name": server.Name, "persisted": persisted}
+		if warnings := duplicateNotes(server.ID, config.FindDuplicateServers(cfg.GetServers())); len(warnings) > 0 {
+			app.Logger.Warn("Added server shares URLs with other servers", "server_id", server.ID, "warnings", warnings)
+			response["warnings"] = warnings
+		}
+		app.writeJSON(w, http.StatusCreated, response)
+	}
+}
+
+// removeServerHandler stops monitoring the server with the given `id`: it is no longer polled
+// from the next collection cycle on, nor included in scheduled bundle refreshes.
+// As with addServerHandler, the change is written to the configuration file if there is one.
+func (app *Application) removeServerHandler(w http.ResponseWriter, r *http.Request) {
+	serverID, err := strconv.Atoi(httprouter.ParamsFromContext(r.Context()).ByName("id"))
+	if err != nil {
+		app.writeJSON(w, http.StatusBadRequest, map[string]string{"error": "invalid server id"})
+		return
+	}
+
+	cfg := app.ConfigService.Config
+	var removed *models.ObaServer
+	for _, server := range append(cfg.GetServers(), cfg.GetURLTargets()...) {
+		if server.ID == serverID {
+			removed = &server
+			break
+		}
+	}
+	if removed == nil || !cfg.RemoveServer(serverID) {
+		app.writeJSON(w, http.StatusNotFound, map[string]string{"error": "unknown server id"})
+		return
+	}
+	if cfg.ConfigFile != "" {
+		if err := config.RemoveServerFromFile(cfg.ConfigFile, serverID); err != nil {
+			_ = cfg.AddServer(*removed)
+			app.Logger.Error("failed to persist removed server", "server_id", serverID, "error", err)
+			app.writeJSON(w, http.StatusInternalServerError, map[string]string{"error": "failed to update the configuration file"})
+			return
+		}
+	}
+
+	app.forgetServer(serverID)
+
+	app.Logger.Info("Removed server", "server_id", serverID, "server_name", removed.Name, "persisted", cfg.ConfigFile != "")
+	w.WriteHeader(http.StatusNoContent)
+}
@@ -0,0 +1,192 @@
+package app
+
+import (
+	"bytes"
+	"context"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"watchdog.onebusaway.org/internal/auth"
+	"watchdog.onebusaway.org/internal/config"
+)
+
+// testAuthenticator maps bearer tokens directly to roles.
+type testAuthenticator map[string]auth.Role
+
+func (a testAuthenticator) Authenticate(r *http.Request) (*auth.Principal, error) {
+	token := auth.BearerToken(r)
+	if token == "" {
+		return nil, nil
+	}
+	role, ok := a[token]
+	if !ok {
+		return nil, auth.ErrUnauthenticated
+	}
+	return &auth.Principal{Name: token, Role: role, Method: "token"}, nil
+}
+
+func TestAdminRoutes(t *testing.T) {
+	app := newTestApplication(t)
+	var audit bytes.Buffer
+	app.AuditLogger = slog.New(slog.NewTextHandler(&audit, nil))
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	// Without an authenticator, the admin API is not served.
+	rr := httptest.NewRecorder()
+	app.Routes(ctx).ServeHTTP(rr, httptest.NewRequest(http.MethodGet, "/v1/admin/whoami", nil))
+	if rr.Code != http.StatusNotFound {
+		t.Errorf("expected 404 without an authenticator, got %d", rr.Code)
+	}
+
+	app.Authenticator = testAuthenticator{"viewer": auth.RoleViewer, "operator": auth.RoleOperator}
+	handler := app.Routes(ctx)
+
+	tests := []struct {
+		name   string
+		method string
+		path   string
+		token  string
+		want   int
+	}{
+		{"no credentials", http.MethodGet, "/v1/admin/whoami", "", http.StatusUnauthorized},
+		{"invalid token", http.MethodGet, "/v1/admin/whoami", "unknown", http.StatusUnauthorized},
+		{"viewer reads", http.MethodGet, "/v1/admin/whoami", "viewer", http.StatusOK},
+		{"viewer cannot refresh", http.MethodPost, "/v1/admin/bundles/refresh", "viewer", http.StatusForbidden},
+		{"operator refreshes unknown server", http.MethodPost, "/v1/admin/bundles/refresh?server_id=99", "operator", http.StatusNotFound},
+		{"operator refreshes invalid server", http.MethodPost, "/v1/admin/bundles/refresh?server_id=x", "operator", http.StatusBadRequest},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			r := httptest.NewRequest(tt.method, tt.path, nil)
+			if tt.token != "" {
+				r.Header.Set("Authorization", "Bearer "+tt.token)
+			}
+			rr := httptest.NewRecorder()
+			handler.ServeHTTP(rr, r)
+			if rr.Code != tt.want {
+				t.Errorf("expected status %d, got %d: %s", tt.want, rr.Code, rr.Body.String())
+			}
+		})
+	}
+
+	log := audit.String()
+	for _, want := range []string{
+		"decision=unauthenticated",
+		"principal=viewer role=viewer auth_method=token decision=forbidden status=403",
+		"principal=viewer role=viewer auth_method=token decision=allowed status=200",
+		"principal=operator role=operator auth_method=token decision=allowed status=404",
+	} {
+		if !strings.Contains(log, want) {
+			t.Errorf("expected the audit log to contain %q, got:\n%s", want, log)
+		}
+	}
+}
+
+func TestServerAdminRoutes(t *testing.T) {
+	app := newTestApplication(t)
+	app.Authenticator = testAuthenticator{"operator": auth.RoleOperator, "admin": auth.RoleAdmin}
+
+	path := filepath.Join(t.TempDir(), "config.json")
+	if err := os.WriteFile(path, []byte(`[{"name": "Test Server", "id": 1, "oba_base_url": "https://test.example.com"}]`), 0600); err != nil {
+		t.Fatal(err)
+	}
+	app.ConfigService.Config.ConfigFile = path
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	handler := app.Routes(ctx)
+
+	tests := []struct {
+		name   string
+		method string
+		path   string
+		token  string
+		body   string
+		want   int
+	}{
+		{"operator cannot add", http.MethodPost, "/v1/admin/servers", "operator", `{"id": 2, "name": "New", "oba_base_url": "https://new.example.com"}`, http.StatusForbidden},
+		{"invalid body", http.MethodPost, "/v1/admin/servers", "admin", `{`, http.StatusBadRequest},
+		{"missing fields", http.MethodPost, "/v1/admin/servers", "admin", `{"id": 2}`, http.StatusBadRequest},
+		{"invalid url", http.MethodPost, "/v1/admin/servers", "admin", `{"id": 2, "name": "New", "oba_base_url": "new.example.com"}`, http.StatusBadRequest},
+		{"exec checks disabled", http.MethodPost, "/v1/admin/servers", "admin", `{"id": 3, "name": "Exec", "oba_base_url": "https://exec.example.com", "exec_checks": [{"name": "x", "command": ["true"]}]}`, http.StatusBadRequest},
+		{"duplicate id", http.MethodPost, "/v1/admin/servers", "admin", `{"id": 1, "name": "Dup", "oba_base_url": "https://dup.example.com"}`, http.StatusConflict},
+		{"add", http.MethodPost, "/v1/admin/servers", "admin", `{"id": 2, "name": "New", "oba_base_url": "https://new.example.com"}`, http.StatusCreated},
+		{"operator cannot remove", http.MethodDelete, "/v1/admin/servers/1", "operator", "", http.StatusForbidden},
+		{"remove invalid id", http.MethodDelete, "/v1/admin/servers/x", "admin", "", http.StatusBadRequest},
+		{"remove unknown id", http.MethodDelete, "/v1/admin/servers/99", "admin", "", http.StatusNotFound},
+		{"remove", http.MethodDelete, "/v1/admin/servers/1", "admin", "", http.StatusNoContent},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			r := httptest.NewRequest(tt.method, tt.path, strings.NewReader(tt.body))
+			r.Header.Set("Authorization", "Bearer "+tt.token)
+			rr := httptest.NewRecorder()
+			handler.ServeHTTP(rr, r)
+			if rr.Code != tt.want {
+				t.Errorf("expected status %d, got %d: %s", tt.want, rr.Code, rr.Body.String())
+			}
+		})
+	}
+
+	servers := app.ConfigService.Config.GetServers()
+	if len(servers) != 1 || servers[0].ID != 2 {
+		t.Errorf("expected only server 2 to be monitored, got %+v", servers)
+	}
+	persisted, err := config.LoadConfigFromFile(path)
+	if err != nil {
+		t.Fatalf("failed to reload config file: %v", err)
+	}
+	if len(persisted) != 1 || persisted[0].ID != 2 || persisted[0].Name != "New" {
+		t.Errorf("expected the config file to hold only server 2, got %+v", persisted)
+	}
+}
+
+func TestAPIAuthRoutes(t *testing.T) {
+	app := newTestApplication(t)
+	apiAuth, err := auth.NewStaticAuthenticator("scrape-token", "ops", "s3cret")
+	if err != nil {
+		t.Fatal(err)
+	}
+	app.APIAuth = apiAuth
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	handler := app.Routes(ctx)
+
+	tests := []struct {
+		name  string
+		path  string
+		setup func(r *http.Request)
+		want  int
+	}{
+		{"metrics without credentials", "/metrics", func(r *http.Request) {}, http.StatusUnauthorized},
+		{"metrics with token", "/metrics", func(r *http.Request) { r.Header.Set("Authorization", "Bearer scrape-token") }, http.StatusOK},
+		{"metrics with wrong token", "/metrics", func(r *http.Request) { r.Header.Set("Authorization", "Bearer nope") }, http.StatusUnauthorized},
+		{"status without credentials", "/v1/servers", func(r *http.Request) {}, http.StatusUnauthorized},
+		{"status with basic auth", "/v1/servers", func(r *http.Request) { r.SetBasicAuth("ops", "s3cret") }, http.StatusOK},
+		{"status with wrong password", "/v1/servers", func(r *http.Request) { r.SetBasicAuth("ops", "guess") }, http.StatusUnauthorized},
+		{"dashboard without credentials", "/ui", func(r *http.Request) {}, http.StatusUnauthorized},
+		{"liveness probe stays public", "/v1/livez", func(r *http.Request) {}, http.StatusOK},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			r := httptest.NewRequest(http.MethodGet, tt.path, nil)
+			tt.setup(r)
+			rr := httptest.NewRecorder()
+			handler.ServeHTTP(rr, r)
+			if rr.Code != tt.want {
+				t.Errorf("expected status %d, got %d: %s", tt.want, rr.Code, rr.Body.String())
+			}
+			if rr.Code == http.StatusUnauthorized && !strings.HasPrefix(rr.Header().Get("WWW-Authenticate"), "Basic") {
+				t.Errorf("expected a basic auth challenge, got %q", rr.Header().Get("WWW-Authenticate"))
+			}
+		})
+	}
+}
@@ -2,12 +2,24 @@ package app
 
 import (
 	"log/slog"
-	"net/http"
+	"time"
 
+	"watchdog.onebusaway.org/internal/alert"
+	"watchdog.onebusaway.org/internal/auth"
 	"watchdog.onebusaway.org/internal/config"
+	"watchdog.onebusaway.org/internal/cycletrace"
 	"watchdog.onebusaway.org/internal/geo"
 	"watchdog.onebusaway.org/internal/gtfs"
+	"watchdog.onebusaway.org/internal/history"
+	"watchdog.onebusaway.org/internal/httpclient"
+	"watchdog.onebusaway.org/internal/leader"
+	"watchdog.onebusaway.org/internal/lifecycle"
+	"watchdog.onebusaway.org/internal/logbuffer"
 	"watchdog.onebusaway.org/internal/metrics"
+	"watchdog.onebusaway.org/internal/models"
+	"watchdog.onebusaway.org/internal/report"
+	"watchdog.onebusaway.org/internal/sharedstore"
+	"watchdog.onebusaway.org/internal/vantage"
 )
 
 // Application represents the main application structure.
@@ -19,29 +31,184 @@ type Application struct {
 	ConfigService  *config.ConfigService
 	GtfsService    *gtfs.GtfsService
 	MetricsService *metrics.MetricsService
-	Logger         *slog.Logger
-	Version        string
+	Alerts         *alert.Manager
+	// Rules evaluates the alert rules of --alert-rules-file; nil if there are none.
+	Rules *alert.RuleEvaluator
+	// AgencyDigest sends the data-quality findings of servers to their agency contacts.
+	AgencyDigest *alert.AgencyDigest
+	// EmailAlerts emails alerts; nil if no alert email recipient is configured.
+	EmailAlerts *alert.EmailNotifier
+	// Incidents records the incidents served by the incident feed; nil disables the feed.
+	Incidents *alert.IncidentLog
+	// Authenticator identifies callers of the admin API; nil disables the admin API.
+	Authenticator auth.Authenticator
+	// OIDC logs users in with an OpenID Connect provider; nil disables login, and leaves the
+	// dashboard and status API public.
+	OIDC *auth.OIDC
+	// APIAuth requires shared credentials on /metrics and the status API; nil leaves them
+	// public, or behind OIDC login.
+	APIAuth *auth.StaticAuthenticator
+	// Bootstrap tracks the progress of the cold start; nil if there is none.
+	Bootstrap *Bootstrap
+	// Live fans check results and vehicle summaries out to the live status stream.
+	Live *LiveHub
+	// Logs keeps the recent log records of each server for snapshots; nil leaves them out.
+	Logs *logbuffer.Buffer
+	// Lifecycle records when servers, routes and GTFS-RT feeds were first and last observed;
+	// nil records nothing.
+	Lifecycle *lifecycle.Store
+	// History records the result of every check run in --history-db; nil records nothing and
+	// disables the history API.
+	History *history.Store
+	// CycleTraces keeps the breakdown of the time of the last collection cycles of each server.
+	CycleTraces *cycletrace.Store
+	// Maintenance holds the maintenance windows of the servers declared with the admin API; the
+	// configured windows of the servers apply on top of them.
+	Maintenance *config.MaintenanceStore
+	// Vantage keeps the ping results pushed by the secondary probe agents; nil if the watchdog
+	// does not accept them (no PROBE_TOKEN, or it is an agent itself).
+	Vantage *vantage.Store
+	// HTTPTimeouts are the global timeouts of outgoing requests, which servers can override
+	// (see httpclient.Timeouts.ForServer).
+	HTTPTimeouts httpclient.Timeouts
+	// Leader elects the replica that runs the checks in a highly available deployment; nil
+	// means this watchdog always runs them.
+	Leader *leader.Elector
+	// AuditLogger records every admin API request.
+	AuditLogger *slog.Logger
+	Logger      *slog.Logger
+	Version     string
 }
 
 // New creates and wires all dependencies for the Application.
-// Accepts config, logger, client, and version as arguments.
-func New(cfg *config.Config, logger *slog.Logger, client *http.Client, version string) *Application {
+// Accepts config, logger, the HTTP clients, and version as arguments. GTFS-RT feeds are polled
+// with clients.Realtime, bundles are downloaded with clients.Bundle, and every other request
+// is made with clients.API.
+func New(cfg *config.Config, logger *slog.Logger, clients *httpclient.Clients, version string) *Application {
+	client := clients.API
 
 	staticStore := gtfs.NewStaticStore()
 	realtimeStore := gtfs.NewRealtimeStore()
 	boundingBoxStore := geo.NewBoundingBoxStore()
 	vehicleLastSeen := metrics.NewVehicleLastSeen()
-	backoffStore := config.NewBackoffStore()
+	backoffStore := config.NewBackoffStore(cfg.CircuitBreakerThreshold, cfg.CircuitBreakerCooldown)
+	bundleMetadataStore := gtfs.NewBundleMetadataStore()
+	bundleContentsStore := gtfs.NewBundleContentsStore()
+
+	bundleThrottle := gtfs.NewBundleThrottle(cfg.BundleDownloadRateLimit, cfg.BundleDownloadGlobalRateLimit)
+	bundleDiskCache, err := gtfs.NewBundleDiskCache(cfg.BundleCacheDir)
+	if err != nil {
+		// The cache is an optimization; run without it rather than failing to start.
+		logger.Error("Failed to initialize GTFS bundle disk cache, caching disabled", "dir", cfg.BundleCacheDir, "error", err)
+	}
+
+	lifecycleStore, err := lifecycle.Open(cfg.LifecycleFile)
+	if err != nil {
+		// Observations start over rather than failing to start.
+		logger.Error("Failed to load the lifecycle file, starting with no observations", "file", cfg.LifecycleFile, "error", err)
+	}
+
+	historyStore, err := history.Open(cfg.HistoryDB, cfg.HistoryRetention)
+	if err != nil {
+		logger.Error("Failed to open the history database, check results are not recorded", "file", cfg.HistoryDB, "error", err)
+	}
+
+	// Deliveries are retried a few times; a deployer that is down for longer
+	// will pick the change up from the gtfs_bundle_changed metric instead.
+	bundleNotifier := gtfs.NewBundleChangeNotifier(cfg.BundleChangeWebhookURL, client, 3)
+
+	var notifiers []alert.Notifier
+	if cfg.SlackWebhookURL != "" || anyAlerts(cfg, func(a *models.AlertConfig) bool { return a.SlackWebhookURL != "" }) {
+		notifiers = append(notifiers, alert.NewSlackNotifier(cfg.SlackWebhookURL, client))
+	}
+	if cfg.PagerDutyRoutingKey != "" || anyAlerts(cfg, func(a *models.AlertConfig) bool { return a.PagerDutyRoutingKey != "" }) {
+		notifiers = append(notifiers, alert.NewPagerDutyNotifier(cfg.PagerDutyRoutingKey, client, 3))
+	}
+	if cfg.AlertWebhookURL != "" || anyAlerts(cfg, func(a *models.AlertConfig) bool { return a.WebhookURL != "" }) {
+		notifiers = append(notifiers, alert.NewWebhookNotifier(cfg.AlertWebhookURL, cfg.AlertWebhookSecret, cfg.AlertWebhookTemplate, client, 3))
+	}
+	mailer := alert.NewMailer(cfg.SMTPAddr, cfg.SMTPFrom, cfg.SMTPUsername, cfg.SMTPPassword, alert.MailerOptions{TLS: cfg.SMTPTLS, Auth: cfg.SMTPAuth})
+	var emailAlerts *alert.EmailNotifier
+	if mailer != nil && (len(cfg.AlertEmailTo) > 0 || anyAlerts(cfg, func(a *models.AlertConfig) bool { return len(a.EmailTo) > 0 })) {
+		emailAlerts = alert.NewEmailNotifier(mailer, cfg.AlertEmailTo, cfg.AlertEmailDigestWindow > 0, logger)
+		notifiers = append(notifiers, emailAlerts)
+	}
+	var incidents *alert.IncidentLog
+	if cfg.IncidentFeed {
+		incidents = alert.NewIncidentLog(incidentFeedSize)
+		notifiers = append(notifiers, incidents)
+	}
+	alertManager := alert.NewManager(notifiers, cfg.AlertCooldown, cfg.AlertLocale, logger)
 
 	configService := config.NewConfigService(logger, client, cfg, backoffStore)
-	gtfsService := gtfs.NewGtfsService(staticStore, realtimeStore, boundingBoxStore, logger, client)
-	metricsService := metrics.NewMetricsService(staticStore, realtimeStore, boundingBoxStore, vehicleLastSeen, logger, client)
+	gtfsService := gtfs.NewGtfsService(staticStore, realtimeStore, boundingBoxStore, logger, clients.Realtime, clients.Bundle, gtfs.GtfsServiceOptions{
+		BundleThrottle:  bundleThrottle,
+		BundleMetadata:  bundleMetadataStore,
+		BundleDiskCache: bundleDiskCache,
+		MaxBundleSize:   cfg.MaxBundleSize,
+		BundleContents:  bundleContentsStore,
+		BundleNotifier:  bundleNotifier,
+	})
+	maintenance := config.NewMaintenanceStore()
+	alertManager.SetMaintenance(func(server models.ObaServer, at time.Time) bool {
+		_, active := maintenance.Active(server, at)
+		return active
+	})
+	alertManager.SetServiceReductions(func(serverID int, at time.Time) bool {
+		_, reduced := gtfsService.ServiceReductions.Active(serverID, at)
+		return reduced
+	})
+	var vantageStore *vantage.Store
+	if cfg.ProbeToken != "" && cfg.ProbePrimaryURL == "" {
+		vantageStore = vantage.NewStore(cfg.ProbeResultMaxAge)
+		alertManager.SetVantages(func(serverID int, at time.Time) []alert.VantageResult {
+			return alertVantages(vantageStore.Get(serverID, at))
+		})
+	}
+
+	metricsService := metrics.NewMetricsService(staticStore, realtimeStore, boundingBoxStore, vehicleLastSeen, metrics.NewCheckResultStore(), metrics.NewPredictionTracker(), logger, client)
 
-	return &Application{
+	application := &Application{
 		ConfigService:  configService,
 		GtfsService:    gtfsService,
 		MetricsService: metricsService,
+		Alerts:         alertManager,
+		Rules:          alert.NewRuleEvaluator(cfg.AlertRules, alertManager),
+		AgencyDigest:   alert.NewAgencyDigest(client, mailer, cfg.AlertLocale, logger),
+		EmailAlerts:    emailAlerts,
+		Incidents:      incidents,
+		Bootstrap:      NewBootstrap(cfg.ColdStartReadyFraction, cfg.ColdStartTimeout),
+		Live:           NewLiveHub(),
+		Lifecycle:      lifecycleStore,
+		History:        historyStore,
+		CycleTraces:    cycletrace.NewStore(cycletrace.DefaultKeep),
+		Maintenance:    maintenance,
+		Vantage:        vantageStore,
+		HTTPTimeouts:   clients.Timeouts(),
+		AuditLogger:    logger.With("log", "audit"),
 		Logger:         logger,
 		Version:        version,
 	}
+	configService.OnChange = application.applyConfigChanges
+	report.SuppressServers(application.reportsSuppressed)
+	if cfg.SharedStore == "redis" {
+		application.shareStores(&sharedstore.Redis{Client: cfg.Redis, Prefix: cfg.SharedStorePrefix})
+	}
+	return application
+}
+
+// anyAlerts reports whether the alert config of any server or aggregate alert rule matches,
+// e.g. to check whether a server routes its alerts to its own Slack webhook.
+func anyAlerts(cfg *config.Config, match func(*models.AlertConfig) bool) bool {
+	for _, server := range cfg.GetServers() {
+		if server.Alerts != nil && match(server.Alerts) {
+			return true
+		}
+	}
+	for _, rule := range cfg.AlertRules {
+		if rule.Alerts != nil && match(rule.Alerts) {
+			return true
+		}
+	}
+	return false
 }
@@ -0,0 +1,95 @@
+package app
+
+import (
+	"bytes"
+	"html/template"
+	"net/http"
+	"strconv"
+	"unicode/utf8"
+
+	"github.com/julienschmidt/httprouter"
+	"watchdog.onebusaway.org/internal/i18n"
+)
+
+// badgeColors are the colors of the message of a badge, by server health.
+var badgeColors = map[string]string{
+	healthUp:       "#4c1",
+	healthDegraded: "#dfb317",
+	healthDown:     "#e05d44",
+	healthUnknown:  "#9f9f9f",
+}
+
+// badgeTemplate is a shields.io-style "flat" badge: a grey label followed by a colored message.
+var badgeTemplate = template.Must(template.New("badge").Parse(`<svg xmlns="http://www.w3.org/2000/svg" width="{{.Width}}" height="20" role="img" aria-label="{{.Label}}: {{.Message}}">` +
+	`<title>{{.Label}}: {{.Message}}</title>` +
+	`<linearGradient id="s" x2="0" y2="100%"><stop offset="0" stop-color="#bbb" stop-opacity=".1"/><stop offset="1" stop-opacity=".1"/></linearGradient>` +
+	`<clipPath id="r"><rect width="{{.Width}}" height="20" rx="3" fill="#fff"/></clipPath>` +
+	`<g clip-path="url(#r)"><rect width="{{.LabelWidth}}" height="20" fill="#555"/><rect x="{{.LabelWidth}}" width="{{.MessageWidth}}" height="20" fill="{{.Color}}"/><rect width="{{.Width}}" height="20" fill="url(#s)"/></g>` +
+	`<g fill="#fff" text-anchor="middle" font-family="Verdana,Geneva,DejaVu Sans,sans-serif" font-size="11">` +
+	`<text x="{{.LabelX}}" y="15" fill="#010101" fill-opacity=".3">{{.Label}}</text><text x="{{.LabelX}}" y="14">{{.Label}}</text>` +
+	`<text x="{{.MessageX}}" y="15" fill="#010101" fill-opacity=".3">{{.Message}}</text><text x="{{.MessageX}}" y="14">{{.Message}}</text>` +
+	`</g></svg>`))
+
+type badge struct {
+	Label, Message, Color    string
+	LabelWidth, MessageWidth int
+}
+
+func (b badge) Width() int    { return b.LabelWidth + b.MessageWidth }
+func (b badge) LabelX() int   { return b.LabelWidth / 2 }
+func (b badge) MessageX() int { return b.LabelWidth + b.MessageWidth/2 }
+
+// textWidth approximates the width in pixels of text in 11px Verdana, plus padding.
+func textWidth(text string) int {
+	return utf8.RuneCountInString(text)*7 + 10
+}
+
+// serverBadgeHandler renders the health of a server (see serverHealth) as an SVG badge that
+// can be embedded in wikis and status pages. The label is the server name unless `label` is
+// given, and the message is translated into the language given by `lang` (e.g. `?lang=es`).
+//
+// Badges only reveal the health of a server, so they are public even when OIDC login is
+// enabled; otherwise they could not be embedded in pages outside the watchdog.
+func (app *Application) serverBadgeHandler(w http.ResponseWriter, r *http.Request) {
+	serverID, err := strconv.Atoi(httprouter.ParamsFromContext(r.Context()).ByName("id"))
+	if err != nil {
+		http.Error(w, "invalid server id", http.StatusBadRequest)
+		return
+	}
+	var label string
+	found := false
+	for _, server := range app.ConfigService.Config.GetServers() {
+		if server.ID == serverID {
+			label, found = server.Name, true
+			break
+		}
+	}
+	if !found {
+		http.Error(w, "unknown server id", http.StatusNotFound)
+		return
+	}
+	if custom := r.URL.Query().Get("label"); custom != "" {
+		label = custom
+	}
+
+	health := serverHealth(app.MetricsService.CheckResults.Get(serverID))
+	message := i18n.T(r.URL.Query().Get("lang"), "health."+health)
+	b := badge{
+		Label:        label,
+		Message:      message,
+		Color:        badgeColors[health],
+		LabelWidth:   textWidth(label),
+		MessageWidth: textWidth(message),
+	}
+
+	var buf bytes.Buffer
+	if err := badgeTemplate.Execute(&buf, b); err != nil {
+		app.Logger.Error("failed to render badge", "server_id", serverID, "error", err)
+		http.Error(w, "failed to render badge", http.StatusInternalServerError)
+		return
+	}
+	w.Header().Set("Content-Type", "image/svg+xml")
+	// Image proxies (e.g. GitHub's camo) would otherwise cache the badge for a long time.
+	w.Header().Set("Cache-Control", "no-cache, max-age=0")
+	_, _ = w.Write(buf.Bytes())
+}
@@ -0,0 +1,44 @@
+package app
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"watchdog.onebusaway.org/internal/metrics"
+)
+
+func TestServerBadgeHandler(t *testing.T) {
+	app := newTestApplication(t)
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	handler := app.Routes(ctx)
+
+	get := func(path string) *httptest.ResponseRecorder {
+		rr := httptest.NewRecorder()
+		handler.ServeHTTP(rr, httptest.NewRequest(http.MethodGet, path, nil))
+		return rr
+	}
+
+	rr := get("/v1/servers/1/badge.svg")
+	if rr.Code != http.StatusOK || rr.Header().Get("Content-Type") != "image/svg+xml" {
+		t.Fatalf("expected an SVG, got %d %q", rr.Code, rr.Header().Get("Content-Type"))
+	}
+	if body := rr.Body.String(); !strings.Contains(body, "Test Server: unknown") || !strings.Contains(body, badgeColors[healthUnknown]) {
+		t.Errorf("expected an unknown badge before any check, got %s", body)
+	}
+
+	app.MetricsService.CheckResults.Record(1, metrics.CheckServerPing, errors.New("timeout"), time.Now())
+	body := get("/v1/servers/1/badge.svg?lang=es&label=<OBA>").Body.String()
+	if !strings.Contains(body, "&lt;OBA&gt;: caído") || !strings.Contains(body, badgeColors[healthDown]) {
+		t.Errorf("expected an escaped, translated down badge, got %s", body)
+	}
+
+	if rr := get("/v1/servers/99/badge.svg"); rr.Code != http.StatusNotFound {
+		t.Errorf("expected 404 for an unknown server, got %d", rr.Code)
+	}
+}
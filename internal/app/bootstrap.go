@@ -0,0 +1,159 @@
+package app
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	"watchdog.onebusaway.org/internal/models"
+)
+
+// Cold start state of a server, in the status API.
+const (
+	bootstrapPending     = "pending"
+	bootstrapDownloading = "downloading"
+	bootstrapLoaded      = "loaded"
+	bootstrapFailed      = "failed"
+)
+
+// Bootstrap tracks the progress of the cold start (see ColdStart): which servers have a GTFS
+// bundle so far, and whether enough of them do for the watchdog to be ready while the others
+// are still downloading. A nil Bootstrap has no cold start in progress.
+type Bootstrap struct {
+	// ReadyFraction is the fraction of the servers whose bundle must be loaded for the cold
+	// start to be ready (1 = all of them), and Timeout bounds how long the cold start can keep
+	// the watchdog from being ready (0 = no bound).
+	ReadyFraction float64
+	Timeout       time.Duration
+
+	mu        sync.Mutex
+	servers   []int
+	states    map[int]string
+	startedAt time.Time
+	complete  bool
+	ready     bool
+	// changed is closed and replaced whenever the state changes, to wake up Wait.
+	changed chan struct{}
+}
+
+// NewBootstrap returns a Bootstrap that is ready once readyFraction of the bundles are loaded,
+// or after timeout (0 = no bound).
+func NewBootstrap(readyFraction float64, timeout time.Duration) *Bootstrap {
+	return &Bootstrap{ReadyFraction: readyFraction, Timeout: timeout, states: make(map[int]string), changed: make(chan struct{})}
+}
+
+// BootstrapProgress is the progress of the cold start.
+type BootstrapProgress struct {
+	Total  int `json:"total"`
+	Loaded int `json:"loaded"`
+	Failed int `json:"failed"`
+	// Ready is whether enough bundles are loaded, or the cold start took too long, for the
+	// watchdog to be ready; Complete whether every server was processed.
+	Ready     bool       `json:"ready"`
+	Complete  bool       `json:"complete"`
+	StartedAt *time.Time `json:"started_at,omitempty"`
+}
+
+// start begins the cold start of servers, those with a bundle (e.g. restored from the disk
+// cache) being loaded already.
+func (b *Bootstrap) start(servers []models.ObaServer, hasBundle func(int) bool) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.startedAt = time.Now()
+	b.servers = b.servers[:0]
+	for _, server := range servers {
+		b.servers = append(b.servers, server.ID)
+		b.states[server.ID] = bootstrapPending
+		if hasBundle(server.ID) {
+			b.states[server.ID] = bootstrapLoaded
+		}
+	}
+	b.notify()
+}
+
+// set records the state of the cold start of a server.
+func (b *Bootstrap) set(serverID int, state string) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.states[serverID] = state
+	b.notify()
+}
+
+// finish records the end of the cold start, whether every tier was processed or not.
+func (b *Bootstrap) finish() {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.complete = true
+	b.notify()
+}
+
+// notify wakes up the callers of Wait. b.mu must be held.
+func (b *Bootstrap) notify() {
+	close(b.changed)
+	b.changed = make(chan struct{})
+}
+
+// State returns the cold start state of a server: pending, downloading, loaded or failed. It is
+// empty for servers that were not part of the cold start, e.g. added later.
+func (b *Bootstrap) State(serverID int) string {
+	if b == nil {
+		return ""
+	}
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return b.states[serverID]
+}
+
+// Progress returns the progress of the cold start. Once ready, the cold start stays ready.
+func (b *Bootstrap) Progress() BootstrapProgress {
+	if b == nil {
+		return BootstrapProgress{Ready: true, Complete: true}
+	}
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return b.progress(time.Now())
+}
+
+// progress returns the progress of the cold start at now. b.mu must be held.
+func (b *Bootstrap) progress(now time.Time) BootstrapProgress {
+	progress := BootstrapProgress{Total: len(b.servers), Complete: b.complete, StartedAt: timePtr(b.startedAt)}
+	for _, id := range b.servers {
+		switch b.states[id] {
+		case bootstrapLoaded:
+			progress.Loaded++
+		case bootstrapFailed:
+			progress.Failed++
+		}
+	}
+	if !b.ready && !b.startedAt.IsZero() {
+		b.ready = b.complete ||
+			(progress.Total > 0 && float64(progress.Loaded) >= b.ReadyFraction*float64(progress.Total)) ||
+			(b.Timeout > 0 && now.Sub(b.startedAt) >= b.Timeout)
+	}
+	progress.Ready = b.ready
+	return progress
+}
+
+// Wait blocks until the cold start is ready (see Progress) or ctx is canceled, and returns its
+// progress.
+func (b *Bootstrap) Wait(ctx context.Context) BootstrapProgress {
+	for {
+		b.mu.Lock()
+		progress := b.progress(time.Now())
+		changed := b.changed
+		var deadline <-chan time.Time
+		if b.Timeout > 0 && !b.startedAt.IsZero() {
+			deadline = time.After(time.Until(b.startedAt.Add(b.Timeout)))
+		}
+		b.mu.Unlock()
+		if progress.Ready {
+			return progress
+		}
+		select {
+		case <-ctx.Done():
+			return progress
+		case <-changed:
+		case <-deadline:
+		}
+	}
+}
@@ -0,0 +1,81 @@
+package app
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"watchdog.onebusaway.org/internal/models"
+)
+
+func TestBootstrap(t *testing.T) {
+	servers := []models.ObaServer{{ID: 1}, {ID: 2}, {ID: 3}, {ID: 4}}
+	bootstrap := NewBootstrap(0.5, 0)
+	bootstrap.start(servers, func(id int) bool { return id == 1 })
+
+	if progress := bootstrap.Progress(); progress.Ready || progress.Loaded != 1 || progress.Total != 4 {
+		t.Fatalf("expected 1 of 4 bundles loaded and not ready, got %+v", progress)
+	}
+	if state := bootstrap.State(2); state != bootstrapPending {
+		t.Errorf("expected server 2 to be pending, got %q", state)
+	}
+
+	waited := make(chan BootstrapProgress)
+	go func() { waited <- bootstrap.Wait(context.Background()) }()
+	bootstrap.set(2, bootstrapDownloading)
+	bootstrap.set(3, bootstrapFailed)
+	bootstrap.set(2, bootstrapLoaded)
+	select {
+	case progress := <-waited:
+		if !progress.Ready || progress.Complete || progress.Loaded != 2 || progress.Failed != 1 {
+			t.Errorf("expected the cold start to be ready with half of the bundles, got %+v", progress)
+		}
+	case <-time.After(time.Second):
+		t.Fatal("expected Wait to return once half of the bundles are loaded")
+	}
+
+	// Once ready, the cold start stays ready.
+	bootstrap.set(2, bootstrapFailed)
+	if progress := bootstrap.Progress(); !progress.Ready {
+		t.Errorf("expected the cold start to stay ready, got %+v", progress)
+	}
+	bootstrap.finish()
+	if progress := bootstrap.Progress(); !progress.Complete {
+		t.Errorf("expected the cold start to be complete, got %+v", progress)
+	}
+}
+
+func TestBootstrapTimeout(t *testing.T) {
+	bootstrap := NewBootstrap(1, 50*time.Millisecond)
+	bootstrap.start([]models.ObaServer{{ID: 1}, {ID: 2}}, func(int) bool { return false })
+
+	start := time.Now()
+	progress := bootstrap.Wait(context.Background())
+	if !progress.Ready || progress.Loaded != 0 || time.Since(start) < 50*time.Millisecond {
+		t.Errorf("expected the cold start to be ready after the timeout, got %+v after %v", progress, time.Since(start))
+	}
+
+	var none *Bootstrap
+	if progress := none.Progress(); !progress.Ready || !progress.Complete || none.State(1) != "" {
+		t.Errorf("expected no cold start to be in progress, got %+v", progress)
+	}
+}
+
+func TestColdStartRecordsBootstrap(t *testing.T) {
+	app := newTestApplication(t)
+	app.Bootstrap = NewBootstrap(1, 0)
+	missing := httptest.NewServer(http.NotFoundHandler())
+	defer missing.Close()
+	// Server 1 keeps the bundle it already has, server 2 has none.
+	servers := []models.ObaServer{{ID: 1, Name: "Test Server", GtfsUrl: missing.URL}, {ID: 2, Name: "Broken Server", GtfsUrl: missing.URL}}
+
+	app.ColdStart(context.Background(), servers, 1)
+	if progress := app.Bootstrap.Progress(); !progress.Complete || !progress.Ready || progress.Loaded != 1 || progress.Failed != 1 {
+		t.Errorf("expected server 1 loaded and server 2 failed, got %+v", progress)
+	}
+	if state := app.Bootstrap.State(2); state != bootstrapFailed {
+		t.Errorf("expected server 2 to have failed, got %q", state)
+	}
+}
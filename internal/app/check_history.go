@@ -0,0 +1,56 @@
+package app
+
+import (
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/julienschmidt/httprouter"
+	"watchdog.onebusaway.org/internal/history"
+)
+
+// defaultHistoryWindow is the period returned by the history API when `from` is not given.
+const defaultHistoryWindow = 24 * time.Hour
+
+// checkHistoryHandler returns the recorded results of the checks of a server, oldest first (see
+// history.Store). The query parameters select them: `check` (default all), `from` and `to` as
+// RFC 3339 times (default the last 24 hours), and `limit` (default history.DefaultLimit, at most
+// history.MaxLimit). Results are kept for removed servers too, until they expire.
+func (app *Application) checkHistoryHandler(w http.ResponseWriter, r *http.Request) {
+	serverID, err := strconv.Atoi(httprouter.ParamsFromContext(r.Context()).ByName("id"))
+	if err != nil {
+		app.writeJSON(w, http.StatusBadRequest, map[string]string{"error": "invalid server id"})
+		return
+	}
+	params := r.URL.Query()
+	query := history.Query{ServerID: serverID, Check: params.Get("check")}
+	end := time.Now().UTC()
+	if to := params.Get("to"); to != "" {
+		if query.To, err = time.Parse(time.RFC3339, to); err != nil {
+			app.writeJSON(w, http.StatusBadRequest, map[string]string{"error": "invalid to, expected an RFC 3339 time"})
+			return
+		}
+		end = query.To
+	}
+	query.From = end.Add(-defaultHistoryWindow)
+	if from := params.Get("from"); from != "" {
+		if query.From, err = time.Parse(time.RFC3339, from); err != nil {
+			app.writeJSON(w, http.StatusBadRequest, map[string]string{"error": "invalid from, expected an RFC 3339 time"})
+			return
+		}
+	}
+	if limit := params.Get("limit"); limit != "" {
+		if query.Limit, err = strconv.Atoi(limit); err != nil || query.Limit < 1 {
+			app.writeJSON(w, http.StatusBadRequest, map[string]string{"error": "invalid limit, expected a positive integer"})
+			return
+		}
+	}
+
+	results, err := app.History.Query(r.Context(), query)
+	if err != nil {
+		app.Logger.Error("Failed to query the check history", "server_id", serverID, "error", err)
+		app.writeJSON(w, http.StatusInternalServerError, map[string]string{"error": "failed to query the check history"})
+		return
+	}
+	app.writeJSON(w, http.StatusOK, map[string][]history.Result{"results": results})
+}
@@ -0,0 +1,80 @@
+package app
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"watchdog.onebusaway.org/internal/history"
+	"watchdog.onebusaway.org/internal/metrics"
+)
+
+func TestCheckHistoryRoute(t *testing.T) {
+	app := newTestApplication(t)
+	store, err := history.Open(filepath.Join(t.TempDir(), "history.db"), 24*time.Hour)
+	if err != nil {
+		t.Fatalf("failed to open the history: %v", err)
+	}
+	defer store.Close()
+	app.History = store
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	handler := app.Routes(ctx)
+
+	server := app.ConfigService.Config.GetServers()[0]
+	app.recordCheck(server, metrics.CheckServerPing, nil)
+	ratio := 0.9
+	app.recordCheckValue(server, metrics.CheckVehicleCount, &ratio, nil)
+	app.recordCheck(server, metrics.CheckRealtimeFeed, errors.New("feed unavailable"))
+
+	var body struct {
+		Results []history.Result `json:"results"`
+	}
+	rr := httptest.NewRecorder()
+	handler.ServeHTTP(rr, httptest.NewRequest(http.MethodGet, "/v1/servers/1/history", nil))
+	if rr.Code != http.StatusOK {
+		t.Fatalf("expected 200, got %d: %s", rr.Code, rr.Body.String())
+	}
+	if err := json.Unmarshal(rr.Body.Bytes(), &body); err != nil {
+		t.Fatal(err)
+	}
+	if len(body.Results) != 3 || body.Results[1].Value == nil || *body.Results[1].Value != ratio || body.Results[2].OK || body.Results[2].Error != "feed unavailable" {
+		t.Errorf("unexpected results %+v", body.Results)
+	}
+
+	rr = httptest.NewRecorder()
+	handler.ServeHTTP(rr, httptest.NewRequest(http.MethodGet, "/v1/servers/1/history?check=server_ping&limit=5", nil))
+	if err := json.Unmarshal(rr.Body.Bytes(), &body); err != nil || len(body.Results) != 1 || body.Results[0].Check != metrics.CheckServerPing {
+		t.Errorf("expected the ping only, got %+v (%v)", body.Results, err)
+	}
+
+	rr = httptest.NewRecorder()
+	// The end of the period is excluded, so it is set past the results just recorded.
+	to := time.Now().Add(time.Minute).UTC().Format(time.RFC3339)
+	handler.ServeHTTP(rr, httptest.NewRequest(http.MethodGet, "/v1/reports/sla?to="+to, nil))
+	var report history.Report
+	if err := json.Unmarshal(rr.Body.Bytes(), &report); err != nil || rr.Code != http.StatusOK {
+		t.Fatalf("expected a report, got %d: %s", rr.Code, rr.Body.String())
+	}
+	if len(report.Servers) != 1 || report.Servers[0].ServerName != server.Name || report.Servers[0].Availability == nil || *report.Servers[0].Availability != 100 {
+		t.Errorf("unexpected report %+v", report.Servers)
+	}
+	rr = httptest.NewRecorder()
+	handler.ServeHTTP(rr, httptest.NewRequest(http.MethodGet, "/v1/reports/sla?format=csv&server_id=1", nil))
+	if rr.Code != http.StatusOK || rr.Header().Get("Content-Type") != "text/csv; charset=utf-8" {
+		t.Errorf("expected a CSV report, got %d %q", rr.Code, rr.Header().Get("Content-Type"))
+	}
+
+	for _, query := range []string{"from=yesterday", "to=2025-06-01", "limit=0"} {
+		rr = httptest.NewRecorder()
+		handler.ServeHTTP(rr, httptest.NewRequest(http.MethodGet, "/v1/servers/1/history?"+query, nil))
+		if rr.Code != http.StatusBadRequest {
+			t.Errorf("%s: expected 400, got %d", query, rr.Code)
+		}
+	}
+}
@@ -0,0 +1,87 @@
+package app
+
+import (
+	"context"
+	"sync"
+
+	"watchdog.onebusaway.org/internal/models"
+	"watchdog.onebusaway.org/internal/shutdown"
+)
+
+// ColdStart downloads the GTFS static bundles and runs the first round of checks for the
+// given servers, one priority tier at a time.
+//
+// On startup the watchdog is blind until a server's bundle is downloaded and its first checks
+// have run. Downloading every bundle at once makes the most important agencies compete for
+// bandwidth with test servers, so servers are grouped by their `priority_tier`:
+//   - All bundles of a tier are downloaded concurrently (see GtfsService.DownloadGTFSBundles).
+//   - The first checks of each server run as soon as its bundle is downloaded (see
+//     CollectMetricsForServer), instead of waiting for the first scheduled collection. A
+//     standby replica (see leader.Elector) only downloads the bundles, to be ready to take over.
+//   - Metrics are pushed to the Pushgateway, if configured, so the tier's first results are visible.
+//   - Only then does the next, lower-priority tier start.
+//
+// The progress of every server is recorded in app.Bootstrap, which tells when enough bundles
+// are loaded for the watchdog to be ready while the rest of the cold start goes on.
+//
+// ColdStart returns once every tier has been processed or ctx is canceled. On shutdown (see
+// shutdown.Coordinator), the tier in progress is finished and the remaining tiers are skipped.
+//
+// Parameters:
+//   - ctx: Context used to cancel downloads and skip the remaining tiers.
+//   - servers: The configured OBA servers.
+//   - maxRetries: The maximum number of retries for each bundle download.
+func (app *Application) ColdStart(ctx context.Context, servers []models.ObaServer, maxRetries int) {
+	done, ok := shutdown.Begin(ctx)
+	if !ok {
+		return
+	}
+	defer done()
+	if app.Bootstrap != nil {
+		app.Bootstrap.start(servers, func(id int) bool { _, ok := app.GtfsService.StaticStore.Get(id); return ok })
+		defer app.Bootstrap.finish()
+	}
+	for _, tier := range models.GroupServersByPriorityTier(servers) {
+		select {
+		case <-ctx.Done():
+			return
+		case <-shutdown.Stopping(ctx):
+			app.Logger.Info("Cold start: shutting down, skipping the remaining tiers")
+			return
+		default:
+		}
+		app.Logger.Info("Cold start: processing priority tier", "tier", tier[0].Tier(), "servers", len(tier))
+		var wg sync.WaitGroup
+		for _, server := range tier {
+			wg.Add(1)
+			go func() {
+				defer wg.Done()
+				app.coldStartServer(ctx, server, maxRetries)
+			}()
+		}
+		wg.Wait()
+		app.pushMetrics(ctx)
+	}
+	app.Logger.Info("Cold start complete", "servers", len(servers))
+}
+
+// coldStartServer downloads the bundle of a server and runs its first checks, recording its
+// progress in app.Bootstrap.
+func (app *Application) coldStartServer(ctx context.Context, server models.ObaServer, maxRetries int) {
+	if app.Bootstrap != nil {
+		if app.Bootstrap.State(server.ID) != bootstrapLoaded {
+			app.Bootstrap.set(server.ID, bootstrapDownloading)
+		}
+		defer func() {
+			if _, ok := app.GtfsService.StaticStore.Get(server.ID); ok {
+				app.Bootstrap.set(server.ID, bootstrapLoaded)
+			} else {
+				app.Bootstrap.set(server.ID, bootstrapFailed)
+			}
+		}()
+	}
+	app.GtfsService.DownloadGTFSBundles(ctx, []models.ObaServer{server}, maxRetries)
+	if app.Leader.IsLeader() {
+		app.CollectMetricsForServer(server)
+	}
+}
@@ -0,0 +1,61 @@
+package app
+
+import (
+	"context"
+	"slices"
+	"strconv"
+
+	"watchdog.onebusaway.org/internal/config"
+	"watchdog.onebusaway.org/internal/metrics"
+	"watchdog.onebusaway.org/internal/models"
+	"watchdog.onebusaway.org/internal/shutdown"
+	"watchdog.onebusaway.org/internal/vantage"
+)
+
+// bundleFields are the settings of a server whose change makes its GTFS bundle stale.
+var bundleFields = []string{"gtfs_url", "gtfs_urls", "proxy_url", "ca_cert_files"}
+
+// applyConfigChanges updates the state of the servers changed by a refresh of the remote
+// configuration, leaving the other servers alone:
+//   - the GTFS bundles of added servers, and of modified servers whose bundleFields changed,
+//     are downloaded right away rather than at the next scheduled bundle refresh;
+//   - the check results, predictions and circuit breaker of removed servers are dropped, as
+//     when a server is removed through the admin API;
+//   - the circuit breaker of modified servers is reset, so that a fixed URL is checked on the
+//     next collection cycle.
+func (app *Application) applyConfigChanges(ctx context.Context, changes config.ServerChanges) {
+	var stale []models.ObaServer
+	for _, server := range changes.Added {
+		if !server.IsURLTarget() {
+			stale = append(stale, server)
+		}
+	}
+	for _, server := range changes.Modified {
+		app.ConfigService.BackoffStore.ResetBackoff(server.ID)
+		metrics.CircuitBreakerState.DeleteLabelValues(strconv.Itoa(server.ID))
+		if !server.IsURLTarget() && slices.ContainsFunc(changes.Fields[server.ID], func(field string) bool {
+			return slices.Contains(bundleFields, field)
+		}) {
+			stale = append(stale, server)
+		}
+	}
+	for _, server := range changes.Removed {
+		app.forgetServer(server.ID)
+	}
+	// While shutting down, the bundles are downloaded on the next start instead.
+	if len(stale) > 0 {
+		shutdown.Go(ctx, func() { app.GtfsService.DownloadGTFSBundles(ctx, stale, 5) })
+	}
+}
+
+// forgetServer drops the state kept about a server that is no longer monitored.
+func (app *Application) forgetServer(serverID int) {
+	app.MetricsService.CheckResults.Delete(serverID)
+	app.MetricsService.Predictions.Delete(serverID)
+	app.Vantage.Delete(serverID)
+	app.CycleTraces.Delete(serverID)
+	app.Maintenance.Clear(serverID)
+	vantage.UnreachableFromPrimaryGauge.DeleteLabelValues(strconv.Itoa(serverID))
+	app.ConfigService.BackoffStore.ResetBackoff(serverID)
+	metrics.CircuitBreakerState.DeleteLabelValues(strconv.Itoa(serverID))
+}
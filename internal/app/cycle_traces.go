@@ -0,0 +1,76 @@
+package app
+
+import (
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/julienschmidt/httprouter"
+	"watchdog.onebusaway.org/internal/cycletrace"
+	"watchdog.onebusaway.org/internal/gtfs"
+	"watchdog.onebusaway.org/internal/metrics"
+	"watchdog.onebusaway.org/internal/models"
+)
+
+// checkPhases is the phase of the time of each step of CollectMetricsForServer: the checks that
+// call the OBA API, and those that only look at the stored bundle and feed. The fetch of the
+// GTFS-RT feed is broken down further by the fetch itself (see recordFetchTimings); its
+// remaining time, e.g. setting up the request, counts as download.
+var checkPhases = map[string]string{
+	metrics.CheckServerPing:           cycletrace.PhaseAPI,
+	metrics.CheckBundleExpiration:     cycletrace.PhaseAnalysis,
+	metrics.CheckFeedInfo:             cycletrace.PhaseAnalysis,
+	metrics.CheckAgenciesWithCoverage: cycletrace.PhaseAPI,
+	metrics.CheckStopsMatch:           cycletrace.PhaseAPI,
+	metrics.CheckRoutesMatch:          cycletrace.PhaseAPI,
+	metrics.CheckObaAPI:               cycletrace.PhaseAPI,
+	metrics.CheckPredictionAccuracy:   cycletrace.PhaseAPI,
+	metrics.CheckRealtimeFeed:         cycletrace.PhaseDownload,
+	metrics.CheckVehicleCount:         cycletrace.PhaseAPI,
+	metrics.CheckIngestionLag:         cycletrace.PhaseAPI,
+	metrics.CheckTripCoverage:         cycletrace.PhaseAnalysis,
+	metrics.CheckVehicleTelemetry:     cycletrace.PhaseAnalysis,
+	metrics.CheckInvalidVehicles:      cycletrace.PhaseAnalysis,
+}
+
+// markCycleStep counts the time since the previous step of the cycle of a server in the trace of
+// the cycle, if check is a step of CollectMetricsForServer (see checkPhases).
+func (app *Application) markCycleStep(server models.ObaServer, check string, at time.Time) {
+	if phase, ok := checkPhases[check]; ok {
+		app.CycleTraces.Mark(server.ID, check, phase, at)
+	}
+}
+
+// recordFetchTimings counts the download, parse and store of a fetch of a feed in the trace of
+// the cycle of a server, as parts of check.
+func (app *Application) recordFetchTimings(server models.ObaServer, check string, timings gtfs.FetchTimings) {
+	app.CycleTraces.Add(server.ID, check, cycletrace.PhaseDownload, timings.Download)
+	app.CycleTraces.Add(server.ID, check, cycletrace.PhaseParse, timings.Parse)
+	app.CycleTraces.Add(server.ID, check, cycletrace.PhaseStore, timings.Store)
+}
+
+// finishCycleTrace ends the trace of the cycle of a server, and logs it at debug level.
+func (app *Application) finishCycleTrace(server models.ObaServer) {
+	trace, ok := app.CycleTraces.Finish(server.ID, time.Now())
+	if !ok {
+		return
+	}
+	app.Logger.Debug("Collection cycle timings", "server_id", server.ID, "seconds", trace.Seconds, "phases", trace.Phases)
+}
+
+// cycleTracesHandler returns the breakdown of the time of the last collection cycles of a
+// server, the most recent first.
+func (app *Application) cycleTracesHandler(w http.ResponseWriter, r *http.Request) {
+	serverID, err := strconv.Atoi(httprouter.ParamsFromContext(r.Context()).ByName("id"))
+	if err != nil {
+		app.writeJSON(w, http.StatusBadRequest, map[string]string{"error": "invalid server id"})
+		return
+	}
+	for _, server := range app.ConfigService.Config.GetServers() {
+		if server.ID == serverID {
+			app.writeJSON(w, http.StatusOK, map[string]any{"server_id": serverID, "cycles": app.CycleTraces.Get(serverID)})
+			return
+		}
+	}
+	app.writeJSON(w, http.StatusNotFound, map[string]string{"error": "unknown server id"})
+}
@@ -0,0 +1,66 @@
+package app
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"watchdog.onebusaway.org/internal/cycletrace"
+	"watchdog.onebusaway.org/internal/metrics"
+)
+
+func TestCycleTraces(t *testing.T) {
+	app := newTestApplication(t)
+	app.CycleTraces = cycletrace.NewStore(cycletrace.DefaultKeep)
+
+	obaServer := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusServiceUnavailable)
+	}))
+	defer obaServer.Close()
+	server := app.ConfigService.Config.Servers[0]
+	server.ObaBaseURL = obaServer.URL
+	app.CollectMetricsForServer(server)
+
+	handler := app.Routes(context.Background())
+
+	rr := httptest.NewRecorder()
+	handler.ServeHTTP(rr, httptest.NewRequest(http.MethodGet, "/v1/servers/1/cycles", nil))
+	if rr.Code != http.StatusOK {
+		t.Fatalf("expected status 200, got %d: %s", rr.Code, rr.Body)
+	}
+	var response struct {
+		Cycles []cycletrace.Trace `json:"cycles"`
+	}
+	if err := json.Unmarshal(rr.Body.Bytes(), &response); err != nil {
+		t.Fatalf("failed to decode response: %v", err)
+	}
+	if len(response.Cycles) != 1 {
+		t.Fatalf("expected one cycle, got %+v", response.Cycles)
+	}
+	// The failed ping ends the cycle, so it is its only step.
+	steps := response.Cycles[0].Steps
+	if len(steps) != 1 || steps[0].Name != metrics.CheckServerPing || steps[0].Phase != cycletrace.PhaseAPI {
+		t.Errorf("expected the ping as the only step, got %+v", steps)
+	}
+	if _, ok := response.Cycles[0].Phases[cycletrace.PhaseAPI]; !ok {
+		t.Errorf("expected time in the api phase, got %v", response.Cycles[0].Phases)
+	}
+
+	rr = httptest.NewRecorder()
+	handler.ServeHTTP(rr, httptest.NewRequest(http.MethodGet, "/v1/servers/1/status", nil))
+	var status serverStatus
+	if err := json.Unmarshal(rr.Body.Bytes(), &status); err != nil {
+		t.Fatalf("failed to decode status: %v", err)
+	}
+	if status.LastCycle == nil || !status.LastCycle.StartedAt.Equal(response.Cycles[0].StartedAt) {
+		t.Errorf("expected the last cycle in the status, got %+v", status.LastCycle)
+	}
+
+	rr = httptest.NewRecorder()
+	handler.ServeHTTP(rr, httptest.NewRequest(http.MethodGet, "/v1/servers/99/cycles", nil))
+	if rr.Code != http.StatusNotFound {
+		t.Errorf("expected status 404 for an unknown server, got %d", rr.Code)
+	}
+}
@@ -0,0 +1,61 @@
+package app
+
+import (
+	"bytes"
+	"html/template"
+	"net/http"
+
+	"watchdog.onebusaway.org/internal/i18n"
+)
+
+var dashboardTemplate = template.Must(template.ParseFS(webFS, "web/templates/dashboard.html"))
+
+// dashboardMessages are the messages used by the script of the dashboard, which renders
+// the servers in the browser.
+var dashboardMessages = []string{
+	"dashboard.none",
+	"dashboard.never",
+	"dashboard.age_seconds",
+	"dashboard.age_minutes",
+	"dashboard.age_hours",
+	"dashboard.expires_in",
+	"dashboard.expired",
+	"dashboard.updated",
+	"dashboard.load_error",
+	"health.up",
+	"health.degraded",
+	"health.down",
+	"health.unknown",
+}
+
+// dashboard is the data of the dashboard template.
+type dashboard struct {
+	Lang string
+	// Messages are the messages of dashboardMessages in the language of the page, unformatted.
+	Messages map[string]string
+}
+
+// T translates a message into the language of the page.
+func (d dashboard) T(key string, args ...interface{}) string {
+	return i18n.T(d.Lang, key, args...)
+}
+
+// dashboardHandler serves the dashboard, a single page for operators without Grafana. The page
+// is only a shell: its script (web/static/dashboard.js) loads the servers from the status API
+// and shows their health, the age of their realtime feed, the expiration of their bundle and
+// the errors of their failing checks.
+func (app *Application) dashboardHandler(w http.ResponseWriter, r *http.Request) {
+	page := dashboard{Lang: pageLanguage(r), Messages: make(map[string]string, len(dashboardMessages))}
+	for _, key := range dashboardMessages {
+		page.Messages[key] = page.T(key)
+	}
+
+	var buf bytes.Buffer
+	if err := dashboardTemplate.Execute(&buf, page); err != nil {
+		app.Logger.Error("failed to render dashboard", "error", err)
+		http.Error(w, "failed to render dashboard", http.StatusInternalServerError)
+		return
+	}
+	w.Header().Set("Content-Type", "text/html; charset=utf-8")
+	_, _ = w.Write(buf.Bytes())
+}
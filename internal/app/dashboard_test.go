@@ -0,0 +1,50 @@
+package app
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func TestDashboard(t *testing.T) {
+	app := newTestApplication(t)
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	handler := app.Routes(ctx)
+
+	rr := httptest.NewRecorder()
+	handler.ServeHTTP(rr, httptest.NewRequest(http.MethodGet, "/ui?lang=es", nil))
+	if rr.Code != http.StatusOK {
+		t.Fatalf("expected 200, got %d", rr.Code)
+	}
+	body := rr.Body.String()
+	if !strings.Contains(body, "<title>Panel del watchdog</title>") || !strings.Contains(body, `<script src="/static/dashboard.js" defer></script>`) {
+		t.Errorf("unexpected dashboard page:\n%s", body)
+	}
+
+	// The messages of the script are embedded as JSON.
+	start := strings.Index(body, `<script type="application/json" id="messages">`)
+	end := strings.Index(body[start:], "</script>")
+	if start < 0 || end < 0 {
+		t.Fatal("expected the page to embed its messages")
+	}
+	var messages map[string]string
+	raw := body[start+len(`<script type="application/json" id="messages">`) : start+end]
+	if err := json.Unmarshal([]byte(raw), &messages); err != nil {
+		t.Fatalf("failed to decode the messages %q: %v", raw, err)
+	}
+	if messages["dashboard.expires_in"] != "%s (en %d días)" || messages["health.down"] != "caído" {
+		t.Errorf("unexpected messages %v", messages)
+	}
+
+	for _, path := range []string{"/static/dashboard.js", "/static/dashboard.css"} {
+		rr = httptest.NewRecorder()
+		handler.ServeHTTP(rr, httptest.NewRequest(http.MethodGet, path, nil))
+		if rr.Code != http.StatusOK {
+			t.Errorf("expected %s to be served, got %d", path, rr.Code)
+		}
+	}
+}
@@ -0,0 +1,47 @@
+package app
+
+import (
+	"fmt"
+	"slices"
+	"strconv"
+	"strings"
+
+	"watchdog.onebusaway.org/internal/config"
+	"watchdog.onebusaway.org/internal/metrics"
+	"watchdog.onebusaway.org/internal/models"
+)
+
+// duplicateNotes describes the URLs a server shares with other servers, e.g.
+// "oba_base_url https://api.example.com is also configured for server 2".
+func duplicateNotes(serverID int, duplicates []config.DuplicateURL) []string {
+	var notes []string
+	for _, duplicate := range duplicates {
+		if !slices.Contains(duplicate.ServerIDs, serverID) {
+			continue
+		}
+		var others []string
+		for _, id := range duplicate.ServerIDs {
+			if id != serverID {
+				others = append(others, strconv.Itoa(id))
+			}
+		}
+		noun := "server"
+		if len(others) > 1 {
+			noun = "servers"
+		}
+		notes = append(notes, fmt.Sprintf("%s %s is also configured for %s %s", duplicate.Field, duplicate.URL, noun, strings.Join(others, ", ")))
+	}
+	return notes
+}
+
+// recordDuplicateServers exports, for each server, the number of other servers sharing its
+// OBA base URL or GTFS URL (see config.FindDuplicateServers). Series of servers that no
+// longer have duplicates are removed.
+func recordDuplicateServers(servers []models.ObaServer) {
+	metrics.DuplicateServers.Reset()
+	for _, duplicate := range config.FindDuplicateServers(servers) {
+		for _, id := range duplicate.ServerIDs {
+			metrics.DuplicateServers.WithLabelValues(strconv.Itoa(id), duplicate.Field).Set(float64(len(duplicate.ServerIDs) - 1))
+		}
+	}
+}
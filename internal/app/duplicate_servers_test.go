@@ -0,0 +1,39 @@
+package app
+
+import (
+	"reflect"
+	"testing"
+
+	"watchdog.onebusaway.org/internal/config"
+	"watchdog.onebusaway.org/internal/metrics"
+	"watchdog.onebusaway.org/internal/models"
+)
+
+func TestDuplicateServers(t *testing.T) {
+	servers := []models.ObaServer{
+		{ID: 1, ObaBaseURL: "https://api.example.com", GtfsUrl: "https://example.com/gtfs.zip"},
+		{ID: 2, ObaBaseURL: "https://api.example.com"},
+		{ID: 3, ObaBaseURL: "https://other.example.com", GtfsUrl: "https://example.com/gtfs.zip"},
+		{ID: 4, ObaBaseURL: "https://api.example.com"},
+	}
+	duplicates := config.FindDuplicateServers(servers)
+
+	expected := []string{
+		"gtfs_url https://example.com/gtfs.zip is also configured for server 3",
+		"oba_base_url https://api.example.com is also configured for servers 2, 4",
+	}
+	if got := duplicateNotes(1, duplicates); !reflect.DeepEqual(got, expected) {
+		t.Errorf("expected notes %q, got %q", expected, got)
+	}
+
+	recordDuplicateServers(servers)
+	if got := collectMetric(t, metrics.DuplicateServers.WithLabelValues("2", "oba_base_url")).GetGauge().GetValue(); got != 2 {
+		t.Errorf("expected server 2 to share its OBA base URL with 2 servers, got %v", got)
+	}
+
+	// Fixing the configuration removes the series.
+	recordDuplicateServers(servers[2:])
+	if removed := metrics.DuplicateServers.DeletePartialMatch(map[string]string{"server_id": "2"}); removed != 0 {
+		t.Errorf("expected the series of server 2 to be removed, found %d", removed)
+	}
+}
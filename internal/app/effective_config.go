@@ -0,0 +1,51 @@
+package app
+
+import (
+	"net/http"
+
+	"watchdog.onebusaway.org/internal/httpclient"
+	"watchdog.onebusaway.org/internal/models"
+)
+
+// effectiveConfig is the response of GET /v1/admin/config.
+type effectiveConfig struct {
+	// HTTPTimeouts are the global timeouts of outgoing requests.
+	HTTPTimeouts models.HTTPTimeouts     `json:"http_timeouts"`
+	Servers      []effectiveServerConfig `json:"servers"`
+}
+
+// effectiveServerConfig is the effective configuration of a server.
+type effectiveServerConfig struct {
+	// Server is the configuration of the server, i.e. after feed settings were derived from
+	// its data sources, with its secrets redacted.
+	Server models.ObaServer `json:"server"`
+	// HTTPTimeouts are the timeouts of the requests to the hosts of the server: its own
+	// timeouts, and the global ones for those it does not set.
+	HTTPTimeouts models.HTTPTimeouts `json:"http_timeouts"`
+}
+
+// effectiveConfigHandler returns the configuration in effect, once the defaults and the
+// overrides of each server are applied, so operators can check what a server actually runs
+// with.
+func (app *Application) effectiveConfigHandler(w http.ResponseWriter, r *http.Request) {
+	servers := app.ConfigService.Config.GetServers()
+	response := effectiveConfig{HTTPTimeouts: timeoutsJSON(app.HTTPTimeouts), Servers: make([]effectiveServerConfig, 0, len(servers))}
+	for _, server := range servers {
+		response.Servers = append(response.Servers, effectiveServerConfig{
+			Server:       redactServer(server),
+			HTTPTimeouts: timeoutsJSON(app.HTTPTimeouts.ForServer(server)),
+		})
+	}
+	app.writeJSON(w, http.StatusOK, response)
+}
+
+// timeoutsJSON returns the timeouts as written in config files.
+func timeoutsJSON(t httpclient.Timeouts) models.HTTPTimeouts {
+	return models.HTTPTimeouts{
+		Connect:      models.Duration(t.Connect),
+		TLSHandshake: models.Duration(t.TLSHandshake),
+		API:          models.Duration(t.API),
+		Realtime:     models.Duration(t.Realtime),
+		Bundle:       models.Duration(t.Bundle),
+	}
+}
@@ -0,0 +1,51 @@
+package app
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"watchdog.onebusaway.org/internal/auth"
+	"watchdog.onebusaway.org/internal/models"
+)
+
+func TestEffectiveConfigHandler(t *testing.T) {
+	app := newTestApplication(t)
+	app.Authenticator = testAuthenticator{"viewer": auth.RoleViewer}
+	app.ConfigService.Config.Servers[0].Timeouts = &models.HTTPTimeouts{Realtime: models.Duration(30 * time.Second)}
+	handler := app.Routes(context.Background())
+
+	r := httptest.NewRequest(http.MethodGet, "/v1/admin/config", nil)
+	rr := httptest.NewRecorder()
+	handler.ServeHTTP(rr, r)
+	if rr.Code != http.StatusUnauthorized {
+		t.Errorf("expected status 401 without credentials, got %d", rr.Code)
+	}
+
+	r.Header.Set("Authorization", "Bearer viewer")
+	rr = httptest.NewRecorder()
+	handler.ServeHTTP(rr, r)
+	if rr.Code != http.StatusOK {
+		t.Fatalf("expected status 200, got %d: %s", rr.Code, rr.Body)
+	}
+	var response effectiveConfig
+	if err := json.Unmarshal(rr.Body.Bytes(), &response); err != nil {
+		t.Fatal(err)
+	}
+	if response.HTTPTimeouts.Realtime.Std() != app.HTTPTimeouts.Realtime {
+		t.Errorf("expected the global realtime timeout, got %v", response.HTTPTimeouts.Realtime.Std())
+	}
+	if len(response.Servers) != 1 {
+		t.Fatalf("expected 1 server, got %d", len(response.Servers))
+	}
+	server := response.Servers[0]
+	if server.HTTPTimeouts.Realtime.Std() != 30*time.Second || server.HTTPTimeouts.API.Std() != app.HTTPTimeouts.API {
+		t.Errorf("expected the realtime timeout of the server and the global API timeout, got %+v", server.HTTPTimeouts)
+	}
+	if server.Server.ObaApiKey != redacted {
+		t.Errorf("expected the API key to be redacted, got %q", server.Server.ObaApiKey)
+	}
+}
@@ -0,0 +1,62 @@
+package app
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/getsentry/sentry-go"
+	"watchdog.onebusaway.org/internal/config"
+	"watchdog.onebusaway.org/internal/metrics"
+	"watchdog.onebusaway.org/internal/report"
+	"watchdog.onebusaway.org/internal/scheduler"
+)
+
+// RunExecChecks runs the custom exec checks of every server (its `exec_checks`) at every
+// activation of schedule, until ctx is canceled, and records their results as the checks
+// "exec:<name>" (see metrics.ExecCheckName).
+//
+// The checks run on their own schedule rather than in every collection cycle, since external
+// commands are usually slower than the built-in checks. Servers whose circuit is open are skipped,
+// and so are all servers on a standby replica (see leader.Elector). Nothing is run unless the
+// exec checks are enabled (see config.Config.AllowExecChecks). The commands run under ctx, so
+// that canceling it, e.g. at the end of the shutdown timeout, kills the checks in flight.
+func (app *Application) RunExecChecks(ctx context.Context, schedule scheduler.Schedule) {
+	scheduler.Run(ctx, "exec_checks", schedule, func() { app.runExecChecks(ctx) })
+	app.Logger.Info("Stopping exec checks")
+}
+
+// runExecChecks runs the exec checks of every server once, one after the other (see RunExecChecks).
+func (app *Application) runExecChecks(ctx context.Context) {
+	if !app.ConfigService.Config.AllowExecChecks || !app.Leader.IsLeader() {
+		return
+	}
+	for _, server := range app.ConfigService.Config.GetServers() {
+		if len(server.ExecChecks) == 0 {
+			continue
+		}
+		if state, _ := app.ConfigService.BackoffStore.CircuitState(server.ID, time.Now()); state == config.CircuitOpen {
+			continue
+		}
+		for _, check := range server.ExecChecks {
+			if ctx.Err() != nil {
+				return
+			}
+			metrics.ChecksInProgress.WithLabelValues("exec").Inc()
+			err := app.MetricsService.RunExecCheck(ctx, server, check)
+			metrics.ChecksInProgress.WithLabelValues("exec").Dec()
+			app.recordCheck(server, metrics.ExecCheckName(check), err)
+			if err != nil {
+				app.Logger.Error("Exec check failed", "server_id", server.ID, "check", check.Name, "error", err)
+				report.ReportErrorWithSentryOptions(err, report.SentryReportOptions{
+					Tags: map[string]string{
+						"server_id":   fmt.Sprintf("%d", server.ID),
+						"server_name": server.Name,
+						"check":       metrics.ExecCheckName(check),
+					},
+					Level: sentry.LevelWarning,
+				})
+			}
+		}
+	}
+}
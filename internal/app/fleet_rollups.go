@@ -0,0 +1,43 @@
+package app
+
+import (
+	"time"
+
+	"watchdog.onebusaway.org/internal/metrics"
+	"watchdog.onebusaway.org/internal/models"
+)
+
+// recordFleetRollups exports the number of servers of each region, of those that are unhealthy
+// (see serverHealth) and of those whose GTFS-RT feed is stale, so that alert rules and dashboard
+// tiles can use them without aggregating the series of every server. A feed is stale when its
+// last fetch failed or its last successful fetch is older than staleAfter; servers whose feed
+// was not fetched yet count as neither. Series of regions that no longer have servers are
+// removed.
+func recordFleetRollups(servers []models.ObaServer, checkResults *metrics.CheckResultStore, staleAfter time.Duration, now time.Time) {
+	type rollup struct{ total, unhealthy, stale int }
+	regions := make(map[string]*rollup)
+	for _, server := range servers {
+		r := regions[server.Region]
+		if r == nil {
+			r = &rollup{}
+			regions[server.Region] = r
+		}
+		r.total++
+		results := checkResults.Get(server.ID)
+		if health := serverHealth(results); health == healthDown || health == healthDegraded {
+			r.unhealthy++
+		}
+		if feed, ok := results[metrics.CheckRealtimeFeed]; ok && (!feed.OK || now.Sub(feed.LastSuccessAt) > staleAfter) {
+			r.stale++
+		}
+	}
+
+	metrics.ServersTotalGauge.Reset()
+	metrics.ServersUnhealthyGauge.Reset()
+	metrics.FeedsStaleGauge.Reset()
+	for region, r := range regions {
+		metrics.ServersTotalGauge.WithLabelValues(region).Set(float64(r.total))
+		metrics.ServersUnhealthyGauge.WithLabelValues(region).Set(float64(r.unhealthy))
+		metrics.FeedsStaleGauge.WithLabelValues(region).Set(float64(r.stale))
+	}
+}
@@ -0,0 +1,52 @@
+package app
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"watchdog.onebusaway.org/internal/metrics"
+	"watchdog.onebusaway.org/internal/models"
+)
+
+func TestRecordFleetRollups(t *testing.T) {
+	now := time.Date(2025, 6, 1, 12, 0, 0, 0, time.UTC)
+	servers := []models.ObaServer{
+		{ID: 1, Name: "Seattle", Region: "Washington"},
+		{ID: 2, Name: "Tacoma", Region: "Washington"},
+		{ID: 3, Name: "Spokane", Region: "Washington"},
+		{ID: 4, Name: "Test"},
+	}
+	results := metrics.NewCheckResultStore()
+	results.Record(1, metrics.CheckServerPing, nil, now)
+	results.Record(1, metrics.CheckRealtimeFeed, nil, now)
+	// Server 2 is down, and its feed failed.
+	results.Record(2, metrics.CheckServerPing, errors.New("timeout"), now)
+	results.Record(2, metrics.CheckRealtimeFeed, errors.New("timeout"), now)
+	// The feed of server 3 was last fetched too long ago.
+	results.Record(3, metrics.CheckRealtimeFeed, nil, now.Add(-time.Hour))
+
+	recordFleetRollups(servers, results, 10*time.Minute, now)
+	for _, want := range []struct {
+		gauge  string
+		region string
+		got    float64
+		value  float64
+	}{
+		{"servers_total", "Washington", collectMetric(t, metrics.ServersTotalGauge.WithLabelValues("Washington")).GetGauge().GetValue(), 3},
+		{"servers_total", "", collectMetric(t, metrics.ServersTotalGauge.WithLabelValues("")).GetGauge().GetValue(), 1},
+		{"servers_unhealthy", "Washington", collectMetric(t, metrics.ServersUnhealthyGauge.WithLabelValues("Washington")).GetGauge().GetValue(), 1},
+		{"servers_unhealthy", "", collectMetric(t, metrics.ServersUnhealthyGauge.WithLabelValues("")).GetGauge().GetValue(), 0},
+		{"feeds_stale", "Washington", collectMetric(t, metrics.FeedsStaleGauge.WithLabelValues("Washington")).GetGauge().GetValue(), 2},
+	} {
+		if want.got != want.value {
+			t.Errorf("expected %s{region=%q} = %v, got %v", want.gauge, want.region, want.value, want.got)
+		}
+	}
+
+	// Regions without servers lose their series.
+	recordFleetRollups(servers[3:], results, 10*time.Minute, now)
+	if removed := metrics.ServersTotalGauge.DeletePartialMatch(map[string]string{"region": "Washington"}); removed != 0 {
+		t.Errorf("expected the series of the removed region to be removed, found %d", removed)
+	}
+}
@@ -2,7 +2,12 @@ package app
 
 import (
 	"encoding/json"
+	"fmt"
 	"net/http"
+	"slices"
+	"time"
+
+	"watchdog.onebusaway.org/internal/report"
 )
 
 // HealthStatus defines the structure of the JSON response returned by the
@@ -20,6 +25,8 @@ import (
 //   - Servers: The number of OBA (OneBusAway) backend servers currently configured and used.
 //   - Ready: A boolean flag indicating whether the application is ready to serve traffic.
 //     The application is considered "ready" if at least one backend server is configured.
+//   - ConfigLastRefresh, ConfigStale: The last successful load of the remote configuration, and
+//     whether it is stale (only with a config URL).
 //
 // This struct is constructed and serialized to JSON by the `healthcheckHandler`,
 // and it plays a central role in operational observability and readiness checks.
@@ -29,6 +36,14 @@ type HealthStatus struct {
 	Version     string `json:"version"`
 	Servers     int    `json:"servers"`
 	Ready       bool   `json:"ready"`
+	// ConfigLastRefresh is when the remote configuration was last loaded successfully, and
+	// ConfigStale whether too many reloads failed since (see config.Config.ConfigStale). Both are
+	// left out without a config URL.
+	ConfigLastRefresh *time.Time `json:"config_last_refresh,omitempty"`
+	ConfigStale       bool       `json:"config_stale,omitempty"`
+	// Role is "leader" or "standby" with leader election, in which case the check results of a
+	// standby are those of the last time it led, so stale. It is left out without election.
+	Role string `json:"role,omitempty"`
 }
 
 // healthcheckHandler responds with a JSON representation of the application's health status.
@@ -50,6 +65,18 @@ func (app *Application) healthcheckHandler(w http.ResponseWriter, r *http.Reques
 		Servers:     numServers,
 		Ready:       ready,
 	}
+	if cfg := app.ConfigService.Config; cfg.ConfigURL != "" {
+		if lastSuccess := cfg.LastRefresh().LastSuccess; !lastSuccess.IsZero() {
+			status.ConfigLastRefresh = &lastSuccess
+		}
+		status.ConfigStale = cfg.ConfigStale(time.Now())
+	}
+	if app.Leader != nil {
+		status.Role = "standby"
+		if app.Leader.IsLeader() {
+			status.Role = "leader"
+		}
+	}
 
 	w.Header().Set("Content-Type", "application/json")
 	if !ready {
@@ -59,3 +86,119 @@ func (app *Application) healthcheckHandler(w http.ResponseWriter, r *http.Reques
 		app.Logger.Warn("failed to write healthcheck response", "error", err)
 	}
 }
+
+// livezHandler is the liveness probe: it answers as long as the process can serve requests,
+// so that an orchestrator only restarts the watchdog when it is stuck, not when a dependency is down.
+func (app *Application) livezHandler(w http.ResponseWriter, r *http.Request) {
+	app.writeJSON(w, http.StatusOK, map[string]string{"status": "alive", "version": app.Version})
+}
+
+// dependencyStatus is the state of a dependency in the response of /v1/readyz.
+type dependencyStatus struct {
+	OK     bool   `json:"ok"`
+	Detail string `json:"detail"`
+	// Required is whether the watchdog is not ready while the dependency fails.
+	Required bool `json:"required"`
+}
+
+// ReadinessStatus is the response of the readiness probe (/v1/readyz).
+type ReadinessStatus struct {
+	// Status is "ready" when every required check is OK, "not_ready" otherwise.
+	Status string                      `json:"status"`
+	Checks map[string]dependencyStatus `json:"checks"`
+}
+
+// External dependencies of the readiness probe, which operators choose to require or only
+// report (see config.Config.ReadinessRequired).
+const (
+	dependencySentry      = "sentry"
+	dependencyConfigURL   = "config_url"
+	dependencyBundleCache = "bundle_cache"
+)
+
+// ReadinessDependencies are the external dependencies the readiness probe can be gated on.
+var ReadinessDependencies = []string{dependencySentry, dependencyConfigURL, dependencyBundleCache}
+
+// readyzHandler is the readiness probe. The watchdog is ready once its configuration is loaded
+// (at least one server), at least one GTFS bundle has been downloaded and stored, the cold
+// start is ready (see Bootstrap), and the external dependencies listed in ReadinessRequired
+// are OK:
+//   - sentry: Sentry is initialized (an invalid DSN or an unreachable Sentry only shows in the
+//     detail: errors are then logged or buffered, not lost);
+//   - config_url: the remote configuration is not stale, i.e. at most ConfigStaleIntervals
+//     scheduled reloads failed since the last successful one (only with a config URL);
+//   - bundle_cache: the GTFS bundle cache directory is writable (only with a cache directory).
+//
+// The other dependencies are still checked and reported, so that a Sentry outage, for example,
+// can be seen without restarting otherwise working pods. It responds with HTTP 200 when ready
+// and 503 otherwise, with the state of each dependency in the body.
+func (app *Application) readyzHandler(w http.ResponseWriter, r *http.Request) {
+	cfg := app.ConfigService.Config
+	checks := make(map[string]dependencyStatus, 5)
+
+	numServers := len(cfg.GetServers())
+	checks["config"] = dependencyStatus{OK: numServers > 0, Detail: fmt.Sprintf("%d servers configured", numServers), Required: true}
+
+	numBundles := 0
+	if app.GtfsService != nil {
+		numBundles = app.GtfsService.StaticStore.Len()
+	}
+	bundleDetail := fmt.Sprintf("%d of %d servers have a GTFS bundle", numBundles, numServers)
+	bootstrap := app.Bootstrap.Progress()
+	if !bootstrap.Complete {
+		bundleDetail += fmt.Sprintf(", cold start in progress (%d of %d loaded, %d failed)", bootstrap.Loaded, bootstrap.Total, bootstrap.Failed)
+	}
+	checks["gtfs_bundle"] = dependencyStatus{OK: numBundles > 0 && bootstrap.Ready, Detail: bundleDetail, Required: true}
+
+	external := func(name string, ok bool, detail string) {
+		checks[name] = dependencyStatus{OK: ok, Detail: detail, Required: slices.Contains(cfg.ReadinessRequired, name)}
+	}
+
+	switch initialized, hasDSN := report.SentryInitialized(); {
+	case report.CurrentReporter().Name() != "sentry":
+		external(dependencySentry, true, fmt.Sprintf("not used, errors are reported with %s", report.CurrentReporter().Name()))
+	case report.InvalidDSN() != nil:
+		external(dependencySentry, true, "invalid DSN, errors are only logged")
+	case !initialized:
+		external(dependencySentry, false, "not initialized")
+	case !hasDSN:
+		external(dependencySentry, true, "initialized without a DSN, errors are not reported")
+	case report.BufferedEvents() > 0:
+		external(dependencySentry, true, fmt.Sprintf("initialized, %d events waiting to be sent again", report.BufferedEvents()))
+	default:
+		external(dependencySentry, true, "initialized")
+	}
+
+	if cfg.ConfigURL != "" {
+		switch refresh := cfg.LastRefresh(); {
+		case refresh.At.IsZero():
+			external(dependencyConfigURL, false, "not reloaded yet")
+		case refresh.Err != nil:
+			detail := fmt.Sprintf("last reload failed %s ago: %v", time.Since(refresh.At).Round(time.Second), refresh.Err)
+			stale := cfg.ConfigStale(time.Now())
+			if stale && !refresh.LastSuccess.IsZero() {
+				detail = fmt.Sprintf("stale, last successful reload %s ago; %s", time.Since(refresh.LastSuccess).Round(time.Second), detail)
+			}
+			external(dependencyConfigURL, !stale, detail)
+		default:
+			external(dependencyConfigURL, true, fmt.Sprintf("reloaded %s ago", time.Since(refresh.At).Round(time.Second)))
+		}
+	}
+
+	if app.GtfsService != nil && app.GtfsService.BundleDiskCache != nil {
+		if err := app.GtfsService.BundleDiskCache.CheckWritable(); err != nil {
+			external(dependencyBundleCache, false, err.Error())
+		} else {
+			external(dependencyBundleCache, true, app.GtfsService.BundleDiskCache.Dir()+" is writable")
+		}
+	}
+
+	status, code := ReadinessStatus{Status: "ready", Checks: checks}, http.StatusOK
+	for _, check := range checks {
+		if check.Required && !check.OK {
+			status.Status, code = "not_ready", http.StatusServiceUnavailable
+			break
+		}
+	}
+	app.writeJSON(w, code, status)
+}
@@ -1,14 +1,23 @@
 package app
 
 import (
+	"context"
 	"encoding/json"
+	"errors"
 	"log/slog"
 	"net/http"
 	"net/http/httptest"
+	"strings"
 	"testing"
+	"time"
 
+	"github.com/getsentry/sentry-go"
 	"watchdog.onebusaway.org/internal/config"
+	"watchdog.onebusaway.org/internal/gtfs"
+	"watchdog.onebusaway.org/internal/leader"
 	"watchdog.onebusaway.org/internal/models"
+	"watchdog.onebusaway.org/internal/report"
+	"watchdog.onebusaway.org/internal/scheduler"
 )
 
 func TestHealthcheckHandler(t *testing.T) {
@@ -65,7 +74,7 @@ func TestHealthcheckHandler(t *testing.T) {
 		)
 		logger := slog.Default()
 		client := http.Client{}
-		backoffStore := config.NewBackoffStore()
+		backoffStore := config.NewBackoffStore(0, 0)
 		app := &Application{
 			ConfigService: config.NewConfigService(logger, &client, cfg, backoffStore),
 			Version:       "test-version",
@@ -112,4 +121,134 @@ func TestHealthcheckHandler(t *testing.T) {
 			t.Errorf("expected version 'test-version', got %q", resp.Version)
 		}
 	})
+
+	t.Run("reports the role of a standby replica", func(t *testing.T) {
+		app := newTestApplication(t)
+		app.Leader = leader.NewElector(heldLock{}, "replica-b", time.Minute, app.Logger)
+		app.Leader.Elect(context.Background())
+
+		rr := httptest.NewRecorder()
+		app.healthcheckHandler(rr, httptest.NewRequest(http.MethodGet, "/v1/healthcheck", nil))
+
+		var resp HealthStatus
+		if err := json.NewDecoder(rr.Body).Decode(&resp); err != nil {
+			t.Fatalf("failed to decode response: %v", err)
+		}
+		if resp.Role != "standby" {
+			t.Errorf("expected role 'standby', got %q", resp.Role)
+		}
+	})
+}
+
+// heldLock is a leader.Lock held by another replica.
+type heldLock struct{}
+
+func (heldLock) Acquire(ctx context.Context, identity string, ttl time.Duration) (bool, error) {
+	return false, nil
+}
+
+func (heldLock) Release(ctx context.Context, identity string) error { return nil }
+
+func TestLivezHandler(t *testing.T) {
+	app := newTestApplication(t)
+	rr := httptest.NewRecorder()
+	app.livezHandler(rr, httptest.NewRequest(http.MethodGet, "/v1/livez", nil))
+	if rr.Code != http.StatusOK {
+		t.Errorf("expected 200, got %d", rr.Code)
+	}
+}
+
+func TestReadyzHandler(t *testing.T) {
+	readyz := func(app *Application) (int, ReadinessStatus) {
+		rr := httptest.NewRecorder()
+		app.readyzHandler(rr, httptest.NewRequest(http.MethodGet, "/v1/readyz", nil))
+		var resp ReadinessStatus
+		if err := json.NewDecoder(rr.Body).Decode(&resp); err != nil {
+			t.Fatalf("failed to decode response: %v", err)
+		}
+		return rr.Code, resp
+	}
+
+	if err := sentry.Init(sentry.ClientOptions{}); err != nil {
+		t.Fatal(err)
+	}
+	app := newTestApplication(t)
+	if code, resp := readyz(app); code != http.StatusOK || resp.Status != "ready" || len(resp.Checks) != 3 {
+		t.Errorf("expected the application to be ready, got %d %+v", code, resp)
+	}
+
+	// Not ready until a GTFS bundle has been stored.
+	app.GtfsService.StaticStore = gtfs.NewStaticStore()
+	code, resp := readyz(app)
+	if code != http.StatusServiceUnavailable || resp.Status != "not_ready" {
+		t.Errorf("expected 503 without a GTFS bundle, got %d %+v", code, resp)
+	}
+	if check := resp.Checks["gtfs_bundle"]; check.OK || check.Detail != "0 of 1 servers have a GTFS bundle" {
+		t.Errorf("unexpected gtfs_bundle check %+v", check)
+	}
+	if !resp.Checks["config"].OK || !resp.Checks["sentry"].OK {
+		t.Errorf("expected the other checks to pass, got %+v", resp.Checks)
+	}
+}
+
+func TestReadyzHandlerDependencies(t *testing.T) {
+	readyz := func(app *Application) (int, ReadinessStatus) {
+		rr := httptest.NewRecorder()
+		app.readyzHandler(rr, httptest.NewRequest(http.MethodGet, "/v1/readyz", nil))
+		var resp ReadinessStatus
+		if err := json.NewDecoder(rr.Body).Decode(&resp); err != nil {
+			t.Fatalf("failed to decode response: %v", err)
+		}
+		return rr.Code, resp
+	}
+
+	if err := sentry.Init(sentry.ClientOptions{}); err != nil {
+		t.Fatal(err)
+	}
+	app := newTestApplication(t)
+	cfg := app.ConfigService.Config
+	cfg.ConfigURL = "https://config.example.com/config.json"
+	cfg.SetLastRefresh(config.RefreshStatus{At: time.Now(), Err: errors.New("remote config returned status: 500")})
+	cache, err := gtfs.NewBundleDiskCache(t.TempDir())
+	if err != nil {
+		t.Fatal(err)
+	}
+	app.GtfsService.BundleDiskCache = cache
+
+	// A failing dependency that is not required is only reported.
+	code, resp := readyz(app)
+	if code != http.StatusOK || resp.Status != "ready" {
+		t.Errorf("expected the application to be ready, got %d %+v", code, resp)
+	}
+	if check := resp.Checks["config_url"]; check.OK || check.Required || !strings.Contains(check.Detail, "status: 500") {
+		t.Errorf("unexpected config_url check %+v", check)
+	}
+	if check := resp.Checks["bundle_cache"]; !check.OK || check.Required {
+		t.Errorf("unexpected bundle_cache check %+v", check)
+	}
+
+	cfg.ReadinessRequired = []string{"config_url", "bundle_cache"}
+	if code, resp := readyz(app); code != http.StatusServiceUnavailable || !resp.Checks["config_url"].Required {
+		t.Errorf("expected a required failing dependency to make the application not ready, got %d %+v", code, resp)
+	}
+
+	cfg.SetLastRefresh(config.RefreshStatus{At: time.Now()})
+	if code, resp := readyz(app); code != http.StatusOK || !resp.Checks["config_url"].OK {
+		t.Errorf("expected the application to be ready after a successful reload, got %d %+v", code, resp)
+	}
+
+	// Sentry is not a dependency when errors are reported elsewhere.
+	report.SetReporter(report.NoopReporter{})
+	if _, resp := readyz(app); !resp.Checks["sentry"].OK || !strings.Contains(resp.Checks["sentry"].Detail, "not used") {
+		t.Errorf("expected Sentry to be reported as not used, got %+v", resp.Checks["sentry"])
+	}
+	report.SetReporter(nil)
+
+	// A failure shortly after a successful reload is tolerated for ConfigStaleIntervals reloads.
+	cfg.ConfigRefreshSchedule = scheduler.Every(time.Minute)
+	cfg.ConfigStaleIntervals = 2
+	cfg.SetLastRefresh(config.RefreshStatus{At: time.Now(), Err: errors.New("remote config returned status: 403")})
+	if code, resp := readyz(app); code != http.StatusOK || !resp.Checks["config_url"].OK || !strings.Contains(resp.Checks["config_url"].Detail, "status: 403") {
+		t.Errorf("expected a recent failure to be reported without making the config stale, got %d %+v", code, resp)
+	}
 }
@@ -0,0 +1,113 @@
+package app
+
+import (
+	"encoding/xml"
+	"fmt"
+	"net/http"
+	"strings"
+	"time"
+
+	"watchdog.onebusaway.org/internal/alert"
+	"watchdog.onebusaway.org/internal/i18n"
+)
+
+// incidentFeedSize is the number of incident events kept for the incident feed.
+const incidentFeedSize = 100
+
+// atomFeed is an Atom (RFC 4287) feed.
+type atomFeed struct {
+	XMLName xml.Name    `xml:"http://www.w3.org/2005/Atom feed"`
+	ID      string      `xml:"id"`
+	Title   string      `xml:"title"`
+	Updated string      `xml:"updated"`
+	Author  atomAuthor  `xml:"author"`
+	Link    atomLink    `xml:"link"`
+	Entries []atomEntry `xml:"entry"`
+}
+
+type atomAuthor struct {
+	Name string `xml:"name"`
+}
+
+type atomLink struct {
+	Rel  string `xml:"rel,attr,omitempty"`
+	Href string `xml:"href,attr"`
+}
+
+type atomEntry struct {
+	ID       string       `xml:"id"`
+	Title    string       `xml:"title"`
+	Updated  string       `xml:"updated"`
+	Category atomCategory `xml:"category"`
+	Link     *atomLink    `xml:"link,omitempty"`
+	Content  atomContent  `xml:"content"`
+}
+
+type atomCategory struct {
+	Term string `xml:"term,attr"`
+}
+
+type atomContent struct {
+	Type string `xml:"type,attr"`
+	Body string `xml:",chardata"`
+}
+
+// incidentEntry converts an incident event into a feed entry. Its ID identifies the event, so
+// that the opening and the resolution of an incident are two entries.
+func incidentEntry(event alert.Alert) atomEntry {
+	status := i18n.T(event.Locale, "alert.status."+string(event.Status))
+	content := []string{
+		event.Description,
+		i18n.T(event.Locale, "alert.field.server") + ": " + fmt.Sprintf("%s (%d)", event.Server.Name, event.Server.ID),
+		i18n.T(event.Locale, "alert.field.check") + ": " + event.Check,
+		i18n.T(event.Locale, "alert.field.firing_since") + ": " + event.StartsAt.UTC().Format(time.RFC3339),
+	}
+	entry := atomEntry{
+		ID:       fmt.Sprintf("urn:watchdog:incident:%d:%s:%d:%s", event.Server.ID, event.Check, event.StartsAt.Unix(), event.Status),
+		Title:    fmt.Sprintf("[%s] %s: %s", status, event.Title, event.Server.Name),
+		Updated:  event.At.UTC().Format(time.RFC3339),
+		Category: atomCategory{Term: string(event.Status)},
+		Content:  atomContent{Type: "text", Body: strings.Join(content, "\n")},
+	}
+	if event.Server.ObaBaseURL != "" {
+		entry.Link = &atomLink{Href: event.Server.ObaBaseURL}
+	}
+	return entry
+}
+
+// incidentFeedHandler serves the incidents as an Atom feed, newest first: an entry when an alert
+// check starts firing, and another when it resolves. Entries are written in the language of
+// the alerts of their server (see alert.Manager).
+func (app *Application) incidentFeedHandler(w http.ResponseWriter, r *http.Request) {
+	scheme := "http"
+	if r.TLS != nil {
+		scheme = "https"
+	}
+	self := scheme + "://" + r.Host + r.URL.Path
+
+	events := app.Incidents.Events()
+	feed := atomFeed{
+		ID:      self,
+		Title:   "OneBusAway Watchdog incidents",
+		Updated: time.Now().UTC().Format(time.RFC3339),
+		Author:  atomAuthor{Name: "OneBusAway Watchdog"},
+		Link:    atomLink{Rel: "self", Href: self},
+		Entries: make([]atomEntry, 0, len(events)),
+	}
+	if len(events) > 0 {
+		feed.Updated = events[0].At.UTC().Format(time.RFC3339)
+	}
+	for _, event := range events {
+		feed.Entries = append(feed.Entries, incidentEntry(event))
+	}
+
+	body, err := xml.MarshalIndent(feed, "", "  ")
+	if err != nil {
+		app.Logger.Error("failed to render incident feed", "error", err)
+		http.Error(w, "failed to render incident feed", http.StatusInternalServerError)
+		return
+	}
+	w.Header().Set("Content-Type", "application/atom+xml; charset=utf-8")
+	_, _ = w.Write([]byte(xml.Header))
+	_, _ = w.Write(body)
+}
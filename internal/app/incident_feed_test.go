@@ -0,0 +1,58 @@
+package app
+
+import (
+	"context"
+	"encoding/xml"
+	"io"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"watchdog.onebusaway.org/internal/alert"
+)
+
+func TestIncidentFeed(t *testing.T) {
+	app := newTestApplication(t)
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	// The feed is only served when enabled.
+	rr := httptest.NewRecorder()
+	app.Routes(ctx).ServeHTTP(rr, httptest.NewRequest(http.MethodGet, "/v1/incidents.atom", nil))
+	if rr.Code != http.StatusNotFound {
+		t.Fatalf("expected 404 when the incident feed is disabled, got %d", rr.Code)
+	}
+
+	app.Incidents = alert.NewIncidentLog(incidentFeedSize)
+	app.Alerts = alert.NewManager([]alert.Notifier{app.Incidents}, time.Hour, "es", slog.New(slog.NewTextHandler(io.Discard, nil)))
+	server := app.ConfigService.Config.GetServers()[0]
+	for _, ok := range []bool{false, false, false, true} {
+		app.Alerts.ObserveResult(server, alert.CheckAPIDown, ok)
+	}
+
+	rr = httptest.NewRecorder()
+	app.Routes(ctx).ServeHTTP(rr, httptest.NewRequest(http.MethodGet, "/v1/incidents.atom", nil))
+	if rr.Code != http.StatusOK || !strings.HasPrefix(rr.Header().Get("Content-Type"), "application/atom+xml") {
+		t.Fatalf("expected an Atom feed, got %d %q", rr.Code, rr.Header().Get("Content-Type"))
+	}
+	var feed atomFeed
+	if err := xml.Unmarshal(rr.Body.Bytes(), &feed); err != nil {
+		t.Fatalf("failed to parse the feed: %v", err)
+	}
+	if feed.Link.Href != "http://example.com/v1/incidents.atom" {
+		t.Errorf("unexpected self link %q", feed.Link.Href)
+	}
+	if len(feed.Entries) != 2 {
+		t.Fatalf("expected the opening and the resolution of the incident, got %+v", feed.Entries)
+	}
+	resolved, firing := feed.Entries[0], feed.Entries[1]
+	if resolved.Category.Term != "resolved" || firing.Category.Term != "firing" || resolved.ID == firing.ID {
+		t.Errorf("unexpected entries %+v", feed.Entries)
+	}
+	if !strings.Contains(firing.Title, "Test Server") || !strings.HasPrefix(firing.Title, "[activa]") {
+		t.Errorf("expected a translated title with the server name, got %q", firing.Title)
+	}
+}
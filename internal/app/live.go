@@ -0,0 +1,350 @@
+package app
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"slices"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/coder/websocket"
+	"watchdog.onebusaway.org/internal/metrics"
+	"watchdog.onebusaway.org/internal/models"
+	"watchdog.onebusaway.org/internal/shutdown"
+)
+
+// Types of the events of the live status stream.
+const (
+	liveCheckResult    = "check_result"
+	liveVehicleSummary = "vehicle_summary"
+)
+
+// liveBufferSize is the number of events buffered for each subscriber of the live stream. Events
+// are dropped for subscribers that fall further behind.
+const liveBufferSize = 256
+
+// livePingInterval is how often idle live stream connections are pinged.
+const livePingInterval = 30 * time.Second
+
+// liveWriteTimeout bounds every write to a live stream connection, including pings, so that a
+// client that stopped reading cannot block its writer.
+const liveWriteTimeout = 10 * time.Second
+
+// liveMaxMessageSize bounds the messages read from clients; larger messages close the connection.
+const liveMaxMessageSize = 64 << 10
+
+// LiveEvent is a message of the live status stream: the result of a check of a server
+// ("check_result", with Check) or the vehicles of its last GTFS-RT feed ("vehicle_summary",
+// with Vehicles).
+type LiveEvent struct {
+	Type       string          `json:"type"`
+	ServerID   int             `json:"server_id"`
+	ServerName string          `json:"server_name"`
+	At         time.Time       `json:"at"`
+	Check      *LiveCheck      `json:"check,omitempty"`
+	Vehicles   *VehicleSummary `json:"vehicles,omitempty"`
+}
+
+// LiveCheck is the result of a check in a LiveEvent.
+type LiveCheck struct {
+	Name  string `json:"name"`
+	OK    bool   `json:"ok"`
+	Error string `json:"error,omitempty"`
+}
+
+// VehicleSummary sums up the vehicles of a server in a LiveEvent.
+type VehicleSummary struct {
+	// FeedVehicles is the number of vehicle positions of the GTFS-RT feed.
+	FeedVehicles int `json:"feed_vehicles"`
+	// MatchRatio is the share of the feed's vehicles that the OBA API returns (see
+	// metrics.VehicleCountMatchRatio); nil if it could not be computed.
+	MatchRatio *float64 `json:"match_ratio,omitempty"`
+}
+
+// liveFilter selects the events sent to a subscriber of the live stream. Empty lists match
+// everything; Checks only applies to check results.
+type liveFilter struct {
+	ServerIDs []int    `json:"server_ids"`
+	Checks    []string `json:"checks"`
+	Types     []string `json:"types"`
+}
+
+func (f liveFilter) matches(event LiveEvent) bool {
+	if len(f.ServerIDs) > 0 && !slices.Contains(f.ServerIDs, event.ServerID) {
+		return false
+	}
+	if len(f.Types) > 0 && !slices.Contains(f.Types, event.Type) {
+		return false
+	}
+	if len(f.Checks) > 0 && event.Check != nil && !slices.Contains(f.Checks, event.Check.Name) {
+		return false
+	}
+	return true
+}
+
+// liveSubscriber is a client of the live stream.
+type liveSubscriber struct {
+	mu     sync.Mutex
+	filter liveFilter
+	events chan LiveEvent
+}
+
+func (s *liveSubscriber) setFilter(filter liveFilter) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.filter = filter
+}
+
+func (s *liveSubscriber) matches(event LiveEvent) bool {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.filter.matches(event)
+}
+
+// LiveHub fans the check results and vehicle summaries of the collection cycles out to the
+// subscribers of the live status stream. A nil *LiveHub drops every event.
+type LiveHub struct {
+	mu          sync.Mutex
+	subscribers map[*liveSubscriber]struct{}
+}
+
+// NewLiveHub returns a LiveHub without subscribers.
+func NewLiveHub() *LiveHub {
+	return &LiveHub{subscribers: make(map[*liveSubscriber]struct{})}
+}
+
+// Publish sends event to the subscribers whose filter matches it. It never blocks: a subscriber
+// whose buffer is full misses the event.
+func (h *LiveHub) Publish(event LiveEvent) {
+	if h == nil {
+		return
+	}
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	for subscriber := range h.subscribers {
+		if !subscriber.matches(event) {
+			continue
+		}
+		select {
+		case subscriber.events <- event:
+		default:
+		}
+	}
+}
+
+func (h *LiveHub) subscribe(filter liveFilter) *liveSubscriber {
+	subscriber := &liveSubscriber{filter: filter, events: make(chan LiveEvent, liveBufferSize)}
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	h.subscribers[subscriber] = struct{}{}
+	return subscriber
+}
+
+func (h *LiveHub) unsubscribe(subscriber *liveSubscriber) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	delete(h.subscribers, subscriber)
+}
+
+// publishCheck sends the result of a check of server to the live stream.
+func (app *Application) publishCheck(server models.ObaServer, check string, err error, at time.Time) {
+	result := &LiveCheck{Name: check, OK: err == nil}
+	if err != nil {
+		result.Error = err.Error()
+	}
+	app.Live.Publish(LiveEvent{Type: liveCheckResult, ServerID: server.ID, ServerName: server.Name, At: at, Check: result})
+}
+
+// publishVehicleSummary sends the vehicles of the GTFS-RT feed just fetched for server to the
+// live stream, with the vehicle count match ratio if it was computed.
+func (app *Application) publishVehicleSummary(server models.ObaServer, matchRatio float64, matched bool) {
+	if app.Live == nil {
+		return
+	}
+	summary := &VehicleSummary{}
+	if data := app.MetricsService.RealtimeStore.Get(); data != nil {
+		summary.FeedVehicles = len(data.Vehicles)
+	}
+	if matched {
+		summary.MatchRatio = &matchRatio
+	}
+	app.Live.Publish(LiveEvent{Type: liveVehicleSummary, ServerID: server.ID, ServerName: server.Name, At: time.Now().UTC(), Vehicles: summary})
+}
+
+// parseLiveFilter reads the filter of a live stream request from its query: server_id, check and
+// type, each repeated or comma-separated.
+func parseLiveFilter(r *http.Request) (liveFilter, error) {
+	var filter liveFilter
+	query := r.URL.Query()
+	for _, id := range splitQuery(query["server_id"]) {
+		serverID, err := strconv.Atoi(id)
+		if err != nil {
+			return filter, errors.New("invalid server_id")
+		}
+		filter.ServerIDs = append(filter.ServerIDs, serverID)
+	}
+	filter.Checks = splitQuery(query["check"])
+	filter.Types = splitQuery(query["type"])
+	return filter, nil
+}
+
+// splitQuery returns the non-empty comma-separated items of values.
+func splitQuery(values []string) []string {
+	var items []string
+	for _, value := range values {
+		for _, item := range strings.Split(value, ",") {
+			if item = strings.TrimSpace(item); item != "" {
+				items = append(items, item)
+			}
+		}
+	}
+	return items
+}
+
+// liveMessage is a message sent by a client of the live stream, to replace its filter:
+// {"type": "subscribe", "server_ids": [1, 2], "checks": ["server_ping"], "types": []}.
+type liveMessage struct {
+	Type string `json:"type"`
+	liveFilter
+}
+
+// liveHandler serves the live status stream over a WebSocket: the result of every check as it is
+// recorded and the vehicle summary of every collection cycle, as LiveEvent JSON messages.
+//
+// The events are filtered with the server_id, check and type query parameters (e.g.
+// /v1/live?server_id=1,2&type=check_result), and clients can replace the filter at any time by
+// sending a "subscribe" message (see liveMessage). After connecting and after each subscribe
+// message, the last result of every matching check is sent first, so clients start with the
+// full state. The connection is closed with "going away" when the watchdog shuts down.
+//
+// Handshakes from a browser page of another origin are rejected: browsers send their cookies
+// with WebSocket handshakes whatever the origin, so accepting them would let any site read the
+// stream with the session of a logged-in user.
+func (app *Application) liveHandler(ctx context.Context) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		filter, err := parseLiveFilter(r)
+		if err != nil {
+			app.writeJSON(w, http.StatusBadRequest, map[string]string{"error": err.Error()})
+			return
+		}
+		conn, err := websocket.Accept(w, r, nil)
+		if err != nil {
+			app.Logger.Warn("Rejected live stream connection", "remote_addr", r.RemoteAddr, "error", err)
+			return
+		}
+		conn.SetReadLimit(liveMaxMessageSize)
+		subscriber := app.Live.subscribe(filter)
+		defer app.Live.unsubscribe(subscriber)
+
+		// The client's messages are read in the background, which also handles the pongs and the
+		// close frames; its filter changes are passed on to the writer, which sends the matching
+		// last results.
+		filters := make(chan liveFilter, 1)
+		readerDone := make(chan struct{})
+		go func() {
+			defer close(readerDone)
+			for {
+				_, data, err := conn.Read(r.Context())
+				if err != nil {
+					return
+				}
+				var message liveMessage
+				if err := json.Unmarshal(data, &message); err != nil || message.Type != "subscribe" {
+					continue
+				}
+				subscriber.setFilter(message.liveFilter)
+				select {
+				case filters <- message.liveFilter:
+				default:
+				}
+			}
+		}()
+
+		ping := time.NewTicker(livePingInterval)
+		defer ping.Stop()
+		send := func(event LiveEvent) bool {
+			data, err := json.Marshal(event)
+			if err != nil {
+				return false
+			}
+			writeCtx, cancel := context.WithTimeout(r.Context(), liveWriteTimeout)
+			defer cancel()
+			return conn.Write(writeCtx, websocket.MessageText, data) == nil
+		}
+		sendSnapshot := func(filter liveFilter) bool {
+			for _, event := range app.liveSnapshot() {
+				if filter.matches(event) && !send(event) {
+					return false
+				}
+			}
+			return true
+		}
+		if !sendSnapshot(filter) {
+			conn.Close(websocket.StatusNormalClosure, "")
+			return
+		}
+		for {
+			select {
+			case event := <-subscriber.events:
+				if !send(event) {
+					conn.Close(websocket.StatusNormalClosure, "")
+					return
+				}
+			case filter := <-filters:
+				if !sendSnapshot(filter) {
+					conn.Close(websocket.StatusNormalClosure, "")
+					return
+				}
+			case <-ping.C:
+				pingCtx, cancel := context.WithTimeout(r.Context(), liveWriteTimeout)
+				err := conn.Ping(pingCtx)
+				cancel()
+				if err != nil {
+					conn.Close(websocket.StatusNormalClosure, "")
+					return
+				}
+			case <-readerDone:
+				conn.Close(websocket.StatusNormalClosure, "")
+				return
+			case <-shutdown.Stopping(ctx):
+				conn.Close(websocket.StatusGoingAway, "shutting down")
+				return
+			case <-ctx.Done():
+				conn.Close(websocket.StatusGoingAway, "shutting down")
+				return
+			}
+		}
+	}
+}
+
+// liveSnapshot returns the last result of every check of every server as live events, by server
+// and in the order the checks run.
+func (app *Application) liveSnapshot() []LiveEvent {
+	cfg := app.ConfigService.Config
+	var events []LiveEvent
+	for _, server := range append(cfg.GetServers(), cfg.GetURLTargets()...) {
+		results := app.MetricsService.CheckResults.Get(server.ID)
+		checks := slices.Clone(metrics.CheckNames)
+		for _, check := range server.ExecChecks {
+			checks = append(checks, metrics.ExecCheckName(check))
+		}
+		for _, check := range checks {
+			result, ok := results[check]
+			if !ok {
+				continue
+			}
+			events = append(events, LiveEvent{
+				Type:       liveCheckResult,
+				ServerID:   server.ID,
+				ServerName: server.Name,
+				At:         result.At.UTC(),
+				Check:      &LiveCheck{Name: check, OK: result.OK, Error: result.Error},
+			})
+		}
+	}
+	return events
+}
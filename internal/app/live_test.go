@@ -0,0 +1,130 @@
+package app
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/coder/websocket"
+	"watchdog.onebusaway.org/internal/metrics"
+)
+
+// liveClient is a client of the live stream.
+type liveClient struct {
+	conn *websocket.Conn
+}
+
+func dialLive(t *testing.T, ts *httptest.Server, query string) *liveClient {
+	t.Helper()
+	conn, _, err := websocket.Dial(context.Background(), "ws"+strings.TrimPrefix(ts.URL, "http")+"/v1/live"+query, nil)
+	if err != nil {
+		t.Fatalf("expected the handshake to succeed, got %v", err)
+	}
+	t.Cleanup(func() { conn.CloseNow() })
+	return &liveClient{conn: conn}
+}
+
+// next returns the next event of the stream.
+func (c *liveClient) next(t *testing.T) LiveEvent {
+	t.Helper()
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	_, payload, err := c.conn.Read(ctx)
+	if err != nil {
+		t.Fatalf("read message: %v", err)
+	}
+	var event LiveEvent
+	if err := json.Unmarshal(payload, &event); err != nil {
+		t.Fatalf("invalid event %q: %v", payload, err)
+	}
+	return event
+}
+
+// send writes a text message.
+func (c *liveClient) send(t *testing.T, message string) {
+	t.Helper()
+	if err := c.conn.Write(context.Background(), websocket.MessageText, []byte(message)); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestLiveStream(t *testing.T) {
+	app := newTestApplication(t)
+	app.Live = NewLiveHub()
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	ts := httptest.NewServer(app.Routes(ctx))
+	defer ts.Close()
+
+	server := app.ConfigService.Config.GetServers()[0]
+	app.recordCheck(server, metrics.CheckServerPing, nil)
+	app.recordCheck(server, metrics.CheckObaAPI, nil)
+
+	client := dialLive(t, ts, "?server_id=1&check=server_ping,gtfs_rt_feed")
+	// The last results are sent first.
+	if event := client.next(t); event.Type != liveCheckResult || event.Check.Name != metrics.CheckServerPing || !event.Check.OK {
+		t.Fatalf("expected the last server_ping result, got %+v", event)
+	}
+
+	app.recordCheck(server, metrics.CheckObaAPI, nil)
+	app.recordCheck(server, metrics.CheckRealtimeFeed, errors.New("feed unavailable"))
+	event := client.next(t)
+	if event.ServerID != 1 || event.Check == nil || event.Check.Name != metrics.CheckRealtimeFeed || event.Check.OK || event.Check.Error != "feed unavailable" {
+		t.Fatalf("expected the gtfs_rt_feed failure, got %+v", event)
+	}
+
+	app.publishVehicleSummary(server, 0.5, true)
+	event = client.next(t)
+	if event.Type != liveVehicleSummary || event.Vehicles.FeedVehicles == 0 || *event.Vehicles.MatchRatio != 0.5 {
+		t.Fatalf("expected a vehicle summary, got %+v", event)
+	}
+
+	// A subscribe message replaces the filter and sends the matching last results.
+	client.send(t, `{"type": "subscribe", "checks": ["oba_api"]}`)
+	if event := client.next(t); event.Check == nil || event.Check.Name != metrics.CheckObaAPI {
+		t.Fatalf("expected the last oba_api result after subscribing, got %+v", event)
+	}
+}
+
+func TestLiveStreamRejects(t *testing.T) {
+	app := newTestApplication(t)
+	app.Live = NewLiveHub()
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	handler := app.Routes(ctx)
+
+	handshake := http.Header{
+		"Connection":            {"Upgrade"},
+		"Upgrade":               {"websocket"},
+		"Sec-Websocket-Key":     {"dGhlIHNhbXBsZSBub25jZQ=="},
+		"Sec-Websocket-Version": {"13"},
+	}
+	tests := []struct {
+		target string
+		header http.Header
+		want   int
+	}{
+		{target: "/v1/live?server_id=x", want: http.StatusBadRequest},
+		{target: "/v1/live", want: http.StatusUpgradeRequired},
+		{target: "/v1/live", header: http.Header{"Origin": {"https://evil.example.com"}}, want: http.StatusForbidden},
+	}
+	for _, tt := range tests {
+		req := httptest.NewRequest(http.MethodGet, tt.target, nil)
+		if tt.header != nil {
+			req.Header = handshake.Clone()
+			for name, values := range tt.header {
+				req.Header[name] = values
+			}
+		}
+		rr := httptest.NewRecorder()
+		handler.ServeHTTP(rr, req)
+		if rr.Code != tt.want {
+			t.Errorf("GET %s %v: expected %d, got %d", tt.target, tt.header, tt.want, rr.Code)
+		}
+	}
+}
@@ -0,0 +1,112 @@
+package app
+
+import (
+	"encoding/json"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/julienschmidt/httprouter"
+	"watchdog.onebusaway.org/internal/models"
+)
+
+// maxMaintenanceBodySize bounds the size of a maintenance window posted to the admin API.
+const maxMaintenanceBodySize = 1 << 16
+
+// maintenanceRequest is the body of POST /v1/admin/servers/:id/maintenance: a window from Start
+// (default: now) to End, or for Duration.
+type maintenanceRequest struct {
+	Start    time.Time       `json:"start"`
+	End      time.Time       `json:"end"`
+	Duration models.Duration `json:"duration"`
+	Reason   string          `json:"reason"`
+}
+
+// reportsSuppressed reports whether the errors of the server with the given ID, as tagged in
+// Sentry reports, are left out of Sentry because the server is in a maintenance window.
+func (app *Application) reportsSuppressed(serverID string) bool {
+	id, err := strconv.Atoi(serverID)
+	if err != nil {
+		return false
+	}
+	server, ok := app.findServer(id)
+	if !ok {
+		return false
+	}
+	_, active := app.Maintenance.Active(server, time.Now())
+	return active
+}
+
+// findServer returns the configured server or URL target with the given ID.
+func (app *Application) findServer(serverID int) (models.ObaServer, bool) {
+	cfg := app.ConfigService.Config
+	for _, server := range append(cfg.GetServers(), cfg.GetURLTargets()...) {
+		if server.ID == serverID {
+			return server, true
+		}
+	}
+	return models.ObaServer{}, false
+}
+
+// addMaintenanceHandler declares a maintenance window of the server with the given `id`, from
+// the `start` of the request body (default: now) to its `end`, or for its `duration`. The window
+// is kept in memory, shared with the other instances if the stores are shared, and forgotten
+// once it ends; windows meant to survive restarts belong in `maintenance_windows`.
+func (app *Application) addMaintenanceHandler(w http.ResponseWriter, r *http.Request) {
+	serverID, err := strconv.Atoi(httprouter.ParamsFromContext(r.Context()).ByName("id"))
+	if err != nil {
+		app.writeJSON(w, http.StatusBadRequest, map[string]string{"error": "invalid server id"})
+		return
+	}
+	if _, ok := app.findServer(serverID); !ok {
+		app.writeJSON(w, http.StatusNotFound, map[string]string{"error": "unknown server id"})
+		return
+	}
+
+	var request maintenanceRequest
+	if err := json.NewDecoder(http.MaxBytesReader(w, r.Body, maxMaintenanceBodySize)).Decode(&request); err != nil {
+		app.writeJSON(w, http.StatusBadRequest, map[string]string{"error": "invalid maintenance window"})
+		return
+	}
+	now := time.Now().UTC()
+	window := models.MaintenanceWindow{Start: request.Start, End: request.End, Reason: request.Reason}
+	if window.Start.IsZero() {
+		window.Start = now
+	}
+	switch {
+	case !window.End.IsZero() && request.Duration != 0:
+		app.writeJSON(w, http.StatusBadRequest, map[string]string{"error": "end and duration are mutually exclusive"})
+		return
+	case request.Duration > 0:
+		window.End = window.Start.Add(request.Duration.Std())
+	case window.End.IsZero():
+		app.writeJSON(w, http.StatusBadRequest, map[string]string{"error": "end or a positive duration is required"})
+		return
+	}
+	if !window.End.After(window.Start) || !window.End.After(now) {
+		app.writeJSON(w, http.StatusBadRequest, map[string]string{"error": "the window must end after its start and in the future"})
+		return
+	}
+
+	app.Maintenance.Add(serverID, window, now)
+	app.Logger.Info("Declared maintenance window", "server_id", serverID, "start", window.Start, "end", window.End, "reason", window.Reason)
+	app.writeJSON(w, http.StatusCreated, map[string]any{"server_id": serverID, "window": window})
+}
+
+// clearMaintenanceHandler removes the maintenance windows of the server with the given `id`
+// declared with the admin API, ending its maintenance early. Windows of the configuration are
+// kept.
+func (app *Application) clearMaintenanceHandler(w http.ResponseWriter, r *http.Request) {
+	serverID, err := strconv.Atoi(httprouter.ParamsFromContext(r.Context()).ByName("id"))
+	if err != nil {
+		app.writeJSON(w, http.StatusBadRequest, map[string]string{"error": "invalid server id"})
+		return
+	}
+	if _, ok := app.findServer(serverID); !ok {
+		app.writeJSON(w, http.StatusNotFound, map[string]string{"error": "unknown server id"})
+		return
+	}
+	removed := app.Maintenance.Clear(serverID)
+	app.Logger.Info("Cleared maintenance windows", "server_id", serverID, "removed", removed)
+	app.writeJSON(w, http.StatusOK, map[string]int{"server_id": serverID, "removed": removed})
+}
@@ -0,0 +1,75 @@
+package app
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"watchdog.onebusaway.org/internal/auth"
+)
+
+func TestMaintenanceRoutes(t *testing.T) {
+	app := newTestApplication(t)
+	app.Authenticator = testAuthenticator{"viewer": auth.RoleViewer, "operator": auth.RoleOperator}
+	handler := app.Routes(context.Background())
+
+	request := func(method, path, token, body string) *httptest.ResponseRecorder {
+		r := httptest.NewRequest(method, path, strings.NewReader(body))
+		r.Header.Set("Authorization", "Bearer "+token)
+		rr := httptest.NewRecorder()
+		handler.ServeHTTP(rr, r)
+		return rr
+	}
+
+	for _, tt := range []struct {
+		name  string
+		path  string
+		token string
+		body  string
+		want  int
+	}{
+		{"viewer cannot declare", "/v1/admin/servers/1/maintenance", "viewer", `{"duration": "1h"}`, http.StatusForbidden},
+		{"unknown server", "/v1/admin/servers/99/maintenance", "operator", `{"duration": "1h"}`, http.StatusNotFound},
+		{"no end", "/v1/admin/servers/1/maintenance", "operator", `{"reason": "upgrade"}`, http.StatusBadRequest},
+		{"end and duration", "/v1/admin/servers/1/maintenance", "operator", `{"end": "2099-01-01T00:00:00Z", "duration": "1h"}`, http.StatusBadRequest},
+		{"ended", "/v1/admin/servers/1/maintenance", "operator", `{"start": "2020-01-01T00:00:00Z", "end": "2020-01-01T02:00:00Z"}`, http.StatusBadRequest},
+	} {
+		if rr := request(http.MethodPost, tt.path, tt.token, tt.body); rr.Code != tt.want {
+			t.Errorf("%s: expected status %d, got %d: %s", tt.name, tt.want, rr.Code, rr.Body)
+		}
+	}
+
+	rr := request(http.MethodPost, "/v1/admin/servers/1/maintenance", "operator", `{"duration": "2h", "reason": "OBA upgrade"}`)
+	if rr.Code != http.StatusCreated {
+		t.Fatalf("expected status 201, got %d: %s", rr.Code, rr.Body)
+	}
+	server := app.ConfigService.Config.Servers[0]
+	window, ok := app.Maintenance.Active(server, time.Now())
+	if !ok || window.Reason != "OBA upgrade" || window.End.Sub(window.Start) != 2*time.Hour {
+		t.Fatalf("expected the server to be in maintenance, got %+v, %v", window, ok)
+	}
+	if !app.reportsSuppressed("1") || app.reportsSuppressed("2") {
+		t.Error("expected only the reports of server 1 to be suppressed")
+	}
+
+	rr = request(http.MethodGet, "/v1/servers/1/status", "viewer", "")
+	var status serverStatus
+	if err := json.Unmarshal(rr.Body.Bytes(), &status); err != nil {
+		t.Fatalf("failed to decode status: %v", err)
+	}
+	if status.Maintenance == nil || status.Maintenance.Reason != "OBA upgrade" || len(status.MaintenanceWindows) != 1 {
+		t.Errorf("expected the maintenance window in the status, got %+v, %+v", status.Maintenance, status.MaintenanceWindows)
+	}
+
+	rr = request(http.MethodDelete, "/v1/admin/servers/1/maintenance", "operator", "")
+	if rr.Code != http.StatusOK || !strings.Contains(rr.Body.String(), `"removed":1`) {
+		t.Fatalf("expected one window to be removed, got %d: %s", rr.Code, rr.Body)
+	}
+	if _, ok := app.Maintenance.Active(server, time.Now()); ok {
+		t.Error("expected the maintenance to end")
+	}
+}
@@ -2,20 +2,30 @@ package app
 
 import (
 	"context"
+	"errors"
 	"fmt"
+	"slices"
+	"strconv"
 	"time"
 
 	"github.com/getsentry/sentry-go"
+	"github.com/prometheus/client_golang/prometheus"
+	"watchdog.onebusaway.org/internal/alert"
+	"watchdog.onebusaway.org/internal/config"
+	"watchdog.onebusaway.org/internal/gtfs"
+	"watchdog.onebusaway.org/internal/history"
+	"watchdog.onebusaway.org/internal/metrics"
 	"watchdog.onebusaway.org/internal/models"
 	"watchdog.onebusaway.org/internal/report"
+	"watchdog.onebusaway.org/internal/scheduler"
 )
 
 // StartMetricsCollection begins a background goroutine that continuously collects metrics
 // from all configured OBA (OneBusAway) servers at a regular interval.
 //
-// It uses a time.Ticker based on the `FetchInterval` configured in the app's config that comes from "fetch-interval" command line flags.
-// The ticker triggers every `FetchInterval` seconds, allowing the application to periodically
-// collect and update metrics related to OBA servers listed in the config.
+// It runs on the `FetchSchedule` configured in the app's config ("fetch-schedule" command line flag),
+// or every `FetchInterval` seconds ("fetch-interval" flag) if no schedule is set, allowing the
+// application to periodically collect and update metrics related to OBA servers listed in the config.
 //
 // The collection routine gracefully shuts down when the provided context is canceled,
 // allowing the application to cleanly exit or restart.
@@ -29,27 +39,54 @@ import (
 //   - Monitor reliability and correctness of OBA and GTFS-RT server integrations.
 //
 // Behavior:
-//   - If no servers are configured, the function silently waits and retries on next tick.
+//   - If no servers are configured, the function silently waits and retries on the next activation.
+//   - With leader election, a standby replica skips the cycles (see leader.Elector).
+//   - Servers sharing an OBA base URL or GTFS URL are counted in watchdog_server_duplicates.
+//   - The name, region and GTFS feed version of every server are exported in oba_server_info.
+//   - After the servers are checked, the number of servers, unhealthy servers and stale GTFS-RT
+//     feeds of each region are exported (see recordFleetRollups).
+//   - After every cycle, the alert rules are evaluated on the collected metrics (see alert.RuleEvaluator).
+//   - After every cycle, metrics are pushed to the Pushgateway if one is configured (see PushMetrics).
+//   - After every cycle, a probe agent pushes its ping results to the primary (see pushProbeResults).
+//   - After every cycle, the lifecycle observations are saved to --lifecycle-file, if set (see lifecycle.Store).
+//   - After every cycle, the check results past --history-retention are deleted, at most hourly (see history.Store).
 //   - On shutdown (context canceled), it logs the stop and exits the goroutine cleanly.
 func (app *Application) StartMetricsCollection(ctx context.Context) {
 
-	ticker := time.NewTicker(time.Duration(app.ConfigService.Config.FetchInterval) * time.Second)
+	schedule := app.ConfigService.Config.FetchSchedule
+	if schedule == nil {
+		schedule = scheduler.Every(time.Duration(app.ConfigService.Config.FetchInterval) * time.Second)
+	}
 	go func() {
-		defer ticker.Stop()
-		for {
-			select {
-			case <-ctx.Done():
-				app.Logger.Info("Stopping metrics collection routine")
+		scheduler.Run(ctx, "metrics_collection", schedule, func() {
+			if !app.Leader.IsLeader() {
 				return
-			case <-ticker.C:
-
-				servers := app.ConfigService.Config.GetServers()
+			}
+			// Higher priority tiers are checked first in every cycle.
+			servers := models.SortServersByPriorityTier(app.ConfigService.Config.GetServers())
+			recordDuplicateServers(servers)
+			recordServerInfo(servers, app.GtfsService.StaticStore, app.Maintenance, time.Now())
 
-				for _, server := range servers {
-					app.CollectMetricsForServer(server)
-				}
+			for _, server := range servers {
+				app.CollectMetricsForServer(server)
 			}
-		}
+			for _, target := range app.ConfigService.Config.GetURLTargets() {
+				app.CollectURLTarget(target)
+			}
+			recordFleetRollups(servers, app.MetricsService.CheckResults, app.ConfigService.Config.FeedStaleAfter, time.Now())
+			if err := app.Rules.Evaluate(prometheus.DefaultGatherer, servers, time.Now().UTC()); err != nil {
+				app.Logger.Error("Failed to evaluate alert rules", "error", err)
+			}
+			app.pushMetrics(ctx)
+			app.pushProbeResults(ctx, servers)
+			if err := app.Lifecycle.Save(time.Now().UTC()); err != nil {
+				app.Logger.Error("Failed to save lifecycle observations", "error", err)
+			}
+			if _, err := app.History.Prune(time.Now().UTC()); err != nil {
+				app.Logger.Error("Failed to prune the check history", "error", err)
+			}
+		})
+		app.Logger.Info("Stopping metrics collection routine")
 	}()
 }
 
@@ -57,14 +94,28 @@ func (app *Application) StartMetricsCollection(ctx context.Context) {
 //
 // It sequentially runs a series of probes and validations against the given server:
 //  1. Pings the server to track basic availability.
-//  2. Checks GTFS static bundle expiration.
-//  3. Verifies agency coverage match (GTFS static vs real-time).
-//  4. Collects metrics from the OBA API endpoints.
+//  2. Checks GTFS static bundle download failures and expiration, and exports feed_info.txt metrics.
+//  3. Verifies agency coverage match (GTFS static vs real-time), looks up a sample of the
+//     bundle's stops in the OBA API, and compares the bundle's routes with the OBA API's.
+//  4. Collects metrics from the OBA API endpoints, and samples the arrivals of the server's
+//     prediction stops to measure the accuracy of arrival predictions.
 //  5. Fetches and stores GTFS-RT (realtime) vehicle positions feed.
-//  6. Validates consistency between expected and actual vehicle counts.
+//  6. Validates consistency between expected and actual vehicle counts, and measures how far
+//     behind the feed the OBA API is for a sample of its vehicles.
 //  7. Tracks frequency of vehicle telemetry reporting over time.
 //  8. Flags invalid vehicles and vehicles stopped outside bounds.
 //
+// While it runs, the server counts in watchdog_checks_in_progress{kind="server"}, and the time
+// of each step is recorded in app.CycleTraces by phase (see checkPhases).
+// The result of every step is recorded in app.MetricsService.CheckResults for the status API,
+// and the server, the routes of its bundle and its GTFS-RT feed are recorded in app.Lifecycle
+// when they are observed (see recordLifecycle).
+// Whether a planned service reduction of the server is in effect is exported first, as
+// gtfs_service_reduction_active.
+//
+// Ping results, consecutive bundle download failures and bundle expiration are also passed to the
+// alert manager (app.Alerts), which notifies the configured sinks when a check crosses its threshold.
+//
 // Errors in each step are logged and reported to Sentry with contextual tags (e.g., server name, ID),
 // but the process continues unless the GTFS-RT feed fails — in which case the function returns early,
 // as later checks depend on the real-time data.
@@ -79,6 +130,11 @@ func (app *Application) StartMetricsCollection(ctx context.Context) {
 //	When a server fails, its backoff delay is increased exponentially and nextRetryAt is updated.
 //	When a server responds successfully, its backoff state is reset.
 //
+//	After CircuitBreakerThreshold consecutive failures the circuit of the server opens: nextRetryAt
+//	is pushed back by CircuitBreakerCooldown, and the ping of the next run is a probe that
+//	either closes the circuit or opens it for another cooldown. Skipped runs of an open
+//	circuit are not reported to Sentry; the opening is.
+//
 // Why this design?
 //
 //	  The StartMetricsCollection scheduler runs every `FetchInterval` (default: 30 seconds) for each server.
@@ -101,9 +157,20 @@ func (app *Application) StartMetricsCollection(ctx context.Context) {
 //   - Sentry reports are tagged for fast debugging and correlation in distributed systems.
 //   - Dependencies are injected (via app fields) to support testability and separation of concerns.
 func (app *Application) CollectMetricsForServer(server models.ObaServer) {
+	metrics.ChecksInProgress.WithLabelValues("server").Inc()
+	defer metrics.ChecksInProgress.WithLabelValues("server").Dec()
+	app.CycleTraces.Start(server.ID, time.Now())
+	defer app.finishCycleTrace(server)
+	app.recordServiceReduction(server)
+	app.recordBundleRoutes(server)
+
 	// Check if server has an active backoff period
 	nextRetryAt, exists := app.ConfigService.BackoffStore.NextRetryAt(server.ID)
 	if exists && time.Now().UTC().Before(nextRetryAt) {
+		if state, _ := app.ConfigService.BackoffStore.CircuitState(server.ID, time.Now()); state == config.CircuitOpen {
+			app.Logger.Info("Skipping metrics collection for server with an open circuit", "server_id", server.ID, "next_probe_at", nextRetryAt)
+			return
+		}
 		// Still in backoff → skip metrics collection
 		app.Logger.Info("Skipping metrics collection for server due to backoff", "server_id", server.ID, "next_retry_at", nextRetryAt)
 		report.ReportErrorWithSentryOptions(fmt.Errorf("skipping metrics collection for server %s due to backoff", server.ObaBaseURL), report.SentryReportOptions{
@@ -120,7 +187,9 @@ func (app *Application) CollectMetricsForServer(server models.ObaServer) {
 	}
 
 	ok := app.MetricsService.ServerPing(server)
+	app.Alerts.ObserveResult(server, alert.CheckAPIDown, ok)
 	if !ok {
+		app.recordCheck(server, metrics.CheckServerPing, errors.New("server did not respond to ping"))
 		// On ping failure → increase backoff for this server
 		app.Logger.Error("Server ping failed", "server_id", server.ID, "server_name", server.Name)
 		report.ReportErrorWithSentryOptions(fmt.Errorf("server ping failed for %s", server.ObaBaseURL), report.SentryReportOptions{
@@ -133,7 +202,23 @@ func (app *Application) CollectMetricsForServer(server models.ObaServer) {
 			},
 			Level: sentry.LevelError,
 		})
-		app.ConfigService.BackoffStore.UpdateBackoff(server.ID)
+		if app.ConfigService.BackoffStore.UpdateBackoff(server.ID) {
+			_, failures := app.ConfigService.BackoffStore.CircuitState(server.ID, time.Now())
+			nextProbeAt, _ := app.ConfigService.BackoffStore.NextRetryAt(server.ID)
+			app.Logger.Warn("Circuit opened for server, skipping its checks until the next probe", "server_id", server.ID, "consecutive_failures", failures, "next_probe_at", nextProbeAt)
+			report.ReportErrorWithSentryOptions(fmt.Errorf("circuit opened for %s after %d consecutive failures", server.ObaBaseURL, failures), report.SentryReportOptions{
+				Tags: map[string]string{
+					"server_id":   fmt.Sprintf("%d", server.ID),
+					"server_name": server.Name,
+				},
+				ExtraContext: map[string]interface{}{
+					"oba_base_url":  server.ObaBaseURL,
+					"next_probe_at": nextProbeAt,
+				},
+				Level: sentry.LevelWarning,
+			})
+		}
+		app.recordCircuitState(server)
 		app.Logger.Info("Skipping further metrics collection for server due to ping failure")
 		return
 	}
@@ -141,8 +226,22 @@ func (app *Application) CollectMetricsForServer(server models.ObaServer) {
 	// On successful ping → reset backoff for this server
 	app.Logger.Info("Server ping successful", "server_id", server.ID, "server_name", server.Name)
 	app.ConfigService.BackoffStore.ResetBackoff(server.ID)
+	app.recordCircuitState(server)
+	app.recordCheck(server, metrics.CheckServerPing, nil)
+
+	if metadata, ok := app.GtfsService.BundleMetadata.Get(server.ID); ok {
+		app.Alerts.Observe(server, alert.CheckBundleDownload, float64(metadata.ConsecutiveFailures))
+		if metadata.SizeRatio > 0 {
+			app.Alerts.Observe(server, alert.CheckBundleSizeDrop, metadata.SizeRatio)
+		}
+	}
 
-	_, _, err := app.MetricsService.CheckBundleExpiration(time.Now().UTC(), server)
+	daysUntilEarliestExpiration, _, err := app.MetricsService.CheckBundleExpiration(time.Now().UTC(), server)
+	days := float64(daysUntilEarliestExpiration)
+	app.recordCheckValue(server, metrics.CheckBundleExpiration, valueIf(days, err), err)
+	if err == nil {
+		app.Alerts.Observe(server, alert.CheckBundleExpiration, days)
+	}
 	if err != nil {
 		app.Logger.Error("Failed to check GTFS bundle expiration", "error", err)
 		report.ReportErrorWithSentryOptions(err, report.SentryReportOptions{
@@ -154,7 +253,14 @@ func (app *Application) CollectMetricsForServer(server models.ObaServer) {
 		})
 	}
 
+	err = app.MetricsService.CheckFeedInfo(time.Now().UTC(), server)
+	app.recordCheck(server, metrics.CheckFeedInfo, err)
+	if err != nil {
+		app.Logger.Error("Failed to check GTFS feed info", "error", err)
+	}
+
 	err = app.MetricsService.CheckAgenciesWithCoverageMatch(server)
+	app.recordCheck(server, metrics.CheckAgenciesWithCoverage, err)
 
 	if err != nil {
 		app.Logger.Error("Failed to check agencies with coverage match metric", "error", err)
@@ -167,7 +273,29 @@ func (app *Application) CollectMetricsForServer(server models.ObaServer) {
 		})
 	}
 
+	err = app.MetricsService.CheckStopsMatch(server)
+	app.recordCheck(server, metrics.CheckStopsMatch, err)
+
+	if err != nil {
+		app.Logger.Error("Failed to check stops of the GTFS bundle in the OBA API", "error", err)
+		report.ReportErrorWithSentryOptions(err, report.SentryReportOptions{
+			Tags: map[string]string{
+				"server_id":   fmt.Sprintf("%d", server.ID),
+				"server_name": server.Name,
+			},
+			Level: sentry.LevelError,
+		})
+	}
+
+	// Discrepancies above the threshold are reported to Sentry, with the route IDs, by the check itself.
+	err = app.MetricsService.CheckRoutesMatch(server, app.ConfigService.Config.RouteMismatchThreshold)
+	app.recordCheck(server, metrics.CheckRoutesMatch, err)
+	if err != nil {
+		app.Logger.Error("Failed to check routes of the GTFS bundle in the OBA API", "error", err)
+	}
+
 	err = app.MetricsService.FetchObaAPIMetrics(server.AgencyID, server.ID, server.ObaBaseURL, server.ObaApiKey)
+	app.recordCheck(server, metrics.CheckObaAPI, err)
 
 	if err != nil {
 		app.Logger.Error("Failed to fetch OBA API metrics", "error", err)
@@ -182,10 +310,29 @@ func (app *Application) CollectMetricsForServer(server models.ObaServer) {
 			Level: sentry.LevelError,
 		})
 	}
+
+	if len(server.PredictionStops) > 0 {
+		err = app.MetricsService.CheckPredictionAccuracy(time.Now().UTC(), server)
+		app.recordCheck(server, metrics.CheckPredictionAccuracy, err)
+		if err != nil {
+			app.Logger.Error("Failed to sample arrival predictions", "error", err)
+			report.ReportErrorWithSentryOptions(err, report.SentryReportOptions{
+				Tags: map[string]string{
+					"server_id":   fmt.Sprintf("%d", server.ID),
+					"server_name": server.Name,
+				},
+				Level: sentry.LevelWarning,
+			})
+		}
+	}
+
 	// Fetch and store GTFS-RT feed
 	// Note : All functions after FetchAndStoreGTFSRTFeed depend on this function
 	// on failure of this function we return and don't proceed
-	err = app.GtfsService.FetchAndStoreGTFSRTFeed(server)
+	var timings gtfs.FetchTimings
+	err = app.GtfsService.FetchAndStoreGTFSRTFeed(server, &timings)
+	app.recordFetchTimings(server, metrics.CheckRealtimeFeed, timings)
+	app.recordCheckValue(server, metrics.CheckRealtimeFeed, app.realtimeFeedAge(err), err)
 	if err != nil {
 		app.Logger.Error("Failed to fetch and store GTFS-RT feed", "error", err)
 		report.ReportErrorWithSentryOptions(err, report.SentryReportOptions{
@@ -198,7 +345,12 @@ func (app *Application) CollectMetricsForServer(server models.ObaServer) {
 		return
 	}
 
-	err = app.MetricsService.CheckVehicleCountMatch(server)
+	vehicleCountRatio, err := app.MetricsService.CheckVehicleCountMatch(server)
+	app.recordCheckValue(server, metrics.CheckVehicleCount, valueIf(vehicleCountRatio, err), err)
+	if err == nil {
+		app.Alerts.Observe(server, alert.CheckVehiclesDropped, vehicleCountRatio)
+	}
+	app.publishVehicleSummary(server, vehicleCountRatio, err == nil)
 	if err != nil {
 		app.Logger.Error("Failed to check vehicle count match metric", "error", err)
 		report.ReportErrorWithSentryOptions(err, report.SentryReportOptions{
@@ -210,7 +362,33 @@ func (app *Application) CollectMetricsForServer(server models.ObaServer) {
 		})
 	}
 
+	err = app.MetricsService.CheckIngestionLag(server)
+	app.recordCheck(server, metrics.CheckIngestionLag, err)
+	if err != nil {
+		app.Logger.Error("Failed to measure realtime ingestion lag", "error", err)
+		report.ReportErrorWithSentryOptions(err, report.SentryReportOptions{
+			Tags: map[string]string{
+				"server_id":   fmt.Sprintf("%d", server.ID),
+				"server_name": server.Name,
+			},
+			Level: sentry.LevelWarning,
+		})
+	}
+
+	err = app.MetricsService.CheckTripCoverage(time.Now(), server)
+	app.recordCheck(server, metrics.CheckTripCoverage, err)
+	if err != nil {
+		app.Logger.Error("Failed to check realtime trip coverage", "error", err)
+		report.ReportErrorWithSentryOptions(err, report.SentryReportOptions{
+			Tags: map[string]string{
+				"server_id": fmt.Sprintf("%d", server.ID),
+			},
+			Level: sentry.LevelError,
+		})
+	}
+
 	err = app.MetricsService.TrackVehicleTelemetry(server)
+	app.recordCheck(server, metrics.CheckVehicleTelemetry, err)
 	if err != nil {
 		app.Logger.Error("Failed to track vehicle reporting frequency", "error", err)
 		report.ReportErrorWithSentryOptions(err, report.SentryReportOptions{
@@ -222,6 +400,7 @@ func (app *Application) CollectMetricsForServer(server models.ObaServer) {
 	}
 
 	err = app.MetricsService.TrackInvalidVehiclesAndStoppedOutOfBounds(server)
+	app.recordCheck(server, metrics.CheckInvalidVehicles, err)
 	if err != nil {
 		app.Logger.Error("Failed to count invalid vehicle coordinates", "error", err)
 		report.ReportErrorWithSentryOptions(err, report.SentryReportOptions{
@@ -233,3 +412,164 @@ func (app *Application) CollectMetricsForServer(server models.ObaServer) {
 	}
 
 }
+
+// recordServiceReduction exports whether a planned service reduction of a server is in effect.
+func (app *Application) recordServiceReduction(server models.ObaServer) {
+	value := 0.0
+	if _, reduced := app.GtfsService.ServiceReductions.Active(server.ID, time.Now()); reduced {
+		value = 1
+	}
+	gtfs.ServiceReductionActiveGauge.WithLabelValues(strconv.Itoa(server.ID)).Set(value)
+}
+
+// circuitStateValues are the values of metrics.CircuitBreakerState.
+var circuitStateValues = map[config.CircuitState]float64{
+	config.CircuitClosed:   0,
+	config.CircuitHalfOpen: 1,
+	config.CircuitOpen:     2,
+}
+
+// recordCircuitState exports the state of the circuit breaker of a server.
+func (app *Application) recordCircuitState(server models.ObaServer) {
+	state, _ := app.ConfigService.BackoffStore.CircuitState(server.ID, time.Now())
+	metrics.CircuitBreakerState.WithLabelValues(strconv.Itoa(server.ID)).Set(circuitStateValues[state])
+}
+
+// CollectURLTarget runs the uptime checks of an entry of type "url" (see
+// metrics.CheckURLTarget), records their results and feeds the url_down alert. Keyword and
+// latency results are only recorded when the target configures them and it answered.
+func (app *Application) CollectURLTarget(target models.ObaServer) {
+	metrics.ChecksInProgress.WithLabelValues("url_target").Inc()
+	defer metrics.ChecksInProgress.WithLabelValues("url_target").Dec()
+	result := app.MetricsService.CheckURLTarget(target)
+	app.recordCheck(target, metrics.CheckURLStatus, result.StatusErr)
+	if target.Keyword != "" && result.StatusCode != 0 {
+		app.recordCheck(target, metrics.CheckURLKeyword, result.KeywordErr)
+	}
+	if target.MaxLatencyMs > 0 && result.StatusCode != 0 {
+		app.recordCheck(target, metrics.CheckURLLatency, result.LatencyErr)
+	}
+	app.Alerts.ObserveResult(target, alert.CheckURLDown, result.OK())
+	if result.OK() {
+		return
+	}
+	err := errors.Join(result.StatusErr, result.KeywordErr, result.LatencyErr)
+	app.Logger.Error("URL target failed its uptime checks", "server_id", target.ID, "server_name", target.Name, "error", err)
+	report.ReportErrorWithSentryOptions(err, report.SentryReportOptions{
+		Tags: map[string]string{
+			"server_id":   fmt.Sprintf("%d", target.ID),
+			"server_name": target.Name,
+		},
+		ExtraContext: map[string]interface{}{
+			"url": target.URL,
+		},
+		Level: sentry.LevelWarning,
+	})
+}
+
+// recordBundleRoutes records the routes of the GTFS bundle of the server in use as observed
+// (see lifecycle.Store).
+func (app *Application) recordBundleRoutes(server models.ObaServer) {
+	staticData, ok := app.GtfsService.StaticStore.Get(server.ID)
+	if !ok || app.Lifecycle == nil {
+		return
+	}
+	routeIDs := make([]string, 0, len(staticData.Routes))
+	for _, route := range staticData.Routes {
+		routeIDs = append(routeIDs, route.Id)
+	}
+	app.Lifecycle.ObserveRoutes(server.ID, routeIDs, time.Now().UTC())
+}
+
+// recordLifecycle records the server as observed when it answers a ping, and its GTFS-RT feed
+// when it is fetched successfully (see lifecycle.Store).
+func (app *Application) recordLifecycle(server models.ObaServer, check string, err error, at time.Time) {
+	if err != nil {
+		return
+	}
+	switch check {
+	case metrics.CheckServerPing:
+		app.Lifecycle.ObserveServer(server.ID, at)
+	case metrics.CheckRealtimeFeed:
+		app.Lifecycle.ObserveRealtimeFeed(server.ID, gtfs.FeedTypeVehiclePositions, at)
+	}
+}
+
+// recordCheck records the result of a step of CollectMetricsForServer (see metrics.CheckResultStore).
+// Results of data-quality checks also go to the agency digest of the server.
+func (app *Application) recordCheck(server models.ObaServer, check string, err error) {
+	app.recordCheckValue(server, check, nil, err)
+}
+
+// recordCheckValue records the result of a step like recordCheck, with the value it measured, if
+// any, which is kept in the check history (see history.Store).
+func (app *Application) recordCheckValue(server models.ObaServer, check string, value *float64, err error) {
+	now := time.Now().UTC()
+	app.MetricsService.CheckResults.Record(server.ID, check, err, now)
+	app.recordHistory(server, check, value, err, now)
+	app.recordRuleValue(server, check, value, err, now)
+	app.publishCheck(server, check, err, now)
+	app.recordLifecycle(server, check, err, now)
+	app.markCycleStep(server, check, now)
+	if check == metrics.CheckServerPing {
+		app.recordVantage(server, err == nil, now)
+	}
+	if slices.Contains(metrics.DataQualityChecks, check) {
+		app.AgencyDigest.Record(server, check, err)
+	}
+}
+
+// recordHistory records the result of a check run in app.History.
+func (app *Application) recordHistory(server models.ObaServer, check string, value *float64, err error, at time.Time) {
+	result := history.Result{At: at, ServerID: server.ID, Check: check, Value: value, OK: err == nil}
+	if err != nil {
+		result.Error = err.Error()
+	}
+	if err := app.History.Record(result); err != nil {
+		app.Logger.Error("Failed to record check result in history", "server_id", server.ID, "check", check, "error", err)
+	}
+}
+
+// recordRuleValue passes the value of a check run to the alert rules on the check: the value it
+// measured, or, for the checks that measure none, 1 if it failed and 0 if it passed. The failed
+// runs of the checks that measure a value have none, and are left out.
+func (app *Application) recordRuleValue(server models.ObaServer, check string, value *float64, err error, at time.Time) {
+	switch {
+	case value != nil:
+		app.Rules.RecordCheck(server.ID, check, *value, at)
+	case slices.Contains(metrics.MeasuredChecks, check):
+	case err != nil:
+		app.Rules.RecordCheck(server.ID, check, 1, at)
+	default:
+		app.Rules.RecordCheck(server.ID, check, 0, at)
+	}
+}
+
+// valueIf returns a pointer to the value measured by a check, or nil if the check failed.
+func valueIf(value float64, err error) *float64 {
+	if err != nil {
+		return nil
+	}
+	return &value
+}
+
+// realtimeFeedAge returns the age, in seconds, of the newest vehicle position of the GTFS-RT feed
+// just fetched, recorded in the check history to measure its freshness, or nil if the fetch
+// failed (err) or no vehicle has a timestamp.
+func (app *Application) realtimeFeedAge(err error) *float64 {
+	data := app.GtfsService.RealtimeStore.Get()
+	if err != nil || data == nil {
+		return nil
+	}
+	var newest time.Time
+	for _, vehicle := range data.Vehicles {
+		if vehicle.Timestamp != nil && vehicle.Timestamp.After(newest) {
+			newest = *vehicle.Timestamp
+		}
+	}
+	if newest.IsZero() {
+		return nil
+	}
+	age := max(time.Since(newest).Seconds(), 0)
+	return &age
+}
@@ -6,12 +6,29 @@ import (
 	"net/http"
 	"net/http/httptest"
 	"strings"
+	"sync/atomic"
 	"testing"
+	"time"
 
 	"github.com/prometheus/client_golang/prometheus"
+	dto "github.com/prometheus/client_model/go"
+	"watchdog.onebusaway.org/internal/config"
 	"watchdog.onebusaway.org/internal/metrics"
+	"watchdog.onebusaway.org/internal/models"
 )
 
+// collectMetric writes the single metric produced by a collector into a dto.Metric.
+func collectMetric(t *testing.T, collector prometheus.Collector) *dto.Metric {
+	t.Helper()
+	c := make(chan prometheus.Metric, 1)
+	collector.Collect(c)
+	pb := &dto.Metric{}
+	if err := (<-c).Write(pb); err != nil {
+		t.Fatalf("failed to read metric: %v", err)
+	}
+	return pb
+}
+
 func TestMetricsEndpoint(t *testing.T) {
 	// Create a new instance of our application
 	app := newTestApplication(t)
@@ -54,3 +71,61 @@ func TestCollectMetricsForServer(t *testing.T) {
 
 	getMetricsForTesting(t, metrics.ObaApiStatus)
 }
+
+func TestCollectMetricsForServerCircuitBreaker(t *testing.T) {
+	app := newTestApplication(t)
+	app.ConfigService.BackoffStore = config.NewBackoffStore(2, time.Hour)
+
+	var pings atomic.Int32
+	obaServer := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		pings.Add(1)
+		w.WriteHeader(http.StatusBadRequest)
+	}))
+	defer obaServer.Close()
+	server := app.ConfigService.Config.Servers[0]
+	server.ObaBaseURL = obaServer.URL
+
+	app.CollectMetricsForServer(server)
+	if state := collectMetric(t, metrics.CircuitBreakerState.WithLabelValues("1")).GetGauge().GetValue(); state != 0 {
+		t.Errorf("expected a closed circuit after one failure, got %v", state)
+	}
+
+	// The backoff of the first failure has to pass before the second ping.
+	time.Sleep(2 * config.BASE_BACKOFF)
+	app.CollectMetricsForServer(server)
+	if state := collectMetric(t, metrics.CircuitBreakerState.WithLabelValues("1")).GetGauge().GetValue(); state != 2 {
+		t.Errorf("expected an open circuit after two failures, got %v", state)
+	}
+
+	// An open circuit skips the server without pinging it.
+	before := pings.Load()
+	app.CollectMetricsForServer(server)
+	if got := pings.Load() - before; got != 0 {
+		t.Errorf("expected no ping while the circuit is open, got %d", got)
+	}
+}
+
+func TestCollectURLTarget(t *testing.T) {
+	app := newTestApplication(t)
+
+	planner := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		_, _ = w.Write([]byte("maintenance"))
+	}))
+	defer planner.Close()
+	target := models.ObaServer{ID: 10, Name: "Trip planner", Type: models.ServerTypeURL, URL: planner.URL, Keyword: "ok"}
+
+	app.CollectURLTarget(target)
+	results := app.MetricsService.CheckResults.Get(target.ID)
+	if !results[metrics.CheckURLStatus].OK {
+		t.Errorf("expected url_status to pass, got %+v", results[metrics.CheckURLStatus])
+	}
+	if results[metrics.CheckURLKeyword].OK {
+		t.Error("expected url_keyword to fail")
+	}
+	if _, ok := results[metrics.CheckURLLatency]; ok {
+		t.Error("expected no url_latency result without max_latency_ms")
+	}
+	if up := collectMetric(t, metrics.URLCheckUp.WithLabelValues("10", planner.URL)).GetGauge().GetValue(); up != 0 {
+		t.Errorf("expected url_check_up 0, got %v", up)
+	}
+}
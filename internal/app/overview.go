@@ -0,0 +1,173 @@
+package app
+
+import (
+	"encoding/csv"
+	"io"
+	"math"
+	"net/http"
+	"strconv"
+	"time"
+
+	"watchdog.onebusaway.org/internal/gtfs"
+	"watchdog.onebusaway.org/internal/metrics"
+	"watchdog.onebusaway.org/internal/models"
+)
+
+// OverviewRow is the row of a server in the fleet overview (see overviewHandler). Fields that
+// are not known yet, e.g. before the first bundle download, are null.
+type OverviewRow struct {
+	ServerID   int    `json:"server_id"`
+	ServerName string `json:"server_name"`
+	// Health is up, degraded, down or unknown (see serverHealth).
+	Health string `json:"health"`
+	// HealthScore is the percentage of the checks of the server whose last run succeeded,
+	// 0 when the server does not answer pings.
+	HealthScore *int `json:"health_score"`
+	// BundleAgeHours is the time since the GTFS bundle was last downloaded.
+	BundleAgeHours *float64 `json:"bundle_age_hours"`
+	// ExpiryRunwayDays is the number of days until the first service of the bundle ends.
+	ExpiryRunwayDays *int `json:"expiry_runway_days"`
+	// RealtimeFreshnessSeconds is the time since the GTFS-RT feed was last fetched successfully.
+	RealtimeFreshnessSeconds *float64 `json:"realtime_freshness_seconds"`
+	// LastIncident is the last incident event of the server, recorded only with --incident-feed.
+	LastIncident *OverviewIncident `json:"last_incident"`
+}
+
+// OverviewIncident is an alert check of a server starting to fire or resolving.
+type OverviewIncident struct {
+	At     time.Time `json:"at"`
+	Check  string    `json:"check"`
+	Status string    `json:"status"`
+}
+
+// overviewColumns are the columns of the CSV fleet overview.
+var overviewColumns = []string{
+	"server_id", "server_name", "health", "health_score", "bundle_age_hours", "expiry_runway_days",
+	"realtime_freshness_seconds", "last_incident_at", "last_incident_check", "last_incident_status",
+}
+
+// healthScore returns the percentage of checks whose last run succeeded (see OverviewRow), and
+// false if the server has not been checked yet.
+func healthScore(results map[string]metrics.CheckResult) (int, bool) {
+	if len(results) == 0 {
+		return 0, false
+	}
+	if serverHealth(results) == healthDown {
+		return 0, true
+	}
+	passing := 0
+	for _, result := range results {
+		if result.OK {
+			passing++
+		}
+	}
+	return passing * 100 / len(results), true
+}
+
+// overviewRow summarizes the state of a server at now for the fleet overview.
+func (app *Application) overviewRow(server models.ObaServer, now time.Time) OverviewRow {
+	row := OverviewRow{ServerID: server.ID, ServerName: server.Name}
+
+	results := app.MetricsService.CheckResults.Get(server.ID)
+	row.Health = serverHealth(results)
+	if score, ok := healthScore(results); ok {
+		row.HealthScore = &score
+	}
+	if result, ok := results[metrics.CheckRealtimeFeed]; ok && !result.LastSuccessAt.IsZero() {
+		freshness := math.Round(now.Sub(result.LastSuccessAt).Seconds())
+		row.RealtimeFreshnessSeconds = &freshness
+	}
+
+	if metadata, ok := app.GtfsService.BundleMetadata.Get(server.ID); ok && !metadata.DownloadedAt.IsZero() {
+		age := math.Round(now.Sub(metadata.DownloadedAt).Hours()*10) / 10
+		row.BundleAgeHours = &age
+	}
+	if staticData, ok := app.GtfsService.StaticStore.Get(server.ID); ok {
+		if earliest, _, err := gtfs.GetEarliestAndLatestServiceDates(staticData); err == nil {
+			// Same rounding as gtfs_bundle_days_until_earliest_expiration.
+			runway := int(earliest.Sub(now.UTC()).Hours() / 24)
+			row.ExpiryRunwayDays = &runway
+		}
+	}
+
+	if app.Incidents != nil {
+		// Events are newest first.
+		for _, event := range app.Incidents.Events() {
+			if event.Server.ID == server.ID {
+				row.LastIncident = &OverviewIncident{At: event.At.UTC(), Check: event.Check, Status: string(event.Status)}
+				break
+			}
+		}
+	}
+	return row
+}
+
+// Overview returns the fleet overview at now: one row per monitored server, in the order of
+// the configuration.
+func (app *Application) Overview(now time.Time) []OverviewRow {
+	servers := app.ConfigService.Config.GetServers()
+	rows := make([]OverviewRow, 0, len(servers))
+	for _, server := range servers {
+		rows = append(rows, app.overviewRow(server, now))
+	}
+	return rows
+}
+
+// WriteOverviewCSV writes the fleet overview as CSV with a header row. Unknown values are
+// left empty.
+func WriteOverviewCSV(w io.Writer, rows []OverviewRow) error {
+	out := csv.NewWriter(w)
+	if err := out.Write(overviewColumns); err != nil {
+		return err
+	}
+	for _, row := range rows {
+		record := []string{
+			strconv.Itoa(row.ServerID),
+			row.ServerName,
+			row.Health,
+			formatOptional(row.HealthScore, strconv.Itoa),
+			formatOptional(row.BundleAgeHours, formatFloat),
+			formatOptional(row.ExpiryRunwayDays, strconv.Itoa),
+			formatOptional(row.RealtimeFreshnessSeconds, formatFloat),
+			"", "", "",
+		}
+		if incident := row.LastIncident; incident != nil {
+			record[7], record[8], record[9] = incident.At.Format(time.RFC3339), incident.Check, incident.Status
+		}
+		if err := out.Write(record); err != nil {
+			return err
+		}
+	}
+	out.Flush()
+	return out.Error()
+}
+
+// formatOptional formats an optional value, empty if it is nil.
+func formatOptional[T any](v *T, format func(T) string) string {
+	if v == nil {
+		return ""
+	}
+	return format(*v)
+}
+
+func formatFloat(f float64) string {
+	return strconv.FormatFloat(f, 'f', -1, 64)
+}
+
+// overviewHandler serves the fleet overview (see Overview) as JSON ({"servers": [...]}), or as
+// CSV with format=csv, for spreadsheets. It backs `watchdog export overview`.
+func (app *Application) overviewHandler(w http.ResponseWriter, r *http.Request) {
+	rows := app.Overview(time.Now())
+	switch r.URL.Query().Get("format") {
+	case "", "json":
+		app.writeJSON(w, http.StatusOK, map[string]any{"servers": rows})
+	case "csv":
+		w.Header().Set("Content-Type", "text/csv; charset=utf-8")
+		w.Header().Set("Content-Disposition", `attachment; filename="watchdog-overview.csv"`)
+		if err := WriteOverviewCSV(w, rows); err != nil {
+			app.Logger.Warn("failed to write response", "error", err)
+		}
+	default:
+		app.writeJSON(w, http.StatusBadRequest, map[string]string{"error": "invalid format, expected csv or json"})
+	}
+}
@@ -0,0 +1,106 @@
+package app
+
+import (
+	"context"
+	"encoding/csv"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"watchdog.onebusaway.org/internal/alert"
+	"watchdog.onebusaway.org/internal/gtfs"
+	"watchdog.onebusaway.org/internal/metrics"
+	"watchdog.onebusaway.org/internal/models"
+)
+
+func TestOverview(t *testing.T) {
+	app := newTestApplication(t)
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	handler := app.Routes(ctx)
+
+	now := time.Now().UTC()
+	app.MetricsService.CheckResults.Record(1, metrics.CheckServerPing, nil, now)
+	app.MetricsService.CheckResults.Record(1, metrics.CheckObaAPI, nil, now)
+	app.MetricsService.CheckResults.Record(1, metrics.CheckRealtimeFeed, nil, now.Add(-90*time.Second))
+	app.MetricsService.CheckResults.Record(1, metrics.CheckRealtimeFeed, errors.New("feed unavailable"), now)
+	app.MetricsService.CheckResults.Record(1, metrics.CheckVehicleCount, nil, now)
+	app.GtfsService.BundleMetadata.Set(1, gtfs.BundleMetadata{DownloadedAt: now.Add(-30 * time.Hour)})
+	app.Incidents = alert.NewIncidentLog(incidentFeedSize)
+	server := app.ConfigService.Config.GetServers()[0]
+	_ = app.Incidents.Notify(ctx, alert.Alert{Server: server, Check: alert.CheckAPIDown, Status: alert.StatusFiring, At: now.Add(-time.Hour)})
+	_ = app.Incidents.Notify(ctx, alert.Alert{Server: server, Check: alert.CheckAPIDown, Status: alert.StatusResolved, At: now.Add(-time.Minute)})
+
+	t.Run("json", func(t *testing.T) {
+		rr := httptest.NewRecorder()
+		handler.ServeHTTP(rr, httptest.NewRequest(http.MethodGet, "/v1/overview", nil))
+		if rr.Code != http.StatusOK {
+			t.Fatalf("expected 200, got %d", rr.Code)
+		}
+		var body struct {
+			Servers []OverviewRow `json:"servers"`
+		}
+		if err := json.Unmarshal(rr.Body.Bytes(), &body); err != nil {
+			t.Fatal(err)
+		}
+		if len(body.Servers) != 1 {
+			t.Fatalf("expected one server, got %+v", body.Servers)
+		}
+		row := body.Servers[0]
+		if row.ServerID != 1 || row.Health != healthDegraded {
+			t.Errorf("unexpected row %+v", row)
+		}
+		if row.HealthScore == nil || *row.HealthScore != 75 {
+			t.Errorf("expected a health score of 75 with 3 of 4 checks passing, got %v", row.HealthScore)
+		}
+		if row.BundleAgeHours == nil || *row.BundleAgeHours != 30 {
+			t.Errorf("expected a bundle age of 30 hours, got %v", row.BundleAgeHours)
+		}
+		if row.ExpiryRunwayDays == nil {
+			t.Error("expected the expiry runway of the bundle")
+		}
+		if row.RealtimeFreshnessSeconds == nil || *row.RealtimeFreshnessSeconds != 90 {
+			t.Errorf("expected the feed to be fresh as of 90s ago, got %v", row.RealtimeFreshnessSeconds)
+		}
+		if incident := row.LastIncident; incident == nil || incident.Check != alert.CheckAPIDown || incident.Status != string(alert.StatusResolved) {
+			t.Errorf("expected the resolution of the incident, got %+v", incident)
+		}
+	})
+
+	t.Run("csv", func(t *testing.T) {
+		rr := httptest.NewRecorder()
+		handler.ServeHTTP(rr, httptest.NewRequest(http.MethodGet, "/v1/overview?format=csv", nil))
+		if rr.Code != http.StatusOK || rr.Header().Get("Content-Type") != "text/csv; charset=utf-8" {
+			t.Fatalf("expected a CSV file, got %d %q", rr.Code, rr.Header().Get("Content-Type"))
+		}
+		records, err := csv.NewReader(rr.Body).ReadAll()
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(records) != 2 || len(records[0]) != len(overviewColumns) {
+			t.Fatalf("expected a header and one row, got %v", records)
+		}
+		row := records[1]
+		if row[0] != "1" || row[2] != healthDegraded || row[3] != "75" || row[4] != "30" || row[6] != "90" || row[8] != alert.CheckAPIDown || row[9] != "resolved" {
+			t.Errorf("unexpected row %v", row)
+		}
+	})
+
+	t.Run("unknown", func(t *testing.T) {
+		row := app.overviewRow(models.ObaServer{ID: 2, Name: "New server"}, now)
+		if row.Health != healthUnknown || row.HealthScore != nil || row.BundleAgeHours != nil || row.ExpiryRunwayDays != nil || row.RealtimeFreshnessSeconds != nil || row.LastIncident != nil {
+			t.Errorf("expected unknown values for a server not checked yet, got %+v", row)
+		}
+	})
+
+	t.Run("invalid format", func(t *testing.T) {
+		rr := httptest.NewRecorder()
+		handler.ServeHTTP(rr, httptest.NewRequest(http.MethodGet, "/v1/overview?format=xlsx", nil))
+		if rr.Code != http.StatusBadRequest {
+			t.Errorf("expected 400, got %d", rr.Code)
+		}
+	})
+}
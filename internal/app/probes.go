@@ -0,0 +1,72 @@
+package app
+
+import (
+	"context"
+	"strconv"
+	"time"
+
+	"github.com/getsentry/sentry-go"
+	"watchdog.onebusaway.org/internal/alert"
+	"watchdog.onebusaway.org/internal/metrics"
+	"watchdog.onebusaway.org/internal/models"
+	"watchdog.onebusaway.org/internal/report"
+	"watchdog.onebusaway.org/internal/vantage"
+)
+
+// recordVantage compares the ping of a server by the primary with the latest pings of the
+// probe agents (see vantage.Diagnose), and exports oba_api_unreachable_from_primary. A server
+// that only the primary cannot reach is logged, as the network of the primary is more likely
+// to blame than the server. It does nothing if the watchdog does not accept agent results.
+func (app *Application) recordVantage(server models.ObaServer, ok bool, at time.Time) {
+	if app.Vantage == nil {
+		return
+	}
+	remote := app.Vantage.Get(server.ID, at)
+	diagnosis := vantage.Diagnose(ok, remote)
+	unreachable := 0.0
+	if diagnosis == vantage.DiagnosisPrimaryUnreachable {
+		unreachable = 1
+		app.Logger.Warn("Server unreachable from the primary but reachable from probe agents", "server_id", server.ID, "server_name", server.Name, "vantages", vantage.Vantages(remote))
+	}
+	vantage.UnreachableFromPrimaryGauge.WithLabelValues(strconv.Itoa(server.ID)).Set(unreachable)
+}
+
+// alertVantages converts the results of the probe agents for a server to the results of an
+// alert (see alert.Manager.SetVantages), sorted by vantage point.
+func alertVantages(remote map[string]vantage.Result) []alert.VantageResult {
+	results := make([]alert.VantageResult, 0, len(remote))
+	for _, name := range vantage.Vantages(remote) {
+		result := remote[name]
+		results = append(results, alert.VantageResult{Vantage: name, OK: result.OK, Error: result.Error})
+	}
+	return results
+}
+
+// pushProbeResults pushes the last ping result of each server to the primary watchdog when
+// this watchdog is a probe agent (--probe-primary-url). Servers that were not pinged yet, e.g.
+// during a backoff, are left out. A failed push is logged and reported; the next cycle pushes
+// again.
+func (app *Application) pushProbeResults(ctx context.Context, servers []models.ObaServer) {
+	cfg := app.ConfigService.Config
+	if cfg.ProbePrimaryURL == "" {
+		return
+	}
+	probeReport := vantage.Report{Vantage: cfg.VantagePoint, Results: []vantage.Result{}}
+	for _, server := range servers {
+		ping, ok := app.MetricsService.CheckResults.Get(server.ID)[metrics.CheckServerPing]
+		if !ok {
+			continue
+		}
+		probeReport.Results = append(probeReport.Results, vantage.Result{ServerID: server.ID, OK: ping.OK, Error: ping.Error, At: ping.At})
+	}
+
+	if err := vantage.Push(ctx, app.ConfigService.Client, cfg.ProbePrimaryURL, cfg.ProbeToken, probeReport); err != nil {
+		app.Logger.Error("Failed to push ping results to the primary watchdog", "primary_url", cfg.ProbePrimaryURL, "error", err)
+		report.ReportErrorWithSentryOptions(err, report.SentryReportOptions{
+			ExtraContext: map[string]interface{}{
+				"primary_url": cfg.ProbePrimaryURL,
+			},
+			Level: sentry.LevelWarning,
+		})
+	}
+}
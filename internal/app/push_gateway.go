@@ -0,0 +1,55 @@
+package app
+
+import (
+	"context"
+	"fmt"
+	"os"
+
+	"github.com/getsentry/sentry-go"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/push"
+	"watchdog.onebusaway.org/internal/report"
+)
+
+// PushMetrics pushes every metric in the default Prometheus registry to the Pushgateway
+// configured with the "push-gateway-url" command line flag. It is a no-op if no Pushgateway is configured.
+//
+// Metrics are pushed with PUT semantics: the whole grouping (job + instance) is replaced on every push,
+// so series that disappeared from the watchdog (e.g. a removed server) also disappear from the Pushgateway.
+// The `instance` grouping label is the host name, so several watchdogs can push under the same job.
+//
+// Pushing is meant for short-lived runs (cron jobs, CI) that exit before Prometheus could scrape /metrics;
+// the /metrics endpoint stays available either way.
+func (app *Application) PushMetrics(ctx context.Context) error {
+	cfg := app.ConfigService.Config
+	if cfg.PushGatewayURL == "" {
+		return nil
+	}
+
+	pusher := push.New(cfg.PushGatewayURL, cfg.PushGatewayJob).
+		Gatherer(prometheus.DefaultGatherer).
+		Client(app.ConfigService.Client)
+	if hostname, err := os.Hostname(); err == nil {
+		pusher = pusher.Grouping("instance", hostname)
+	}
+
+	if err := pusher.PushContext(ctx); err != nil {
+		return fmt.Errorf("failed to push metrics to %s: %w", cfg.PushGatewayURL, err)
+	}
+	return nil
+}
+
+// pushMetrics calls PushMetrics and logs and reports a failure.
+// A failed push is never fatal; the next collection cycle pushes again.
+func (app *Application) pushMetrics(ctx context.Context) {
+	err := app.PushMetrics(ctx)
+	if err != nil {
+		app.Logger.Error("Failed to push metrics to Pushgateway", "error", err)
+		report.ReportErrorWithSentryOptions(err, report.SentryReportOptions{
+			ExtraContext: map[string]interface{}{
+				"push_gateway_url": app.ConfigService.Config.PushGatewayURL,
+			},
+			Level: sentry.LevelWarning,
+		})
+	}
+}
@@ -0,0 +1,62 @@
+package app
+
+import (
+	"context"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"watchdog.onebusaway.org/internal/metrics"
+)
+
+func TestPushMetrics(t *testing.T) {
+	app := newTestApplication(t)
+
+	t.Run("Disabled", func(t *testing.T) {
+		if err := app.PushMetrics(context.Background()); err != nil {
+			t.Errorf("expected no error without a Pushgateway, got %v", err)
+		}
+	})
+
+	t.Run("Pushes default registry", func(t *testing.T) {
+		var method, path, body string
+		gateway := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			method, path = r.Method, r.URL.Path
+			data, _ := io.ReadAll(r.Body)
+			body = string(data)
+			w.WriteHeader(http.StatusOK)
+		}))
+		defer gateway.Close()
+
+		metrics.ObaApiStatus.WithLabelValues("1", "https://test.example.com").Set(1)
+		app.ConfigService.Config.PushGatewayURL = gateway.URL
+		app.ConfigService.Config.PushGatewayJob = "watchdog-ci"
+
+		if err := app.PushMetrics(context.Background()); err != nil {
+			t.Fatalf("PushMetrics failed: %v", err)
+		}
+		if method != http.MethodPut {
+			t.Errorf("expected a PUT request, got %s", method)
+		}
+		if !strings.HasPrefix(path, "/metrics/job/watchdog-ci") {
+			t.Errorf("unexpected push path %q", path)
+		}
+		if !strings.Contains(body, "oba_api_status") {
+			t.Error("pushed metrics don't contain oba_api_status")
+		}
+	})
+
+	t.Run("Gateway error", func(t *testing.T) {
+		gateway := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			w.WriteHeader(http.StatusInternalServerError)
+		}))
+		defer gateway.Close()
+
+		app.ConfigService.Config.PushGatewayURL = gateway.URL
+		if err := app.PushMetrics(context.Background()); err == nil {
+			t.Error("expected an error when the Pushgateway fails")
+		}
+	})
+}
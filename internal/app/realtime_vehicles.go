@@ -0,0 +1,109 @@
+package app
+
+import (
+	"net/http"
+	"strconv"
+	"time"
+
+	remoteGtfs "github.com/OneBusAway/go-gtfs"
+	"github.com/julienschmidt/httprouter"
+)
+
+// maxRealtimeVehicles is the number of vehicles returned by the GTFS-RT vehicles endpoint when
+// `limit` is not given, and the most it returns, so that large feeds do not produce huge responses.
+const maxRealtimeVehicles = 5000
+
+// realtimeVehicle is a vehicle position of a GTFS-RT feed, as returned by the GTFS-RT vehicles
+// endpoint. Fields missing from the feed are omitted.
+type realtimeVehicle struct {
+	ID                  string     `json:"id,omitempty"`
+	Label               string     `json:"label,omitempty"`
+	LicensePlate        string     `json:"license_plate,omitempty"`
+	TripID              string     `json:"trip_id,omitempty"`
+	RouteID             string     `json:"route_id,omitempty"`
+	Latitude            *float32   `json:"latitude,omitempty"`
+	Longitude           *float32   `json:"longitude,omitempty"`
+	Bearing             *float32   `json:"bearing,omitempty"`
+	Speed               *float32   `json:"speed,omitempty"`
+	StopID              *string    `json:"stop_id,omitempty"`
+	CurrentStopSequence *uint32    `json:"current_stop_sequence,omitempty"`
+	CurrentStatus       string     `json:"current_status,omitempty"`
+	OccupancyStatus     string     `json:"occupancy_status,omitempty"`
+	Timestamp           *time.Time `json:"timestamp,omitempty"`
+}
+
+// newRealtimeVehicle flattens a decoded vehicle position.
+func newRealtimeVehicle(vehicle remoteGtfs.Vehicle) realtimeVehicle {
+	v := realtimeVehicle{
+		StopID:              vehicle.StopID,
+		CurrentStopSequence: vehicle.CurrentStopSequence,
+		Timestamp:           vehicle.Timestamp,
+	}
+	if vehicle.ID != nil {
+		v.ID, v.Label, v.LicensePlate = vehicle.ID.ID, vehicle.ID.Label, vehicle.ID.LicensePlate
+	}
+	if vehicle.Trip != nil {
+		v.TripID, v.RouteID = vehicle.Trip.ID.ID, vehicle.Trip.ID.RouteID
+	}
+	if vehicle.Position != nil {
+		v.Latitude, v.Longitude = vehicle.Position.Latitude, vehicle.Position.Longitude
+		v.Bearing, v.Speed = vehicle.Position.Bearing, vehicle.Position.Speed
+	}
+	if vehicle.CurrentStatus != nil {
+		v.CurrentStatus = vehicle.CurrentStatus.String()
+	}
+	if vehicle.OccupancyStatus != nil {
+		v.OccupancyStatus = vehicle.OccupancyStatus.String()
+	}
+	return v
+}
+
+// realtimeVehiclesHandler returns the vehicle positions of the last fetched GTFS-RT feed of a
+// server as JSON, so that developers can inspect the feed without protobuf tooling. At most
+// `limit` vehicles are returned (default and maximum maxRealtimeVehicles); `vehicle_count` is
+// the number in the feed, and `truncated` tells whether some were left out.
+func (app *Application) realtimeVehiclesHandler(w http.ResponseWriter, r *http.Request) {
+	serverID, err := strconv.Atoi(httprouter.ParamsFromContext(r.Context()).ByName("id"))
+	if err != nil {
+		app.writeJSON(w, http.StatusBadRequest, map[string]string{"error": "invalid server id"})
+		return
+	}
+	limit := maxRealtimeVehicles
+	if param := r.URL.Query().Get("limit"); param != "" {
+		if limit, err = strconv.Atoi(param); err != nil || limit < 1 {
+			app.writeJSON(w, http.StatusBadRequest, map[string]string{"error": "invalid limit, expected a positive integer"})
+			return
+		}
+		limit = min(limit, maxRealtimeVehicles)
+	}
+	known := false
+	for _, server := range app.ConfigService.Config.GetServers() {
+		known = known || server.ID == serverID
+	}
+	if !known {
+		app.writeJSON(w, http.StatusNotFound, map[string]string{"error": "unknown server id"})
+		return
+	}
+	feed, ok := app.GtfsService.RealtimeStore.Raw(serverID)
+	if !ok {
+		app.writeJSON(w, http.StatusNotFound, map[string]string{"error": "no GTFS-RT feed fetched for the server"})
+		return
+	}
+	realtime, err := remoteGtfs.ParseRealtime(feed.Data, &remoteGtfs.ParseRealtimeOptions{})
+	if err != nil {
+		app.writeJSON(w, http.StatusUnprocessableEntity, map[string]string{"error": "failed to decode the GTFS-RT feed: " + err.Error()})
+		return
+	}
+
+	vehicles := make([]realtimeVehicle, 0, min(len(realtime.Vehicles), limit))
+	for _, vehicle := range realtime.Vehicles[:min(len(realtime.Vehicles), limit)] {
+		vehicles = append(vehicles, newRealtimeVehicle(vehicle))
+	}
+	app.writeJSON(w, http.StatusOK, map[string]any{
+		"server_id":     serverID,
+		"fetched_at":    feed.FetchedAt,
+		"vehicle_count": len(realtime.Vehicles),
+		"truncated":     len(realtime.Vehicles) > limit,
+		"vehicles":      vehicles,
+	})
+}
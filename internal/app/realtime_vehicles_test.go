@@ -0,0 +1,75 @@
+package app
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"testing"
+	"time"
+
+	"watchdog.onebusaway.org/internal/gtfs"
+)
+
+func TestRealtimeVehiclesHandler(t *testing.T) {
+	app := newTestApplication(t)
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	handler := app.Routes(ctx)
+
+	get := func(target string) *httptest.ResponseRecorder {
+		rr := httptest.NewRecorder()
+		handler.ServeHTTP(rr, httptest.NewRequest(http.MethodGet, target, nil))
+		return rr
+	}
+
+	if rr := get("/v1/servers/1/gtfs-rt/vehicles.json"); rr.Code != http.StatusNotFound {
+		t.Errorf("expected 404 before the feed is fetched, got %d", rr.Code)
+	}
+
+	data, err := os.ReadFile("../../testdata/gtfs_rt_feed_vehicles.pb")
+	if err != nil {
+		t.Fatal(err)
+	}
+	app.GtfsService.RealtimeStore.SetRaw(1, gtfs.RawFeed{Data: data, FetchedAt: time.Now().UTC()})
+
+	var body struct {
+		ServerID     int               `json:"server_id"`
+		VehicleCount int               `json:"vehicle_count"`
+		Truncated    bool              `json:"truncated"`
+		Vehicles     []realtimeVehicle `json:"vehicles"`
+	}
+	rr := get("/v1/servers/1/gtfs-rt/vehicles.json")
+	if rr.Code != http.StatusOK {
+		t.Fatalf("expected 200, got %d: %s", rr.Code, rr.Body.String())
+	}
+	if err := json.Unmarshal(rr.Body.Bytes(), &body); err != nil {
+		t.Fatal(err)
+	}
+	if body.ServerID != 1 || body.VehicleCount < 2 || len(body.Vehicles) != body.VehicleCount || body.Truncated {
+		t.Fatalf("expected every vehicle of the feed, got %d of %d (truncated %v)", len(body.Vehicles), body.VehicleCount, body.Truncated)
+	}
+	if body.Vehicles[0].ID == "" || body.Vehicles[0].Latitude == nil {
+		t.Errorf("expected the vehicle id and position, got %+v", body.Vehicles[0])
+	}
+
+	rr = get("/v1/servers/1/gtfs-rt/vehicles.json?limit=1")
+	body.Vehicles = nil
+	if err := json.Unmarshal(rr.Body.Bytes(), &body); err != nil {
+		t.Fatal(err)
+	}
+	if len(body.Vehicles) != 1 || !body.Truncated {
+		t.Errorf("expected 1 vehicle and truncated, got %d (truncated %v)", len(body.Vehicles), body.Truncated)
+	}
+
+	for target, code := range map[string]int{
+		"/v1/servers/1/gtfs-rt/vehicles.json?limit=0": http.StatusBadRequest,
+		"/v1/servers/x/gtfs-rt/vehicles.json":         http.StatusBadRequest,
+		"/v1/servers/42/gtfs-rt/vehicles.json":        http.StatusNotFound,
+	} {
+		if rr := get(target); rr.Code != code {
+			t.Errorf("%s: expected %d, got %d", target, code, rr.Code)
+		}
+	}
+}
@@ -0,0 +1,53 @@
+package app
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/getsentry/sentry-go"
+	"watchdog.onebusaway.org/internal/config"
+	"watchdog.onebusaway.org/internal/metrics"
+	"watchdog.onebusaway.org/internal/report"
+	"watchdog.onebusaway.org/internal/scheduler"
+)
+
+// RunReportProblemChecks submits a test problem report to the OBA API of every server with a
+// `report_problem_stop_id` at every activation of schedule, until ctx is canceled, and records
+// whether it was accepted as the report_problem check (see metrics.CheckReportProblem).
+//
+// The check runs on its own schedule rather than in every collection cycle, since each run
+// adds a report to the problems the agency reviews. Servers whose circuit is open are skipped,
+// and so are all servers on a standby replica (see leader.Elector).
+func (app *Application) RunReportProblemChecks(ctx context.Context, schedule scheduler.Schedule) {
+	scheduler.Run(ctx, "report_problem_checks", schedule, app.checkReportProblems)
+	app.Logger.Info("Stopping report problem checks")
+}
+
+// checkReportProblems runs the report_problem check once for every server with a
+// `report_problem_stop_id` (see RunReportProblemChecks).
+func (app *Application) checkReportProblems() {
+	if !app.Leader.IsLeader() {
+		return
+	}
+	for _, server := range app.ConfigService.Config.GetServers() {
+		if server.ReportProblemStopID == "" {
+			continue
+		}
+		if state, _ := app.ConfigService.BackoffStore.CircuitState(server.ID, time.Now()); state == config.CircuitOpen {
+			continue
+		}
+		err := app.MetricsService.CheckReportProblem(server)
+		app.recordCheck(server, metrics.CheckReportProblem, err)
+		if err != nil {
+			app.Logger.Error("Failed to submit a test problem report", "server_id", server.ID, "error", err)
+			report.ReportErrorWithSentryOptions(err, report.SentryReportOptions{
+				Tags: map[string]string{
+					"server_id":   fmt.Sprintf("%d", server.ID),
+					"server_name": server.Name,
+				},
+				Level: sentry.LevelWarning,
+			})
+		}
+	}
+}
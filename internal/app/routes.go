@@ -6,7 +6,9 @@ import (
 	"time"
 
 	"github.com/prometheus/client_golang/prometheus"
+	"watchdog.onebusaway.org/internal/auth"
 	"watchdog.onebusaway.org/internal/middleware"
+	"watchdog.onebusaway.org/internal/vantage"
 
 	"github.com/julienschmidt/httprouter"
 )
@@ -23,10 +25,88 @@ import (
 //   - GET /v1/healthcheck:
 //     Provides a JSON-formatted snapshot of the application's current health and readiness status.
 //     Handled by `app.healthcheckHandler`.
+//   - GET /v1/livez:
+//     Liveness probe: responds while the process is up. Handled by `app.livezHandler`.
+//   - GET /v1/readyz:
+//     Readiness probe: responds with 503 until the configuration, a GTFS bundle and Sentry
+//     are ready, with the state of each. Handled by `app.readyzHandler`.
 //   - GET /metrics:
 //     Exposes all Prometheus metrics collected by the application for scraping by Prometheus.
 //     Handled by a cached Prometheus handler (`middleware.NewCachedPromHandler`), which
 //     reduces collection overhead by caching exposition output for a configurable duration.
+//   - GET /v1/servers:
+//     Lists the monitored servers with a summary of their health. Handled by `app.serversHandler`.
+//   - GET /v1/servers/:id/status:
+//     Returns the GTFS bundle, realtime feed, backoff and check state of a server.
+//     Handled by `app.serverStatusHandler`.
+//   - GET /v1/servers/:id/cycles:
+//     Returns the time spent in each phase and step of the last collection cycles of a server.
+//     Handled by `app.cycleTracesHandler`.
+//   - GET /v1/overview:
+//     Returns the fleet overview, one row per server, as JSON or as CSV with `?format=csv`.
+//     Handled by `app.overviewHandler`.
+//   - GET /v1/servers/:id/snapshot.zip:
+//     Returns a diagnostics archive of a server for support issues. Handled by `app.serverSnapshotHandler`.
+//   - GET /v1/servers/:id/service-coverage:
+//     Returns the number of services of the bundle of a server active on each of the next 60
+//     days. Handled by `app.serviceCoverageHandler`.
+//   - GET /v1/servers/:id/gtfs-rt/vehicles.json:
+//     Returns the vehicle positions of the last fetched GTFS-RT feed of a server, decoded to
+//     JSON. Handled by `app.realtimeVehiclesHandler`.
+//   - GET /v1/servers/:id/history:
+//     Returns the recorded results of the checks of a server, registered when --history-db is
+//     set. Handled by `app.checkHistoryHandler`.
+//   - GET /v1/reports/sla:
+//     Returns the availability, GTFS-RT freshness and bundle expiration report of the servers
+//     over a period, registered when --history-db is set. Handled by `app.slaReportHandler`.
+//   - GET /v1/servers/:id/badge.svg:
+//     Renders the health of a server as an SVG badge. Handled by `app.serverBadgeHandler`.
+//   - GET /v1/thresholds/suggestions:
+//     Suggests alert thresholds from the recorded values of the checks.
+//     Handled by `app.thresholdSuggestionsHandler`.
+//   - GET /v1/live:
+//     Streams check results and vehicle summaries over a WebSocket, filtered by server and
+//     check. Handled by `app.liveHandler`.
+//   - GET /v1/admin/whoami (viewer):
+//     Returns the authenticated caller and its role. Handled by `app.whoamiHandler`.
+//   - GET /v1/admin/config (viewer):
+//     Returns the effective configuration: the global HTTP timeouts, and the redacted
+//     configuration and HTTP timeouts of each server. Handled by `app.effectiveConfigHandler`.
+//   - POST /v1/admin/bundles/refresh (operator):
+//     Starts a GTFS bundle refresh for all servers, or one with `?server_id=`.
+//     Handled by `app.refreshBundlesHandler`.
+//   - POST /v1/admin/servers (admin):
+//     Adds a server to the monitored servers. Handled by `app.addServerHandler`.
+//   - DELETE /v1/admin/servers/:id (admin):
+//     Stops monitoring a server. Handled by `app.removeServerHandler`.
+//   - POST /v1/admin/servers/:id/maintenance (operator):
+//     Declares a maintenance window of a server. Handled by `app.addMaintenanceHandler`.
+//   - DELETE /v1/admin/servers/:id/maintenance (operator):
+//     Ends the maintenance windows of a server declared with the API.
+//     Handled by `app.clearMaintenanceHandler`.
+//
+//   - GET /status:
+//     The public status page, registered when enabled with --status-page.
+//     Handled by `app.statusPageHandler`.
+//   - GET /v1/incidents.atom:
+//     The incidents (alerts firing and resolving) as an Atom feed, registered when enabled
+//     with --incident-feed. Handled by `app.incidentFeedHandler`.
+//   - GET /ui:
+//     The dashboard, a page showing the status of the servers from the status API.
+//     Handled by `app.dashboardHandler`.
+//   - GET /static/*filepath:
+//     Stylesheets and scripts of the web pages.
+//   - POST /v1/probes/results:
+//     Receives the ping results of the secondary probe agents, registered when app.Vantage is
+//     set. Handled by `vantage.Store.Handler`, authenticated with PROBE_TOKEN.
+//   - GET /auth/login, GET /auth/callback, GET|POST /auth/logout:
+//     The OIDC login flow, registered when app.OIDC is set (see auth.OIDC).
+//
+// The dashboard, the status routes (/v1/servers..., including the history, /v1/overview, /v1/reports/sla, /v1/live), except badges, and the threshold suggestions are public unless OIDC login or API credentials are enabled (see `app.protect`).
+// /metrics is public unless API credentials are enabled (see `app.requireAPIAuth`). Health
+// probes, badges, the status page and the incident feed are always public.
+// Admin routes (/v1/admin/...) are only registered when app.Authenticator is set, and require
+// the role given in parentheses (see `app.requireRole`).
 //
 // Middleware:
 //   - middleware.SentryMiddleware:
@@ -54,7 +134,62 @@ func (app *Application) Routes(ctx context.Context) http.Handler {
 	// http.MethodPost are constants which equate to the strings "GET" and "POST"
 	// respectively.
 	router.HandlerFunc(http.MethodGet, "/v1/healthcheck", app.healthcheckHandler)
-	router.Handler(http.MethodGet, "/metrics", middleware.NewCachedPromHandler(ctx, prometheus.DefaultGatherer, 10*time.Second))
+	router.HandlerFunc(http.MethodGet, "/v1/livez", app.livezHandler)
+	router.HandlerFunc(http.MethodGet, "/v1/readyz", app.readyzHandler)
+	router.Handler(http.MethodGet, "/metrics", app.requireAPIAuth(middleware.NewCachedPromHandler(ctx, prometheus.DefaultGatherer, 10*time.Second)))
+
+	// Read-only status of the monitored servers.
+	router.Handler(http.MethodGet, "/v1/servers", app.protect(app.serversHandler))
+	router.Handler(http.MethodGet, "/v1/servers/:id/status", app.protect(app.serverStatusHandler))
+	router.Handler(http.MethodGet, "/v1/servers/:id/cycles", app.protect(app.cycleTracesHandler))
+	router.Handler(http.MethodGet, "/v1/servers/:id/snapshot.zip", app.protect(app.serverSnapshotHandler))
+	router.Handler(http.MethodGet, "/v1/servers/:id/service-coverage", app.protect(app.serviceCoverageHandler))
+	router.Handler(http.MethodGet, "/v1/servers/:id/gtfs-rt/vehicles.json", app.protect(app.realtimeVehiclesHandler))
+	router.Handler(http.MethodGet, "/v1/overview", app.protect(app.overviewHandler))
+	router.HandlerFunc(http.MethodGet, "/v1/servers/:id/badge.svg", app.serverBadgeHandler)
+	router.Handler(http.MethodGet, "/v1/thresholds/suggestions", app.protect(app.thresholdSuggestionsHandler))
+	if app.Live != nil {
+		router.Handler(http.MethodGet, "/v1/live", app.protect(app.liveHandler(ctx)))
+	}
+	if app.History != nil {
+		router.Handler(http.MethodGet, "/v1/servers/:id/history", app.protect(app.checkHistoryHandler))
+		router.Handler(http.MethodGet, "/v1/reports/sla", app.protect(app.slaReportHandler))
+	}
+
+	// The public status page is meant for riders, so it never requires a login.
+	if app.ConfigService.Config.StatusPage {
+		router.HandlerFunc(http.MethodGet, "/status", app.statusPageHandler)
+	}
+	// Partner agencies subscribe to incidents with feed readers, which cannot log in,
+	// so the feed is public once enabled.
+	if app.Incidents != nil {
+		router.HandlerFunc(http.MethodGet, "/v1/incidents.atom", app.incidentFeedHandler)
+	}
+	// Probe agents authenticate with the shared PROBE_TOKEN rather than API credentials.
+	if app.Vantage != nil {
+		router.Handler(http.MethodPost, vantage.Path, app.Vantage.Handler(app.ConfigService.Config.ProbeToken))
+	}
+	router.Handler(http.MethodGet, "/ui", app.protect(app.dashboardHandler))
+	router.Handler(http.MethodGet, "/static/*filepath", http.StripPrefix("/static", staticFiles()))
+
+	// Users log in through the OIDC provider, if one is configured.
+	if app.OIDC != nil {
+		router.HandlerFunc(http.MethodGet, "/auth/login", app.OIDC.LoginHandler)
+		router.HandlerFunc(http.MethodGet, "/auth/callback", app.OIDC.CallbackHandler)
+		router.HandlerFunc(http.MethodGet, "/auth/logout", app.OIDC.LogoutHandler)
+		router.HandlerFunc(http.MethodPost, "/auth/logout", app.OIDC.LogoutHandler)
+	}
+
+	// Admin endpoints are only served when callers can be authenticated.
+	if app.Authenticator != nil {
+		router.Handler(http.MethodGet, "/v1/admin/whoami", app.requireRole(auth.RoleViewer, app.whoamiHandler))
+		router.Handler(http.MethodGet, "/v1/admin/config", app.requireRole(auth.RoleViewer, app.effectiveConfigHandler))
+		router.Handler(http.MethodPost, "/v1/admin/bundles/refresh", app.requireRole(auth.RoleOperator, app.refreshBundlesHandler(ctx)))
+		router.Handler(http.MethodPost, "/v1/admin/servers", app.requireRole(auth.RoleAdmin, app.addServerHandler(ctx)))
+		router.Handler(http.MethodDelete, "/v1/admin/servers/:id", app.requireRole(auth.RoleAdmin, app.removeServerHandler))
+		router.Handler(http.MethodPost, "/v1/admin/servers/:id/maintenance", app.requireRole(auth.RoleOperator, app.addMaintenanceHandler))
+		router.Handler(http.MethodDelete, "/v1/admin/servers/:id/maintenance", app.requireRole(auth.RoleOperator, app.clearMaintenanceHandler))
+	}
 
 	// Wrap router with Sentry and SecurityHeaders middlewares
 	// Return wrapped httprouter instance.
@@ -0,0 +1,109 @@
+package app
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"slices"
+
+	"watchdog.onebusaway.org/internal/metrics"
+	"watchdog.onebusaway.org/internal/models"
+)
+
+// CheckReport is the outcome of running the checks of the servers once (see RunChecksOnce).
+type CheckReport struct {
+	// OK is true when every check of every server succeeded.
+	OK      bool                `json:"ok"`
+	Servers []ServerCheckReport `json:"servers"`
+}
+
+// ServerCheckReport is the outcome of the checks of a server, in the order they ran. Checks that
+// did not run, e.g. because the server did not answer pings, are left out.
+type ServerCheckReport struct {
+	ID     int            `json:"id"`
+	Name   string         `json:"name"`
+	OK     bool           `json:"ok"`
+	Checks []CheckOutcome `json:"checks"`
+}
+
+// CheckOutcome is the result of a check.
+type CheckOutcome struct {
+	Name  string `json:"name"`
+	OK    bool   `json:"ok"`
+	Error string `json:"error,omitempty"`
+}
+
+// RunChecksOnce downloads the GTFS bundles of servers, runs every check of each server once,
+// including the report_problem and exec checks that otherwise run on their own schedules, then
+// the checks of the configured entries of type "url", and reports the results. It backs the
+// --once mode, e.g. to validate a new GTFS bundle in CI before deploying it.
+func (app *Application) RunChecksOnce(ctx context.Context, servers []models.ObaServer, maxRetries int) CheckReport {
+	app.GtfsService.DownloadGTFSBundles(ctx, servers, maxRetries)
+	for _, server := range servers {
+		app.CollectMetricsForServer(server)
+	}
+	app.checkReportProblems()
+	app.runExecChecks(ctx)
+	targets := app.ConfigService.Config.GetURLTargets()
+	for _, target := range targets {
+		app.CollectURLTarget(target)
+	}
+
+	entries := append(append([]models.ObaServer(nil), servers...), targets...)
+	report := CheckReport{OK: true, Servers: make([]ServerCheckReport, 0, len(entries))}
+	for _, server := range entries {
+		results := app.MetricsService.CheckResults.Get(server.ID)
+		// A server without any result was not checked, which is a failure too.
+		serverReport := ServerCheckReport{ID: server.ID, Name: server.Name, OK: len(results) > 0, Checks: []CheckOutcome{}}
+		checks := slices.Clone(metrics.CheckNames)
+		for _, check := range server.ExecChecks {
+			checks = append(checks, metrics.ExecCheckName(check))
+		}
+		for _, check := range checks {
+			result, ok := results[check]
+			if !ok {
+				continue
+			}
+			serverReport.Checks = append(serverReport.Checks, CheckOutcome{Name: check, OK: result.OK, Error: result.Error})
+			serverReport.OK = serverReport.OK && result.OK
+		}
+		report.OK = report.OK && serverReport.OK
+		report.Servers = append(report.Servers, serverReport)
+	}
+	return report
+}
+
+// WriteText writes the report for humans: the checks of each server, then a summary line.
+func (r CheckReport) WriteText(w io.Writer) error {
+	failed := 0
+	for _, server := range r.Servers {
+		status := "OK"
+		if !server.OK {
+			status = "FAIL"
+			failed++
+		}
+		if _, err := fmt.Fprintf(w, "%s (%d): %s\n", server.Name, server.ID, status); err != nil {
+			return err
+		}
+		if len(server.Checks) == 0 {
+			if _, err := fmt.Fprintln(w, "  no check ran"); err != nil {
+				return err
+			}
+		}
+		for _, check := range server.Checks {
+			line := fmt.Sprintf("  ok    %s", check.Name)
+			if !check.OK {
+				line = fmt.Sprintf("  FAIL  %s: %s", check.Name, check.Error)
+			}
+			if _, err := fmt.Fprintln(w, line); err != nil {
+				return err
+			}
+		}
+	}
+	summary := fmt.Sprintf("All checks passed for %d servers", len(r.Servers))
+	if failed > 0 {
+		summary = fmt.Sprintf("Checks failed for %d of %d servers", failed, len(r.Servers))
+	}
+	_, err := fmt.Fprintln(w, summary)
+	return err
+}
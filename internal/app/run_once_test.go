@@ -0,0 +1,58 @@
+package app
+
+import (
+	"bytes"
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"watchdog.onebusaway.org/internal/metrics"
+	"watchdog.onebusaway.org/internal/models"
+)
+
+func TestRunChecksOnce(t *testing.T) {
+	app := newTestApplication(t)
+	obaServer := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusBadRequest)
+	}))
+	defer obaServer.Close()
+	server := app.ConfigService.Config.Servers[0]
+	server.ObaBaseURL = obaServer.URL
+	server.GtfsUrl = obaServer.URL + "/gtfs.zip"
+
+	report := app.RunChecksOnce(context.Background(), []models.ObaServer{server}, 0)
+	if report.OK || len(report.Servers) != 1 {
+		t.Fatalf("expected a failed report for one server, got %+v", report)
+	}
+	checks := report.Servers[0].Checks
+	if report.Servers[0].OK || len(checks) != 1 || checks[0].Name != metrics.CheckServerPing || checks[0].OK || checks[0].Error == "" {
+		t.Errorf("expected only the failed ping, got %+v", report.Servers[0])
+	}
+
+	var text bytes.Buffer
+	if err := report.WriteText(&text); err != nil {
+		t.Fatal(err)
+	}
+	for _, want := range []string{"Test Server (1): FAIL", "FAIL  server_ping: ", "Checks failed for 1 of 1 servers"} {
+		if !strings.Contains(text.String(), want) {
+			t.Errorf("expected %q in the report, got:\n%s", want, text.String())
+		}
+	}
+}
+
+func TestCheckReportText(t *testing.T) {
+	report := CheckReport{OK: true, Servers: []ServerCheckReport{
+		{ID: 1, Name: "Metro", OK: true, Checks: []CheckOutcome{{Name: metrics.CheckServerPing, OK: true}}},
+		{ID: 2, Name: "Transit", OK: true, Checks: []CheckOutcome{{Name: metrics.CheckServerPing, OK: true}, {Name: metrics.CheckObaAPI, OK: true}}},
+	}}
+	var text bytes.Buffer
+	if err := report.WriteText(&text); err != nil {
+		t.Fatal(err)
+	}
+	want := "Metro (1): OK\n  ok    server_ping\nTransit (2): OK\n  ok    server_ping\n  ok    oba_api\nAll checks passed for 2 servers\n"
+	if text.String() != want {
+		t.Errorf("expected:\n%s\ngot:\n%s", want, text.String())
+	}
+}
@@ -0,0 +1,30 @@
+package app
+
+import (
+	"strconv"
+	"time"
+
+	"watchdog.onebusaway.org/internal/config"
+	"watchdog.onebusaway.org/internal/gtfs"
+	"watchdog.onebusaway.org/internal/metrics"
+	"watchdog.onebusaway.org/internal/models"
+)
+
+// recordServerInfo exports the oba_server_info series of servers, so that dashboards can join
+// the name and region of a server onto its numeric series by server_id. The gtfs_feed_version
+// is the feed_version of the bundle in staticStore, empty until a bundle with a feed_info.txt
+// is loaded. The maintenance label is "true" while a maintenance window of the server is in
+// effect at now (see config.MaintenanceStore), so that dashboards and alert rules can leave
+// planned downtime out. Series of servers that are no longer configured, or whose settings
+// changed, are removed.
+func recordServerInfo(servers []models.ObaServer, staticStore gtfs.StaticStore, maintenance *config.MaintenanceStore, now time.Time) {
+	metrics.ServerInfo.Reset()
+	for _, server := range servers {
+		feedVersion := ""
+		if staticData, ok := staticStore.Get(server.ID); ok && staticData != nil && staticData.FeedInfo != nil {
+			feedVersion = staticData.FeedInfo.Version
+		}
+		_, inMaintenance := maintenance.Active(server, now)
+		metrics.ServerInfo.WithLabelValues(strconv.Itoa(server.ID), server.Name, server.Region, server.ObaBaseURL, feedVersion, strconv.FormatBool(inMaintenance)).Set(1)
+	}
+}
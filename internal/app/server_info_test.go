@@ -0,0 +1,42 @@
+package app
+
+import (
+	"testing"
+	"time"
+
+	"watchdog.onebusaway.org/internal/config"
+	"watchdog.onebusaway.org/internal/gtfs"
+	"watchdog.onebusaway.org/internal/metrics"
+	"watchdog.onebusaway.org/internal/models"
+)
+
+func TestRecordServerInfo(t *testing.T) {
+	staticStore := gtfs.NewStaticStore()
+	staticStore.Set(1, &models.StaticData{FeedInfo: &models.FeedInfo{Version: "2025-06-01"}})
+	servers := []models.ObaServer{
+		{ID: 1, Name: "Puget Sound", Region: "Washington", ObaBaseURL: "https://api.pugetsound.example.com"},
+		{ID: 2, Name: "Test Server", ObaBaseURL: "https://test.example.com"},
+	}
+
+	now := time.Date(2026, 5, 1, 3, 0, 0, 0, time.UTC)
+	maintenance := config.NewMaintenanceStore()
+	maintenance.Add(2, models.MaintenanceWindow{Start: now.Add(-time.Hour), End: now.Add(time.Hour)}, now)
+
+	recordServerInfo(servers, staticStore, maintenance, now)
+	if got := collectMetric(t, metrics.ServerInfo.WithLabelValues("1", "Puget Sound", "Washington", "https://api.pugetsound.example.com", "2025-06-01", "false")).GetGauge().GetValue(); got != 1 {
+		t.Errorf("expected the info of server 1, got %v", got)
+	}
+	if got := collectMetric(t, metrics.ServerInfo.WithLabelValues("2", "Test Server", "", "https://test.example.com", "", "true")).GetGauge().GetValue(); got != 1 {
+		t.Errorf("expected server 2 to be in maintenance, got %v", got)
+	}
+
+	// A renamed server replaces its series, and a removed server loses it.
+	servers[0].Name = "Sound Transit"
+	recordServerInfo(servers[:1], staticStore, maintenance, now)
+	if removed := metrics.ServerInfo.DeletePartialMatch(map[string]string{"name": "Puget Sound"}); removed != 0 {
+		t.Errorf("expected the series of the old name to be removed, found %d", removed)
+	}
+	if removed := metrics.ServerInfo.DeletePartialMatch(map[string]string{"server_id": "2"}); removed != 0 {
+		t.Errorf("expected the series of server 2 to be removed, found %d", removed)
+	}
+}
@@ -0,0 +1,38 @@
+package app
+
+import (
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/julienschmidt/httprouter"
+	"watchdog.onebusaway.org/internal/metrics"
+)
+
+// serviceCoverageHandler returns the number of services of the GTFS bundle of a server active on
+// each of the next metrics.ServiceCoverageDays days, starting today, for the dashboard to render
+// as a heatmap: the days past the end of the bundle and the gaps in its calendar have none.
+func (app *Application) serviceCoverageHandler(w http.ResponseWriter, r *http.Request) {
+	serverID, err := strconv.Atoi(httprouter.ParamsFromContext(r.Context()).ByName("id"))
+	if err != nil {
+		app.writeJSON(w, http.StatusBadRequest, map[string]string{"error": "invalid server id"})
+		return
+	}
+	known := false
+	for _, server := range app.ConfigService.Config.GetServers() {
+		known = known || server.ID == serverID
+	}
+	if !known {
+		app.writeJSON(w, http.StatusNotFound, map[string]string{"error": "unknown server id"})
+		return
+	}
+	staticData, ok := app.GtfsService.StaticStore.Get(serverID)
+	if !ok || staticData == nil {
+		app.writeJSON(w, http.StatusNotFound, map[string]string{"error": "no GTFS bundle loaded for the server"})
+		return
+	}
+	app.writeJSON(w, http.StatusOK, map[string]any{
+		"server_id": serverID,
+		"days":      metrics.ServiceCoverage(staticData, time.Now(), metrics.ServiceCoverageDays),
+	})
+}
@@ -0,0 +1,64 @@
+package app
+
+import (
+	"context"
+
+	"watchdog.onebusaway.org/internal/scheduler"
+	"watchdog.onebusaway.org/internal/sharedstore"
+)
+
+// shareStores shares the GTFS static and GTFS-RT stores, the bundle metadata, the bounding
+// boxes, the backoffs and the maintenance windows of the servers through backend (see sharedstore). Only the stores
+// that are sharedstore.Shareable, such as the in-memory ones, are shared.
+func (app *Application) shareStores(backend sharedstore.Backend) {
+	for _, store := range []any{app.GtfsService.StaticStore, app.GtfsService.RealtimeStore, app.GtfsService.BundleMetadata, app.GtfsService.BoundingBoxStore, app.ConfigService.BackoffStore, app.Maintenance} {
+		if shareable, ok := store.(sharedstore.Shareable); ok {
+			shareable.Share(backend)
+		}
+	}
+}
+
+// RunSharedStoreSync syncs the shared stores at every activation of schedule (see
+// SyncSharedStores), until ctx is canceled.
+func (app *Application) RunSharedStoreSync(ctx context.Context, schedule scheduler.Schedule) {
+	scheduler.Run(ctx, "shared_store_sync", schedule, func() { app.SyncSharedStores(ctx) })
+	app.Logger.Info("Stopping shared store sync")
+}
+
+// SyncSharedStores picks up the entries of the configured servers published by the other
+// instances to the shared stores. The metadata of a bundle is only synced along with its data,
+// which is published after it: the refreshes of this instance would otherwise skip a bundle
+// it does not have as not modified. It does nothing if the stores are not shared.
+func (app *Application) SyncSharedStores(ctx context.Context) {
+	servers := app.ConfigService.Config.GetServers()
+	serverIDs := make([]int, len(servers))
+	for i, server := range servers {
+		serverIDs[i] = server.ID
+	}
+
+	gs := app.GtfsService
+	app.syncStore(ctx, "gtfs_static", gs.StaticStore, serverIDs)
+	var withBundle []int
+	for _, id := range serverIDs {
+		if _, ok := gs.StaticStore.Get(id); ok {
+			withBundle = append(withBundle, id)
+		}
+	}
+	app.syncStore(ctx, "gtfs_bundle_metadata", gs.BundleMetadata, withBundle)
+	app.syncStore(ctx, "bounding_box", gs.BoundingBoxStore, serverIDs)
+	app.syncStore(ctx, "gtfs_realtime", gs.RealtimeStore, serverIDs)
+	app.syncStore(ctx, "backoff", app.ConfigService.BackoffStore, serverIDs)
+	app.syncStore(ctx, "maintenance", app.Maintenance, serverIDs)
+}
+
+// syncStore syncs a store if it is sharedstore.Shareable, and logs the servers that could not
+// be synced.
+func (app *Application) syncStore(ctx context.Context, name string, store any, serverIDs []int) {
+	shareable, ok := store.(sharedstore.Shareable)
+	if !ok {
+		return
+	}
+	if err := shareable.Sync(ctx, serverIDs); err != nil {
+		app.Logger.Warn("Failed to sync shared store", "store", name, "error", err)
+	}
+}
@@ -0,0 +1,53 @@
+package app
+
+import (
+	"context"
+	"testing"
+
+	"github.com/alicebob/miniredis/v2"
+	"github.com/redis/go-redis/v9"
+	"watchdog.onebusaway.org/internal/geo"
+	"watchdog.onebusaway.org/internal/gtfs"
+	"watchdog.onebusaway.org/internal/sharedstore"
+)
+
+func TestSyncSharedStores(t *testing.T) {
+	server := miniredis.RunT(t)
+	backend := &sharedstore.Redis{Client: redis.NewClient(&redis.Options{Addr: server.Addr()}), Prefix: "watchdog:"}
+	ctx := context.Background()
+
+	downloader := newTestApplication(t)
+	downloader.shareStores(backend)
+	staticData, ok := downloader.GtfsService.StaticStore.Get(1)
+	if !ok {
+		t.Fatal("expected the test application to have static data")
+	}
+	bbox, _ := downloader.GtfsService.BoundingBoxStore.Get(1)
+
+	app := newTestApplication(t)
+	app.GtfsService.StaticStore = gtfs.NewStaticStore()
+	app.GtfsService.BundleMetadata = gtfs.NewBundleMetadataStore()
+	app.GtfsService.BoundingBoxStore = geo.NewBoundingBoxStore()
+	app.shareStores(backend)
+
+	// The metadata of a bundle is published before its data, and only synced with it.
+	downloader.GtfsService.BundleMetadata.Set(1, gtfs.BundleMetadata{ETag: `"v1"`})
+	app.SyncSharedStores(ctx)
+	if _, ok := app.GtfsService.BundleMetadata.Get(1); ok {
+		t.Error("expected the metadata of a bundle not synced yet to be left out")
+	}
+
+	downloader.GtfsService.StaticStore.Set(1, staticData)
+	downloader.GtfsService.BoundingBoxStore.Set(1, bbox)
+	app.SyncSharedStores(ctx)
+	synced, ok := app.GtfsService.StaticStore.Get(1)
+	if !ok || len(synced.Stops) != len(staticData.Stops) || len(synced.TripSpans) != len(staticData.TripSpans) {
+		t.Errorf("expected the static data of the other instance to be synced")
+	}
+	if metadata, ok := app.GtfsService.BundleMetadata.Get(1); !ok || metadata.ETag != `"v1"` {
+		t.Errorf("expected the validators of the bundle to be synced, got %+v", metadata)
+	}
+	if got, ok := app.GtfsService.BoundingBoxStore.Get(1); !ok || got != bbox {
+		t.Errorf("expected bounding box %+v, got %+v", bbox, got)
+	}
+}
@@ -0,0 +1,73 @@
+package app
+
+import (
+	"net/http"
+	"strconv"
+	"time"
+
+	"watchdog.onebusaway.org/internal/history"
+)
+
+// slaReportHandler serves the uptime and SLA report of the servers over a period, computed from
+// the check history (see history.Store.Report), as JSON or as CSV with format=csv. The query
+// parameters select it: `from` and `to` as RFC 3339 times or dates (default the last 30 days),
+// `server_id` (default every server with results), `freshness_slo` as a duration (default
+// history.DefaultFreshnessSLO) and `expiration_days` (default history.DefaultExpirationDays).
+func (app *Application) slaReportHandler(w http.ResponseWriter, r *http.Request) {
+	params := r.URL.Query()
+	var opts history.ReportOptions
+	var err error
+	if opts.From, opts.To, err = history.ParseReportRange(params.Get("from"), params.Get("to"), time.Now()); err != nil {
+		app.writeJSON(w, http.StatusBadRequest, map[string]string{"error": err.Error()})
+		return
+	}
+	if id := params.Get("server_id"); id != "" {
+		serverID, err := strconv.Atoi(id)
+		if err != nil {
+			app.writeJSON(w, http.StatusBadRequest, map[string]string{"error": "invalid server_id"})
+			return
+		}
+		opts.ServerIDs = []int{serverID}
+	}
+	if slo := params.Get("freshness_slo"); slo != "" {
+		if opts.FreshnessSLO, err = time.ParseDuration(slo); err != nil || opts.FreshnessSLO <= 0 {
+			app.writeJSON(w, http.StatusBadRequest, map[string]string{"error": "invalid freshness_slo, expected a positive duration"})
+			return
+		}
+	}
+	if days := params.Get("expiration_days"); days != "" {
+		if opts.ExpirationDays, err = strconv.ParseFloat(days, 64); err != nil || opts.ExpirationDays <= 0 {
+			app.writeJSON(w, http.StatusBadRequest, map[string]string{"error": "invalid expiration_days, expected a positive number"})
+			return
+		}
+	}
+	format := params.Get("format")
+	if format != "" && format != "json" && format != "csv" {
+		app.writeJSON(w, http.StatusBadRequest, map[string]string{"error": "invalid format, expected csv or json"})
+		return
+	}
+
+	report, err := app.History.Report(r.Context(), opts)
+	if err != nil {
+		app.Logger.Error("Failed to compute the SLA report", "error", err)
+		app.writeJSON(w, http.StatusInternalServerError, map[string]string{"error": "failed to compute the report"})
+		return
+	}
+	names := make(map[int]string)
+	for _, server := range app.ConfigService.Config.GetServers() {
+		names[server.ID] = server.Name
+	}
+	for i := range report.Servers {
+		report.Servers[i].ServerName = names[report.Servers[i].ServerID]
+	}
+
+	if format == "csv" {
+		w.Header().Set("Content-Type", "text/csv; charset=utf-8")
+		w.Header().Set("Content-Disposition", `attachment; filename="watchdog-sla-report.csv"`)
+		if err := history.WriteReportCSV(w, report); err != nil {
+			app.Logger.Warn("failed to write response", "error", err)
+		}
+		return
+	}
+	app.writeJSON(w, http.StatusOK, report)
+}
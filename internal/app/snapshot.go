@@ -0,0 +1,195 @@
+package app
+
+import (
+	"archive/zip"
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strconv"
+	"time"
+
+	"github.com/julienschmidt/httprouter"
+	"watchdog.onebusaway.org/internal/models"
+)
+
+// redacted replaces secrets in snapshots.
+const redacted = "REDACTED"
+
+// snapshotServer is the server.json file of a snapshot.
+type snapshotServer struct {
+	WatchdogVersion string    `json:"watchdog_version"`
+	GeneratedAt     time.Time `json:"generated_at"`
+	// Server is the effective configuration of the server, i.e. after feed settings were
+	// derived from its data sources, with its secrets redacted.
+	Server models.ObaServer `json:"server"`
+}
+
+// snapshotBundle is the bundle_metadata.json file of a snapshot.
+type snapshotBundle struct {
+	ETag                string               `json:"etag,omitempty"`
+	LastModified        string               `json:"last_modified,omitempty"`
+	Hash                string               `json:"sha256,omitempty"`
+	DownloadedAt        *time.Time           `json:"downloaded_at,omitempty"`
+	CheckedAt           *time.Time           `json:"checked_at,omitempty"`
+	ConsecutiveFailures int                  `json:"consecutive_failures"`
+	FeedInfo            *models.FeedInfo     `json:"feed_info,omitempty"`
+	Attributions        []models.Attribution `json:"attributions,omitempty"`
+}
+
+// redactURL replaces the values of the query parameters of a URL, which commonly carry API
+// keys. URLs that cannot be parsed are redacted entirely.
+func redactURL(raw string) string {
+	if raw == "" {
+		return ""
+	}
+	u, err := url.Parse(raw)
+	if err != nil {
+		return redacted
+	}
+	query := u.Query()
+	for name := range query {
+		query[name] = []string{redacted}
+	}
+	u.RawQuery = query.Encode()
+	u.User = nil
+	return u.String()
+}
+
+// redactSecret replaces a non-empty secret.
+func redactSecret(secret string) string {
+	if secret == "" {
+		return ""
+	}
+	return redacted
+}
+
+// redactServer returns the server configuration without its API keys, webhook URLs and
+// routing keys.
+func redactServer(server models.ObaServer) models.ObaServer {
+	server.ObaApiKey = redactSecret(server.ObaApiKey)
+	server.GtfsRtApiValue = redactSecret(server.GtfsRtApiValue)
+	server.ObaBaseURL = redactURL(server.ObaBaseURL)
+	server.GtfsUrl = redactURL(server.GtfsUrl)
+	if server.GtfsUrls != nil {
+		gtfsURLs := make([]string, len(server.GtfsUrls))
+		for i, u := range server.GtfsUrls {
+			gtfsURLs[i] = redactURL(u)
+		}
+		server.GtfsUrls = gtfsURLs
+	}
+	server.TripUpdateUrl = redactURL(server.TripUpdateUrl)
+	server.VehiclePositionUrl = redactURL(server.VehiclePositionUrl)
+	server.DataSourcesURL = redactURL(server.DataSourcesURL)
+	server.ProxyURL = redactURL(server.ProxyURL)
+	if server.Alerts != nil {
+		alerts := *server.Alerts
+		alerts.SlackWebhookURL = redactSecret(alerts.SlackWebhookURL)
+		alerts.PagerDutyRoutingKey = redactSecret(alerts.PagerDutyRoutingKey)
+		alerts.WebhookURL = redactSecret(alerts.WebhookURL)
+		server.Alerts = &alerts
+	}
+	return server
+}
+
+// redactLogs replaces the API keys of a server in its log records, e.g. in logged URLs.
+func redactLogs(logs []byte, server models.ObaServer) []byte {
+	for _, secret := range []string{server.ObaApiKey, server.GtfsRtApiValue} {
+		if secret != "" {
+			logs = bytes.ReplaceAll(logs, []byte(secret), []byte(redacted))
+		}
+	}
+	return logs
+}
+
+// serverSnapshotHandler serves a zip archive with the diagnostics of a server, to attach to
+// support issues of the OBA instance:
+//   - server.json: the effective configuration of the server, redacted.
+//   - status.json: the status of the server, as served by the status API.
+//   - bundle_metadata.json: the metadata of the last GTFS bundle download.
+//   - logs.jsonl: the last log records of the server, if they are kept (see app.Logs).
+//   - gtfs_rt.pb: the last fetched GTFS-RT feed, if any.
+func (app *Application) serverSnapshotHandler(w http.ResponseWriter, r *http.Request) {
+	serverID, err := strconv.Atoi(httprouter.ParamsFromContext(r.Context()).ByName("id"))
+	if err != nil {
+		app.writeJSON(w, http.StatusBadRequest, map[string]string{"error": "invalid server id"})
+		return
+	}
+	var server *models.ObaServer
+	for _, s := range app.ConfigService.Config.GetServers() {
+		if s.ID == serverID {
+			server = &s
+			break
+		}
+	}
+	if server == nil {
+		app.writeJSON(w, http.StatusNotFound, map[string]string{"error": "unknown server id"})
+		return
+	}
+
+	// The archive is built in memory, so a failure can still be answered with an error status.
+	var archive bytes.Buffer
+	if err := app.writeSnapshot(&archive, *server, time.Now().UTC()); err != nil {
+		app.Logger.Error("failed to build snapshot", "server_id", serverID, "error", err)
+		app.writeJSON(w, http.StatusInternalServerError, map[string]string{"error": "failed to build snapshot"})
+		return
+	}
+	w.Header().Set("Content-Type", "application/zip")
+	w.Header().Set("Content-Disposition", fmt.Sprintf(`attachment; filename="watchdog-server-%d-%s.zip"`, serverID, time.Now().UTC().Format("20060102T150405Z")))
+	_, _ = w.Write(archive.Bytes())
+}
+
+// writeSnapshot writes the diagnostics archive of a server (see serverSnapshotHandler).
+func (app *Application) writeSnapshot(w *bytes.Buffer, server models.ObaServer, now time.Time) error {
+	archive := zip.NewWriter(w)
+	add := func(name string, data []byte) error {
+		f, err := archive.CreateHeader(&zip.FileHeader{Name: name, Method: zip.Deflate, Modified: now})
+		if err != nil {
+			return err
+		}
+		_, err = f.Write(data)
+		return err
+	}
+	addJSON := func(name string, v any) error {
+		data, err := json.MarshalIndent(v, "", "  ")
+		if err != nil {
+			return fmt.Errorf("failed to encode %s: %w", name, err)
+		}
+		return add(name, data)
+	}
+
+	if err := addJSON("server.json", snapshotServer{WatchdogVersion: app.Version, GeneratedAt: now, Server: redactServer(server)}); err != nil {
+		return err
+	}
+	if err := addJSON("status.json", app.serverStatus(server)); err != nil {
+		return err
+	}
+	var bundle snapshotBundle
+	if metadata, ok := app.GtfsService.BundleMetadata.Get(server.ID); ok {
+		bundle = snapshotBundle{
+			ETag:                metadata.ETag,
+			LastModified:        metadata.LastModified,
+			Hash:                metadata.Hash,
+			DownloadedAt:        timePtr(metadata.DownloadedAt),
+			CheckedAt:           timePtr(metadata.CheckedAt),
+			ConsecutiveFailures: metadata.ConsecutiveFailures,
+			FeedInfo:            metadata.FeedInfo,
+			Attributions:        metadata.Attributions,
+		}
+	}
+	if err := addJSON("bundle_metadata.json", bundle); err != nil {
+		return err
+	}
+	if app.Logs != nil {
+		if err := add("logs.jsonl", redactLogs(app.Logs.Records(server.ID), server)); err != nil {
+			return err
+		}
+	}
+	if feed, ok := app.GtfsService.RealtimeStore.Raw(server.ID); ok {
+		if err := add("gtfs_rt.pb", feed.Data); err != nil {
+			return err
+		}
+	}
+	return archive.Close()
+}
@@ -0,0 +1,76 @@
+package app
+
+import (
+	"archive/zip"
+	"bytes"
+	"context"
+	"encoding/json"
+	"io"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"watchdog.onebusaway.org/internal/gtfs"
+	"watchdog.onebusaway.org/internal/logbuffer"
+	"watchdog.onebusaway.org/internal/models"
+)
+
+func TestServerSnapshotHandler(t *testing.T) {
+	app := newTestApplication(t)
+	app.Logs = logbuffer.New(10)
+	slog.New(logbuffer.NewHandler(slog.NewTextHandler(io.Discard, nil), app.Logs)).
+		Error("Failed to fetch https://test.example.com/api?key=test-key", "server_id", 1)
+	app.GtfsService.RealtimeStore.SetRaw(1, gtfs.RawFeed{Data: []byte("feed")})
+	app.ConfigService.Config.Servers[0].VehiclePositionUrl = "https://feed.example.com/vehicles?api_key=secret"
+	app.ConfigService.Config.Servers[0].Alerts = &models.AlertConfig{SlackWebhookURL: "https://hooks.slack.com/services/T/B/X"}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	handler := app.Routes(ctx)
+
+	rr := httptest.NewRecorder()
+	handler.ServeHTTP(rr, httptest.NewRequest(http.MethodGet, "/v1/servers/1/snapshot.zip", nil))
+	if rr.Code != http.StatusOK || rr.Header().Get("Content-Type") != "application/zip" {
+		t.Fatalf("expected a zip archive, got %d %s", rr.Code, rr.Header().Get("Content-Type"))
+	}
+	archive, err := zip.NewReader(bytes.NewReader(rr.Body.Bytes()), int64(rr.Body.Len()))
+	if err != nil {
+		t.Fatal(err)
+	}
+	files := make(map[string]string)
+	for _, f := range archive.File {
+		r, err := f.Open()
+		if err != nil {
+			t.Fatal(err)
+		}
+		data, _ := io.ReadAll(r)
+		files[f.Name] = string(data)
+	}
+	for _, name := range []string{"server.json", "status.json", "bundle_metadata.json", "logs.jsonl", "gtfs_rt.pb"} {
+		if _, ok := files[name]; !ok {
+			t.Errorf("expected %s in the snapshot", name)
+		}
+	}
+
+	var server snapshotServer
+	if err := json.Unmarshal([]byte(files["server.json"]), &server); err != nil {
+		t.Fatal(err)
+	}
+	if server.Server.ObaApiKey != redacted || server.Server.VehiclePositionUrl != "https://feed.example.com/vehicles?api_key=REDACTED" || server.Server.Alerts.SlackWebhookURL != redacted {
+		t.Errorf("expected the secrets to be redacted, got %+v", server.Server)
+	}
+	if strings.Contains(files["logs.jsonl"], "test-key") || !strings.Contains(files["logs.jsonl"], "key=REDACTED") {
+		t.Errorf("expected the API key to be redacted from the logs, got %q", files["logs.jsonl"])
+	}
+	if files["gtfs_rt.pb"] != "feed" {
+		t.Errorf("expected the raw GTFS-RT feed, got %q", files["gtfs_rt.pb"])
+	}
+
+	rr = httptest.NewRecorder()
+	handler.ServeHTTP(rr, httptest.NewRequest(http.MethodGet, "/v1/servers/9/snapshot.zip", nil))
+	if rr.Code != http.StatusNotFound {
+		t.Errorf("expected 404 for an unknown server, got %d", rr.Code)
+	}
+}
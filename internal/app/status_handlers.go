@@ -0,0 +1,268 @@
+package app
+
+import (
+	"net/http"
+	"sort"
+	"strconv"
+	"time"
+
+	"github.com/julienschmidt/httprouter"
+	"watchdog.onebusaway.org/internal/config"
+	"watchdog.onebusaway.org/internal/cycletrace"
+	"watchdog.onebusaway.org/internal/gtfs"
+	"watchdog.onebusaway.org/internal/lifecycle"
+	"watchdog.onebusaway.org/internal/metrics"
+	"watchdog.onebusaway.org/internal/models"
+	"watchdog.onebusaway.org/internal/vantage"
+)
+
+// Health of a server, derived from the last results of its checks (see serverHealth).
+const (
+	healthUp       = "up"
+	healthDegraded = "degraded"
+	healthDown     = "down"
+	healthUnknown  = "unknown"
+)
+
+// serverHealth summarizes the last results of the checks of a server: down if it does not
+// answer pings, degraded if another check fails, unknown if it has not been checked yet.
+func serverHealth(results map[string]metrics.CheckResult) string {
+	if len(results) == 0 {
+		return healthUnknown
+	}
+	if ping, ok := results[metrics.CheckServerPing]; ok && !ping.OK {
+		return healthDown
+	}
+	for _, result := range results {
+		if !result.OK {
+			return healthDegraded
+		}
+	}
+	return healthUp
+}
+
+// serverSummary is an entry of GET /v1/servers.
+type serverSummary struct {
+	ID         int    `json:"id"`
+	Name       string `json:"name"`
+	ObaBaseURL string `json:"oba_base_url"`
+	// Health is up, degraded, down or unknown (see serverHealth).
+	Health string `json:"health"`
+	// Healthy is true when the last run of every check succeeded.
+	Healthy bool `json:"healthy"`
+	// FailingChecks lists the checks whose last run failed.
+	FailingChecks []string   `json:"failing_checks"`
+	LastCheckAt   *time.Time `json:"last_check_at,omitempty"`
+	// Bootstrap is the cold start state of the server: pending, downloading, loaded or failed.
+	Bootstrap string `json:"bootstrap,omitempty"`
+	// Maintenance is true while a maintenance window of the server is in effect.
+	Maintenance bool `json:"maintenance,omitempty"`
+}
+
+// serverStatus is the response of GET /v1/servers/:id/status.
+type serverStatus struct {
+	ID         int                    `json:"id"`
+	Name       string                 `json:"name"`
+	ObaBaseURL string                 `json:"oba_base_url"`
+	Bundle     bundleStatus           `json:"gtfs_bundle"`
+	Realtime   realtimeStatus         `json:"gtfs_realtime"`
+	Backoff    backoffStatus          `json:"backoff"`
+	Circuit    circuitStatus          `json:"circuit"`
+	Checks     map[string]checkStatus `json:"checks"`
+	// Bootstrap is the cold start state of the server (see serverSummary).
+	Bootstrap string `json:"bootstrap,omitempty"`
+	// Notes are configuration issues of the server, e.g. a URL shared with another server.
+	Notes []string `json:"notes,omitempty"`
+	// Lifecycle is when the server, the routes of its bundle and its GTFS-RT feeds were first
+	// and last observed, if they were.
+	Lifecycle *lifecycle.Entities `json:"lifecycle,omitempty"`
+	// Vantages are the latest pings of the server by the probe agents, by vantage point, and
+	// Diagnosis compares them with the ping of this watchdog (see vantage.Diagnose).
+	Vantages  map[string]vantage.Result `json:"vantages,omitempty"`
+	Diagnosis string                    `json:"diagnosis,omitempty"`
+	// Maintenance is the maintenance window of the server in effect, if any, and
+	// MaintenanceWindows its windows that have not ended, configured or declared with the admin
+	// API.
+	Maintenance        *models.MaintenanceWindow  `json:"maintenance,omitempty"`
+	MaintenanceWindows []models.MaintenanceWindow `json:"maintenance_windows,omitempty"`
+	// LastCycle is the breakdown of the time of the last collection cycle of the server, if it
+	// was checked (see GET /v1/servers/:id/cycles).
+	LastCycle *cycletrace.Trace `json:"last_cycle,omitempty"`
+}
+
+type bundleStatus struct {
+	LastDownloadAt      *time.Time `json:"last_download_at,omitempty"`
+	LastCheckedAt       *time.Time `json:"last_checked_at,omitempty"`
+	ConsecutiveFailures int        `json:"consecutive_failures"`
+	// EarliestServiceEnd and LatestServiceEnd are the end dates (YYYY-MM-DD) of the services
+	// of the bundle that end first and last.
+	EarliestServiceEnd string `json:"earliest_service_end,omitempty"`
+	LatestServiceEnd   string `json:"latest_service_end,omitempty"`
+	// License holds the publisher and attributions declared by the bundle, if any.
+	License *models.License `json:"license,omitempty"`
+}
+
+type realtimeStatus struct {
+	LastFetchAt   *time.Time `json:"last_fetch_at,omitempty"`
+	LastAttemptAt *time.Time `json:"last_attempt_at,omitempty"`
+	Error         string     `json:"error,omitempty"`
+}
+
+type backoffStatus struct {
+	Active       bool       `json:"active"`
+	DelaySeconds float64    `json:"delay_seconds,omitempty"`
+	NextRetryAt  *time.Time `json:"next_retry_at,omitempty"`
+}
+
+type circuitStatus struct {
+	State               config.CircuitState `json:"state"`
+	ConsecutiveFailures int                 `json:"consecutive_failures"`
+	// NextProbeAt is when an open circuit is probed again.
+	NextProbeAt *time.Time `json:"next_probe_at,omitempty"`
+}
+
+type checkStatus struct {
+	OK            bool       `json:"ok"`
+	Error         string     `json:"error,omitempty"`
+	At            time.Time  `json:"at"`
+	LastSuccessAt *time.Time `json:"last_success_at,omitempty"`
+	Streak        int        `json:"streak"`
+	Flakiness     float64    `json:"flakiness"`
+}
+
+// timePtr returns nil for the zero time, so that it is omitted from JSON responses.
+func timePtr(t time.Time) *time.Time {
+	if t.IsZero() {
+		return nil
+	}
+	t = t.UTC()
+	return &t
+}
+
+// serversHandler lists the monitored servers with a summary of their health, and the progress
+// of the cold start.
+func (app *Application) serversHandler(w http.ResponseWriter, r *http.Request) {
+	servers := app.ConfigService.Config.GetServers()
+	summaries := make([]serverSummary, 0, len(servers))
+	for _, server := range servers {
+		summary := serverSummary{
+			ID:            server.ID,
+			Name:          server.Name,
+			ObaBaseURL:    server.ObaBaseURL,
+			Healthy:       true,
+			FailingChecks: []string{},
+			Bootstrap:     app.Bootstrap.State(server.ID),
+		}
+		_, summary.Maintenance = app.Maintenance.Active(server, time.Now())
+		results := app.MetricsService.CheckResults.Get(server.ID)
+		summary.Health = serverHealth(results)
+		var lastCheckAt time.Time
+		for check, result := range results {
+			if !result.OK {
+				summary.Healthy = false
+				summary.FailingChecks = append(summary.FailingChecks, check)
+			}
+			if result.At.After(lastCheckAt) {
+				lastCheckAt = result.At
+			}
+		}
+		sort.Strings(summary.FailingChecks)
+		summary.LastCheckAt = timePtr(lastCheckAt)
+		summaries = append(summaries, summary)
+	}
+	app.writeJSON(w, http.StatusOK, map[string]any{"servers": summaries, "bootstrap": app.Bootstrap.Progress()})
+}
+
+// serverStatusHandler returns the state of a server: its GTFS bundle, its last realtime fetch,
+// its backoff state, the last result of each check and when its entities were first and last
+// observed.
+func (app *Application) serverStatusHandler(w http.ResponseWriter, r *http.Request) {
+	serverID, err := strconv.Atoi(httprouter.ParamsFromContext(r.Context()).ByName("id"))
+	if err != nil {
+		app.writeJSON(w, http.StatusBadRequest, map[string]string{"error": "invalid server id"})
+		return
+	}
+	for _, server := range app.ConfigService.Config.GetServers() {
+		if server.ID == serverID {
+			app.writeJSON(w, http.StatusOK, app.serverStatus(server))
+			return
+		}
+	}
+	app.writeJSON(w, http.StatusNotFound, map[string]string{"error": "unknown server id"})
+}
+
+func (app *Application) serverStatus(server models.ObaServer) serverStatus {
+	status := serverStatus{
+		ID:         server.ID,
+		Name:       server.Name,
+		ObaBaseURL: server.ObaBaseURL,
+		Checks:     make(map[string]checkStatus),
+		Bootstrap:  app.Bootstrap.State(server.ID),
+		Notes:      duplicateNotes(server.ID, config.FindDuplicateServers(app.ConfigService.Config.GetServers())),
+	}
+
+	if metadata, ok := app.GtfsService.BundleMetadata.Get(server.ID); ok {
+		status.Bundle.LastDownloadAt = timePtr(metadata.DownloadedAt)
+		status.Bundle.LastCheckedAt = timePtr(metadata.CheckedAt)
+		status.Bundle.ConsecutiveFailures = metadata.ConsecutiveFailures
+	}
+	if staticData, ok := app.GtfsService.StaticStore.Get(server.ID); ok {
+		if earliest, latest, err := gtfs.GetEarliestAndLatestServiceDates(staticData); err == nil {
+			status.Bundle.EarliestServiceEnd = earliest.Format(time.DateOnly)
+			status.Bundle.LatestServiceEnd = latest.Format(time.DateOnly)
+		}
+		if license := models.NewLicense(staticData.FeedInfo, staticData.Attributions); !license.IsZero() {
+			status.Bundle.License = &license
+		}
+	}
+
+	if delay, ok := app.ConfigService.BackoffStore.BackoffDelay(server.ID); ok {
+		nextRetryAt, _ := app.ConfigService.BackoffStore.NextRetryAt(server.ID)
+		status.Backoff = backoffStatus{Active: true, DelaySeconds: delay.Seconds(), NextRetryAt: timePtr(nextRetryAt)}
+	}
+	state, failures := app.ConfigService.BackoffStore.CircuitState(server.ID, time.Now())
+	status.Circuit = circuitStatus{State: state, ConsecutiveFailures: failures}
+	if state == config.CircuitOpen {
+		status.Circuit.NextProbeAt = status.Backoff.NextRetryAt
+	}
+
+	if entities, ok := app.Lifecycle.Get(server.ID); ok {
+		status.Lifecycle = &entities
+	}
+
+	if remote := app.Vantage.Get(server.ID, time.Now()); len(remote) > 0 {
+		status.Vantages = remote
+		if ping, ok := app.MetricsService.CheckResults.Get(server.ID)[metrics.CheckServerPing]; ok {
+			status.Diagnosis = vantage.Diagnose(ping.OK, remote)
+		}
+	}
+
+	now := time.Now()
+	if window, ok := app.Maintenance.Active(server, now); ok {
+		status.Maintenance = &window
+	}
+	status.MaintenanceWindows = app.Maintenance.Windows(server, now)
+
+	if trace, ok := app.CycleTraces.Last(server.ID); ok {
+		status.LastCycle = &trace
+	}
+
+	for check, result := range app.MetricsService.CheckResults.Get(server.ID) {
+		status.Checks[check] = checkStatus{
+			OK:            result.OK,
+			Error:         result.Error,
+			At:            result.At.UTC(),
+			LastSuccessAt: timePtr(result.LastSuccessAt),
+			Streak:        result.Streak,
+			Flakiness:     result.Flakiness,
+		}
+		if check == metrics.CheckRealtimeFeed {
+			status.Realtime = realtimeStatus{
+				LastFetchAt:   timePtr(result.LastSuccessAt),
+				LastAttemptAt: timePtr(result.At),
+				Error:         result.Error,
+			}
+		}
+	}
+	return status
+}
@@ -0,0 +1,198 @@
+package app
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"testing"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	"watchdog.onebusaway.org/internal/config"
+	"watchdog.onebusaway.org/internal/lifecycle"
+	"watchdog.onebusaway.org/internal/metrics"
+	"watchdog.onebusaway.org/internal/models"
+	"watchdog.onebusaway.org/internal/vantage"
+)
+
+func TestStatusRoutes(t *testing.T) {
+	app := newTestApplication(t)
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	handler := app.Routes(ctx)
+
+	at := time.Date(2025, 6, 1, 12, 0, 0, 0, time.UTC)
+	app.MetricsService.CheckResults.Record(1, metrics.CheckServerPing, nil, at)
+	app.MetricsService.CheckResults.Record(1, metrics.CheckRealtimeFeed, nil, at)
+	app.MetricsService.CheckResults.Record(1, metrics.CheckRealtimeFeed, errors.New("feed unavailable"), at.Add(time.Minute))
+	app.ConfigService.BackoffStore.UpdateBackoff(1)
+	staticData, _ := app.GtfsService.StaticStore.Get(1)
+	staticData.FeedInfo = &models.FeedInfo{PublisherName: "Sound Transit"}
+	staticData.Attributions = []models.Attribution{{OrganizationName: "King County Metro", Roles: []string{"operator"}}}
+
+	t.Run("list", func(t *testing.T) {
+		rr := httptest.NewRecorder()
+		handler.ServeHTTP(rr, httptest.NewRequest(http.MethodGet, "/v1/servers", nil))
+		if rr.Code != http.StatusOK {
+			t.Fatalf("expected 200, got %d", rr.Code)
+		}
+		var body struct {
+			Servers []serverSummary `json:"servers"`
+		}
+		if err := json.Unmarshal(rr.Body.Bytes(), &body); err != nil {
+			t.Fatal(err)
+		}
+		if len(body.Servers) != 1 {
+			t.Fatalf("expected one server, got %+v", body.Servers)
+		}
+		server := body.Servers[0]
+		if server.ID != 1 || server.Health != healthDegraded || server.Healthy || len(server.FailingChecks) != 1 || server.FailingChecks[0] != metrics.CheckRealtimeFeed {
+			t.Errorf("unexpected summary %+v", server)
+		}
+		if server.LastCheckAt == nil || !server.LastCheckAt.Equal(at.Add(time.Minute)) {
+			t.Errorf("expected the last check at %v, got %v", at.Add(time.Minute), server.LastCheckAt)
+		}
+	})
+
+	t.Run("status", func(t *testing.T) {
+		rr := httptest.NewRecorder()
+		handler.ServeHTTP(rr, httptest.NewRequest(http.MethodGet, "/v1/servers/1/status", nil))
+		if rr.Code != http.StatusOK {
+			t.Fatalf("expected 200, got %d", rr.Code)
+		}
+		var status serverStatus
+		if err := json.Unmarshal(rr.Body.Bytes(), &status); err != nil {
+			t.Fatal(err)
+		}
+		if status.Bundle.EarliestServiceEnd == "" || status.Bundle.LatestServiceEnd == "" {
+			t.Errorf("expected the service end dates of the bundle, got %+v", status.Bundle)
+		}
+		if license := status.Bundle.License; license == nil || license.PublisherName != "Sound Transit" || len(license.Attributions) != 1 || license.Attributions[0].Roles[0] != "operator" {
+			t.Errorf("unexpected license %+v", license)
+		}
+		if status.Realtime.LastFetchAt == nil || !status.Realtime.LastFetchAt.Equal(at) || status.Realtime.Error != "feed unavailable" {
+			t.Errorf("unexpected realtime status %+v", status.Realtime)
+		}
+		if !status.Backoff.Active || status.Backoff.DelaySeconds != 1 || status.Backoff.NextRetryAt == nil {
+			t.Errorf("unexpected backoff status %+v", status.Backoff)
+		}
+		if status.Circuit.State != config.CircuitClosed || status.Circuit.ConsecutiveFailures != 1 || status.Circuit.NextProbeAt != nil {
+			t.Errorf("unexpected circuit status %+v", status.Circuit)
+		}
+		if len(status.Checks) != 2 || !status.Checks[metrics.CheckServerPing].OK {
+			t.Errorf("unexpected checks %+v", status.Checks)
+		}
+	})
+
+	for path, want := range map[string]int{
+		"/v1/servers/99/status": http.StatusNotFound,
+		"/v1/servers/x/status":  http.StatusBadRequest,
+	} {
+		rr := httptest.NewRecorder()
+		handler.ServeHTTP(rr, httptest.NewRequest(http.MethodGet, path, nil))
+		if rr.Code != want {
+			t.Errorf("GET %s: expected %d, got %d", path, want, rr.Code)
+		}
+	}
+}
+
+func TestServerHealth(t *testing.T) {
+	ok := metrics.CheckResult{OK: true}
+	failed := metrics.CheckResult{Error: "failed"}
+	tests := []struct {
+		name    string
+		results map[string]metrics.CheckResult
+		want    string
+	}{
+		{"not checked", nil, healthUnknown},
+		{"all checks pass", map[string]metrics.CheckResult{metrics.CheckServerPing: ok, metrics.CheckObaAPI: ok}, healthUp},
+		{"a check fails", map[string]metrics.CheckResult{metrics.CheckServerPing: ok, metrics.CheckObaAPI: failed}, healthDegraded},
+		{"ping fails", map[string]metrics.CheckResult{metrics.CheckServerPing: failed, metrics.CheckObaAPI: ok}, healthDown},
+	}
+	for _, tt := range tests {
+		if got := serverHealth(tt.results); got != tt.want {
+			t.Errorf("%s: expected %s, got %s", tt.name, tt.want, got)
+		}
+	}
+}
+
+func TestServerStatusLifecycle(t *testing.T) {
+	app := newTestApplication(t)
+	app.Lifecycle, _ = lifecycle.Open("")
+	server := app.ConfigService.Config.GetServers()[0]
+
+	if status := app.serverStatus(server); status.Lifecycle != nil {
+		t.Errorf("expected no lifecycle before any observation, got %+v", status.Lifecycle)
+	}
+
+	app.recordBundleRoutes(server)
+	app.recordCheck(server, metrics.CheckServerPing, nil)
+	app.recordCheck(server, metrics.CheckRealtimeFeed, errors.New("feed unavailable"))
+
+	status := app.serverStatus(server)
+	if status.Lifecycle == nil || status.Lifecycle.Server == nil || status.Lifecycle.Server.LastSeen.IsZero() {
+		t.Fatalf("expected the server to be observed, got %+v", status.Lifecycle)
+	}
+	staticData, _ := app.GtfsService.StaticStore.Get(server.ID)
+	if len(status.Lifecycle.Routes) != len(staticData.Routes) {
+		t.Errorf("expected the %d routes of the bundle, got %d", len(staticData.Routes), len(status.Lifecycle.Routes))
+	}
+	if len(status.Lifecycle.RealtimeFeeds) != 0 {
+		t.Errorf("expected a failed fetch not to observe the feed, got %+v", status.Lifecycle.RealtimeFeeds)
+	}
+}
+
+func TestServerStatusVantages(t *testing.T) {
+	app := newTestApplication(t)
+	app.Vantage = vantage.NewStore(time.Minute)
+	server := app.ConfigService.Config.GetServers()[0]
+	serverID := strconv.Itoa(server.ID)
+
+	app.Vantage.Record(vantage.Report{Vantage: "eu-west", Results: []vantage.Result{{ServerID: server.ID, OK: true, At: time.Now()}}})
+	app.recordCheck(server, metrics.CheckServerPing, errors.New("server did not respond to ping"))
+
+	status := app.serverStatus(server)
+	if !status.Vantages["eu-west"].OK || status.Diagnosis != vantage.DiagnosisPrimaryUnreachable {
+		t.Errorf("expected the server to be unreachable from the primary only, got %q with %+v", status.Diagnosis, status.Vantages)
+	}
+	if got := testutil.ToFloat64(vantage.UnreachableFromPrimaryGauge.WithLabelValues(serverID)); got != 1 {
+		t.Errorf("expected oba_api_unreachable_from_primary to be 1, got %v", got)
+	}
+
+	app.recordCheck(server, metrics.CheckServerPing, nil)
+	if got := testutil.ToFloat64(vantage.UnreachableFromPrimaryGauge.WithLabelValues(serverID)); got != 0 {
+		t.Errorf("expected oba_api_unreachable_from_primary to be 0 once the primary reaches the server, got %v", got)
+	}
+}
+
+func TestServiceCoverageRoute(t *testing.T) {
+	app := newTestApplication(t)
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	handler := app.Routes(ctx)
+
+	rr := httptest.NewRecorder()
+	handler.ServeHTTP(rr, httptest.NewRequest(http.MethodGet, "/v1/servers/1/service-coverage", nil))
+	if rr.Code != http.StatusOK {
+		t.Fatalf("expected 200, got %d: %s", rr.Code, rr.Body.String())
+	}
+	var body struct {
+		ServerID int                  `json:"server_id"`
+		Days     []metrics.ServiceDay `json:"days"`
+	}
+	if err := json.Unmarshal(rr.Body.Bytes(), &body); err != nil {
+		t.Fatal(err)
+	}
+	if body.ServerID != 1 || len(body.Days) != metrics.ServiceCoverageDays {
+		t.Errorf("expected %d days for server 1, got %+v", metrics.ServiceCoverageDays, body)
+	}
+
+	rr = httptest.NewRecorder()
+	handler.ServeHTTP(rr, httptest.NewRequest(http.MethodGet, "/v1/servers/42/service-coverage", nil))
+	if rr.Code != http.StatusNotFound {
+		t.Errorf("expected 404 for an unknown server, got %d", rr.Code)
+	}
+}
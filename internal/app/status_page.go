@@ -0,0 +1,189 @@
+package app
+
+import (
+	"bytes"
+	"context"
+	"embed"
+	"fmt"
+	"html/template"
+	"io/fs"
+	"net/http"
+	"strings"
+	"time"
+
+	"watchdog.onebusaway.org/internal/i18n"
+	"watchdog.onebusaway.org/internal/metrics"
+)
+
+// webFS holds the templates of the web pages, and the static files they use (served under /static/).
+//
+//go:embed web
+var webFS embed.FS
+
+var statusPageTemplate = template.Must(template.ParseFS(webFS, "web/templates/status_page.html"))
+
+// staticFiles serves the files of web/static.
+func staticFiles() http.Handler {
+	static, err := fs.Sub(webFS, "web/static")
+	if err != nil {
+		panic(err)
+	}
+	return http.FileServer(http.FS(static))
+}
+
+// statusPage is the data of the status page template.
+type statusPage struct {
+	Lang           string
+	Title          string
+	AllOperational bool
+	Servers        []statusPageServer
+	Days           int
+	Updated        string
+}
+
+// T translates a message into the language of the page.
+func (p statusPage) T(key string, args ...interface{}) string {
+	return i18n.T(p.Lang, key, args...)
+}
+
+type statusPageServer struct {
+	Name string
+	// State is the health of the server (see serverHealth), computed from the exposed checks only.
+	State string
+	// Uptime is the availability over the days shown, or "" without history.
+	Uptime string
+	Days   []statusPageDay
+}
+
+type statusPageDay struct {
+	// State is up, degraded, down, or "" for days without data.
+	State string
+	Title string
+}
+
+// dayAvailability returns the availability of a server on a day: the success rate of the least
+// available of the checks, or -1 if none of them ran that day.
+func dayAvailability(counts []metrics.DailyCount) float64 {
+	availability := -1.0
+	for _, count := range counts {
+		if count.Runs == 0 {
+			continue
+		}
+		rate := 1 - float64(count.Failures)/float64(count.Runs)
+		if availability < 0 || rate < availability {
+			availability = rate
+		}
+	}
+	return availability
+}
+
+// availabilityState maps an availability to the color of its bar.
+func availabilityState(availability float64) string {
+	switch {
+	case availability < 0:
+		return ""
+	case availability >= 0.99:
+		return healthUp
+	case availability >= 0.95:
+		return healthDegraded
+	default:
+		return healthDown
+	}
+}
+
+// formatPercent formats a ratio as a percentage, e.g. 0.99953 → "99.95%".
+func formatPercent(ratio float64) string {
+	return fmt.Sprintf("%.2f%%", ratio*100)
+}
+
+// pageLanguage returns the language of a page: the `lang` query parameter, or the first
+// language of the Accept-Language header.
+func pageLanguage(r *http.Request) string {
+	if lang := r.URL.Query().Get("lang"); lang != "" {
+		return i18n.Match(lang)
+	}
+	lang, _, _ := strings.Cut(r.Header.Get("Accept-Language"), ",")
+	lang, _, _ = strings.Cut(lang, ";")
+	return i18n.Match(lang)
+}
+
+// dailyCounts returns the runs and failures of a check of a server on each of the last days
+// days: from the history database with --history-db, so that the uptime survives restarts, or
+// else from the daily counts kept in memory by the check results.
+func (app *Application) dailyCounts(ctx context.Context, serverID int, check string, days int, now time.Time) []metrics.DailyCount {
+	if app.History != nil {
+		counts, err := app.History.DailyCounts(ctx, serverID, check, days, now)
+		if err == nil {
+			daily := make([]metrics.DailyCount, len(counts))
+			for i, count := range counts {
+				daily[i] = metrics.DailyCount(count)
+			}
+			return daily
+		}
+		app.Logger.Warn("Failed to read the daily uptime from the history database", "server_id", serverID, "check", check, "error", err)
+	}
+	return app.MetricsService.CheckResults.History(serverID, check, days, now)
+}
+
+// statusPageHandler renders the public status page: the current state of each server and its
+// daily uptime over the last StatusPageDays days (see dailyCounts). Only the checks listed in StatusPageChecks
+// are taken into account, so internal checks (e.g. data quality) do not show as outages to riders.
+func (app *Application) statusPageHandler(w http.ResponseWriter, r *http.Request) {
+	cfg := app.ConfigService.Config
+	now := time.Now().UTC()
+	page := statusPage{
+		Lang:           pageLanguage(r),
+		Title:          cfg.StatusPageTitle,
+		AllOperational: true,
+		Days:           cfg.StatusPageDays,
+		Updated:        now.Format("2006-01-02 15:04 MST"),
+	}
+
+	for _, server := range cfg.GetServers() {
+		results := app.MetricsService.CheckResults.Get(server.ID)
+		exposed := make(map[string]metrics.CheckResult)
+		histories := make([][]metrics.DailyCount, 0, len(cfg.StatusPageChecks))
+		for _, check := range cfg.StatusPageChecks {
+			if result, ok := results[check]; ok {
+				exposed[check] = result
+			}
+			histories = append(histories, app.dailyCounts(r.Context(), server.ID, check, cfg.StatusPageDays, now))
+		}
+
+		entry := statusPageServer{Name: server.Name, State: serverHealth(exposed)}
+		if entry.State == healthDegraded || entry.State == healthDown {
+			page.AllOperational = false
+		}
+
+		var total float64
+		var daysWithData int
+		for i := 0; i < cfg.StatusPageDays; i++ {
+			counts := make([]metrics.DailyCount, len(histories))
+			for j, history := range histories {
+				counts[j] = history[i]
+			}
+			availability := dayAvailability(counts)
+			date := now.AddDate(0, 0, i-cfg.StatusPageDays+1).Format(time.DateOnly)
+			day := statusPageDay{State: availabilityState(availability), Title: date + " · " + page.T("statuspage.no_data")}
+			if availability >= 0 {
+				day.Title = date + " · " + formatPercent(availability)
+				total += availability
+				daysWithData++
+			}
+			entry.Days = append(entry.Days, day)
+		}
+		if daysWithData > 0 {
+			entry.Uptime = formatPercent(total / float64(daysWithData))
+		}
+		page.Servers = append(page.Servers, entry)
+	}
+
+	var buf bytes.Buffer
+	if err := statusPageTemplate.Execute(&buf, page); err != nil {
+		app.Logger.Error("failed to render status page", "error", err)
+		http.Error(w, "failed to render status page", http.StatusInternalServerError)
+		return
+	}
+	w.Header().Set("Content-Type", "text/html; charset=utf-8")
+	_, _ = w.Write(buf.Bytes())
+}
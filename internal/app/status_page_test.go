@@ -0,0 +1,114 @@
+package app
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"watchdog.onebusaway.org/internal/history"
+	"watchdog.onebusaway.org/internal/metrics"
+)
+
+func TestStatusPage(t *testing.T) {
+	app := newTestApplication(t)
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	// The page is only served when enabled.
+	rr := httptest.NewRecorder()
+	app.Routes(ctx).ServeHTTP(rr, httptest.NewRequest(http.MethodGet, "/status", nil))
+	if rr.Code != http.StatusNotFound {
+		t.Fatalf("expected 404 when the status page is disabled, got %d", rr.Code)
+	}
+
+	cfg := app.ConfigService.Config
+	cfg.StatusPage = true
+	cfg.StatusPageTitle = "Test Transit Status"
+	cfg.StatusPageChecks = []string{metrics.CheckServerPing}
+	cfg.StatusPageDays = 30
+	handler := app.Routes(ctx)
+
+	now := time.Now().UTC()
+	app.MetricsService.CheckResults.Record(1, metrics.CheckServerPing, nil, now.AddDate(0, 0, -1))
+	app.MetricsService.CheckResults.Record(1, metrics.CheckServerPing, nil, now)
+	// Not exposed, so it does not make the server degraded.
+	app.MetricsService.CheckResults.Record(1, metrics.CheckVehicleCount, errors.New("mismatch"), now)
+
+	r := httptest.NewRequest(http.MethodGet, "/status", nil)
+	r.Header.Set("Accept-Language", "fr-CA,fr;q=0.9,en;q=0.8")
+	rr = httptest.NewRecorder()
+	handler.ServeHTTP(rr, r)
+	if rr.Code != http.StatusOK {
+		t.Fatalf("expected 200, got %d", rr.Code)
+	}
+	body := rr.Body.String()
+	for _, want := range []string{
+		"<title>Test Transit Status</title>",
+		"Tous les systèmes sont opérationnels",
+		`<span class="state up">Opérationnel</span>`,
+		"100.00% de disponibilité",
+		"il y a 30 jours",
+	} {
+		if !strings.Contains(body, want) {
+			t.Errorf("expected the page to contain %q", want)
+		}
+	}
+	if bars := strings.Count(body, `class="bar `); bars != 30 {
+		t.Errorf("expected 30 daily bars, got %d", bars)
+	}
+
+	rr = httptest.NewRecorder()
+	handler.ServeHTTP(rr, httptest.NewRequest(http.MethodGet, "/static/status_page.css", nil))
+	if rr.Code != http.StatusOK || !strings.Contains(rr.Header().Get("Content-Type"), "text/css") {
+		t.Errorf("expected the stylesheet to be served, got %d %q", rr.Code, rr.Header().Get("Content-Type"))
+	}
+}
+
+func TestStatusPageUptimeFromHistory(t *testing.T) {
+	app := newTestApplication(t)
+	store, err := history.Open(filepath.Join(t.TempDir(), "history.db"), 30*24*time.Hour)
+	if err != nil {
+		t.Fatalf("failed to open the history: %v", err)
+	}
+	defer store.Close()
+	app.History = store
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	cfg := app.ConfigService.Config
+	cfg.StatusPage = true
+	cfg.StatusPageChecks = []string{metrics.CheckServerPing}
+	cfg.StatusPageDays = 7
+
+	// Results recorded before a restart: the in-memory check results are empty.
+	now := time.Now().UTC()
+	for _, ok := range []bool{true, true, true, false} {
+		if err := store.Record(history.Result{At: now.AddDate(0, 0, -2), ServerID: 1, Check: metrics.CheckServerPing, OK: ok}); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	rr := httptest.NewRecorder()
+	app.Routes(ctx).ServeHTTP(rr, httptest.NewRequest(http.MethodGet, "/status?lang=en", nil))
+	if rr.Code != http.StatusOK {
+		t.Fatalf("expected 200, got %d", rr.Code)
+	}
+	if body := rr.Body.String(); !strings.Contains(body, "75.00%") {
+		t.Errorf("expected the uptime to be read from the history database, got %s", body)
+	}
+}
+
+func TestDayAvailability(t *testing.T) {
+	if got := dayAvailability([]metrics.DailyCount{{}, {}}); got != -1 {
+		t.Errorf("expected -1 without runs, got %v", got)
+	}
+	// The least available check determines the availability of the day.
+	if got := dayAvailability([]metrics.DailyCount{{Runs: 10}, {Runs: 4, Failures: 1}, {}}); got != 0.75 {
+		t.Errorf("expected 0.75, got %v", got)
+	}
+}
@@ -12,6 +12,7 @@ import (
 	"watchdog.onebusaway.org/internal/config"
 	"watchdog.onebusaway.org/internal/geo"
 	"watchdog.onebusaway.org/internal/gtfs"
+	"watchdog.onebusaway.org/internal/httpclient"
 	"watchdog.onebusaway.org/internal/metrics"
 	"watchdog.onebusaway.org/internal/models"
 )
@@ -87,12 +88,15 @@ func newTestApplication(t *testing.T) *Application {
 	realtimeStore.Set(realtimeData)
 
 	vehicleLastSeen := metrics.NewVehicleLastSeen()
-	backoffStore := config.NewBackoffStore()
+	backoffStore := config.NewBackoffStore(0, 0)
 	return &Application{
 		ConfigService:  config.NewConfigService(logger, client, cfg, backoffStore),
-		GtfsService:    gtfs.NewGtfsService(staticStore, realtimeStore, boundingBoxStore, logger, client),
-		MetricsService: metrics.NewMetricsService(staticStore, realtimeStore, boundingBoxStore, vehicleLastSeen, logger, client),
+		GtfsService:    gtfs.NewGtfsService(staticStore, realtimeStore, boundingBoxStore, logger, client, client, gtfs.GtfsServiceOptions{}),
+		MetricsService: metrics.NewMetricsService(staticStore, realtimeStore, boundingBoxStore, vehicleLastSeen, metrics.NewCheckResultStore(), metrics.NewPredictionTracker(), logger, client),
+		Maintenance:    config.NewMaintenanceStore(),
+		HTTPTimeouts:   httpclient.DefaultTimeouts,
 		Version:        "1.0.0",
+		AuditLogger:    logger,
 		Logger:         logger,
 	}
 }
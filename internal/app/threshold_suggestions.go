@@ -0,0 +1,42 @@
+package app
+
+import (
+	"net/http"
+	"time"
+)
+
+// thresholdSuggestion is an entry of the response of GET /v1/thresholds/suggestions.
+type thresholdSuggestion struct {
+	ServerID int    `json:"server_id"`
+	Check    string `json:"check"`
+	// Series is the series of an alert rule, absent for built-in checks.
+	Series             string    `json:"series,omitempty"`
+	CurrentThreshold   float64   `json:"current_threshold"`
+	SuggestedThreshold float64   `json:"suggested_threshold"`
+	Percentile         float64   `json:"percentile"`
+	Samples            int       `json:"samples"`
+	Since              time.Time `json:"since"`
+	Reason             string    `json:"reason"`
+}
+
+// thresholdSuggestionsHandler lists the alert thresholds that no longer fit the recorded values
+// of their checks (see alert.Manager.SuggestThresholds). Suggestions are only a report: they are
+// applied by editing the `alerts` config of the servers or the alert rules.
+func (app *Application) thresholdSuggestionsHandler(w http.ResponseWriter, r *http.Request) {
+	suggestions := app.Alerts.SuggestThresholds()
+	body := make([]thresholdSuggestion, 0, len(suggestions))
+	for _, s := range suggestions {
+		body = append(body, thresholdSuggestion{
+			ServerID:           s.ServerID,
+			Check:              s.Check,
+			Series:             s.Series,
+			CurrentThreshold:   s.Current,
+			SuggestedThreshold: s.Suggested,
+			Percentile:         s.Percentile,
+			Samples:            s.Samples,
+			Since:              s.Since.UTC(),
+			Reason:             s.Reason,
+		})
+	}
+	app.writeJSON(w, http.StatusOK, map[string][]thresholdSuggestion{"suggestions": body})
+}
@@ -0,0 +1,107 @@
+// The dashboard (/ui) renders the servers from the status API, and refreshes them periodically.
+"use strict";
+
+const REFRESH_INTERVAL_MS = 30000;
+
+const messages = JSON.parse(document.getElementById("messages").textContent);
+
+// t returns a message, with its %s and %d verbs replaced by args in order.
+function t(key, ...args) {
+  let i = 0;
+  return (messages[key] || key).replace(/%[sd]/g, () => String(args[i++]));
+}
+
+function element(tag, className, text) {
+  const el = document.createElement(tag);
+  if (className) {
+    el.className = className;
+  }
+  if (text !== undefined) {
+    el.textContent = text;
+  }
+  return el;
+}
+
+async function getJSON(url) {
+  const response = await fetch(url, { credentials: "same-origin", headers: { Accept: "application/json" } });
+  if (!response.ok) {
+    throw new Error(response.status + " " + response.statusText);
+  }
+  return response.json();
+}
+
+// age formats how long ago a time was, e.g. "5 min ago".
+function age(time, now) {
+  if (!time) {
+    return t("dashboard.never");
+  }
+  const seconds = Math.max(0, Math.round((now - new Date(time)) / 1000));
+  if (seconds < 60) {
+    return t("dashboard.age_seconds", seconds);
+  }
+  if (seconds < 3600) {
+    return t("dashboard.age_minutes", Math.floor(seconds / 60));
+  }
+  return t("dashboard.age_hours", Math.floor(seconds / 3600));
+}
+
+// expiration formats the end date (YYYY-MM-DD) of the first service of a bundle to end.
+function expiration(date, now) {
+  if (!date) {
+    return element("span", "muted", t("dashboard.none"));
+  }
+  const days = Math.floor((Date.parse(date + "T00:00:00Z") - now) / 86400000) + 1;
+  if (days < 0) {
+    return element("span", "expired", t("dashboard.expired", date));
+  }
+  return element("span", "", t("dashboard.expires_in", date, days));
+}
+
+// errors lists the failing checks of a server with their error.
+function errors(checks) {
+  const failing = Object.keys(checks || {}).filter((name) => !checks[name].ok).sort();
+  if (failing.length === 0) {
+    return element("span", "muted", t("dashboard.none"));
+  }
+  const list = element("ul", "errors");
+  for (const name of failing) {
+    const item = element("li");
+    item.append(element("code", "", name), " " + (checks[name].error || ""));
+    item.title = new Date(checks[name].at).toLocaleString(document.documentElement.lang);
+    list.append(item);
+  }
+  return list;
+}
+
+function row(summary, status, now) {
+  const tr = element("tr");
+  tr.append(
+    element("td", "", summary.name),
+    element("td", "health " + summary.health, t("health." + summary.health)),
+    element("td", "", age(status.gtfs_realtime.last_fetch_at, now)),
+  );
+  const bundle = element("td");
+  bundle.append(expiration(status.gtfs_bundle.earliest_service_end, now));
+  const failures = element("td");
+  failures.append(errors(status.checks));
+  tr.append(bundle, failures);
+  return tr;
+}
+
+async function refresh() {
+  const error = document.getElementById("error");
+  try {
+    const { servers } = await getJSON("/v1/servers");
+    const statuses = await Promise.all(servers.map((server) => getJSON("/v1/servers/" + server.id + "/status")));
+    const now = Date.now();
+    document.getElementById("servers").replaceChildren(...servers.map((server, i) => row(server, statuses[i], now)));
+    document.getElementById("updated").textContent = t("dashboard.updated", new Date(now).toLocaleString(document.documentElement.lang));
+    error.hidden = true;
+  } catch (err) {
+    error.textContent = t("dashboard.load_error", err.message);
+    error.hidden = false;
+  }
+}
+
+refresh();
+setInterval(refresh, REFRESH_INTERVAL_MS);
@@ -0,0 +1,117 @@
+// Package auth authenticates callers of the admin API and assigns them a role.
+//
+// Roles are ordered: an operator can do everything a viewer can, and an admin everything an
+// operator can. Endpoints declare the minimum role they require (see middleware.RequireRole).
+package auth
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"strings"
+)
+
+// Role is the level of access of a caller.
+type Role int
+
+const (
+	// RoleNone is the zero Role; it grants nothing.
+	RoleNone Role = iota
+	// RoleViewer can read the state of the watchdog.
+	RoleViewer
+	// RoleOperator can also trigger actions on monitored servers, such as refreshing a bundle.
+	RoleOperator
+	// RoleAdmin can also change the watchdog's configuration.
+	RoleAdmin
+)
+
+var roleNames = map[Role]string{
+	RoleViewer:   "viewer",
+	RoleOperator: "operator",
+	RoleAdmin:    "admin",
+}
+
+func (r Role) String() string {
+	if name, ok := roleNames[r]; ok {
+		return name
+	}
+	return "none"
+}
+
+// Allows reports whether the role grants at least the required role.
+func (r Role) Allows(required Role) bool {
+	return r != RoleNone && r >= required
+}
+
+// ParseRole parses a role name ("viewer", "operator" or "admin").
+func ParseRole(name string) (Role, error) {
+	for role, roleName := range roleNames {
+		if strings.EqualFold(name, roleName) {
+			return role, nil
+		}
+	}
+	return RoleNone, fmt.Errorf("unknown role %q (expected viewer, operator or admin)", name)
+}
+
+// Principal is an authenticated caller.
+type Principal struct {
+	// Name identifies the caller in the audit log, e.g. the name of a token.
+	Name string
+	Role Role
+	// Method is how the caller was authenticated, e.g. "token".
+	Method string
+}
+
+// ErrUnauthenticated is returned when a request carries credentials that are not valid.
+var ErrUnauthenticated = errors.New("invalid credentials")
+
+// Authenticator authenticates HTTP requests.
+type Authenticator interface {
+	// Authenticate returns the caller of the request. It returns (nil, nil) if the request carries
+	// no credentials this authenticator handles, and ErrUnauthenticated (or another error) if it
+	// carries invalid ones.
+	Authenticate(r *http.Request) (*Principal, error)
+}
+
+// Authenticators tries each authenticator in order and returns the first caller found.
+// Credentials rejected by one authenticator may be accepted by another (e.g. a bearer token
+// that is not a static token but a valid OIDC token), so an error is only returned if no
+// authenticator accepts the request.
+type Authenticators []Authenticator
+
+func (as Authenticators) Authenticate(r *http.Request) (*Principal, error) {
+	var firstErr error
+	for _, a := range as {
+		principal, err := a.Authenticate(r)
+		if principal != nil {
+			return principal, nil
+		}
+		if err != nil && firstErr == nil {
+			firstErr = err
+		}
+	}
+	return nil, firstErr
+}
+
+// BearerToken returns the token of an `Authorization: Bearer <token>` header, or "".
+func BearerToken(r *http.Request) string {
+	scheme, token, ok := strings.Cut(r.Header.Get("Authorization"), " ")
+	if !ok || !strings.EqualFold(scheme, "Bearer") {
+		return ""
+	}
+	return strings.TrimSpace(token)
+}
+
+type principalKey struct{}
+
+// WithPrincipal returns a copy of ctx carrying the authenticated caller.
+func WithPrincipal(ctx context.Context, principal *Principal) context.Context {
+	return context.WithValue(ctx, principalKey{}, principal)
+}
+
+// PrincipalFromContext returns the authenticated caller stored by WithPrincipal, or nil.
+func PrincipalFromContext(ctx context.Context) *Principal {
+	principal, _ := ctx.Value(principalKey{}).(*Principal)
+	return principal
+}
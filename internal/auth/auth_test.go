@@ -0,0 +1,148 @@
+package auth
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func tokenHash(token string) string {
+	sum := sha256.Sum256([]byte(token))
+	return hex.EncodeToString(sum[:])
+}
+
+func requestWithToken(token string) *http.Request {
+	r := httptest.NewRequest(http.MethodGet, "/v1/admin/whoami", nil)
+	if token != "" {
+		r.Header.Set("Authorization", "Bearer "+token)
+	}
+	return r
+}
+
+func TestRoleAllows(t *testing.T) {
+	if !RoleAdmin.Allows(RoleOperator) || !RoleOperator.Allows(RoleViewer) || !RoleViewer.Allows(RoleViewer) {
+		t.Error("expected higher roles to include lower ones")
+	}
+	if RoleViewer.Allows(RoleOperator) || RoleOperator.Allows(RoleAdmin) || RoleNone.Allows(RoleNone) {
+		t.Error("expected lower roles not to include higher ones")
+	}
+
+	role, err := ParseRole("Operator")
+	if err != nil || role != RoleOperator {
+		t.Errorf("ParseRole(Operator) = %v, %v", role, err)
+	}
+	if _, err := ParseRole("root"); err == nil {
+		t.Error("expected an error for an unknown role")
+	}
+}
+
+func TestLoadTokenFile(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "tokens.json")
+	data := `[
+		{"name": "dashboard", "role": "viewer", "token_sha256": "` + tokenHash("view-token") + `"},
+		{"name": "ops-bot", "role": "operator", "token_sha256": "` + tokenHash("ops-token") + `"}
+	]`
+	if err := os.WriteFile(path, []byte(data), 0o600); err != nil {
+		t.Fatal(err)
+	}
+
+	tokens, err := LoadTokenFile(path)
+	if err != nil {
+		t.Fatalf("LoadTokenFile failed: %v", err)
+	}
+
+	principal, err := tokens.Authenticate(requestWithToken("ops-token"))
+	if err != nil || principal == nil || principal.Name != "ops-bot" || principal.Role != RoleOperator || principal.Method != "token" {
+		t.Errorf("unexpected principal %+v, err %v", principal, err)
+	}
+	if principal, err := tokens.Authenticate(requestWithToken("")); principal != nil || err != nil {
+		t.Errorf("expected no principal and no error without credentials, got %+v, %v", principal, err)
+	}
+	if _, err := tokens.Authenticate(requestWithToken("wrong")); !errors.Is(err, ErrUnauthenticated) {
+		t.Errorf("expected ErrUnauthenticated for an unknown token, got %v", err)
+	}
+}
+
+func TestTokenFileValidation(t *testing.T) {
+	tests := map[string][]tokenEntry{
+		"missing name": {{Role: "viewer", TokenSHA256: tokenHash("a")}},
+		"unknown role": {{Name: "a", Role: "root", TokenSHA256: tokenHash("a")}},
+		"invalid hash": {{Name: "a", Role: "viewer", TokenSHA256: "not-a-hash"}},
+		"duplicate token": {
+			{Name: "a", Role: "viewer", TokenSHA256: tokenHash("a")},
+			{Name: "b", Role: "admin", TokenSHA256: tokenHash("a")},
+		},
+	}
+	for name, entries := range tests {
+		if _, err := newTokenAuthenticator(entries); err == nil {
+			t.Errorf("%s: expected an error", name)
+		}
+	}
+}
+
+type staticAuthenticator struct {
+	principal *Principal
+	err       error
+}
+
+func (a staticAuthenticator) Authenticate(*http.Request) (*Principal, error) {
+	return a.principal, a.err
+}
+
+func TestAuthenticators(t *testing.T) {
+	admin := &Principal{Name: "admin", Role: RoleAdmin}
+	chain := Authenticators{staticAuthenticator{err: ErrUnauthenticated}, staticAuthenticator{principal: admin}}
+	if principal, err := chain.Authenticate(requestWithToken("x")); principal != admin || err != nil {
+		t.Errorf("expected a later authenticator to accept the request, got %+v, %v", principal, err)
+	}
+
+	chain = Authenticators{staticAuthenticator{}, staticAuthenticator{err: ErrUnauthenticated}}
+	if principal, err := chain.Authenticate(requestWithToken("x")); principal != nil || !errors.Is(err, ErrUnauthenticated) {
+		t.Errorf("expected ErrUnauthenticated, got %+v, %v", principal, err)
+	}
+}
+
+func TestStaticAuthenticator(t *testing.T) {
+	if _, err := NewStaticAuthenticator("", "", ""); err == nil {
+		t.Error("expected an error without credentials")
+	}
+	if _, err := NewStaticAuthenticator("", "ops", ""); err == nil {
+		t.Error("expected an error for a user without a password")
+	}
+
+	a, err := NewStaticAuthenticator("scrape-token", "", "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if principal, err := a.Authenticate(requestWithToken("scrape-token")); err != nil || principal == nil || principal.Role != RoleViewer {
+		t.Errorf("expected the token to authenticate a viewer, got %v, %v", principal, err)
+	}
+	if _, err := a.Authenticate(requestWithToken("other")); !errors.Is(err, ErrUnauthenticated) {
+		t.Errorf("expected ErrUnauthenticated for another token, got %v", err)
+	}
+	basic := requestWithToken("")
+	basic.SetBasicAuth("ops", "s3cret")
+	if principal, err := a.Authenticate(basic); principal != nil || err != nil {
+		t.Errorf("expected basic auth to be ignored when disabled, got %v, %v", principal, err)
+	}
+	if a.Challenge() != `Bearer realm="watchdog"` {
+		t.Errorf("unexpected challenge %q", a.Challenge())
+	}
+
+	a, err = NewStaticAuthenticator("", "ops", "s3cret")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if principal, err := a.Authenticate(basic); err != nil || principal == nil || principal.Name != "ops" || principal.Method != "basic" {
+		t.Errorf("expected basic auth to authenticate ops, got %v, %v", principal, err)
+	}
+	basic.SetBasicAuth("admin", "s3cret")
+	if _, err := a.Authenticate(basic); !errors.Is(err, ErrUnauthenticated) {
+		t.Errorf("expected ErrUnauthenticated for another user, got %v", err)
+	}
+}
@@ -0,0 +1,298 @@
+package auth
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+
+	"github.com/coreos/go-oidc/v3/oidc"
+	"golang.org/x/oauth2"
+)
+
+const (
+	// SessionCookieName is the cookie holding the session of a user logged in with OIDC.
+	SessionCookieName = "watchdog_session"
+	// stateCookieName holds the state of a login in progress, between the redirect to the
+	// identity provider and the callback.
+	stateCookieName = "watchdog_oidc_state"
+	// loginTimeout is how long a user has to complete a login at the identity provider.
+	loginTimeout = 10 * time.Minute
+)
+
+// OIDCConfig configures login with an OpenID Connect identity provider.
+type OIDCConfig struct {
+	// IssuerURL is the provider's issuer; its configuration is discovered from
+	// <IssuerURL>/.well-known/openid-configuration.
+	IssuerURL    string
+	ClientID     string
+	ClientSecret string
+	// RedirectURL is the public URL of the callback endpoint, e.g. https://watchdog.example.org/auth/callback.
+	// It must be registered with the provider.
+	RedirectURL string
+	// RolesClaim is the ID token claim (a string or a list of strings) mapped to roles, e.g. "groups".
+	RolesClaim string
+	// RoleMapping maps values of RolesClaim to roles. A user gets the highest role mapped from their claim values.
+	RoleMapping map[string]Role
+	// DefaultRole is the role of users none of whose claim values are mapped.
+	// With RoleNone, such users cannot log in.
+	DefaultRole Role
+	// SessionTTL is how long a login lasts.
+	SessionTTL time.Duration
+	// SessionSecret signs session cookies. Sessions stay valid across restarts only if it is kept.
+	SessionSecret []byte
+}
+
+// OIDC logs users in with the OpenID Connect authorization code flow (with PKCE), and keeps
+// them logged in with a signed session cookie. It is also an Authenticator: it accepts the
+// session cookie, and bearer ID tokens issued by the provider to this client (for scripts).
+type OIDC struct {
+	config   OIDCConfig
+	oauth2   oauth2.Config
+	verifier *oidc.IDTokenVerifier
+	signer   *cookieSigner
+	client   *http.Client
+	secure   bool
+}
+
+// session is the content of the session cookie.
+type session struct {
+	Name string `json:"name"`
+	Role Role   `json:"role"`
+}
+
+// loginState is the content of the state cookie of a login in progress.
+type loginState struct {
+	State    string `json:"state"`
+	Nonce    string `json:"nonce"`
+	Verifier string `json:"verifier"`
+	Next     string `json:"next"`
+}
+
+// NewOIDC discovers the configuration of the identity provider. client is used for every
+// request to the provider.
+func NewOIDC(ctx context.Context, config OIDCConfig, client *http.Client) (*OIDC, error) {
+	if config.IssuerURL == "" || config.ClientID == "" || config.RedirectURL == "" {
+		return nil, errors.New("OIDC requires an issuer URL, a client ID and a redirect URL")
+	}
+	if len(config.SessionSecret) == 0 {
+		return nil, errors.New("OIDC requires a session secret")
+	}
+	redirect, err := url.Parse(config.RedirectURL)
+	if err != nil {
+		return nil, fmt.Errorf("invalid OIDC redirect URL %q: %w", config.RedirectURL, err)
+	}
+
+	provider, err := oidc.NewProvider(oidc.ClientContext(ctx, client), config.IssuerURL)
+	if err != nil {
+		return nil, fmt.Errorf("failed to discover OIDC provider %s: %w", config.IssuerURL, err)
+	}
+	return &OIDC{
+		config: config,
+		oauth2: oauth2.Config{
+			ClientID:     config.ClientID,
+			ClientSecret: config.ClientSecret,
+			Endpoint:     provider.Endpoint(),
+			RedirectURL:  config.RedirectURL,
+			Scopes:       []string{oidc.ScopeOpenID, "profile", "email"},
+		},
+		// The key set is fetched lazily, with the client of the background context.
+		verifier: provider.VerifierContext(oidc.ClientContext(context.Background(), client), &oidc.Config{ClientID: config.ClientID}),
+		signer:   &cookieSigner{key: config.SessionSecret, now: time.Now},
+		client:   client,
+		secure:   redirect.Scheme == "https",
+	}, nil
+}
+
+// ParseRoleMapping parses a comma-separated list of claim values and roles,
+// e.g. "watchdog-admins=admin,transit-ops=operator".
+func ParseRoleMapping(s string) (map[string]Role, error) {
+	mapping := make(map[string]Role)
+	for _, entry := range strings.Split(s, ",") {
+		entry = strings.TrimSpace(entry)
+		if entry == "" {
+			continue
+		}
+		value, roleName, ok := strings.Cut(entry, "=")
+		if !ok || strings.TrimSpace(value) == "" {
+			return nil, fmt.Errorf("invalid role mapping %q (expected <claim value>=<role>)", entry)
+		}
+		role, err := ParseRole(strings.TrimSpace(roleName))
+		if err != nil {
+			return nil, err
+		}
+		mapping[strings.TrimSpace(value)] = role
+	}
+	return mapping, nil
+}
+
+// LoginURL returns the path that starts a login and then returns the user to next.
+func LoginURL(next string) string {
+	return "/auth/login?" + url.Values{"next": {next}}.Encode()
+}
+
+// safeNext returns next if it is a path on this site, so that logins cannot redirect elsewhere.
+func safeNext(next string) string {
+	if !strings.HasPrefix(next, "/") || strings.HasPrefix(next, "//") || strings.HasPrefix(next, "/\\") {
+		return "/"
+	}
+	return next
+}
+
+func randomString() (string, error) {
+	b := make([]byte, 32)
+	if _, err := rand.Read(b); err != nil {
+		return "", err
+	}
+	return base64.RawURLEncoding.EncodeToString(b), nil
+}
+
+// LoginHandler redirects to the identity provider. The page to return to after login
+// is given by the `next` query parameter.
+func (o *OIDC) LoginHandler(w http.ResponseWriter, r *http.Request) {
+	state := loginState{Next: safeNext(r.URL.Query().Get("next")), Verifier: oauth2.GenerateVerifier()}
+	var err error
+	if state.State, err = randomString(); err == nil {
+		state.Nonce, err = randomString()
+	}
+	if err != nil {
+		http.Error(w, "failed to start login", http.StatusInternalServerError)
+		return
+	}
+	cookie, err := o.signer.encode(state, loginTimeout)
+	if err != nil {
+		http.Error(w, "failed to start login", http.StatusInternalServerError)
+		return
+	}
+	http.SetCookie(w, newCookie(stateCookieName, cookie, "/auth/", loginTimeout, o.secure))
+	http.Redirect(w, r, o.oauth2.AuthCodeURL(state.State, oidc.Nonce(state.Nonce), oauth2.S256ChallengeOption(state.Verifier)), http.StatusFound)
+}
+
+// CallbackHandler completes a login: it exchanges the authorization code for an ID token,
+// maps the user's claims to a role, sets the session cookie and returns the user to the page
+// they started from.
+func (o *OIDC) CallbackHandler(w http.ResponseWriter, r *http.Request) {
+	var state loginState
+	cookie, err := r.Cookie(stateCookieName)
+	if err != nil || o.signer.decode(cookie.Value, &state) != nil || r.URL.Query().Get("state") != state.State {
+		http.Error(w, "invalid or expired login, please try again", http.StatusBadRequest)
+		return
+	}
+	http.SetCookie(w, newCookie(stateCookieName, "", "/auth/", -1, o.secure))
+	if errCode := r.URL.Query().Get("error"); errCode != "" {
+		http.Error(w, "login failed: "+errCode, http.StatusUnauthorized)
+		return
+	}
+
+	ctx := oidc.ClientContext(r.Context(), o.client)
+	token, err := o.oauth2.Exchange(ctx, r.URL.Query().Get("code"), oauth2.VerifierOption(state.Verifier))
+	if err != nil {
+		http.Error(w, "login failed: could not exchange the authorization code", http.StatusUnauthorized)
+		return
+	}
+	rawIDToken, _ := token.Extra("id_token").(string)
+	principal, err := o.verify(ctx, rawIDToken, state.Nonce)
+	if err != nil {
+		http.Error(w, "login failed: "+err.Error(), http.StatusUnauthorized)
+		return
+	}
+	if principal.Role == RoleNone {
+		http.Error(w, "your account has no access to the watchdog", http.StatusForbidden)
+		return
+	}
+
+	value, err := o.signer.encode(session{Name: principal.Name, Role: principal.Role}, o.config.SessionTTL)
+	if err != nil {
+		http.Error(w, "failed to create session", http.StatusInternalServerError)
+		return
+	}
+	http.SetCookie(w, newCookie(SessionCookieName, value, "/", o.config.SessionTTL, o.secure))
+	http.Redirect(w, r, state.Next, http.StatusFound)
+}
+
+// LogoutHandler ends the session and returns to the home page. Sessions at the identity
+// provider are not ended.
+func (o *OIDC) LogoutHandler(w http.ResponseWriter, r *http.Request) {
+	http.SetCookie(w, newCookie(SessionCookieName, "", "/", -1, o.secure))
+	http.Redirect(w, r, "/", http.StatusFound)
+}
+
+// Authenticate accepts the session cookie, or an ID token as a bearer token.
+func (o *OIDC) Authenticate(r *http.Request) (*Principal, error) {
+	if token := BearerToken(r); token != "" {
+		principal, err := o.verify(r.Context(), token, "")
+		if err != nil || principal.Role == RoleNone {
+			return nil, ErrUnauthenticated
+		}
+		return principal, nil
+	}
+
+	cookie, err := r.Cookie(SessionCookieName)
+	if err != nil {
+		return nil, nil
+	}
+	var s session
+	if err := o.signer.decode(cookie.Value, &s); err != nil {
+		return nil, ErrUnauthenticated
+	}
+	return &Principal{Name: s.Name, Role: s.Role, Method: "oidc"}, nil
+}
+
+// verify checks an ID token and maps its claims to a principal. If nonce is set, the token
+// must carry it.
+func (o *OIDC) verify(ctx context.Context, rawIDToken, nonce string) (*Principal, error) {
+	if rawIDToken == "" {
+		return nil, errors.New("no ID token")
+	}
+	idToken, err := o.verifier.Verify(oidc.ClientContext(ctx, o.client), rawIDToken)
+	if err != nil {
+		return nil, fmt.Errorf("invalid ID token: %w", err)
+	}
+	if nonce != "" && idToken.Nonce != nonce {
+		return nil, errors.New("invalid ID token: nonce mismatch")
+	}
+
+	var claims map[string]any
+	if err := idToken.Claims(&claims); err != nil {
+		return nil, fmt.Errorf("invalid ID token claims: %w", err)
+	}
+	name := idToken.Subject
+	for _, claim := range []string{"email", "preferred_username"} {
+		if value, ok := claims[claim].(string); ok && value != "" {
+			name = value
+			break
+		}
+	}
+	return &Principal{Name: name, Role: o.role(claims[o.config.RolesClaim]), Method: "oidc"}, nil
+}
+
+// role returns the highest role mapped from the values of the roles claim, or the default role.
+func (o *OIDC) role(claim any) Role {
+	var values []string
+	switch v := claim.(type) {
+	case string:
+		values = []string{v}
+	case []any:
+		for _, value := range v {
+			if s, ok := value.(string); ok {
+				values = append(values, s)
+			}
+		}
+	}
+
+	role := RoleNone
+	for _, value := range values {
+		if mapped, ok := o.config.RoleMapping[value]; ok && mapped > role {
+			role = mapped
+		}
+	}
+	if role == RoleNone {
+		return o.config.DefaultRole
+	}
+	return role
+}
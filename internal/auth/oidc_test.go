@@ -0,0 +1,239 @@
+package auth
+
+import (
+	"context"
+	"crypto"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"math/big"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+	"time"
+)
+
+// fakeProvider is a minimal OpenID Connect provider: discovery, JWKS and a token endpoint
+// that issues an ID token for the code "good-code".
+type fakeProvider struct {
+	*httptest.Server
+	key *rsa.PrivateKey
+	// claims are added to the ID tokens issued by the token endpoint.
+	claims map[string]any
+	// nonce and challenge are those of the last authorization request, set by the test.
+	nonce, challenge string
+}
+
+func newFakeProvider(t *testing.T) *fakeProvider {
+	t.Helper()
+	key, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		t.Fatal(err)
+	}
+	p := &fakeProvider{key: key}
+	mux := http.NewServeMux()
+	mux.HandleFunc("/.well-known/openid-configuration", func(w http.ResponseWriter, r *http.Request) {
+		_ = json.NewEncoder(w).Encode(map[string]any{
+			"issuer":                                p.URL,
+			"authorization_endpoint":                p.URL + "/authorize",
+			"token_endpoint":                        p.URL + "/token",
+			"jwks_uri":                              p.URL + "/jwks",
+			"id_token_signing_alg_values_supported": []string{"RS256"},
+		})
+	})
+	mux.HandleFunc("/jwks", func(w http.ResponseWriter, r *http.Request) {
+		_ = json.NewEncoder(w).Encode(map[string]any{"keys": []map[string]string{{
+			"kty": "RSA", "kid": "test", "alg": "RS256", "use": "sig",
+			"n": base64.RawURLEncoding.EncodeToString(key.N.Bytes()),
+			"e": base64.RawURLEncoding.EncodeToString(big.NewInt(int64(key.E)).Bytes()),
+		}}})
+	})
+	mux.HandleFunc("/token", func(w http.ResponseWriter, r *http.Request) {
+		verifier := sha256.Sum256([]byte(r.FormValue("code_verifier")))
+		if r.FormValue("code") != "good-code" || base64.RawURLEncoding.EncodeToString(verifier[:]) != p.challenge {
+			w.WriteHeader(http.StatusBadRequest)
+			_ = json.NewEncoder(w).Encode(map[string]string{"error": "invalid_grant"})
+			return
+		}
+		w.Header().Set("Content-Type", "application/json")
+		_ = json.NewEncoder(w).Encode(map[string]any{
+			"access_token": "access",
+			"token_type":   "Bearer",
+			"expires_in":   3600,
+			"id_token":     p.idToken(t, p.nonce),
+		})
+	})
+	p.Server = httptest.NewServer(mux)
+	t.Cleanup(p.Close)
+	return p
+}
+
+// idToken returns an RS256-signed ID token for the test client.
+func (p *fakeProvider) idToken(t *testing.T, nonce string) string {
+	t.Helper()
+	claims := map[string]any{
+		"iss": p.URL,
+		"sub": "user-1",
+		"aud": "watchdog",
+		"exp": time.Now().Add(time.Hour).Unix(),
+		"iat": time.Now().Unix(),
+	}
+	if nonce != "" {
+		claims["nonce"] = nonce
+	}
+	for k, v := range p.claims {
+		claims[k] = v
+	}
+	header, _ := json.Marshal(map[string]string{"alg": "RS256", "kid": "test", "typ": "JWT"})
+	payload, _ := json.Marshal(claims)
+	signingInput := base64.RawURLEncoding.EncodeToString(header) + "." + base64.RawURLEncoding.EncodeToString(payload)
+	digest := sha256.Sum256([]byte(signingInput))
+	signature, err := rsa.SignPKCS1v15(rand.Reader, p.key, crypto.SHA256, digest[:])
+	if err != nil {
+		t.Fatal(err)
+	}
+	return signingInput + "." + base64.RawURLEncoding.EncodeToString(signature)
+}
+
+func newTestOIDC(t *testing.T, p *fakeProvider) *OIDC {
+	t.Helper()
+	o, err := NewOIDC(context.Background(), OIDCConfig{
+		IssuerURL:     p.URL,
+		ClientID:      "watchdog",
+		RedirectURL:   "https://watchdog.example.org/auth/callback",
+		RolesClaim:    "groups",
+		RoleMapping:   map[string]Role{"ops": RoleOperator, "admins": RoleAdmin},
+		DefaultRole:   RoleViewer,
+		SessionTTL:    time.Hour,
+		SessionSecret: []byte("test-secret"),
+	}, p.Client())
+	if err != nil {
+		t.Fatalf("NewOIDC failed: %v", err)
+	}
+	return o
+}
+
+// login runs the authorization code flow and returns the callback response.
+func login(t *testing.T, o *OIDC, p *fakeProvider, next, code string) *http.Response {
+	t.Helper()
+	rr := httptest.NewRecorder()
+	o.LoginHandler(rr, httptest.NewRequest(http.MethodGet, LoginURL(next), nil))
+	if rr.Code != http.StatusFound {
+		t.Fatalf("expected a redirect to the provider, got %d", rr.Code)
+	}
+	authURL, err := url.Parse(rr.Header().Get("Location"))
+	if err != nil || !strings.HasPrefix(authURL.String(), p.URL+"/authorize") {
+		t.Fatalf("unexpected authorization URL %q", rr.Header().Get("Location"))
+	}
+	query := authURL.Query()
+	p.nonce, p.challenge = query.Get("nonce"), query.Get("code_challenge")
+
+	callback := httptest.NewRequest(http.MethodGet, "/auth/callback?"+url.Values{"code": {code}, "state": {query.Get("state")}}.Encode(), nil)
+	for _, cookie := range rr.Result().Cookies() {
+		callback.AddCookie(cookie)
+	}
+	rr = httptest.NewRecorder()
+	o.CallbackHandler(rr, callback)
+	return rr.Result()
+}
+
+func sessionCookie(resp *http.Response) *http.Cookie {
+	for _, cookie := range resp.Cookies() {
+		if cookie.Name == SessionCookieName && cookie.Value != "" {
+			return cookie
+		}
+	}
+	return nil
+}
+
+func TestOIDCLogin(t *testing.T) {
+	p := newFakeProvider(t)
+	p.claims = map[string]any{"email": "jane@agency.example", "groups": []string{"staff", "ops"}}
+	o := newTestOIDC(t, p)
+
+	resp := login(t, o, p, "/ui/servers/1", "good-code")
+	if resp.StatusCode != http.StatusFound || resp.Header.Get("Location") != "/ui/servers/1" {
+		t.Fatalf("expected a redirect to the original page, got %d %q", resp.StatusCode, resp.Header.Get("Location"))
+	}
+	cookie := sessionCookie(resp)
+	if cookie == nil || !cookie.HttpOnly || !cookie.Secure {
+		t.Fatalf("expected a secure, HttpOnly session cookie, got %+v", cookie)
+	}
+
+	r := httptest.NewRequest(http.MethodGet, "/ui", nil)
+	r.AddCookie(cookie)
+	principal, err := o.Authenticate(r)
+	if err != nil || principal == nil || principal.Name != "jane@agency.example" || principal.Role != RoleOperator || principal.Method != "oidc" {
+		t.Errorf("unexpected principal %+v, %v", principal, err)
+	}
+
+	r = httptest.NewRequest(http.MethodGet, "/ui", nil)
+	r.AddCookie(&http.Cookie{Name: SessionCookieName, Value: strings.Replace(cookie.Value, ".", "x.", 1)})
+	if _, err := o.Authenticate(r); !errors.Is(err, ErrUnauthenticated) {
+		t.Errorf("expected a tampered session to be rejected, got %v", err)
+	}
+
+	if principal, err := o.Authenticate(httptest.NewRequest(http.MethodGet, "/ui", nil)); principal != nil || err != nil {
+		t.Errorf("expected no principal without credentials, got %+v, %v", principal, err)
+	}
+}
+
+func TestOIDCLoginFailures(t *testing.T) {
+	p := newFakeProvider(t)
+	o := newTestOIDC(t, p)
+
+	if resp := login(t, o, p, "/ui", "bad-code"); resp.StatusCode != http.StatusUnauthorized || sessionCookie(resp) != nil {
+		t.Errorf("expected a failed exchange to be rejected, got %d", resp.StatusCode)
+	}
+
+	// A callback without the state cookie (e.g. forged by another site) is rejected.
+	rr := httptest.NewRecorder()
+	o.CallbackHandler(rr, httptest.NewRequest(http.MethodGet, "/auth/callback?code=good-code&state=x", nil))
+	if rr.Code != http.StatusBadRequest {
+		t.Errorf("expected 400 without a login in progress, got %d", rr.Code)
+	}
+
+	// Users without a mapped role cannot log in when there is no default role.
+	o.config.DefaultRole = RoleNone
+	if resp := login(t, o, p, "/ui", "good-code"); resp.StatusCode != http.StatusForbidden {
+		t.Errorf("expected 403 for a user without a role, got %d", resp.StatusCode)
+	}
+
+	// Logins never redirect to another site.
+	o.config.DefaultRole = RoleViewer
+	if resp := login(t, o, p, "//evil.example.com", "good-code"); resp.Header.Get("Location") != "/" {
+		t.Errorf("expected an external next to be replaced, got %q", resp.Header.Get("Location"))
+	}
+}
+
+func TestOIDCBearerIDToken(t *testing.T) {
+	p := newFakeProvider(t)
+	p.claims = map[string]any{"preferred_username": "ci", "groups": "admins"}
+	o := newTestOIDC(t, p)
+
+	r := requestWithToken(p.idToken(t, ""))
+	principal, err := o.Authenticate(r)
+	if err != nil || principal == nil || principal.Name != "ci" || principal.Role != RoleAdmin {
+		t.Errorf("unexpected principal %+v, %v", principal, err)
+	}
+	if _, err := o.Authenticate(requestWithToken("not-a-jwt")); !errors.Is(err, ErrUnauthenticated) {
+		t.Errorf("expected an invalid bearer token to be rejected, got %v", err)
+	}
+}
+
+func TestParseRoleMapping(t *testing.T) {
+	mapping, err := ParseRoleMapping("watchdog-admins=admin, transit-ops=operator,")
+	if err != nil || len(mapping) != 2 || mapping["watchdog-admins"] != RoleAdmin || mapping["transit-ops"] != RoleOperator {
+		t.Errorf("unexpected mapping %v, %v", mapping, err)
+	}
+	for _, invalid := range []string{"admins", "=admin", "admins=root"} {
+		if _, err := ParseRoleMapping(invalid); err == nil {
+			t.Errorf("expected an error for %q", invalid)
+		}
+	}
+}
@@ -0,0 +1,82 @@
+package auth
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"strings"
+	"time"
+)
+
+// errInvalidCookie is returned when a signed cookie is malformed, forged or expired.
+var errInvalidCookie = errors.New("invalid or expired cookie")
+
+// cookieSigner encodes values into cookies that cannot be forged nor altered by the browser:
+// the value is JSON, base64url-encoded, followed by its HMAC-SHA256 with a server-side key.
+// Values are not encrypted, so they must not hold secrets the browser should not see.
+type cookieSigner struct {
+	key []byte
+	now func() time.Time
+}
+
+// signedValue wraps an encoded value with its expiry.
+type signedValue struct {
+	Expires int64           `json:"exp"`
+	Value   json.RawMessage `json:"v"`
+}
+
+func (s *cookieSigner) mac(payload string) string {
+	mac := hmac.New(sha256.New, s.key)
+	mac.Write([]byte(payload))
+	return base64.RawURLEncoding.EncodeToString(mac.Sum(nil))
+}
+
+// encode returns the cookie value of v, valid for ttl.
+func (s *cookieSigner) encode(v any, ttl time.Duration) (string, error) {
+	value, err := json.Marshal(v)
+	if err != nil {
+		return "", err
+	}
+	data, err := json.Marshal(signedValue{Expires: s.now().Add(ttl).Unix(), Value: value})
+	if err != nil {
+		return "", err
+	}
+	payload := base64.RawURLEncoding.EncodeToString(data)
+	return payload + "." + s.mac(payload), nil
+}
+
+// decode checks the signature and expiry of a cookie value and decodes it into v.
+func (s *cookieSigner) decode(cookie string, v any) error {
+	payload, mac, ok := strings.Cut(cookie, ".")
+	if !ok || !hmac.Equal([]byte(mac), []byte(s.mac(payload))) {
+		return errInvalidCookie
+	}
+	data, err := base64.RawURLEncoding.DecodeString(payload)
+	if err != nil {
+		return errInvalidCookie
+	}
+	var signed signedValue
+	if err := json.Unmarshal(data, &signed); err != nil || s.now().Unix() >= signed.Expires {
+		return errInvalidCookie
+	}
+	if err := json.Unmarshal(signed.Value, v); err != nil {
+		return errInvalidCookie
+	}
+	return nil
+}
+
+// newCookie returns an HttpOnly cookie; it is Secure when the site is served over HTTPS.
+func newCookie(name, value, path string, maxAge time.Duration, secure bool) *http.Cookie {
+	return &http.Cookie{
+		Name:     name,
+		Value:    value,
+		Path:     path,
+		MaxAge:   int(maxAge / time.Second),
+		HttpOnly: true,
+		Secure:   secure,
+		SameSite: http.SameSiteLaxMode,
+	}
+}
@@ -0,0 +1,69 @@
+package auth
+
+import (
+	"crypto/sha256"
+	"crypto/subtle"
+	"errors"
+	"fmt"
+	"net/http"
+)
+
+// StaticAuthenticator authenticates requests carrying shared credentials: a bearer token,
+// e.g. for Prometheus scrapes, and/or a basic auth user and password, e.g. for browsers.
+// Callers are viewers.
+type StaticAuthenticator struct {
+	// The SHA-256 of the secrets are compared, so comparisons take the same time whatever their
+	// length.
+	token    *[sha256.Size]byte
+	username [sha256.Size]byte
+	password *[sha256.Size]byte
+}
+
+// NewStaticAuthenticator creates a StaticAuthenticator accepting token (if not empty) and the
+// basic auth credentials username and password (if username is not empty).
+func NewStaticAuthenticator(token, username, password string) (*StaticAuthenticator, error) {
+	if token == "" && username == "" {
+		return nil, errors.New("no credentials: a token or a username and password are required")
+	}
+	if username != "" && password == "" {
+		return nil, fmt.Errorf("missing password for basic auth user %q", username)
+	}
+	a := &StaticAuthenticator{}
+	if token != "" {
+		sum := sha256.Sum256([]byte(token))
+		a.token = &sum
+	}
+	if username != "" {
+		sum := sha256.Sum256([]byte(password))
+		a.username, a.password = sha256.Sum256([]byte(username)), &sum
+	}
+	return a, nil
+}
+
+// Authenticate checks the bearer token or the basic auth credentials of the request.
+func (a *StaticAuthenticator) Authenticate(r *http.Request) (*Principal, error) {
+	if token := BearerToken(r); token != "" && a.token != nil {
+		sum := sha256.Sum256([]byte(token))
+		if subtle.ConstantTimeCompare(sum[:], a.token[:]) != 1 {
+			return nil, ErrUnauthenticated
+		}
+		return &Principal{Name: "api-token", Role: RoleViewer, Method: "bearer"}, nil
+	}
+	if username, password, ok := r.BasicAuth(); ok && a.password != nil {
+		user, pass := sha256.Sum256([]byte(username)), sha256.Sum256([]byte(password))
+		if subtle.ConstantTimeCompare(user[:], a.username[:])&subtle.ConstantTimeCompare(pass[:], a.password[:]) != 1 {
+			return nil, ErrUnauthenticated
+		}
+		return &Principal{Name: username, Role: RoleViewer, Method: "basic"}, nil
+	}
+	return nil, nil
+}
+
+// Challenge returns the WWW-Authenticate header of unauthenticated responses. Basic auth is
+// offered when enabled, so that browsers prompt for the credentials.
+func (a *StaticAuthenticator) Challenge() string {
+	if a.password != nil {
+		return `Basic realm="watchdog", charset="UTF-8"`
+	}
+	return `Bearer realm="watchdog"`
+}
@@ -2,11 +2,14 @@ package config
 
 import (
 	"context"
+	"errors"
 	"fmt"
 	"math/rand/v2"
 	"net/http"
 	"sync"
 	"time"
+
+	"watchdog.onebusaway.org/internal/sharedstore"
 )
 
 const (
@@ -21,6 +24,20 @@ const (
 	JITTER_FACTOR = 0.5
 )
 
+// CircuitState is the state of the circuit breaker of a server.
+type CircuitState string
+
+const (
+	// CircuitClosed is the normal state: checks run, with exponential backoff after failures.
+	CircuitClosed CircuitState = "closed"
+	// CircuitOpen means the server failed too many times in a row: checks are skipped until
+	// the cooldown ends.
+	CircuitOpen CircuitState = "open"
+	// CircuitHalfOpen means the cooldown ended: the next run probes the server, closing the
+	// circuit on success and opening it again on failure.
+	CircuitHalfOpen CircuitState = "half_open"
+)
+
 // backoffData holds the backoff delay and the timestamp of the next retry attempt
 // for a given server.
 type backoffData struct {
@@ -28,25 +45,79 @@ type backoffData struct {
 	BackoffDelay time.Duration
 	// NextRetryAt is the absolute timestamp when the next retry can be attempted.
 	NextRetryAt time.Time
+	// ConsecutiveFailures counts the failures since the last success.
+	ConsecutiveFailures int
+}
+
+// BackoffStore manages the backoff and circuit breaker state of multiple servers (see
+// MemoryBackoffStore). Implementations must be safe for concurrent use.
+type BackoffStore interface {
+	// CircuitState returns the state of the circuit of a server at now, and its number of
+	// consecutive failures.
+	CircuitState(serverID int, now time.Time) (CircuitState, int)
+	// NextRetryAt returns when a server can be retried, and false if it has no backoff.
+	NextRetryAt(serverID int) (time.Time, bool)
+	// BackoffDelay returns the current backoff delay of a server, and false if it has no backoff.
+	BackoffDelay(serverID int) (time.Duration, bool)
+	// UpdateBackoff records a failure of a server, and reports whether it opened its circuit.
+	UpdateBackoff(serverID int) bool
+	// ResetBackoff records a success of a server, ending its backoff and closing its circuit.
+	ResetBackoff(serverID int)
 }
 
-// BackoffStore manages backoff state for multiple servers.
+// MemoryBackoffStore is an in-memory BackoffStore, managing backoff state for multiple servers.
+//
+// It acts as a circuit breaker: after failureThreshold consecutive failures of a server, its
+// circuit opens and the server is left alone for cooldown, instead of being retried every
+// MAX_BACKOFF. The first retry after the cooldown is a probe: another failure opens the
+// circuit again for a full cooldown, a success closes it (see ResetBackoff).
+//
 // It is safe for concurrent use across goroutines.
-type BackoffStore struct {
+type MemoryBackoffStore struct {
 	mu       sync.RWMutex
 	backoffs map[int]backoffData
+	// failureThreshold is the number of consecutive failures that opens the circuit (0 = never).
+	failureThreshold int
+	cooldown         time.Duration
+	// replica shares the backoffs with the other instances (see Share); nil if they are not
+	// shared.
+	replica *sharedstore.Replica[backoffData]
 }
 
-// NewBackoffStore creates and returns a new BackoffStore instance.
-func NewBackoffStore() *BackoffStore {
-	return &BackoffStore{
-		backoffs: make(map[int]backoffData),
+// NewBackoffStore creates and returns a new MemoryBackoffStore instance whose circuits open after
+// failureThreshold consecutive failures (0 disables the circuit breaker) for cooldown.
+func NewBackoffStore(failureThreshold int, cooldown time.Duration) *MemoryBackoffStore {
+	return &MemoryBackoffStore{
+		backoffs:         make(map[int]backoffData),
+		failureThreshold: failureThreshold,
+		cooldown:         cooldown,
+	}
+}
+
+// circuitOpened reports whether a server failed enough times in a row to open its circuit.
+func (s *MemoryBackoffStore) circuitOpened(backoff backoffData) bool {
+	return s.failureThreshold > 0 && backoff.ConsecutiveFailures >= s.failureThreshold
+}
+
+// CircuitState returns the state of the circuit of the given server ID at now, and its number
+// of consecutive failures.
+func (s *MemoryBackoffStore) CircuitState(serverID int, now time.Time) (CircuitState, int) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	backoff, exists := s.backoffs[serverID]
+	switch {
+	case !exists || !s.circuitOpened(backoff):
+		return CircuitClosed, backoff.ConsecutiveFailures
+	case now.Before(backoff.NextRetryAt):
+		return CircuitOpen, backoff.ConsecutiveFailures
+	default:
+		return CircuitHalfOpen, backoff.ConsecutiveFailures
 	}
 }
 
 // NextRetryAt retrieves the next retry time for the given server ID.
 // It returns the timestamp in UTC and a boolean indicating whether the server has an active backoff.
-func (s *BackoffStore) NextRetryAt(serverID int) (time.Time, bool) {
+func (s *MemoryBackoffStore) NextRetryAt(serverID int) (time.Time, bool) {
 	s.mu.RLock()
 	defer s.mu.RUnlock()
 	if backoff, exists := s.backoffs[serverID]; exists {
@@ -55,30 +126,74 @@ func (s *BackoffStore) NextRetryAt(serverID int) (time.Time, bool) {
 	return time.Time{}, false
 }
 
-// UpdateBackoff updates the backoff delay and next retry time for the given server ID.
-// If no backoff exists for the server, it initializes one with BASE_BACKOFF.
-func (s *BackoffStore) UpdateBackoff(serverID int) {
+// BackoffDelay returns the current backoff delay of the given server ID, and a boolean
+// indicating whether the server has an active backoff.
+func (s *MemoryBackoffStore) BackoffDelay(serverID int) (time.Duration, bool) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	backoff, exists := s.backoffs[serverID]
+	return backoff.BackoffDelay, exists
+}
+
+// UpdateBackoff records a failure of the given server ID and updates its backoff delay and
+// next retry time. If no backoff exists for the server, it initializes one with BASE_BACKOFF.
+// Once the failures reach the circuit breaker threshold, the next retry is after the cooldown.
+// It returns true if this failure opened the circuit (including a failed half-open probe).
+func (s *MemoryBackoffStore) UpdateBackoff(serverID int) bool {
 	s.mu.Lock()
-	defer s.mu.Unlock()
 
-	if backoff, exists := s.backoffs[serverID]; exists {
+	backoff, exists := s.backoffs[serverID]
+	if exists {
 		backoff.BackoffDelay = calculateNewBackoffDelay(backoff.BackoffDelay)
-		backoff.NextRetryAt = calculateNextRetryAt(backoff.BackoffDelay)
-		s.backoffs[serverID] = backoff
 	} else {
-		s.backoffs[serverID] = backoffData{
-			BackoffDelay: BASE_BACKOFF,
-			NextRetryAt:  calculateNextRetryAt(BASE_BACKOFF),
-		}
+		backoff.BackoffDelay = BASE_BACKOFF
+	}
+	backoff.ConsecutiveFailures++
+	opened := s.circuitOpened(backoff)
+	if opened {
+		backoff.NextRetryAt = time.Now().Add(s.cooldown).UTC()
+	} else {
+		backoff.NextRetryAt = calculateNextRetryAt(backoff.BackoffDelay)
 	}
+	s.backoffs[serverID] = backoff
+	s.mu.Unlock()
+
+	s.replica.Publish(serverID, backoff)
+	return opened
 }
 
-// ResetBackoff removes any existing backoff data for the given server ID.
-func (s *BackoffStore) ResetBackoff(serverID int) {
+// ResetBackoff removes any existing backoff data for the given server ID, closing its circuit.
+func (s *MemoryBackoffStore) ResetBackoff(serverID int) {
 	s.mu.Lock()
-	defer s.mu.Unlock()
-
+	_, exists := s.backoffs[serverID]
 	delete(s.backoffs, serverID)
+	s.mu.Unlock()
+
+	// Most checks succeed: only the end of a backoff is worth a write to the backend.
+	if exists {
+		s.replica.Unpublish(serverID)
+	}
+}
+
+// Share publishes the backoffs from now on to backend, for the other instances to sync (see
+// Sync), so that a server failing for one instance is backed off by all of them. It must be
+// called before the store is used.
+func (s *MemoryBackoffStore) Share(backend sharedstore.Backend) {
+	s.replica = sharedstore.NewReplica[backoffData]("backoff", backend)
+}
+
+// Sync stores the backoffs of the given servers updated or reset by the other instances since
+// the last sync. It does nothing if the store is not shared.
+func (s *MemoryBackoffStore) Sync(ctx context.Context, serverIDs []int) error {
+	return s.replica.Sync(ctx, serverIDs, func(serverID int, backoff backoffData) {
+		s.mu.Lock()
+		defer s.mu.Unlock()
+		s.backoffs[serverID] = backoff
+	}, func(serverID int) {
+		s.mu.Lock()
+		defer s.mu.Unlock()
+		delete(s.backoffs, serverID)
+	})
 }
 
 // DoWithBackoff executes an HTTP request with exponential backoff on failure.
@@ -112,6 +227,42 @@ func DoWithBackoff(ctx context.Context, client *http.Client, req *http.Request,
 	}
 }
 
+// PermanentError wraps an error that retrying cannot fix, such as a request rejected as invalid.
+// RetryWithBackoff returns it without further attempts.
+type PermanentError struct {
+	Err error
+}
+
+func (e *PermanentError) Error() string { return e.Err.Error() }
+
+func (e *PermanentError) Unwrap() error { return e.Err }
+
+// RetryWithBackoff calls fn until it succeeds, waiting with exponential backoff between attempts.
+// Unlike DoWithBackoff it suits requests with a body, since fn builds a new request on every attempt.
+//   - fn is retried at most maxRetries times; zero means it is called only once.
+//   - An error wrapped in a *PermanentError is returned immediately.
+//   - If the context is canceled, it returns immediately.
+func RetryWithBackoff(ctx context.Context, maxRetries int, fn func() error) error {
+	backoffDelay := BASE_BACKOFF
+	for attempt := 0; ; attempt++ {
+		err := fn()
+		if err == nil {
+			return nil
+		}
+		var permanent *PermanentError
+		if errors.As(err, &permanent) || attempt >= maxRetries {
+			return fmt.Errorf("failed after %d attempts: %w", attempt+1, err)
+		}
+
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-time.After(backoffDelay):
+		}
+		backoffDelay = calculateNewBackoffDelay(backoffDelay)
+	}
+}
+
 // calculateNextRetryAt returns the next retry time by adding jitter to the given backoff duration.
 // The result is capped at MAX_BACKOFF and returned as a UTC timestamp.
 func calculateNextRetryAt(backoff time.Duration) time.Time {
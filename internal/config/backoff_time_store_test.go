@@ -7,6 +7,10 @@ import (
 	"strings"
 	"testing"
 	"time"
+
+	"github.com/alicebob/miniredis/v2"
+	"github.com/redis/go-redis/v9"
+	"watchdog.onebusaway.org/internal/sharedstore"
 )
 
 func TestDoWithBackoff(t *testing.T) {
@@ -137,7 +141,7 @@ func TestCalculateNextRetryAt(t *testing.T) {
 }
 
 func TestBackoffStore(t *testing.T) {
-	store := NewBackoffStore()
+	store := NewBackoffStore(0, 0)
 	serverID := 42
 
 	t.Run("NextRetryAt returns false when no entry", func(t *testing.T) {
@@ -178,3 +182,129 @@ func TestBackoffStore(t *testing.T) {
 		}
 	})
 }
+
+func TestBackoffStoreCircuitBreaker(t *testing.T) {
+	store := NewBackoffStore(3, 10*time.Minute)
+	serverID := 42
+
+	if state, failures := store.CircuitState(serverID, time.Now()); state != CircuitClosed || failures != 0 {
+		t.Errorf("expected a closed circuit without failures, got %s with %d", state, failures)
+	}
+	for i := range 2 {
+		if store.UpdateBackoff(serverID) {
+			t.Fatalf("expected failure %d not to open the circuit", i+1)
+		}
+	}
+	if !store.UpdateBackoff(serverID) {
+		t.Fatal("expected the third failure to open the circuit")
+	}
+
+	now := time.Now()
+	if state, failures := store.CircuitState(serverID, now); state != CircuitOpen || failures != 3 {
+		t.Errorf("expected an open circuit with 3 failures, got %s with %d", state, failures)
+	}
+	// The cooldown replaces the exponential backoff.
+	if next, _ := store.NextRetryAt(serverID); next.Before(now.Add(9 * time.Minute)) {
+		t.Errorf("expected the next retry after the cooldown, got %v", next)
+	}
+
+	// After the cooldown the next run probes the server; a failed probe opens the circuit again.
+	if state, _ := store.CircuitState(serverID, now.Add(11*time.Minute)); state != CircuitHalfOpen {
+		t.Errorf("expected a half-open circuit after the cooldown, got %s", state)
+	}
+	if !store.UpdateBackoff(serverID) {
+		t.Error("expected a failed probe to open the circuit again")
+	}
+
+	store.ResetBackoff(serverID)
+	if state, failures := store.CircuitState(serverID, now); state != CircuitClosed || failures != 0 {
+		t.Errorf("expected a successful probe to close the circuit, got %s with %d", state, failures)
+	}
+
+	disabled := NewBackoffStore(0, 0)
+	for range 10 {
+		disabled.UpdateBackoff(serverID)
+	}
+	if state, _ := disabled.CircuitState(serverID, time.Now()); state != CircuitClosed {
+		t.Errorf("expected the circuit breaker to be disabled, got %s", state)
+	}
+}
+
+func TestRetryWithBackoff(t *testing.T) {
+	t.Run("retries until success", func(t *testing.T) {
+		calls := 0
+		err := RetryWithBackoff(context.Background(), 2, func() error {
+			calls++
+			if calls < 2 {
+				return errors.New("temporary")
+			}
+			return nil
+		})
+		if err != nil || calls != 2 {
+			t.Errorf("expected success on the second call, got err=%v calls=%d", err, calls)
+		}
+	})
+
+	t.Run("zero retries calls once", func(t *testing.T) {
+		calls := 0
+		err := RetryWithBackoff(context.Background(), 0, func() error {
+			calls++
+			return errors.New("temporary")
+		})
+		if err == nil || calls != 1 {
+			t.Errorf("expected a single failed call, got err=%v calls=%d", err, calls)
+		}
+	})
+
+	t.Run("permanent errors are not retried", func(t *testing.T) {
+		calls := 0
+		invalid := errors.New("invalid")
+		err := RetryWithBackoff(context.Background(), 3, func() error {
+			calls++
+			return &PermanentError{Err: invalid}
+		})
+		if !errors.Is(err, invalid) || calls != 1 {
+			t.Errorf("expected the permanent error after one call, got err=%v calls=%d", err, calls)
+		}
+	})
+
+	t.Run("context canceled", func(t *testing.T) {
+		ctx, cancel := context.WithCancel(context.Background())
+		cancel()
+		err := RetryWithBackoff(ctx, 3, func() error { return errors.New("temporary") })
+		if !errors.Is(err, context.Canceled) {
+			t.Errorf("expected context.Canceled, got %v", err)
+		}
+	})
+}
+
+func TestBackoffStoreShared(t *testing.T) {
+	server := miniredis.RunT(t)
+	backend := &sharedstore.Redis{Client: redis.NewClient(&redis.Options{Addr: server.Addr()}), Prefix: "test:"}
+	first := NewBackoffStore(2, 10*time.Minute)
+	first.Share(backend)
+	second := NewBackoffStore(2, 10*time.Minute)
+	second.Share(backend)
+	ctx := context.Background()
+
+	first.UpdateBackoff(7)
+	first.UpdateBackoff(7)
+	if err := second.Sync(ctx, []int{7, 8}); err != nil {
+		t.Fatal(err)
+	}
+	if state, failures := second.CircuitState(7, time.Now()); state != CircuitOpen || failures != 2 {
+		t.Errorf("expected the circuit opened by the other instance, got %s with %d", state, failures)
+	}
+	if _, ok := second.NextRetryAt(8); ok {
+		t.Error("expected no backoff for a server that never failed")
+	}
+
+	// A reset by one instance ends the backoff of the others.
+	second.ResetBackoff(7)
+	if err := first.Sync(ctx, []int{7}); err != nil {
+		t.Fatal(err)
+	}
+	if _, ok := first.NextRetryAt(7); ok {
+		t.Error("expected the backoff reset by the other instance to be removed")
+	}
+}
@@ -0,0 +1,94 @@
+package config
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"strings"
+
+	"cloud.google.com/go/storage"
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/s3"
+	"google.golang.org/api/option"
+	"watchdog.onebusaway.org/internal/secrets"
+)
+
+// isBucketURL reports whether rawURL is the URL of an object of a cloud storage bucket, read by
+// readBucketObject rather than requested over HTTP.
+func isBucketURL(rawURL string) bool {
+	return strings.HasPrefix(rawURL, "s3://") || strings.HasPrefix(rawURL, "gs://")
+}
+
+// readBucketObject returns the content of the object of a cloud storage bucket at rawURL:
+//   - s3://<bucket>/<key>, read with the AWS SDK and its default credential chain (see
+//     secrets.AWSConfig). AWS_ENDPOINT_URL_S3 (or AWS_ENDPOINT_URL) overrides the endpoint of
+//     S3, e.g. for MinIO, and the bucket is then part of the path;
+//   - gs://<bucket>/<object>, read with the Cloud Storage client and the Application Default
+//     Credentials (see secrets.GoogleClient). STORAGE_EMULATOR_HOST overrides the endpoint of
+//     Cloud Storage, without authentication.
+//
+// Buckets without a public endpoint can hold the configuration this way. The SDKs retry the
+// failed reads themselves.
+func readBucketObject(ctx context.Context, client *http.Client, rawURL string) ([]byte, error) {
+	scheme, rest, _ := strings.Cut(rawURL, "://")
+	bucket, object, _ := strings.Cut(rest, "/")
+	switch scheme {
+	case "s3":
+		if bucket == "" || object == "" {
+			return nil, fmt.Errorf("invalid S3 URL %q, expected s3://<bucket>/<key>", rawURL)
+		}
+		return readS3Object(ctx, client, bucket, object)
+	case "gs":
+		if bucket == "" || object == "" {
+			return nil, fmt.Errorf("invalid Cloud Storage URL %q, expected gs://<bucket>/<object>", rawURL)
+		}
+		return readGCSObject(ctx, client, bucket, object)
+	}
+	return nil, fmt.Errorf("unsupported bucket URL %q", rawURL)
+}
+
+// readS3Object returns the content of the key of an S3 bucket.
+func readS3Object(ctx context.Context, client *http.Client, bucket, key string) ([]byte, error) {
+	cfg, err := secrets.AWSConfig(ctx, client)
+	if err != nil {
+		return nil, err
+	}
+	if cfg.Region == "" {
+		return nil, fmt.Errorf("AWS_REGION must be set to read s3://%s/%s", bucket, key)
+	}
+	s3Client := s3.NewFromConfig(cfg, func(o *s3.Options) {
+		// Custom endpoints, e.g. of MinIO, usually only serve path-style requests.
+		o.UsePathStyle = o.BaseEndpoint != nil
+	})
+	object, err := s3Client.GetObject(ctx, &s3.GetObjectInput{Bucket: aws.String(bucket), Key: aws.String(key)})
+	if err != nil {
+		return nil, fmt.Errorf("failed to read s3://%s/%s: %w", bucket, key, err)
+	}
+	defer object.Body.Close()
+	return io.ReadAll(object.Body)
+}
+
+// readGCSObject returns the content of an object of a Cloud Storage bucket.
+func readGCSObject(ctx context.Context, client *http.Client, bucket, object string) ([]byte, error) {
+	// The Cloud Storage client does not authenticate the requests to emulators.
+	httpClient := client
+	if os.Getenv("STORAGE_EMULATOR_HOST") == "" {
+		var err error
+		if httpClient, err = secrets.GoogleClient(ctx, client, storage.ScopeReadOnly); err != nil {
+			return nil, err
+		}
+	}
+	gcs, err := storage.NewClient(ctx, option.WithHTTPClient(httpClient))
+	if err != nil {
+		return nil, fmt.Errorf("failed to create the Cloud Storage client: %w", err)
+	}
+	defer gcs.Close()
+	reader, err := gcs.Bucket(bucket).Object(object).NewReader(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read gs://%s/%s: %w", bucket, object, err)
+	}
+	defer reader.Close()
+	return io.ReadAll(reader)
+}
@@ -0,0 +1,74 @@
+package config
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+const bucketConfig = `[{"name": "Test Server", "id": 1, "oba_base_url": "https://test.example.com", "oba_api_key": "test-key", "gtfs_url": "https://gtfs.example.com"}]`
+
+// isolateAWS keeps the AWS SDK from reading the AWS configuration of the machine running the tests.
+func isolateAWS(t *testing.T) {
+	dir := t.TempDir()
+	t.Setenv("AWS_CONFIG_FILE", filepath.Join(dir, "config"))
+	t.Setenv("AWS_SHARED_CREDENTIALS_FILE", filepath.Join(dir, "credentials"))
+	t.Setenv("AWS_EC2_METADATA_DISABLED", "true")
+	t.Setenv("AWS_PROFILE", "")
+}
+
+func TestLoadConfigFromS3(t *testing.T) {
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		auth := r.Header.Get("Authorization")
+		if r.URL.Path != "/fleet/prod/fleet config.json" || !strings.HasPrefix(auth, "AWS4-HMAC-SHA256 Credential=AKID/") ||
+			!strings.Contains(auth, "/us-west-2/s3/aws4_request") || r.Header.Get("X-Amz-Security-Token") != "session" {
+			t.Errorf("unexpected request %s %q", r.URL.Path, auth)
+			w.WriteHeader(http.StatusForbidden)
+			return
+		}
+		w.Write([]byte(bucketConfig))
+	}))
+	defer ts.Close()
+	isolateAWS(t)
+	t.Setenv("AWS_REGION", "us-west-2")
+	t.Setenv("AWS_ACCESS_KEY_ID", "AKID")
+	t.Setenv("AWS_SECRET_ACCESS_KEY", "secret")
+	t.Setenv("AWS_SESSION_TOKEN", "session")
+	t.Setenv("AWS_ENDPOINT_URL_S3", ts.URL)
+
+	servers, err := loadConfigFromURL(context.Background(), ts.Client(), "s3://fleet/prod/fleet config.json", "", "", 1)
+	if err != nil || len(servers) != 1 || servers[0].ObaApiKey != "test-key" {
+		t.Fatalf("expected the config of the bucket, got %+v, %v", servers, err)
+	}
+
+	t.Setenv("AWS_ACCESS_KEY_ID", "")
+	t.Setenv("AWS_SECRET_ACCESS_KEY", "")
+	if _, err := loadConfigFromURL(context.Background(), ts.Client(), "s3://fleet/prod/fleet config.json", "", "", 1); err == nil {
+		t.Error("expected an error without credentials")
+	}
+	if _, err := loadConfigFromURL(context.Background(), ts.Client(), "s3://fleet", "", "", 1); err == nil {
+		t.Error("expected an error for a URL without a key")
+	}
+}
+
+func TestLoadConfigFromGCS(t *testing.T) {
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		// Emulators are read with the XML API, without authentication.
+		if r.URL.Path != "/fleet/prod/config.json" || r.Header.Get("Authorization") != "" {
+			t.Errorf("unexpected request %s %q", r.URL, r.Header.Get("Authorization"))
+			w.WriteHeader(http.StatusForbidden)
+			return
+		}
+		w.Write([]byte(bucketConfig))
+	}))
+	defer ts.Close()
+	t.Setenv("STORAGE_EMULATOR_HOST", ts.URL)
+
+	servers, err := loadConfigFromURL(context.Background(), ts.Client(), "gs://fleet/prod/config.json", "", "", 1)
+	if err != nil || len(servers) != 1 || servers[0].ID != 1 {
+		t.Fatalf("expected the config of the bucket, got %+v, %v", servers, err)
+	}
+}
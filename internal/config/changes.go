@@ -0,0 +1,102 @@
+package config
+
+import (
+	"crypto/sha256"
+	"encoding/json"
+	"fmt"
+	"slices"
+	"strings"
+
+	"watchdog.onebusaway.org/internal/models"
+)
+
+// ServerChanges are the differences between two configurations, matching servers by id.
+type ServerChanges struct {
+	Added   []models.ObaServer
+	Removed []models.ObaServer
+	// Modified are the new definitions of the servers whose settings changed, and Fields the
+	// names of the changed settings of each of them, by server id.
+	Modified []models.ObaServer
+	Fields   map[int][]string
+}
+
+// Empty reports whether the configurations are the same.
+func (c ServerChanges) Empty() bool {
+	return len(c.Added) == 0 && len(c.Removed) == 0 && len(c.Modified) == 0
+}
+
+// LogAttrs returns the changes as log attributes: the ids of the added and removed servers,
+// and the changed settings of each modified server (e.g. "3:gtfs_url,oba_api_key"). Values are
+// left out, as they can be API keys.
+func (c ServerChanges) LogAttrs() []any {
+	ids := func(servers []models.ObaServer) []int {
+		ids := make([]int, len(servers))
+		for i, server := range servers {
+			ids[i] = server.ID
+		}
+		return ids
+	}
+	modified := make([]string, len(c.Modified))
+	for i, server := range c.Modified {
+		modified[i] = fmt.Sprintf("%d:%s", server.ID, strings.Join(c.Fields[server.ID], ","))
+	}
+	return []any{"added", ids(c.Added), "removed", ids(c.Removed), "modified", modified}
+}
+
+// DiffServers returns the changes from the servers of old to those of new.
+func DiffServers(old, new []models.ObaServer) ServerChanges {
+	changes := ServerChanges{Fields: make(map[int][]string)}
+	previous := make(map[int]models.ObaServer, len(old))
+	for _, server := range old {
+		previous[server.ID] = server
+	}
+	for _, server := range new {
+		before, ok := previous[server.ID]
+		delete(previous, server.ID)
+		if !ok {
+			changes.Added = append(changes.Added, server)
+		} else if fields := changedFields(before, server); len(fields) > 0 {
+			changes.Modified = append(changes.Modified, server)
+			changes.Fields[server.ID] = fields
+		}
+	}
+	for _, server := range old {
+		if _, ok := previous[server.ID]; ok {
+			changes.Removed = append(changes.Removed, server)
+		}
+	}
+	return changes
+}
+
+// changedFields returns the JSON names of the settings that differ between a and b, sorted.
+func changedFields(a, b models.ObaServer) []string {
+	fieldsA, fieldsB := serverFields(a), serverFields(b)
+	var changed []string
+	for name, value := range fieldsA {
+		if other, ok := fieldsB[name]; !ok || string(other) != string(value) {
+			changed = append(changed, name)
+		}
+	}
+	for name := range fieldsB {
+		if _, ok := fieldsA[name]; !ok {
+			changed = append(changed, name)
+		}
+	}
+	slices.Sort(changed)
+	return changed
+}
+
+// serverFields returns the settings of server by JSON name.
+func serverFields(server models.ObaServer) map[string]json.RawMessage {
+	var fields map[string]json.RawMessage
+	data, _ := json.Marshal(server)
+	_ = json.Unmarshal(data, &fields)
+	return fields
+}
+
+// hashServers returns a digest of the definitions of servers, to tell whether a configuration
+// changed without comparing it field by field.
+func hashServers(servers []models.ObaServer) [sha256.Size]byte {
+	data, _ := json.Marshal(servers)
+	return sha256.Sum256(data)
+}
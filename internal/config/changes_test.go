@@ -0,0 +1,40 @@
+package config
+
+import (
+	"reflect"
+	"testing"
+
+	"watchdog.onebusaway.org/internal/models"
+)
+
+func TestDiffServers(t *testing.T) {
+	old := []models.ObaServer{
+		{ID: 1, Name: "One", GtfsUrl: "https://one.example.com/gtfs.zip"},
+		{ID: 2, Name: "Two", ObaApiKey: "old-key"},
+		{ID: 3, Name: "Three"},
+	}
+	new := []models.ObaServer{
+		{ID: 1, Name: "One", GtfsUrl: "https://one.example.com/gtfs.zip"},
+		{ID: 2, Name: "Two", ObaApiKey: "new-key", GtfsUrl: "https://two.example.com/gtfs.zip"},
+		{ID: 4, Name: "Four"},
+	}
+
+	changes := DiffServers(old, new)
+	if len(changes.Added) != 1 || changes.Added[0].ID != 4 || len(changes.Removed) != 1 || changes.Removed[0].ID != 3 {
+		t.Fatalf("expected server 4 added and server 3 removed, got %+v", changes)
+	}
+	if len(changes.Modified) != 1 || changes.Modified[0].ObaApiKey != "new-key" {
+		t.Fatalf("expected the new definition of server 2, got %+v", changes.Modified)
+	}
+	if got := changes.Fields[2]; !reflect.DeepEqual(got, []string{"gtfs_url", "oba_api_key"}) {
+		t.Errorf("expected the changed settings of server 2, got %v", got)
+	}
+	want := []any{"added", []int{4}, "removed", []int{3}, "modified", []string{"2:gtfs_url,oba_api_key"}}
+	if got := changes.LogAttrs(); !reflect.DeepEqual(got, want) {
+		t.Errorf("expected log attributes %v, got %v", want, got)
+	}
+
+	if changes := DiffServers(old, old); !changes.Empty() {
+		t.Errorf("expected no changes, got %+v", changes)
+	}
+}
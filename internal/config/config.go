@@ -1,9 +1,15 @@
 package config
 
 import (
+	"errors"
 	"sync"
+	"text/template"
+	"time"
 
+	"github.com/redis/go-redis/v9"
+	"watchdog.onebusaway.org/internal/auth"
 	"watchdog.onebusaway.org/internal/models"
+	"watchdog.onebusaway.org/internal/scheduler"
 )
 
 // Config holds all the configuration settings for our application.
@@ -11,8 +17,248 @@ type Config struct {
 	Port          int
 	Env           string
 	FetchInterval int
-	Mu            sync.RWMutex
-	Servers       []models.ObaServer
+	// TLSCertFile and TLSKeyFile are the PEM certificate and key the server is served with over
+	// HTTPS (empty = plain HTTP). TLSReloadSchedule is when they are reloaded if they changed
+	// (nil = loaded once on startup).
+	TLSCertFile       string
+	TLSKeyFile        string
+	TLSReloadSchedule scheduler.Schedule
+	// ColdStartReadyFraction is the fraction of the servers whose GTFS bundle must be loaded on
+	// startup before the watchdog is ready, the rest being downloaded in the background, and
+	// ColdStartTimeout bounds how long startup waits for them (0 = no bound).
+	ColdStartReadyFraction float64
+	ColdStartTimeout       time.Duration
+	// ShutdownTimeout bounds how long the watchdog waits on SIGINT or SIGTERM for the checks and
+	// GTFS downloads in flight, then for the HTTP requests in flight, before exiting.
+	ShutdownTimeout time.Duration
+	// BundleDownloadRateLimit caps each GTFS bundle download, in bytes per second (0 = unlimited).
+	BundleDownloadRateLimit int64
+	// BundleDownloadGlobalRateLimit caps all concurrent GTFS bundle downloads combined,
+	// in bytes per second (0 = unlimited).
+	BundleDownloadGlobalRateLimit int64
+	// ConnectTimeout and TLSHandshakeTimeout bound establishing the connections of outgoing
+	// requests, and APITimeout, RealtimeTimeout and BundleDownloadTimeout are the overall
+	// timeouts of OBA API calls, GTFS-RT feed polls and GTFS bundle downloads (see
+	// httpclient.Timeouts). Servers can override them with their timeouts.
+	ConnectTimeout        time.Duration
+	TLSHandshakeTimeout   time.Duration
+	APITimeout            time.Duration
+	RealtimeTimeout       time.Duration
+	BundleDownloadTimeout time.Duration
+	// OutboundRateLimit caps the requests per second sent to each remote host (0 = unlimited);
+	// servers can override it with rate_limit. OutboundGlobalRateLimit caps all outgoing
+	// requests combined (0 = unlimited).
+	OutboundRateLimit       float64
+	OutboundGlobalRateLimit float64
+	// ProxyURL is the proxy of outgoing requests (empty = HTTP_PROXY, HTTPS_PROXY and NO_PROXY),
+	// and CACertFiles are PEM bundles of CA certificates trusted besides the system ones.
+	// Servers can override both with proxy_url and ca_cert_files.
+	ProxyURL    string
+	CACertFiles []string
+	// BundleCacheDir is the directory where downloaded GTFS bundles are persisted
+	// across restarts (empty = disabled).
+	BundleCacheDir string
+	// LifecycleFile is the file where the times the servers, routes and GTFS-RT feeds were
+	// first and last observed are persisted across restarts (empty = kept in memory only).
+	LifecycleFile string
+	// HistoryDB is the SQLite database where the result of every check run is recorded, for the
+	// history API (empty = disabled). Results are kept for HistoryRetention.
+	HistoryDB        string
+	HistoryRetention time.Duration
+	// ProbePrimaryURL makes this watchdog a secondary probe agent, which pushes the results of
+	// its pings to the primary watchdog at this URL after every collection cycle (empty = not
+	// an agent). VantagePoint names the agent in the results of the primary (e.g. its region).
+	ProbePrimaryURL string
+	VantagePoint    string
+	// ProbeToken authenticates the probe agents to the primary; the primary accepts results from
+	// agents only when it is set. ProbeResultMaxAge is how long the primary takes the last result
+	// of an agent into account.
+	ProbeToken        string
+	ProbeResultMaxAge time.Duration
+	// LeaderElection is the lock replicas elect a leader with, "kubernetes" (a Lease) or "redis"
+	// (empty = no election, the watchdog always runs the checks). LeaderElectionName is the name
+	// of the lease or the Redis key, LeaderElectionNamespace the namespace of the lease (empty =
+	// the namespace of the pod), and LeaderElectionIdentity the name of this replica (empty =
+	// host name). The leader holds the lock for LeaderElectionTTL at a time.
+	LeaderElection          string
+	LeaderElectionName      string
+	LeaderElectionNamespace string
+	LeaderElectionIdentity  string
+	LeaderElectionTTL       time.Duration
+	// RedisAddr is the Redis server ("host:port", or a redis:// or rediss:// URL) of the "redis"
+	// leader election and shared stores, and RedisPassword its optional password. Redis is the
+	// client of RedisAddr they share, set up on startup when one of them is enabled.
+	RedisAddr     string
+	RedisPassword string
+	Redis         *redis.Client
+	// SharedStore is the backend the instances of a horizontally scaled deployment share their
+	// GTFS, bounding box and backoff stores through, "redis" (empty = not shared).
+	// SharedStorePrefix namespaces its keys, and SharedStoreSyncSchedule is when the entries
+	// published by the other instances are synced.
+	SharedStore             string
+	SharedStorePrefix       string
+	SharedStoreSyncSchedule scheduler.Schedule
+	// MaxBundleSize is the largest GTFS bundle, in bytes, that will be downloaded (0 = unlimited).
+	MaxBundleSize int64
+	// BundleChangeWebhookURL receives a JSON event whenever a server's GTFS bundle changes (empty = disabled).
+	BundleChangeWebhookURL string
+	// PushGatewayURL is the Prometheus Pushgateway metrics are pushed to after every collection (empty = disabled).
+	PushGatewayURL string
+	// PushGatewayJob is the `job` grouping label used when pushing to the Pushgateway.
+	PushGatewayJob string
+	// OTLPEndpoint is the OTLP/HTTP endpoint of an OpenTelemetry collector metrics and spans are exported to (empty = disabled).
+	OTLPEndpoint string
+	// OTLPExportInterval is how often metrics and queued spans are exported to the OTLP endpoint.
+	OTLPExportInterval time.Duration
+	// SlackWebhookURL is the default Slack incoming webhook alerts are posted to (empty = disabled
+	// unless a server sets its own).
+	SlackWebhookURL string
+	// AlertCooldown is the default minimum time between two notifications for the same server and check.
+	AlertCooldown time.Duration
+	// AlertRules are user-defined alerts on the exported metrics, evaluated after every collection cycle.
+	AlertRules []models.AlertRule
+	// PagerDutyRoutingKey is the default PagerDuty Events API v2 routing key alerts page
+	// (empty = disabled unless a server sets its own).
+	PagerDutyRoutingKey string
+	// AlertWebhookURL is the default URL that check state changes are posted to
+	// (empty = disabled unless a server sets its own).
+	AlertWebhookURL string
+	// AlertWebhookSecret signs alert webhook requests with HMAC-SHA256 (empty = unsigned).
+	AlertWebhookSecret string
+	// AlertWebhookTemplate renders the body of alert webhook requests (nil = default JSON payload).
+	AlertWebhookTemplate *template.Template
+	// AuthTokensFile is the JSON file of admin API tokens and their roles (empty = admin API disabled).
+	AuthTokensFile string
+	// APIAuthToken, and APIAuthUser with APIAuthPassword, are the bearer token and basic auth
+	// credentials required on /metrics and the status API (all empty = no credentials required).
+	APIAuthToken    string
+	APIAuthUser     string
+	APIAuthPassword string
+	// AuditLogFile is the file admin API requests are appended to as JSON lines (empty = application log).
+	AuditLogFile string
+	// OIDCIssuerURL is the OpenID Connect provider users log in with (empty = OIDC login disabled).
+	OIDCIssuerURL string
+	// OIDCClientID and OIDCClientSecret identify the watchdog at the OIDC provider.
+	OIDCClientID     string
+	OIDCClientSecret string
+	// OIDCRedirectURL is the public URL of the watchdog's /auth/callback endpoint.
+	OIDCRedirectURL string
+	// OIDCRolesClaim is the ID token claim whose values are mapped to roles with OIDCRoleMapping.
+	OIDCRolesClaim  string
+	OIDCRoleMapping map[string]auth.Role
+	// OIDCDefaultRole is the role of users without a mapped claim value (auth.RoleNone = denied).
+	OIDCDefaultRole auth.Role
+	// OIDCSessionTTL is how long a login lasts.
+	OIDCSessionTTL time.Duration
+	// OIDCSessionSecret signs session cookies (empty = a random secret, so sessions end on restart).
+	OIDCSessionSecret string
+	// StatusPage enables the public status page at /status.
+	StatusPage bool
+	// StatusPageTitle is the title of the public status page.
+	StatusPageTitle string
+	// StatusPageChecks are the checks (see metrics.CheckNames) the public status page reports on.
+	StatusPageChecks []string
+	// StatusPageDays is the number of days of uptime history shown on the public status page.
+	StatusPageDays int
+	// RouteMismatchThreshold is the share of an agency's routes missing from or extra in the OBA API
+	// above which the discrepancy is reported to Sentry.
+	RouteMismatchThreshold float64
+	// CircuitBreakerThreshold is the number of consecutive ping failures after which the checks of
+	// a server are skipped for CircuitBreakerCooldown (0 = only exponential backoff).
+	CircuitBreakerThreshold int
+	CircuitBreakerCooldown  time.Duration
+	// IncidentFeed enables the Atom feed of incidents at /v1/incidents.atom.
+	IncidentFeed bool
+	// AlertLocale is the default language of alert notifications (e.g. "en", "es", "fr").
+	AlertLocale string
+	// AgencyDigestSchedule controls when the data-quality findings of servers are sent to their
+	// agency contacts.
+	AgencyDigestSchedule scheduler.Schedule
+	// SMTPAddr is the SMTP server ("host:port") emails are sent through (empty = no emails);
+	// SMTPFrom is their sender, and SMTPUsername and SMTPPassword the optional credentials.
+	SMTPAddr     string
+	SMTPFrom     string
+	SMTPUsername string
+	SMTPPassword string
+	// SMTPTLS is the security of SMTP connections (alert.SMTPTLSAuto, SMTPTLSStartTLS or
+	// SMTPTLSImplicit), and SMTPAuth the authentication mechanism (alert.SMTPAuthPlain or
+	// SMTPAuthCRAMMD5).
+	SMTPTLS  string
+	SMTPAuth string
+	// AlertEmailTo are the default recipients of alert emails (empty = only the servers with
+	// alerts.email_to). Alerts are sent one email each, or batched in a digest every
+	// AlertEmailDigestWindow if it is positive.
+	AlertEmailTo           []string
+	AlertEmailDigestWindow time.Duration
+	// ConfigFile is the local configuration file servers were loaded from. Servers added or removed
+	// through the admin API are written back to it (empty = changes are kept in memory only).
+	ConfigFile string
+	// FetchSchedule overrides FetchInterval for metrics collection when set.
+	FetchSchedule scheduler.Schedule
+	// FeedStaleAfter is how long after its last successful fetch a GTFS-RT feed counts as stale in
+	// watchdog_feeds_stale.
+	FeedStaleAfter time.Duration
+	// BundleRefreshSchedule controls when GTFS static bundles are refreshed.
+	BundleRefreshSchedule scheduler.Schedule
+	// LocalBundleWatchSchedule controls when the GTFS bundles read from local files are checked
+	// for changes.
+	LocalBundleWatchSchedule scheduler.Schedule
+	// ConfigRefreshSchedule controls when a remote configuration is reloaded.
+	ConfigRefreshSchedule scheduler.Schedule
+	// ConfigStaleIntervals is the number of scheduled reloads of the remote configuration that
+	// can fail in a row before the configuration is stale (see Config.ConfigStale).
+	ConfigStaleIntervals int
+	// ReportProblemSchedule controls when test problem reports are submitted to the OBA APIs of
+	// the servers with a report_problem_stop_id.
+	ReportProblemSchedule scheduler.Schedule
+	// ExecCheckSchedule controls when the custom exec checks of the servers are run.
+	ExecCheckSchedule scheduler.Schedule
+	// AllowExecChecks enables the exec checks of the servers. Without it, configurations and
+	// servers added through the admin API that define exec checks are rejected (see
+	// DisallowExecChecks), since whoever controls them could run commands on the watchdog host.
+	AllowExecChecks bool
+	// SecretsRefreshSchedule controls when the secret references of the servers are resolved
+	// again, to pick up rotated secrets.
+	SecretsRefreshSchedule scheduler.Schedule
+	// SentryRetrySchedule controls when the events that could not be delivered to Sentry are
+	// sent again.
+	SentryRetrySchedule scheduler.Schedule
+	// ErrorReporter is where reported errors are sent: sentry (the default), rollbar, log or
+	// none (see report.Reporter). RollbarAccessToken authenticates to Rollbar.
+	ErrorReporter      string
+	RollbarAccessToken string
+	// SentryMinInterval and SentryBurst throttle the reports of the same error of the same server
+	// to SentryBurst reports at once, then one per SentryMinInterval; 0 disables the throttling.
+	SentryMinInterval time.Duration
+	SentryBurst       int
+	// UpdateCheck enables the check of the releases of UpdateCheckRepo for a newer version, on
+	// startup and on UpdateCheckSchedule.
+	UpdateCheck         bool
+	UpdateCheckRepo     string
+	UpdateCheckSchedule scheduler.Schedule
+	// ServiceCalendarRefreshSchedule controls when the reduced service calendars of servers are reloaded.
+	ServiceCalendarRefreshSchedule scheduler.Schedule
+	// VehicleCleanupSchedule controls when stale vehicle entries are removed.
+	VehicleCleanupSchedule scheduler.Schedule
+	// ConfigURL is the remote configuration servers are reloaded from (empty = none).
+	ConfigURL string
+	// ReadinessRequired are the external dependencies (see app.ReadinessDependencies) whose
+	// failure makes the watchdog not ready; the others are only reported by /v1/readyz.
+	ReadinessRequired []string
+	Mu                sync.RWMutex
+	Servers           []models.ObaServer
+	// lastRefresh is the outcome of the last reload of ConfigURL.
+	lastRefresh RefreshStatus
+}
+
+// RefreshStatus is the outcome of a reload of the remote configuration.
+type RefreshStatus struct {
+	// At is when the reload finished; zero if the configuration was not reloaded yet.
+	At time.Time
+	// Err is why the reload failed, nil if the servers were updated.
+	Err error
+	// LastSuccess is when a reload last succeeded, set by SetLastRefresh; zero if none did.
+	LastSuccess time.Time
 }
 
 // NewConfig creates a new instance of a Config struct.
@@ -31,12 +277,105 @@ func (cfg *Config) UpdateConfig(newServers []models.ObaServer) {
 	cfg.Servers = newServers
 }
 
-// GetServers safely returns a copy of the servers slice to avoid
-// concurrent modification issues.
+// SetLastRefresh records the outcome of a reload of the remote configuration, and exports the
+// time of the last successful one in ConfigLastRefreshTimestamp.
+func (cfg *Config) SetLastRefresh(status RefreshStatus) {
+	cfg.Mu.Lock()
+	defer cfg.Mu.Unlock()
+	if status.Err == nil {
+		status.LastSuccess = status.At
+		ConfigLastRefreshTimestamp.Set(float64(status.At.Unix()))
+	} else {
+		status.LastSuccess = cfg.lastRefresh.LastSuccess
+	}
+	cfg.lastRefresh = status
+}
+
+// ConfigStale reports whether more than ConfigStaleIntervals scheduled reloads of the remote
+// configuration failed since the last successful one, e.g. because the config URL has been
+// answering 403 for days. Without a ConfigRefreshSchedule, any failed reload makes it stale.
+func (cfg *Config) ConfigStale(now time.Time) bool {
+	refresh := cfg.LastRefresh()
+	if refresh.Err == nil {
+		return false
+	}
+	if refresh.LastSuccess.IsZero() || cfg.ConfigRefreshSchedule == nil {
+		return true
+	}
+	missed := 0
+	for next := cfg.ConfigRefreshSchedule.Next(refresh.LastSuccess); !next.After(now); next = cfg.ConfigRefreshSchedule.Next(next) {
+		if missed++; missed > cfg.ConfigStaleIntervals {
+			return true
+		}
+	}
+	return false
+}
+
+// LastRefresh returns the outcome of the last reload of the remote configuration.
+func (cfg *Config) LastRefresh() RefreshStatus {
+	cfg.Mu.RLock()
+	defer cfg.Mu.RUnlock()
+	return cfg.lastRefresh
+}
+
+// GetServers safely returns a copy of the OBA servers of the configuration to avoid
+// concurrent modification issues. Entries of type "url" are left out (see GetURLTargets).
 // This method should be used to access the servers from other parts of the application.
 // It returns a copy of the servers slice to ensure thread safety.
 func (cfg *Config) GetServers() []models.ObaServer {
+	return cfg.entries(false)
+}
+
+// GetURLTargets safely returns a copy of the entries of type "url" of the configuration,
+// the plain HTTP services checked for uptime alongside the OBA servers.
+func (cfg *Config) GetURLTargets() []models.ObaServer {
+	return cfg.entries(true)
+}
+
+// entries returns the URL targets of the configuration, or its OBA servers.
+func (cfg *Config) entries(urlTargets bool) []models.ObaServer {
 	cfg.Mu.RLock()
 	defer cfg.Mu.RUnlock()
-	return append([]models.ObaServer(nil), cfg.Servers...)
+	entries := make([]models.ObaServer, 0, len(cfg.Servers))
+	for _, server := range cfg.Servers {
+		if server.IsURLTarget() == urlTargets {
+			entries = append(entries, server)
+		}
+	}
+	return entries
+}
+
+// ErrServerExists is returned by AddServer when a server with the same ID is already configured.
+var ErrServerExists = errors.New("a server with this id already exists")
+
+// AddServer safely appends a server to the config servers.
+// It returns ErrServerExists if a server with the same ID is already configured.
+func (cfg *Config) AddServer(server models.ObaServer) error {
+	cfg.Mu.Lock()
+	defer cfg.Mu.Unlock()
+	for _, existing := range cfg.Servers {
+		if existing.ID == server.ID {
+			return ErrServerExists
+		}
+	}
+	cfg.Servers = append(append([]models.ObaServer(nil), cfg.Servers...), server)
+	return nil
+}
+
+// RemoveServer safely removes the server with the given ID from the config servers.
+// It returns false if no such server is configured.
+func (cfg *Config) RemoveServer(id int) bool {
+	cfg.Mu.Lock()
+	defer cfg.Mu.Unlock()
+	servers := make([]models.ObaServer, 0, len(cfg.Servers))
+	for _, server := range cfg.Servers {
+		if server.ID != id {
+			servers = append(servers, server)
+		}
+	}
+	if len(servers) == len(cfg.Servers) {
+		return false
+	}
+	cfg.Servers = servers
+	return true
 }
@@ -2,6 +2,7 @@ package config
 
 import (
 	"context"
+	"crypto/sha256"
 	"encoding/json"
 	"flag"
 	"fmt"
@@ -15,6 +16,8 @@ import (
 	"github.com/getsentry/sentry-go"
 	"watchdog.onebusaway.org/internal/models"
 	"watchdog.onebusaway.org/internal/report"
+	"watchdog.onebusaway.org/internal/scheduler"
+	"watchdog.onebusaway.org/internal/secrets"
 	"watchdog.onebusaway.org/internal/utils"
 )
 
@@ -38,12 +41,24 @@ func ValidateConfigFlags(configFile, configURL *string) error {
 // The fetch process is resilient:
 //   - It uses `loadConfigFromURL`, which applies exponential backoff retries
 //     (up to `maxRetries`) when transient network or parsing errors occur.
-//   - On success, the application's configuration is updated via `cfg.UpdateConfig`.
+//   - A configuration identical to the last one applied (see hashServers) is left at that: the
+//     servers in use, and their rotated secrets, are kept.
+//   - Otherwise, secret references are resolved with resolver (see ResolveSecrets), feed
+//     settings are derived from the servers' OBA data sources (see resolveDataSources)
+//     and the application's configuration is updated via `cfg.UpdateConfig`. The servers
+//     added, removed and modified are logged and passed to onChange (if not nil), so that only
+//     their state is updated.
 //   - On failure, errors are logged and reported to Sentry, but the loop continues,
-//     ensuring that the service keeps running even under repeated failures.
+//     ensuring that the service keeps running even under repeated failures. A config that
+//     fails validation (see Validate), has exec checks while cfg.AllowExecChecks is off (see
+//     DisallowExecChecks), or whose secrets cannot be resolved, is a failure too: the servers in
+//     use are kept rather than swapped for a broken list.
+//   - The outcome of each reload is recorded with `cfg.SetLastRefresh`, for the readiness probe,
+//     and counted in ConfigReloads.
 //
-// The function runs in a loop, sleeping for the specified `interval` between
-// refresh attempts, and terminates gracefully when the context is canceled.
+// The function first refreshes at the first activation of `schedule`, not right away, since the
+// configuration was just loaded from the same URL on startup, then at every activation, and
+// terminates gracefully when the context is canceled.
 //
 // Parameters:
 //   - ctx: Context for graceful cancellation of the refresh routine.
@@ -52,31 +67,63 @@ func ValidateConfigFlags(configFile, configURL *string) error {
 //   - configAuthUser: Optional username for basic authentication.
 //   - configAuthPass: Optional password for basic authentication.
 //   - cfg: Pointer to the application Config object to update.
+//   - resolver: Resolves the secret references of the config (nil = none).
+//   - onChange: Called with the changes of the servers after an update (nil = none).
 //   - logger: Logger for structured log output.
-//   - interval: Time duration between consecutive refresh attempts.
+//   - schedule: When to refresh (a fixed interval or a cron expression, see scheduler.Parse).
 //   - maxRetries: Maximum number of exponential backoff retries per fetch attempt.
 
-func refreshConfig(ctx context.Context, client *http.Client, configURL, configAuthUser, configAuthPass string, cfg *Config, logger *slog.Logger, interval time.Duration, maxRetries int) {
-	for {
-		select {
-		case <-ctx.Done():
-			logger.Info("Stopping config refresh routine")
-			return
+func refreshConfig(ctx context.Context, client *http.Client, configURL, configAuthUser, configAuthPass string, cfg *Config, resolver *secrets.Resolver, onChange func(context.Context, ServerChanges), logger *slog.Logger, schedule scheduler.Schedule, maxRetries int) {
+	// lastHash is the digest of the last configuration applied, as fetched.
+	var lastHash [sha256.Size]byte
+	refresh := func() {
+		newServers, err := loadConfigFromURL(ctx, client, configURL, configAuthUser, configAuthPass, maxRetries)
+		if err == nil && !cfg.AllowExecChecks {
+			err = DisallowExecChecks(newServers)
+		}
+		unchanged := false
+		if err == nil {
+			hash := hashServers(newServers)
+			unchanged = hash == lastHash
+			if !unchanged {
+				if err = ResolveSecrets(ctx, resolver, newServers); err == nil {
+					lastHash = hash
+				}
+			}
+		}
+		switch {
+		case err != nil:
+			ConfigReloads.WithLabelValues("failure").Inc()
+			report.ReportErrorWithSentryOptions(err, report.SentryReportOptions{
+				Tags:  utils.MakeMap("config_url", configURL),
+				Level: sentry.LevelError,
+			})
+			logger.Error("Failed to refresh remote config", "error", err)
+		case unchanged:
+			ConfigReloads.WithLabelValues("success").Inc()
+			logger.Debug("Remote config unchanged")
 		default:
-			newServers, err := loadConfigFromURL(ctx, client, configURL, configAuthUser, configAuthPass, maxRetries)
-			if err != nil {
-				report.ReportErrorWithSentryOptions(err, report.SentryReportOptions{
-					Tags:  utils.MakeMap("config_url", configURL),
-					Level: sentry.LevelError,
-				})
-				logger.Error("Failed to refresh remote config", "error", err)
-			} else {
-				cfg.UpdateConfig(newServers)
-				logger.Info("Successfully refreshed server configuration")
+			ConfigReloads.WithLabelValues("success").Inc()
+			newServers = resolveDataSources(ctx, client, newServers, logger)
+			WarnDuplicateServers(newServers, logger)
+			cfg.Mu.RLock()
+			changes := DiffServers(cfg.Servers, newServers)
+			cfg.Mu.RUnlock()
+			cfg.UpdateConfig(newServers)
+			if changes.Empty() {
+				logger.Info("Successfully refreshed server configuration, no server changed")
+				break
+			}
+			logger.Info("Successfully refreshed server configuration", changes.LogAttrs()...)
+			if onChange != nil {
+				onChange(ctx, changes)
 			}
-			time.Sleep(interval)
 		}
+		cfg.SetLastRefresh(RefreshStatus{At: time.Now(), Err: err})
 	}
+
+	scheduler.Run(ctx, "config_refresh", schedule, refresh)
+	logger.Info("Stopping config refresh routine")
 }
 
 // LoadConfigFromFile reads a JSON configuration file from disk and unmarshals it
@@ -86,11 +133,42 @@ func refreshConfig(ctx context.Context, client *http.Client, configURL, configAu
 // Without this restriction, a user could supply any file path on the machine
 // (e.g., /etc/passwd), and the application would attempt to read it.
 //
+// Environment variable placeholders (${VAR}) in its values are expanded (see ExpandEnv), then
+// the servers are checked with Validate, and a config with problems is rejected with a
+// ValidationErrors naming each offending server and field.
+//
 // On error, it reports issues to Sentry and returns a descriptive error.
 //
 // This function is used when the application is configured to load its server list
 // from a static file using the --config-file flag.
 func loadConfigFromFile(filePath string) ([]models.ObaServer, error) {
+	servers, err := readConfigFile(filePath)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := ExpandEnv(servers); err != nil {
+		report.ReportErrorWithSentryOptions(err, report.SentryReportOptions{
+			Tags:  utils.MakeMap("file_path", filePath),
+			Level: sentry.LevelError,
+		})
+		return nil, err
+	}
+
+	if err := Validate(servers); err != nil {
+		report.ReportErrorWithSentryOptions(err, report.SentryReportOptions{
+			Tags:  utils.MakeMap("file_path", filePath),
+			Level: sentry.LevelError,
+		})
+		return nil, err
+	}
+
+	return servers, nil
+}
+
+// readConfigFile reads the servers of a configuration file as written, without expanding or
+// validating them (see loadConfigFromFile).
+func readConfigFile(filePath string) ([]models.ObaServer, error) {
 	if filepath.Base(filePath) != "config.json" {
 		return nil, fmt.Errorf("invalid config file name: %s (only config.json is allowed)", filePath)
 	}
@@ -117,68 +195,139 @@ func loadConfigFromFile(filePath string) ([]models.ObaServer, error) {
 	return servers, nil
 }
 
+// saveConfigToFile writes servers to a JSON configuration file, in the format read by
+// loadConfigFromFile. The file is replaced atomically (written to a temporary file in the
+// same directory, then renamed), so a crash cannot leave a truncated configuration behind.
+func saveConfigToFile(filePath string, servers []models.ObaServer) error {
+	if filepath.Base(filePath) != "config.json" {
+		return fmt.Errorf("invalid config file name: %s (only config.json is allowed)", filePath)
+	}
+
+	data, err := json.MarshalIndent(servers, "", "  ")
+	if err != nil {
+		return fmt.Errorf("failed to marshal JSON: %v", err)
+	}
+
+	tmp, err := os.CreateTemp(filepath.Dir(filePath), ".config-*.json")
+	if err != nil {
+		return fmt.Errorf("failed to create temporary config file: %v", err)
+	}
+	defer os.Remove(tmp.Name())
+	if _, err := tmp.Write(append(data, '\n')); err != nil {
+		tmp.Close()
+		return fmt.Errorf("failed to write config file: %v", err)
+	}
+	if err := tmp.Close(); err != nil {
+		return fmt.Errorf("failed to write config file: %v", err)
+	}
+	if info, err := os.Stat(filePath); err == nil {
+		_ = os.Chmod(tmp.Name(), info.Mode().Perm())
+	}
+	if err := os.Rename(tmp.Name(), filePath); err != nil {
+		return fmt.Errorf("failed to replace config file: %v", err)
+	}
+	return nil
+}
+
+// updateConfigFile applies update to the servers of a configuration file and saves the result.
+// The servers are read from the file rather than taken from Config, so that settings derived
+// at runtime (see resolveDataSources) and the values of environment variable placeholders
+// (see ExpandEnv), e.g. API keys, are not written back.
+func updateConfigFile(filePath string, update func([]models.ObaServer) ([]models.ObaServer, error)) error {
+	servers, err := readConfigFile(filePath)
+	if err != nil {
+		return err
+	}
+	servers, err = update(servers)
+	if err != nil {
+		return err
+	}
+	return saveConfigToFile(filePath, servers)
+}
+
 // loadConfigFromURL fetches a JSON configuration from a remote HTTP(S) endpoint,
-// using the provided client and optional basic authentication.
+// using the provided client and optional basic authentication, or from an S3 or Cloud
+// Storage bucket (s3:// and gs:// URLs, see readBucketObject).
 //
 // It validates the response status, reads the body, and unmarshals the configuration
 // into a slice of `models.ObaServer`.
 //
-// Requests are executed with exponential backoff using DoWithBackoff. This ensures
+// HTTP requests are executed with exponential backoff using DoWithBackoff. This ensures
 // that transient network errors (e.g., timeouts, connection failures) are retried
 // with increasing delays, up to `maxRetries` attempts.
 //
+// As with loadConfigFromFile, environment variable placeholders are expanded, and a config
+// that fails Validate is rejected.
+//
 // Errors are logged and reported to Sentry for observability.
 func loadConfigFromURL(ctx context.Context, client *http.Client, url, authUser, authPass string, maxRetries int) ([]models.ObaServer, error) {
-	req, err := http.NewRequest("GET", url, nil)
-	if err != nil {
-		report.ReportErrorWithSentryOptions(err, report.SentryReportOptions{
-			Tags:  utils.MakeMap("config_url", url),
-			Level: sentry.LevelError,
-		})
-		return nil, fmt.Errorf("failed to create request: %v", err)
-	}
-
-	if authUser != "" && authPass != "" {
-		req.SetBasicAuth(authUser, authPass)
+	var data []byte
+	var err error
+	if isBucketURL(url) {
+		data, err = readBucketObject(ctx, client, url)
+	} else {
+		data, err = fetchConfig(ctx, client, url, authUser, authPass, maxRetries)
 	}
-
-	resp, err := DoWithBackoff(ctx, client, req, maxRetries)
 	if err != nil {
 		report.ReportErrorWithSentryOptions(err, report.SentryReportOptions{
 			Tags:  utils.MakeMap("config_url", url),
 			Level: sentry.LevelError,
 		})
-		return nil, fmt.Errorf("failed to fetch remote config: %v", err)
+		return nil, err
 	}
 
-	defer resp.Body.Close()
-
-	if resp.StatusCode != http.StatusOK {
-		statusErr := fmt.Errorf("remote config returned status: %d", resp.StatusCode)
-		report.ReportErrorWithSentryOptions(statusErr, report.SentryReportOptions{
+	var servers []models.ObaServer
+	if err := json.Unmarshal(data, &servers); err != nil {
+		report.ReportErrorWithSentryOptions(err, report.SentryReportOptions{
 			Tags:  utils.MakeMap("config_url", url),
 			Level: sentry.LevelError,
 		})
-		return nil, statusErr
+		return nil, fmt.Errorf("failed to unmarshal JSON: %v", err)
 	}
 
-	data, err := io.ReadAll(resp.Body)
-	if err != nil {
+	if err := ExpandEnv(servers); err != nil {
 		report.ReportErrorWithSentryOptions(err, report.SentryReportOptions{
 			Tags:  utils.MakeMap("config_url", url),
 			Level: sentry.LevelError,
 		})
-		return nil, fmt.Errorf("failed to read remote config: %v", err)
+		return nil, err
 	}
 
-	var servers []models.ObaServer
-	if err := json.Unmarshal(data, &servers); err != nil {
+	if err := Validate(servers); err != nil {
 		report.ReportErrorWithSentryOptions(err, report.SentryReportOptions{
 			Tags:  utils.MakeMap("config_url", url),
 			Level: sentry.LevelError,
 		})
-		return nil, fmt.Errorf("failed to unmarshal JSON: %v", err)
+		return nil, err
 	}
 
 	return servers, nil
 }
+
+// fetchConfig requests the configuration at the HTTP(S) URL rawURL, with basic authentication
+// if authUser and authPass are set, and returns the body of a successful response.
+func fetchConfig(ctx context.Context, client *http.Client, rawURL, authUser, authPass string, maxRetries int) ([]byte, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, rawURL, nil)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create request: %v", err)
+	}
+	if authUser != "" && authPass != "" {
+		req.SetBasicAuth(authUser, authPass)
+	}
+
+	resp, err := DoWithBackoff(ctx, client, req, maxRetries)
+	if err != nil {
+		return nil, fmt.Errorf("failed to fetch remote config: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("remote config returned status: %d", resp.StatusCode)
+	}
+
+	data, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read remote config: %w", err)
+	}
+	return data, nil
+}
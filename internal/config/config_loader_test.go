@@ -3,6 +3,7 @@ package config
 import (
 	"bytes"
 	"context"
+	"errors"
 	"flag"
 	"fmt"
 	"io"
@@ -11,11 +12,15 @@ import (
 	"net/http/httptest"
 	"os"
 	"path/filepath"
+	"reflect"
 	"strings"
+	"sync/atomic"
 	"testing"
 	"time"
 
+	"github.com/prometheus/client_golang/prometheus/testutil"
 	"watchdog.onebusaway.org/internal/models"
+	"watchdog.onebusaway.org/internal/scheduler"
 )
 
 func TestLoadConfigFromFile(t *testing.T) {
@@ -58,7 +63,7 @@ func TestLoadConfigFromFile(t *testing.T) {
 			GtfsRtApiValue:     "",
 		}
 
-		if servers[0] != expected {
+		if !reflect.DeepEqual(servers[0], expected) {
 			t.Errorf("expected %+v, got %+v", expected, servers[0])
 		}
 	})
@@ -76,6 +81,33 @@ func TestLoadConfigFromFile(t *testing.T) {
 		}
 	})
 
+	t.Run("InvalidServers", func(t *testing.T) {
+		dir := t.TempDir()
+		fp := filepath.Join(dir, "config.json")
+		content := `[
+		{"name": "A", "id": 1, "oba_base_url": "https://a.example.com", "gtfs_rt_api_key": "X-Api-Key"},
+		{"name": "B", "id": 1, "oba_base_url": "a.example.com"}
+		]`
+		if err := os.WriteFile(fp, []byte(content), 0o600); err != nil {
+			t.Fatalf("write config.json: %v", err)
+		}
+
+		_, err := loadConfigFromFile(fp)
+		var problems ValidationErrors
+		if !errors.As(err, &problems) || len(problems) != 3 {
+			t.Fatalf("expected 3 validation errors, got %v", err)
+		}
+		for _, want := range []string{
+			"servers[0] (id 1): gtfs_rt_api_value: is required when gtfs_rt_api_key is set",
+			"servers[1] (id 1): id: duplicates the id of servers[0]",
+			`servers[1] (id 1): oba_base_url: invalid URL "a.example.com"`,
+		} {
+			if !strings.Contains(err.Error(), want) {
+				t.Errorf("expected the error to contain %q, got %v", want, err)
+			}
+		}
+	})
+
 	t.Run("NonExistentFile", func(t *testing.T) {
 		dir := t.TempDir()
 		fp := filepath.Join(dir, "config.json")
@@ -86,6 +118,46 @@ func TestLoadConfigFromFile(t *testing.T) {
 	})
 }
 
+func TestAddAndRemoveServerInFile(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "config.json")
+	content := `[{"name": "Server 1", "id": 1, "oba_base_url": "https://one.example.com"}]`
+	if err := os.WriteFile(path, []byte(content), 0600); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := AddServerToFile(path, models.ObaServer{ID: 2, Name: "Server 2", ObaBaseURL: "https://two.example.com"}); err != nil {
+		t.Fatalf("AddServerToFile failed: %v", err)
+	}
+	if err := AddServerToFile(path, models.ObaServer{ID: 1, Name: "Duplicate"}); !errors.Is(err, ErrServerExists) {
+		t.Errorf("expected ErrServerExists for a duplicate id, got %v", err)
+	}
+	servers, err := loadConfigFromFile(path)
+	if err != nil {
+		t.Fatalf("failed to reload config: %v", err)
+	}
+	if len(servers) != 2 || servers[1].ObaBaseURL != "https://two.example.com" {
+		t.Fatalf("unexpected servers after AddServerToFile: %+v", servers)
+	}
+
+	if err := RemoveServerFromFile(path, 1); err != nil {
+		t.Fatalf("RemoveServerFromFile failed: %v", err)
+	}
+	servers, err = loadConfigFromFile(path)
+	if err != nil {
+		t.Fatalf("failed to reload config: %v", err)
+	}
+	if len(servers) != 1 || servers[0].ID != 2 {
+		t.Errorf("unexpected servers after RemoveServerFromFile: %+v", servers)
+	}
+	if info, err := os.Stat(path); err != nil || info.Mode().Perm() != 0600 {
+		t.Errorf("expected the file mode to be kept, got %v (%v)", info.Mode(), err)
+	}
+
+	if err := AddServerToFile(filepath.Join(t.TempDir(), "servers.json"), models.ObaServer{ID: 3}); err == nil {
+		t.Error("expected an error for a file not named config.json")
+	}
+}
+
 func TestLoadConfigFromURL(t *testing.T) {
 	client := &http.Client{
 		Timeout: 10 * time.Second,
@@ -126,7 +198,7 @@ func TestLoadConfigFromURL(t *testing.T) {
 			VehiclePositionUrl: "https://vehicle.example.com",
 		}
 
-		if servers[0] != expected {
+		if !reflect.DeepEqual(servers[0], expected) {
 			t.Errorf("Expected server %+v, got %+v", expected, servers[0])
 		}
 	})
@@ -295,9 +367,9 @@ func TestRefreshConfig(t *testing.T) {
 
 	testLogger := slog.New(slog.NewTextHandler(io.Discard, nil))
 
-	var serverHitCount int
+	var serverHitCount atomic.Int32
 	mockServer := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
-		serverHitCount++
+		serverHitCount.Add(1)
 
 		user, pass, hasAuth := r.BasicAuth()
 		if hasAuth && (user != "testuser" || pass != "testpass") {
@@ -310,8 +382,8 @@ func TestRefreshConfig(t *testing.T) {
 					{
 							"id": 999,
 							"name": "Refreshed Test Server",
-							"url": "https://refreshed.example.com",
-							"api_key": "refreshed-key",
+							"oba_base_url": "https://refreshed.example.com",
+							"oba_api_key": "refreshed-key",
 							"gtfs_url": "https://refreshed.example.com/gtfs.zip"
 					}
 			]`)
@@ -323,11 +395,11 @@ func TestRefreshConfig(t *testing.T) {
 
 	ctx, cancel := context.WithCancel(context.Background())
 	defer cancel()
-	go refreshConfig(ctx, client, mockServer.URL, "testuser", "testpass", cfg, testLogger, 100*time.Millisecond, 1)
+	go refreshConfig(ctx, client, mockServer.URL, "testuser", "testpass", cfg, nil, nil, testLogger, scheduler.Every(100*time.Millisecond), 1)
 
 	time.Sleep(200 * time.Millisecond)
 
-	if serverHitCount == 0 {
+	if serverHitCount.Load() == 0 {
 		t.Fatal("Mock server was never called")
 	}
 
@@ -349,3 +421,77 @@ func TestRefreshConfig(t *testing.T) {
 		t.Errorf("Config not updated with refreshed server data. Original: %+v, Updated: %+v", originalConfig, updatedServers)
 	}
 }
+
+func TestRefreshConfigRejectsInvalidConfig(t *testing.T) {
+	cfg := NewConfig(4000, "testing", []models.ObaServer{{ID: 1, Name: "Test Server", ObaBaseURL: "https://test.example.com"}})
+	mockServer := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		fmt.Fprintln(w, `[{"id": 2, "name": "Broken Server"}]`)
+	}))
+	defer mockServer.Close()
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	go refreshConfig(ctx, mockServer.Client(), mockServer.URL, "", "", cfg, nil, nil, slog.New(slog.NewTextHandler(io.Discard, nil)), scheduler.Every(50*time.Millisecond), 0)
+	time.Sleep(150 * time.Millisecond)
+
+	if servers := cfg.GetServers(); len(servers) != 1 || servers[0].ID != 1 {
+		t.Errorf("expected the invalid config to be rejected, got %+v", servers)
+	}
+}
+
+func TestRefreshConfigSkipsUnchangedConfig(t *testing.T) {
+	cfg := NewConfig(4000, "testing", []models.ObaServer{{ID: 1, Name: "Test Server", ObaBaseURL: "https://test.example.com"}})
+	var body atomic.Value
+	body.Store(`[{"id": 1, "name": "Test Server", "oba_base_url": "https://test.example.com"}, {"id": 2, "name": "New Server", "oba_base_url": "https://new.example.com"}]`)
+	mockServer := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		fmt.Fprintln(w, body.Load().(string))
+	}))
+	defer mockServer.Close()
+
+	changes := make(chan ServerChanges, 10)
+	onChange := func(_ context.Context, c ServerChanges) { changes <- c }
+	successes := testutil.ToFloat64(ConfigReloads.WithLabelValues("success"))
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	go refreshConfig(ctx, mockServer.Client(), mockServer.URL, "", "", cfg, nil, onChange, slog.New(slog.NewTextHandler(io.Discard, nil)), scheduler.Every(20*time.Millisecond), 0)
+	time.Sleep(150 * time.Millisecond)
+
+	if len(changes) != 1 {
+		t.Fatalf("expected a single change for repeated identical configs, got %d", len(changes))
+	}
+	if c := <-changes; len(c.Added) != 1 || c.Added[0].ID != 2 || len(c.Removed) != 0 || len(c.Modified) != 0 {
+		t.Errorf("expected server 2 added, got %+v", c)
+	}
+	if got := testutil.ToFloat64(ConfigReloads.WithLabelValues("success")) - successes; got < 2 {
+		t.Errorf("expected every reload counted as a success, got %v", got)
+	}
+
+	body.Store(`[{"id": 2, "name": "Renamed Server", "oba_base_url": "https://new.example.com"}]`)
+	select {
+	case c := <-changes:
+		if len(c.Removed) != 1 || c.Removed[0].ID != 1 || len(c.Modified) != 1 || !reflect.DeepEqual(c.Fields[2], []string{"name"}) {
+			t.Errorf("expected server 1 removed and server 2 renamed, got %+v", c)
+		}
+	case <-time.After(time.Second):
+		t.Fatal("expected the changed config to be applied")
+	}
+}
+
+func TestRefreshConfigWaitsForSchedule(t *testing.T) {
+	cfg := NewConfig(4000, "testing", []models.ObaServer{{ID: 1, Name: "Test Server", ObaBaseURL: "https://test.example.com"}})
+	var requests atomic.Int32
+	mockServer := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requests.Add(1)
+		fmt.Fprintln(w, `[{"id": 1, "name": "Test Server", "oba_base_url": "https://test.example.com"}]`)
+	}))
+	defer mockServer.Close()
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	go refreshConfig(ctx, mockServer.Client(), mockServer.URL, "", "", cfg, nil, nil, slog.New(slog.NewTextHandler(io.Discard, nil)), scheduler.Every(time.Hour), 0)
+	time.Sleep(100 * time.Millisecond)
+
+	if got := requests.Load(); got != 0 {
+		t.Errorf("expected no refresh before the first activation of the schedule, got %d requests", got)
+	}
+}
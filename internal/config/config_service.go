@@ -5,11 +5,12 @@ import (
 	"fmt"
 	"log/slog"
 	"net/http"
-	"time"
 
 	"github.com/getsentry/sentry-go"
 	"watchdog.onebusaway.org/internal/models"
 	"watchdog.onebusaway.org/internal/report"
+	"watchdog.onebusaway.org/internal/scheduler"
+	"watchdog.onebusaway.org/internal/secrets"
 	"watchdog.onebusaway.org/internal/utils"
 )
 
@@ -18,11 +19,16 @@ type ConfigService struct {
 	Logger       *slog.Logger
 	Client       *http.Client
 	Config       *Config
-	BackoffStore *BackoffStore
+	BackoffStore BackoffStore
+	// Secrets resolves the secret references of the configuration; nil leaves them as is.
+	Secrets *secrets.Resolver
+	// OnChange is called with the servers added, removed and modified by a refresh of the
+	// remote configuration; nil ignores the changes.
+	OnChange func(ctx context.Context, changes ServerChanges)
 }
 
 // NewConfigService creates a new ConfigService instance with the provided logger and HTTP client.
-func NewConfigService(logger *slog.Logger, client *http.Client, config *Config, backoffStore *BackoffStore) *ConfigService {
+func NewConfigService(logger *slog.Logger, client *http.Client, config *Config, backoffStore BackoffStore) *ConfigService {
 	return &ConfigService{
 		Logger:       logger,
 		Client:       client,
@@ -31,8 +37,8 @@ func NewConfigService(logger *slog.Logger, client *http.Client, config *Config,
 	}
 }
 
-func (cs *ConfigService) RefreshConfig(ctx context.Context, url, authUser, authPass string, interval time.Duration, maxRetries int) {
-	refreshConfig(ctx, cs.Client, url, authUser, authPass, cs.Config, cs.Logger, interval, maxRetries)
+func (cs *ConfigService) RefreshConfig(ctx context.Context, url, authUser, authPass string, schedule scheduler.Schedule, maxRetries int) {
+	refreshConfig(ctx, cs.Client, url, authUser, authPass, cs.Config, cs.Secrets, cs.OnChange, cs.Logger, schedule, maxRetries)
 }
 
 // exported helper functions
@@ -51,6 +57,33 @@ func LoadConfigFromFile(filePath string) ([]models.ObaServer, error) {
 	return servers, nil
 }
 
+// AddServerToFile appends a server to a configuration file.
+// It returns ErrServerExists if the file already has a server with the same ID.
+func AddServerToFile(filePath string, server models.ObaServer) error {
+	return updateConfigFile(filePath, func(servers []models.ObaServer) ([]models.ObaServer, error) {
+		for _, existing := range servers {
+			if existing.ID == server.ID {
+				return nil, ErrServerExists
+			}
+		}
+		return append(servers, server), nil
+	})
+}
+
+// RemoveServerFromFile removes the server with the given ID from a configuration file.
+// Removing a server the file does not have is not an error.
+func RemoveServerFromFile(filePath string, id int) error {
+	return updateConfigFile(filePath, func(servers []models.ObaServer) ([]models.ObaServer, error) {
+		kept := servers[:0]
+		for _, server := range servers {
+			if server.ID != id {
+				kept = append(kept, server)
+			}
+		}
+		return kept, nil
+	})
+}
+
 // Load config from URL and update Config.
 func LoadConfigFromURL(ctx context.Context, client *http.Client, url, authUser, authPass string, maxRetires int) ([]models.ObaServer, error) {
 	servers, err := loadConfigFromURL(ctx, client, url, authUser, authPass, maxRetires)
@@ -64,3 +97,8 @@ func LoadConfigFromURL(ctx context.Context, client *http.Client, url, authUser,
 	}
 	return servers, nil
 }
+
+// ResolveDataSources fills in empty feed settings of servers from their OBA instance's data-sources.xml.
+func ResolveDataSources(ctx context.Context, client *http.Client, servers []models.ObaServer, logger *slog.Logger) []models.ObaServer {
+	return resolveDataSources(ctx, client, servers, logger)
+}
@@ -1,10 +1,14 @@
 package config
 
 import (
+	"errors"
 	"reflect"
 	"testing"
+	"time"
 
+	"github.com/prometheus/client_golang/prometheus/testutil"
 	"watchdog.onebusaway.org/internal/models"
+	"watchdog.onebusaway.org/internal/scheduler"
 )
 
 func TestNewConfig(t *testing.T) {
@@ -142,3 +146,68 @@ func TestUpdateConfig(t *testing.T) {
 		t.Errorf("Expected server name to be updated to 'Server 1 Updated', got %s", config.Servers[0].Name)
 	}
 }
+
+func TestAddAndRemoveServer(t *testing.T) {
+	config := NewConfig(1, "testing", []models.ObaServer{{ID: 1, Name: "Server 1"}})
+
+	if err := config.AddServer(models.ObaServer{ID: 2, Name: "Server 2"}); err != nil {
+		t.Fatalf("AddServer failed: %v", err)
+	}
+	if err := config.AddServer(models.ObaServer{ID: 2, Name: "Duplicate"}); !errors.Is(err, ErrServerExists) {
+		t.Errorf("expected ErrServerExists for a duplicate id, got %v", err)
+	}
+	if servers := config.GetServers(); len(servers) != 2 || servers[1].Name != "Server 2" {
+		t.Fatalf("unexpected servers after AddServer: %+v", servers)
+	}
+
+	if !config.RemoveServer(1) {
+		t.Error("expected RemoveServer to remove server 1")
+	}
+	if config.RemoveServer(1) {
+		t.Error("expected RemoveServer to report an unknown server")
+	}
+	if servers := config.GetServers(); len(servers) != 1 || servers[0].ID != 2 {
+		t.Errorf("unexpected servers after RemoveServer: %+v", servers)
+	}
+}
+
+func TestGetURLTargets(t *testing.T) {
+	config := NewConfig(1, "testing", []models.ObaServer{
+		{ID: 1, Name: "Server 1"},
+		{ID: 2, Name: "Trip planner", Type: models.ServerTypeURL, URL: "https://otp.example.com/health"},
+	})
+
+	if servers := config.GetServers(); len(servers) != 1 || servers[0].ID != 1 {
+		t.Errorf("expected GetServers to return only the OBA server, got %+v", servers)
+	}
+	if targets := config.GetURLTargets(); len(targets) != 1 || targets[0].ID != 2 {
+		t.Errorf("expected GetURLTargets to return only the URL target, got %+v", targets)
+	}
+}
+
+func TestConfigStale(t *testing.T) {
+	cfg := NewConfig(4000, "testing", nil)
+	cfg.ConfigRefreshSchedule = scheduler.Every(time.Minute)
+	cfg.ConfigStaleIntervals = 3
+	start := time.Date(2025, 6, 7, 12, 0, 0, 0, time.UTC)
+
+	cfg.SetLastRefresh(RefreshStatus{At: start})
+	if got := testutil.ToFloat64(ConfigLastRefreshTimestamp); got != float64(start.Unix()) {
+		t.Errorf("expected the last refresh timestamp %d, got %v", start.Unix(), got)
+	}
+	cfg.SetLastRefresh(RefreshStatus{At: start.Add(3 * time.Minute), Err: errors.New("remote config returned status: 403")})
+	if refresh := cfg.LastRefresh(); !refresh.LastSuccess.Equal(start) {
+		t.Errorf("expected the last success to be kept, got %v", refresh.LastSuccess)
+	}
+	if cfg.ConfigStale(start.Add(3 * time.Minute)) {
+		t.Error("expected 3 failed reloads to be tolerated")
+	}
+	if !cfg.ConfigStale(start.Add(4 * time.Minute)) {
+		t.Error("expected the config to be stale after 4 failed reloads")
+	}
+
+	cfg.SetLastRefresh(RefreshStatus{At: start.Add(5 * time.Minute)})
+	if cfg.ConfigStale(start.Add(time.Hour)) {
+		t.Error("expected a successful reload to clear the staleness")
+	}
+}
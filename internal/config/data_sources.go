@@ -0,0 +1,248 @@
+package config
+
+import (
+	"context"
+	"encoding/xml"
+	"fmt"
+	"io"
+	"log/slog"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/getsentry/sentry-go"
+	"watchdog.onebusaway.org/internal/models"
+	"watchdog.onebusaway.org/internal/report"
+	"watchdog.onebusaway.org/internal/utils"
+)
+
+// maxDataSourcesSize bounds the size of a data-sources.xml document.
+const maxDataSourcesSize = 1 << 20
+
+// dataSources holds the feed URLs an OBA instance is configured with, as read from its
+// Spring data-sources.xml.
+type dataSources struct {
+	// GtfsURL is the `url` of the first GtfsBundle bean, if any.
+	GtfsURL string
+	// Realtime contains one entry per GtfsRealtimeSource bean, in document order.
+	Realtime []realtimeSource
+}
+
+// realtimeSource is a GtfsRealtimeSource bean from data-sources.xml.
+type realtimeSource struct {
+	AgencyID           string
+	TripUpdatesURL     string
+	VehiclePositionURL string
+	// Headers is the source's headersMap, typically holding the feed's API key.
+	Headers map[string]string
+}
+
+// springBean, springProperty and springEntry map the parts of a Spring beans document
+// needed to read OBA's data sources. Beans can be nested inside list and map properties,
+// e.g. GtfsBundle beans inside the `bundles` list of a GtfsBundles bean.
+type springBean struct {
+	Class      string           `xml:"class,attr"`
+	Properties []springProperty `xml:"property"`
+}
+
+type springProperty struct {
+	Name       string        `xml:"name,attr"`
+	Value      string        `xml:"value,attr"`
+	ValueElem  string        `xml:"value"`
+	ListValues []string      `xml:"list>value"`
+	ListBeans  []springBean  `xml:"list>bean"`
+	Bean       *springBean   `xml:"bean"`
+	Entries    []springEntry `xml:"map>entry"`
+}
+
+type springEntry struct {
+	Key   string `xml:"key,attr"`
+	Value string `xml:"value,attr"`
+}
+
+func (p springProperty) value() string {
+	if p.Value != "" {
+		return strings.TrimSpace(p.Value)
+	}
+	return strings.TrimSpace(p.ValueElem)
+}
+
+func (b springBean) property(name string) (springProperty, bool) {
+	for _, p := range b.Properties {
+		if p.Name == name {
+			return p, true
+		}
+	}
+	return springProperty{}, false
+}
+
+// parseDataSources extracts GTFS bundle and GTFS-realtime feed URLs from an OBA
+// data-sources.xml document. Beans are matched by the simple name of their class, so the
+// OBA package names do not matter. Properties using Spring placeholders (`${...}`) are
+// ignored, since their value is only known to the OBA instance.
+func parseDataSources(data []byte) (*dataSources, error) {
+	var doc struct {
+		Beans []springBean `xml:"bean"`
+	}
+	if err := xml.Unmarshal(data, &doc); err != nil {
+		return nil, fmt.Errorf("failed to parse data sources XML: %w", err)
+	}
+
+	sources := &dataSources{}
+	var visit func(bean springBean)
+	visit = func(bean springBean) {
+		switch className(bean.Class) {
+		case "GtfsBundle":
+			if p, ok := bean.property("url"); ok && sources.GtfsURL == "" {
+				sources.GtfsURL = resolved(p.value())
+			}
+		case "GtfsRealtimeSource":
+			rt := realtimeSource{Headers: make(map[string]string)}
+			if p, ok := bean.property("tripUpdatesUrl"); ok {
+				rt.TripUpdatesURL = resolved(p.value())
+			}
+			if p, ok := bean.property("vehiclePositionsUrl"); ok {
+				rt.VehiclePositionURL = resolved(p.value())
+			}
+			if p, ok := bean.property("agencyId"); ok {
+				rt.AgencyID = resolved(p.value())
+			} else if p, ok := bean.property("agencyIds"); ok && len(p.ListValues) > 0 {
+				rt.AgencyID = resolved(strings.TrimSpace(p.ListValues[0]))
+			}
+			if p, ok := bean.property("headersMap"); ok {
+				for _, entry := range p.Entries {
+					if value := resolved(entry.Value); entry.Key != "" && value != "" {
+						rt.Headers[entry.Key] = value
+					}
+				}
+			}
+			sources.Realtime = append(sources.Realtime, rt)
+		}
+
+		for _, p := range bean.Properties {
+			for _, nested := range p.ListBeans {
+				visit(nested)
+			}
+			if p.Bean != nil {
+				visit(*p.Bean)
+			}
+		}
+	}
+	for _, bean := range doc.Beans {
+		visit(bean)
+	}
+	return sources, nil
+}
+
+func className(class string) string {
+	return class[strings.LastIndex(class, ".")+1:]
+}
+
+// resolved returns value, or "" if it is an unresolved Spring placeholder.
+func resolved(value string) string {
+	if strings.Contains(value, "${") {
+		return ""
+	}
+	return value
+}
+
+// realtimeSourceFor returns the realtime source matching the agency, or the first one
+// if the agency is unset or not found.
+func (ds *dataSources) realtimeSourceFor(agencyID string) (realtimeSource, bool) {
+	if len(ds.Realtime) == 0 {
+		return realtimeSource{}, false
+	}
+	for _, rt := range ds.Realtime {
+		if agencyID != "" && rt.AgencyID == agencyID {
+			return rt, true
+		}
+	}
+	return ds.Realtime[0], true
+}
+
+// fetchDataSources downloads and parses the data-sources.xml published by an OBA instance.
+func fetchDataSources(ctx context.Context, client *http.Client, url string) (*dataSources, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, url, nil)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create request for %s: %w", url, err)
+	}
+	resp, err := client.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("failed to fetch data sources from %s: %w", url, err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("data sources %s returned status: %d", url, resp.StatusCode)
+	}
+	data, err := io.ReadAll(io.LimitReader(resp.Body, maxDataSourcesSize))
+	if err != nil {
+		return nil, fmt.Errorf("failed to read data sources from %s: %w", url, err)
+	}
+	return parseDataSources(data)
+}
+
+// resolveDataSources fills in each server's feed URLs from the data-sources.xml of its
+// OBA instance, for servers that set `oba_data_sources_url`.
+//
+// Values in the watchdog config always take precedence, so a field is only derived when it is
+// empty. When a configured value differs from the one OBA is actually using, a warning is logged
+// so drift between the watchdog and OBA configuration is visible.
+//
+// The GTFS-RT API key and value are taken from the first header of the realtime source's
+// headersMap, and only if neither is configured.
+//
+// A server whose data sources cannot be fetched keeps its configured values; the failure is
+// logged and reported, and other servers are not affected.
+func resolveDataSources(ctx context.Context, client *http.Client, servers []models.ObaServer, logger *slog.Logger) []models.ObaServer {
+	resolvedServers := make([]models.ObaServer, len(servers))
+	for i, server := range servers {
+		resolvedServers[i] = server
+		if server.DataSourcesURL == "" {
+			continue
+		}
+
+		sources, err := fetchDataSources(ctx, client, server.DataSourcesURL)
+		if err != nil {
+			report.ReportErrorWithSentryOptions(err, report.SentryReportOptions{
+				Tags: utils.MakeMap("server_id", strconv.Itoa(server.ID)),
+				ExtraContext: map[string]interface{}{
+					"oba_data_sources_url": server.DataSourcesURL,
+				},
+				Level: sentry.LevelWarning,
+			})
+			logger.Warn("Failed to read OBA data sources, using configured URLs", "server_id", server.ID, "error", err)
+			continue
+		}
+
+		s := &resolvedServers[i]
+		derive := func(field string, configured *string, derived string) {
+			switch {
+			case derived == "":
+			case *configured == "":
+				*configured = derived
+				logger.Info("Derived server setting from OBA data sources", "server_id", s.ID, "field", field)
+			case *configured != derived:
+				// Values are not logged, feed URLs may embed API keys.
+				logger.Warn("Configured server setting differs from OBA data sources", "server_id", s.ID, "field", field)
+			}
+		}
+
+		derive("gtfs_url", &s.GtfsUrl, sources.GtfsURL)
+		if rt, ok := sources.realtimeSourceFor(s.AgencyID); ok {
+			derive("agency_id", &s.AgencyID, rt.AgencyID)
+			derive("trip_update_url", &s.TripUpdateUrl, rt.TripUpdatesURL)
+			derive("vehicle_position_url", &s.VehiclePositionUrl, rt.VehiclePositionURL)
+			if s.GtfsRtApiKey == "" && s.GtfsRtApiValue == "" && len(rt.Headers) > 0 {
+				keys := make([]string, 0, len(rt.Headers))
+				for k := range rt.Headers {
+					keys = append(keys, k)
+				}
+				sort.Strings(keys)
+				s.GtfsRtApiKey, s.GtfsRtApiValue = keys[0], rt.Headers[keys[0]]
+				logger.Info("Derived server setting from OBA data sources", "server_id", s.ID, "field", "gtfs_rt_api_key")
+			}
+		}
+	}
+	return resolvedServers
+}
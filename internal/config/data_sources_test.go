@@ -0,0 +1,123 @@
+package config
+
+import (
+	"context"
+	"io"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"testing"
+
+	"watchdog.onebusaway.org/internal/models"
+)
+
+const testDataSourcesXML = `<?xml version="1.0" encoding="UTF-8"?>
+<beans xmlns="http://www.springframework.org/schema/beans">
+  <bean id="gtfsBundles" class="org.onebusaway.transit_data_federation.bundle.model.GtfsBundles">
+    <property name="bundles">
+      <list>
+        <bean class="org.onebusaway.transit_data_federation.bundle.model.GtfsBundle">
+          <property name="url" value="https://agency.example.com/gtfs.zip" />
+        </bean>
+      </list>
+    </property>
+  </bean>
+  <bean class="org.onebusaway.transit_data_federation.impl.realtime.gtfs_realtime.GtfsRealtimeSource">
+    <property name="tripUpdatesUrl" value="https://rt.example.com/metro/trip-updates" />
+    <property name="vehiclePositionsUrl" value="https://rt.example.com/metro/vehicle-positions" />
+    <property name="agencyId" value="metro" />
+    <property name="headersMap">
+      <map>
+        <entry key="x-api-key" value="secret" />
+      </map>
+    </property>
+  </bean>
+  <bean class="org.onebusaway.transit_data_federation.impl.realtime.gtfs_realtime.GtfsRealtimeSource">
+    <property name="tripUpdatesUrl"><value>https://rt.example.com/rail/trip-updates</value></property>
+    <property name="vehiclePositionsUrl" value="${rail.vehicle.url}" />
+    <property name="agencyIds">
+      <list>
+        <value>rail</value>
+      </list>
+    </property>
+  </bean>
+</beans>`
+
+func TestParseDataSources(t *testing.T) {
+	sources, err := parseDataSources([]byte(testDataSourcesXML))
+	if err != nil {
+		t.Fatalf("parseDataSources failed: %v", err)
+	}
+	if sources.GtfsURL != "https://agency.example.com/gtfs.zip" {
+		t.Errorf("unexpected GTFS URL %q", sources.GtfsURL)
+	}
+	if len(sources.Realtime) != 2 {
+		t.Fatalf("expected 2 realtime sources, got %d", len(sources.Realtime))
+	}
+
+	metro := sources.Realtime[0]
+	if metro.AgencyID != "metro" || metro.TripUpdatesURL != "https://rt.example.com/metro/trip-updates" || metro.Headers["x-api-key"] != "secret" {
+		t.Errorf("unexpected first realtime source: %+v", metro)
+	}
+
+	rail := sources.Realtime[1]
+	if rail.AgencyID != "rail" || rail.TripUpdatesURL != "https://rt.example.com/rail/trip-updates" {
+		t.Errorf("unexpected second realtime source: %+v", rail)
+	}
+	if rail.VehiclePositionURL != "" {
+		t.Errorf("expected an unresolved placeholder to be ignored, got %q", rail.VehiclePositionURL)
+	}
+
+	if _, err := parseDataSources([]byte("<beans><bean>")); err == nil {
+		t.Error("expected an error for malformed XML")
+	}
+}
+
+func TestResolveDataSources(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/data-sources.xml" {
+			w.WriteHeader(http.StatusNotFound)
+			return
+		}
+		_, _ = w.Write([]byte(testDataSourcesXML))
+	}))
+	defer server.Close()
+
+	logger := slog.New(slog.NewTextHandler(io.Discard, nil))
+	servers := []models.ObaServer{
+		{ID: 1, DataSourcesURL: server.URL + "/data-sources.xml"},
+		{ID: 2, DataSourcesURL: server.URL + "/data-sources.xml", AgencyID: "rail", GtfsUrl: "https://mirror.example.com/gtfs.zip"},
+		{ID: 3, DataSourcesURL: server.URL + "/missing.xml", TripUpdateUrl: "https://configured.example.com"},
+		{ID: 4, TripUpdateUrl: "https://untouched.example.com"},
+	}
+
+	resolved := ResolveDataSources(context.Background(), server.Client(), servers, logger)
+
+	if servers[0].GtfsUrl != "" {
+		t.Error("ResolveDataSources must not modify its input")
+	}
+
+	first := resolved[0]
+	if first.GtfsUrl != "https://agency.example.com/gtfs.zip" || first.AgencyID != "metro" ||
+		first.TripUpdateUrl != "https://rt.example.com/metro/trip-updates" ||
+		first.VehiclePositionUrl != "https://rt.example.com/metro/vehicle-positions" ||
+		first.GtfsRtApiKey != "x-api-key" || first.GtfsRtApiValue != "secret" {
+		t.Errorf("unexpected derived settings for server 1: %+v", first)
+	}
+
+	second := resolved[1]
+	if second.GtfsUrl != "https://mirror.example.com/gtfs.zip" {
+		t.Errorf("expected the configured GTFS URL to win, got %q", second.GtfsUrl)
+	}
+	if second.TripUpdateUrl != "https://rt.example.com/rail/trip-updates" || second.VehiclePositionUrl != "" {
+		t.Errorf("expected the realtime source of agency rail, got %+v", second)
+	}
+
+	if resolved[2].TripUpdateUrl != "https://configured.example.com" || resolved[2].GtfsUrl != "" {
+		t.Errorf("expected server 3 to keep its configuration when data sources fail, got %+v", resolved[2])
+	}
+	if !reflect.DeepEqual(resolved[3], servers[3]) {
+		t.Errorf("expected server 4 without data sources to be unchanged, got %+v", resolved[3])
+	}
+}
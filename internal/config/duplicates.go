@@ -0,0 +1,79 @@
+package config
+
+import (
+	"cmp"
+	"log/slog"
+	"net/url"
+	"slices"
+	"strings"
+
+	"watchdog.onebusaway.org/internal/models"
+)
+
+// DuplicateURL is a URL configured for more than one server, usually after a server entry
+// was copied and not fully edited. Such servers are probed twice and raise the same alerts.
+type DuplicateURL struct {
+	// Field is the setting the servers share: "oba_base_url" or "gtfs_url".
+	Field string
+	URL   string
+	// ServerIDs are the servers configured with URL, in configuration order.
+	ServerIDs []int
+}
+
+// normalizeURL makes URLs that only differ in letter case of the scheme and host, or in a
+// trailing slash, compare equal. URLs that cannot be parsed are compared as is.
+func normalizeURL(raw string) string {
+	u, err := url.Parse(strings.TrimSpace(raw))
+	if err != nil {
+		return raw
+	}
+	u.Scheme = strings.ToLower(u.Scheme)
+	u.Host = strings.ToLower(u.Host)
+	u.Path = strings.TrimSuffix(u.Path, "/")
+	return u.String()
+}
+
+// FindDuplicateServers returns the OBA base URLs and GTFS URLs that are configured for more
+// than one server, sorted by field and URL. Empty URLs are ignored.
+func FindDuplicateServers(servers []models.ObaServer) []DuplicateURL {
+	var duplicates []DuplicateURL
+	fields := []struct {
+		name  string
+		value func(models.ObaServer) string
+	}{
+		{"oba_base_url", func(s models.ObaServer) string { return s.ObaBaseURL }},
+		{"gtfs_url", func(s models.ObaServer) string { return s.GtfsUrl }},
+	}
+	for _, field := range fields {
+		groups := make(map[string]*DuplicateURL)
+		for _, server := range servers {
+			value := field.value(server)
+			if value == "" {
+				continue
+			}
+			key := normalizeURL(value)
+			if groups[key] == nil {
+				groups[key] = &DuplicateURL{Field: field.name, URL: value}
+			}
+			groups[key].ServerIDs = append(groups[key].ServerIDs, server.ID)
+		}
+		for _, group := range groups {
+			if len(group.ServerIDs) > 1 {
+				duplicates = append(duplicates, *group)
+			}
+		}
+	}
+	slices.SortFunc(duplicates, func(a, b DuplicateURL) int {
+		return cmp.Or(cmp.Compare(a.Field, b.Field), cmp.Compare(a.URL, b.URL))
+	})
+	return duplicates
+}
+
+// WarnDuplicateServers logs a warning for each URL configured for more than one server.
+// Duplicates are not rejected, as two entries may legitimately monitor one OBA instance
+// with different settings.
+func WarnDuplicateServers(servers []models.ObaServer, logger *slog.Logger) {
+	for _, duplicate := range FindDuplicateServers(servers) {
+		logger.Warn("Several servers are configured with the same URL", "field", duplicate.Field, "url", duplicate.URL, "server_ids", duplicate.ServerIDs)
+	}
+}
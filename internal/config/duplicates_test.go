@@ -0,0 +1,28 @@
+package config
+
+import (
+	"reflect"
+	"testing"
+
+	"watchdog.onebusaway.org/internal/models"
+)
+
+func TestFindDuplicateServers(t *testing.T) {
+	servers := []models.ObaServer{
+		{ID: 1, ObaBaseURL: "https://api.example.com", GtfsUrl: "https://example.com/gtfs.zip"},
+		{ID: 2, ObaBaseURL: "https://API.example.com/", GtfsUrl: "https://example.com/gtfs.zip?agency=2"},
+		{ID: 3, ObaBaseURL: "https://other.example.com", GtfsUrl: "https://example.com/gtfs.zip"},
+		{ID: 4, ObaBaseURL: "https://fourth.example.com"},
+		{ID: 5, ObaBaseURL: "https://api.example.com"},
+	}
+	expected := []DuplicateURL{
+		{Field: "gtfs_url", URL: "https://example.com/gtfs.zip", ServerIDs: []int{1, 3}},
+		{Field: "oba_base_url", URL: "https://api.example.com", ServerIDs: []int{1, 2, 5}},
+	}
+	if got := FindDuplicateServers(servers); !reflect.DeepEqual(got, expected) {
+		t.Errorf("expected %+v, got %+v", expected, got)
+	}
+	if got := FindDuplicateServers(servers[3:4]); got != nil {
+		t.Errorf("expected no duplicates, got %+v", got)
+	}
+}
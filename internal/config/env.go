@@ -0,0 +1,101 @@
+package config
+
+import (
+	"fmt"
+	"os"
+	"reflect"
+	"regexp"
+	"strings"
+
+	"watchdog.onebusaway.org/internal/models"
+)
+
+// envPlaceholder matches the ${VAR} and ${VAR:-default} placeholders of config values, and the
+// $${ escape of a literal "${".
+var envPlaceholder = regexp.MustCompile(`\$\$\{|\$\{([A-Za-z_][A-Za-z0-9_]*)(?::-([^}]*))?\}`)
+
+// ExpandEnv replaces the environment variable placeholders in the string settings of servers,
+// in place, so that secrets such as API keys can be kept out of the configuration:
+//   - ${VAR} is replaced by the value of VAR, which must be set (it may be empty);
+//   - ${VAR:-default} is replaced by the value of VAR, or default if VAR is unset or empty;
+//   - $${ is replaced by a literal "${".
+//
+// Other "$" characters are left alone. Every unset variable is reported, as a ValidationErrors
+// naming the server and the field.
+func ExpandEnv(servers []models.ObaServer) error {
+	return expandEnv(servers, os.LookupEnv)
+}
+
+func expandEnv(servers []models.ObaServer, lookup func(string) (string, bool)) error {
+	var problems ValidationErrors
+	for i := range servers {
+		expandValue(reflect.ValueOf(&servers[i]).Elem(), "", func(field, value string) string {
+			return envPlaceholder.ReplaceAllStringFunc(value, func(placeholder string) string {
+				if placeholder == "$${" {
+					return "${"
+				}
+				match := envPlaceholder.FindStringSubmatch(placeholder)
+				name, hasDefault := match[1], strings.Contains(placeholder, ":-")
+				value, ok := lookup(name)
+				switch {
+				case hasDefault && value == "":
+					return match[2]
+				case !ok:
+					problems = append(problems, ValidationError{Index: i, ServerID: servers[i].ID, Field: field,
+						Message: fmt.Sprintf("environment variable %s is not set", name)})
+				}
+				return value
+			})
+		})
+	}
+	if len(problems) > 0 {
+		return problems
+	}
+	return nil
+}
+
+// expandValue calls expand on every string reachable from v, which must be settable, and stores
+// the result. path is the JSON path of v, e.g. "alerts.webhook_url" or "prediction_stops[1]".
+func expandValue(v reflect.Value, path string, expand func(field, value string) string) {
+	switch v.Kind() {
+	case reflect.String:
+		if strings.Contains(v.String(), "${") {
+			v.SetString(expand(path, v.String()))
+		}
+	case reflect.Pointer:
+		if !v.IsNil() {
+			expandValue(v.Elem(), path, expand)
+		}
+	case reflect.Slice:
+		for i := 0; i < v.Len(); i++ {
+			expandValue(v.Index(i), fmt.Sprintf("%s[%d]", path, i), expand)
+		}
+	case reflect.Map:
+		// Map values cannot be set in place: expand a copy and store it back.
+		iter := v.MapRange()
+		for iter.Next() {
+			value := reflect.New(iter.Value().Type()).Elem()
+			value.Set(iter.Value())
+			expandValue(value, fmt.Sprintf("%s.%v", path, iter.Key()), expand)
+			v.SetMapIndex(iter.Key(), value)
+		}
+	case reflect.Struct:
+		for i := 0; i < v.NumField(); i++ {
+			field := v.Type().Field(i)
+			if !field.IsExported() {
+				continue
+			}
+			name, _, _ := strings.Cut(field.Tag.Get("json"), ",")
+			if name == "-" {
+				continue
+			}
+			if name == "" {
+				name = field.Name
+			}
+			if path != "" {
+				name = path + "." + name
+			}
+			expandValue(v.Field(i), name, expand)
+		}
+	}
+}
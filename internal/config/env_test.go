@@ -0,0 +1,88 @@
+package config
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"watchdog.onebusaway.org/internal/models"
+)
+
+func TestExpandEnv(t *testing.T) {
+	env := map[string]string{"OBA_KEY": "secret", "HOST": "oba.example.com", "EMPTY": ""}
+	lookup := func(name string) (string, bool) {
+		value, ok := env[name]
+		return value, ok
+	}
+	servers := []models.ObaServer{{
+		ID:              1,
+		Name:            "Costs $5",
+		ObaBaseURL:      "https://${HOST}/",
+		ObaApiKey:       "${OBA_KEY}",
+		GtfsRtApiValue:  "${EMPTY}",
+		GtfsUrl:         "${GTFS_URL:-https://gtfs.example.com/gtfs.zip}",
+		TripUpdateUrl:   "${EMPTY:-https://rt.example.com}",
+		AgencyID:        "$${NOT_EXPANDED}",
+		PredictionStops: []string{"1_${OBA_KEY}"},
+		Alerts: &models.AlertConfig{
+			WebhookURL: "https://hooks.example.com/${OBA_KEY}",
+			Checks:     map[string]models.AlertCheckConfig{"api_down": {}},
+		},
+	}}
+	if err := expandEnv(servers, lookup); err != nil {
+		t.Fatalf("expandEnv failed: %v", err)
+	}
+	got := servers[0]
+	for field, pair := range map[string][2]string{
+		"name":               {got.Name, "Costs $5"},
+		"oba_base_url":       {got.ObaBaseURL, "https://oba.example.com/"},
+		"oba_api_key":        {got.ObaApiKey, "secret"},
+		"gtfs_rt_api_value":  {got.GtfsRtApiValue, ""},
+		"gtfs_url":           {got.GtfsUrl, "https://gtfs.example.com/gtfs.zip"},
+		"trip_update_url":    {got.TripUpdateUrl, "https://rt.example.com"},
+		"agency_id":          {got.AgencyID, "${NOT_EXPANDED}"},
+		"prediction_stops":   {got.PredictionStops[0], "1_secret"},
+		"alerts.webhook_url": {got.Alerts.WebhookURL, "https://hooks.example.com/secret"},
+	} {
+		if pair[0] != pair[1] {
+			t.Errorf("%s: expected %q, got %q", field, pair[1], pair[0])
+		}
+	}
+
+	servers = []models.ObaServer{{ID: 2, ObaApiKey: "${MISSING_KEY}", AgencyContact: &models.AgencyContact{Email: "${MISSING_EMAIL}"}}}
+	err := expandEnv(servers, lookup)
+	var problems ValidationErrors
+	if !errors.As(err, &problems) || len(problems) != 2 {
+		t.Fatalf("expected 2 problems, got %v", err)
+	}
+	if problems[0].Error() != "servers[0] (id 2): oba_api_key: environment variable MISSING_KEY is not set" ||
+		problems[1].Field != "agency_contact.email" {
+		t.Errorf("unexpected problems: %v", problems)
+	}
+}
+
+func TestUpdateConfigFileKeepsPlaceholders(t *testing.T) {
+	t.Setenv("WATCHDOG_TEST_OBA_KEY", "secret")
+	fp := filepath.Join(t.TempDir(), "config.json")
+	content := `[{"name": "Test Server", "id": 1, "oba_base_url": "https://test.example.com", "oba_api_key": "${WATCHDOG_TEST_OBA_KEY}"}]`
+	if err := os.WriteFile(fp, []byte(content), 0o600); err != nil {
+		t.Fatal(err)
+	}
+
+	servers, err := LoadConfigFromFile(fp)
+	if err != nil || servers[0].ObaApiKey != "secret" {
+		t.Fatalf("expected the API key to be expanded, got %+v, %v", servers, err)
+	}
+	if err := AddServerToFile(fp, models.ObaServer{ID: 2, Name: "Added", ObaBaseURL: "https://added.example.com"}); err != nil {
+		t.Fatal(err)
+	}
+	data, err := os.ReadFile(fp)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if strings.Contains(string(data), "secret") || !strings.Contains(string(data), "${WATCHDOG_TEST_OBA_KEY}") {
+		t.Errorf("expected the placeholder to be kept in the file, got %s", data)
+	}
+}
@@ -0,0 +1,24 @@
+package config
+
+import (
+	"net/url"
+	"path/filepath"
+	"strings"
+)
+
+// LocalBundlePath returns the path of a GTFS bundle URL that designates a local file: a path,
+// absolute or relative to the working directory, or a file:// URL (e.g.
+// file:///srv/gtfs/bundle.zip). It returns false for any other URL.
+func LocalBundlePath(rawURL string) (string, bool) {
+	if rawURL == "" {
+		return "", false
+	}
+	if !strings.Contains(rawURL, "://") && !strings.HasPrefix(strings.ToLower(rawURL), "file:") {
+		return rawURL, true
+	}
+	u, err := url.Parse(rawURL)
+	if err != nil || !strings.EqualFold(u.Scheme, "file") || (u.Host != "" && u.Host != "localhost") || u.Path == "" {
+		return "", false
+	}
+	return filepath.FromSlash(u.Path), true
+}
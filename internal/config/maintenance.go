@@ -0,0 +1,118 @@
+package config
+
+import (
+	"context"
+	"slices"
+	"sync"
+	"time"
+
+	"watchdog.onebusaway.org/internal/models"
+	"watchdog.onebusaway.org/internal/sharedstore"
+)
+
+// MaintenanceStore holds the maintenance windows of servers declared with the admin API, which
+// apply on top of the `maintenance_windows` of their configuration. Windows are forgotten once
+// they end. It is safe for concurrent use; a nil store has no declared windows, so only the
+// configured ones apply.
+type MaintenanceStore struct {
+	mu      sync.RWMutex
+	windows map[int][]models.MaintenanceWindow
+	// replica shares the declared windows with the other instances (see Share); nil if they
+	// are not shared.
+	replica *sharedstore.Replica[[]models.MaintenanceWindow]
+}
+
+// NewMaintenanceStore creates an empty MaintenanceStore.
+func NewMaintenanceStore() *MaintenanceStore {
+	return &MaintenanceStore{windows: make(map[int][]models.MaintenanceWindow)}
+}
+
+// Add declares a maintenance window of a server, forgetting its windows that ended before now.
+func (s *MaintenanceStore) Add(serverID int, window models.MaintenanceWindow, now time.Time) {
+	s.mu.Lock()
+	windows := []models.MaintenanceWindow{window}
+	for _, w := range s.windows[serverID] {
+		if w.End.After(now) {
+			windows = append(windows, w)
+		}
+	}
+	s.windows[serverID] = windows
+	s.mu.Unlock()
+	s.replica.Publish(serverID, windows)
+}
+
+// Clear removes the declared maintenance windows of a server, ending its maintenance unless a
+// configured window is in effect, and returns the number of windows removed.
+func (s *MaintenanceStore) Clear(serverID int) int {
+	if s == nil {
+		return 0
+	}
+	s.mu.Lock()
+	removed := len(s.windows[serverID])
+	delete(s.windows, serverID)
+	s.mu.Unlock()
+	if removed > 0 {
+		s.replica.Unpublish(serverID)
+	}
+	return removed
+}
+
+// Windows returns the maintenance windows of a server that have not ended at now, configured
+// and declared, by start time.
+func (s *MaintenanceStore) Windows(server models.ObaServer, now time.Time) []models.MaintenanceWindow {
+	var windows []models.MaintenanceWindow
+	for _, w := range server.MaintenanceWindows {
+		if w.End.After(now) {
+			windows = append(windows, w)
+		}
+	}
+	if s != nil {
+		s.mu.RLock()
+		for _, w := range s.windows[server.ID] {
+			if w.End.After(now) {
+				windows = append(windows, w)
+			}
+		}
+		s.mu.RUnlock()
+	}
+	slices.SortStableFunc(windows, func(a, b models.MaintenanceWindow) int { return a.Start.Compare(b.Start) })
+	return windows
+}
+
+// Active returns the maintenance window of a server in effect at a point in time, configured or
+// declared, and false if the server is not in maintenance.
+func (s *MaintenanceStore) Active(server models.ObaServer, at time.Time) (models.MaintenanceWindow, bool) {
+	for _, w := range s.Windows(server, at) {
+		if w.Contains(at) {
+			return w, true
+		}
+	}
+	return models.MaintenanceWindow{}, false
+}
+
+// Share publishes the windows declared from now on to backend, for the other instances to sync
+// (see Sync), so that a window declared through any instance applies to the one running the
+// checks. It must be called before the store is used.
+func (s *MaintenanceStore) Share(backend sharedstore.Backend) {
+	if s == nil {
+		return
+	}
+	s.replica = sharedstore.NewReplica[[]models.MaintenanceWindow]("maintenance", backend)
+}
+
+// Sync stores the windows of the given servers declared or cleared by the other instances since
+// the last sync. It does nothing if the store is not shared.
+func (s *MaintenanceStore) Sync(ctx context.Context, serverIDs []int) error {
+	if s == nil {
+		return nil
+	}
+	return s.replica.Sync(ctx, serverIDs, func(serverID int, windows []models.MaintenanceWindow) {
+		s.mu.Lock()
+		defer s.mu.Unlock()
+		s.windows[serverID] = windows
+	}, func(serverID int) {
+		s.mu.Lock()
+		defer s.mu.Unlock()
+		delete(s.windows, serverID)
+	})
+}
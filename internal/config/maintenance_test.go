@@ -0,0 +1,47 @@
+package config
+
+import (
+	"testing"
+	"time"
+
+	"watchdog.onebusaway.org/internal/models"
+)
+
+func TestMaintenanceStore(t *testing.T) {
+	now := time.Date(2026, 5, 1, 3, 0, 0, 0, time.UTC)
+	configured := models.MaintenanceWindow{Start: now.Add(2 * time.Hour), End: now.Add(3 * time.Hour), Reason: "configured"}
+	server := models.ObaServer{ID: 1, MaintenanceWindows: []models.MaintenanceWindow{
+		{Start: now.Add(-3 * time.Hour), End: now.Add(-2 * time.Hour)},
+		configured,
+	}}
+
+	var none *MaintenanceStore
+	if _, ok := none.Active(server, now); ok {
+		t.Error("expected no maintenance now")
+	}
+	if window, ok := none.Active(server, configured.Start); !ok || window != configured {
+		t.Errorf("expected the configured window to apply without a store, got %+v", window)
+	}
+
+	store := NewMaintenanceStore()
+	store.Add(1, models.MaintenanceWindow{Start: now.Add(-time.Hour), End: now.Add(-time.Minute)}, now.Add(-time.Hour))
+	declared := models.MaintenanceWindow{Start: now.Add(-time.Minute), End: now.Add(time.Hour), Reason: "declared"}
+	store.Add(1, declared, now)
+	if window, ok := store.Active(server, now); !ok || window != declared {
+		t.Errorf("expected the declared window, got %+v, %v", window, ok)
+	}
+	// The ended windows are left out, the others are sorted by start.
+	if windows := store.Windows(server, now); len(windows) != 2 || windows[0] != declared || windows[1] != configured {
+		t.Errorf("unexpected windows %+v", windows)
+	}
+	if _, ok := store.Active(models.ObaServer{ID: 2}, now); ok {
+		t.Error("expected server 2 not to be in maintenance")
+	}
+
+	if removed := store.Clear(1); removed != 1 {
+		t.Errorf("expected the ended window to be forgotten, removed %d", removed)
+	}
+	if _, ok := store.Active(server, now); ok {
+		t.Error("expected the maintenance to end")
+	}
+}
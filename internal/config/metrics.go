@@ -0,0 +1,26 @@
+package config
+
+import (
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promauto"
+)
+
+var (
+	// ConfigReloads counts the refreshes of the remote configuration, by result (success,
+	// failure). A refresh that finds the configuration unchanged is a success.
+	ConfigReloads = promauto.NewCounterVec(
+		prometheus.CounterOpts{
+			Name: "config_reload_total",
+			Help: "Refreshes of the remote configuration, by result (success, failure); unchanged configurations count as successes",
+		},
+		[]string{"result"},
+	)
+
+	// ConfigLastRefreshTimestamp is when the remote configuration was last loaded successfully.
+	ConfigLastRefreshTimestamp = promauto.NewGauge(
+		prometheus.GaugeOpts{
+			Name: "watchdog_config_last_refresh_timestamp",
+			Help: "Unix time of the last successful load of the remote configuration, on startup or by a refresh",
+		},
+	)
+)
@@ -0,0 +1,128 @@
+package config
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+
+	"watchdog.onebusaway.org/internal/models"
+)
+
+// DefaultRegionsURL is the regions API of the OneBusAway multi-region apps.
+const DefaultRegionsURL = "https://regions.onebusaway.org/regions-v3.json"
+
+// maxRegionsSize bounds the size of a regions API response.
+const maxRegionsSize = 4 << 20
+
+// Region is a region of the OneBusAway regions API, with the fields used to configure a server.
+type Region struct {
+	ID           int    `json:"id"`
+	Name         string `json:"regionName"`
+	ObaBaseURL   string `json:"obaBaseUrl"`
+	Active       bool   `json:"active"`
+	Experimental bool   `json:"experimental"`
+	ContactEmail string `json:"contactEmail"`
+}
+
+// FetchRegions returns the regions listed by the regions API at url.
+func FetchRegions(ctx context.Context, client *http.Client, url string) ([]Region, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, url, nil)
+	if err != nil {
+		return nil, fmt.Errorf("invalid regions URL: %w", err)
+	}
+	resp, err := client.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("failed to fetch the regions: %w", err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("the regions API returned status: %d", resp.StatusCode)
+	}
+	var body struct {
+		Data struct {
+			List []Region `json:"list"`
+		} `json:"data"`
+	}
+	if err := json.NewDecoder(io.LimitReader(resp.Body, maxRegionsSize)).Decode(&body); err != nil {
+		return nil, fmt.Errorf("failed to decode the regions: %w", err)
+	}
+	return body.Data.List, nil
+}
+
+// RegionServers returns a configuration skeleton with a server for each region with an OBA
+// base URL: its name, id and region are those of the region, and its agency contact the
+// contact email of the region. The regions API does not publish the GTFS and GTFS-RT feeds or
+// the API keys of the regions, so they are left empty to be filled in, e.g. with
+// oba_data_sources_url. Inactive and experimental regions are left out unless asked for.
+func RegionServers(regions []Region, includeInactive, includeExperimental bool) []models.ObaServer {
+	var servers []models.ObaServer
+	for _, region := range regions {
+		if (!region.Active && !includeInactive) || (region.Experimental && !includeExperimental) {
+			continue
+		}
+		baseURL := normalizeObaBaseURL(region.ObaBaseURL)
+		if baseURL == "" {
+			continue
+		}
+		server := models.ObaServer{
+			Name:       region.Name,
+			ID:         region.ID,
+			ObaBaseURL: baseURL,
+			Region:     region.Name,
+		}
+		if region.ContactEmail != "" {
+			server.AgencyContact = &models.AgencyContact{Email: region.ContactEmail}
+		}
+		servers = append(servers, server)
+	}
+	return servers
+}
+
+// normalizeObaBaseURL returns the OBA base URL of the regions API without the /api path and
+// the trailing slash it usually ends with, as the oba_base_url of a server.
+func normalizeObaBaseURL(raw string) string {
+	baseURL := strings.TrimSuffix(strings.TrimSpace(raw), "/")
+	return strings.TrimSuffix(baseURL, "/api")
+}
+
+// MergeServers merges the imported servers into the existing ones, and returns the merged
+// servers with the number of servers added. An imported server is matched with an existing one
+// by its OBA base URL: the existing server is kept as configured, with only its empty name and
+// region filled in. The other imported servers are appended, with their id unless it is
+// already used, in which case they are given the next free id.
+func MergeServers(existing, imported []models.ObaServer) ([]models.ObaServer, int) {
+	merged := append([]models.ObaServer(nil), existing...)
+	byBaseURL := make(map[string]int)
+	used := make(map[int]bool)
+	maxID := 0
+	for i, server := range merged {
+		byBaseURL[normalizeObaBaseURL(server.ObaBaseURL)] = i
+		used[server.ID] = true
+		maxID = max(maxID, server.ID)
+	}
+
+	added := 0
+	for _, server := range imported {
+		if i, ok := byBaseURL[server.ObaBaseURL]; ok {
+			if merged[i].Name == "" {
+				merged[i].Name = server.Name
+			}
+			if merged[i].Region == "" {
+				merged[i].Region = server.Region
+			}
+			continue
+		}
+		if server.ID <= 0 || used[server.ID] {
+			server.ID = maxID + 1
+		}
+		used[server.ID] = true
+		maxID = max(maxID, server.ID)
+		byBaseURL[server.ObaBaseURL] = len(merged)
+		merged = append(merged, server)
+		added++
+	}
+	return merged, added
+}
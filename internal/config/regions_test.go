@@ -0,0 +1,71 @@
+package config
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"watchdog.onebusaway.org/internal/models"
+)
+
+const regionsResponse = `{"code": 200, "version": 3, "data": {"list": [
+  {"id": 1, "regionName": "Puget Sound", "obaBaseUrl": "https://api.pugetsound.onebusaway.org/", "active": true, "experimental": false, "contactEmail": "info@example.org"},
+  {"id": 2, "regionName": "Tampa Bay", "obaBaseUrl": "http://api.tampa.onebusaway.org/api/", "active": true, "experimental": false},
+  {"id": 3, "regionName": "Retired", "obaBaseUrl": "https://retired.example.org/", "active": false, "experimental": false},
+  {"id": 4, "regionName": "Lab", "obaBaseUrl": "https://lab.example.org/", "active": true, "experimental": true},
+  {"id": 5, "regionName": "No API", "obaBaseUrl": null, "active": true, "experimental": false}
+]}}`
+
+func TestImportRegions(t *testing.T) {
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte(regionsResponse))
+	}))
+	defer ts.Close()
+
+	regions, err := FetchRegions(context.Background(), ts.Client(), ts.URL)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(regions) != 5 {
+		t.Fatalf("expected 5 regions, got %d", len(regions))
+	}
+
+	servers := RegionServers(regions, false, false)
+	if len(servers) != 2 {
+		t.Fatalf("expected the 2 active, non-experimental regions with an API, got %+v", servers)
+	}
+	if servers[0].ObaBaseURL != "https://api.pugetsound.onebusaway.org" || servers[1].ObaBaseURL != "http://api.tampa.onebusaway.org" {
+		t.Errorf("expected normalized OBA base URLs, got %q and %q", servers[0].ObaBaseURL, servers[1].ObaBaseURL)
+	}
+	if servers[0].Name != "Puget Sound" || servers[0].Region != "Puget Sound" || servers[0].AgencyContact == nil || servers[0].AgencyContact.Email != "info@example.org" {
+		t.Errorf("unexpected server %+v", servers[0])
+	}
+	if servers := RegionServers(regions, true, true); len(servers) != 4 {
+		t.Errorf("expected the inactive and experimental regions to be included, got %d servers", len(servers))
+	}
+
+	existing := []models.ObaServer{
+		{ID: 2, Name: "Seattle", ObaBaseURL: "https://api.pugetsound.onebusaway.org/", GtfsUrl: "https://example.org/gtfs.zip"},
+	}
+	merged, added := MergeServers(existing, servers)
+	if added != 1 || len(merged) != 2 {
+		t.Fatalf("expected one server to be added, got %d: %+v", added, merged)
+	}
+	if merged[0].Name != "Seattle" || merged[0].GtfsUrl != "https://example.org/gtfs.zip" || merged[0].Region != "Puget Sound" {
+		t.Errorf("expected the existing server to be kept with its region filled in, got %+v", merged[0])
+	}
+	if merged[1].Name != "Tampa Bay" || merged[1].ID != 3 {
+		t.Errorf("expected the new server to get the next free id, got %+v", merged[1])
+	}
+}
+
+func TestFetchRegionsError(t *testing.T) {
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		http.Error(w, "down", http.StatusBadGateway)
+	}))
+	defer ts.Close()
+	if _, err := FetchRegions(context.Background(), ts.Client(), ts.URL); err == nil {
+		t.Error("expected an error for a failed request")
+	}
+}
@@ -0,0 +1,154 @@
+package config
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+
+	"github.com/getsentry/sentry-go"
+	"watchdog.onebusaway.org/internal/models"
+	"watchdog.onebusaway.org/internal/report"
+	"watchdog.onebusaway.org/internal/scheduler"
+	"watchdog.onebusaway.org/internal/secrets"
+	"watchdog.onebusaway.org/internal/utils"
+)
+
+// secretFields are the settings of servers that can reference a secret of a secrets backend
+// instead of holding its value.
+var secretFields = []struct {
+	name  string
+	value func(*models.ObaServer) *string
+}{
+	{"oba_api_key", func(s *models.ObaServer) *string { return &s.ObaApiKey }},
+	{"gtfs_rt_api_value", func(s *models.ObaServer) *string { return &s.GtfsRtApiValue }},
+}
+
+// ResolveSecrets replaces the secret references (see package secrets) in the oba_api_key and
+// gtfs_rt_api_value of servers by the values of the secrets, in place, and records the
+// references in SecretRefs for RefreshSecrets. Each secret is read once, however many servers
+// reference it. Every reference that cannot be resolved is reported, as a ValidationErrors
+// naming the server and the field; a nil resolver leaves servers untouched.
+func ResolveSecrets(ctx context.Context, resolver *secrets.Resolver, servers []models.ObaServer) error {
+	if resolver == nil {
+		return nil
+	}
+	resolved := make(map[string]string)
+	var problems ValidationErrors
+	for i := range servers {
+		server := &servers[i]
+		for _, field := range secretFields {
+			value := field.value(server)
+			if !resolver.IsReference(*value) {
+				continue
+			}
+			ref := *value
+			secret, ok := resolved[ref]
+			if !ok {
+				var err error
+				if secret, err = resolver.Resolve(ctx, ref); err != nil {
+					problems = append(problems, ValidationError{Index: i, ServerID: server.ID, Field: field.name,
+						Message: fmt.Sprintf("failed to resolve secret %s: %v", ref, err)})
+					continue
+				}
+				resolved[ref] = secret
+			}
+			if server.SecretRefs == nil {
+				server.SecretRefs = make(map[string]string)
+			}
+			server.SecretRefs[field.name] = ref
+			*value = secret
+		}
+	}
+	if len(problems) > 0 {
+		return problems
+	}
+	return nil
+}
+
+// secretUpdate is a new value of a setting of a server, read from a rotated secret.
+type secretUpdate struct {
+	serverID int
+	field    string
+	ref      string
+	value    string
+}
+
+// refreshSecrets resolves the secret references of the servers of cfg again, and updates the
+// settings whose secret was rotated. A secret that cannot be read is reported, and the server
+// keeps its current value until the next refresh.
+func refreshSecrets(ctx context.Context, resolver *secrets.Resolver, cfg *Config, logger *slog.Logger) {
+	resolved := make(map[string]string)
+	failed := make(map[string]bool)
+	var updates []secretUpdate
+	for _, server := range cfg.GetServers() {
+		for _, field := range secretFields {
+			ref, ok := server.SecretRefs[field.name]
+			if !ok || failed[ref] {
+				continue
+			}
+			secret, ok := resolved[ref]
+			if !ok {
+				var err error
+				if secret, err = resolver.Resolve(ctx, ref); err != nil {
+					failed[ref] = true
+					err = fmt.Errorf("failed to refresh secret %s: %w", ref, err)
+					report.ReportErrorWithSentryOptions(err, report.SentryReportOptions{
+						Tags:  utils.MakeMap("server_id", fmt.Sprint(server.ID)),
+						Level: sentry.LevelWarning,
+					})
+					logger.Warn("Failed to refresh secret, keeping the current value", "server_id", server.ID, "field", field.name, "error", err)
+					continue
+				}
+				resolved[ref] = secret
+			}
+			if secret != *field.value(&server) {
+				updates = append(updates, secretUpdate{serverID: server.ID, field: field.name, ref: ref, value: secret})
+			}
+		}
+	}
+	for _, update := range cfg.applySecretUpdates(updates) {
+		logger.Info("Secret rotated, updated the server", "server_id", update.serverID, "field", update.field)
+	}
+}
+
+// applySecretUpdates sets the settings of updates, unless the server was reconfigured with
+// another reference in the meantime, and returns the updates applied. Servers are replaced by
+// updated copies, so the copies returned by GetServers are never modified.
+func (cfg *Config) applySecretUpdates(updates []secretUpdate) []secretUpdate {
+	if len(updates) == 0 {
+		return nil
+	}
+	cfg.Mu.Lock()
+	defer cfg.Mu.Unlock()
+	var applied []secretUpdate
+	servers := append([]models.ObaServer(nil), cfg.Servers...)
+	for _, update := range updates {
+		for i := range servers {
+			server := &servers[i]
+			if server.ID != update.serverID || server.SecretRefs[update.field] != update.ref {
+				continue
+			}
+			for _, field := range secretFields {
+				if field.name == update.field {
+					*field.value(server) = update.value
+				}
+			}
+			applied = append(applied, update)
+		}
+	}
+	cfg.Servers = servers
+	return applied
+}
+
+// RefreshSecrets resolves the secret references of the configured servers again at every
+// activation of schedule, until ctx is canceled, so that rotated API keys are picked up
+// without a restart.
+func (cs *ConfigService) RefreshSecrets(ctx context.Context, schedule scheduler.Schedule) {
+	if cs.Secrets == nil {
+		return
+	}
+	scheduler.Run(ctx, "secrets_refresh", schedule, func() {
+		refreshSecrets(ctx, cs.Secrets, cs.Config, cs.Logger)
+	})
+	cs.Logger.Info("Stopping secrets refresh routine")
+}
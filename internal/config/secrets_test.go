@@ -0,0 +1,89 @@
+package config
+
+import (
+	"context"
+	"errors"
+	"io"
+	"log/slog"
+	"strings"
+	"testing"
+
+	"watchdog.onebusaway.org/internal/models"
+	"watchdog.onebusaway.org/internal/secrets"
+)
+
+// fakeSecrets is a secrets.Provider reading from a map, counting the reads.
+type fakeSecrets struct {
+	values map[string]string
+	reads  int
+}
+
+func (f *fakeSecrets) Resolve(_ context.Context, path, key string) (string, error) {
+	f.reads++
+	value, ok := f.values[path+"#"+key]
+	if !ok {
+		return "", errors.New("secret not found")
+	}
+	return value, nil
+}
+
+func newFakeResolver(values map[string]string) (*secrets.Resolver, *fakeSecrets) {
+	fake := &fakeSecrets{values: values}
+	resolver := secrets.NewResolver(nil)
+	resolver.Register("vault", fake)
+	return resolver, fake
+}
+
+func TestResolveSecrets(t *testing.T) {
+	resolver, fake := newFakeResolver(map[string]string{"secret/data/oba#api_key": "oba-key", "secret/data/rt#value": "rt-key"})
+	servers := []models.ObaServer{
+		{ID: 1, ObaApiKey: "vault://secret/data/oba#api_key", GtfsRtApiValue: "vault://secret/data/rt#value"},
+		{ID: 2, ObaApiKey: "vault://secret/data/oba#api_key", GtfsRtApiValue: "literal"},
+	}
+	if err := ResolveSecrets(context.Background(), resolver, servers); err != nil {
+		t.Fatalf("ResolveSecrets failed: %v", err)
+	}
+	if servers[0].ObaApiKey != "oba-key" || servers[0].GtfsRtApiValue != "rt-key" || servers[1].ObaApiKey != "oba-key" || servers[1].GtfsRtApiValue != "literal" {
+		t.Errorf("unexpected resolved servers %+v", servers)
+	}
+	if servers[0].SecretRefs["oba_api_key"] != "vault://secret/data/oba#api_key" || len(servers[1].SecretRefs) != 1 {
+		t.Errorf("expected the references to be recorded, got %v and %v", servers[0].SecretRefs, servers[1].SecretRefs)
+	}
+	if fake.reads != 2 {
+		t.Errorf("expected each secret to be read once, got %d reads", fake.reads)
+	}
+
+	servers = []models.ObaServer{{ID: 3, ObaApiKey: "vault://secret/data/missing#api_key"}}
+	err := ResolveSecrets(context.Background(), resolver, servers)
+	var problems ValidationErrors
+	if !errors.As(err, &problems) || len(problems) != 1 || problems[0].Field != "oba_api_key" || !strings.Contains(problems[0].Message, "secret not found") {
+		t.Errorf("expected the unresolved secret to be reported, got %v", err)
+	}
+}
+
+func TestRefreshSecrets(t *testing.T) {
+	resolver, fake := newFakeResolver(map[string]string{"secret/data/oba#api_key": "old-key", "secret/data/rt#value": "rt-key"})
+	servers := []models.ObaServer{{ID: 1, ObaApiKey: "vault://secret/data/oba#api_key", GtfsRtApiValue: "vault://secret/data/rt#value"}}
+	if err := ResolveSecrets(context.Background(), resolver, servers); err != nil {
+		t.Fatal(err)
+	}
+	cfg := NewConfig(4000, "test", servers)
+	before := cfg.GetServers()
+	logger := slog.New(slog.NewTextHandler(io.Discard, nil))
+
+	// The key is rotated, and the GTFS-RT secret can no longer be read.
+	fake.values["secret/data/oba#api_key"] = "new-key"
+	delete(fake.values, "secret/data/rt#value")
+	refreshSecrets(context.Background(), resolver, cfg, logger)
+
+	got := cfg.GetServers()[0]
+	if got.ObaApiKey != "new-key" {
+		t.Errorf("expected the rotated key, got %q", got.ObaApiKey)
+	}
+	if got.GtfsRtApiValue != "rt-key" {
+		t.Errorf("expected the value of the unreadable secret to be kept, got %q", got.GtfsRtApiValue)
+	}
+	if before[0].ObaApiKey != "old-key" {
+		t.Error("expected the servers returned before the refresh not to be modified")
+	}
+}
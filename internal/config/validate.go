@@ -0,0 +1,341 @@
+package config
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/mail"
+	"net/url"
+	"os"
+	"strings"
+
+	"watchdog.onebusaway.org/internal/models"
+)
+
+// ValidationError is a problem of one server of a configuration, found by ValidateServers or
+// ProbeServers.
+type ValidationError struct {
+	// Index is the position of the server in the configuration (-1 for the whole configuration).
+	Index int `json:"index"`
+	// ServerID is the id of the server, if it has one.
+	ServerID int `json:"server_id,omitempty"`
+	// Field is the JSON name of the offending setting, e.g. "gtfs_url" or "alerts.webhook_url".
+	Field   string `json:"field,omitempty"`
+	Message string `json:"message"`
+}
+
+func (e ValidationError) Error() string {
+	var b strings.Builder
+	if e.Index >= 0 {
+		fmt.Fprintf(&b, "servers[%d]", e.Index)
+		if e.ServerID != 0 {
+			fmt.Fprintf(&b, " (id %d)", e.ServerID)
+		}
+		b.WriteString(": ")
+	}
+	if e.Field != "" {
+		b.WriteString(e.Field + ": ")
+	}
+	b.WriteString(e.Message)
+	return b.String()
+}
+
+// ValidationErrors is the error of a configuration that failed ValidateServers, listing
+// every problem found.
+type ValidationErrors []ValidationError
+
+func (e ValidationErrors) Error() string {
+	problems := make([]string, len(e))
+	for i, problem := range e {
+		problems[i] = problem.Error()
+	}
+	return fmt.Sprintf("invalid configuration (%d problems): %s", len(e), strings.Join(problems, "; "))
+}
+
+// Validate returns the problems of servers found by ValidateServers as a ValidationErrors, or
+// nil if there are none.
+func Validate(servers []models.ObaServer) error {
+	if problems := ValidateServers(servers); len(problems) > 0 {
+		return ValidationErrors(problems)
+	}
+	return nil
+}
+
+// DisallowExecChecks returns a ValidationErrors naming every server of servers that has
+// exec_checks, or nil if none has. Exec checks run commands on the host of the watchdog, so
+// unless the operator enables them (Config.AllowExecChecks), configurations and servers added
+// through the admin API that define some are rejected.
+func DisallowExecChecks(servers []models.ObaServer) error {
+	var problems ValidationErrors
+	for i, server := range servers {
+		if len(server.ExecChecks) > 0 {
+			problems = append(problems, ValidationError{Index: i, ServerID: server.ID, Field: "exec_checks", Message: "exec checks are disabled (see --allow-exec-checks)"})
+		}
+	}
+	if len(problems) > 0 {
+		return problems
+	}
+	return nil
+}
+
+// ParseServers strictly decodes a configuration: unlike the loaders, which ignore what they do
+// not know, it rejects unknown fields (usually misspelled settings) and trailing data, and
+// names the position of type errors.
+func ParseServers(data []byte) ([]models.ObaServer, error) {
+	decoder := json.NewDecoder(bytes.NewReader(data))
+	decoder.DisallowUnknownFields()
+	var servers []models.ObaServer
+	if err := decoder.Decode(&servers); err != nil {
+		var typeErr *json.UnmarshalTypeError
+		var syntaxErr *json.SyntaxError
+		switch {
+		case errors.As(err, &typeErr):
+			return nil, fmt.Errorf("%s: expected %s, got %s (offset %d)", typeErr.Field, typeErr.Type, typeErr.Value, typeErr.Offset)
+		case errors.As(err, &syntaxErr):
+			return nil, fmt.Errorf("invalid JSON at offset %d: %w", syntaxErr.Offset, err)
+		}
+		// Unknown fields are reported as `json: unknown field "nmae"`.
+		return nil, errors.New(strings.TrimPrefix(err.Error(), "json: "))
+	}
+	if _, err := decoder.Token(); err != io.EOF {
+		return nil, errors.New("unexpected data after the list of servers")
+	}
+	return servers, nil
+}
+
+// serverURL is a URL setting of a server, with the JSON name of its field.
+type serverURL struct {
+	field string
+	value string
+}
+
+// serverURLs returns the URL settings of server that are set.
+func serverURLs(server models.ObaServer) []serverURL {
+	all := []serverURL{
+		{"url", server.URL},
+		{"oba_base_url", server.ObaBaseURL},
+		{"gtfs_url", server.GtfsUrl},
+		{"trip_update_url", server.TripUpdateUrl},
+		{"vehicle_position_url", server.VehiclePositionUrl},
+		{"oba_data_sources_url", server.DataSourcesURL},
+		{"reduced_service_calendar_url", server.ReducedServiceCalendarURL},
+		{"proxy_url", server.ProxyURL},
+	}
+	for i, u := range server.GtfsUrls {
+		all = append(all, serverURL{fmt.Sprintf("gtfs_urls[%d]", i), u})
+	}
+	if server.Alerts != nil {
+		all = append(all,
+			serverURL{"alerts.slack_webhook_url", server.Alerts.SlackWebhookURL},
+			serverURL{"alerts.webhook_url", server.Alerts.WebhookURL})
+	}
+	if server.AgencyContact != nil {
+		all = append(all, serverURL{"agency_contact.slack_webhook_url", server.AgencyContact.SlackWebhookURL})
+	}
+	urls := all[:0]
+	for _, u := range all {
+		if u.value != "" {
+			urls = append(urls, u)
+		}
+	}
+	return urls
+}
+
+// ValidateServers checks a configuration without any network access and returns every problem
+// found, in the order of the servers:
+//   - id and name are required, ids are unique, and type is empty, "oba" or "url";
+//   - OBA servers need an oba_base_url, entries of type "url" a url;
+//   - URL settings are absolute http(s) URLs (socks5 is also accepted for proxy_url), except
+//     the GTFS bundle URLs, which can also be local paths or file:// URLs;
+//   - gtfs_rt_api_key and gtfs_rt_api_value are either both set or both empty;
+//   - alert email recipients are valid addresses;
+//   - exec checks have a name and a command;
+//   - maintenance windows have a start and end after it.
+func ValidateServers(servers []models.ObaServer) []ValidationError {
+	var problems []ValidationError
+	firstIndex := make(map[int]int)
+	for i, server := range servers {
+		problem := func(field, format string, args ...any) {
+			problems = append(problems, ValidationError{Index: i, ServerID: server.ID, Field: field, Message: fmt.Sprintf(format, args...)})
+		}
+
+		if server.ID == 0 {
+			problem("id", "is required")
+		} else if first, ok := firstIndex[server.ID]; ok {
+			problem("id", "duplicates the id of servers[%d]", first)
+		} else {
+			firstIndex[server.ID] = i
+		}
+		if strings.TrimSpace(server.Name) == "" {
+			problem("name", "is required")
+		}
+		switch server.Type {
+		case "", "oba":
+			if server.ObaBaseURL == "" {
+				problem("oba_base_url", "is required")
+			}
+		case models.ServerTypeURL:
+			if server.URL == "" {
+				problem("url", "is required for entries of type %q", models.ServerTypeURL)
+			}
+		default:
+			problem("type", "unknown type %q, expected \"oba\" or %q", server.Type, models.ServerTypeURL)
+		}
+
+		for _, u := range serverURLs(server) {
+			if _, ok := LocalBundlePath(u.value); ok && isBundleField(u.field) {
+				continue
+			}
+			schemes := []string{"http", "https"}
+			if u.field == "proxy_url" {
+				schemes = append(schemes, "socks5")
+			}
+			if err := checkURLSyntax(u.value, schemes); err != nil {
+				problem(u.field, "%v", err)
+			}
+		}
+
+		if server.GtfsRtApiKey != "" && server.GtfsRtApiValue == "" {
+			problem("gtfs_rt_api_value", "is required when gtfs_rt_api_key is set")
+		} else if server.GtfsRtApiKey == "" && server.GtfsRtApiValue != "" {
+			problem("gtfs_rt_api_key", "is required when gtfs_rt_api_value is set")
+		}
+
+		if server.Alerts != nil {
+			for j, address := range server.Alerts.EmailTo {
+				if _, err := mail.ParseAddress(address); err != nil {
+					problem(fmt.Sprintf("alerts.email_to[%d]", j), "invalid email address %q", address)
+				}
+			}
+		}
+
+		for j, check := range server.ExecChecks {
+			if check.Name == "" {
+				problem(fmt.Sprintf("exec_checks[%d].name", j), "is required")
+			}
+			if len(check.Command) == 0 || check.Command[0] == "" {
+				problem(fmt.Sprintf("exec_checks[%d].command", j), "is required")
+			}
+		}
+
+		if t := server.Timeouts; t != nil {
+			for _, timeout := range []struct {
+				field string
+				value models.Duration
+			}{{"connect", t.Connect}, {"tls_handshake", t.TLSHandshake}, {"api", t.API}, {"realtime", t.Realtime}, {"bundle", t.Bundle}} {
+				if timeout.value < 0 {
+					problem("timeouts."+timeout.field, "must not be negative")
+				}
+			}
+			// Connections are established within the overall timeout of the requests, so a
+			// longer connect or TLS timeout could never apply.
+			if t.API > 0 && t.Connect+t.TLSHandshake > t.API {
+				problem("timeouts.api", "must be longer than timeouts.connect and timeouts.tls_handshake together")
+			}
+		}
+
+		for j, window := range server.MaintenanceWindows {
+			if window.Start.IsZero() {
+				problem(fmt.Sprintf("maintenance_windows[%d].start", j), "is required")
+			} else if !window.End.After(window.Start) {
+				problem(fmt.Sprintf("maintenance_windows[%d].end", j), "must be after the start")
+			}
+		}
+	}
+	return problems
+}
+
+// isBundleField reports whether field is one of the GTFS bundle URLs of a server, gtfs_url or
+// an entry of gtfs_urls, which can also be local files (see LocalBundlePath).
+func isBundleField(field string) bool {
+	return field == "gtfs_url" || strings.HasPrefix(field, "gtfs_urls[")
+}
+
+// checkURLSyntax returns an error unless raw is an absolute URL with a host and one of schemes.
+func checkURLSyntax(raw string, schemes []string) error {
+	u, err := url.Parse(raw)
+	if err != nil {
+		return fmt.Errorf("invalid URL %q: %v", raw, errors.Unwrap(err))
+	}
+	scheme := strings.ToLower(u.Scheme)
+	valid := false
+	for _, s := range schemes {
+		valid = valid || scheme == s
+	}
+	if !valid {
+		return fmt.Errorf("invalid URL %q: the scheme must be %s", raw, strings.Join(schemes, " or "))
+	}
+	if u.Host == "" {
+		return fmt.Errorf("invalid URL %q: missing host", raw)
+	}
+	return nil
+}
+
+// ProbeServers requests the URLs of every server and returns the ones that cannot be reached:
+// the OBA API (its current-time endpoint, with the API key of the server), the GTFS bundles
+// (local bundles must exist instead), the GTFS-RT feeds (with the GTFS-RT API key header), the data sources, the reduced service
+// calendar and the url of entries of type "url". A URL fails if the request fails or it answers
+// with a status of 400 or more. Webhooks and proxies are not probed, since requesting them has
+// side effects or proves nothing. Invalid URLs are left to ValidateServers.
+func ProbeServers(ctx context.Context, client *http.Client, servers []models.ObaServer) []ValidationError {
+	var problems []ValidationError
+	for i, server := range servers {
+		for _, u := range serverURLs(server) {
+			target := u.value
+			header := http.Header{}
+			if path, ok := LocalBundlePath(u.value); ok && isBundleField(u.field) {
+				if _, err := os.Stat(path); err != nil {
+					problems = append(problems, ValidationError{Index: i, ServerID: server.ID, Field: u.field, Message: fmt.Sprintf("unreadable: %v", errors.Unwrap(err))})
+				}
+				continue
+			}
+			switch u.field {
+			case "oba_base_url":
+				target = strings.TrimSuffix(target, "/") + "/api/where/current-time.json?key=" + url.QueryEscape(server.ObaApiKey)
+			case "trip_update_url", "vehicle_position_url":
+				if server.GtfsRtApiKey != "" {
+					header.Set(server.GtfsRtApiKey, server.GtfsRtApiValue)
+				}
+			case "url", "gtfs_url", "oba_data_sources_url", "reduced_service_calendar_url":
+			default:
+				if !isBundleField(u.field) {
+					continue
+				}
+			}
+			if checkURLSyntax(u.value, []string{"http", "https"}) != nil {
+				continue
+			}
+			if err := probeURL(ctx, client, target, header); err != nil {
+				problems = append(problems, ValidationError{Index: i, ServerID: server.ID, Field: u.field, Message: err.Error()})
+			}
+		}
+	}
+	return problems
+}
+
+// probeURL requests target and returns an error if it cannot be reached. The body is not read.
+func probeURL(ctx context.Context, client *http.Client, target string, header http.Header) error {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, target, nil)
+	if err != nil {
+		return fmt.Errorf("failed to create request: %v", err)
+	}
+	req.Header = header
+	resp, err := client.Do(req)
+	if err != nil {
+		// The error of the client repeats the URL, which may hold the API key of the server.
+		var urlErr *url.Error
+		if errors.As(err, &urlErr) {
+			err = urlErr.Err
+		}
+		return fmt.Errorf("unreachable: %v", err)
+	}
+	resp.Body.Close()
+	if resp.StatusCode >= 400 {
+		return fmt.Errorf("answered with status %d", resp.StatusCode)
+	}
+	return nil
+}
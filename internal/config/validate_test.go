@@ -0,0 +1,138 @@
+package config
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"watchdog.onebusaway.org/internal/models"
+)
+
+func TestParseServers(t *testing.T) {
+	if servers, err := ParseServers([]byte(`[{"id": 1, "name": "A", "oba_base_url": "https://a.example.com"}]`)); err != nil || len(servers) != 1 {
+		t.Fatalf("expected one server, got %v, %v", servers, err)
+	}
+	for input, want := range map[string]string{
+		`[{"id": 1, "nmae": "A"}]`: `unknown field "nmae"`,
+		`[{"id": "1"}]`:            "id: expected int, got string",
+		`[{"id": 1,}]`:             "invalid JSON at offset",
+		`[{"id": 1}] [{"id": 2}]`:  "unexpected data after the list of servers",
+		`{"servers": [{"id": 1}]}`: "expected []models.ObaServer",
+	} {
+		if _, err := ParseServers([]byte(input)); err == nil || !strings.Contains(err.Error(), want) {
+			t.Errorf("ParseServers(%s): expected an error containing %q, got %v", input, want, err)
+		}
+	}
+}
+
+func TestValidateServers(t *testing.T) {
+	servers := []models.ObaServer{
+		{ID: 1, Name: "Valid", ObaBaseURL: "https://a.example.com", ProxyURL: "socks5://proxy:1080", GtfsUrl: "file:///srv/gtfs/bundle.zip", GtfsUrls: []string{"ferry.zip"}},
+		{ID: 1, Name: " ", ObaBaseURL: "a.example.com", GtfsUrl: "ftp://b.example.com/gtfs.zip"},
+		{ID: 3, Name: "Planner", Type: models.ServerTypeURL},
+		{Name: "Unknown", Type: "ftp", Alerts: &models.AlertConfig{WebhookURL: "https://", EmailTo: []string{"ops@example.com", "ops"}}},
+		{ID: 5, Name: "Exec", ObaBaseURL: "https://c.example.com", ExecChecks: []models.ExecCheck{{Name: "zones"}},
+			Timeouts: &models.HTTPTimeouts{Connect: models.Duration(-time.Second), TLSHandshake: models.Duration(5 * time.Second), API: models.Duration(2 * time.Second)}},
+		{ID: 6, Name: "Upgrade", ObaBaseURL: "https://d.example.com", MaintenanceWindows: []models.MaintenanceWindow{
+			{Start: time.Date(2026, 5, 1, 2, 0, 0, 0, time.UTC), End: time.Date(2026, 5, 1, 4, 0, 0, 0, time.UTC)},
+			{Start: time.Date(2026, 5, 2, 2, 0, 0, 0, time.UTC), End: time.Date(2026, 5, 2, 1, 0, 0, 0, time.UTC)},
+			{End: time.Date(2026, 5, 3, 4, 0, 0, 0, time.UTC)},
+		}},
+	}
+	var got []string
+	for _, problem := range ValidateServers(servers) {
+		got = append(got, problem.Error())
+	}
+	want := []string{
+		"servers[1] (id 1): id: duplicates the id of servers[0]",
+		"servers[1] (id 1): name: is required",
+		`servers[1] (id 1): oba_base_url: invalid URL "a.example.com": the scheme must be http or https`,
+		`servers[1] (id 1): gtfs_url: invalid URL "ftp://b.example.com/gtfs.zip": the scheme must be http or https`,
+		`servers[2] (id 3): url: is required for entries of type "url"`,
+		"servers[3]: id: is required",
+		`servers[3]: type: unknown type "ftp", expected "oba" or "url"`,
+		`servers[3]: alerts.webhook_url: invalid URL "https://": missing host`,
+		`servers[3]: alerts.email_to[1]: invalid email address "ops"`,
+		"servers[4] (id 5): exec_checks[0].command: is required",
+		"servers[4] (id 5): timeouts.connect: must not be negative",
+		"servers[4] (id 5): timeouts.api: must be longer than timeouts.connect and timeouts.tls_handshake together",
+		"servers[5] (id 6): maintenance_windows[1].end: must be after the start",
+		"servers[5] (id 6): maintenance_windows[2].start: is required",
+	}
+	if strings.Join(got, "\n") != strings.Join(want, "\n") {
+		t.Errorf("unexpected problems:\n%s\nwant:\n%s", strings.Join(got, "\n"), strings.Join(want, "\n"))
+	}
+}
+
+func TestDisallowExecChecks(t *testing.T) {
+	servers := []models.ObaServer{
+		{ID: 1, Name: "Plain"},
+		{ID: 2, Name: "Exec", ExecChecks: []models.ExecCheck{{Name: "zones", Command: []string{"true"}}}},
+	}
+	err := DisallowExecChecks(servers)
+	var problems ValidationErrors
+	if !errors.As(err, &problems) || len(problems) != 1 || problems[0].Index != 1 || problems[0].Field != "exec_checks" {
+		t.Fatalf("expected a problem for the exec checks of servers[1], got %v", err)
+	}
+	if err := DisallowExecChecks(servers[:1]); err != nil {
+		t.Errorf("expected no problem without exec checks, got %v", err)
+	}
+}
+
+func TestProbeServers(t *testing.T) {
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch {
+		case r.URL.Path == "/api/where/current-time.json" && r.URL.Query().Get("key") == "secret":
+		case r.URL.Path == "/trip-updates" && r.Header.Get("X-Api-Key") == "rt":
+		default:
+			w.WriteHeader(http.StatusForbidden)
+		}
+	}))
+	defer ts.Close()
+
+	servers := []models.ObaServer{
+		{ID: 1, Name: "Reachable", ObaBaseURL: ts.URL, ObaApiKey: "secret", TripUpdateUrl: ts.URL + "/trip-updates", GtfsRtApiKey: "X-Api-Key", GtfsRtApiValue: "rt",
+			Alerts: &models.AlertConfig{WebhookURL: ts.URL + "/not-probed"}},
+		{ID: 2, Name: "Wrong key", ObaBaseURL: ts.URL, ObaApiKey: "wrong", GtfsUrl: "http://127.0.0.1:1/gtfs.zip"},
+		{ID: 3, Name: "Local", ObaBaseURL: ts.URL, ObaApiKey: "secret", GtfsUrl: "validate_test.go", GtfsUrls: []string{"missing/gtfs.zip"}},
+	}
+	problems := ProbeServers(context.Background(), ts.Client(), servers)
+	if len(problems) != 3 {
+		t.Fatalf("expected 3 problems, got %v", problems)
+	}
+	if problems[0].ServerID != 2 || problems[0].Field != "oba_base_url" || problems[0].Message != "answered with status 403" {
+		t.Errorf("unexpected problem for the API key: %+v", problems[0])
+	}
+	if problems[1].Field != "gtfs_url" || !strings.HasPrefix(problems[1].Message, "unreachable:") || strings.Contains(problems[1].Message, "127.0.0.1:1/gtfs.zip") {
+		t.Errorf("unexpected problem for the GTFS URL: %+v", problems[1])
+	}
+	if problems[2].ServerID != 3 || problems[2].Field != "gtfs_urls[0]" || problems[2].Message != "unreadable: no such file or directory" {
+		t.Errorf("unexpected problem for the local GTFS bundle: %+v", problems[2])
+	}
+}
+
+func TestLocalBundlePath(t *testing.T) {
+	tests := []struct {
+		url   string
+		path  string
+		local bool
+	}{
+		{"/srv/gtfs/bundle.zip", "/srv/gtfs/bundle.zip", true},
+		{"testdata/gtfs.zip", "testdata/gtfs.zip", true},
+		{"file:///srv/gtfs/bundle.zip", "/srv/gtfs/bundle.zip", true},
+		{"file://localhost/srv/gtfs/bundle.zip", "/srv/gtfs/bundle.zip", true},
+		{"file://fileserver/srv/gtfs/bundle.zip", "", false},
+		{"https://gtfs.example.com/bundle.zip", "", false},
+		{"", "", false},
+	}
+	for _, tt := range tests {
+		path, local := LocalBundlePath(tt.url)
+		if path != tt.path || local != tt.local {
+			t.Errorf("LocalBundlePath(%q) = %q, %v, want %q, %v", tt.url, path, local, tt.path, tt.local)
+		}
+	}
+}
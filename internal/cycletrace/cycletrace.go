@@ -0,0 +1,185 @@
+// Package cycletrace records where the time of the collection cycles of each server goes: how
+// long was spent downloading, parsing and storing feeds, calling the OBA API and analyzing the
+// data, step by step, so that a cycle that got slow can be explained without profiling the
+// watchdog.
+package cycletrace
+
+import (
+	"sync"
+	"time"
+)
+
+// Phases the time of a cycle is broken down into.
+const (
+	// PhaseDownload is the time spent fetching feeds, e.g. the GTFS-RT feed.
+	PhaseDownload = "download"
+	// PhaseParse is the time spent decoding the fetched feeds.
+	PhaseParse = "parse"
+	// PhaseStore is the time spent storing the decoded feeds for the checks.
+	PhaseStore = "store"
+	// PhaseAPI is the time spent in checks that call the OBA API.
+	PhaseAPI = "api"
+	// PhaseAnalysis is the time spent in checks that only look at the stored data.
+	PhaseAnalysis = "analysis"
+)
+
+// DefaultKeep is the number of traces kept per server by default.
+const DefaultKeep = 20
+
+// Step is the time spent in a step of a cycle, e.g. a check, in one phase. A step that spans
+// several phases, like the fetch of the GTFS-RT feed, has one Step per phase.
+type Step struct {
+	Name    string  `json:"name"`
+	Phase   string  `json:"phase"`
+	Seconds float64 `json:"seconds"`
+}
+
+// Trace is the breakdown of the time of a collection cycle of a server.
+type Trace struct {
+	ServerID  int       `json:"server_id"`
+	StartedAt time.Time `json:"started_at"`
+	// Seconds is the duration of the whole cycle of the server.
+	Seconds float64 `json:"seconds"`
+	// Phases is the time spent in each phase, in seconds.
+	Phases map[string]float64 `json:"phases"`
+	// Steps are the steps of the cycle, in the order they ran.
+	Steps []Step `json:"steps"`
+
+	// marked is when the previous step ended, and added the time of the steps added with Add
+	// since then, which the next step does not count again.
+	marked time.Time
+	added  time.Duration
+}
+
+// Store keeps the traces of the last cycles of each server, and the trace of the cycle of each
+// server in progress. It is safe for concurrent use; a nil Store records nothing.
+type Store struct {
+	keep int
+
+	mu      sync.Mutex
+	running map[int]*Trace
+	done    map[int][]Trace
+}
+
+// NewStore creates a Store that keeps the traces of the last keep cycles of each server.
+func NewStore(keep int) *Store {
+	return &Store{keep: keep, running: make(map[int]*Trace), done: make(map[int][]Trace)}
+}
+
+// Start starts the trace of a cycle of a server, replacing the trace in progress, if any.
+func (s *Store) Start(serverID int, now time.Time) {
+	if s == nil {
+		return
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.running[serverID] = &Trace{ServerID: serverID, StartedAt: now.UTC(), Phases: make(map[string]float64), marked: now}
+}
+
+// Add records that the step of the cycle in progress of a server spent d in phase, e.g. the
+// download part of the fetch of a feed timed by the fetch itself. The time is left out of the
+// next Mark. It does nothing if no cycle of the server is in progress.
+func (s *Store) Add(serverID int, step, phase string, d time.Duration) {
+	if s == nil {
+		return
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if trace, ok := s.running[serverID]; ok {
+		trace.add(step, phase, d)
+		trace.added += d
+	}
+}
+
+// Mark records that a step of the cycle in progress of a server ended at now, and counts the
+// time since the end of the previous step, less the time added with Add, in phase. It does
+// nothing if no cycle of the server is in progress, e.g. for checks run outside of cycles.
+func (s *Store) Mark(serverID int, step, phase string, now time.Time) {
+	if s == nil {
+		return
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	trace, ok := s.running[serverID]
+	if !ok {
+		return
+	}
+	trace.add(step, phase, max(now.Sub(trace.marked)-trace.added, 0))
+	trace.marked = now
+	trace.added = 0
+}
+
+// Finish ends the trace of the cycle in progress of a server, keeps it, and returns it, with
+// false if no cycle of the server was in progress.
+func (s *Store) Finish(serverID int, now time.Time) (Trace, bool) {
+	if s == nil {
+		return Trace{}, false
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	trace, ok := s.running[serverID]
+	if !ok {
+		return Trace{}, false
+	}
+	delete(s.running, serverID)
+	trace.Seconds = now.Sub(trace.StartedAt).Seconds()
+
+	traces := append(s.done[serverID], *trace)
+	if len(traces) > s.keep {
+		traces = traces[len(traces)-s.keep:]
+	}
+	s.done[serverID] = traces
+	return *trace, true
+}
+
+// Get returns the kept traces of a server, the most recent first.
+func (s *Store) Get(serverID int) []Trace {
+	if s == nil {
+		return nil
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	traces := make([]Trace, 0, len(s.done[serverID]))
+	for i := len(s.done[serverID]) - 1; i >= 0; i-- {
+		traces = append(traces, s.done[serverID][i])
+	}
+	return traces
+}
+
+// Last returns the trace of the last finished cycle of a server, and false if none finished.
+func (s *Store) Last(serverID int) (Trace, bool) {
+	if s == nil {
+		return Trace{}, false
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	traces := s.done[serverID]
+	if len(traces) == 0 {
+		return Trace{}, false
+	}
+	return traces[len(traces)-1], true
+}
+
+// Delete forgets the traces of a server, e.g. when it is removed from the configuration.
+func (s *Store) Delete(serverID int) {
+	if s == nil {
+		return
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	delete(s.running, serverID)
+	delete(s.done, serverID)
+}
+
+// add counts d in the step and phase, merging it with the step of the same name and phase, if
+// any.
+func (t *Trace) add(step, phase string, d time.Duration) {
+	t.Phases[phase] += d.Seconds()
+	for i := range t.Steps {
+		if t.Steps[i].Name == step && t.Steps[i].Phase == phase {
+			t.Steps[i].Seconds += d.Seconds()
+			return
+		}
+	}
+	t.Steps = append(t.Steps, Step{Name: step, Phase: phase, Seconds: d.Seconds()})
+}
@@ -0,0 +1,69 @@
+package cycletrace
+
+import (
+	"testing"
+	"time"
+)
+
+func TestStore(t *testing.T) {
+	store := NewStore(2)
+	start := time.Date(2026, 3, 1, 12, 0, 0, 0, time.UTC)
+
+	store.Mark(1, "server_ping", PhaseAPI, start)
+	if _, ok := store.Finish(1, start); ok {
+		t.Fatal("expected no trace before the cycle starts")
+	}
+
+	store.Start(1, start)
+	store.Mark(1, "server_ping", PhaseAPI, start.Add(time.Second))
+	store.Add(1, "gtfs_rt_feed", PhaseDownload, 2*time.Second)
+	store.Add(1, "gtfs_rt_feed", PhaseParse, 500*time.Millisecond)
+	store.Mark(1, "gtfs_rt_feed", PhaseDownload, start.Add(4*time.Second))
+	store.Mark(1, "trip_coverage", PhaseAnalysis, start.Add(5*time.Second))
+	trace, ok := store.Finish(1, start.Add(6*time.Second))
+	if !ok {
+		t.Fatal("expected the trace of the cycle")
+	}
+
+	if trace.Seconds != 6 || !trace.StartedAt.Equal(start) {
+		t.Errorf("unexpected cycle duration %v or start %v", trace.Seconds, trace.StartedAt)
+	}
+	want := map[string]float64{PhaseAPI: 1, PhaseDownload: 2.5, PhaseParse: 0.5, PhaseAnalysis: 1}
+	for phase, seconds := range want {
+		if trace.Phases[phase] != seconds {
+			t.Errorf("expected %v seconds in %s, got %v", seconds, phase, trace.Phases)
+		}
+	}
+	steps := []Step{
+		{Name: "server_ping", Phase: PhaseAPI, Seconds: 1},
+		{Name: "gtfs_rt_feed", Phase: PhaseDownload, Seconds: 2.5},
+		{Name: "gtfs_rt_feed", Phase: PhaseParse, Seconds: 0.5},
+		{Name: "trip_coverage", Phase: PhaseAnalysis, Seconds: 1},
+	}
+	if len(trace.Steps) != len(steps) {
+		t.Fatalf("expected steps %v, got %v", steps, trace.Steps)
+	}
+	for i, step := range steps {
+		if trace.Steps[i] != step {
+			t.Errorf("expected step %v, got %v", step, trace.Steps[i])
+		}
+	}
+
+	// Only the last two traces are kept, the most recent first.
+	for i := 1; i <= 2; i++ {
+		store.Start(1, start.Add(time.Duration(i)*time.Minute))
+		store.Finish(1, start.Add(time.Duration(i)*time.Minute+time.Second))
+	}
+	traces := store.Get(1)
+	if len(traces) != 2 || !traces[0].StartedAt.Equal(start.Add(2*time.Minute)) || !traces[1].StartedAt.Equal(start.Add(time.Minute)) {
+		t.Errorf("expected the last two traces, got %v", traces)
+	}
+	if last, ok := store.Last(1); !ok || !last.StartedAt.Equal(traces[0].StartedAt) {
+		t.Errorf("expected the last trace, got %v", last)
+	}
+
+	store.Delete(1)
+	if traces := store.Get(1); len(traces) != 0 {
+		t.Errorf("expected no traces after delete, got %v", traces)
+	}
+}
@@ -4,12 +4,14 @@
 package geo
 
 import (
+	"context"
 	"fmt"
 	"math"
 	"sync"
 
 	remoteGtfs "github.com/OneBusAway/go-gtfs"
 	"github.com/golang/geo/s2"
+	"watchdog.onebusaway.org/internal/sharedstore"
 )
 
 // BoundingBox defines the geographic boundaries of a rectangular area.
@@ -71,31 +73,61 @@ func computeBoundingBox(stops []remoteGtfs.Stop) (BoundingBox, error) {
 	}, nil
 }
 
-// BoundingBoxStore is a concurrency-safe in-memory store for
+// BoundingBoxStore stores the bounding box of the stops of each server, indexed by server ID.
+// Implementations must be safe for concurrent use.
+type BoundingBoxStore interface {
+	// Set stores the bounding box of a server.
+	Set(serverID int, bbox BoundingBox)
+	// Get returns the bounding box of a server, and false if it has none.
+	Get(serverID int) (BoundingBox, bool)
+}
+
+// MemoryBoundingBoxStore is a concurrency-safe in-memory BoundingBoxStore for
 // bounding boxes indexed by server ID.
-type BoundingBoxStore struct {
+type MemoryBoundingBoxStore struct {
 	mu    sync.RWMutex
 	store map[int]BoundingBox
+	// replica shares the bounding boxes with the other instances (see Share); nil if they
+	// are not shared.
+	replica *sharedstore.Replica[BoundingBox]
 }
 
-// NewBoundingBoxStore returns a new instance of BoundingBoxStore.
-func NewBoundingBoxStore() *BoundingBoxStore {
-	return &BoundingBoxStore{
+// NewBoundingBoxStore returns a new instance of MemoryBoundingBoxStore.
+func NewBoundingBoxStore() *MemoryBoundingBoxStore {
+	return &MemoryBoundingBoxStore{
 		store: make(map[int]BoundingBox),
 	}
 }
 
 // Set stores the bounding box associated with the given server ID.
-func (s *BoundingBoxStore) Set(serverID int, bbox BoundingBox) {
+func (s *MemoryBoundingBoxStore) Set(serverID int, bbox BoundingBox) {
+	s.set(serverID, bbox)
+	s.replica.Publish(serverID, bbox)
+}
+
+// set stores the bounding box of a server without publishing it.
+func (s *MemoryBoundingBoxStore) set(serverID int, bbox BoundingBox) {
 	s.mu.Lock()
 	defer s.mu.Unlock()
 	s.store[serverID] = bbox
 }
 
+// Share publishes the bounding boxes stored from now on to backend, for the other instances to
+// sync (see Sync). It must be called before the store is used.
+func (s *MemoryBoundingBoxStore) Share(backend sharedstore.Backend) {
+	s.replica = sharedstore.NewReplica[BoundingBox]("bounding_box", backend)
+}
+
+// Sync stores the bounding boxes of the given servers published by the other instances since
+// the last sync. It does nothing if the store is not shared.
+func (s *MemoryBoundingBoxStore) Sync(ctx context.Context, serverIDs []int) error {
+	return s.replica.Sync(ctx, serverIDs, s.set, func(int) {})
+}
+
 // Get retrieves the bounding box associated with the given server ID.
 //
 // The second return value indicates whether a bounding box was found.
-func (s *BoundingBoxStore) Get(serverID int) (BoundingBox, bool) {
+func (s *MemoryBoundingBoxStore) Get(serverID int) (BoundingBox, bool) {
 	s.mu.RLock()
 	defer s.mu.RUnlock()
 	bbox, ok := s.store[serverID]
@@ -104,7 +136,7 @@ func (s *BoundingBoxStore) Get(serverID int) (BoundingBox, bool) {
 
 // IsInBoundingBox checks whether the given lat/lon is within the
 // bounding box associated with the specified server ID.
-func (s *BoundingBoxStore) IsInBoundingBox(serverID int, lat, lon float64) bool {
+func (s *MemoryBoundingBoxStore) IsInBoundingBox(serverID int, lat, lon float64) bool {
 	bbox, ok := s.Get(serverID)
 	if !ok {
 		return false
@@ -0,0 +1,124 @@
+package gtfs
+
+import (
+	"fmt"
+	"log/slog"
+	"slices"
+	"strconv"
+	"strings"
+
+	"github.com/getsentry/sentry-go"
+	"watchdog.onebusaway.org/internal/models"
+	"watchdog.onebusaway.org/internal/report"
+	"watchdog.onebusaway.org/internal/utils"
+)
+
+// attributionFile is the name of the optional GTFS file crediting the organizations behind the data.
+const attributionFile = "attribution.txt"
+
+// attributionRoles are the role columns of attribution.txt, in the order roles are listed.
+var attributionRoles = []struct {
+	column, role string
+}{
+	{"is_producer", "producer"},
+	{"is_operator", "operator"},
+	{"is_authority", "authority"},
+}
+
+// parseAttributions extracts attribution.txt from a raw GTFS bundle, which the GTFS library
+// does not read either (see parseFeedInfo).
+//
+// Records are often repeated for each agency, route or trip they apply to; the scope is not
+// kept, and identical records are returned once, sorted by organization name.
+// Returns nil (and no error) if the bundle has no attribution.txt.
+func parseAttributions(data []byte) ([]models.Attribution, error) {
+	records, err := readBundleCSV(data, attributionFile, 0)
+	if err != nil {
+		return nil, err
+	}
+
+	var attributions []models.Attribution
+	for _, columns := range records {
+		attribution := models.Attribution{
+			OrganizationName: columns["organization_name"],
+			URL:              columns["attribution_url"],
+			Email:            columns["attribution_email"],
+			Phone:            columns["attribution_phone"],
+		}
+		if attribution.OrganizationName == "" {
+			continue
+		}
+		for _, role := range attributionRoles {
+			if columns[role.column] == "1" {
+				attribution.Roles = append(attribution.Roles, role.role)
+			}
+		}
+		if !slices.ContainsFunc(attributions, func(a models.Attribution) bool { return attributionsEqual(a, attribution) }) {
+			attributions = append(attributions, attribution)
+		}
+	}
+	slices.SortStableFunc(attributions, func(a, b models.Attribution) int {
+		return strings.Compare(a.OrganizationName, b.OrganizationName)
+	})
+	return attributions, nil
+}
+
+func attributionsEqual(a, b models.Attribution) bool {
+	return a.OrganizationName == b.OrganizationName && slices.Equal(a.Roles, b.Roles) &&
+		a.URL == b.URL && a.Email == b.Email && a.Phone == b.Phone
+}
+
+// licenseChanges lists the fields of the license of a bundle that differ from the previous
+// bundle's, e.g. `feed_publisher_name: "Metro" -> "Metro Transit"`. Attributions are compared
+// as a whole.
+func licenseChanges(previous, current models.License) []string {
+	var changes []string
+	fields := []struct {
+		name          string
+		before, after string
+	}{
+		{"feed_publisher_name", previous.PublisherName, current.PublisherName},
+		{"feed_publisher_url", previous.PublisherURL, current.PublisherURL},
+		{"feed_contact_email", previous.ContactEmail, current.ContactEmail},
+		{"feed_contact_url", previous.ContactURL, current.ContactURL},
+	}
+	for _, field := range fields {
+		if field.before != field.after {
+			changes = append(changes, fmt.Sprintf("%s: %q -> %q", field.name, field.before, field.after))
+		}
+	}
+	if !slices.EqualFunc(previous.Attributions, current.Attributions, attributionsEqual) {
+		changes = append(changes, fmt.Sprintf("attributions: %s -> %s", organizationNames(previous.Attributions), organizationNames(current.Attributions)))
+	}
+	return changes
+}
+
+// organizationNames lists the organizations of attributions, e.g. `["Metro" "King County"]`.
+func organizationNames(attributions []models.Attribution) string {
+	names := make([]string, len(attributions))
+	for i, attribution := range attributions {
+		names[i] = attribution.OrganizationName
+	}
+	return fmt.Sprintf("%q", names)
+}
+
+// checkLicenseChange warns when the license of a newly stored bundle differs from the license
+// of the previous bundle of the server: publishers and attributions are not expected to change
+// with a schedule update, and data-usage terms may have to be reviewed when they do.
+// The change is logged, reported to Sentry and counted in LicenseChangesCounter.
+func checkLicenseChange(server models.ObaServer, previous, current models.License, logger *slog.Logger) {
+	changes := licenseChanges(previous, current)
+	if len(changes) == 0 {
+		return
+	}
+	LicenseChangesCounter.WithLabelValues(strconv.Itoa(server.ID)).Inc()
+	logger.Warn("GTFS bundle license changed", "server_id", server.ID, "changes", changes)
+	report.ReportErrorWithSentryOptions(fmt.Errorf("license of the GTFS bundle of server %d changed", server.ID), report.SentryReportOptions{
+		Tags: utils.MakeMap("server_id", strconv.Itoa(server.ID)),
+		ExtraContext: map[string]interface{}{
+			"gtfs_url": server.GtfsUrl,
+			"changes":  changes,
+		},
+		Level: sentry.LevelWarning,
+	})
+}
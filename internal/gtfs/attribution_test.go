@@ -0,0 +1,59 @@
+package gtfs
+
+import (
+	"io"
+	"log/slog"
+	"reflect"
+	"strings"
+	"testing"
+
+	"watchdog.onebusaway.org/internal/models"
+)
+
+func TestParseAttributions(t *testing.T) {
+	data := zipWithFiles(t, map[string]string{
+		"attribution.txt": "\ufefforganization_name,is_producer,is_operator,is_authority,attribution_url,route_id\n" +
+			"Sound Transit,1,0,1,https://www.soundtransit.org,100\n" +
+			"King County Metro,0,1,0,,100\n" +
+			"Sound Transit,1,0,1,https://www.soundtransit.org,200\n" +
+			",1,0,0,,\n",
+	})
+	attributions, err := parseAttributions(data)
+	if err != nil {
+		t.Fatalf("parseAttributions failed: %v", err)
+	}
+	expected := []models.Attribution{
+		{OrganizationName: "King County Metro", Roles: []string{"operator"}},
+		{OrganizationName: "Sound Transit", Roles: []string{"producer", "authority"}, URL: "https://www.soundtransit.org"},
+	}
+	if !reflect.DeepEqual(attributions, expected) {
+		t.Errorf("expected %+v, got %+v", expected, attributions)
+	}
+
+	attributions, err = parseAttributions(zipWithFiles(t, map[string]string{"stops.txt": "stop_id\n"}))
+	if err != nil || attributions != nil {
+		t.Errorf("expected no attributions without attribution.txt, got %+v, %v", attributions, err)
+	}
+}
+
+func TestCheckLicenseChange(t *testing.T) {
+	logger := slog.New(slog.NewTextHandler(io.Discard, nil))
+	server := models.ObaServer{ID: 4242}
+	previous := models.License{PublisherName: "Metro", Attributions: []models.Attribution{{OrganizationName: "Metro"}}}
+
+	checkLicenseChange(server, previous, previous, logger)
+	if got := counterValue(t, LicenseChangesCounter.WithLabelValues("4242")); got != 0 {
+		t.Fatalf("expected no change to be counted, got %v", got)
+	}
+
+	current := models.License{PublisherName: "Metro Transit", Attributions: []models.Attribution{{OrganizationName: "Metro"}, {OrganizationName: "County"}}}
+	changes := licenseChanges(previous, current)
+	expected := []string{`feed_publisher_name: "Metro" -> "Metro Transit"`, `attributions: ["Metro"] -> ["Metro" "County"]`}
+	if strings.Join(changes, "\n") != strings.Join(expected, "\n") {
+		t.Errorf("expected changes %q, got %q", expected, changes)
+	}
+	checkLicenseChange(server, previous, current, logger)
+	if got := counterValue(t, LicenseChangesCounter.WithLabelValues("4242")); got != 1 {
+		t.Errorf("expected the change to be counted, got %v", got)
+	}
+}
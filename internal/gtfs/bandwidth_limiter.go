@@ -0,0 +1,158 @@
+package gtfs
+
+import (
+	"context"
+	"io"
+	"sync"
+	"time"
+)
+
+// BandwidthLimiter is a token bucket that caps the throughput of one or more readers
+// to a fixed number of bytes per second.
+//
+// Tokens represent bytes. The bucket refills continuously at `rate` tokens per second
+// and holds at most `burst` tokens, which is also the largest chunk a single Read
+// is allowed to consume. A limiter can be shared by several readers, in which case
+// the cap applies to their combined throughput.
+//
+// A nil *BandwidthLimiter is valid and means "unlimited".
+type BandwidthLimiter struct {
+	mu     sync.Mutex
+	rate   float64 // bytes per second
+	burst  float64 // bucket capacity in bytes
+	tokens float64 // currently available bytes
+	last   time.Time
+}
+
+// NewBandwidthLimiter creates a BandwidthLimiter allowing bytesPerSecond bytes per second.
+// The burst size is one second worth of traffic, so short idle periods do not let a
+// download exceed the cap by more than one second of data.
+//
+// Returns nil (unlimited) if bytesPerSecond is zero or negative.
+func NewBandwidthLimiter(bytesPerSecond int64) *BandwidthLimiter {
+	if bytesPerSecond <= 0 {
+		return nil
+	}
+	return &BandwidthLimiter{
+		rate:   float64(bytesPerSecond),
+		burst:  float64(bytesPerSecond),
+		tokens: float64(bytesPerSecond),
+		last:   time.Now(),
+	}
+}
+
+// waitN blocks until n bytes worth of tokens are available, or the context is canceled.
+// n must not exceed the burst size; callers are expected to chunk their reads accordingly.
+func (l *BandwidthLimiter) waitN(ctx context.Context, n int) error {
+	if l == nil || n <= 0 {
+		return nil
+	}
+	for {
+		l.mu.Lock()
+		now := time.Now()
+		l.tokens += now.Sub(l.last).Seconds() * l.rate
+		if l.tokens > l.burst {
+			l.tokens = l.burst
+		}
+		l.last = now
+
+		if l.tokens >= float64(n) {
+			l.tokens -= float64(n)
+			l.mu.Unlock()
+			return nil
+		}
+		wait := time.Duration((float64(n) - l.tokens) / l.rate * float64(time.Second))
+		l.mu.Unlock()
+
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-time.After(wait):
+		}
+	}
+}
+
+// maxChunk returns the largest number of bytes a single read may consume from this limiter.
+func (l *BandwidthLimiter) maxChunk() int {
+	if l == nil {
+		return 0
+	}
+	return int(l.burst)
+}
+
+// BundleThrottle holds the bandwidth caps applied to GTFS static bundle downloads.
+//
+//   - PerDownload: bytes per second allowed for a single bundle download (0 = unlimited).
+//   - Global: a limiter shared by every concurrent bundle download (nil = unlimited).
+//
+// Both caps apply at the same time, so a download never exceeds its own cap and the sum
+// of all downloads never exceeds the global cap. This keeps daily bundle refreshes from
+// saturating the network interface and starving GTFS-RT polling and the metrics endpoint.
+type BundleThrottle struct {
+	PerDownload int64
+	Global      *BandwidthLimiter
+}
+
+// NewBundleThrottle creates a BundleThrottle from per-download and global limits,
+// both expressed in bytes per second. Zero or negative values disable the respective cap.
+func NewBundleThrottle(perDownload, global int64) *BundleThrottle {
+	if perDownload < 0 {
+		perDownload = 0
+	}
+	return &BundleThrottle{
+		PerDownload: perDownload,
+		Global:      NewBandwidthLimiter(global),
+	}
+}
+
+// enabled reports whether at least one bandwidth cap is configured.
+func (t *BundleThrottle) enabled() bool {
+	return t != nil && (t.PerDownload > 0 || t.Global != nil)
+}
+
+// wrap returns r throttled by the per-download cap (a fresh limiter for this download)
+// and the shared global cap. If neither cap is configured, r is returned unchanged.
+func (t *BundleThrottle) wrap(ctx context.Context, r io.Reader) io.Reader {
+	if t == nil {
+		return r
+	}
+	var limiters []*BandwidthLimiter
+	if l := NewBandwidthLimiter(t.PerDownload); l != nil {
+		limiters = append(limiters, l)
+	}
+	if t.Global != nil {
+		limiters = append(limiters, t.Global)
+	}
+	if len(limiters) == 0 {
+		return r
+	}
+	return &throttledReader{ctx: ctx, r: r, limiters: limiters}
+}
+
+// throttledReader is an io.Reader that waits on one or more BandwidthLimiters
+// after each read, so the consumer never receives bytes faster than the slowest cap allows.
+type throttledReader struct {
+	ctx      context.Context
+	r        io.Reader
+	limiters []*BandwidthLimiter
+}
+
+// Read implements io.Reader. Reads are capped to the smallest limiter burst size so that
+// a single call never requests more tokens than a bucket can hold.
+func (tr *throttledReader) Read(p []byte) (int, error) {
+	for _, l := range tr.limiters {
+		if chunk := l.maxChunk(); chunk > 0 && len(p) > chunk {
+			p = p[:chunk]
+		}
+	}
+
+	n, err := tr.r.Read(p)
+	if n > 0 {
+		for _, l := range tr.limiters {
+			if waitErr := l.waitN(tr.ctx, n); waitErr != nil {
+				return n, waitErr
+			}
+		}
+	}
+	return n, err
+}
@@ -0,0 +1,66 @@
+package gtfs
+
+import (
+	"bytes"
+	"context"
+	"io"
+	"testing"
+	"time"
+)
+
+func TestNewBandwidthLimiter(t *testing.T) {
+	if l := NewBandwidthLimiter(0); l != nil {
+		t.Errorf("expected nil limiter for zero rate, got %+v", l)
+	}
+	if l := NewBandwidthLimiter(-5); l != nil {
+		t.Errorf("expected nil limiter for negative rate, got %+v", l)
+	}
+	if l := NewBandwidthLimiter(1024); l == nil || l.maxChunk() != 1024 {
+		t.Errorf("expected limiter with 1024 byte burst, got %+v", l)
+	}
+}
+
+func TestBundleThrottleWrap(t *testing.T) {
+	t.Run("Unlimited throttle returns the original reader", func(t *testing.T) {
+		src := bytes.NewReader([]byte("gtfs"))
+		var throttle *BundleThrottle
+		if r := throttle.wrap(context.Background(), src); r != src {
+			t.Error("expected nil throttle to return the original reader")
+		}
+		if r := NewBundleThrottle(0, 0).wrap(context.Background(), src); r != src {
+			t.Error("expected throttle without caps to return the original reader")
+		}
+	})
+
+	t.Run("Per-download cap slows reads", func(t *testing.T) {
+		data := make([]byte, 30000)
+		throttle := NewBundleThrottle(20000, 0)
+
+		start := time.Now()
+		got, err := io.ReadAll(throttle.wrap(context.Background(), bytes.NewReader(data)))
+		elapsed := time.Since(start)
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		if len(got) != len(data) {
+			t.Fatalf("expected %d bytes, got %d", len(data), len(got))
+		}
+		// The first 20000 bytes are served from the initial burst, the remaining
+		// 10000 bytes need roughly half a second of refill.
+		if elapsed < 400*time.Millisecond {
+			t.Errorf("expected throttled read to take at least 400ms, took %v", elapsed)
+		}
+	})
+
+	t.Run("Canceled context aborts the read", func(t *testing.T) {
+		data := make([]byte, 10000)
+		throttle := NewBundleThrottle(1000, 0)
+		ctx, cancel := context.WithCancel(context.Background())
+		cancel()
+
+		_, err := io.ReadAll(throttle.wrap(ctx, bytes.NewReader(data)))
+		if err == nil {
+			t.Error("expected error from canceled context, got none")
+		}
+	})
+}
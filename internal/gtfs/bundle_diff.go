@@ -0,0 +1,160 @@
+package gtfs
+
+import (
+	"log/slog"
+	"strconv"
+	"sync"
+
+	remoteGtfs "github.com/OneBusAway/go-gtfs"
+)
+
+// bundleRegressionThreshold is the fraction of an entity type that has to disappear between
+// two bundles for the change summary to be logged as a warning instead of informational.
+const bundleRegressionThreshold = 0.1
+
+// Entity types compared between bundles, used as the `entity` metric label.
+const (
+	bundleEntityRoutes   = "routes"
+	bundleEntityStops    = "stops"
+	bundleEntityTrips    = "trips"
+	bundleEntityServices = "services"
+)
+
+var bundleEntities = []string{bundleEntityRoutes, bundleEntityStops, bundleEntityTrips, bundleEntityServices}
+
+// BundleContents is the set of entity IDs contained in a GTFS static bundle.
+// Only IDs are kept, so the previous bundle can be compared against a new one
+// without holding the whole previous bundle in memory.
+type BundleContents map[string]map[string]struct{}
+
+// newBundleContents collects the route, stop, trip and service IDs of a parsed bundle.
+func newBundleContents(staticBundle *remoteGtfs.Static) BundleContents {
+	contents := make(BundleContents, len(bundleEntities))
+	contents[bundleEntityRoutes] = make(map[string]struct{}, len(staticBundle.Routes))
+	for _, route := range staticBundle.Routes {
+		contents[bundleEntityRoutes][route.Id] = struct{}{}
+	}
+	contents[bundleEntityStops] = make(map[string]struct{}, len(staticBundle.Stops))
+	for _, stop := range staticBundle.Stops {
+		contents[bundleEntityStops][stop.Id] = struct{}{}
+	}
+	contents[bundleEntityTrips] = make(map[string]struct{}, len(staticBundle.Trips))
+	for _, trip := range staticBundle.Trips {
+		contents[bundleEntityTrips][trip.ID] = struct{}{}
+	}
+	contents[bundleEntityServices] = make(map[string]struct{}, len(staticBundle.Services))
+	for _, service := range staticBundle.Services {
+		contents[bundleEntityServices][service.Id] = struct{}{}
+	}
+	return contents
+}
+
+// EntityChange describes how one entity type changed between two bundles.
+type EntityChange struct {
+	Entity   string
+	Previous int
+	Current  int
+	Added    int
+	Removed  int
+}
+
+// removedRatio returns the fraction of the previous entities that were removed.
+func (c EntityChange) removedRatio() float64 {
+	if c.Previous == 0 {
+		return 0
+	}
+	return float64(c.Removed) / float64(c.Previous)
+}
+
+// diffBundleContents compares two bundles and returns one EntityChange per entity type,
+// in the order of bundleEntities.
+func diffBundleContents(previous, current BundleContents) []EntityChange {
+	changes := make([]EntityChange, 0, len(bundleEntities))
+	for _, entity := range bundleEntities {
+		prevIDs, currIDs := previous[entity], current[entity]
+		change := EntityChange{Entity: entity, Previous: len(prevIDs), Current: len(currIDs)}
+		for id := range currIDs {
+			if _, ok := prevIDs[id]; !ok {
+				change.Added++
+			}
+		}
+		for id := range prevIDs {
+			if _, ok := currIDs[id]; !ok {
+				change.Removed++
+			}
+		}
+		changes = append(changes, change)
+	}
+	return changes
+}
+
+// BundleContentsStore is a thread-safe in-memory store of the contents of the last bundle
+// stored for each server, indexed by server ID. A nil *BundleContentsStore disables diffing.
+type BundleContentsStore struct {
+	mu   sync.Mutex
+	data map[int]BundleContents
+	// routeNames holds the route names of the last bundle of each server (see recordRouteRenames).
+	routeNames map[int]map[string]routeName
+}
+
+// NewBundleContentsStore creates and returns a new, empty BundleContentsStore.
+func NewBundleContentsStore() *BundleContentsStore {
+	return &BundleContentsStore{data: make(map[int]BundleContents), routeNames: make(map[int]map[string]routeName)}
+}
+
+// swap records contents as the latest bundle for the server and returns the previous contents, if any.
+func (s *BundleContentsStore) swap(serverID int, contents BundleContents) (BundleContents, bool) {
+	if s == nil {
+		return nil, false
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	previous, ok := s.data[serverID]
+	s.data[serverID] = contents
+	return previous, ok
+}
+
+// recordBundleChanges diffs a newly stored bundle against the previous bundle of the same server.
+//
+// The change counts are exported as gtfs_bundle_change_* metrics and summarised in a single
+// structured log line. The summary is logged as a warning if any entity type lost at least
+// bundleRegressionThreshold of its entries, which usually points to an accidental data regression
+// (e.g. an agency publishing a partial export).
+//
+// The first bundle seen for a server only seeds the store; there is nothing to compare it to.
+// Returns the changes, or nil if there was no previous bundle or diffing is disabled.
+func recordBundleChanges(serverID int, staticBundle *remoteGtfs.Static, contentsStore *BundleContentsStore, logger *slog.Logger) []EntityChange {
+	if contentsStore == nil {
+		return nil
+	}
+	current := newBundleContents(staticBundle)
+	previous, ok := contentsStore.swap(serverID, current)
+	if !ok {
+		return nil
+	}
+
+	changes := diffBundleContents(previous, current)
+	serverLabel := strconv.Itoa(serverID)
+	logArgs := []any{"server_id", serverID}
+	regression := false
+	for _, change := range changes {
+		BundleChangeAddedGauge.WithLabelValues(serverLabel, change.Entity).Set(float64(change.Added))
+		BundleChangeRemovedGauge.WithLabelValues(serverLabel, change.Entity).Set(float64(change.Removed))
+		BundleChangeNetGauge.WithLabelValues(serverLabel, change.Entity).Set(float64(change.Current - change.Previous))
+		logArgs = append(logArgs,
+			change.Entity+"_added", change.Added,
+			change.Entity+"_removed", change.Removed,
+			change.Entity+"_total", change.Current,
+		)
+		if change.removedRatio() >= bundleRegressionThreshold {
+			regression = true
+		}
+	}
+
+	if regression {
+		logger.Warn("GTFS bundle lost a significant share of its entities", logArgs...)
+	} else {
+		logger.Info("GTFS bundle content changed", logArgs...)
+	}
+	return changes
+}
@@ -0,0 +1,101 @@
+package gtfs
+
+import (
+	"log/slog"
+	"os"
+	"testing"
+
+	remoteGtfs "github.com/OneBusAway/go-gtfs"
+)
+
+func TestDiffBundleContents(t *testing.T) {
+	previous := newBundleContents(&remoteGtfs.Static{
+		Routes:   []remoteGtfs.Route{{Id: "r1"}, {Id: "r2"}},
+		Stops:    []remoteGtfs.Stop{{Id: "s1"}, {Id: "s2"}, {Id: "s3"}},
+		Trips:    []remoteGtfs.ScheduledTrip{{ID: "t1"}},
+		Services: []remoteGtfs.Service{{Id: "weekday"}},
+	})
+	current := newBundleContents(&remoteGtfs.Static{
+		Routes:   []remoteGtfs.Route{{Id: "r1"}, {Id: "r3"}},
+		Stops:    []remoteGtfs.Stop{{Id: "s1"}},
+		Trips:    []remoteGtfs.ScheduledTrip{{ID: "t1"}, {ID: "t2"}},
+		Services: []remoteGtfs.Service{{Id: "weekday"}},
+	})
+
+	expected := map[string]EntityChange{
+		bundleEntityRoutes:   {Entity: bundleEntityRoutes, Previous: 2, Current: 2, Added: 1, Removed: 1},
+		bundleEntityStops:    {Entity: bundleEntityStops, Previous: 3, Current: 1, Added: 0, Removed: 2},
+		bundleEntityTrips:    {Entity: bundleEntityTrips, Previous: 1, Current: 2, Added: 1, Removed: 0},
+		bundleEntityServices: {Entity: bundleEntityServices, Previous: 1, Current: 1, Added: 0, Removed: 0},
+	}
+
+	changes := diffBundleContents(previous, current)
+	if len(changes) != len(expected) {
+		t.Fatalf("expected %d entity changes, got %d", len(expected), len(changes))
+	}
+	for _, change := range changes {
+		if change != expected[change.Entity] {
+			t.Errorf("%s: got %+v, want %+v", change.Entity, change, expected[change.Entity])
+		}
+	}
+}
+
+func TestRecordBundleChanges(t *testing.T) {
+	logger := slog.New(slog.NewTextHandler(os.Stdout, nil))
+	store := NewBundleContentsStore()
+
+	first := &remoteGtfs.Static{Routes: []remoteGtfs.Route{{Id: "r1"}, {Id: "r2"}}}
+	if changes := recordBundleChanges(1, first, store, logger); changes != nil {
+		t.Errorf("expected no changes for the first bundle, got %+v", changes)
+	}
+
+	second := &remoteGtfs.Static{Routes: []remoteGtfs.Route{{Id: "r1"}}}
+	changes := recordBundleChanges(1, second, store, logger)
+	if len(changes) == 0 || changes[0].Entity != bundleEntityRoutes || changes[0].Removed != 1 {
+		t.Fatalf("expected one removed route, got %+v", changes)
+	}
+
+	removed := BundleChangeRemovedGauge.WithLabelValues("1", bundleEntityRoutes)
+	if got := gaugeValue(t, removed); got != 1 {
+		t.Errorf("expected gtfs_bundle_change_removed{entity=routes} = 1, got %v", got)
+	}
+	net := BundleChangeNetGauge.WithLabelValues("1", bundleEntityRoutes)
+	if got := gaugeValue(t, net); got != -1 {
+		t.Errorf("expected gtfs_bundle_change_net{entity=routes} = -1, got %v", got)
+	}
+
+	if changes := recordBundleChanges(1, second, nil, logger); changes != nil {
+		t.Errorf("expected diffing to be disabled with a nil store, got %+v", changes)
+	}
+}
+
+func TestRecordRouteRenames(t *testing.T) {
+	logger := slog.New(slog.NewTextHandler(os.Stdout, nil))
+	store := NewBundleContentsStore()
+
+	first := &remoteGtfs.Static{Routes: []remoteGtfs.Route{
+		{Id: "r1", ShortName: "1", LongName: "Downtown"},
+		{Id: "r2", ShortName: "2", LongName: "Airport"},
+		{Id: "r3", ShortName: "3", LongName: "University"},
+	}}
+	if renamed := recordRouteRenames(1, first, store, logger); renamed != 0 {
+		t.Errorf("expected no renames for the first bundle, got %d", renamed)
+	}
+
+	second := &remoteGtfs.Static{Routes: []remoteGtfs.Route{
+		{Id: "r1", ShortName: "1", LongName: "Downtown"},
+		{Id: "r2", ShortName: "A", LongName: "Airport"},
+		{Id: "r3", ShortName: "3", LongName: "University Express"},
+		{Id: "r4", ShortName: "4", LongName: "Harbor"},
+	}}
+	if renamed := recordRouteRenames(1, second, store, logger); renamed != 2 {
+		t.Errorf("expected 2 renamed routes, got %d", renamed)
+	}
+	if got := gaugeValue(t, RoutesRenamedGauge.WithLabelValues("1")); got != 2 {
+		t.Errorf("expected gtfs_routes_renamed_last_refresh = 2, got %v", got)
+	}
+
+	if renamed := recordRouteRenames(1, second, nil, logger); renamed != 0 {
+		t.Errorf("expected diffing to be disabled with a nil store, got %d", renamed)
+	}
+}
@@ -0,0 +1,306 @@
+package gtfs
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+
+	remoteGtfs "github.com/OneBusAway/go-gtfs"
+	"watchdog.onebusaway.org/internal/models"
+	"watchdog.onebusaway.org/internal/report"
+	"watchdog.onebusaway.org/internal/utils"
+)
+
+// BundleDiskCache persists raw GTFS static bundles on disk so they survive restarts.
+//
+// Each server has at most one cached bundle, stored as `server-<id>-<sha256>.zip`, plus a
+// `server-<id>.json` manifest holding the bundle hash, source URL and HTTP validators. Each
+// bundle of a server with several GTFS URLs is cached on its own, keyed by the server and its
+// URL, as `server-<id>-feed-<hash of the URL>` (see SaveFeed).
+// On startup the cached bundles are parsed and stored before the first download completes,
+// so checks can run immediately instead of waiting for (possibly slow or failing) downloads.
+// Restoring the validators also lets the first refresh be answered with 304 Not Modified.
+//
+// A nil *BundleDiskCache is valid and disables caching.
+type BundleDiskCache struct {
+	dir string
+}
+
+// bundleCacheManifest is the on-disk description of a cached bundle.
+type bundleCacheManifest struct {
+	ServerID     int       `json:"server_id"`
+	GtfsURL      string    `json:"gtfs_url"`
+	Hash         string    `json:"hash"`
+	ETag         string    `json:"etag,omitempty"`
+	LastModified string    `json:"last_modified,omitempty"`
+	DownloadedAt time.Time `json:"downloaded_at"`
+}
+
+// NewBundleDiskCache creates the cache directory if needed and returns a BundleDiskCache.
+// Returns nil (caching disabled) if dir is empty.
+func NewBundleDiskCache(dir string) (*BundleDiskCache, error) {
+	if dir == "" {
+		return nil, nil
+	}
+	if err := os.MkdirAll(dir, 0o750); err != nil {
+		return nil, fmt.Errorf("failed to create bundle cache directory %s: %w", dir, err)
+	}
+	return &BundleDiskCache{dir: dir}, nil
+}
+
+// hashBundle returns the hex-encoded SHA-256 hash of raw bundle bytes.
+func hashBundle(data []byte) string {
+	sum := sha256.Sum256(data)
+	return hex.EncodeToString(sum[:])
+}
+
+// Dir returns the directory of the cache, or "" if caching is disabled.
+func (c *BundleDiskCache) Dir() string {
+	if c == nil {
+		return ""
+	}
+	return c.dir
+}
+
+// CheckWritable verifies that bundles can still be saved, by creating and removing a file in
+// the cache directory (e.g. a full disk or a volume remounted read-only fails it).
+func (c *BundleDiskCache) CheckWritable() error {
+	if c == nil {
+		return errors.New("bundle cache disabled")
+	}
+	f, err := os.CreateTemp(c.dir, ".probe-*")
+	if err != nil {
+		return err
+	}
+	f.Close()
+	return os.Remove(f.Name())
+}
+
+// serverEntry is the name the bundle of a server is cached under, and feedEntry that of the
+// bundle at gtfsURL, one of the GTFS URLs of a server with several of them.
+func serverEntry(serverID int) string {
+	return fmt.Sprintf("server-%d", serverID)
+}
+
+func feedEntry(serverID int, gtfsURL string) string {
+	sum := sha256.Sum256([]byte(gtfsURL))
+	return fmt.Sprintf("server-%d-feed-%s", serverID, hex.EncodeToString(sum[:8]))
+}
+
+func (c *BundleDiskCache) manifestPath(entry string) string {
+	return filepath.Join(c.dir, entry+".json")
+}
+
+func (c *BundleDiskCache) bundlePath(entry string, hash string) string {
+	return filepath.Join(c.dir, fmt.Sprintf("%s-%s.zip", entry, hash))
+}
+
+// Save writes the raw bundle and its manifest for the given server, replacing any
+// previously cached bundle. Files are written to a temporary name and renamed so a
+// crash never leaves a truncated bundle behind.
+func (c *BundleDiskCache) Save(serverID int, gtfsURL string, data []byte, metadata BundleMetadata) error {
+	return c.save(serverEntry(serverID), serverID, gtfsURL, data, metadata)
+}
+
+// SaveFeed is Save for the bundle at gtfsURL, one of the GTFS URLs of a server with several of
+// them, which is cached alongside the other bundles of the server.
+func (c *BundleDiskCache) SaveFeed(serverID int, gtfsURL string, data []byte, metadata BundleMetadata) error {
+	return c.save(feedEntry(serverID, gtfsURL), serverID, gtfsURL, data, metadata)
+}
+
+func (c *BundleDiskCache) save(entry string, serverID int, gtfsURL string, data []byte, metadata BundleMetadata) error {
+	if c == nil {
+		return nil
+	}
+	hash := metadata.Hash
+	if hash == "" {
+		hash = hashBundle(data)
+	}
+
+	bundlePath := c.bundlePath(entry, hash)
+	if err := writeFileAtomic(bundlePath, data); err != nil {
+		return fmt.Errorf("failed to write cached bundle for server %d: %w", serverID, err)
+	}
+
+	manifest, err := json.Marshal(bundleCacheManifest{
+		ServerID:     serverID,
+		GtfsURL:      gtfsURL,
+		Hash:         hash,
+		ETag:         metadata.ETag,
+		LastModified: metadata.LastModified,
+		DownloadedAt: metadata.DownloadedAt,
+	})
+	if err != nil {
+		return fmt.Errorf("failed to encode bundle cache manifest for server %d: %w", serverID, err)
+	}
+	if err := writeFileAtomic(c.manifestPath(entry), manifest); err != nil {
+		return fmt.Errorf("failed to write bundle cache manifest for server %d: %w", serverID, err)
+	}
+
+	// Remove the bundles of the entry cached under previous hashes, leaving out those of the
+	// other entries of the server (a hash has no dash).
+	previous, _ := filepath.Glob(filepath.Join(c.dir, entry+"-*.zip"))
+	for _, path := range previous {
+		hash := strings.TrimSuffix(strings.TrimPrefix(filepath.Base(path), entry+"-"), ".zip")
+		if path != bundlePath && !strings.Contains(hash, "-") {
+			_ = os.Remove(path)
+		}
+	}
+	return nil
+}
+
+// Load returns the cached raw bundle and its metadata for the given server.
+//
+// The cache entry is ignored (os.ErrNotExist is returned) if the server's GTFS URL changed
+// since the bundle was cached. An error is returned if the bundle does not match its recorded hash.
+func (c *BundleDiskCache) Load(serverID int, gtfsURL string) ([]byte, BundleMetadata, error) {
+	return c.load(serverEntry(serverID), serverID, gtfsURL)
+}
+
+// LoadFeed is Load for the bundle at gtfsURL, one of the GTFS URLs of a server with several of
+// them (see SaveFeed).
+func (c *BundleDiskCache) LoadFeed(serverID int, gtfsURL string) ([]byte, BundleMetadata, error) {
+	return c.load(feedEntry(serverID, gtfsURL), serverID, gtfsURL)
+}
+
+func (c *BundleDiskCache) load(entry string, serverID int, gtfsURL string) ([]byte, BundleMetadata, error) {
+	if c == nil {
+		return nil, BundleMetadata{}, os.ErrNotExist
+	}
+	raw, err := os.ReadFile(c.manifestPath(entry))
+	if err != nil {
+		return nil, BundleMetadata{}, err
+	}
+	var manifest bundleCacheManifest
+	if err := json.Unmarshal(raw, &manifest); err != nil {
+		return nil, BundleMetadata{}, fmt.Errorf("failed to decode bundle cache manifest for server %d: %w", serverID, err)
+	}
+	if manifest.GtfsURL != gtfsURL {
+		return nil, BundleMetadata{}, os.ErrNotExist
+	}
+
+	data, err := os.ReadFile(c.bundlePath(entry, manifest.Hash))
+	if err != nil {
+		return nil, BundleMetadata{}, err
+	}
+	if hashBundle(data) != manifest.Hash {
+		return nil, BundleMetadata{}, fmt.Errorf("cached bundle for server %d does not match its hash", serverID)
+	}
+
+	metadata := BundleMetadata{
+		ETag:         manifest.ETag,
+		LastModified: manifest.LastModified,
+		Hash:         manifest.Hash,
+		DownloadedAt: manifest.DownloadedAt,
+	}
+	return data, metadata, nil
+}
+
+// writeFileAtomic writes data to a temporary file next to path and renames it into place.
+func writeFileAtomic(path string, data []byte) error {
+	tmp := path + ".tmp"
+	if err := os.WriteFile(tmp, data, 0o640); err != nil {
+		return err
+	}
+	return os.Rename(tmp, path)
+}
+
+// LoadCachedGTFSBundles restores GTFS static data from the disk cache for every server, so
+// checks can run before the first download completes.
+//
+// For each server with a valid cache entry, it parses the cached bundle, stores it in the
+// StaticStore, computes the bounding box, restores the bundle metadata (including HTTP
+// validators, so the next download can be conditional) and seeds the contents store used for diffing. Servers without a cache entry are skipped.
+// A server with several GTFS URLs is restored from the merged cache entries of its bundles, and
+// skipped unless all of them are cached.
+// Failures are logged and reported but never stop the remaining servers from loading.
+//
+// Returns the number of servers restored from the cache.
+func (gs *GtfsService) LoadCachedGTFSBundles(servers []models.ObaServer) int {
+	logger, diskCache := gs.Logger, gs.BundleDiskCache
+	if diskCache == nil {
+		return 0
+	}
+	loaded := 0
+	for _, server := range servers {
+		if urls := server.GtfsFeedURLs(); len(urls) > 1 {
+			// The bundles of a server with several of them are cached one by one (see
+			// downloadGTFSFeeds), and all of them are needed to restore the server.
+			feeds := make([]*downloadedBundle, 0, len(urls))
+			for _, url := range urls {
+				feed, err := gs.loadCachedFeed(server.ID, url)
+				if err != nil {
+					if !errors.Is(err, os.ErrNotExist) {
+						report.ReportErrorWithSentryOptions(err, report.SentryReportOptions{
+							Tags: utils.MakeMap("server_id", strconv.Itoa(server.ID)),
+						})
+						logger.Warn("Failed to load cached GTFS bundle", "server_id", server.ID, "url", url, "error", err)
+					}
+					feeds = nil
+					break
+				}
+				feeds = append(feeds, feed)
+			}
+			if feeds != nil {
+				bundle := mergeFeeds(feeds, server.GtfsUrl)
+				if gs.restoreCachedGTFSBundle(server.ID, bundle.static, bundle.metadata) {
+					loaded++
+				}
+			}
+			continue
+		}
+		data, metadata, err := diskCache.Load(server.ID, server.GtfsUrl)
+		if errors.Is(err, os.ErrNotExist) {
+			continue
+		}
+		if err != nil {
+			report.ReportErrorWithSentryOptions(err, report.SentryReportOptions{
+				Tags: utils.MakeMap("server_id", strconv.Itoa(server.ID)),
+			})
+			logger.Warn("Failed to load cached GTFS bundle", "server_id", server.ID, "error", err)
+			continue
+		}
+
+		parseStart := time.Now()
+		staticBundle, err := remoteGtfs.ParseStatic(data, remoteGtfs.ParseStaticOptions{})
+		metadata.Size, metadata.ParseDuration = int64(len(data)), time.Since(parseStart)
+		if err != nil {
+			logger.Warn("Failed to parse cached GTFS bundle", "server_id", server.ID, "error", err)
+			continue
+		}
+		metadata.FeedInfo, err = parseFeedInfo(data)
+		if err != nil {
+			logger.Warn("Failed to parse feed_info.txt of cached GTFS bundle", "server_id", server.ID, "error", err)
+		}
+		metadata.Attributions, err = parseAttributions(data)
+		if err != nil {
+			logger.Warn("Failed to parse attribution.txt of cached GTFS bundle", "server_id", server.ID, "error", err)
+		}
+		if gs.restoreCachedGTFSBundle(server.ID, staticBundle, metadata) {
+			loaded++
+		}
+	}
+	return loaded
+}
+
+// restoreCachedGTFSBundle stores a bundle of the server read from the disk cache, restores its
+// metadata and seeds the contents store with it. It reports whether the bundle was stored.
+func (gs *GtfsService) restoreCachedGTFSBundle(serverID int, staticBundle *remoteGtfs.Static, metadata BundleMetadata) bool {
+	if err := storeGTFSBundle(staticBundle, metadata, serverID, gs.StaticStore, gs.BoundingBoxStore); err != nil {
+		gs.Logger.Warn("Failed to store cached GTFS bundle", "server_id", serverID, "error", err)
+		return false
+	}
+	gs.BundleMetadata.Set(serverID, metadata)
+	// Seed the contents store so the next downloaded bundle is diffed against the cached one.
+	gs.BundleContents.swap(serverID, newBundleContents(staticBundle))
+	gs.BundleContents.swapRouteNames(serverID, newRouteNames(staticBundle))
+	gs.Logger.Info("Loaded GTFS bundle from disk cache", "server_id", serverID, "hash", metadata.Hash)
+	return true
+}
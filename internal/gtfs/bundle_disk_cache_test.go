@@ -0,0 +1,158 @@
+package gtfs
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"watchdog.onebusaway.org/internal/models"
+)
+
+func TestNewBundleDiskCacheDisabled(t *testing.T) {
+	cache, err := NewBundleDiskCache("")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if cache != nil {
+		t.Fatal("expected nil cache for empty directory")
+	}
+	if _, _, err := cache.Load(1, "http://example.com/gtfs.zip"); !errors.Is(err, os.ErrNotExist) {
+		t.Errorf("expected os.ErrNotExist from nil cache, got %v", err)
+	}
+}
+
+func TestBundleDiskCacheSaveAndLoad(t *testing.T) {
+	dir := t.TempDir()
+	cache, err := NewBundleDiskCache(dir)
+	if err != nil {
+		t.Fatalf("failed to create cache: %v", err)
+	}
+	const url = "http://example.com/gtfs.zip"
+
+	if err := cache.Save(1, url, []byte("old bundle"), BundleMetadata{ETag: `"v1"`}); err != nil {
+		t.Fatalf("failed to save bundle: %v", err)
+	}
+	if err := cache.Save(1, url, []byte("new bundle"), BundleMetadata{ETag: `"v2"`}); err != nil {
+		t.Fatalf("failed to save bundle: %v", err)
+	}
+
+	data, metadata, err := cache.Load(1, url)
+	if err != nil {
+		t.Fatalf("failed to load bundle: %v", err)
+	}
+	if string(data) != "new bundle" {
+		t.Errorf("expected latest bundle, got %q", data)
+	}
+	if metadata.ETag != `"v2"` || metadata.Hash != hashBundle([]byte("new bundle")) {
+		t.Errorf("unexpected metadata: %+v", metadata)
+	}
+
+	bundles, _ := filepath.Glob(filepath.Join(dir, "server-1-*.zip"))
+	if len(bundles) != 1 {
+		t.Errorf("expected previous bundles to be removed, found %d", len(bundles))
+	}
+
+	if _, _, err := cache.Load(1, "http://example.com/other.zip"); !errors.Is(err, os.ErrNotExist) {
+		t.Errorf("expected os.ErrNotExist when the GTFS URL changed, got %v", err)
+	}
+	if _, _, err := cache.Load(2, url); !errors.Is(err, os.ErrNotExist) {
+		t.Errorf("expected os.ErrNotExist for uncached server, got %v", err)
+	}
+}
+
+func TestBundleDiskCacheFeeds(t *testing.T) {
+	dir := t.TempDir()
+	cache, err := NewBundleDiskCache(dir)
+	if err != nil {
+		t.Fatalf("failed to create cache: %v", err)
+	}
+	const transit, ferry = "http://example.com/transit.zip", "http://example.com/ferry.zip"
+
+	for _, save := range []struct {
+		url, data string
+	}{{transit, "old transit"}, {ferry, "ferry"}, {transit, "new transit"}} {
+		if err := cache.SaveFeed(1, save.url, []byte(save.data), BundleMetadata{}); err != nil {
+			t.Fatalf("failed to save bundle: %v", err)
+		}
+	}
+	if err := cache.Save(1, transit, []byte("server"), BundleMetadata{}); err != nil {
+		t.Fatalf("failed to save bundle: %v", err)
+	}
+
+	for url, want := range map[string]string{transit: "new transit", ferry: "ferry"} {
+		if data, _, err := cache.LoadFeed(1, url); err != nil || string(data) != want {
+			t.Errorf("expected %q cached for %s, got %q (%v)", want, url, data, err)
+		}
+	}
+	if data, _, err := cache.Load(1, transit); err != nil || string(data) != "server" {
+		t.Errorf("expected the bundle of the server to be cached apart from its feeds, got %q (%v)", data, err)
+	}
+	if bundles, _ := filepath.Glob(filepath.Join(dir, "server-1-*.zip")); len(bundles) != 3 {
+		t.Errorf("expected one bundle per entry, found %d", len(bundles))
+	}
+}
+
+func TestBundleDiskCacheRejectsCorruptBundle(t *testing.T) {
+	dir := t.TempDir()
+	cache, _ := NewBundleDiskCache(dir)
+	const url = "http://example.com/gtfs.zip"
+	if err := cache.Save(1, url, []byte("bundle"), BundleMetadata{}); err != nil {
+		t.Fatalf("failed to save bundle: %v", err)
+	}
+	if err := os.WriteFile(cache.bundlePath(serverEntry(1), hashBundle([]byte("bundle"))), []byte("tampered"), 0o640); err != nil {
+		t.Fatal(err)
+	}
+	if _, _, err := cache.Load(1, url); err == nil || errors.Is(err, os.ErrNotExist) {
+		t.Errorf("expected hash mismatch error, got %v", err)
+	}
+}
+
+func TestLoadCachedGTFSBundlesAfterRestart(t *testing.T) {
+	data := readFixture(t, "gtfs.zip")
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("ETag", `"bundle-v1"`)
+		if r.Header.Get("If-None-Match") == `"bundle-v1"` {
+			w.WriteHeader(http.StatusNotModified)
+			return
+		}
+		// #nosec G104
+		w.Write(data)
+	}))
+	defer server.Close()
+
+	cache, err := NewBundleDiskCache(t.TempDir())
+	if err != nil {
+		t.Fatalf("failed to create cache: %v", err)
+	}
+	ctx := context.Background()
+	downloader := newTestGtfsService(GtfsServiceOptions{BundleDiskCache: cache})
+	bundle, err := downloader.downloadGTFSBundle(ctx, server.URL, 1, 1)
+	if err != nil {
+		t.Fatalf("failed to download bundle: %v", err)
+	}
+	downloader.commitGTFSBundle(1, bundle)
+
+	// Simulate a restart with empty in-memory stores.
+	servers := []models.ObaServer{{ID: 1, GtfsUrl: server.URL}}
+	gs := newTestGtfsService(GtfsServiceOptions{BundleDiskCache: cache})
+	staticStore, boundingBoxStore := gs.StaticStore, gs.BoundingBoxStore
+
+	if loaded := gs.LoadCachedGTFSBundles(servers); loaded != 1 {
+		t.Fatalf("expected 1 bundle loaded from cache, got %d", loaded)
+	}
+	if staticData, ok := staticStore.Get(1); !ok || len(staticData.Stops) == 0 {
+		t.Fatal("expected static data to be restored from cache")
+	}
+	if _, ok := boundingBoxStore.Get(1); !ok {
+		t.Error("expected bounding box to be restored from cache")
+	}
+
+	// The restored validators make the next download conditional.
+	if _, err := gs.downloadGTFSBundle(ctx, server.URL, 1, 1); !errors.Is(err, ErrBundleNotModified) {
+		t.Errorf("expected ErrBundleNotModified after restoring from cache, got %v", err)
+	}
+}
@@ -0,0 +1,82 @@
+package gtfs
+
+import (
+	"context"
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"watchdog.onebusaway.org/internal/models"
+	"watchdog.onebusaway.org/internal/scheduler"
+)
+
+func TestDownloadGTFSBundleFromLocalFile(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "gtfs.zip")
+	if err := os.WriteFile(path, readFixture(t, "gtfs.zip"), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	var gs *GtfsService
+	for _, url := range []string{path, "file://" + filepath.ToSlash(path)} {
+		gs = newTestGtfsService(GtfsServiceOptions{})
+		bundle, err := gs.downloadGTFSBundle(context.Background(), url, 1, 1)
+		if err != nil || len(bundle.static.Stops) == 0 {
+			t.Fatalf("expected the bundle to be read from %s, got %v", url, err)
+		}
+		gs.commitGTFSBundle(1, bundle)
+	}
+	if _, err := gs.downloadGTFSBundle(context.Background(), path, 1, 1); !errors.Is(err, ErrBundleNotModified) {
+		t.Errorf("expected an unchanged file to be not modified, got %v", err)
+	}
+
+	later := time.Now().Add(time.Minute)
+	if err := os.Chtimes(path, later, later); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := gs.downloadGTFSBundle(context.Background(), path, 1, 1); err != nil {
+		t.Errorf("expected a modified file to be read again, got %v", err)
+	}
+	gs.MaxBundleSize = 10
+	if _, err := gs.downloadGTFSBundle(context.Background(), path, 1, 1); !errors.Is(err, ErrBundleTooLarge) {
+		t.Errorf("expected a bundle over the maximum size to fail, got %v", err)
+	}
+	if _, err := gs.downloadGTFSBundle(context.Background(), filepath.Join(t.TempDir(), "missing.zip"), 2, 1); !errors.Is(err, os.ErrNotExist) {
+		t.Errorf("expected a missing file to fail, got %v", err)
+	}
+}
+
+func TestWatchLocalGTFSBundles(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "gtfs.zip")
+	if err := os.WriteFile(path, zipWithFiles(t, smallBundle), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	servers := []models.ObaServer{{ID: 72, GtfsUrl: path}}
+	gs := newTestGtfsService(GtfsServiceOptions{})
+	staticStore := gs.StaticStore
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	go gs.WatchLocalGTFSBundles(ctx, func() []models.ObaServer { return servers }, scheduler.Every(10*time.Millisecond), 1)
+
+	waitForBundle := func(loaded func(*models.StaticData) bool) {
+		t.Helper()
+		deadline := time.Now().Add(5 * time.Second)
+		for time.Now().Before(deadline) {
+			if staticData, ok := staticStore.Get(72); ok && loaded(staticData) {
+				return
+			}
+			time.Sleep(10 * time.Millisecond)
+		}
+		t.Fatal("expected the local bundle to be loaded")
+	}
+	waitForBundle(func(staticData *models.StaticData) bool { return len(staticData.Stops) == 1 })
+
+	if err := os.WriteFile(path, readFixture(t, "gtfs.zip"), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	later := time.Now().Add(time.Minute)
+	if err := os.Chtimes(path, later, later); err != nil {
+		t.Fatal(err)
+	}
+	waitForBundle(func(staticData *models.StaticData) bool { return len(staticData.Stops) > 1 })
+}
@@ -0,0 +1,152 @@
+package gtfs
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	"watchdog.onebusaway.org/internal/models"
+	"watchdog.onebusaway.org/internal/sharedstore"
+)
+
+// BundleMetadata holds information about the last GTFS static bundle downloaded for a server.
+//
+// ETag and LastModified are the HTTP validators returned by the agency server. They are sent
+// back as If-None-Match / If-Modified-Since on the next refresh so that an unchanged bundle
+// can be answered with 304 Not Modified instead of being downloaded and parsed again.
+type BundleMetadata struct {
+	// ETag is the entity tag of the last downloaded bundle, if the server sent one.
+	ETag string
+	// LastModified is the raw Last-Modified header of the last downloaded bundle, if any.
+	LastModified string
+	// Hash is the hex-encoded SHA-256 hash of the raw bundle bytes.
+	Hash string
+	// Size is the size of the raw bundle in bytes.
+	Size int64
+	// ParseDuration is how long parsing the raw bundle took, recorded with the time taken to
+	// store it in gtfs_bundle_parse_duration_seconds (see storeGTFSBundle).
+	ParseDuration time.Duration
+	// DownloadedAt is when a changed bundle was last downloaded and parsed successfully.
+	DownloadedAt time.Time
+	// CheckedAt is when the bundle URL was last checked, including 304 responses.
+	CheckedAt time.Time
+	// FeedInfo is the parsed feed_info.txt of the last downloaded bundle, or nil if it has none.
+	FeedInfo *models.FeedInfo
+	// Attributions are the parsed records of attribution.txt of the last downloaded bundle.
+	Attributions []models.Attribution
+	// RecentSizes are the sizes of the last bundles downloaded, this one last, and SizeRatio the
+	// ratio of Size to the median size of the bundles before it (0 = unknown, see
+	// recordBundleSize).
+	RecentSizes []int64
+	SizeRatio   float64
+	// ConsecutiveFailures is the number of bundle refreshes in a row that failed,
+	// reset by a successful download or a 304 Not Modified.
+	ConsecutiveFailures int
+	// Feeds holds, for a server with several GTFS URLs, the validators and hash of each of its
+	// bundles, keyed by URL (see downloadGTFSFeeds). Those of the server are then empty.
+	Feeds map[string]BundleFeed
+}
+
+// BundleFeed holds the validators and hash of one of the bundles of a server with several GTFS
+// URLs.
+type BundleFeed struct {
+	ETag         string
+	LastModified string
+	Hash         string
+}
+
+// hasValidators reports whether a conditional request can be made from this metadata.
+func (m BundleMetadata) hasValidators() bool {
+	return m.ETag != "" || m.LastModified != ""
+}
+
+// BundleMetadataStore is a thread-safe in-memory store for BundleMetadata, indexed by server ID.
+type BundleMetadataStore struct {
+	mu   sync.RWMutex
+	data map[int]BundleMetadata
+	// replica shares the metadata with the other instances (see Share); nil if it is not shared.
+	replica *sharedstore.Replica[BundleMetadata]
+}
+
+// NewBundleMetadataStore creates and returns a new, empty BundleMetadataStore.
+func NewBundleMetadataStore() *BundleMetadataStore {
+	return &BundleMetadataStore{
+		data: make(map[int]BundleMetadata),
+	}
+}
+
+// Get retrieves the bundle metadata for the given server ID.
+// The second return value reports whether metadata exists for the server.
+func (s *BundleMetadataStore) Get(serverID int) (BundleMetadata, bool) {
+	if s == nil {
+		return BundleMetadata{}, false
+	}
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	metadata, ok := s.data[serverID]
+	return metadata, ok
+}
+
+// Set stores the bundle metadata for the given server ID.
+func (s *BundleMetadataStore) Set(serverID int, metadata BundleMetadata) {
+	if s == nil {
+		return
+	}
+	s.set(serverID, metadata)
+	s.replica.Publish(serverID, metadata)
+}
+
+// set stores the metadata of a server without publishing it.
+func (s *BundleMetadataStore) set(serverID int, metadata BundleMetadata) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.data[serverID] = metadata
+}
+
+// Share publishes the metadata stored from now on to backend, for the other instances to sync
+// (see Sync). It must be called before the store is used.
+func (s *BundleMetadataStore) Share(backend sharedstore.Backend) {
+	if s == nil {
+		return
+	}
+	s.replica = sharedstore.NewReplica[BundleMetadata]("gtfs_bundle_metadata", backend)
+}
+
+// Sync stores the metadata of the given servers published by the other instances since the
+// last sync. With the validators of a bundle downloaded by another instance, the refreshes of
+// this instance send conditional requests, so the bundle is not downloaded again. It does
+// nothing if the store is not shared.
+func (s *BundleMetadataStore) Sync(ctx context.Context, serverIDs []int) error {
+	if s == nil {
+		return nil
+	}
+	return s.replica.Sync(ctx, serverIDs, s.set, func(int) {})
+}
+
+// markChecked records that the bundle for the given server was checked at the given time
+// without being downloaded again (e.g. the server answered 304 Not Modified).
+func (s *BundleMetadataStore) markChecked(serverID int, checkedAt time.Time) {
+	if s == nil {
+		return
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	metadata := s.data[serverID]
+	metadata.CheckedAt = checkedAt
+	metadata.ConsecutiveFailures = 0
+	s.data[serverID] = metadata
+}
+
+// markFailed records a failed bundle refresh for the given server and returns
+// the number of consecutive failures, or 0 for a nil store.
+func (s *BundleMetadataStore) markFailed(serverID int) int {
+	if s == nil {
+		return 0
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	metadata := s.data[serverID]
+	metadata.ConsecutiveFailures++
+	s.data[serverID] = metadata
+	return metadata.ConsecutiveFailures
+}
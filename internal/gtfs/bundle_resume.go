@@ -0,0 +1,221 @@
+package gtfs
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"strconv"
+	"strings"
+
+	"watchdog.onebusaway.org/internal/config"
+)
+
+// ErrBundleTooLarge is returned when a GTFS bundle exceeds the configured maximum size.
+var ErrBundleTooLarge = errors.New("GTFS bundle exceeds maximum allowed size")
+
+// defaultBundleResumes is the number of times the transfer of a bundle downloaded with
+// maxRetries 0 may be resumed (see bundleResumes).
+const defaultBundleResumes = 5
+
+// bundleResumes returns the number of times the transfer of a bundle downloaded with maxRetries
+// may be resumed. The initial request retries forever when maxRetries is zero (see
+// config.DoWithBackoff), but a transfer that keeps failing is not resumed forever: it is
+// resumed up to defaultBundleResumes times instead.
+func bundleResumes(maxRetries int) int {
+	if maxRetries <= 0 {
+		return defaultBundleResumes
+	}
+	return maxRetries
+}
+
+// readBundleBody reads the body of a GTFS bundle download, resuming the transfer with
+// HTTP Range requests when it fails partway through.
+//
+// The body is streamed to a temporary file rather than accumulated in a growing in-memory
+// buffer, which only bounds the memory of the transfer itself: the buffer never grows past the
+// chunks in flight, and a failed transfer is resumed from the file. Once the transfer is
+// complete, the whole bundle is read back from the file into memory, with a single
+// exactly-sized allocation, since the GTFS parser only accepts a byte slice. A download
+// therefore still holds the whole raw bundle in memory while it is parsed, and with a disk
+// cache until the bundle is stored and saved to it (see downloadedBundle).
+//
+// Large bundles (hundreds of MB) served by flaky agency servers frequently fail mid-transfer.
+// Instead of restarting from zero, each failure triggers a new GET request with
+// `Range: bytes=<received>-`. The `If-Range` header carries the ETag (or Last-Modified) of
+// the original response, so the server only honours the range if the bundle has not changed
+// in the meantime; otherwise it replies with the full new bundle and reading restarts from zero.
+//
+// Resumption attempts wait with the exponential backoff of config.RetryWithBackoff (starting at
+// config.BASE_BACKOFF, capped at config.MAX_BACKOFF) and are limited to maxResumes. A failed
+// resume request counts as an attempt. Each resumed transfer increments
+// BundleDownloadResumesCounter.
+//
+// Parameters:
+//   - ctx: Context used to cancel waiting and in-flight requests.
+//   - client: HTTP client used for resume requests.
+//   - url: The URL of the GTFS bundle.
+//   - serverID: The server the bundle belongs to, used for metric labels.
+//   - resp: The initial 200 OK response; its body is read first.
+//   - maxResumes: Maximum number of resume requests before giving up.
+//   - throttle: Bandwidth caps applied while reading (nil = unlimited).
+//   - maxSize: Maximum bundle size in bytes (0 = unlimited). Larger bundles fail with ErrBundleTooLarge,
+//     checked against Content-Length up front and enforced while streaming.
+//
+// Returns the complete bundle bytes, read back into memory, or the last read error if the transfer could not be completed.
+func readBundleBody(ctx context.Context, client *http.Client, url string, serverID int, resp *http.Response, maxResumes int, throttle *BundleThrottle, maxSize int64) ([]byte, error) {
+	if maxSize > 0 && resp.ContentLength > maxSize {
+		return nil, fmt.Errorf("%w: Content-Length %d > %d bytes", ErrBundleTooLarge, resp.ContentLength, maxSize)
+	}
+
+	file, err := os.CreateTemp("", "gtfs-bundle-*.zip")
+	if err != nil {
+		return nil, fmt.Errorf("failed to create temporary file for GTFS bundle: %w", err)
+	}
+	defer func() {
+		file.Close()
+		os.Remove(file.Name())
+	}()
+	out := &limitedFileWriter{file: file, maxSize: maxSize}
+
+	validator := resp.Header.Get("ETag")
+	if validator == "" {
+		validator = resp.Header.Get("Last-Modified")
+	}
+
+	// body is the response being read, or nil once it failed and the transfer must be resumed.
+	body := resp.Body
+	// The bodies are throttled through the same limiters, created once for the download, so that
+	// the per-download cap applies to the transfer as a whole rather than afresh to each resume.
+	source := &resumedBody{}
+	throttled := throttle.wrap(ctx, source)
+	err = config.RetryWithBackoff(ctx, maxResumes, func() error {
+		if body == nil {
+			next, err := resumeBundleBody(ctx, client, url, out, validator)
+			if err != nil {
+				return err
+			}
+			BundleDownloadResumesCounter.WithLabelValues(strconv.Itoa(serverID)).Inc()
+			body = next
+		}
+		source.body = body
+		_, err := io.Copy(out, throttled)
+		if body != resp.Body {
+			body.Close()
+		}
+		body = nil
+		if err != nil && (errors.Is(err, ErrBundleTooLarge) || validator == "") {
+			// Without a validator we cannot safely tell whether a ranged response
+			// belongs to the same bundle, so the error is returned as-is.
+			return &config.PermanentError{Err: err}
+		}
+		return err
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	data := make([]byte, out.written)
+	if _, err := file.ReadAt(data, 0); err != nil && !errors.Is(err, io.EOF) {
+		return nil, fmt.Errorf("failed to read GTFS bundle from temporary file: %w", err)
+	}
+	return data, nil
+}
+
+// resumeBundleBody requests the rest of the bundle after the bytes written to out, and returns
+// the body to read it from. If the server answers with the whole bundle instead, out is reset
+// to read it from the start.
+func resumeBundleBody(ctx context.Context, client *http.Client, url string, out *limitedFileWriter, validator string) (io.ReadCloser, error) {
+	next, err := requestBundleRange(ctx, client, url, out.written, validator)
+	if err != nil {
+		return nil, fmt.Errorf("failed to resume GTFS bundle download after %d bytes: %w", out.written, err)
+	}
+
+	switch next.StatusCode {
+	case http.StatusPartialContent:
+		if start, ok := parseContentRangeStart(next.Header.Get("Content-Range")); !ok || start != out.written {
+			next.Body.Close()
+			return nil, &config.PermanentError{Err: fmt.Errorf("server returned unexpected Content-Range %q when resuming at byte %d", next.Header.Get("Content-Range"), out.written)}
+		}
+	case http.StatusOK:
+		// The server ignored the range (or the bundle changed), start over.
+		if err := out.reset(); err != nil {
+			next.Body.Close()
+			return nil, &config.PermanentError{Err: err}
+		}
+	default:
+		next.Body.Close()
+		return nil, &config.PermanentError{Err: fmt.Errorf("unexpected response status %d when resuming GTFS bundle download", next.StatusCode)}
+	}
+	return next.Body, nil
+}
+
+// resumedBody reads the body of the response the transfer currently reads from.
+type resumedBody struct {
+	body io.Reader
+}
+
+func (b *resumedBody) Read(p []byte) (int, error) {
+	return b.body.Read(p)
+}
+
+// limitedFileWriter appends to a file and fails with ErrBundleTooLarge once more than
+// maxSize bytes (if positive) have been written.
+type limitedFileWriter struct {
+	file    *os.File
+	written int64
+	maxSize int64
+}
+
+func (w *limitedFileWriter) Write(p []byte) (int, error) {
+	if w.maxSize > 0 && w.written+int64(len(p)) > w.maxSize {
+		return 0, fmt.Errorf("%w: more than %d bytes received", ErrBundleTooLarge, w.maxSize)
+	}
+	n, err := w.file.Write(p)
+	w.written += int64(n)
+	return n, err
+}
+
+// reset discards everything written so far.
+func (w *limitedFileWriter) reset() error {
+	if err := w.file.Truncate(0); err != nil {
+		return err
+	}
+	if _, err := w.file.Seek(0, io.SeekStart); err != nil {
+		return err
+	}
+	w.written = 0
+	return nil
+}
+
+// requestBundleRange issues a ranged GET request for the bundle starting at offset.
+// The If-Range header makes the server fall back to a full 200 response if the bundle changed.
+func requestBundleRange(ctx context.Context, client *http.Client, url string, offset int64, validator string) (*http.Response, error) {
+	req, err := http.NewRequestWithContext(ctx, "GET", url, nil)
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Range", fmt.Sprintf("bytes=%d-", offset))
+	req.Header.Set("If-Range", validator)
+	return client.Do(req)
+}
+
+// parseContentRangeStart extracts the first byte position from a Content-Range header
+// of the form "bytes <start>-<end>/<size>".
+func parseContentRangeStart(contentRange string) (int64, bool) {
+	rangeSpec, ok := strings.CutPrefix(contentRange, "bytes ")
+	if !ok {
+		return 0, false
+	}
+	startStr, _, ok := strings.Cut(rangeSpec, "-")
+	if !ok {
+		return 0, false
+	}
+	start, err := strconv.ParseInt(startStr, 10, 64)
+	if err != nil {
+		return 0, false
+	}
+	return start, true
+}
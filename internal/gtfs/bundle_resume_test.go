@@ -0,0 +1,132 @@
+package gtfs
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"testing"
+	"time"
+)
+
+func TestReadBundleBodyResumesAfterPartialTransfer(t *testing.T) {
+	data := readFixture(t, "gtfs.zip")
+	const etag = `"bundle-v1"`
+
+	var rangeRequests int
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("ETag", etag)
+		if r.Header.Get("Range") == "" {
+			// Send only half of the bundle, then drop the connection.
+			w.Header().Set("Content-Length", strconv.Itoa(len(data)))
+			w.WriteHeader(http.StatusOK)
+			// #nosec G104
+			w.Write(data[:len(data)/2])
+			w.(http.Flusher).Flush()
+			panic(http.ErrAbortHandler)
+		}
+		rangeRequests++
+		if r.Header.Get("If-Range") != etag {
+			t.Errorf("expected If-Range %s, got %q", etag, r.Header.Get("If-Range"))
+		}
+		http.ServeContent(w, r, "gtfs.zip", time.Time{}, bytes.NewReader(data))
+	}))
+	defer server.Close()
+
+	// maxRetries 0 retries the initial request forever, and still resumes the transfer.
+	for _, maxRetries := range []int{2, 0} {
+		t.Run("maxRetries "+strconv.Itoa(maxRetries), func(t *testing.T) {
+			rangeRequests = 0
+			ctx := context.Background()
+			staticBundle, err := newTestGtfsService(GtfsServiceOptions{}).downloadGTFSBundle(ctx, server.URL, 1, maxRetries)
+			if err != nil {
+				t.Fatalf("expected download to be resumed, got error: %v", err)
+			}
+			if staticBundle == nil || len(staticBundle.static.Agencies) == 0 {
+				t.Fatal("expected a parsed bundle after resumption")
+			}
+			if rangeRequests != 1 {
+				t.Errorf("expected 1 range request, got %d", rangeRequests)
+			}
+		})
+	}
+}
+
+func TestBundleResumes(t *testing.T) {
+	if got := bundleResumes(3); got != 3 {
+		t.Errorf("expected 3 resumes for maxRetries 3, got %d", got)
+	}
+	if got := bundleResumes(0); got != defaultBundleResumes {
+		t.Errorf("expected %d resumes for maxRetries 0, got %d", defaultBundleResumes, got)
+	}
+}
+
+func TestParseContentRangeStart(t *testing.T) {
+	tests := []struct {
+		header string
+		start  int64
+		ok     bool
+	}{
+		{"bytes 100-199/200", 100, true},
+		{"bytes 0-99/*", 0, true},
+		{"items 0-1/2", 0, false},
+		{"bytes abc-1/2", 0, false},
+		{"", 0, false},
+	}
+	for _, tt := range tests {
+		start, ok := parseContentRangeStart(tt.header)
+		if ok != tt.ok || start != tt.start {
+			t.Errorf("parseContentRangeStart(%q) = (%d, %v), want (%d, %v)", tt.header, start, ok, tt.start, tt.ok)
+		}
+	}
+}
+
+func TestDownloadGTFSBundleMaxSize(t *testing.T) {
+	data := readFixture(t, "gtfs.zip")
+	ctx := context.Background()
+
+	t.Run("rejected by Content-Length", func(t *testing.T) {
+		server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			// #nosec G104
+			w.Write(data)
+		}))
+		defer server.Close()
+
+		_, err := newTestGtfsService(GtfsServiceOptions{MaxBundleSize: int64(len(data) - 1)}).downloadGTFSBundle(ctx, server.URL, 1, 1)
+		if !errors.Is(err, ErrBundleTooLarge) {
+			t.Errorf("expected ErrBundleTooLarge, got %v", err)
+		}
+	})
+
+	t.Run("rejected while streaming", func(t *testing.T) {
+		// A chunked response has no Content-Length, so the limit is enforced while reading.
+		server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			w.Header().Set("ETag", `"bundle-v1"`)
+			for i := 0; i < len(data); i += 1024 {
+				// #nosec G104
+				w.Write(data[i:min(i+1024, len(data))])
+				w.(http.Flusher).Flush()
+			}
+		}))
+		defer server.Close()
+
+		_, err := newTestGtfsService(GtfsServiceOptions{MaxBundleSize: 4096}).downloadGTFSBundle(ctx, server.URL, 1, 2)
+		if !errors.Is(err, ErrBundleTooLarge) {
+			t.Errorf("expected ErrBundleTooLarge, got %v", err)
+		}
+	})
+
+	t.Run("within limit", func(t *testing.T) {
+		server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			// #nosec G104
+			w.Write(data)
+		}))
+		defer server.Close()
+
+		if _, err := newTestGtfsService(GtfsServiceOptions{MaxBundleSize: int64(len(data))}).downloadGTFSBundle(ctx, server.URL, 1, 1); err != nil {
+			t.Errorf("expected bundle within limit to download, got %v", err)
+		}
+	})
+}
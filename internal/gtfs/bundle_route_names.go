@@ -0,0 +1,92 @@
+package gtfs
+
+import (
+	"log/slog"
+	"slices"
+	"strconv"
+
+	remoteGtfs "github.com/OneBusAway/go-gtfs"
+)
+
+// routeRenameExamples is the maximum number of renamed routes listed in the log line of a bulk
+// rename.
+const routeRenameExamples = 10
+
+// routeName is the short and long name of a route, as shown to riders.
+type routeName struct {
+	ShortName string
+	LongName  string
+}
+
+// newRouteNames collects the names of the routes of a parsed bundle, by route ID.
+func newRouteNames(staticBundle *remoteGtfs.Static) map[string]routeName {
+	names := make(map[string]routeName, len(staticBundle.Routes))
+	for _, route := range staticBundle.Routes {
+		names[route.Id] = routeName{ShortName: route.ShortName, LongName: route.LongName}
+	}
+	return names
+}
+
+// renamedRoutes returns the sorted IDs of the routes present in both bundles whose short or long
+// name changed, and the number of routes present in both.
+func renamedRoutes(previous, current map[string]routeName) ([]string, int) {
+	var renamed []string
+	kept := 0
+	for id, name := range current {
+		previousName, ok := previous[id]
+		if !ok {
+			continue
+		}
+		kept++
+		if name != previousName {
+			renamed = append(renamed, id)
+		}
+	}
+	slices.Sort(renamed)
+	return renamed, kept
+}
+
+// swapRouteNames records the route names of the latest bundle for the server and returns those of
+// the previous bundle, if any.
+func (s *BundleContentsStore) swapRouteNames(serverID int, names map[string]routeName) (map[string]routeName, bool) {
+	if s == nil {
+		return nil, false
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	previous, ok := s.routeNames[serverID]
+	s.routeNames[serverID] = names
+	return previous, ok
+}
+
+// recordRouteRenames compares the route names of a newly stored bundle with those of the previous
+// bundle of the same server, and exports the number of routes whose short or long name changed
+// under the same route ID as gtfs_routes_renamed_last_refresh. Renames break the bookmarks of
+// riders and the integrations keyed on route names, so a rename of at least
+// bundleRegressionThreshold of the routes kept is logged as a warning with some of their IDs.
+//
+// Like recordBundleChanges, the first bundle seen for a server only seeds the store. Returns the
+// number of renamed routes, or 0 if there was no previous bundle or diffing is disabled.
+func recordRouteRenames(serverID int, staticBundle *remoteGtfs.Static, contentsStore *BundleContentsStore, logger *slog.Logger) int {
+	if contentsStore == nil {
+		return 0
+	}
+	current := newRouteNames(staticBundle)
+	previous, ok := contentsStore.swapRouteNames(serverID, current)
+	if !ok {
+		return 0
+	}
+
+	renamed, kept := renamedRoutes(previous, current)
+	RoutesRenamedGauge.WithLabelValues(strconv.Itoa(serverID)).Set(float64(len(renamed)))
+	if len(renamed) == 0 {
+		return 0
+	}
+	examples := renamed[:min(len(renamed), routeRenameExamples)]
+	if float64(len(renamed)) >= bundleRegressionThreshold*float64(kept) {
+		logger.Warn("GTFS bundle renamed many routes", "server_id", serverID, "renamed", len(renamed), "routes", kept, "route_ids", examples)
+	} else {
+		logger.Info("GTFS bundle renamed routes", "server_id", serverID, "renamed", len(renamed), "routes", kept, "route_ids", examples)
+	}
+	return len(renamed)
+}
@@ -0,0 +1,57 @@
+package gtfs
+
+import (
+	"log/slog"
+	"slices"
+	"strconv"
+)
+
+// bundleSizeWindow is the number of recent bundles of a server the size of a new bundle is
+// compared with.
+const bundleSizeWindow = 5
+
+// bundleSizeDropRatio is the size ratio below which a new bundle is logged as much smaller than
+// the previous ones. The alerts use the threshold of alert.CheckBundleSizeDrop.
+const bundleSizeDropRatio = 0.5
+
+// recordBundleSize compares the size of the bundle of a server just stored with the median size
+// of its previous bundles, given the metadata of the previous bundle, and records the ratio in
+// its metadata and in gtfs_bundle_size_ratio. A bundle much smaller than usual, e.g. a 2 MB zip
+// replacing a 150 MB one, is almost always a truncated publish by the agency. A bundle with
+// the hash of the previous one is not counted again.
+func recordBundleSize(serverID int, previous BundleMetadata, metadataStore *BundleMetadataStore, logger *slog.Logger) {
+	metadata, ok := metadataStore.Get(serverID)
+	if !ok || metadata.Size <= 0 || metadata.Hash == previous.Hash {
+		return
+	}
+	recent := previous.RecentSizes
+	if len(recent) == 0 && previous.Size > 0 {
+		// The previous bundle was restored from the disk cache, without the sizes before it.
+		recent = []int64{previous.Size}
+	}
+	metadata.SizeRatio = 0
+	if len(recent) > 0 {
+		median := medianSize(recent)
+		metadata.SizeRatio = float64(metadata.Size) / float64(median)
+		BundleSizeRatioGauge.WithLabelValues(strconv.Itoa(serverID)).Set(metadata.SizeRatio)
+		if metadata.SizeRatio < bundleSizeDropRatio {
+			logger.Warn("GTFS bundle much smaller than the previous ones, possibly a truncated publish", "server_id", serverID, "size", metadata.Size, "median_size", median, "ratio", metadata.SizeRatio)
+		}
+	}
+	metadata.RecentSizes = append(slices.Clone(recent), metadata.Size)
+	if len(metadata.RecentSizes) > bundleSizeWindow {
+		metadata.RecentSizes = metadata.RecentSizes[len(metadata.RecentSizes)-bundleSizeWindow:]
+	}
+	metadataStore.Set(serverID, metadata)
+}
+
+// medianSize returns the median of sizes, which must not be empty.
+func medianSize(sizes []int64) int64 {
+	sorted := slices.Clone(sizes)
+	slices.Sort(sorted)
+	middle := len(sorted) / 2
+	if len(sorted)%2 == 0 {
+		return (sorted[middle-1] + sorted[middle]) / 2
+	}
+	return sorted[middle]
+}
@@ -0,0 +1,58 @@
+package gtfs
+
+import (
+	"log/slog"
+	"os"
+	"testing"
+)
+
+func TestRecordBundleSize(t *testing.T) {
+	logger := slog.New(slog.NewTextHandler(os.Stdout, nil))
+	store := NewBundleMetadataStore()
+
+	var previous BundleMetadata
+	store.Set(1, BundleMetadata{Hash: "a", Size: 150})
+	recordBundleSize(1, previous, store, logger)
+	metadata, _ := store.Get(1)
+	if metadata.SizeRatio != 0 || len(metadata.RecentSizes) != 1 {
+		t.Fatalf("expected no ratio for the first bundle, got %+v", metadata)
+	}
+
+	for i, size := range []int64{140, 160, 150} {
+		previous = metadata
+		store.Set(1, BundleMetadata{Hash: string(rune('b' + i)), Size: size, RecentSizes: previous.RecentSizes})
+		recordBundleSize(1, previous, store, logger)
+		metadata, _ = store.Get(1)
+	}
+	if len(metadata.RecentSizes) != 4 || metadata.SizeRatio != 1 {
+		t.Fatalf("expected a ratio of 1 to the median of 150, got %+v", metadata)
+	}
+
+	previous = metadata
+	store.Set(1, BundleMetadata{Hash: "truncated", Size: 3})
+	recordBundleSize(1, previous, store, logger)
+	metadata, _ = store.Get(1)
+	if metadata.SizeRatio != 0.02 {
+		t.Errorf("expected a ratio of 0.02, got %v", metadata.SizeRatio)
+	}
+	if got := gaugeValue(t, BundleSizeRatioGauge.WithLabelValues("1")); got != 0.02 {
+		t.Errorf("expected gtfs_bundle_size_ratio = 0.02, got %v", got)
+	}
+
+	// The same bundle downloaded again is not counted twice.
+	recordBundleSize(1, metadata, store, logger)
+	if again, _ := store.Get(1); len(again.RecentSizes) != len(metadata.RecentSizes) {
+		t.Errorf("expected an unchanged bundle to be ignored, got %+v", again)
+	}
+
+	// A bundle restored from the disk cache, without its size history, is compared with its size.
+	previous = BundleMetadata{Hash: "cached", Size: 100}
+	store.Set(2, BundleMetadata{Hash: "new", Size: 40})
+	recordBundleSize(2, previous, store, logger)
+	if metadata, _ := store.Get(2); metadata.SizeRatio != 0.4 || len(metadata.RecentSizes) != 2 {
+		t.Errorf("expected a ratio of 0.4 to the cached bundle, got %+v", metadata)
+	}
+	if got := medianSize([]int64{1, 2, 3, 4, 5, 6}); got != 3 {
+		t.Errorf("expected a median of 3, got %d", got)
+	}
+}
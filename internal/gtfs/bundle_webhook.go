@@ -0,0 +1,174 @@
+package gtfs
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/getsentry/sentry-go"
+	"watchdog.onebusaway.org/internal/config"
+	"watchdog.onebusaway.org/internal/models"
+	"watchdog.onebusaway.org/internal/report"
+	"watchdog.onebusaway.org/internal/utils"
+)
+
+// BundleChangedEventType is the `event` field of every bundle change webhook payload.
+const BundleChangedEventType = "gtfs_bundle.changed"
+
+// BundleChangedEvent is the JSON payload posted when a server's GTFS bundle changed and was
+// downloaded, parsed and stored successfully.
+//
+// It carries enough metadata for a deployment pipeline (e.g. the OneBusAway bundle builder)
+// to decide whether to rebuild, without polling the agency URL itself.
+type BundleChangedEvent struct {
+	Event        string             `json:"event"`
+	ServerID     int                `json:"server_id"`
+	ServerName   string             `json:"server_name"`
+	GtfsURL      string             `json:"gtfs_url"`
+	Hash         string             `json:"sha256"`
+	ETag         string             `json:"etag,omitempty"`
+	LastModified string             `json:"last_modified,omitempty"`
+	DownloadedAt time.Time          `json:"downloaded_at"`
+	FeedInfo     *bundleEventFeed   `json:"feed_info,omitempty"`
+	Changes      []bundleEventDelta `json:"changes,omitempty"`
+}
+
+// bundleEventFeed is the feed_info.txt part of a BundleChangedEvent.
+type bundleEventFeed struct {
+	Version       string `json:"feed_version,omitempty"`
+	PublisherName string `json:"feed_publisher_name,omitempty"`
+	StartDate     string `json:"feed_start_date,omitempty"`
+	EndDate       string `json:"feed_end_date,omitempty"`
+}
+
+// bundleEventDelta is the JSON form of an EntityChange.
+type bundleEventDelta struct {
+	Entity   string `json:"entity"`
+	Previous int    `json:"previous"`
+	Current  int    `json:"current"`
+	Added    int    `json:"added"`
+	Removed  int    `json:"removed"`
+}
+
+// newBundleChangedEvent builds the webhook payload for a stored bundle.
+// changes is nil for the first bundle seen for a server.
+func newBundleChangedEvent(server models.ObaServer, metadata BundleMetadata, changes []EntityChange) BundleChangedEvent {
+	event := BundleChangedEvent{
+		Event:        BundleChangedEventType,
+		ServerID:     server.ID,
+		ServerName:   server.Name,
+		GtfsURL:      server.GtfsUrl,
+		Hash:         metadata.Hash,
+		ETag:         metadata.ETag,
+		LastModified: metadata.LastModified,
+		DownloadedAt: metadata.DownloadedAt,
+	}
+	if feedInfo := metadata.FeedInfo; feedInfo != nil {
+		event.FeedInfo = &bundleEventFeed{
+			Version:       feedInfo.Version,
+			PublisherName: feedInfo.PublisherName,
+		}
+		if !feedInfo.StartDate.IsZero() {
+			event.FeedInfo.StartDate = feedInfo.StartDate.Format(time.DateOnly)
+		}
+		if !feedInfo.EndDate.IsZero() {
+			event.FeedInfo.EndDate = feedInfo.EndDate.Format(time.DateOnly)
+		}
+	}
+	for _, change := range changes {
+		event.Changes = append(event.Changes, bundleEventDelta(change))
+	}
+	return event
+}
+
+// BundleChangeNotifier posts a BundleChangedEvent to a webhook URL whenever a changed bundle
+// has been stored. A nil *BundleChangeNotifier is valid and disables notifications.
+type BundleChangeNotifier struct {
+	url        string
+	client     *http.Client
+	maxRetries int
+}
+
+// NewBundleChangeNotifier creates a notifier posting to url with the given client.
+// Failed deliveries are retried up to maxRetries times with exponential backoff.
+// Returns nil (notifications disabled) if url is empty.
+func NewBundleChangeNotifier(url string, client *http.Client, maxRetries int) *BundleChangeNotifier {
+	if url == "" {
+		return nil
+	}
+	return &BundleChangeNotifier{url: url, client: client, maxRetries: maxRetries}
+}
+
+// Notify delivers the event. Any 2xx response counts as delivered.
+//
+// A new request is built for every attempt, since a request body cannot be replayed,
+// and attempts are spaced with exponential backoff (see config.RetryWithBackoff). Only
+// network errors, 429 and 5xx responses are retried.
+func (n *BundleChangeNotifier) Notify(ctx context.Context, event BundleChangedEvent) error {
+	if n == nil {
+		return nil
+	}
+	body, err := json.Marshal(event)
+	if err != nil {
+		return fmt.Errorf("failed to encode bundle change event: %w", err)
+	}
+
+	err = config.RetryWithBackoff(ctx, n.maxRetries, func() error {
+		return n.post(ctx, body)
+	})
+	if err != nil {
+		return fmt.Errorf("failed to deliver bundle change webhook: %w", err)
+	}
+	return nil
+}
+
+func (n *BundleChangeNotifier) post(ctx context.Context, body []byte) error {
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, n.url, bytes.NewReader(body))
+	if err != nil {
+		return &config.PermanentError{Err: err}
+	}
+	req.Header.Set("Content-Type", "application/json")
+	resp, err := n.client.Do(req)
+	if err != nil {
+		return err
+	}
+	resp.Body.Close()
+	switch {
+	case resp.StatusCode >= 200 && resp.StatusCode <= 299:
+		return nil
+	case resp.StatusCode == http.StatusTooManyRequests || resp.StatusCode >= 500:
+		return fmt.Errorf("webhook responded with status %d", resp.StatusCode)
+	default:
+		// The receiver rejected the event, sending it again would not change that.
+		return &config.PermanentError{Err: fmt.Errorf("webhook responded with status %d", resp.StatusCode)}
+	}
+}
+
+// notifyBundleChanged sends the bundle change webhook for a server whose new bundle was stored,
+// and records the outcome in BundleWebhookDeliveriesCounter. Delivery failures are logged and
+// reported but never affect the stored bundle.
+func notifyBundleChanged(ctx context.Context, notifier *BundleChangeNotifier, server models.ObaServer, metadata BundleMetadata, changes []EntityChange, logger *slog.Logger) {
+	if notifier == nil {
+		return
+	}
+	err := notifier.Notify(ctx, newBundleChangedEvent(server, metadata, changes))
+	if err != nil {
+		BundleWebhookDeliveriesCounter.WithLabelValues(strconv.Itoa(server.ID), "failure").Inc()
+		report.ReportErrorWithSentryOptions(err, report.SentryReportOptions{
+			Tags: utils.MakeMap("server_id", strconv.Itoa(server.ID)),
+			ExtraContext: map[string]interface{}{
+				"gtfs_url": server.GtfsUrl,
+			},
+			Level: sentry.LevelWarning,
+		})
+		logger.Warn("Failed to send bundle change webhook", "server_id", server.ID, "error", err)
+		return
+	}
+	BundleWebhookDeliveriesCounter.WithLabelValues(strconv.Itoa(server.ID), "success").Inc()
+	logger.Info("Sent bundle change webhook", "server_id", server.ID, "hash", metadata.Hash)
+}
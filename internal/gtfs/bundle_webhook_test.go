@@ -0,0 +1,122 @@
+package gtfs
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+
+	"watchdog.onebusaway.org/internal/models"
+)
+
+func TestNewBundleChangeNotifierDisabled(t *testing.T) {
+	notifier := NewBundleChangeNotifier("", http.DefaultClient, 0)
+	if notifier != nil {
+		t.Fatal("expected a nil notifier for an empty URL")
+	}
+	if err := notifier.Notify(context.Background(), BundleChangedEvent{}); err != nil {
+		t.Errorf("expected a nil notifier to be a no-op, got %v", err)
+	}
+}
+
+func TestBundleChangeNotifierRetries(t *testing.T) {
+	attempts := 0
+	webhook := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		attempts++
+		var event BundleChangedEvent
+		if err := json.NewDecoder(r.Body).Decode(&event); err != nil {
+			t.Errorf("attempt %d: failed to decode webhook body: %v", attempts, err)
+		}
+		if attempts == 1 {
+			w.WriteHeader(http.StatusServiceUnavailable)
+			return
+		}
+		w.WriteHeader(http.StatusNoContent)
+	}))
+	defer webhook.Close()
+
+	notifier := NewBundleChangeNotifier(webhook.URL, webhook.Client(), 1)
+	if err := notifier.Notify(context.Background(), BundleChangedEvent{Event: BundleChangedEventType}); err != nil {
+		t.Fatalf("Notify failed: %v", err)
+	}
+	if attempts != 2 {
+		t.Errorf("expected 2 attempts, got %d", attempts)
+	}
+}
+
+func TestBundleChangeNotifierDoesNotRetryRejectedEvent(t *testing.T) {
+	var attempts atomic.Int32
+	webhook := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		attempts.Add(1)
+		w.WriteHeader(http.StatusBadRequest)
+	}))
+	defer webhook.Close()
+
+	notifier := NewBundleChangeNotifier(webhook.URL, webhook.Client(), 3)
+	if err := notifier.Notify(context.Background(), BundleChangedEvent{Event: BundleChangedEventType}); err == nil {
+		t.Fatal("expected a rejected event to fail")
+	}
+	if got := attempts.Load(); got != 1 {
+		t.Errorf("expected a 4xx response not to be retried, got %d attempts", got)
+	}
+}
+
+func TestDownloadGTFSBundlesNotifiesChangedBundle(t *testing.T) {
+	gtfsServer := setupGtfsServer(t, "gtfs.zip")
+	defer gtfsServer.Close()
+
+	events := make(chan BundleChangedEvent, 2)
+	webhook := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var event BundleChangedEvent
+		if err := json.NewDecoder(r.Body).Decode(&event); err != nil {
+			t.Errorf("failed to decode webhook body: %v", err)
+		}
+		events <- event
+	}))
+	defer webhook.Close()
+
+	servers := []models.ObaServer{{ID: 1, Name: "Test Server", GtfsUrl: gtfsServer.URL}}
+	notifier := NewBundleChangeNotifier(webhook.URL, webhook.Client(), 0)
+	gs := newTestGtfsService(GtfsServiceOptions{BundleNotifier: notifier})
+	metadataStore := gs.BundleMetadata
+
+	download := func() {
+		gs.DownloadGTFSBundles(context.Background(), servers, 1)
+	}
+
+	// The first bundle seen for a server is not a change.
+	download()
+	if len(events) != 0 {
+		t.Fatalf("expected no webhook for the first bundle, got %d", len(events))
+	}
+
+	// Same bundle again: the hash is unchanged.
+	download()
+	if len(events) != 0 {
+		t.Fatalf("expected no webhook for an identical bundle, got %d", len(events))
+	}
+
+	metadata, _ := metadataStore.Get(1)
+	metadata.Hash = "previous"
+	metadataStore.Set(1, metadata)
+	download()
+
+	if len(events) != 1 {
+		t.Fatalf("expected one webhook for a changed bundle, got %d", len(events))
+	}
+	event := <-events
+	if event.Event != BundleChangedEventType || event.ServerID != 1 || event.ServerName != "Test Server" {
+		t.Errorf("unexpected event header: %+v", event)
+	}
+	if event.GtfsURL != gtfsServer.URL || event.Hash == "" || event.Hash == "previous" {
+		t.Errorf("unexpected bundle fields: url=%q hash=%q", event.GtfsURL, event.Hash)
+	}
+	if event.FeedInfo == nil || event.FeedInfo.Version != "SC-Fall-2024.11" || event.FeedInfo.EndDate != "2025-03-28" {
+		t.Errorf("unexpected feed info: %+v", event.FeedInfo)
+	}
+	if got := counterValue(t, BundleWebhookDeliveriesCounter.WithLabelValues("1", "success")); got < 1 {
+		t.Errorf("expected a successful delivery to be counted, got %v", got)
+	}
+}
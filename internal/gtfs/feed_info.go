@@ -0,0 +1,120 @@
+package gtfs
+
+import (
+	"archive/zip"
+	"bytes"
+	"encoding/csv"
+	"errors"
+	"fmt"
+	"io"
+	"strings"
+	"time"
+
+	"watchdog.onebusaway.org/internal/models"
+)
+
+// feedInfoFile is the name of the optional GTFS file describing the feed itself.
+const feedInfoFile = "feed_info.txt"
+
+// parseFeedInfo extracts feed_info.txt from a raw GTFS bundle and parses its first record.
+//
+// The GTFS library used for parsing bundles does not read feed_info.txt, which carries the
+// publisher's own validity window (feed_start_date / feed_end_date) and feed_version.
+// This small parser reads just that file from the zip, so the rest of the bundle is not decompressed.
+//
+// Returns nil (and no error) if the bundle has no feed_info.txt or the file has no records.
+// Dates use the GTFS YYYYMMDD format and are returned as midnight UTC; invalid dates are an error.
+func parseFeedInfo(data []byte) (*models.FeedInfo, error) {
+	records, err := readBundleCSV(data, feedInfoFile, 1)
+	if err != nil || len(records) == 0 {
+		return nil, err
+	}
+	columns := records[0]
+
+	feedInfo := &models.FeedInfo{
+		PublisherName: columns["feed_publisher_name"],
+		PublisherURL:  columns["feed_publisher_url"],
+		Lang:          columns["feed_lang"],
+		Version:       columns["feed_version"],
+		ContactEmail:  columns["feed_contact_email"],
+		ContactURL:    columns["feed_contact_url"],
+	}
+	if feedInfo.StartDate, err = parseGTFSDate(columns["feed_start_date"]); err != nil {
+		return nil, fmt.Errorf("invalid feed_start_date in %s: %w", feedInfoFile, err)
+	}
+	if feedInfo.EndDate, err = parseGTFSDate(columns["feed_end_date"]); err != nil {
+		return nil, fmt.Errorf("invalid feed_end_date in %s: %w", feedInfoFile, err)
+	}
+	return feedInfo, nil
+}
+
+// readBundleCSV reads up to limit records (0 = all) of a CSV file of a raw GTFS bundle, as
+// maps from column names to trimmed values. Only that file is decompressed.
+// It returns no records (and no error) if the bundle has no such file.
+func readBundleCSV(data []byte, name string, limit int) ([]map[string]string, error) {
+	reader, err := zip.NewReader(bytes.NewReader(data), int64(len(data)))
+	if err != nil {
+		return nil, fmt.Errorf("failed to open GTFS bundle: %w", err)
+	}
+
+	var file *zip.File
+	for _, f := range reader.File {
+		if f.Name == name {
+			file = f
+			break
+		}
+	}
+	if file == nil {
+		return nil, nil
+	}
+
+	rc, err := file.Open()
+	if err != nil {
+		return nil, fmt.Errorf("failed to open %s: %w", name, err)
+	}
+	defer rc.Close()
+
+	csvReader := csv.NewReader(rc)
+	csvReader.FieldsPerRecord = -1
+	header, err := csvReader.Read()
+	if err != nil {
+		if errors.Is(err, io.EOF) {
+			return nil, nil
+		}
+		return nil, fmt.Errorf("failed to read %s header: %w", name, err)
+	}
+	for i := range header {
+		if i == 0 {
+			// Many producers write a UTF-8 byte order mark at the start of the file.
+			header[i] = strings.TrimPrefix(header[i], "\ufeff")
+		}
+		header[i] = strings.TrimSpace(header[i])
+	}
+
+	var records []map[string]string
+	for limit == 0 || len(records) < limit {
+		record, err := csvReader.Read()
+		if errors.Is(err, io.EOF) {
+			break
+		}
+		if err != nil {
+			return nil, fmt.Errorf("failed to read %s record: %w", name, err)
+		}
+		columns := make(map[string]string, len(header))
+		for i, column := range header {
+			if i < len(record) {
+				columns[column] = strings.TrimSpace(record[i])
+			}
+		}
+		records = append(records, columns)
+	}
+	return records, nil
+}
+
+// parseGTFSDate parses a GTFS YYYYMMDD date. An empty value yields the zero time.
+func parseGTFSDate(value string) (time.Time, error) {
+	if value == "" {
+		return time.Time{}, nil
+	}
+	return time.Parse("20060102", value)
+}
@@ -0,0 +1,79 @@
+package gtfs
+
+import (
+	"archive/zip"
+	"bytes"
+	"testing"
+	"time"
+)
+
+// zipWithFiles builds an in-memory zip archive containing the given files.
+func zipWithFiles(t *testing.T, files map[string]string) []byte {
+	t.Helper()
+	var buf bytes.Buffer
+	w := zip.NewWriter(&buf)
+	for name, content := range files {
+		f, err := w.Create(name)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if _, err := f.Write([]byte(content)); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := w.Close(); err != nil {
+		t.Fatal(err)
+	}
+	return buf.Bytes()
+}
+
+func TestParseFeedInfo(t *testing.T) {
+	t.Run("fixture bundle", func(t *testing.T) {
+		feedInfo, err := parseFeedInfo(readFixture(t, "gtfs.zip"))
+		if err != nil {
+			t.Fatalf("parseFeedInfo failed: %v", err)
+		}
+		if feedInfo == nil {
+			t.Fatal("expected feed info from fixture bundle")
+		}
+		if feedInfo.PublisherName != "Sound Transit" || feedInfo.Version != "SC-Fall-2024.11" || feedInfo.Lang != "en" {
+			t.Errorf("unexpected feed info: %+v", feedInfo)
+		}
+		if !feedInfo.StartDate.Equal(time.Date(2024, 12, 1, 0, 0, 0, 0, time.UTC)) {
+			t.Errorf("unexpected feed_start_date: %v", feedInfo.StartDate)
+		}
+		if !feedInfo.EndDate.Equal(time.Date(2025, 3, 28, 0, 0, 0, 0, time.UTC)) {
+			t.Errorf("unexpected feed_end_date: %v", feedInfo.EndDate)
+		}
+	})
+
+	t.Run("byte order mark and missing dates", func(t *testing.T) {
+		data := zipWithFiles(t, map[string]string{
+			"feed_info.txt": "\ufefffeed_version,feed_publisher_name\nv2,Agency\n",
+		})
+		feedInfo, err := parseFeedInfo(data)
+		if err != nil {
+			t.Fatalf("parseFeedInfo failed: %v", err)
+		}
+		if feedInfo.Version != "v2" || feedInfo.PublisherName != "Agency" {
+			t.Errorf("unexpected feed info: %+v", feedInfo)
+		}
+		if !feedInfo.EndDate.IsZero() || !feedInfo.StartDate.IsZero() {
+			t.Errorf("expected zero dates, got %v / %v", feedInfo.StartDate, feedInfo.EndDate)
+		}
+	})
+
+	t.Run("no feed_info.txt", func(t *testing.T) {
+		feedInfo, err := parseFeedInfo(zipWithFiles(t, map[string]string{"agency.txt": "agency_id\n1\n"}))
+		if err != nil || feedInfo != nil {
+			t.Errorf("expected (nil, nil), got (%+v, %v)", feedInfo, err)
+		}
+	})
+
+	t.Run("invalid date", func(t *testing.T) {
+		data := zipWithFiles(t, map[string]string{"feed_info.txt": "feed_end_date\n2025-03-28\n"})
+		if _, err := parseFeedInfo(data); err == nil {
+			t.Error("expected an error for an invalid feed_end_date")
+		}
+	})
+}
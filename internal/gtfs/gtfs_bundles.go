@@ -2,9 +2,9 @@ package gtfs
 
 import (
 	"context"
+	"errors"
 	"fmt"
 	"io"
-	"log/slog"
 	"net/http"
 	"net/url"
 	"strconv"
@@ -15,36 +15,69 @@ import (
 	"github.com/getsentry/sentry-go"
 	"watchdog.onebusaway.org/internal/config"
 	"watchdog.onebusaway.org/internal/geo"
+	"watchdog.onebusaway.org/internal/httpclient"
 	"watchdog.onebusaway.org/internal/models"
 	"watchdog.onebusaway.org/internal/report"
+	"watchdog.onebusaway.org/internal/scheduler"
 	"watchdog.onebusaway.org/internal/utils"
 )
 
-// downloadGTFSBundles fetches and processes GTFS static bundles concurrently for a list of OBA servers.
+// ErrBundleNotModified is returned by downloadGTFSBundle when the agency server reports
+// (via 304 Not Modified) that the bundle has not changed since the last successful download.
+// It is not a failure: callers should keep the previously stored data and skip parsing.
+var ErrBundleNotModified = errors.New("GTFS bundle not modified")
+
+// downloadedBundle is a GTFS static bundle that was downloaded and parsed but not stored yet.
+// Its metadata are recorded, and its raw data cached, only once it is stored (see
+// commitGTFSBundle), so that a bundle that fails to be stored is downloaded again on the next
+// refresh instead of being answered 304 Not Modified with its validators.
+type downloadedBundle struct {
+	static   *remoteGtfs.Static
+	metadata BundleMetadata
+	// url is the URL the bundle was downloaded from, and data the raw bundle saved to the disk
+	// cache (nil = not cached).
+	url  string
+	data []byte
+	// feeds are the bundles a bundle merged from those of a server with several GTFS URLs was
+	// merged from, each cached on its own (see mergeFeeds).
+	feeds []*downloadedBundle
+}
+
+// DownloadGTFSBundles fetches and processes GTFS static bundles concurrently for a list of OBA servers.
 //
 // For each server, it starts a dedicated goroutine that:
-//   1. Attempts to download and parse the GTFS static bundle from the server’s GTFS URL,
-//      using exponential backoff with retries (up to maxRetries).
-//   2. Stores the parsed GTFS static data in the provided StaticStore, keyed by server ID.
-//   3. Computes a geographic bounding box from the stop locations in the static data.
-//   4. Stores the bounding box in the provided BoundingBoxStore.
+//  1. Attempts to download and parse the GTFS static bundle from the server’s GTFS URL,
+//     using exponential backoff with retries (up to maxRetries). The bundles of a server
+//     with several GTFS URLs are merged into one (see downloadGTFSFeeds).
+//     If the server answers 304 Not Modified, parsing, storing and bounding box
+//     computation are skipped and the previously stored data is kept.
+//  2. Stores the parsed GTFS static data in the StaticStore, keyed by server ID.
+//  3. Computes a geographic bounding box from the stop locations in the static data.
+//  4. Stores the bounding box in the BoundingBoxStore.
+//  5. Records the metadata of the bundle, with its validators, in BundleMetadata and saves it
+//     to BundleDiskCache (see commitGTFSBundle). A bundle that fails to be stored leaves the
+//     previous metadata in place, so it is downloaded again on the next refresh.
 //
 // Concurrency:
 //   - A goroutine is launched for each server.
 //   - sync.WaitGroup is used to ensure all goroutines complete before the function returns.
 //   - Errors are handled per-server, reported via Sentry and logs, but do not stop processing other servers.
 //
-// Parameters:
-//   - ctx: Context used to manage cancellation and timeouts across all goroutines.
-//   - servers: A list of OBA servers, each containing a GTFS URL and unique ID.
-//   - logger: A structured logger for recording success/failure logs.
-//   - boundingBoxStore: A store for computed bounding boxes, one per server.
-//   - staticStore: A store for parsed GTFS static data, keyed by server ID.
-//   - maxRetries: The maximum number of retries (with exponential backoff) when downloading a bundle.
+// The bundles are downloaded with BundleClient, within the limits of BundleThrottle and
+// MaxBundleSize, conditionally with the validators of BundleMetadata, and cached in
+// BundleDiskCache (see downloadGTFSBundle).
+//
+// The BundleChangedGauge metric is set to 1 when a new bundle was stored and to 0 when the bundle was not modified.
+// BundleDownloadConsecutiveFailuresGauge counts failed refreshes in a row (download or storage errors).
+// The download and parse durations and the size of changed bundles are recorded by downloadGTFSBundle
+// and storeGTFSBundle.
+// Each newly stored bundle is diffed against the previous one of BundleContents (see recordBundleChanges and
+// recordRouteRenames). When the previous bundle is known and its hash differs, the change is also sent to
+// BundleNotifier (see notifyBundleChanged); the first bundle seen for a server, e.g. right after a start without a disk cache, does not trigger a notification.
 //
 // This function does not return an error; failures are handled and reported individually per server.
-
-func downloadGTFSBundles(ctx context.Context, servers []models.ObaServer, logger *slog.Logger, boundingBoxStore *geo.BoundingBoxStore, staticStore *StaticStore, maxRetries int) {
+func (gs *GtfsService) DownloadGTFSBundles(ctx context.Context, servers []models.ObaServer, maxRetries int) {
+	logger, metadataStore, staticStore := gs.Logger, gs.BundleMetadata, gs.StaticStore
 	var wg sync.WaitGroup
 	for _, server := range servers {
 		s := server
@@ -52,7 +85,15 @@ func downloadGTFSBundles(ctx context.Context, servers []models.ObaServer, logger
 		go func() {
 			defer wg.Done()
 
-			staticBundle, err := downloadGTFSBundle(ctx, s.GtfsUrl, s.ID, maxRetries)
+			previous, _ := metadataStore.Get(s.ID)
+			previousData, hadBundle := staticStore.Get(s.ID)
+			bundle, err := gs.downloadGTFSFeeds(ctx, s, maxRetries)
+			if errors.Is(err, ErrBundleNotModified) {
+				BundleChangedGauge.WithLabelValues(strconv.Itoa(s.ID)).Set(0)
+				BundleDownloadConsecutiveFailuresGauge.WithLabelValues(strconv.Itoa(s.ID)).Set(0)
+				logger.Info("GTFS bundle not modified, keeping stored data", "server_id", s.ID)
+				return
+			}
 			if err != nil {
 				report.ReportErrorWithSentryOptions(err, report.SentryReportOptions{
 					Tags: utils.MakeMap("server_id", fmt.Sprintf("%d", server.ID)),
@@ -62,11 +103,14 @@ func downloadGTFSBundles(ctx context.Context, servers []models.ObaServer, logger
 					Level: sentry.LevelError,
 				})
 				logger.Error("Failed to download GTFS bundle", "server_id", s.ID, "error", err)
+				failures := metadataStore.markFailed(s.ID)
+				BundleDownloadConsecutiveFailuresGauge.WithLabelValues(strconv.Itoa(s.ID)).Set(float64(failures))
 				return
 			}
 			logger.Info("Successfully downloaded GTFS bundle", "server_id", s.ID)
 
-			err = storeGTFSBundle(staticBundle, s.ID, staticStore, boundingBoxStore)
+			staticBundle, metadata := bundle.static, bundle.metadata
+			err = storeGTFSBundle(staticBundle, metadata, s.ID, staticStore, gs.BoundingBoxStore)
 			if err != nil {
 				report.ReportErrorWithSentryOptions(err, report.SentryReportOptions{
 					Tags: utils.MakeMap("server_id", fmt.Sprintf("%d", s.ID)),
@@ -76,70 +120,99 @@ func downloadGTFSBundles(ctx context.Context, servers []models.ObaServer, logger
 					Level: sentry.LevelError,
 				})
 				logger.Error("Failed to store GTFS bundle", "server_id", s.ID, "error", err)
+				failures := metadataStore.markFailed(s.ID)
+				BundleDownloadConsecutiveFailuresGauge.WithLabelValues(strconv.Itoa(s.ID)).Set(float64(failures))
+				return
+			}
+			gs.commitGTFSBundle(s.ID, bundle)
+			BundleChangedGauge.WithLabelValues(strconv.Itoa(s.ID)).Set(1)
+			BundleDownloadConsecutiveFailuresGauge.WithLabelValues(strconv.Itoa(s.ID)).Set(0)
+			recordBundleSize(s.ID, previous, metadataStore, logger)
+			changes := recordBundleChanges(s.ID, staticBundle, gs.BundleContents, logger)
+			recordRouteRenames(s.ID, staticBundle, gs.BundleContents, logger)
+			if hadBundle && previousData != nil {
+				checkLicenseChange(s, models.NewLicense(previousData.FeedInfo, previousData.Attributions), models.NewLicense(metadata.FeedInfo, metadata.Attributions), logger)
+			}
+			if previous.Hash != "" && previous.Hash != metadata.Hash {
+				notifyBundleChanged(ctx, gs.BundleNotifier, s, metadata, changes, logger)
 			}
 		}()
 	}
 	wg.Wait()
 }
 
-// refreshGTFSBundles periodically refreshes GTFS static bundles for a list of OBA servers.
+// RefreshGTFSBundles periodically refreshes GTFS static bundles for a list of OBA servers.
 //
-// It runs in a loop, triggered at the specified interval, and performs the following:
-//   1. Logs the refresh operation.
-//   2. Calls downloadGTFSBundles to fetch, parse, and store updated GTFS data for all servers.
-//      - Each server’s bundle download uses exponential backoff with retries, up to maxRetries attempts.
-//   3. Updates geographic bounding boxes based on the downloaded data.
+// It runs in a loop, triggered at each activation of the given schedule, and performs the following:
+//  1. Logs the refresh operation.
+//  2. Calls DownloadGTFSBundles to fetch, parse, and store updated GTFS data for all servers.
+//     The server list is read again on every refresh, so servers added or removed at runtime
+//     (through the admin API or a remote configuration) are picked up.
+//     - Each server’s bundle download uses exponential backoff with retries, up to maxRetries attempts.
+//  3. Updates geographic bounding boxes based on the downloaded data.
 //
 // The function listens for context cancellation (`ctx.Done()`) to gracefully stop the refresh routine.
-//
-// Parameters:
-//   - ctx: Context used to cancel the refresh routine gracefully.
-//   - servers: List of OBA servers to fetch GTFS data from.
-//   - logger: Logger for structured logging of refresh activity.
-//   - interval: Time duration between each refresh cycle.
-//   - boundingBoxStore: Store to keep geographic bounding boxes per server.
-//   - staticStore: Store to keep parsed GTFS static data per server.
-//   - maxRetries: Maximum number of retries (with exponential backoff) for each server’s bundle download.
-
-func refreshGTFSBundles(ctx context.Context, servers []models.ObaServer, logger *slog.Logger, interval time.Duration, boundingBoxstore *geo.BoundingBoxStore, staticStore *StaticStore, maxRetries int) {
-	ticker := time.NewTicker(interval)
-	defer ticker.Stop()
-	for {
-		select {
-		case <-ctx.Done():
-			logger.Info("Stopping GTFS bundle refresh routine")
-			return
-		case <-ticker.C:
-			logger.Info("Refreshing GTFS bundles")
-			downloadGTFSBundles(ctx, servers, logger, boundingBoxstore, staticStore, maxRetries)
-		}
-	}
+func (gs *GtfsService) RefreshGTFSBundles(ctx context.Context, servers func() []models.ObaServer, schedule scheduler.Schedule, maxRetries int) {
+	scheduler.Run(ctx, "gtfs_bundle_refresh", schedule, func() {
+		gs.Logger.Info("Refreshing GTFS bundles")
+		gs.DownloadGTFSBundles(ctx, servers(), maxRetries)
+	})
+	gs.Logger.Info("Stopping GTFS bundle refresh routine")
 }
 
-// downloadAndStoreGTFSBundle fetches a GTFS static bundle from the provided URL,
-// parses it, and stores the resulting static data in the given StaticStore using
-// the serverID as the key. Requests are executed with exponential backoff to handle
-// transient network errors (e.g., timeouts, connection failures).
+// downloadGTFSBundle fetches a GTFS static bundle from the provided URL and parses it,
+// for DownloadGTFSBundles to store and commit. Requests are executed with exponential
+// backoff to handle transient network errors (e.g., timeouts, connection failures).
 //
 // It performs the following steps:
 //   1. Makes an HTTP GET request (with exponential backoff) to download the GTFS bundle.
-//   2. Reads and parses the response body as GTFS static data.
-//   3. Stores the parsed data in the StaticStore.
+//      If validators from a previous download are known, the request is made conditional
+//      with If-None-Match / If-Modified-Since.
+//   2. Returns ErrBundleNotModified if the server answers 304 Not Modified.
+//   3. Streams the response body to a temporary file, resuming with HTTP Range requests
+//      if the transfer fails partway (see readBundleBody), and parses it as GTFS static data.
+//   4. Returns the bundle with the response's ETag / Last-Modified, the bundle hash and the
+//      parsed feed_info.txt and attribution.txt (see parseFeedInfo and parseAttributions) as
+//      its metadata, and the raw bundle for the disk cache, if one is configured.
+//
+// A url that is a local path or a file:// URL is read from the disk instead (see
+// readLocalGTFSBundle), and never cached.
 //
 // Parameters:
 //   - url: The URL of the GTFS static bundle (usually a zip file).
-//   - serverID: The identifier used to store and retrieve the static data from the store.
-//   - staticStore: The in-memory store that holds GTFS static data indexed by server ID.
+//   - serverID: The identifier the metadata of the bundle are recorded with.
 //   - maxRetries: The maximum number of retry attempts allowed during exponential backoff
-//                 before giving up on reaching the server
+//                 before giving up on reaching the server (0 = unlimited), and of resumes of
+//                 the transfer (0 = defaultBundleResumes, see bundleResumes)
+//
+// The bundle is downloaded with BundleClient (nil = httpclient.Default().Bundle), whose overall
+// timeout throttled downloads ignore, and read within the bandwidth caps of BundleThrottle.
+// Bundles larger than MaxBundleSize (0 = unlimited) fail with ErrBundleTooLarge.
 //
 // Returns:
-//   - gtfs static data
-//   - error: Describes what went wrong, or nil if the operation was successful.
+//   - the downloaded bundle, not yet stored nor recorded in BundleMetadata
+//   - error: Describes what went wrong, ErrBundleNotModified if the bundle is unchanged,
+//     or nil if the operation was successful.
 
-func downloadGTFSBundle(ctx context.Context, url string, serverID int, maxRetries int) (*remoteGtfs.Static, error) {
-	client := &http.Client{Timeout: 10 * time.Second}
-	req, err := http.NewRequest("GET", url, nil)
+func (gs *GtfsService) downloadGTFSBundle(ctx context.Context, url string, serverID int, maxRetries int) (*downloadedBundle, error) {
+	client, throttle, metadataStore, maxBundleSize := gs.BundleClient, gs.BundleThrottle, gs.BundleMetadata, gs.MaxBundleSize
+	if client == nil {
+		client = httpclient.Default().Bundle
+	}
+	ctx = httpclient.WithTarget(ctx, serverID, httpclient.TargetGTFSStatic)
+	if throttle.enabled() {
+		// A throttled transfer of a large bundle legitimately takes longer than the
+		// overall client timeout, so only the wait for the response headers (bounded by
+		// the transport) is.
+		ctx = httpclient.WithoutTimeout(ctx)
+		unbounded := *client
+		unbounded.Timeout = 0
+		client = &unbounded
+	}
+	if path, ok := config.LocalBundlePath(url); ok {
+		return readLocalGTFSBundle(path, url, serverID, metadataStore, maxBundleSize)
+	}
+	req, err := http.NewRequestWithContext(ctx, "GET", url, nil)
 	if err != nil {
 		err = fmt.Errorf("failed to create request for %s: %w", url, err)
 		report.ReportErrorWithSentryOptions(err, report.SentryReportOptions{
@@ -151,6 +224,16 @@ func downloadGTFSBundle(ctx context.Context, url string, serverID int, maxRetrie
 		return nil, err
 	}
 
+	if previous, ok := metadataStore.Get(serverID); ok && previous.hasValidators() {
+		if previous.ETag != "" {
+			req.Header.Set("If-None-Match", previous.ETag)
+		}
+		if previous.LastModified != "" {
+			req.Header.Set("If-Modified-Since", previous.LastModified)
+		}
+	}
+
+	downloadStart := time.Now()
 	resp, err := config.DoWithBackoff(ctx, client, req, maxRetries)
 
 	if err != nil {
@@ -165,6 +248,11 @@ func downloadGTFSBundle(ctx context.Context, url string, serverID int, maxRetrie
 	}
 	defer resp.Body.Close()
 
+	if resp.StatusCode == http.StatusNotModified {
+		metadataStore.markChecked(serverID, time.Now().UTC())
+		return nil, ErrBundleNotModified
+	}
+
 	if resp.StatusCode != http.StatusOK {
 		err = fmt.Errorf("unexpected response status %d when downloading GTFS bundle from %s", resp.StatusCode, url)
 		report.ReportErrorWithSentryOptions(err, report.SentryReportOptions{
@@ -177,14 +265,34 @@ func downloadGTFSBundle(ctx context.Context, url string, serverID int, maxRetrie
 		return nil, err
 	}
 
-	data, err := io.ReadAll(resp.Body)
+	data, err := readBundleBody(ctx, client, url, serverID, resp, bundleResumes(maxRetries), throttle, maxBundleSize)
 	if err != nil {
 		err = fmt.Errorf("failed to read GTFS bundle response body from %s: %w", url, err)
-		report.ReportError(err)
+		report.ReportErrorWithSentryOptions(err, report.SentryReportOptions{
+			Tags: utils.MakeMap("server_id", strconv.Itoa(serverID)),
+			ExtraContext: map[string]interface{}{
+				"url": url,
+			},
+		})
 		return nil, err
 	}
+	BundleDownloadDurationHistogram.WithLabelValues(strconv.Itoa(serverID)).Observe(time.Since(downloadStart).Seconds())
+	bundle, err := parseGTFSBundle(data, url, serverID, resp.Header.Get("ETag"), resp.Header.Get("Last-Modified"))
+	if err != nil {
+		return nil, err
+	}
+	if gs.BundleDiskCache != nil {
+		bundle.data = data
+	}
+	return bundle, nil
+}
 
+// parseGTFSBundle parses the raw bundle data downloaded (or read) from url and returns it with
+// its metadata and the given validators, not to be cached.
+func parseGTFSBundle(data []byte, url string, serverID int, etag, lastModified string) (*downloadedBundle, error) {
+	parseStart := time.Now()
 	staticBundle, err := remoteGtfs.ParseStatic(data, remoteGtfs.ParseStaticOptions{})
+	parseDuration := time.Since(parseStart)
 	if err != nil {
 		err = fmt.Errorf("failed to parse GTFS static data from %s: %w", url, err)
 		report.ReportErrorWithSentryOptions(err, report.SentryReportOptions{
@@ -195,21 +303,91 @@ func downloadGTFSBundle(ctx context.Context, url string, serverID int, maxRetrie
 		})
 		return nil, err
 	}
-	return staticBundle, nil
 
+	// feed_info.txt and attribution.txt are optional and only used for monitoring,
+	// so a malformed file is reported without failing the download.
+	feedInfo, err := parseFeedInfo(data)
+	if err != nil {
+		report.ReportErrorWithSentryOptions(err, report.SentryReportOptions{
+			Tags: utils.MakeMap("server_id", strconv.Itoa(serverID)),
+			ExtraContext: map[string]interface{}{
+				"url": url,
+			},
+			Level: sentry.LevelWarning,
+		})
+	}
+
+	attributions, err := parseAttributions(data)
+	if err != nil {
+		report.ReportErrorWithSentryOptions(err, report.SentryReportOptions{
+			Tags: utils.MakeMap("server_id", strconv.Itoa(serverID)),
+			ExtraContext: map[string]interface{}{
+				"url": url,
+			},
+			Level: sentry.LevelWarning,
+		})
+	}
+
+	now := time.Now().UTC()
+	metadata := BundleMetadata{
+		FeedInfo:      feedInfo,
+		Attributions:  attributions,
+		ETag:          etag,
+		LastModified:  lastModified,
+		Hash:          hashBundle(data),
+		Size:          int64(len(data)),
+		ParseDuration: parseDuration,
+		DownloadedAt:  now,
+		CheckedAt:     now,
+	}
+	return &downloadedBundle{static: staticBundle, metadata: metadata, url: url}, nil
+}
+
+// commitGTFSBundle records the metadata of a stored bundle in BundleMetadata, so that the next
+// refresh is a conditional request with its validators, and saves the raw bundle, if any, and
+// those of the bundles it was merged from to BundleDiskCache.
+func (gs *GtfsService) commitGTFSBundle(serverID int, bundle *downloadedBundle) {
+	gs.BundleMetadata.Set(serverID, bundle.metadata)
+	if bundle.data != nil {
+		reportBundleCacheError(serverID, bundle.url, gs.BundleDiskCache.Save(serverID, bundle.url, bundle.data, bundle.metadata))
+	}
+	for _, feed := range bundle.feeds {
+		if feed.data != nil {
+			reportBundleCacheError(serverID, feed.url, gs.BundleDiskCache.SaveFeed(serverID, feed.url, feed.data, feed.metadata))
+		}
+	}
+}
+
+// reportBundleCacheError reports a failure to cache the bundle of the server at url, if err is not
+// nil. A failure to cache is not a failure to download, so it is only reported as a warning.
+func reportBundleCacheError(serverID int, url string, err error) {
+	if err == nil {
+		return
+	}
+	report.ReportErrorWithSentryOptions(err, report.SentryReportOptions{
+		Tags: utils.MakeMap("server_id", strconv.Itoa(serverID)),
+		ExtraContext: map[string]interface{}{
+			"url": url,
+		},
+		Level: sentry.LevelWarning,
+	})
 }
 
 // storeGTFSBundle stores a parsed GTFS static bundle in memory and computes its bounding box.
 //
 // The function performs the following:
 //   1. Wraps the GTFS static bundle into a StaticData object, keeping only the relevant parts
-//      needed by the application to avoid storing the full bundle in memory.
+//      needed by the application to avoid storing the full bundle in memory, together with
+//      the bundle's feed info and attributions.
 //   2. Stores the StaticData in the StaticStore, keyed by serverID.
 //   3. Computes the bounding box from the stops in the GTFS data.
 //   4. Stores the bounding box in the BoundingBoxStore, also keyed by serverID.
+//   5. Records the time taken, with that of parsing the bundle (metadata.ParseDuration), in
+//      gtfs_bundle_parse_duration_seconds, and the bundle size in gtfs_bundle_size_bytes.
 //
 // Parameters:
 //   - staticBundle: The parsed GTFS static bundle containing routes, stops, and other transit data.
+//   - metadata: The bundle's metadata, holding its parsed feed_info.txt and attribution.txt.
 //   - serverID: The identifier used to store and retrieve data for a specific server.
 //   - staticStore: The in-memory store holding GTFS static data indexed by server ID.
 //   - boundingBoxStore: The in-memory store holding computed bounding boxes for GTFS data.
@@ -217,12 +395,15 @@ func downloadGTFSBundle(ctx context.Context, url string, serverID int, maxRetrie
 // Returns:
 //   - error: If computing the bounding box fails, an error is returned. Otherwise, nil.
 
-func storeGTFSBundle(staticBundle *remoteGtfs.Static, serverID int, staticStore *StaticStore, boundingBoxStore *geo.BoundingBoxStore) error {
+func storeGTFSBundle(staticBundle *remoteGtfs.Static, metadata BundleMetadata, serverID int, staticStore StaticStore, boundingBoxStore geo.BoundingBoxStore) error {
 	// StaticData is a wrapper around the GTFS static bundle
 	// that includes only the parts we use in the application.
 	// So we do not keep the whole GTFS static bundle in memory,
 	// but only the parts we need.
+	start := time.Now()
 	staticData := models.NewStaticData(staticBundle)
+	staticData.FeedInfo = metadata.FeedInfo
+	staticData.Attributions = metadata.Attributions
 	staticBundle = nil // drop reference, GC can collect earlier
 	staticStore.Set(serverID, staticData)
 	// compute bounding box for each downloaded GTFS bundle
@@ -232,13 +413,17 @@ func storeGTFSBundle(staticBundle *remoteGtfs.Static, serverID int, staticStore
 	}
 	// one bounding box per server
 	boundingBoxStore.Set(serverID, bbox)
+	BundleParseDurationHistogram.WithLabelValues(strconv.Itoa(serverID)).Observe((metadata.ParseDuration + time.Since(start)).Seconds())
+	if metadata.Size > 0 {
+		BundleSizeGauge.WithLabelValues(strconv.Itoa(serverID)).Set(float64(metadata.Size))
+	}
 	return nil
 }
 
 // getStopLocationsByIDs retrieves stop locations by their IDs from the GTFS cache.
 // It returns a map of stop IDs to gtfs.Stop objects.
 
-func getStopLocationsByIDs(serverID int, stopIDs []string, staticStore *StaticStore) (map[string]remoteGtfs.Stop, error) {
+func getStopLocationsByIDs(serverID int, stopIDs []string, staticStore StaticStore) (map[string]remoteGtfs.Stop, error) {
 	staticData, ok := staticStore.Get(serverID)
 	if !ok || staticData == nil {
 		err := fmt.Errorf("no GTFS static data found for server ID %d", serverID)
@@ -269,8 +454,17 @@ func getStopLocationsByIDs(serverID int, stopIDs []string, staticStore *StaticSt
 // The realtimeStore is designed to be thread-safe, and this function ensures
 // that the parsed data is written using the store’s locking mechanisms,
 // making it safe for concurrent access across goroutines.
+//
+// Failed fetches are counted in RealtimeFetchFailuresCounter, and the time of the last
+// successful one is RealtimeLastSuccessfulFetchGauge (see recordRealtimeFetch).
+// The time spent downloading, parsing and storing the feed is set in timings, if not nil.
+
+func fetchAndStoreGTFSRTFeed(server models.ObaServer, realtimeStore RealtimeStore, client *http.Client, timings *FetchTimings) (err error) {
+	defer func() { recordRealtimeFetch(server.ID, FeedTypeVehiclePositions, err) }()
+	if timings == nil {
+		timings = &FetchTimings{}
+	}
 
-func fetchAndStoreGTFSRTFeed(server models.ObaServer, realtimeStore *RealtimeStore, client *http.Client) error {
 	parsedURL, err := url.Parse(server.VehiclePositionUrl)
 	if err != nil {
 		err = fmt.Errorf("failed to parse GTFS-RT URL: %v", err)
@@ -283,9 +477,15 @@ func fetchAndStoreGTFSRTFeed(server models.ObaServer, realtimeStore *RealtimeSto
 		return err
 	}
 
-	req, err := http.NewRequest("GET", parsedURL.String(), nil)
+	ctx := httpclient.WithTarget(context.Background(), server.ID, httpclient.TargetGTFSRT)
+	req, err := http.NewRequestWithContext(ctx, "GET", parsedURL.String(), nil)
 	if err != nil {
-		report.ReportError(err)
+		report.ReportErrorWithSentryOptions(err, report.SentryReportOptions{
+			Tags: utils.MakeMap("server_id", strconv.Itoa(server.ID)),
+			ExtraContext: map[string]interface{}{
+				"vehicle_position_url": server.VehiclePositionUrl,
+			},
+		})
 		return err
 	}
 
@@ -293,8 +493,10 @@ func fetchAndStoreGTFSRTFeed(server models.ObaServer, realtimeStore *RealtimeSto
 		req.Header.Set(server.GtfsRtApiKey, server.GtfsRtApiValue)
 	}
 
+	downloadStart := time.Now()
 	resp, err := client.Do(req)
 	if err != nil {
+		timings.Download = time.Since(downloadStart)
 		err = fmt.Errorf("failed to fetch GTFS-RT feed: %v", err)
 		report.ReportErrorWithSentryOptions(err, report.SentryReportOptions{
 			Tags: utils.MakeMap("server_id", strconv.Itoa(server.ID)),
@@ -306,31 +508,86 @@ func fetchAndStoreGTFSRTFeed(server models.ObaServer, realtimeStore *RealtimeSto
 	}
 	defer resp.Body.Close()
 
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		err = fmt.Errorf("unexpected response status %d when fetching GTFS-RT feed", resp.StatusCode)
+		report.ReportErrorWithSentryOptions(err, report.SentryReportOptions{
+			Tags: utils.MakeMap("server_id", strconv.Itoa(server.ID)),
+			ExtraContext: map[string]interface{}{
+				"vehicle_position_url": server.VehiclePositionUrl,
+				"status":               resp.Status,
+			},
+		})
+		return err
+	}
+
 	data, err := io.ReadAll(resp.Body)
+	timings.Download = time.Since(downloadStart)
 	if err != nil {
-		report.ReportError(err)
+		report.ReportErrorWithSentryOptions(err, report.SentryReportOptions{
+			Tags: utils.MakeMap("server_id", strconv.Itoa(server.ID)),
+			ExtraContext: map[string]interface{}{
+				"vehicle_position_url": server.VehiclePositionUrl,
+			},
+		})
 		return err
 	}
+	storeStart := time.Now()
+	realtimeStore.SetRaw(server.ID, RawFeed{Data: data, FetchedAt: time.Now().UTC()})
+	recordProducer(server, realtimeStore, identifyProducer(server.VehiclePositionUrl, resp.Header, data))
+	timings.Store = time.Since(storeStart)
 
+	parseStart := time.Now()
 	gtfsRT, err := remoteGtfs.ParseRealtime(data, &remoteGtfs.ParseRealtimeOptions{})
 	if err != nil {
-		report.ReportError(err)
+		timings.Parse = time.Since(parseStart)
+		report.ReportErrorWithSentryOptions(err, report.SentryReportOptions{
+			Tags: utils.MakeMap("server_id", strconv.Itoa(server.ID)),
+			ExtraContext: map[string]interface{}{
+				"vehicle_position_url": server.VehiclePositionUrl,
+			},
+		})
 		return err
 	}
 	realtimeData := models.NewRealtimeData(gtfsRT)
 	gtfsRT = nil // drop reference, GC can collect earlier
+	timings.Parse = time.Since(parseStart)
+
+	storeStart = time.Now()
 	realtimeStore.Set(realtimeData)
+	timings.Store += time.Since(storeStart)
 	return nil
 }
 
+// FetchTimings is the time spent in each part of a fetch of a GTFS-RT feed. The parts that did
+// not run, e.g. after a failed download, are zero.
+type FetchTimings struct {
+	Download time.Duration
+	Parse    time.Duration
+	Store    time.Duration
+}
+
+// FeedTypeVehiclePositions is the type of the GTFS-RT vehicle positions feed, e.g. in the
+// feed_type label of its metrics.
+const FeedTypeVehiclePositions = "vehicle_positions"
+
+// recordRealtimeFetch records the result of a fetch of the GTFS-RT feed of type feedType of a
+// server: a failure if err is not nil, the time of the last successful fetch otherwise.
+func recordRealtimeFetch(serverID int, feedType string, err error) {
+	id := strconv.Itoa(serverID)
+	if err != nil {
+		RealtimeFetchFailuresCounter.WithLabelValues(id, feedType).Inc()
+		return
+	}
+	RealtimeLastSuccessfulFetchGauge.WithLabelValues(id, feedType).SetToCurrentTime()
+}
+
 // getEarliestAndLatestServiceDates returns the earliest and latest service end dates
 // from the GTFS static data's calendar entries.
 //
-// This is used as a workaround because the GTFS library does not currently support
-// parsing `feed_info.txt`, which usually provides feed start/end dates.
-//
-// Instead, this function infers expiration information by scanning all `calendar.txt`
-// entries (i.e., service periods), and returns the minimum and maximum `EndDate` values.
+// It infers expiration information by scanning all `calendar.txt` entries (i.e., service
+// periods), and returns the minimum and maximum `EndDate` values. This works for every bundle,
+// including those without the optional `feed_info.txt`, whose declared feed_end_date is
+// available separately as StaticData.FeedInfo (see parseFeedInfo).
 //
 // Returns an error if no services are found in the bundle.
 func getEarliestAndLatestServiceDates(staticData *models.StaticData) (earliestEndDate, latestEndDate time.Time, err error) {
@@ -3,16 +3,21 @@ package gtfs
 import (
 	"bytes"
 	"context"
-	"io"
+	"errors"
 	"log/slog"
 	"net/http"
 	"net/http/httptest"
+	"os"
 	"testing"
 	"time"
 
 	remoteGtfs "github.com/OneBusAway/go-gtfs"
-	"watchdog.onebusaway.org/internal/geo"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	dto "github.com/prometheus/client_model/go"
 	"watchdog.onebusaway.org/internal/models"
+	"watchdog.onebusaway.org/internal/report"
+	"watchdog.onebusaway.org/internal/scheduler"
 )
 
 func TestDownloadGTFSBundles(t *testing.T) {
@@ -20,12 +25,74 @@ func TestDownloadGTFSBundles(t *testing.T) {
 		{ID: 1, GtfsUrl: "https://example.com/gtfs.zip"},
 	}
 
-	logger := slog.New(slog.NewTextHandler(io.Discard, nil))
-	boundingBoxStore := geo.NewBoundingBoxStore()
-	staticStore := NewStaticStore()
 	ctx := context.Background()
-	downloadGTFSBundles(ctx, servers, logger, boundingBoxStore, staticStore, 1)
+	newTestGtfsService(GtfsServiceOptions{}).DownloadGTFSBundles(ctx, servers, 1)
+
+}
+
+func TestDownloadGTFSBundlesConsecutiveFailures(t *testing.T) {
+	fail := true
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if fail {
+			w.WriteHeader(http.StatusInternalServerError)
+			return
+		}
+		_, _ = w.Write(readFixture(t, "gtfs.zip"))
+	}))
+	defer server.Close()
+
+	servers := []models.ObaServer{{ID: 9001, GtfsUrl: server.URL}}
+	gs := newTestGtfsService(GtfsServiceOptions{})
+	metadataStore := gs.BundleMetadata
+	gauge := BundleDownloadConsecutiveFailuresGauge.WithLabelValues("9001")
+	download := func() {
+		gs.DownloadGTFSBundles(context.Background(), servers, 0)
+	}
 
+	download()
+	download()
+	if got := gaugeValue(t, gauge); got != 2 {
+		t.Errorf("expected 2 consecutive failures, got %v", got)
+	}
+	if metadata, _ := metadataStore.Get(9001); metadata.ConsecutiveFailures != 2 {
+		t.Errorf("expected 2 consecutive failures in the metadata, got %d", metadata.ConsecutiveFailures)
+	}
+
+	fail = false
+	download()
+	if got := gaugeValue(t, gauge); got != 0 {
+		t.Errorf("expected the failure count to reset after a successful download, got %v", got)
+	}
+	if metadata, _ := metadataStore.Get(9001); metadata.ConsecutiveFailures != 0 {
+		t.Errorf("expected the metadata failure count to reset, got %d", metadata.ConsecutiveFailures)
+	}
+}
+
+func TestDownloadGTFSBundlesDurationsAndSize(t *testing.T) {
+	fixture := readFixture(t, "gtfs.zip")
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		_, _ = w.Write(fixture)
+	}))
+	defer server.Close()
+
+	servers := []models.ObaServer{{ID: 9002, GtfsUrl: server.URL}}
+	newTestGtfsService(GtfsServiceOptions{}).DownloadGTFSBundles(context.Background(), servers, 0)
+
+	if got := gaugeValue(t, BundleSizeGauge.WithLabelValues("9002")); got != float64(len(fixture)) {
+		t.Errorf("expected a bundle size of %d bytes, got %v", len(fixture), got)
+	}
+	for name, histogram := range map[string]*prometheus.HistogramVec{
+		"download": BundleDownloadDurationHistogram,
+		"parse":    BundleParseDurationHistogram,
+	} {
+		var metric dto.Metric
+		if err := histogram.WithLabelValues("9002").(prometheus.Histogram).Write(&metric); err != nil {
+			t.Fatal(err)
+		}
+		if count := metric.GetHistogram().GetSampleCount(); count != 1 {
+			t.Errorf("expected one %s duration observation, got %d", name, count)
+		}
+	}
 }
 
 func TestRefreshGTFSBundles(t *testing.T) {
@@ -33,15 +100,15 @@ func TestRefreshGTFSBundles(t *testing.T) {
 	logger := slog.New(slog.NewTextHandler(&logBuffer, &slog.HandlerOptions{Level: slog.LevelDebug}))
 
 	servers := []models.ObaServer{{ID: 1, Name: "Test Server", GtfsUrl: "http://example.com/gtfs.zip"}}
-	boundingBoxStore := geo.NewBoundingBoxStore()
-	staticStore := NewStaticStore()
+	gs := newTestGtfsService(GtfsServiceOptions{})
+	gs.Logger = logger
 	ctx, cancel := context.WithCancel(context.Background())
 	defer cancel()
-	go refreshGTFSBundles(ctx, servers, logger, 10*time.Millisecond, boundingBoxStore, staticStore, 1)
+	go gs.RefreshGTFSBundles(ctx, func() []models.ObaServer { return servers }, scheduler.Every(10*time.Millisecond), 1)
 
 	time.Sleep(15 * time.Millisecond)
 
-	t.Log("refreshGTFSBundles executed without crashing")
+	t.Log("RefreshGTFSBundles executed without crashing")
 }
 
 func TestDownloadGTFSBundle(t *testing.T) {
@@ -49,10 +116,11 @@ func TestDownloadGTFSBundle(t *testing.T) {
 	serverID := 1
 	ctx := context.Background()
 	t.Run("Success Response", func(t *testing.T) {
-		staticBundle, err := downloadGTFSBundle(ctx, mockServer.URL, serverID, 1)
+		bundle, err := newTestGtfsService(GtfsServiceOptions{}).downloadGTFSBundle(ctx, mockServer.URL, serverID, 1)
 		if err != nil {
-			t.Fatalf("DownloadGTFSBundle failed: %v", err)
+			t.Fatalf("downloadGTFSBundle failed: %v", err)
 		}
+		staticBundle := bundle.static
 		if staticBundle == nil {
 			t.Fatal("static data retrieved from the store is nil; expected non-nil value")
 		}
@@ -100,7 +168,7 @@ func TestDownloadGTFSBundle(t *testing.T) {
 
 	t.Run("Invalid URL", func(t *testing.T) {
 		invalidURL := "http://invalid-url"
-		_, err := downloadGTFSBundle(ctx, invalidURL, 2, 1)
+		_, err := newTestGtfsService(GtfsServiceOptions{}).downloadGTFSBundle(ctx, invalidURL, 2, 1)
 		if err == nil {
 			t.Errorf("Expected error for invalid URL, got none")
 		}
@@ -295,13 +363,23 @@ func TestFetchAndStoreGTFSRTFeed(t *testing.T) {
 			Timeout: 5 * time.Second,
 		}
 		realtimeStore := NewRealtimeStore()
-		err := fetchAndStoreGTFSRTFeed(server, realtimeStore, client)
+		var timings FetchTimings
+		err := fetchAndStoreGTFSRTFeed(server, realtimeStore, client, &timings)
 		if err != nil {
 			t.Fatalf("Expected no error, got: %v", err)
 		}
 		if realtimeStore.Get() == nil {
 			t.Fatalf("Expected realtimeStore to contain parsed GTFS-RT data, but it is nil")
 		}
+		if timings.Download <= 0 || timings.Parse <= 0 {
+			t.Errorf("Expected the download and parse of the feed to be timed, got %+v", timings)
+		}
+		if raw, ok := realtimeStore.Raw(server.ID); !ok || len(raw.Data) == 0 || raw.FetchedAt.IsZero() {
+			t.Errorf("Expected the raw feed to be kept for diagnostics, got %v", ok)
+		}
+		if lastSuccess := gaugeValue(t, RealtimeLastSuccessfulFetchGauge.WithLabelValues("1", FeedTypeVehiclePositions)); lastSuccess < float64(time.Now().Add(-time.Minute).Unix()) {
+			t.Errorf("Expected the time of the successful fetch to be recorded, got %v", lastSuccess)
+		}
 
 		data := readFixture(t, "gtfs_rt_feed_vehicles.pb")
 		gtfsRT, err := remoteGtfs.ParseRealtime(data, &remoteGtfs.ParseRealtimeOptions{})
@@ -356,7 +434,7 @@ func TestFetchAndStoreGTFSRTFeed(t *testing.T) {
 		}
 		realtimeStore := NewRealtimeStore()
 
-		err := fetchAndStoreGTFSRTFeed(server, realtimeStore, client)
+		err := fetchAndStoreGTFSRTFeed(server, realtimeStore, client, nil)
 		if err == nil {
 			t.Error("Expected error due to invalid URL, got nil")
 		}
@@ -374,9 +452,168 @@ func TestFetchAndStoreGTFSRTFeed(t *testing.T) {
 			Timeout: 5 * time.Second,
 		}
 		realtimeStore := NewRealtimeStore()
-		err := fetchAndStoreGTFSRTFeed(server, realtimeStore, client)
+		err := fetchAndStoreGTFSRTFeed(server, realtimeStore, client, nil)
 		if err == nil {
 			t.Error("Expected error when accessing closed server, got nil")
 		}
+		if failures := testutil.ToFloat64(RealtimeFetchFailuresCounter.WithLabelValues("3", FeedTypeVehiclePositions)); failures != 1 {
+			t.Errorf("Expected 1 fetch failure, got %v", failures)
+		}
 	})
+
+	t.Run("Failure Case - Error Status", func(t *testing.T) {
+		mockServer := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			http.Error(w, "unavailable", http.StatusServiceUnavailable)
+		}))
+		defer mockServer.Close()
+
+		server := models.ObaServer{ID: 4, VehiclePositionUrl: mockServer.URL}
+		err := fetchAndStoreGTFSRTFeed(server, NewRealtimeStore(), mockServer.Client(), nil)
+		if err == nil {
+			t.Error("Expected error for a 503 response, got nil")
+		}
+		if failures := testutil.ToFloat64(RealtimeFetchFailuresCounter.WithLabelValues("4", FeedTypeVehiclePositions)); failures != 1 {
+			t.Errorf("Expected 1 fetch failure, got %v", failures)
+		}
+		if lastSuccess := testutil.ToFloat64(RealtimeLastSuccessfulFetchGauge.WithLabelValues("4", FeedTypeVehiclePositions)); lastSuccess != 0 {
+			t.Errorf("Expected no successful fetch, got %v", lastSuccess)
+		}
+	})
+}
+
+func TestDownloadGTFSBundleConditional(t *testing.T) {
+	data := readFixture(t, "gtfs.zip")
+	const etag = `"bundle-v1"`
+
+	var conditionalRequests int
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("If-None-Match") == etag {
+			conditionalRequests++
+			w.WriteHeader(http.StatusNotModified)
+			return
+		}
+		w.Header().Set("ETag", etag)
+		w.Header().Set("Last-Modified", "Mon, 02 Jan 2006 15:04:05 GMT")
+		// #nosec G104
+		w.Write(data)
+	}))
+	defer server.Close()
+
+	ctx := context.Background()
+	gs := newTestGtfsService(GtfsServiceOptions{})
+	metadataStore := gs.BundleMetadata
+
+	bundle, err := gs.downloadGTFSBundle(ctx, server.URL, 1, 1)
+	if err != nil {
+		t.Fatalf("first download failed: %v", err)
+	}
+	if bundle == nil || bundle.static == nil {
+		t.Fatal("expected a parsed bundle on first download")
+	}
+	if _, ok := metadataStore.Get(1); ok {
+		t.Fatal("expected no metadata to be recorded before the bundle is stored")
+	}
+	gs.commitGTFSBundle(1, bundle)
+
+	metadata, ok := metadataStore.Get(1)
+	if !ok {
+		t.Fatal("expected metadata to be recorded after download")
+	}
+	if metadata.ETag != etag {
+		t.Errorf("expected ETag %s, got %s", etag, metadata.ETag)
+	}
+	if metadata.LastModified == "" {
+		t.Error("expected Last-Modified to be recorded")
+	}
+
+	bundle, err = gs.downloadGTFSBundle(ctx, server.URL, 1, 1)
+	if !errors.Is(err, ErrBundleNotModified) {
+		t.Fatalf("expected ErrBundleNotModified, got %v", err)
+	}
+	if bundle != nil {
+		t.Error("expected no bundle when not modified")
+	}
+	if conditionalRequests != 1 {
+		t.Errorf("expected 1 conditional request, got %d", conditionalRequests)
+	}
+
+	updated, _ := metadataStore.Get(1)
+	if updated.CheckedAt.Before(metadata.CheckedAt) {
+		t.Error("expected CheckedAt to advance after a 304 response")
+	}
+	if !updated.DownloadedAt.Equal(metadata.DownloadedAt) {
+		t.Error("expected DownloadedAt to remain unchanged after a 304 response")
+	}
+}
+
+func TestDownloadGTFSBundlesUnstoredBundleNotCommitted(t *testing.T) {
+	// A bundle without stop coordinates is parsed, but its bounding box cannot be computed.
+	files := make(map[string]string, len(smallBundle))
+	for name, content := range smallBundle {
+		files[name] = content
+	}
+	files["stops.txt"] = "stop_id,stop_name\nferry_dock,Ferry Dock\n"
+	data := zipWithFiles(t, files)
+
+	var conditionalRequests int
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("If-None-Match") != "" {
+			conditionalRequests++
+		}
+		w.Header().Set("ETag", `"bundle-v1"`)
+		// #nosec G104
+		w.Write(data)
+	}))
+	defer server.Close()
+
+	cache, err := NewBundleDiskCache(t.TempDir())
+	if err != nil {
+		t.Fatalf("failed to create cache: %v", err)
+	}
+	gs := newTestGtfsService(GtfsServiceOptions{BundleDiskCache: cache})
+	servers := []models.ObaServer{{ID: 9003, GtfsUrl: server.URL}}
+
+	gs.DownloadGTFSBundles(context.Background(), servers, 0)
+	if metadata, _ := gs.BundleMetadata.Get(9003); metadata.ETag != "" || metadata.ConsecutiveFailures != 1 {
+		t.Errorf("expected only the failure of an unstored bundle to be recorded, got %+v", metadata)
+	}
+	if _, _, err := cache.Load(9003, server.URL); !errors.Is(err, os.ErrNotExist) {
+		t.Errorf("expected an unstored bundle not to be cached, got %v", err)
+	}
+
+	gs.DownloadGTFSBundles(context.Background(), servers, 0)
+	if conditionalRequests != 0 {
+		t.Errorf("expected an unstored bundle to be downloaded again unconditionally, got %d conditional requests", conditionalRequests)
+	}
+}
+
+func TestDownloadGTFSBundlesReportsSuppressedInMaintenance(t *testing.T) {
+	// A chunked response has no Content-Length, so the bundle fails while its body is read.
+	data := readFixture(t, "gtfs.zip")
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		for i := 0; i < len(data); i += 1024 {
+			// #nosec G104
+			w.Write(data[i:min(i+1024, len(data))])
+			w.(http.Flusher).Flush()
+		}
+	}))
+	defer server.Close()
+
+	report.SuppressServers(func(serverID string) bool { return serverID == "9004" })
+	defer report.SuppressServers(nil)
+
+	reported := testutil.ToFloat64(report.ReportedErrors.WithLabelValues("error"))
+	suppressed := testutil.ToFloat64(report.ReportSuppressed.WithLabelValues("maintenance"))
+	gs := newTestGtfsService(GtfsServiceOptions{MaxBundleSize: 4096})
+	gs.DownloadGTFSBundles(context.Background(), []models.ObaServer{{ID: 9004, GtfsUrl: server.URL}}, 1)
+
+	if metadata, _ := gs.BundleMetadata.Get(9004); metadata.ConsecutiveFailures != 1 {
+		t.Fatalf("expected the download to fail, got %+v", metadata)
+	}
+	if got := testutil.ToFloat64(report.ReportedErrors.WithLabelValues("error")) - reported; got != 0 {
+		t.Errorf("expected no error to be reported during maintenance, got %v", got)
+	}
+	if got := testutil.ToFloat64(report.ReportSuppressed.WithLabelValues("maintenance")) - suppressed; got == 0 {
+		t.Error("expected the bundle failure to be suppressed")
+	}
 }
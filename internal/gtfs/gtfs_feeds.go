@@ -0,0 +1,175 @@
+package gtfs
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"time"
+
+	remoteGtfs "github.com/OneBusAway/go-gtfs"
+	"watchdog.onebusaway.org/internal/models"
+)
+
+// downloadGTFSFeeds downloads the GTFS static bundles of server (see ObaServer.GtfsFeedURLs)
+// and returns them merged into one (see mergeFeeds).
+//
+// A server with a single bundle is downloaded by downloadGTFSBundle, with conditional requests
+// and the disk cache. Like it, the merged bundle is returned for DownloadGTFSBundles to store
+// and commit.
+//
+// Each bundle of a server with several of them is downloaded conditionally with validators of
+// its own, recorded in the Feeds of the server metadata, and cached in an entry of its own (see
+// BundleDiskCache.SaveFeed). The merged bundle is reported unchanged (ErrBundleNotModified) when
+// every bundle is not modified, or has the same hash as on the previous download. Otherwise
+// the unchanged bundles are read back from the disk cache, or downloaded again if they are not
+// cached, to be merged with the others.
+func (gs *GtfsService) downloadGTFSFeeds(ctx context.Context, server models.ObaServer, maxRetries int) (*downloadedBundle, error) {
+	urls := server.GtfsFeedURLs()
+	if len(urls) <= 1 {
+		url := server.GtfsUrl
+		if len(urls) == 1 {
+			url = urls[0]
+		}
+		return gs.downloadGTFSBundle(ctx, url, server.ID, maxRetries)
+	}
+
+	previous, _ := gs.BundleMetadata.Get(server.ID)
+	feeds := make([]*downloadedBundle, len(urls))
+	var unchanged []int
+	for i, url := range urls {
+		feed, err := gs.downloadGTFSFeed(ctx, server.ID, url, previous.Feeds[url], maxRetries)
+		if errors.Is(err, ErrBundleNotModified) {
+			unchanged = append(unchanged, i)
+			continue
+		}
+		if err != nil {
+			return nil, err
+		}
+		feeds[i] = feed
+	}
+	if len(unchanged) == len(urls) {
+		gs.BundleMetadata.markChecked(server.ID, time.Now().UTC())
+		return nil, ErrBundleNotModified
+	}
+	for _, i := range unchanged {
+		feed, err := gs.loadCachedFeed(server.ID, urls[i])
+		if err != nil {
+			feed, err = gs.downloadGTFSFeed(ctx, server.ID, urls[i], BundleFeed{}, maxRetries)
+		}
+		if err != nil {
+			return nil, err
+		}
+		feeds[i] = feed
+	}
+
+	bundle := mergeFeeds(feeds, server.GtfsUrl)
+	if previous.Hash != "" && previous.Hash == bundle.metadata.Hash {
+		gs.BundleMetadata.markChecked(server.ID, bundle.metadata.CheckedAt)
+		return nil, ErrBundleNotModified
+	}
+	return bundle, nil
+}
+
+// downloadGTFSFeed downloads the bundle at url, one of the GTFS URLs of the server, with the
+// validators of its previous download. Since an unchanged bundle must be read back from the
+// disk cache to be merged, the request is only conditional if there is one.
+func (gs *GtfsService) downloadGTFSFeed(ctx context.Context, serverID int, url string, previous BundleFeed, maxRetries int) (*downloadedBundle, error) {
+	// The validators of the bundle are given to downloadGTFSBundle in a store of its own, so
+	// that those of the other bundles of the server are not sent along.
+	feed := *gs
+	feed.BundleMetadata = NewBundleMetadataStore()
+	if gs.BundleDiskCache != nil {
+		feed.BundleMetadata.set(serverID, BundleMetadata{ETag: previous.ETag, LastModified: previous.LastModified, Hash: previous.Hash})
+	}
+	return feed.downloadGTFSBundle(ctx, url, serverID, maxRetries)
+}
+
+// loadCachedFeed reads the bundle at url, one of the GTFS URLs of the server, back from the
+// disk cache. Since it is cached already, the bundle returned has no raw data.
+func (gs *GtfsService) loadCachedFeed(serverID int, url string) (*downloadedBundle, error) {
+	data, metadata, err := gs.BundleDiskCache.LoadFeed(serverID, url)
+	if err != nil {
+		return nil, err
+	}
+	feed, err := parseGTFSBundle(data, url, serverID, metadata.ETag, metadata.LastModified)
+	if err != nil {
+		return nil, err
+	}
+	feed.metadata.DownloadedAt, feed.metadata.CheckedAt = metadata.DownloadedAt, metadata.DownloadedAt
+	return feed, nil
+}
+
+// mergeFeeds merges the bundles of a server with several GTFS URLs into one downloaded from url
+// (see mergeStatic), whose bundles are committed with it (see commitGTFSBundle).
+//
+// The metadata of the merged bundle are the feed_info.txt of the first bundle, the attributions
+// of all of them, a hash of their hashes, the sums of their sizes and parse durations, the
+// latest of their download and check times, and their validators and hashes as Feeds.
+func mergeFeeds(feeds []*downloadedBundle, url string) *downloadedBundle {
+	bundles := make([]*remoteGtfs.Static, 0, len(feeds))
+	merged := BundleMetadata{Feeds: make(map[string]BundleFeed, len(feeds))}
+	hash := sha256.New()
+	for i, feed := range feeds {
+		bundles = append(bundles, feed.static)
+		metadata := feed.metadata
+		if i == 0 {
+			merged.FeedInfo = metadata.FeedInfo
+		}
+		merged.Attributions = append(merged.Attributions, metadata.Attributions...)
+		merged.Size += metadata.Size
+		merged.ParseDuration += metadata.ParseDuration
+		if metadata.DownloadedAt.After(merged.DownloadedAt) {
+			merged.DownloadedAt = metadata.DownloadedAt
+		}
+		if metadata.CheckedAt.After(merged.CheckedAt) {
+			merged.CheckedAt = metadata.CheckedAt
+		}
+		merged.Feeds[feed.url] = BundleFeed{ETag: metadata.ETag, LastModified: metadata.LastModified, Hash: metadata.Hash}
+		hash.Write([]byte(metadata.Hash))
+	}
+	merged.Hash = hex.EncodeToString(hash.Sum(nil))
+	return &downloadedBundle{static: mergeStatic(bundles), metadata: merged, url: url, feeds: feeds}
+}
+
+// mergeStatic merges GTFS static bundles into one. The agencies, routes, stops and services
+// whose id is already in an earlier bundle are left out, so that the entities shared by the
+// bundles of several agencies are counted once; trips, transfers and shapes are all kept.
+func mergeStatic(bundles []*remoteGtfs.Static) *remoteGtfs.Static {
+	merged := &remoteGtfs.Static{}
+	agencies := make(map[string]bool)
+	routes := make(map[string]bool)
+	stops := make(map[string]bool)
+	services := make(map[string]bool)
+	for _, bundle := range bundles {
+		for _, agency := range bundle.Agencies {
+			if !agencies[agency.Id] {
+				agencies[agency.Id] = true
+				merged.Agencies = append(merged.Agencies, agency)
+			}
+		}
+		for _, route := range bundle.Routes {
+			if !routes[route.Id] {
+				routes[route.Id] = true
+				merged.Routes = append(merged.Routes, route)
+			}
+		}
+		for _, stop := range bundle.Stops {
+			if !stops[stop.Id] {
+				stops[stop.Id] = true
+				merged.Stops = append(merged.Stops, stop)
+			}
+		}
+		for _, service := range bundle.Services {
+			if !services[service.Id] {
+				services[service.Id] = true
+				merged.Services = append(merged.Services, service)
+			}
+		}
+		merged.Trips = append(merged.Trips, bundle.Trips...)
+		merged.Transfers = append(merged.Transfers, bundle.Transfers...)
+		merged.Shapes = append(merged.Shapes, bundle.Shapes...)
+		merged.Warnings = append(merged.Warnings, bundle.Warnings...)
+	}
+	return merged
+}
@@ -0,0 +1,159 @@
+package gtfs
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	"watchdog.onebusaway.org/internal/models"
+)
+
+// smallBundle is a GTFS bundle of another agency, with a stop far from those of the fixture.
+var smallBundle = map[string]string{
+	"agency.txt":     "agency_id,agency_name,agency_url,agency_timezone\nferry,Ferry Co,https://ferry.example.com,America/Los_Angeles\n",
+	"stops.txt":      "stop_id,stop_name,stop_lat,stop_lon\nferry_dock,Ferry Dock,10.0,10.0\n",
+	"routes.txt":     "route_id,agency_id,route_short_name,route_type\nferry_route,ferry,F,4\n",
+	"calendar.txt":   "service_id,monday,tuesday,wednesday,thursday,friday,saturday,sunday,start_date,end_date\nferry_weekdays,1,1,1,1,1,0,0,20240101,20301231\n",
+	"trips.txt":      "route_id,service_id,trip_id\nferry_route,ferry_weekdays,ferry_trip\n",
+	"stop_times.txt": "trip_id,arrival_time,departure_time,stop_id,stop_sequence\nferry_trip,08:00:00,08:00:00,ferry_dock,1\nferry_trip,08:30:00,08:30:00,ferry_dock,2\n",
+}
+
+func TestDownloadGTFSBundlesMergesFeeds(t *testing.T) {
+	fixture := readFixture(t, "gtfs.zip")
+	small := zipWithFiles(t, smallBundle)
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/transit.zip":
+			w.Write(fixture)
+		case "/ferry.zip":
+			w.Write(small)
+		default:
+			http.NotFound(w, r)
+		}
+	}))
+	defer ts.Close()
+
+	servers := []models.ObaServer{{ID: 71, GtfsUrl: ts.URL + "/transit.zip", GtfsUrls: []string{ts.URL + "/ferry.zip", ts.URL + "/transit.zip"}}}
+	gs := newTestGtfsService(GtfsServiceOptions{})
+	gs.BundleClient = ts.Client()
+	staticStore, boundingBoxStore := gs.StaticStore, gs.BoundingBoxStore
+	gs.DownloadGTFSBundles(context.Background(), servers, 1)
+
+	staticData, ok := staticStore.Get(71)
+	if !ok {
+		t.Fatal("expected the merged static data to be stored")
+	}
+	var ferryStop, transitStop bool
+	for _, stop := range staticData.Stops {
+		ferryStop = ferryStop || stop.Id == "ferry_dock"
+		transitStop = transitStop || stop.Id != "ferry_dock"
+	}
+	if !ferryStop || !transitStop {
+		t.Errorf("expected the stops of both bundles, got %d stops", len(staticData.Stops))
+	}
+	if staticData.FeedInfo == nil || staticData.FeedInfo.PublisherName != "Sound Transit" {
+		t.Errorf("expected the feed info of the first bundle, got %+v", staticData.FeedInfo)
+	}
+	bbox, ok := boundingBoxStore.Get(71)
+	if !ok || !bbox.Contains(10, 10) || !bbox.Contains(47.6, -122.3) {
+		t.Errorf("expected a bounding box covering both bundles, got %+v", bbox)
+	}
+
+	gs.DownloadGTFSBundles(context.Background(), servers, 1)
+	if changed := testutil.ToFloat64(BundleChangedGauge.WithLabelValues("71")); changed != 0 {
+		t.Errorf("expected unchanged bundles to be reported as not modified, got %v", changed)
+	}
+}
+
+func TestDownloadGTFSBundlesFeedsConditionalAndCached(t *testing.T) {
+	fixture := readFixture(t, "gtfs.zip")
+	ferry := smallBundle
+	var mu sync.Mutex
+	ferryVersion := "v1"
+	downloads := make(map[string]int)
+	downloaded := func(transit, ferry int) bool {
+		mu.Lock()
+		defer mu.Unlock()
+		return downloads["/transit.zip"] == transit && downloads["/ferry.zip"] == ferry
+	}
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		mu.Lock()
+		defer mu.Unlock()
+		data, etag := fixture, `"transit-v1"`
+		if r.URL.Path == "/ferry.zip" {
+			data, etag = zipWithFiles(t, ferry), `"ferry-`+ferryVersion+`"`
+		}
+		if r.Header.Get("If-None-Match") == etag {
+			w.WriteHeader(http.StatusNotModified)
+			return
+		}
+		downloads[r.URL.Path]++
+		w.Header().Set("ETag", etag)
+		// #nosec G104
+		w.Write(data)
+	}))
+	defer ts.Close()
+
+	cache, err := NewBundleDiskCache(t.TempDir())
+	if err != nil {
+		t.Fatalf("failed to create cache: %v", err)
+	}
+	servers := []models.ObaServer{{ID: 73, GtfsUrl: ts.URL + "/transit.zip", GtfsUrls: []string{ts.URL + "/ferry.zip"}}}
+	gs := newTestGtfsService(GtfsServiceOptions{BundleDiskCache: cache})
+	gs.DownloadGTFSBundles(context.Background(), servers, 1)
+	if metadata, _ := gs.BundleMetadata.Get(73); metadata.Feeds[ts.URL+"/ferry.zip"].ETag != `"ferry-v1"` {
+		t.Fatalf("expected the validators of each bundle to be recorded, got %+v", metadata.Feeds)
+	}
+
+	// Every bundle is answered 304 Not Modified.
+	gs.DownloadGTFSBundles(context.Background(), servers, 1)
+	if changed := testutil.ToFloat64(BundleChangedGauge.WithLabelValues("73")); changed != 0 {
+		t.Errorf("expected unchanged bundles to be reported as not modified, got %v", changed)
+	}
+	if !downloaded(1, 1) {
+		t.Errorf("expected unchanged bundles not to be downloaded again")
+	}
+
+	// Only the ferry bundle changed: the other one is read back from the disk cache.
+	mu.Lock()
+	ferry = map[string]string{}
+	for name, content := range smallBundle {
+		ferry[name] = content
+	}
+	ferry["stops.txt"] = "stop_id,stop_name,stop_lat,stop_lon\nferry_pier,Ferry Pier,11.0,11.0\n"
+	ferry["stop_times.txt"] = "trip_id,arrival_time,departure_time,stop_id,stop_sequence\nferry_trip,08:00:00,08:00:00,ferry_pier,1\nferry_trip,08:30:00,08:30:00,ferry_pier,2\n"
+	ferryVersion = "v2"
+	mu.Unlock()
+	gs.DownloadGTFSBundles(context.Background(), servers, 1)
+	if !downloaded(1, 2) {
+		t.Errorf("expected only the changed bundle to be downloaded again")
+	}
+	hasStop := func(staticStore StaticStore, id string) bool {
+		staticData, _ := staticStore.Get(73)
+		for _, stop := range staticData.Stops {
+			if stop.Id == id {
+				return true
+			}
+		}
+		return false
+	}
+	if staticData, _ := gs.StaticStore.Get(73); !hasStop(gs.StaticStore, "ferry_pier") || hasStop(gs.StaticStore, "ferry_dock") || len(staticData.Stops) < 2 {
+		t.Error("expected the changed bundle to be merged with the cached one")
+	}
+
+	// After a restart, the server is restored from the cached bundles.
+	restarted := newTestGtfsService(GtfsServiceOptions{BundleDiskCache: cache})
+	if loaded := restarted.LoadCachedGTFSBundles(servers); loaded != 1 {
+		t.Fatalf("expected the server to be restored from the cache, got %d", loaded)
+	}
+	if !hasStop(restarted.StaticStore, "ferry_pier") {
+		t.Error("expected the restored bundle to merge the cached bundles")
+	}
+	restarted.DownloadGTFSBundles(context.Background(), servers, 1)
+	if !downloaded(1, 2) {
+		t.Errorf("expected the restored validators to make the downloads conditional")
+	}
+}
@@ -0,0 +1,115 @@
+package gtfs
+
+import (
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promauto"
+)
+
+// Metrics owned by the gtfs package.
+//
+// They live here rather than in the metrics package because the metrics package
+// depends on gtfs, and recording them from gtfs would otherwise create an import cycle.
+var (
+	ServiceReductionActiveGauge = promauto.NewGaugeVec(prometheus.GaugeOpts{
+		Name: "gtfs_service_reduction_active",
+		Help: "Whether a planned service reduction of the reduced service calendar of a server is in effect (1 = reduced service, 0 = normal)",
+	}, []string{"server_id"})
+
+	ProducerInfoGauge = promauto.NewGaugeVec(prometheus.GaugeOpts{
+		Name: "gtfs_rt_producer_info",
+		Help: "Likely software producing the GTFS-RT feed of a server (always 1), identified from the feed header, the response headers or the feed URL, with the version fields of the feed header",
+	}, []string{"server_id", "producer", "evidence", "gtfs_realtime_version", "feed_version", "incrementality"})
+
+	BundleChangedGauge = promauto.NewGaugeVec(prometheus.GaugeOpts{
+		Name: "gtfs_bundle_changed",
+		Help: "Whether the last GTFS bundle refresh downloaded a changed bundle (1 = changed, 0 = not modified)",
+	}, []string{"server_id"})
+
+	BundleDownloadResumesCounter = promauto.NewCounterVec(prometheus.CounterOpts{
+		Name: "gtfs_bundle_download_resumes_total",
+		Help: "Total number of GTFS bundle downloads resumed with an HTTP Range request after a partial transfer",
+	}, []string{"server_id"})
+
+	BundleChangeAddedGauge = promauto.NewGaugeVec(prometheus.GaugeOpts{
+		Name: "gtfs_bundle_change_added",
+		Help: "Number of entities (routes, stops, trips, services) added by the last changed GTFS bundle compared to the previous one",
+	}, []string{"server_id", "entity"})
+
+	BundleChangeRemovedGauge = promauto.NewGaugeVec(prometheus.GaugeOpts{
+		Name: "gtfs_bundle_change_removed",
+		Help: "Number of entities (routes, stops, trips, services) removed by the last changed GTFS bundle compared to the previous one",
+	}, []string{"server_id", "entity"})
+
+	BundleChangeNetGauge = promauto.NewGaugeVec(prometheus.GaugeOpts{
+		Name: "gtfs_bundle_change_net",
+		Help: "Net change in the number of entities (routes, stops, trips, services) between the previous and the last changed GTFS bundle",
+	}, []string{"server_id", "entity"})
+
+	RoutesRenamedGauge = promauto.NewGaugeVec(prometheus.GaugeOpts{
+		Name: "gtfs_routes_renamed_last_refresh",
+		Help: "Number of routes whose short or long name changed under the same route ID in the last changed GTFS bundle compared to the previous one",
+	}, []string{"server_id"})
+
+	BundleWebhookDeliveriesCounter = promauto.NewCounterVec(prometheus.CounterOpts{
+		Name: "gtfs_bundle_webhook_deliveries_total",
+		Help: "Total number of bundle change webhook deliveries, by result (success, failure)",
+	}, []string{"server_id", "result"})
+
+	LicenseChangesCounter = promauto.NewCounterVec(prometheus.CounterOpts{
+		Name: "gtfs_license_changes_total",
+		Help: "Total number of GTFS bundles whose publisher (feed_info.txt) or attributions (attribution.txt) differ from the previous bundle's",
+	}, []string{"server_id"})
+
+	BundleDownloadConsecutiveFailuresGauge = promauto.NewGaugeVec(prometheus.GaugeOpts{
+		Name: "gtfs_bundle_download_consecutive_failures",
+		Help: "Number of GTFS bundle refreshes in a row that failed to download or store the bundle (0 after a success)",
+	}, []string{"server_id"})
+
+	BundleDownloadDurationHistogram = promauto.NewHistogramVec(
+		prometheus.HistogramOpts{
+			Name:    "gtfs_bundle_download_duration_seconds",
+			Help:    "Time taken to download a changed GTFS bundle, from the request to the end of the transfer (including retries and resumes), in seconds",
+			Buckets: []float64{0.5, 1, 2.5, 5, 10, 30, 60, 120, 300, 600},
+		},
+		[]string{"server_id"},
+	)
+
+	BundleParseDurationHistogram = promauto.NewHistogramVec(
+		prometheus.HistogramOpts{
+			Name:    "gtfs_bundle_parse_duration_seconds",
+			Help:    "Time taken to parse a GTFS bundle and store its static data, in seconds",
+			Buckets: []float64{0.1, 0.25, 0.5, 1, 2.5, 5, 10, 30, 60, 120},
+		},
+		[]string{"server_id"},
+	)
+
+	BundleSizeGauge = promauto.NewGaugeVec(prometheus.GaugeOpts{
+		Name: "gtfs_bundle_size_bytes",
+		Help: "Size of the raw GTFS bundle (the sum of its bundles for a server with several) currently loaded for a server, in bytes",
+	}, []string{"server_id"})
+
+	BundleSizeRatioGauge = promauto.NewGaugeVec(prometheus.GaugeOpts{
+		Name: "gtfs_bundle_size_ratio",
+		Help: "Size of the last GTFS bundle downloaded for a server divided by the median size of its previous bundles (up to 5); far below 1 usually means a truncated publish",
+	}, []string{"server_id"})
+
+	RealtimeFetchFailuresCounter = promauto.NewCounterVec(prometheus.CounterOpts{
+		Name: "gtfs_rt_fetch_failures_total",
+		Help: "Total number of GTFS-RT feed fetches that failed (request error, unexpected status or unparsable feed), by feed type",
+	}, []string{"server_id", "feed_type"})
+
+	RealtimeLastSuccessfulFetchGauge = promauto.NewGaugeVec(prometheus.GaugeOpts{
+		Name: "gtfs_rt_last_successful_fetch_timestamp",
+		Help: "Unix timestamp of the last GTFS-RT feed fetch that was downloaded and parsed successfully, by feed type",
+	}, []string{"server_id", "feed_type"})
+
+	StoreEntriesGauge = promauto.NewGaugeVec(prometheus.GaugeOpts{
+		Name: "watchdog_store_entries",
+		Help: "Number of servers with data in an in-memory store, by store (gtfs_static, gtfs_realtime_raw)",
+	}, []string{"store"})
+
+	StoreBytesGauge = promauto.NewGaugeVec(prometheus.GaugeOpts{
+		Name: "watchdog_store_bytes",
+		Help: "Approximate memory footprint of an in-memory store, by store (gtfs_static, gtfs_realtime_raw), in bytes",
+	}, []string{"store"})
+)
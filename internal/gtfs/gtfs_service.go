@@ -9,50 +9,86 @@ import (
 	remoteGtfs "github.com/OneBusAway/go-gtfs"
 	"watchdog.onebusaway.org/internal/geo"
 	"watchdog.onebusaway.org/internal/models"
+	"watchdog.onebusaway.org/internal/scheduler"
 )
 
 type GtfsService struct {
-	StaticStore      *StaticStore
-	RealtimeStore    *RealtimeStore
-	BoundingBoxStore *geo.BoundingBoxStore
-	Logger           *slog.Logger
-	Client           *http.Client
+	StaticStore      StaticStore
+	RealtimeStore    RealtimeStore
+	BoundingBoxStore geo.BoundingBoxStore
+	BundleThrottle   *BundleThrottle
+	BundleMetadata   *BundleMetadataStore
+	BundleDiskCache  *BundleDiskCache
+	MaxBundleSize    int64
+	BundleContents   *BundleContentsStore
+	BundleNotifier   *BundleChangeNotifier
+	// ServiceReductions holds the planned service reductions of the servers.
+	ServiceReductions *ServiceReductionStore
+	Logger            *slog.Logger
+	// Client polls the GTFS-RT feeds and the reduced service calendars, and BundleClient
+	// downloads the GTFS static bundles.
+	Client       *http.Client
+	BundleClient *http.Client
 }
 
-func NewGtfsService(staticStore *StaticStore, realtimeStore *RealtimeStore, boundingBoxStore *geo.BoundingBoxStore, logger *slog.Logger, client *http.Client) *GtfsService {
-	return &GtfsService{
-		StaticStore:      staticStore,
-		RealtimeStore:    realtimeStore,
-		BoundingBoxStore: boundingBoxStore,
-		Logger:           logger,
-		Client:           client,
-	}
-}
-
-func (gs *GtfsService) DownloadGTFSBundles(ctx context.Context, servers []models.ObaServer, maxRetries int) {
-	downloadGTFSBundles(ctx, servers, gs.Logger, gs.BoundingBoxStore, gs.StaticStore, maxRetries)
-}
-
-// This service method downloads a GTFS static bundle from the provided URL,
-// currently we uses (DownloadGTFSBundles) to fetch GTFS data for all servers.
-// which internally calls downloadAndStoreGTFSBundle for each server.
-// but this public method can be used to download a single GTFS bundle.
-// It parses the GTFS data and stores it in the StaticStore using the serverID as the key.
-// It returns an error if the download or parsing fails.
-func (gs *GtfsService) DownloadGTFSBundle(ctx context.Context, url string, serverID int, maxRetires int) (*remoteGtfs.Static, error) {
-	return downloadGTFSBundle(ctx, url, serverID, maxRetires)
+// GtfsServiceOptions configures how a GtfsService downloads and keeps the GTFS static bundles.
+// The zero value downloads them without bandwidth or size limits, caching or notifications.
+type GtfsServiceOptions struct {
+	// BundleThrottle caps the bandwidth of each download and of all downloads combined
+	// (nil = unlimited).
+	BundleThrottle *BundleThrottle
+	// BundleMetadata holds the validators of the last bundles, used for conditional requests
+	// (nil = a new store).
+	BundleMetadata *BundleMetadataStore
+	// BundleDiskCache persists the downloaded bundles (nil disables caching).
+	BundleDiskCache *BundleDiskCache
+	// MaxBundleSize is the maximum size of a bundle in bytes (0 = unlimited).
+	MaxBundleSize int64
+	// BundleContents holds the entity IDs of the previous bundles, used to diff new bundles
+	// (nil = a new store).
+	BundleContents *BundleContentsStore
+	// BundleNotifier is notified when a stored bundle differs from the previous one (nil
+	// disables notifications).
+	BundleNotifier *BundleChangeNotifier
 }
 
-func (gs *GtfsService) StoreGTFSBundle(staticBundle *remoteGtfs.Static, serverID int) error {
-	return storeGTFSBundle(staticBundle, serverID, gs.StaticStore, gs.BoundingBoxStore)
+// NewGtfsService returns a GtfsService keeping the GTFS data of the servers in the given stores.
+// client polls the GTFS-RT feeds and the reduced service calendars, and bundleClient downloads
+// the GTFS static bundles (nil = httpclient.Default().Bundle).
+func NewGtfsService(staticStore StaticStore, realtimeStore RealtimeStore, boundingBoxStore geo.BoundingBoxStore, logger *slog.Logger, client, bundleClient *http.Client, options GtfsServiceOptions) *GtfsService {
+	if options.BundleMetadata == nil {
+		options.BundleMetadata = NewBundleMetadataStore()
+	}
+	if options.BundleContents == nil {
+		options.BundleContents = NewBundleContentsStore()
+	}
+	return &GtfsService{
+		StaticStore:       staticStore,
+		RealtimeStore:     realtimeStore,
+		BoundingBoxStore:  boundingBoxStore,
+		BundleThrottle:    options.BundleThrottle,
+		BundleMetadata:    options.BundleMetadata,
+		BundleDiskCache:   options.BundleDiskCache,
+		MaxBundleSize:     options.MaxBundleSize,
+		BundleContents:    options.BundleContents,
+		BundleNotifier:    options.BundleNotifier,
+		ServiceReductions: NewServiceReductionStore(),
+		Logger:            logger,
+		Client:            client,
+		BundleClient:      bundleClient,
+	}
 }
 
-func (gs *GtfsService) RefreshGTFSBundles(ctx context.Context, servers []models.ObaServer, interval time.Duration, maxRetries int) {
-	refreshGTFSBundles(ctx, servers, gs.Logger, interval, gs.BoundingBoxStore, gs.StaticStore, maxRetries)
+// RefreshServiceReductions loads the reduced service calendars of the servers returned by
+// servers right away, then at every activation of schedule, until ctx is canceled.
+func (gs *GtfsService) RefreshServiceReductions(ctx context.Context, servers func() []models.ObaServer, schedule scheduler.Schedule) {
+	refreshServiceReductions(ctx, gs.Client, servers, schedule, gs.ServiceReductions, gs.Logger)
 }
 
-func (gs *GtfsService) FetchAndStoreGTFSRTFeed(server models.ObaServer) error {
-	return fetchAndStoreGTFSRTFeed(server, gs.RealtimeStore, gs.Client)
+// FetchAndStoreGTFSRTFeed fetches, parses and stores the GTFS-RT vehicle positions feed of a
+// server, setting the time spent in each part in timings, if not nil.
+func (gs *GtfsService) FetchAndStoreGTFSRTFeed(server models.ObaServer, timings *FetchTimings) error {
+	return fetchAndStoreGTFSRTFeed(server, gs.RealtimeStore, gs.Client, timings)
 }
 
 // exported helper functions
@@ -64,6 +100,6 @@ func GetEarliestAndLatestServiceDates(staticData *models.StaticData) (earliest,
 	return earliestTime, latestTime, nil
 }
 
-func GetStopLocationsByIDs(serverID int, stopIDs []string, staticStore *StaticStore) (map[string]remoteGtfs.Stop, error) {
+func GetStopLocationsByIDs(serverID int, stopIDs []string, staticStore StaticStore) (map[string]remoteGtfs.Stop, error) {
 	return getStopLocationsByIDs(serverID, stopIDs, staticStore)
 }
@@ -0,0 +1,179 @@
+package gtfs
+
+import (
+	"errors"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"google.golang.org/protobuf/encoding/protowire"
+	"watchdog.onebusaway.org/internal/models"
+)
+
+// ProducerUnknown is the producer of feeds that match no producerSignature.
+const ProducerUnknown = "unknown"
+
+// Sources of the evidence a producer was identified from, in order of precedence.
+const (
+	EvidenceFeedVersion = "feed_version"
+	EvidenceHTTPHeader  = "http_header"
+	EvidenceURL         = "url"
+)
+
+// producerSignature identifies the software producing a GTFS-RT feed. A feed matches if one of
+// the keywords appears (case-insensitively) in its feed_version, in the Server or X-Powered-By
+// headers of its response, or in its URL.
+type producerSignature struct {
+	name     string
+	keywords []string
+}
+
+// producerSignatures are the producers the watchdog recognizes. Keywords are host names and
+// paths of hosted products, and product names that appear in headers and feed versions.
+var producerSignatures = []producerSignature{
+	{"swiftly", []string{"goswift.ly", "swiftly"}},
+	{"transitclock", []string{"transitclock", "transitime", "/command/gtfs-rt"}},
+	{"onebusaway", []string{"/api/gtfs_realtime/", "onebusaway"}},
+	{"trillium", []string{"trilliumtransit", "trillium"}},
+	{"clever_devices", []string{"cleverdevices", "clever devices"}},
+	{"avail", []string{"availtec", "myavail"}},
+	{"syncromatics", []string{"syncromatics"}},
+	{"passio", []string{"passiogo", "passio3"}},
+	{"tripspark", []string{"tripspark"}},
+	{"routematch", []string{"routematch"}},
+	{"connexionz", []string{"connexionz"}},
+	{"optibus", []string{"optibus"}},
+}
+
+// Producer is the likely software producing the GTFS-RT feed of a server, as identified by
+// identifyProducer, with the version fields of the feed header.
+type Producer struct {
+	Name string
+	// Evidence is what the producer was identified from (EvidenceFeedVersion, EvidenceHTTPHeader
+	// or EvidenceURL), empty for ProducerUnknown.
+	Evidence            string
+	GtfsRealtimeVersion string
+	FeedVersion         string
+	Incrementality      string
+}
+
+// feedHeader holds the fields of the FeedHeader of a GTFS-RT feed used to identify producers.
+type feedHeader struct {
+	gtfsRealtimeVersion string
+	incrementality      string
+	// feedVersion is field 4 of FeedHeader, added to the specification after the version of
+	// the bindings of go-gtfs, so the header is decoded from the wire format.
+	feedVersion string
+}
+
+// parseFeedHeader decodes the header (field 1) of a GTFS-RT FeedMessage.
+func parseFeedHeader(data []byte) (feedHeader, error) {
+	var header feedHeader
+	for len(data) > 0 {
+		num, typ, n := protowire.ConsumeTag(data)
+		if n < 0 {
+			return header, protowire.ParseError(n)
+		}
+		data = data[n:]
+		if num != 1 || typ != protowire.BytesType {
+			n = protowire.ConsumeFieldValue(num, typ, data)
+			if n < 0 {
+				return header, protowire.ParseError(n)
+			}
+			data = data[n:]
+			continue
+		}
+		value, n := protowire.ConsumeBytes(data)
+		if n < 0 {
+			return header, protowire.ParseError(n)
+		}
+		return parseFeedHeaderFields(value)
+	}
+	return header, errors.New("no feed header")
+}
+
+// parseFeedHeaderFields decodes the fields of a FeedHeader message.
+func parseFeedHeaderFields(data []byte) (feedHeader, error) {
+	header := feedHeader{incrementality: "FULL_DATASET"}
+	for len(data) > 0 {
+		num, typ, n := protowire.ConsumeTag(data)
+		if n < 0 {
+			return header, protowire.ParseError(n)
+		}
+		data = data[n:]
+		switch {
+		case num == 1 && typ == protowire.BytesType, num == 4 && typ == protowire.BytesType:
+			value, n := protowire.ConsumeString(data)
+			if n < 0 {
+				return header, protowire.ParseError(n)
+			}
+			if num == 1 {
+				header.gtfsRealtimeVersion = value
+			} else {
+				header.feedVersion = value
+			}
+			data = data[n:]
+		case num == 2 && typ == protowire.VarintType:
+			value, n := protowire.ConsumeVarint(data)
+			if n < 0 {
+				return header, protowire.ParseError(n)
+			}
+			if value == 1 {
+				header.incrementality = "DIFFERENTIAL"
+			}
+			data = data[n:]
+		default:
+			n = protowire.ConsumeFieldValue(num, typ, data)
+			if n < 0 {
+				return header, protowire.ParseError(n)
+			}
+			data = data[n:]
+		}
+	}
+	return header, nil
+}
+
+// identifyProducer guesses the producer of a GTFS-RT feed from its feed_version, then from the
+// Server and X-Powered-By headers of its response, then from its URL. Feeds whose header cannot
+// be decoded are identified from the response and the URL alone.
+func identifyProducer(feedURL string, headers http.Header, data []byte) Producer {
+	header, _ := parseFeedHeader(data)
+	producer := Producer{
+		Name:                ProducerUnknown,
+		GtfsRealtimeVersion: header.gtfsRealtimeVersion,
+		FeedVersion:         header.feedVersion,
+		Incrementality:      header.incrementality,
+	}
+	sources := []struct {
+		evidence string
+		value    string
+	}{
+		{EvidenceFeedVersion, header.feedVersion},
+		{EvidenceHTTPHeader, headers.Get("Server") + " " + headers.Get("X-Powered-By")},
+		{EvidenceURL, feedURL},
+	}
+	for _, source := range sources {
+		value := strings.ToLower(source.value)
+		for _, signature := range producerSignatures {
+			for _, keyword := range signature.keywords {
+				if strings.Contains(value, keyword) {
+					producer.Name, producer.Evidence = signature.name, source.evidence
+					return producer
+				}
+			}
+		}
+	}
+	return producer
+}
+
+// recordProducer exports the producer of the GTFS-RT feed of a server as gtfs_rt_producer_info,
+// replacing the previous series of the server when the producer or its versions change.
+func recordProducer(server models.ObaServer, realtimeStore RealtimeStore, producer Producer) {
+	if previous, ok := realtimeStore.Producer(server.ID); ok && previous == producer {
+		return
+	}
+	serverID := strconv.Itoa(server.ID)
+	ProducerInfoGauge.DeletePartialMatch(map[string]string{"server_id": serverID})
+	ProducerInfoGauge.WithLabelValues(serverID, producer.Name, producer.Evidence, producer.GtfsRealtimeVersion, producer.FeedVersion, producer.Incrementality).Set(1)
+	realtimeStore.SetProducer(server.ID, producer)
+}
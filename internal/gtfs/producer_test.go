@@ -0,0 +1,94 @@
+package gtfs
+
+import (
+	"net/http"
+	"testing"
+
+	"google.golang.org/protobuf/encoding/protowire"
+	"watchdog.onebusaway.org/internal/models"
+)
+
+// feedWithHeader encodes a GTFS-RT FeedMessage with only a header.
+func feedWithHeader(version, feedVersion string, differential bool) []byte {
+	var header []byte
+	header = protowire.AppendTag(header, 1, protowire.BytesType)
+	header = protowire.AppendString(header, version)
+	if differential {
+		header = protowire.AppendTag(header, 2, protowire.VarintType)
+		header = protowire.AppendVarint(header, 1)
+	}
+	header = protowire.AppendTag(header, 3, protowire.VarintType)
+	header = protowire.AppendVarint(header, 1741593600)
+	if feedVersion != "" {
+		header = protowire.AppendTag(header, 4, protowire.BytesType)
+		header = protowire.AppendString(header, feedVersion)
+	}
+	var feed []byte
+	feed = protowire.AppendTag(feed, 1, protowire.BytesType)
+	return protowire.AppendBytes(feed, header)
+}
+
+func TestIdentifyProducer(t *testing.T) {
+	tests := []struct {
+		name    string
+		url     string
+		headers http.Header
+		data    []byte
+		want    Producer
+	}{
+		{
+			name: "feed version",
+			url:  "https://feeds.example.org/vehicles.pb",
+			data: feedWithHeader("2.0", "TransitClock 2.1.0", false),
+			want: Producer{Name: "transitclock", Evidence: EvidenceFeedVersion, GtfsRealtimeVersion: "2.0", FeedVersion: "TransitClock 2.1.0", Incrementality: "FULL_DATASET"},
+		},
+		{
+			name:    "response header",
+			url:     "https://feeds.example.org/vehicles.pb",
+			headers: http.Header{"X-Powered-By": {"Syncromatics GTFS-RT"}},
+			data:    feedWithHeader("1.0", "", false),
+			want:    Producer{Name: "syncromatics", Evidence: EvidenceHTTPHeader, GtfsRealtimeVersion: "1.0", Incrementality: "FULL_DATASET"},
+		},
+		{
+			name: "url",
+			url:  "https://api.goswift.ly/real-time/agency/gtfs-rt-vehicle-positions",
+			data: feedWithHeader("2.0", "", true),
+			want: Producer{Name: "swiftly", Evidence: EvidenceURL, GtfsRealtimeVersion: "2.0", Incrementality: "DIFFERENTIAL"},
+		},
+		{
+			name: "unknown producer and undecodable feed",
+			url:  "https://feeds.example.org/vehicles.pb",
+			data: []byte("<html>"),
+			want: Producer{Name: ProducerUnknown},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			headers := tt.headers
+			if headers == nil {
+				headers = http.Header{}
+			}
+			if got := identifyProducer(tt.url, headers, tt.data); got != tt.want {
+				t.Errorf("identifyProducer() = %+v, want %+v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestRecordProducer(t *testing.T) {
+	server := models.ObaServer{ID: 9101}
+	store := NewRealtimeStore()
+
+	recordProducer(server, store, Producer{Name: "swiftly", Evidence: EvidenceURL, GtfsRealtimeVersion: "2.0", Incrementality: "FULL_DATASET"})
+	recordProducer(server, store, Producer{Name: "swiftly", Evidence: EvidenceURL, GtfsRealtimeVersion: "2.0", FeedVersion: "v2", Incrementality: "FULL_DATASET"})
+
+	if got := gaugeValue(t, ProducerInfoGauge.WithLabelValues("9101", "swiftly", EvidenceURL, "2.0", "v2", "FULL_DATASET")); got != 1 {
+		t.Errorf("expected the current producer series to be 1, got %v", got)
+	}
+	if ProducerInfoGauge.DeleteLabelValues("9101", "swiftly", EvidenceURL, "2.0", "", "FULL_DATASET") {
+		t.Error("expected the previous series of the server to be removed")
+	}
+	if producer, ok := store.Producer(server.ID); !ok || producer.FeedVersion != "v2" {
+		t.Errorf("expected the store to hold the current producer, got %+v", producer)
+	}
+}
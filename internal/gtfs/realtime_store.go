@@ -1,29 +1,63 @@
 package gtfs
 
 import (
+	"context"
+	"errors"
 	"sync"
+	"time"
 
 	"watchdog.onebusaway.org/internal/models"
+	"watchdog.onebusaway.org/internal/sharedstore"
 )
 
-// RealtimeStore is used to store GTFS-RT data
+// RealtimeStore stores the GTFS-RT data: the parsed feed shared by the checks, and the raw feed
+// and producer of each server. Implementations must be safe for concurrent use.
+type RealtimeStore interface {
+	// Set stores the latest parsed GTFS-RT data, and Get returns it, or nil if none was set.
+	Set(newData *models.RealtimeData)
+	Get() *models.RealtimeData
+	// SetRaw stores the last fetched feed of a server, and Raw returns it, with false if none
+	// was fetched.
+	SetRaw(serverID int, feed RawFeed)
+	Raw(serverID int) (RawFeed, bool)
+	// SetProducer stores the producer of the feed of a server, and Producer returns it, with
+	// false if its feed was never fetched.
+	SetProducer(serverID int, producer Producer)
+	Producer(serverID int) (Producer, bool)
+}
+
+// MemoryRealtimeStore is an in-memory RealtimeStore, used to store GTFS-RT data
 // fetched once by a designated function. This avoids making multiple API calls for the same data
 // and allows other components to reuse the parsed result safely across goroutines.
 //
 // It provides a thread-safe way to store and retrieve parsed GTFS-RT data.
 // It ensures that multiple goroutines can safely read the same data after it is set once.
-type RealtimeStore struct {
+type MemoryRealtimeStore struct {
 	mu   sync.RWMutex
 	data *models.RealtimeData
+	// raw holds the last fetched feed of each server, kept for diagnostics.
+	raw map[int]RawFeed
+	// producers holds the producer of the feed of each server (see identifyProducer).
+	producers map[int]Producer
+	// rawReplica and producerReplica share the raw feeds and the producers with the other
+	// instances (see Share); nil if they are not shared. The parsed data is not shared.
+	rawReplica      *sharedstore.Replica[RawFeed]
+	producerReplica *sharedstore.Replica[Producer]
+}
+
+// RawFeed is a GTFS-RT feed as fetched, before parsing.
+type RawFeed struct {
+	Data      []byte
+	FetchedAt time.Time
 }
 
-// NewRealtimeStore creates and returns a new empty RealtimeStore instance.
+// NewRealtimeStore creates and returns a new empty MemoryRealtimeStore instance.
 //
 // Usage:
 //
 //	store := gtfs.NewRealtimeStore()
-func NewRealtimeStore() *RealtimeStore {
-	return &RealtimeStore{}
+func NewRealtimeStore() *MemoryRealtimeStore {
+	return &MemoryRealtimeStore{raw: make(map[int]RawFeed), producers: make(map[int]Producer)}
 }
 
 // Set stores the latest parsed GTFS-RT data in a thread-safe way.
@@ -31,7 +65,7 @@ func NewRealtimeStore() *RealtimeStore {
 //
 // Parameters:
 //   - newData: The parsed GTFS-RT feed to store.
-func (s *RealtimeStore) Set(newData *models.RealtimeData) {
+func (s *MemoryRealtimeStore) Set(newData *models.RealtimeData) {
 	s.mu.Lock()
 	defer s.mu.Unlock()
 	s.data = newData
@@ -42,8 +76,76 @@ func (s *RealtimeStore) Set(newData *models.RealtimeData) {
 //
 // Returns:
 //   - A pointer to the parsed GTFS-RT feed, or nil if not set.
-func (s *RealtimeStore) Get() *models.RealtimeData {
+func (s *MemoryRealtimeStore) Get() *models.RealtimeData {
 	s.mu.RLock()
 	defer s.mu.RUnlock()
 	return s.data
 }
+
+// SetRaw stores the last fetched GTFS-RT feed of a server, whether or not it could be parsed.
+// The number of stored feeds and their total size are exported as watchdog_store_entries and
+// watchdog_store_bytes with store="gtfs_realtime_raw".
+func (s *MemoryRealtimeStore) SetRaw(serverID int, feed RawFeed) {
+	s.setRaw(serverID, feed)
+	s.rawReplica.Publish(serverID, feed)
+}
+
+// setRaw stores the raw feed of a server without publishing it.
+func (s *MemoryRealtimeStore) setRaw(serverID int, feed RawFeed) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.raw[serverID] = feed
+
+	var total int
+	for _, raw := range s.raw {
+		total += len(raw.Data)
+	}
+	StoreEntriesGauge.WithLabelValues("gtfs_realtime_raw").Set(float64(len(s.raw)))
+	StoreBytesGauge.WithLabelValues("gtfs_realtime_raw").Set(float64(total))
+}
+
+// Raw returns the last fetched GTFS-RT feed of a server, and false if none was fetched.
+func (s *MemoryRealtimeStore) Raw(serverID int) (RawFeed, bool) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	feed, ok := s.raw[serverID]
+	return feed, ok
+}
+
+// SetProducer stores the producer of the GTFS-RT feed of a server.
+func (s *MemoryRealtimeStore) SetProducer(serverID int, producer Producer) {
+	s.setProducer(serverID, producer)
+	s.producerReplica.Publish(serverID, producer)
+}
+
+// setProducer stores the producer of a server without publishing it.
+func (s *MemoryRealtimeStore) setProducer(serverID int, producer Producer) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.producers[serverID] = producer
+}
+
+// Share publishes the raw feeds and producers stored from now on to backend, for the other
+// instances to sync (see Sync). It must be called before the store is used.
+func (s *MemoryRealtimeStore) Share(backend sharedstore.Backend) {
+	s.rawReplica = sharedstore.NewReplica[RawFeed]("gtfs_realtime_raw", backend)
+	s.producerReplica = sharedstore.NewReplica[Producer]("gtfs_realtime_producer", backend)
+}
+
+// Sync stores the raw feeds and producers of the given servers published by the other
+// instances since the last sync. It does nothing if the store is not shared.
+func (s *MemoryRealtimeStore) Sync(ctx context.Context, serverIDs []int) error {
+	return errors.Join(
+		s.rawReplica.Sync(ctx, serverIDs, s.setRaw, func(int) {}),
+		s.producerReplica.Sync(ctx, serverIDs, s.setProducer, func(int) {}),
+	)
+}
+
+// Producer returns the producer of the GTFS-RT feed of a server, and false if its feed was
+// never fetched.
+func (s *MemoryRealtimeStore) Producer(serverID int) (Producer, bool) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	producer, ok := s.producers[serverID]
+	return producer, ok
+}
@@ -0,0 +1,278 @@
+package gtfs
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"log/slog"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/getsentry/sentry-go"
+	"watchdog.onebusaway.org/internal/models"
+	"watchdog.onebusaway.org/internal/report"
+	"watchdog.onebusaway.org/internal/scheduler"
+	"watchdog.onebusaway.org/internal/utils"
+)
+
+// maxServiceCalendarSize bounds the size of a reduced service calendar.
+const maxServiceCalendarSize = 1 << 20
+
+// ServiceReduction is a window of planned reduced service of an agency, e.g. a school break
+// or a snow day, during which fewer vehicles than scheduled are expected to run.
+type ServiceReduction struct {
+	Start   time.Time `json:"start"`
+	End     time.Time `json:"end"`
+	Summary string    `json:"summary,omitempty"`
+}
+
+// ServiceReductionStore is a thread-safe in-memory store of the reduced service calendars of
+// servers, indexed by server ID.
+type ServiceReductionStore struct {
+	mu         sync.RWMutex
+	reductions map[int][]ServiceReduction
+}
+
+// NewServiceReductionStore creates an empty ServiceReductionStore.
+func NewServiceReductionStore() *ServiceReductionStore {
+	return &ServiceReductionStore{reductions: make(map[int][]ServiceReduction)}
+}
+
+// Set replaces the service reductions of a server.
+func (s *ServiceReductionStore) Set(serverID int, reductions []ServiceReduction) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.reductions[serverID] = reductions
+}
+
+// Delete forgets the service reductions of a server.
+func (s *ServiceReductionStore) Delete(serverID int) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	delete(s.reductions, serverID)
+}
+
+// Active returns the service reduction of a server in effect at a point in time, if any.
+// A nil store has no reductions.
+func (s *ServiceReductionStore) Active(serverID int, at time.Time) (ServiceReduction, bool) {
+	if s == nil {
+		return ServiceReduction{}, false
+	}
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	for _, reduction := range s.reductions[serverID] {
+		if !at.Before(reduction.Start) && at.Before(reduction.End) {
+			return reduction, true
+		}
+	}
+	return ServiceReduction{}, false
+}
+
+// parseServiceReductions reads a calendar of planned service reductions, either as iCalendar
+// (RFC 5545, the VEVENTs of the calendar) or as a JSON array of {"start", "end", "summary"}
+// objects. Reductions are returned in chronological order.
+func parseServiceReductions(data []byte) ([]ServiceReduction, error) {
+	var reductions []ServiceReduction
+	var err error
+	if trimmed := bytes.TrimSpace(data); len(trimmed) > 0 && trimmed[0] == '[' {
+		reductions, err = parseServiceReductionsJSON(trimmed)
+	} else {
+		reductions, err = parseServiceReductionsICal(data)
+	}
+	if err != nil {
+		return nil, err
+	}
+	sort.Slice(reductions, func(i, j int) bool { return reductions[i].Start.Before(reductions[j].Start) })
+	return reductions, nil
+}
+
+// parseServiceReductionsJSON reads a JSON calendar. Start and end are RFC 3339 times, or
+// dates (2006-01-02) in UTC covering whole days; an end date is included in the reduction.
+func parseServiceReductionsJSON(data []byte) ([]ServiceReduction, error) {
+	var entries []struct {
+		Start   string `json:"start"`
+		End     string `json:"end"`
+		Summary string `json:"summary"`
+	}
+	if err := json.Unmarshal(data, &entries); err != nil {
+		return nil, fmt.Errorf("failed to parse service calendar JSON: %w", err)
+	}
+	parse := func(value string, end bool) (time.Time, error) {
+		if t, err := time.Parse(time.RFC3339, value); err == nil {
+			return t, nil
+		}
+		day, err := time.Parse(time.DateOnly, value)
+		if err != nil {
+			return time.Time{}, fmt.Errorf("invalid time %q: expected an RFC 3339 time or a date", value)
+		}
+		if end {
+			day = day.AddDate(0, 0, 1)
+		}
+		return day, nil
+	}
+
+	reductions := make([]ServiceReduction, 0, len(entries))
+	for i, entry := range entries {
+		start, err := parse(entry.Start, false)
+		if err != nil {
+			return nil, fmt.Errorf("service reduction %d: %w", i+1, err)
+		}
+		end, err := parse(entry.End, true)
+		if err != nil {
+			return nil, fmt.Errorf("service reduction %d: %w", i+1, err)
+		}
+		if !end.After(start) {
+			return nil, fmt.Errorf("service reduction %d ends before it starts", i+1)
+		}
+		reductions = append(reductions, ServiceReduction{Start: start, End: end, Summary: entry.Summary})
+	}
+	return reductions, nil
+}
+
+// parseServiceReductionsICal reads the DTSTART, DTEND and SUMMARY of the VEVENTs of an
+// iCalendar document. Times are read in their TZID, or in the X-WR-TIMEZONE of the calendar
+// for floating times and all-day events, or in UTC. An event without DTEND lasts a day if it
+// is an all-day event, and is ignored otherwise. Recurrence rules are not expanded.
+func parseServiceReductionsICal(data []byte) ([]ServiceReduction, error) {
+	// Long lines are folded into lines starting with a space or a tab.
+	text := strings.NewReplacer("\r\n ", "", "\r\n\t", "", "\n ", "", "\n\t", "").Replace(string(data))
+	text = strings.TrimSpace(strings.TrimPrefix(text, "\ufeff"))
+	lines := strings.Split(strings.ReplaceAll(text, "\r\n", "\n"), "\n")
+	if !strings.EqualFold(strings.TrimSpace(lines[0]), "BEGIN:VCALENDAR") {
+		return nil, fmt.Errorf("failed to parse service calendar: not a JSON array or an iCalendar document")
+	}
+
+	location := time.UTC
+	var reductions []ServiceReduction
+	var event *ServiceReduction
+	var allDay bool
+	for _, line := range lines {
+		name, value, ok := strings.Cut(strings.TrimSpace(line), ":")
+		if !ok {
+			continue
+		}
+		name, params, _ := strings.Cut(name, ";")
+		switch strings.ToUpper(name) {
+		case "X-WR-TIMEZONE":
+			if loc, err := time.LoadLocation(value); err == nil {
+				location = loc
+			}
+		case "BEGIN":
+			if strings.EqualFold(value, "VEVENT") {
+				event, allDay = &ServiceReduction{}, false
+			}
+		case "END":
+			if !strings.EqualFold(value, "VEVENT") || event == nil {
+				continue
+			}
+			if event.End.IsZero() && allDay {
+				event.End = event.Start.AddDate(0, 0, 1)
+			}
+			if !event.Start.IsZero() && event.End.After(event.Start) {
+				reductions = append(reductions, *event)
+			}
+			event = nil
+		case "SUMMARY":
+			if event != nil {
+				event.Summary = strings.NewReplacer(`\,`, ",", `\;`, ";", `\n`, " ", `\\`, `\`).Replace(value)
+			}
+		case "DTSTART", "DTEND":
+			if event == nil {
+				continue
+			}
+			t, date, err := parseICalTime(value, params, location)
+			if err != nil {
+				return nil, fmt.Errorf("failed to parse service calendar: %s: %w", name, err)
+			}
+			if strings.EqualFold(name, "DTSTART") {
+				event.Start, allDay = t, date
+			} else {
+				event.End = t
+			}
+		}
+	}
+	return reductions, nil
+}
+
+// parseICalTime parses an iCalendar DATE or DATE-TIME value, and reports whether it is a date.
+func parseICalTime(value, params string, location *time.Location) (t time.Time, date bool, err error) {
+	for _, param := range strings.Split(params, ";") {
+		if name, tzid, ok := strings.Cut(param, "="); ok && strings.EqualFold(name, "TZID") {
+			loc, err := time.LoadLocation(strings.Trim(tzid, `"`))
+			if err != nil {
+				return time.Time{}, false, fmt.Errorf("unknown time zone %q", tzid)
+			}
+			location = loc
+		}
+	}
+	switch {
+	case len(value) == 8:
+		t, err = time.ParseInLocation("20060102", value, location)
+		return t, true, err
+	case strings.HasSuffix(value, "Z"):
+		t, err = time.Parse("20060102T150405Z", value)
+	default:
+		t, err = time.ParseInLocation("20060102T150405", value, location)
+	}
+	return t, false, err
+}
+
+// fetchServiceReductions downloads and parses the reduced service calendar at url.
+func fetchServiceReductions(ctx context.Context, client *http.Client, url string) ([]ServiceReduction, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, url, nil)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create request for %s: %w", url, err)
+	}
+	resp, err := client.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("failed to fetch service calendar: %w", err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("service calendar returned status: %d", resp.StatusCode)
+	}
+	data, err := io.ReadAll(io.LimitReader(resp.Body, maxServiceCalendarSize))
+	if err != nil {
+		return nil, fmt.Errorf("failed to read service calendar: %w", err)
+	}
+	return parseServiceReductions(data)
+}
+
+// loadServiceReductions fetches the reduced service calendar of every server that sets
+// `reduced_service_calendar_url` into store, and forgets the calendars of the other servers.
+// A server whose calendar cannot be fetched keeps its previous reductions; the failure is
+// logged and reported.
+func loadServiceReductions(ctx context.Context, client *http.Client, servers []models.ObaServer, store *ServiceReductionStore, logger *slog.Logger) {
+	for _, server := range servers {
+		if server.ReducedServiceCalendarURL == "" {
+			store.Delete(server.ID)
+			continue
+		}
+		reductions, err := fetchServiceReductions(ctx, client, server.ReducedServiceCalendarURL)
+		if err != nil {
+			report.ReportErrorWithSentryOptions(err, report.SentryReportOptions{
+				Tags:  utils.MakeMap("server_id", strconv.Itoa(server.ID)),
+				Level: sentry.LevelWarning,
+			})
+			logger.Warn("Failed to read reduced service calendar, keeping the previous one", "server_id", server.ID, "error", err)
+			continue
+		}
+		store.Set(server.ID, reductions)
+		logger.Info("Loaded reduced service calendar", "server_id", server.ID, "reductions", len(reductions))
+	}
+}
+
+// refreshServiceReductions loads the reduced service calendars of the servers returned by
+// servers right away, then at every activation of schedule, until ctx is canceled.
+func refreshServiceReductions(ctx context.Context, client *http.Client, servers func() []models.ObaServer, schedule scheduler.Schedule, store *ServiceReductionStore, logger *slog.Logger) {
+	loadServiceReductions(ctx, client, servers(), store, logger)
+	scheduler.Run(ctx, "service_reductions_refresh", schedule, func() {
+		loadServiceReductions(ctx, client, servers(), store, logger)
+	})
+}
@@ -0,0 +1,113 @@
+package gtfs
+
+import (
+	"context"
+	"io"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"watchdog.onebusaway.org/internal/models"
+)
+
+func TestParseServiceReductionsICal(t *testing.T) {
+	calendar := "BEGIN:VCALENDAR\r\n" +
+		"VERSION:2.0\r\n" +
+		"X-WR-TIMEZONE:America/Los_Angeles\r\n" +
+		"BEGIN:VEVENT\r\n" +
+		"DTSTART;TZID=America/New_York:20250210T060000\r\n" +
+		"DTEND;TZID=America/New_York:20250210T120000\r\n" +
+		"SUMMARY:Snow day\\, reduced\r\n" +
+		"  service\r\n" +
+		"END:VEVENT\r\n" +
+		"BEGIN:VEVENT\r\n" +
+		"DTSTART;VALUE=DATE:20241223\r\n" +
+		"DTEND;VALUE=DATE:20250102\r\n" +
+		"SUMMARY:Winter break\r\n" +
+		"END:VEVENT\r\n" +
+		"BEGIN:VEVENT\r\n" +
+		"DTSTART:20250301T080000Z\r\n" +
+		"SUMMARY:No end\r\n" +
+		"END:VEVENT\r\n" +
+		"END:VCALENDAR\r\n"
+	reductions, err := parseServiceReductions([]byte(calendar))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(reductions) != 2 {
+		t.Fatalf("expected 2 reductions, got %+v", reductions)
+	}
+
+	losAngeles, _ := time.LoadLocation("America/Los_Angeles")
+	winter := reductions[0]
+	if winter.Summary != "Winter break" || !winter.Start.Equal(time.Date(2024, 12, 23, 0, 0, 0, 0, losAngeles)) || !winter.End.Equal(time.Date(2025, 1, 2, 0, 0, 0, 0, losAngeles)) {
+		t.Errorf("unexpected all-day reduction %+v", winter)
+	}
+	snow := reductions[1]
+	if snow.Summary != "Snow day, reduced service" || !snow.Start.Equal(time.Date(2025, 2, 10, 11, 0, 0, 0, time.UTC)) || snow.End.Sub(snow.Start) != 6*time.Hour {
+		t.Errorf("unexpected reduction %+v", snow)
+	}
+
+	if _, err := parseServiceReductions([]byte("not a calendar")); err == nil {
+		t.Error("expected an error for a document that is neither JSON nor iCalendar")
+	}
+}
+
+func TestParseServiceReductionsJSON(t *testing.T) {
+	reductions, err := parseServiceReductions([]byte(`[
+		{"start": "2025-02-10T06:00:00-05:00", "end": "2025-02-10T12:00:00-05:00", "summary": "Snow day"},
+		{"start": "2024-12-23", "end": "2025-01-01", "summary": "Winter break"}
+	]`))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(reductions) != 2 || reductions[0].Summary != "Winter break" {
+		t.Fatalf("expected 2 reductions in chronological order, got %+v", reductions)
+	}
+	// The end date is included.
+	if want := time.Date(2025, 1, 2, 0, 0, 0, 0, time.UTC); !reductions[0].End.Equal(want) {
+		t.Errorf("expected the winter break to end at %v, got %v", want, reductions[0].End)
+	}
+
+	if _, err := parseServiceReductions([]byte(`[{"start": "2025-01-02", "end": "2025-01-01"}]`)); err == nil {
+		t.Error("expected an error for a reduction ending before it starts")
+	}
+	if _, err := parseServiceReductions([]byte(`[{"start": "tomorrow", "end": "2025-01-01"}]`)); err == nil {
+		t.Error("expected an error for an invalid time")
+	}
+}
+
+func TestLoadServiceReductions(t *testing.T) {
+	calendar := `[{"start": "2025-01-01T00:00:00Z", "end": "2025-01-01T12:00:00Z", "summary": "New Year"}]`
+	status := http.StatusOK
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(status)
+		_, _ = w.Write([]byte(calendar))
+	}))
+	defer ts.Close()
+
+	logger := slog.New(slog.NewTextHandler(io.Discard, nil))
+	store := NewServiceReductionStore()
+	servers := []models.ObaServer{{ID: 1, ReducedServiceCalendarURL: ts.URL}, {ID: 2}}
+	store.Set(2, []ServiceReduction{{Start: time.Time{}, End: time.Now().Add(time.Hour)}})
+
+	loadServiceReductions(context.Background(), ts.Client(), servers, store, logger)
+	if reduction, ok := store.Active(1, time.Date(2025, 1, 1, 6, 0, 0, 0, time.UTC)); !ok || reduction.Summary != "New Year" {
+		t.Errorf("expected the New Year reduction to be active, got %+v, %v", reduction, ok)
+	}
+	if _, ok := store.Active(1, time.Date(2025, 1, 1, 12, 0, 0, 0, time.UTC)); ok {
+		t.Error("expected no reduction at its end")
+	}
+	if _, ok := store.Active(2, time.Now()); ok {
+		t.Error("expected the reductions of a server without a calendar to be forgotten")
+	}
+
+	// A failed refresh keeps the previous calendar.
+	status = http.StatusInternalServerError
+	loadServiceReductions(context.Background(), ts.Client(), servers, store, logger)
+	if _, ok := store.Active(1, time.Date(2025, 1, 1, 6, 0, 0, 0, time.UTC)); !ok {
+		t.Error("expected the previous calendar to be kept after a failed refresh")
+	}
+}
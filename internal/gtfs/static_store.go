@@ -1,42 +1,89 @@
 package gtfs
 
 import (
+	"context"
 	"sync"
+	"unsafe"
 
 	"watchdog.onebusaway.org/internal/models"
+	"watchdog.onebusaway.org/internal/sharedstore"
 )
 
-// StaticStore is a thread-safe in-memory store for GTFS static bundles,
+// StaticStore stores the GTFS static data of each server, indexed by server ID.
+// Implementations must be safe for concurrent use.
+type StaticStore interface {
+	// Set stores the GTFS static data of a server, replacing the previous data.
+	Set(serverID int, newData *models.StaticData)
+	// Get returns the GTFS static data of a server, and false if it has none.
+	Get(serverID int) (*models.StaticData, bool)
+	// Len returns the number of servers with GTFS static data.
+	Len() int
+}
+
+// MemoryStaticStore is a thread-safe in-memory StaticStore for GTFS static bundles,
 // indexed by server ID. It allows concurrent access to GTFS data
 // using read-write locks using a sync.RWMutex.
-type StaticStore struct {
+type MemoryStaticStore struct {
 	mu   sync.RWMutex
 	data map[int]*models.StaticData // GTFS Static bundle data of each server, indexed by server ID
+	// sizes holds the approximate size of the data of each server (see approxStaticDataSize).
+	sizes map[int]int64
+	// replica shares the data with the other instances (see Share); nil if it is not shared.
+	replica *sharedstore.Replica[*models.StaticData]
 }
 
-// NewStaticStore initializes and returns a new instance of StaticStore.
+// NewStaticStore initializes and returns a new instance of MemoryStaticStore.
 // The underlying map is lazily initialized on first use in Set.
 //
 // Returns:
-//   - *StaticStore: A new, empty StaticStore instance.
-func NewStaticStore() *StaticStore {
-	return &StaticStore{}
+//   - *MemoryStaticStore: A new, empty MemoryStaticStore instance.
+func NewStaticStore() *MemoryStaticStore {
+	return &MemoryStaticStore{}
 }
 
 // Set stores the given GTFS static data for the specified server ID.
 // If the internal map is not initialized, it creates it.
+// The number of servers and the approximate size of their data are exported as
+// watchdog_store_entries and watchdog_store_bytes with store="gtfs_static".
 // This method is thread-safe and uses a write lock.
 //
 // Parameters:
 //   - serverID: The unique identifier for the OBA server.
 //   - newData: A pointer to the GTFS static data to store.
-func (s *StaticStore) Set(serverID int, newData *models.StaticData) {
+func (s *MemoryStaticStore) Set(serverID int, newData *models.StaticData) {
+	s.set(serverID, newData)
+	s.replica.Publish(serverID, newData)
+}
+
+// set stores the data of a server without publishing it.
+func (s *MemoryStaticStore) set(serverID int, newData *models.StaticData) {
 	s.mu.Lock()
 	defer s.mu.Unlock()
 	if s.data == nil {
 		s.data = make(map[int]*models.StaticData)
+		s.sizes = make(map[int]int64)
 	}
 	s.data[serverID] = newData
+	s.sizes[serverID] = approxStaticDataSize(newData)
+
+	var total int64
+	for _, size := range s.sizes {
+		total += size
+	}
+	StoreEntriesGauge.WithLabelValues("gtfs_static").Set(float64(len(s.data)))
+	StoreBytesGauge.WithLabelValues("gtfs_static").Set(float64(total))
+}
+
+// Share publishes the data stored from now on to backend, for the other instances to sync (see
+// Sync). It must be called before the store is used.
+func (s *MemoryStaticStore) Share(backend sharedstore.Backend) {
+	s.replica = sharedstore.NewReplica[*models.StaticData]("gtfs_static", backend)
+}
+
+// Sync stores the data of the given servers published by the other instances since the last
+// sync. It does nothing if the store is not shared.
+func (s *MemoryStaticStore) Sync(ctx context.Context, serverIDs []int) error {
+	return s.replica.Sync(ctx, serverIDs, s.set, func(int) {})
 }
 
 // Get retrieves the GTFS static data for the specified server ID.
@@ -48,9 +95,36 @@ func (s *StaticStore) Set(serverID int, newData *models.StaticData) {
 // Returns:
 //   - *remoteGtfs.Static: A pointer to the GTFS static data, if present.
 //   - bool: True if data exists for the given server ID, false otherwise.
-func (s *StaticStore) Get(serverID int) (*models.StaticData, bool) {
+func (s *MemoryStaticStore) Get(serverID int) (*models.StaticData, bool) {
 	s.mu.RLock()
 	defer s.mu.RUnlock()
 	data, exists := s.data[serverID]
 	return data, exists
 }
+
+// Len returns the number of servers for which GTFS static data is stored.
+// This method is thread-safe and uses a read lock.
+func (s *MemoryStaticStore) Len() int {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	return len(s.data)
+}
+
+// approxStaticDataSize estimates the memory used by static data: the size of its records, plus
+// the identifiers of its trip spans, which make up most of it. The other strings are not
+// counted, so the estimate is a lower bound meant to follow the trend, not an exact figure.
+func approxStaticDataSize(data *models.StaticData) int64 {
+	if data == nil {
+		return 0
+	}
+	size := int64(len(data.Stops))*int64(unsafe.Sizeof(data.Stops[0])) +
+		int64(len(data.Routes))*int64(unsafe.Sizeof(data.Routes[0])) +
+		int64(len(data.Agencies))*int64(unsafe.Sizeof(data.Agencies[0])) +
+		int64(len(data.Services))*int64(unsafe.Sizeof(data.Services[0])) +
+		int64(len(data.TripSpans))*int64(unsafe.Sizeof(data.TripSpans[0])) +
+		int64(len(data.Attributions))*int64(unsafe.Sizeof(data.Attributions[0]))
+	for _, span := range data.TripSpans {
+		size += int64(len(span.TripID) + len(span.RouteID) + len(span.ServiceID))
+	}
+	return size
+}
@@ -1,6 +1,8 @@
 package gtfs
 
 import (
+	"io"
+	"log/slog"
 	"net/http"
 	"net/http/httptest"
 	"os"
@@ -9,8 +11,18 @@ import (
 	"time"
 
 	remoteGtfs "github.com/OneBusAway/go-gtfs"
+	"github.com/prometheus/client_golang/prometheus"
+	dto "github.com/prometheus/client_model/go"
+	"watchdog.onebusaway.org/internal/geo"
 )
 
+// newTestGtfsService returns a GtfsService with empty stores, a discarded log and the given
+// bundle options.
+func newTestGtfsService(options GtfsServiceOptions) *GtfsService {
+	logger := slog.New(slog.NewTextHandler(io.Discard, nil))
+	return NewGtfsService(NewStaticStore(), NewRealtimeStore(), geo.NewBoundingBoxStore(), logger, nil, nil, options)
+}
+
 func setupGtfsServer(t *testing.T, fixturePath string) *httptest.Server {
 	t.Helper()
 
@@ -136,3 +148,25 @@ func assertVehicle(t *testing.T, actual *remoteGtfs.Vehicle, expected *remoteGtf
 			expected.IsEntityInMessage, actual.IsEntityInMessage)
 	}
 }
+
+// gaugeValue returns the current value of a Prometheus gauge.
+// It fails the test immediately if the metric cannot be read.
+func gaugeValue(t *testing.T, gauge prometheus.Gauge) float64 {
+	t.Helper()
+	pb := &dto.Metric{}
+	if err := gauge.Write(pb); err != nil {
+		t.Fatalf("Failed to read gauge: %v", err)
+	}
+	return pb.GetGauge().GetValue()
+}
+
+// counterValue returns the current value of a Prometheus counter.
+// It fails the test immediately if the metric cannot be read.
+func counterValue(t *testing.T, counter prometheus.Counter) float64 {
+	t.Helper()
+	pb := &dto.Metric{}
+	if err := counter.Write(pb); err != nil {
+		t.Fatalf("Failed to read counter: %v", err)
+	}
+	return pb.GetCounter().GetValue()
+}
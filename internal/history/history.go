@@ -0,0 +1,237 @@
+// Package history records the result of every check run in an embedded SQLite database, so that
+// raw check outcomes can be reported on long after Prometheus has dropped them, e.g. for the
+// monthly reports of an agency.
+package history
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"sync"
+	"time"
+
+	// Registers the "sqlite" driver, written in pure Go so that builds need no cgo.
+	_ "modernc.org/sqlite"
+)
+
+// DefaultLimit and MaxLimit are the default and maximum number of results returned by Query.
+const (
+	DefaultLimit = 1000
+	MaxLimit     = 10000
+)
+
+// pruneInterval is how often Prune deletes the results past the retention period.
+const pruneInterval = time.Hour
+
+// schema creates the table of the results. Times are stored as Unix milliseconds.
+const schema = `
+CREATE TABLE IF NOT EXISTS check_results (
+	at         INTEGER NOT NULL,
+	server_id  INTEGER NOT NULL,
+	check_name TEXT    NOT NULL,
+	value      REAL,
+	ok         INTEGER NOT NULL,
+	error      TEXT    NOT NULL DEFAULT ''
+);
+CREATE INDEX IF NOT EXISTS check_results_server_at ON check_results (server_id, at);
+CREATE INDEX IF NOT EXISTS check_results_at ON check_results (at);
+`
+
+// Result is the outcome of a run of a check for a server.
+type Result struct {
+	At       time.Time `json:"at"`
+	ServerID int       `json:"server_id"`
+	Check    string    `json:"check"`
+	// Value is the value measured by the check, e.g. the days until the bundle expires; nil for
+	// the checks that only pass or fail.
+	Value *float64 `json:"value"`
+	OK    bool     `json:"ok"`
+	// Error is the error of the run, if it failed.
+	Error string `json:"error,omitempty"`
+}
+
+// Query selects results of a server: those of Check (empty = all checks) from From, included,
+// to To, excluded (zero = no end), oldest first, up to Limit (0 = DefaultLimit, capped at
+// MaxLimit).
+type Query struct {
+	ServerID int
+	Check    string
+	From     time.Time
+	To       time.Time
+	Limit    int
+}
+
+// Store records check results in a SQLite database and forgets them after the retention period.
+// It is safe for concurrent use; a nil Store records nothing.
+type Store struct {
+	db        *sql.DB
+	retention time.Duration
+
+	mu       sync.Mutex
+	prunedAt time.Time
+}
+
+// Open opens, or creates, the database at path, keeping results for retention. It returns a nil
+// Store if path is empty.
+func Open(path string, retention time.Duration) (*Store, error) {
+	if path == "" {
+		return nil, nil
+	}
+	db, err := sql.Open("sqlite", "file:"+path+"?_pragma=journal_mode(WAL)&_pragma=busy_timeout(5000)")
+	if err != nil {
+		return nil, fmt.Errorf("failed to open history database %s: %w", path, err)
+	}
+	// SQLite has a single writer; one connection avoids "database is locked" errors.
+	db.SetMaxOpenConns(1)
+	if _, err := db.Exec(schema); err != nil {
+		db.Close()
+		return nil, fmt.Errorf("failed to create history database %s: %w", path, err)
+	}
+	return &Store{db: db, retention: retention}, nil
+}
+
+// Record stores a result.
+func (s *Store) Record(r Result) error {
+	if s == nil {
+		return nil
+	}
+	var value sql.NullFloat64
+	if r.Value != nil {
+		value = sql.NullFloat64{Float64: *r.Value, Valid: true}
+	}
+	_, err := s.db.Exec(`INSERT INTO check_results (at, server_id, check_name, value, ok, error) VALUES (?, ?, ?, ?, ?, ?)`,
+		r.At.UnixMilli(), r.ServerID, r.Check, value, r.OK, r.Error)
+	if err != nil {
+		HistoryErrorsCounter.WithLabelValues("record").Inc()
+		return fmt.Errorf("failed to record check result: %w", err)
+	}
+	return nil
+}
+
+// Query returns the results selected by q.
+func (s *Store) Query(ctx context.Context, q Query) ([]Result, error) {
+	if s == nil {
+		return nil, errors.New("history is disabled")
+	}
+	limit := q.Limit
+	if limit <= 0 {
+		limit = DefaultLimit
+	}
+	limit = min(limit, MaxLimit)
+
+	statement := `SELECT at, check_name, value, ok, error FROM check_results WHERE server_id = ? AND at >= ?`
+	args := []any{q.ServerID, q.From.UnixMilli()}
+	if !q.To.IsZero() {
+		statement += ` AND at < ?`
+		args = append(args, q.To.UnixMilli())
+	}
+	if q.Check != "" {
+		statement += ` AND check_name = ?`
+		args = append(args, q.Check)
+	}
+	statement += ` ORDER BY at, rowid LIMIT ?`
+	args = append(args, limit)
+
+	rows, err := s.db.QueryContext(ctx, statement, args...)
+	if err != nil {
+		HistoryErrorsCounter.WithLabelValues("query").Inc()
+		return nil, fmt.Errorf("failed to query check results: %w", err)
+	}
+	defer rows.Close()
+	results := []Result{}
+	for rows.Next() {
+		var (
+			at    int64
+			value sql.NullFloat64
+		)
+		r := Result{ServerID: q.ServerID}
+		if err := rows.Scan(&at, &r.Check, &value, &r.OK, &r.Error); err != nil {
+			HistoryErrorsCounter.WithLabelValues("query").Inc()
+			return nil, fmt.Errorf("failed to read check results: %w", err)
+		}
+		r.At = time.UnixMilli(at).UTC()
+		if value.Valid {
+			r.Value = &value.Float64
+		}
+		results = append(results, r)
+	}
+	if err := rows.Err(); err != nil {
+		HistoryErrorsCounter.WithLabelValues("query").Inc()
+		return nil, fmt.Errorf("failed to read check results: %w", err)
+	}
+	return results, nil
+}
+
+// DailyCount counts the runs of a check on a day (UTC), and how many of them failed.
+type DailyCount struct {
+	Runs     int
+	Failures int
+}
+
+// DailyCounts returns the runs and failures of a check of a server on each of the last days
+// days, up to and including the day of now, oldest first. Days without runs have a zero
+// DailyCount.
+func (s *Store) DailyCounts(ctx context.Context, serverID int, check string, days int, now time.Time) ([]DailyCount, error) {
+	if s == nil {
+		return nil, errors.New("history is disabled")
+	}
+	const day = int64(24 * time.Hour / time.Millisecond)
+	first := now.UnixMilli()/day - int64(days) + 1
+	rows, err := s.db.QueryContext(ctx, `SELECT at / ?, COUNT(*), COUNT(*) - SUM(ok) FROM check_results WHERE server_id = ? AND check_name = ? AND at >= ? AND at < ? GROUP BY at / ?`,
+		day, serverID, check, first*day, (first+int64(days))*day, day)
+	if err != nil {
+		HistoryErrorsCounter.WithLabelValues("query").Inc()
+		return nil, fmt.Errorf("failed to query daily check results: %w", err)
+	}
+	defer rows.Close()
+	counts := make([]DailyCount, days)
+	for rows.Next() {
+		var (
+			index int64
+			count DailyCount
+		)
+		if err := rows.Scan(&index, &count.Runs, &count.Failures); err != nil {
+			HistoryErrorsCounter.WithLabelValues("query").Inc()
+			return nil, fmt.Errorf("failed to read daily check results: %w", err)
+		}
+		counts[index-first] = count
+	}
+	if err := rows.Err(); err != nil {
+		HistoryErrorsCounter.WithLabelValues("query").Inc()
+		return nil, fmt.Errorf("failed to read daily check results: %w", err)
+	}
+	return counts, nil
+}
+
+// Prune deletes the results older than the retention period, at most once every pruneInterval,
+// and returns how many it deleted.
+func (s *Store) Prune(now time.Time) (int64, error) {
+	if s == nil {
+		return 0, nil
+	}
+	s.mu.Lock()
+	if now.Sub(s.prunedAt) < pruneInterval {
+		s.mu.Unlock()
+		return 0, nil
+	}
+	s.prunedAt = now
+	s.mu.Unlock()
+
+	result, err := s.db.Exec(`DELETE FROM check_results WHERE at < ?`, now.Add(-s.retention).UnixMilli())
+	if err != nil {
+		HistoryErrorsCounter.WithLabelValues("prune").Inc()
+		return 0, fmt.Errorf("failed to prune check results: %w", err)
+	}
+	deleted, _ := result.RowsAffected()
+	HistoryPrunedCounter.Add(float64(deleted))
+	return deleted, nil
+}
+
+// Close closes the database.
+func (s *Store) Close() error {
+	if s == nil {
+		return nil
+	}
+	return s.db.Close()
+}
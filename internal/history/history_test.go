@@ -0,0 +1,114 @@
+package history
+
+import (
+	"context"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func TestStore(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "history.db")
+	store, err := Open(path, 30*24*time.Hour)
+	if err != nil {
+		t.Fatalf("Open failed: %v", err)
+	}
+	defer store.Close()
+
+	now := time.Date(2025, 6, 1, 12, 0, 0, 0, time.UTC)
+	days := 12.0
+	results := []Result{
+		{At: now.AddDate(0, 0, -40), ServerID: 1, Check: "server_ping", OK: true},
+		{At: now.Add(-2 * time.Hour), ServerID: 1, Check: "server_ping", OK: false, Error: "server did not respond to ping"},
+		{At: now.Add(-time.Hour), ServerID: 1, Check: "bundle_expiration", Value: &days, OK: true},
+		{At: now.Add(-time.Hour), ServerID: 2, Check: "server_ping", OK: true},
+	}
+	for _, r := range results {
+		if err := store.Record(r); err != nil {
+			t.Fatalf("Record failed: %v", err)
+		}
+	}
+
+	ctx := context.Background()
+	got, err := store.Query(ctx, Query{ServerID: 1, From: now.AddDate(0, 0, -1), To: now})
+	if err != nil {
+		t.Fatalf("Query failed: %v", err)
+	}
+	if len(got) != 2 || got[0].Check != "server_ping" || got[0].OK || got[0].Error == "" || got[0].Value != nil {
+		t.Fatalf("unexpected results %+v", got)
+	}
+	if got[1].Value == nil || *got[1].Value != days || !got[1].At.Equal(now.Add(-time.Hour)) {
+		t.Errorf("expected the bundle expiration value, got %+v", got[1])
+	}
+
+	got, err = store.Query(ctx, Query{ServerID: 1, Check: "server_ping", From: now.AddDate(0, 0, -60), To: now, Limit: 1})
+	if err != nil || len(got) != 1 || !got[0].OK {
+		t.Errorf("expected the oldest ping only, got %+v (%v)", got, err)
+	}
+
+	if deleted, err := store.Prune(now); err != nil || deleted != 1 {
+		t.Errorf("expected 1 result pruned, got %d (%v)", deleted, err)
+	}
+	if deleted, _ := store.Prune(now.Add(time.Minute)); deleted != 0 {
+		t.Errorf("expected pruning to wait for the next interval, got %d deleted", deleted)
+	}
+
+	// Results survive a restart.
+	store.Close()
+	store, err = Open(path, 30*24*time.Hour)
+	if err != nil {
+		t.Fatalf("Open failed: %v", err)
+	}
+	defer store.Close()
+	got, _ = store.Query(ctx, Query{ServerID: 1, From: now.AddDate(0, 0, -60), To: now})
+	if len(got) != 2 {
+		t.Errorf("expected 2 results after reopening, got %d", len(got))
+	}
+}
+
+func TestNilStore(t *testing.T) {
+	store, err := Open("", time.Hour)
+	if err != nil || store != nil {
+		t.Fatalf("expected a nil store for an empty path, got %v (%v)", store, err)
+	}
+	if err := store.Record(Result{ServerID: 1}); err != nil {
+		t.Errorf("expected a nil store to ignore results, got %v", err)
+	}
+}
+
+func TestDailyCounts(t *testing.T) {
+	store, err := Open(filepath.Join(t.TempDir(), "history.db"), 30*24*time.Hour)
+	if err != nil {
+		t.Fatalf("Open failed: %v", err)
+	}
+	defer store.Close()
+
+	now := time.Date(2025, 6, 3, 12, 0, 0, 0, time.UTC)
+	results := []Result{
+		{At: now.AddDate(0, 0, -5), ServerID: 1, Check: "server_ping", OK: true},
+		{At: time.Date(2025, 6, 1, 0, 0, 0, 0, time.UTC), ServerID: 1, Check: "server_ping", OK: true},
+		{At: time.Date(2025, 6, 1, 23, 59, 0, 0, time.UTC), ServerID: 1, Check: "server_ping", OK: false},
+		{At: now, ServerID: 1, Check: "server_ping", OK: true},
+		{At: now, ServerID: 1, Check: "oba_api", OK: false},
+		{At: now, ServerID: 2, Check: "server_ping", OK: false},
+	}
+	for _, r := range results {
+		if err := store.Record(r); err != nil {
+			t.Fatalf("Record failed: %v", err)
+		}
+	}
+
+	counts, err := store.DailyCounts(context.Background(), 1, "server_ping", 3, now)
+	if err != nil {
+		t.Fatalf("DailyCounts failed: %v", err)
+	}
+	want := []DailyCount{{Runs: 2, Failures: 1}, {}, {Runs: 1}}
+	if len(counts) != len(want) {
+		t.Fatalf("expected %d days, got %+v", len(want), counts)
+	}
+	for i := range want {
+		if counts[i] != want[i] {
+			t.Errorf("day %d: expected %+v, got %+v", i, want[i], counts[i])
+		}
+	}
+}
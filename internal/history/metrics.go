@@ -0,0 +1,18 @@
+package history
+
+import (
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promauto"
+)
+
+var (
+	HistoryErrorsCounter = promauto.NewCounterVec(prometheus.CounterOpts{
+		Name: "watchdog_history_errors_total",
+		Help: "Total number of failed operations on the check history database, by operation (record, query, prune)",
+	}, []string{"operation"})
+
+	HistoryPrunedCounter = promauto.NewCounter(prometheus.CounterOpts{
+		Name: "watchdog_history_pruned_total",
+		Help: "Total number of check results deleted from the check history database after the retention period",
+	})
+)
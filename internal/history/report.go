@@ -0,0 +1,294 @@
+package history
+
+import (
+	"context"
+	"encoding/csv"
+	"errors"
+	"fmt"
+	"io"
+	"strconv"
+	"time"
+)
+
+// Names of the checks the reports are computed from, as recorded by the watchdog (see
+// metrics.CheckNames).
+const (
+	checkServerPing       = "server_ping"
+	checkRealtimeFeed     = "gtfs_rt_feed"
+	checkBundleExpiration = "bundle_expiration"
+)
+
+// Defaults of ReportOptions.
+const (
+	// DefaultFreshnessSLO is the largest age of the newest vehicle position of a GTFS-RT feed
+	// that meets the freshness SLO.
+	DefaultFreshnessSLO = 2 * time.Minute
+	// DefaultExpirationDays matches the default threshold of the bundle_expiration alert.
+	DefaultExpirationDays = 7
+)
+
+// ReportOptions selects the results a report is computed from and the objectives they are held
+// against.
+type ReportOptions struct {
+	// From, included, and To, excluded, bound the period of the report.
+	From time.Time
+	To   time.Time
+	// ServerIDs are the servers to report on; empty reports on every server with results in the
+	// period.
+	ServerIDs []int
+	// FreshnessSLO is the largest age of the newest vehicle position of a GTFS-RT feed that
+	// meets the freshness SLO (0 = DefaultFreshnessSLO).
+	FreshnessSLO time.Duration
+	// ExpirationDays is the number of days before the end of a bundle under which a run of
+	// bundle_expiration is an expiration incident (0 = DefaultExpirationDays).
+	ExpirationDays float64
+}
+
+// Report is the uptime and SLA report of servers over a period.
+type Report struct {
+	From                time.Time      `json:"from"`
+	To                  time.Time      `json:"to"`
+	FreshnessSLOSeconds float64        `json:"freshness_slo_seconds"`
+	ExpirationDays      float64        `json:"expiration_days"`
+	Servers             []ServerReport `json:"servers"`
+}
+
+// ServerReport is the report of a server. The percentages are nil when there was no run to
+// compute them from.
+type ServerReport struct {
+	ServerID int `json:"server_id"`
+	// ServerName is filled in by callers that know the configuration.
+	ServerName string `json:"server_name,omitempty"`
+	// Availability is the percentage of successful server_ping runs, out of PingRuns.
+	Availability *float64 `json:"availability_percent"`
+	PingRuns     int      `json:"ping_runs"`
+	// FreshnessCompliance is the percentage of gtfs_rt_feed runs whose feed was fetched and
+	// whose newest vehicle position was at most FreshnessSLO old, out of FreshnessRuns. Fetched
+	// feeds without vehicle timestamps, e.g. empty at night, are not counted.
+	FreshnessCompliance *float64 `json:"freshness_slo_percent"`
+	FreshnessRuns       int      `json:"freshness_runs"`
+	// ExpirationIncidents are the periods the bundle was less than ExpirationDays from its end.
+	ExpirationIncidents []ExpirationIncident `json:"bundle_expiration_incidents"`
+}
+
+// ExpirationIncident is a period during which the bundle of a server was about to expire: from
+// the first run of bundle_expiration under the threshold to the first run over it, or nil if the
+// incident was still open at the end of the report.
+type ExpirationIncident struct {
+	Start   time.Time  `json:"start"`
+	End     *time.Time `json:"end"`
+	MinDays float64    `json:"min_days"`
+}
+
+// Report computes the report selected by opts from the recorded results.
+func (s *Store) Report(ctx context.Context, opts ReportOptions) (Report, error) {
+	if s == nil {
+		return Report{}, errors.New("history is disabled")
+	}
+	if opts.FreshnessSLO <= 0 {
+		opts.FreshnessSLO = DefaultFreshnessSLO
+	}
+	if opts.ExpirationDays <= 0 {
+		opts.ExpirationDays = DefaultExpirationDays
+	}
+	report := Report{
+		From:                opts.From.UTC(),
+		To:                  opts.To.UTC(),
+		FreshnessSLOSeconds: opts.FreshnessSLO.Seconds(),
+		ExpirationDays:      opts.ExpirationDays,
+		Servers:             []ServerReport{},
+	}
+	from, to := opts.From.UnixMilli(), opts.To.UnixMilli()
+
+	serverIDs := opts.ServerIDs
+	if len(serverIDs) == 0 {
+		var err error
+		if serverIDs, err = s.reportedServers(ctx, from, to); err != nil {
+			return Report{}, err
+		}
+	}
+	for _, serverID := range serverIDs {
+		server := ServerReport{ServerID: serverID, ExpirationIncidents: []ExpirationIncident{}}
+
+		var passed int
+		err := s.db.QueryRowContext(ctx, `SELECT COUNT(*), COALESCE(SUM(ok), 0) FROM check_results WHERE server_id = ? AND check_name = ? AND at >= ? AND at < ?`,
+			serverID, checkServerPing, from, to).Scan(&server.PingRuns, &passed)
+		if err != nil {
+			return Report{}, reportError(err)
+		}
+		server.Availability = percentage(passed, server.PingRuns)
+
+		err = s.db.QueryRowContext(ctx, `SELECT COUNT(*), COALESCE(SUM(ok AND value <= ?), 0) FROM check_results WHERE server_id = ? AND check_name = ? AND at >= ? AND at < ? AND (ok = 0 OR value IS NOT NULL)`,
+			opts.FreshnessSLO.Seconds(), serverID, checkRealtimeFeed, from, to).Scan(&server.FreshnessRuns, &passed)
+		if err != nil {
+			return Report{}, reportError(err)
+		}
+		server.FreshnessCompliance = percentage(passed, server.FreshnessRuns)
+
+		if server.ExpirationIncidents, err = s.expirationIncidents(ctx, serverID, from, to, opts.ExpirationDays); err != nil {
+			return Report{}, err
+		}
+		report.Servers = append(report.Servers, server)
+	}
+	return report, nil
+}
+
+// reportedServers returns the IDs of the servers with results in the period, in order.
+func (s *Store) reportedServers(ctx context.Context, from, to int64) ([]int, error) {
+	rows, err := s.db.QueryContext(ctx, `SELECT DISTINCT server_id FROM check_results WHERE at >= ? AND at < ? ORDER BY server_id`, from, to)
+	if err != nil {
+		return nil, reportError(err)
+	}
+	defer rows.Close()
+	var serverIDs []int
+	for rows.Next() {
+		var serverID int
+		if err := rows.Scan(&serverID); err != nil {
+			return nil, reportError(err)
+		}
+		serverIDs = append(serverIDs, serverID)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, reportError(err)
+	}
+	return serverIDs, nil
+}
+
+// expirationIncidents returns the periods during which the successful runs of bundle_expiration
+// of a server measured fewer than days days until the end of the bundle.
+func (s *Store) expirationIncidents(ctx context.Context, serverID int, from, to int64, days float64) ([]ExpirationIncident, error) {
+	rows, err := s.db.QueryContext(ctx, `SELECT at, value FROM check_results WHERE server_id = ? AND check_name = ? AND at >= ? AND at < ? AND ok = 1 AND value IS NOT NULL ORDER BY at, rowid`,
+		serverID, checkBundleExpiration, from, to)
+	if err != nil {
+		return nil, reportError(err)
+	}
+	defer rows.Close()
+	incidents := []ExpirationIncident{}
+	var open *ExpirationIncident
+	for rows.Next() {
+		var (
+			at    int64
+			value float64
+		)
+		if err := rows.Scan(&at, &value); err != nil {
+			return nil, reportError(err)
+		}
+		when := time.UnixMilli(at).UTC()
+		switch {
+		case value < days && open == nil:
+			open = &ExpirationIncident{Start: when, MinDays: value}
+		case value < days:
+			open.MinDays = min(open.MinDays, value)
+		case open != nil:
+			open.End = &when
+			incidents = append(incidents, *open)
+			open = nil
+		}
+	}
+	if err := rows.Err(); err != nil {
+		return nil, reportError(err)
+	}
+	if open != nil {
+		incidents = append(incidents, *open)
+	}
+	return incidents, nil
+}
+
+// reportError counts and wraps an error of the queries of a report.
+func reportError(err error) error {
+	HistoryErrorsCounter.WithLabelValues("query").Inc()
+	return fmt.Errorf("failed to compute the report: %w", err)
+}
+
+// percentage returns part out of total as a percentage, or nil if total is 0.
+func percentage(part, total int) *float64 {
+	if total == 0 {
+		return nil
+	}
+	p := 100 * float64(part) / float64(total)
+	return &p
+}
+
+// reportCSVHeader is the header row of WriteReportCSV.
+var reportCSVHeader = []string{
+	"server_id",
+	"server_name",
+	"availability_percent",
+	"ping_runs",
+	"freshness_slo_percent",
+	"freshness_runs",
+	"bundle_expiration_incidents",
+}
+
+// WriteReportCSV writes a report as CSV, one row per server. Percentages without runs are left
+// empty.
+func WriteReportCSV(w io.Writer, report Report) error {
+	writer := csv.NewWriter(w)
+	if err := writer.Write(reportCSVHeader); err != nil {
+		return err
+	}
+	for _, server := range report.Servers {
+		err := writer.Write([]string{
+			strconv.Itoa(server.ServerID),
+			server.ServerName,
+			formatPercentage(server.Availability),
+			strconv.Itoa(server.PingRuns),
+			formatPercentage(server.FreshnessCompliance),
+			strconv.Itoa(server.FreshnessRuns),
+			strconv.Itoa(len(server.ExpirationIncidents)),
+		})
+		if err != nil {
+			return err
+		}
+	}
+	writer.Flush()
+	return writer.Error()
+}
+
+// formatPercentage formats a percentage of a report with 3 decimals, or as empty if it is nil.
+func formatPercentage(p *float64) string {
+	if p == nil {
+		return ""
+	}
+	return strconv.FormatFloat(*p, 'f', 3, 64)
+}
+
+// ParseReportRange parses the bounds of the period of a report, each an RFC 3339 time or a
+// date (YYYY-MM-DD, UTC). A date as the end includes the whole day. An empty from is 30 days
+// before the end, and an empty to is now.
+func ParseReportRange(from, to string, now time.Time) (time.Time, time.Time, error) {
+	end := now.UTC()
+	if to != "" {
+		var err error
+		if end, err = parseReportTime(to, true); err != nil {
+			return time.Time{}, time.Time{}, fmt.Errorf("invalid end %q: %w", to, err)
+		}
+	}
+	start := end.AddDate(0, 0, -30)
+	if from != "" {
+		var err error
+		if start, err = parseReportTime(from, false); err != nil {
+			return time.Time{}, time.Time{}, fmt.Errorf("invalid start %q: %w", from, err)
+		}
+	}
+	if !start.Before(end) {
+		return time.Time{}, time.Time{}, fmt.Errorf("the start %s is not before the end %s", start.Format(time.RFC3339), end.Format(time.RFC3339))
+	}
+	return start, end, nil
+}
+
+// parseReportTime parses an RFC 3339 time or a date; the end of a period is the day after the
+// date.
+func parseReportTime(value string, end bool) (time.Time, error) {
+	if t, err := time.Parse(time.RFC3339, value); err == nil {
+		return t.UTC(), nil
+	}
+	t, err := time.Parse(time.DateOnly, value)
+	if err != nil {
+		return time.Time{}, errors.New("expected an RFC 3339 time or a YYYY-MM-DD date")
+	}
+	if end {
+		t = t.AddDate(0, 0, 1)
+	}
+	return t, nil
+}
@@ -0,0 +1,98 @@
+package history
+
+import (
+	"bytes"
+	"context"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestReport(t *testing.T) {
+	store, err := Open(filepath.Join(t.TempDir(), "history.db"), 30*24*time.Hour)
+	if err != nil {
+		t.Fatalf("Open failed: %v", err)
+	}
+	defer store.Close()
+
+	start := time.Date(2025, 6, 1, 0, 0, 0, 0, time.UTC)
+	value := func(v float64) *float64 { return &v }
+	results := []Result{
+		// 3 of 4 pings succeeded.
+		{At: start.Add(time.Hour), ServerID: 1, Check: "server_ping", OK: true},
+		{At: start.Add(2 * time.Hour), ServerID: 1, Check: "server_ping", OK: false},
+		{At: start.Add(3 * time.Hour), ServerID: 1, Check: "server_ping", OK: true},
+		{At: start.Add(4 * time.Hour), ServerID: 1, Check: "server_ping", OK: true},
+		// Out of the period.
+		{At: start.AddDate(0, 0, -1), ServerID: 1, Check: "server_ping", OK: false},
+		// 1 of 3 counted feeds was fresh: one is too old, one failed, one has no timestamps.
+		{At: start.Add(time.Hour), ServerID: 1, Check: "gtfs_rt_feed", Value: value(30), OK: true},
+		{At: start.Add(2 * time.Hour), ServerID: 1, Check: "gtfs_rt_feed", Value: value(600), OK: true},
+		{At: start.Add(3 * time.Hour), ServerID: 1, Check: "gtfs_rt_feed", OK: false},
+		{At: start.Add(4 * time.Hour), ServerID: 1, Check: "gtfs_rt_feed", OK: true},
+		// One incident, closed by a new bundle, then another still open.
+		{At: start.Add(time.Hour), ServerID: 1, Check: "bundle_expiration", Value: value(8), OK: true},
+		{At: start.Add(2 * time.Hour), ServerID: 1, Check: "bundle_expiration", Value: value(6), OK: true},
+		{At: start.Add(3 * time.Hour), ServerID: 1, Check: "bundle_expiration", Value: value(5), OK: true},
+		{At: start.Add(4 * time.Hour), ServerID: 1, Check: "bundle_expiration", Value: value(90), OK: true},
+		{At: start.Add(5 * time.Hour), ServerID: 1, Check: "bundle_expiration", Value: value(2), OK: true},
+		{At: start.Add(time.Hour), ServerID: 2, Check: "vehicle_count", Value: value(0.9), OK: true},
+	}
+	for _, r := range results {
+		if err := store.Record(r); err != nil {
+			t.Fatalf("Record failed: %v", err)
+		}
+	}
+
+	report, err := store.Report(context.Background(), ReportOptions{From: start, To: start.AddDate(0, 0, 1)})
+	if err != nil {
+		t.Fatalf("Report failed: %v", err)
+	}
+	if len(report.Servers) != 2 || report.FreshnessSLOSeconds != 120 || report.ExpirationDays != 7 {
+		t.Fatalf("unexpected report %+v", report)
+	}
+	server := report.Servers[0]
+	if server.ServerID != 1 || server.PingRuns != 4 || server.Availability == nil || *server.Availability != 75 {
+		t.Errorf("expected 75%% availability over 4 pings, got %+v", server)
+	}
+	if server.FreshnessRuns != 3 || server.FreshnessCompliance == nil || *server.FreshnessCompliance != 100.0/3 {
+		t.Errorf("expected 1 of 3 fresh feeds, got %d runs and %v", server.FreshnessRuns, server.FreshnessCompliance)
+	}
+	incidents := server.ExpirationIncidents
+	if len(incidents) != 2 || !incidents[0].Start.Equal(start.Add(2*time.Hour)) || incidents[0].End == nil || !incidents[0].End.Equal(start.Add(4*time.Hour)) || incidents[0].MinDays != 5 {
+		t.Fatalf("unexpected incidents %+v", incidents)
+	}
+	if incidents[1].End != nil || incidents[1].MinDays != 2 {
+		t.Errorf("expected an open incident, got %+v", incidents[1])
+	}
+	if other := report.Servers[1]; other.Availability != nil || other.FreshnessCompliance != nil || len(other.ExpirationIncidents) != 0 {
+		t.Errorf("expected no figures for server 2, got %+v", other)
+	}
+
+	var buf bytes.Buffer
+	if err := WriteReportCSV(&buf, report); err != nil {
+		t.Fatalf("WriteReportCSV failed: %v", err)
+	}
+	lines := strings.Split(strings.TrimSpace(buf.String()), "\n")
+	if len(lines) != 3 || lines[1] != "1,,75.000,4,33.333,3,2" || lines[2] != "2,,,0,,0,0" {
+		t.Errorf("unexpected CSV:\n%s", buf.String())
+	}
+}
+
+func TestParseReportRange(t *testing.T) {
+	now := time.Date(2025, 6, 15, 12, 0, 0, 0, time.UTC)
+	from, to, err := ParseReportRange("2025-05-01", "2025-05-31", now)
+	if err != nil || !from.Equal(time.Date(2025, 5, 1, 0, 0, 0, 0, time.UTC)) || !to.Equal(time.Date(2025, 6, 1, 0, 0, 0, 0, time.UTC)) {
+		t.Errorf("expected May 2025, got %v to %v (%v)", from, to, err)
+	}
+	from, to, err = ParseReportRange("", "", now)
+	if err != nil || !to.Equal(now) || !from.Equal(now.AddDate(0, 0, -30)) {
+		t.Errorf("expected the last 30 days, got %v to %v (%v)", from, to, err)
+	}
+	for _, bounds := range [][2]string{{"yesterday", ""}, {"2025-06-02", "2025-06-01"}} {
+		if _, _, err := ParseReportRange(bounds[0], bounds[1], now); err == nil {
+			t.Errorf("expected %v to be rejected", bounds)
+		}
+	}
+}
@@ -0,0 +1,232 @@
+// Package httpclient provides the HTTP clients every outgoing request of the watchdog goes
+// through: one client per type of request, sharing a pooled and instrumented Transport.
+package httpclient
+
+import (
+	"crypto/tls"
+	"net"
+	"net/http"
+	"net/url"
+	"sync"
+	"time"
+
+	"watchdog.onebusaway.org/internal/models"
+)
+
+// Names of the clients, used as the `client` label of ClientRequestDuration.
+const (
+	ClientAPI      = "api"
+	ClientRealtime = "realtime"
+	ClientBundle   = "bundle"
+)
+
+// Timeouts are the timeouts of outgoing requests: of establishing their connections, and the
+// overall timeouts (connect, TLS, waiting for the headers and reading the body) of the requests
+// of each client. Servers can override them for the requests to their hosts (see ForServer).
+type Timeouts struct {
+	// Connect bounds establishing a TCP connection.
+	Connect time.Duration
+	// TLSHandshake bounds the TLS handshake of a connection.
+	TLSHandshake time.Duration
+	// API covers calls to the OBA REST API, remote configs and notifications.
+	API time.Duration
+	// Realtime covers GTFS-RT feed polls.
+	Realtime time.Duration
+	// Bundle covers GTFS static bundle downloads, which are much larger than other responses.
+	// Throttled downloads (see gtfs.BundleThrottle) are only bounded by the response headers
+	// timeout of the transport.
+	Bundle time.Duration
+}
+
+// DefaultTimeouts are the timeouts of the clients unless configured otherwise.
+var DefaultTimeouts = Timeouts{
+	Connect:      5 * time.Second,
+	TLSHandshake: 5 * time.Second,
+	API:          10 * time.Second,
+	Realtime:     10 * time.Second,
+	Bundle:       5 * time.Minute,
+}
+
+// withDefaults returns t with the timeouts that are not positive replaced by those of
+// defaults.
+func (t Timeouts) withDefaults(defaults Timeouts) Timeouts {
+	t.Connect = orDefault(t.Connect, defaults.Connect)
+	t.TLSHandshake = orDefault(t.TLSHandshake, defaults.TLSHandshake)
+	t.API = orDefault(t.API, defaults.API)
+	t.Realtime = orDefault(t.Realtime, defaults.Realtime)
+	t.Bundle = orDefault(t.Bundle, defaults.Bundle)
+	return t
+}
+
+// ForServer returns the timeouts of the requests to the hosts of server: those set in its
+// timeouts, and t for the others.
+func (t Timeouts) ForServer(server models.ObaServer) Timeouts {
+	if server.Timeouts == nil {
+		return t
+	}
+	return Timeouts{
+		Connect:      server.Timeouts.Connect.Std(),
+		TLSHandshake: server.Timeouts.TLSHandshake.Std(),
+		API:          server.Timeouts.API.Std(),
+		Realtime:     server.Timeouts.Realtime.Std(),
+		Bundle:       server.Timeouts.Bundle.Std(),
+	}.withDefaults(t)
+}
+
+// overall returns the overall timeout of the requests of a client.
+func (t Timeouts) overall(client string) time.Duration {
+	switch client {
+	case ClientRealtime:
+		return t.Realtime
+	case ClientBundle:
+		return t.Bundle
+	default:
+		return t.API
+	}
+}
+
+// Options configures the shared Transport of the clients.
+type Options struct {
+	Timeouts Timeouts
+	// TLSConfig is the TLS configuration of outgoing connections (nil = Go defaults), e.g.
+	// with the RootCAs of LoadCertPool.
+	TLSConfig *tls.Config
+	// Proxy is the proxy of outgoing requests (nil = the HTTP_PROXY, HTTPS_PROXY and NO_PROXY
+	// environment variables). Servers can use their own proxy, see Clients.SetServers.
+	Proxy *url.URL
+}
+
+// Clients are the HTTP clients of the watchdog. They share a Transport, so connections to a
+// host are pooled across types of requests, and differ in their timeouts. The overall timeouts
+// are applied by their transports rather than http.Client.Timeout, so that servers can set
+// their own (see Timeouts.ForServer).
+type Clients struct {
+	API      *http.Client
+	Realtime *http.Client
+	Bundle   *http.Client
+
+	timeouts Timeouts
+	// transport is the shared Transport, below the per-client metrics.
+	transport http.RoundTripper
+	// servers routes the requests to servers with their own proxy or CAs (see SetServers).
+	servers *serverTransport
+}
+
+// New creates the clients of the watchdog. The transport configuration is tuned for polling
+// APIs every 30 seconds:
+//
+//   - MaxIdleConns: 100
+//     Allows up to 100 idle (keep-alive) connections across all hosts.
+//     Suitable for multiple monitored servers; reduces connection churn.
+//
+//   - MaxIdleConnsPerHost: 10
+//     Allows each API host to maintain up to 10 idle connections.
+//     Helps when Watchdog queries many endpoints on the same host (e.g., GTFS feeds).
+//
+//   - IdleConnTimeout: 90s
+//     Idle connections are kept for 90 seconds before being closed.
+//     Since requests happen every 30 seconds, this ensures most connections stay alive.
+//     Reduces cost of re-establishing TCP/TLS handshakes.
+//
+//   - DialContext (Timeout: Timeouts.Connect, 5s by default, KeepAlive: 30s):
+//     Sets TCP connection timeout to 5s to fail fast if the server is unreachable.
+//     TCP keep-alives are enabled to detect dead peers if connection remains open.
+//
+//   - TLSHandshakeTimeout: Timeouts.TLSHandshake, 5s by default
+//     Caps the TLS handshake time. Prevents indefinite stalls during slow server negotiation.
+//     Lower than default (10s) to reduce latency during degraded network conditions.
+//
+//   - ResponseHeaderTimeout: 10s
+//     Caps the wait for the response headers, whatever the client. Large bundle downloads
+//     may take minutes, but their server must still start answering quickly.
+//
+//   - Proxy: Options.Proxy, or the environment. Servers with a proxy_url, ca_cert_files or
+//     connect or TLS timeouts have their own Transport with the same settings, see SetServers.
+//
+//   - Overall timeout: see Timeouts.
+//     A timeout covering the full lifecycle of each request (connect, TLS, read), and of each
+//     redirect. Ensures the system doesn't hang longer than necessary if the API is
+//     unresponsive.
+//
+// Instrumentation:
+//
+//   - latencyTrackingRoundTripper tracks the latency of outgoing HTTP requests by URL.
+//   - connectionHealthRoundTripper records per-host DNS resolution time, TLS handshake time,
+//     and connection errors.
+//   - clientMetricsRoundTripper records the duration and status code of requests by client.
+//   - targetMetricsRoundTripper records the duration and status code of requests by monitored
+//     server and target type (see WithTarget).
+func New(opts Options) *Clients {
+	proxy := http.ProxyFromEnvironment
+	if opts.Proxy != nil {
+		proxy = http.ProxyURL(opts.Proxy)
+	}
+	timeouts := opts.Timeouts.withDefaults(DefaultTimeouts)
+	transport := &http.Transport{
+		Proxy:               proxy,
+		MaxIdleConns:        100,
+		MaxIdleConnsPerHost: 10,
+		IdleConnTimeout:     90 * time.Second,
+		DialContext: (&net.Dialer{
+			Timeout:   timeouts.Connect,
+			KeepAlive: 30 * time.Second,
+		}).DialContext,
+		TLSHandshakeTimeout:   timeouts.TLSHandshake,
+		ResponseHeaderTimeout: 10 * time.Second,
+		TLSClientConfig:       opts.TLSConfig,
+		ForceAttemptHTTP2:     true,
+	}
+
+	servers := newServerTransport(transport)
+	c := &Clients{
+		API:       &http.Client{},
+		Realtime:  &http.Client{},
+		Bundle:    &http.Client{},
+		timeouts:  timeouts,
+		transport: &targetMetricsRoundTripper{next: &latencyTrackingRoundTripper{next: &connectionHealthRoundTripper{next: servers}}},
+		servers:   servers,
+	}
+	c.setTransports()
+	return c
+}
+
+// Timeouts returns the global timeouts of the clients, the defaults included.
+func (c *Clients) Timeouts() Timeouts {
+	return c.timeouts
+}
+
+// orDefault returns d, or fallback if d is not positive.
+func orDefault(d, fallback time.Duration) time.Duration {
+	if d <= 0 {
+		return fallback
+	}
+	return d
+}
+
+// setTransports points the clients at the shared transport.
+func (c *Clients) setTransports() {
+	for client, httpClient := range map[string]*http.Client{ClientAPI: c.API, ClientRealtime: c.Realtime, ClientBundle: c.Bundle} {
+		httpClient.Transport = &timeoutRoundTripper{client: client, clients: c, next: &clientMetricsRoundTripper{client: client, next: c.transport}}
+	}
+}
+
+// WrapTransport wraps the shared transport of the clients, e.g. to rate limit or trace their
+// requests. It must be called before the clients are used.
+func (c *Clients) WrapTransport(wrap func(http.RoundTripper) http.RoundTripper) {
+	c.transport = wrap(c.transport)
+	c.setTransports()
+}
+
+var (
+	defaultOnce    sync.Once
+	defaultClients *Clients
+)
+
+// Default returns clients with the default options, for code that is not given clients,
+// e.g. in tests. Requests made with them are instrumented like any other.
+func Default() *Clients {
+	defaultOnce.Do(func() {
+		defaultClients = New(Options{})
+	})
+	return defaultClients
+}
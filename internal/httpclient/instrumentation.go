@@ -0,0 +1,186 @@
+package httpclient
+
+import (
+	"crypto/tls"
+	"net/http"
+	"net/http/httptrace"
+	"strconv"
+	"sync"
+	"time"
+)
+
+// latencyTrackingRoundTripper is a custom HTTP RoundTripper that wraps another RoundTripper
+// to measure and record the latency (duration) of each outgoing HTTP request.
+//
+// Purpose:
+// - Collect Prometheus metrics on request latency (in seconds)
+// - Label the metrics by URL, HTTP method, and response status
+// - Help monitor external API performance in systems like Watchdog
+//
+// Why use this:
+// Prometheus doesn’t automatically track request latency. Wrapping the transport lets us
+// measure latency without changing the logic of every API call.
+type latencyTrackingRoundTripper struct {
+	// next is the underlying RoundTripper that actually performs the request.
+	// This allows us to insert instrumentation without changing request behavior.
+	next http.RoundTripper
+}
+
+// RoundTrip implements the http.RoundTripper interface.
+// It records the time before and after delegating to the next RoundTripper,
+// then exports the observed duration to Prometheus under OutgoingLatency.
+func (rt *latencyTrackingRoundTripper) RoundTrip(req *http.Request) (*http.Response, error) {
+	start := time.Now()
+	resp, err := rt.next.RoundTrip(req)
+	duration := time.Since(start).Seconds()
+
+	// Default to "error" if the request failed or response is nil
+	status := "error"
+	if err == nil && resp != nil {
+		status = strconv.Itoa(resp.StatusCode)
+	}
+
+	// Construct a safe, normalized URL label (scheme + host + path) without query params
+	safeURL := req.URL.Scheme + "://" + req.URL.Host + req.URL.Path
+
+	// Record latency with Prometheus, labeled by URL, HTTP method, and status
+	OutgoingLatency.WithLabelValues(
+		safeURL,
+		req.Method,
+		status,
+	).Observe(duration)
+
+	return resp, err
+}
+
+// connectionHealthRoundTripper is an HTTP RoundTripper that records network-level health
+// of outgoing requests per remote host, independent of which check issued the request.
+//
+// Using net/http/httptrace it records:
+//   - DNS resolution time (OutgoingDNSDuration)
+//   - TLS handshake time (OutgoingTLSHandshakeDuration)
+//   - Failed requests, labeled by the stage that failed: dns, connect, tls,
+//     or request for failures after the connection was established (OutgoingConnectionErrors)
+//
+// Reused keep-alive connections skip DNS and TLS, so those histograms only count new connections.
+type connectionHealthRoundTripper struct {
+	next http.RoundTripper
+}
+
+// connectionTrace collects the outcome of the connection stages of a single request.
+// httptrace callbacks may run concurrently (e.g. dialing IPv4 and IPv6 in parallel),
+// so access is guarded by a mutex.
+type connectionTrace struct {
+	mu          sync.Mutex
+	dnsStart    time.Time
+	tlsStart    time.Time
+	failedStage string
+}
+
+func (ct *connectionTrace) fail(stage string) {
+	ct.mu.Lock()
+	ct.failedStage = stage
+	ct.mu.Unlock()
+}
+
+// RoundTrip implements the http.RoundTripper interface.
+func (rt *connectionHealthRoundTripper) RoundTrip(req *http.Request) (*http.Response, error) {
+	host := req.URL.Host
+	ct := &connectionTrace{}
+
+	trace := &httptrace.ClientTrace{
+		DNSStart: func(httptrace.DNSStartInfo) {
+			ct.mu.Lock()
+			ct.dnsStart = time.Now()
+			ct.mu.Unlock()
+		},
+		DNSDone: func(info httptrace.DNSDoneInfo) {
+			ct.mu.Lock()
+			start := ct.dnsStart
+			ct.mu.Unlock()
+			if !start.IsZero() {
+				OutgoingDNSDuration.WithLabelValues(host).Observe(time.Since(start).Seconds())
+			}
+			if info.Err != nil {
+				ct.fail("dns")
+			}
+		},
+		ConnectDone: func(_, _ string, err error) {
+			if err != nil {
+				ct.fail("connect")
+			}
+		},
+		TLSHandshakeStart: func() {
+			ct.mu.Lock()
+			ct.tlsStart = time.Now()
+			ct.mu.Unlock()
+		},
+		TLSHandshakeDone: func(_ tls.ConnectionState, err error) {
+			ct.mu.Lock()
+			start := ct.tlsStart
+			ct.mu.Unlock()
+			if !start.IsZero() {
+				OutgoingTLSHandshakeDuration.WithLabelValues(host).Observe(time.Since(start).Seconds())
+			}
+			if err != nil {
+				ct.fail("tls")
+			}
+		},
+	}
+
+	req = req.WithContext(httptrace.WithClientTrace(req.Context(), trace))
+	resp, err := rt.next.RoundTrip(req)
+	if err != nil {
+		ct.mu.Lock()
+		stage := ct.failedStage
+		ct.mu.Unlock()
+		if stage == "" {
+			stage = "request"
+		}
+		OutgoingConnectionErrors.WithLabelValues(host, stage).Inc()
+	}
+	return resp, err
+}
+
+// clientMetricsRoundTripper records the duration and status code of the requests of one of
+// the Clients in ClientRequestDuration, labeled by the name of the client.
+type clientMetricsRoundTripper struct {
+	client string
+	next   http.RoundTripper
+}
+
+// RoundTrip implements the http.RoundTripper interface.
+func (rt *clientMetricsRoundTripper) RoundTrip(req *http.Request) (*http.Response, error) {
+	start := time.Now()
+	resp, err := rt.next.RoundTrip(req)
+	status := "error"
+	if err == nil && resp != nil {
+		status = strconv.Itoa(resp.StatusCode)
+	}
+	ClientRequestDuration.WithLabelValues(rt.client, status).Observe(time.Since(start).Seconds())
+	return resp, err
+}
+
+// targetMetricsRoundTripper records the duration and status code of the requests made to the
+// endpoints of monitored servers in OutboundRequestDuration, labeled by the target set on
+// their context with WithTarget, to correlate upstream latency with failed checks. As with
+// the other metrics of the transport, the duration ends when the response headers are read.
+type targetMetricsRoundTripper struct {
+	next http.RoundTripper
+}
+
+// RoundTrip implements the http.RoundTripper interface.
+func (rt *targetMetricsRoundTripper) RoundTrip(req *http.Request) (*http.Response, error) {
+	target, ok := targetFromContext(req.Context())
+	if !ok {
+		return rt.next.RoundTrip(req)
+	}
+	start := time.Now()
+	resp, err := rt.next.RoundTrip(req)
+	status := "error"
+	if err == nil && resp != nil {
+		status = strconv.Itoa(resp.StatusCode)
+	}
+	OutboundRequestDuration.WithLabelValues(strconv.Itoa(target.serverID), target.targetType, status).Observe(time.Since(start).Seconds())
+	return resp, err
+}
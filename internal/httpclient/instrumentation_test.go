@@ -0,0 +1,139 @@
+package httpclient
+
+import (
+	"context"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	dto "github.com/prometheus/client_model/go"
+)
+
+// collectMetric writes the single metric produced by a collector into a dto.Metric.
+func collectMetric(t *testing.T, collector prometheus.Collector) *dto.Metric {
+	t.Helper()
+	c := make(chan prometheus.Metric, 1)
+	collector.Collect(c)
+	pb := &dto.Metric{}
+	if err := (<-c).Write(pb); err != nil {
+		t.Fatalf("failed to read metric: %v", err)
+	}
+	return pb
+}
+
+func TestConnectionHealthRoundTripperRecordsTLSHandshake(t *testing.T) {
+	server := httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusOK)
+	}))
+	defer server.Close()
+
+	client := server.Client()
+	client.Transport = &connectionHealthRoundTripper{next: client.Transport}
+
+	resp, err := client.Get(server.URL)
+	if err != nil {
+		t.Fatalf("request failed: %v", err)
+	}
+	resp.Body.Close()
+
+	host := server.Listener.Addr().String()
+	histogram := OutgoingTLSHandshakeDuration.WithLabelValues(host).(prometheus.Histogram)
+	if got := collectMetric(t, histogram).GetHistogram().GetSampleCount(); got != 1 {
+		t.Errorf("expected 1 TLS handshake observation for %s, got %d", host, got)
+	}
+}
+
+func TestConnectionHealthRoundTripperRecordsConnectError(t *testing.T) {
+	// Reserve a local port and close it so connecting to it is refused.
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	host := listener.Addr().String()
+	listener.Close()
+
+	client := &http.Client{Transport: &connectionHealthRoundTripper{next: &http.Transport{}}}
+	if _, err := client.Get("http://" + host); err == nil {
+		t.Fatal("expected request to a closed port to fail")
+	}
+
+	counter := OutgoingConnectionErrors.WithLabelValues(host, "connect")
+	if got := collectMetric(t, counter).GetCounter().GetValue(); got != 1 {
+		t.Errorf("expected 1 connect error for %s, got %v", host, got)
+	}
+}
+
+func TestClientsRecordRequestsByClient(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusTeapot)
+	}))
+	defer server.Close()
+
+	clients := New(Options{Timeouts: Timeouts{Realtime: time.Second}})
+	if timeouts := clients.Timeouts(); timeouts.Realtime != time.Second || timeouts.API != DefaultTimeouts.API || timeouts.Bundle != DefaultTimeouts.Bundle || timeouts.Connect != DefaultTimeouts.Connect {
+		t.Errorf("unexpected timeouts: %+v", timeouts)
+	}
+
+	var wrapped int
+	clients.WrapTransport(func(next http.RoundTripper) http.RoundTripper {
+		return roundTripperFunc(func(req *http.Request) (*http.Response, error) {
+			wrapped++
+			return next.RoundTrip(req)
+		})
+	})
+
+	histogram := ClientRequestDuration.WithLabelValues(ClientRealtime, "418").(prometheus.Histogram)
+	before := collectMetric(t, histogram).GetHistogram().GetSampleCount()
+	resp, err := clients.Realtime.Get(server.URL)
+	if err != nil {
+		t.Fatalf("request failed: %v", err)
+	}
+	resp.Body.Close()
+
+	if got := collectMetric(t, histogram).GetHistogram().GetSampleCount(); got != before+1 {
+		t.Errorf("expected 1 more realtime request with status 418, got %d", got-before)
+	}
+	if wrapped != 1 {
+		t.Errorf("expected the request to go through the wrapped transport, got %d", wrapped)
+	}
+}
+
+func TestTargetMetricsRoundTripperRecordsTaggedRequests(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusNotFound)
+	}))
+	defer server.Close()
+
+	client := &http.Client{Transport: &targetMetricsRoundTripper{next: http.DefaultTransport}}
+	histogram := OutboundRequestDuration.WithLabelValues("9101", TargetGTFSRT, "404").(prometheus.Histogram)
+
+	// Requests without a target are not recorded.
+	resp, err := client.Get(server.URL)
+	if err != nil {
+		t.Fatalf("request failed: %v", err)
+	}
+	resp.Body.Close()
+	if got := collectMetric(t, histogram).GetHistogram().GetSampleCount(); got != 0 {
+		t.Errorf("expected no observation for a request without a target, got %d", got)
+	}
+
+	req, err := http.NewRequestWithContext(WithTarget(context.Background(), 9101, TargetGTFSRT), http.MethodGet, server.URL, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	resp, err = client.Do(req)
+	if err != nil {
+		t.Fatalf("request failed: %v", err)
+	}
+	resp.Body.Close()
+	if got := collectMetric(t, histogram).GetHistogram().GetSampleCount(); got != 1 {
+		t.Errorf("expected 1 gtfs_rt observation for server 9101 with status 404, got %d", got)
+	}
+}
+
+type roundTripperFunc func(*http.Request) (*http.Response, error)
+
+func (f roundTripperFunc) RoundTrip(req *http.Request) (*http.Response, error) { return f(req) }
@@ -0,0 +1,72 @@
+package httpclient
+
+import (
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promauto"
+)
+
+var (
+	OutgoingLatency = promauto.NewHistogramVec(
+		prometheus.HistogramOpts{
+			Name:    "http_outgoing_request_duration_seconds",
+			Help:    "Duration of outgoing HTTP requests to external APIs (in seconds)",
+			Buckets: prometheus.DefBuckets,
+		},
+		[]string{"url", "method", "status_code"},
+	)
+
+	ClientRequestDuration = promauto.NewHistogramVec(
+		prometheus.HistogramOpts{
+			Name:    "http_outgoing_client_request_duration_seconds",
+			Help:    "Duration of outgoing HTTP requests by client (api, realtime, bundle) and response status code (in seconds)",
+			Buckets: []float64{.05, .1, .25, .5, 1, 2.5, 5, 10, 30, 60, 120, 300},
+		},
+		[]string{"client", "status_code"},
+	)
+
+	OutboundRequestDuration = promauto.NewHistogramVec(
+		prometheus.HistogramOpts{
+			Name:    "watchdog_outbound_request_duration_seconds",
+			Help:    "Duration of outgoing HTTP requests to the endpoints of monitored servers, by server, target type (gtfs_static, gtfs_rt, oba_api) and response status code (in seconds)",
+			Buckets: []float64{.05, .1, .25, .5, 1, 2.5, 5, 10, 30, 60},
+		},
+		[]string{"server_id", "target_type", "status_code"},
+	)
+)
+
+var (
+	OutgoingDNSDuration = promauto.NewHistogramVec(
+		prometheus.HistogramOpts{
+			Name:    "http_outgoing_dns_duration_seconds",
+			Help:    "Duration of DNS resolution for outgoing HTTP requests, by remote host (in seconds)",
+			Buckets: []float64{.001, .005, .01, .025, .05, .1, .25, .5, 1, 2.5, 5},
+		},
+		[]string{"host"},
+	)
+
+	OutgoingTLSHandshakeDuration = promauto.NewHistogramVec(
+		prometheus.HistogramOpts{
+			Name:    "http_outgoing_tls_handshake_duration_seconds",
+			Help:    "Duration of TLS handshakes for outgoing HTTP requests, by remote host (in seconds)",
+			Buckets: []float64{.005, .01, .025, .05, .1, .25, .5, 1, 2.5, 5},
+		},
+		[]string{"host"},
+	)
+
+	OutgoingConnectionErrors = promauto.NewCounterVec(
+		prometheus.CounterOpts{
+			Name: "http_outgoing_connection_errors_total",
+			Help: "Total number of failed outgoing HTTP requests, by remote host and the stage that failed (dns, connect, tls, request)",
+		},
+		[]string{"host", "stage"},
+	)
+
+	OutgoingRateLimitWait = promauto.NewHistogramVec(
+		prometheus.HistogramOpts{
+			Name:    "http_outgoing_rate_limit_wait_seconds",
+			Help:    "Time outgoing HTTP requests waited for the outbound rate limit, by remote host (in seconds)",
+			Buckets: []float64{.01, .05, .1, .25, .5, 1, 2.5, 5, 10},
+		},
+		[]string{"host"},
+	)
+)
@@ -0,0 +1,189 @@
+package httpclient
+
+import (
+	"crypto/tls"
+	"crypto/x509"
+	"fmt"
+	"net"
+	"net/http"
+	"net/url"
+	"os"
+	"strings"
+	"sync"
+	"time"
+
+	"watchdog.onebusaway.org/internal/models"
+)
+
+// LoadCertPool returns the system CA certificates together with the certificates of the PEM
+// bundles at paths, e.g. the CA of a corporate proxy intercepting TLS.
+func LoadCertPool(paths []string) (*x509.CertPool, error) {
+	pool, err := x509.SystemCertPool()
+	if err != nil {
+		pool = x509.NewCertPool()
+	}
+	if err := appendCerts(pool, paths); err != nil {
+		return nil, err
+	}
+	return pool, nil
+}
+
+// appendCerts adds the certificates of the PEM bundles at paths to pool.
+func appendCerts(pool *x509.CertPool, paths []string) error {
+	for _, path := range paths {
+		// #nosec G304 -- the paths are given by the operator in the flags or the config.
+		data, err := os.ReadFile(path)
+		if err != nil {
+			return fmt.Errorf("failed to read CA certificates: %w", err)
+		}
+		if !pool.AppendCertsFromPEM(data) {
+			return fmt.Errorf("no PEM certificate found in %s", path)
+		}
+	}
+	return nil
+}
+
+// ParseProxyURL parses the URL of an HTTP(S) or SOCKS5 proxy, e.g. "http://proxy.internal:3128".
+func ParseProxyURL(raw string) (*url.URL, error) {
+	u, err := url.Parse(raw)
+	if err != nil {
+		return nil, fmt.Errorf("invalid proxy URL: %w", err)
+	}
+	switch u.Scheme {
+	case "http", "https", "socks5":
+	default:
+		return nil, fmt.Errorf("invalid proxy URL %q: the scheme must be http, https or socks5", u.Redacted())
+	}
+	if u.Host == "" {
+		return nil, fmt.Errorf("invalid proxy URL %q: missing host", u.Redacted())
+	}
+	return u, nil
+}
+
+// serverTransport is the bottom of the shared transport of the Clients. Requests to the hosts
+// of servers with a proxy_url, ca_cert_files or connect or TLS timeouts of their own go through
+// a Transport configured with them; the other requests go through base. Transports are created
+// on first use and shared by the servers with the same settings.
+type serverTransport struct {
+	base *http.Transport
+
+	mu         sync.Mutex
+	servers    func() []models.ObaServer
+	transports map[string]*http.Transport
+}
+
+func newServerTransport(base *http.Transport) *serverTransport {
+	return &serverTransport{base: base, transports: make(map[string]*http.Transport)}
+}
+
+// RoundTrip implements the http.RoundTripper interface.
+func (st *serverTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	server, ok := st.server(req.URL.Host, hasTransportSettings)
+	if !ok {
+		return st.base.RoundTrip(req)
+	}
+	transport, err := st.transport(server)
+	if err != nil {
+		return nil, fmt.Errorf("server %d: %w", server.ID, err)
+	}
+	return transport.RoundTrip(req)
+}
+
+// server returns the first of the servers set with SetServers for which match returns true
+// and whose OBA API, GTFS bundle, GTFS-RT feeds or data sources are on host.
+func (st *serverTransport) server(host string, match func(models.ObaServer) bool) (models.ObaServer, bool) {
+	st.mu.Lock()
+	servers := st.servers
+	st.mu.Unlock()
+	if servers == nil {
+		return models.ObaServer{}, false
+	}
+	return serverOnHost(host, servers(), match)
+}
+
+// serverOnHost returns the first server for which match returns true and whose OBA API, GTFS
+// bundle, GTFS-RT feeds or data sources are on host.
+func serverOnHost(host string, servers []models.ObaServer, match func(models.ObaServer) bool) (models.ObaServer, bool) {
+	for _, server := range servers {
+		if !match(server) {
+			continue
+		}
+		for _, raw := range append([]string{server.ObaBaseURL, server.GtfsUrl, server.TripUpdateUrl, server.VehiclePositionUrl, server.DataSourcesURL}, server.GtfsUrls...) {
+			if u, err := url.Parse(raw); err == nil && raw != "" && u.Host == host {
+				return server, true
+			}
+		}
+	}
+	return models.ObaServer{}, false
+}
+
+// hasTransportSettings reports whether the requests to a server need a Transport of their own.
+func hasTransportSettings(server models.ObaServer) bool {
+	return server.ProxyURL != "" || len(server.CACertFiles) > 0 ||
+		(server.Timeouts != nil && (server.Timeouts.Connect > 0 || server.Timeouts.TLSHandshake > 0))
+}
+
+// hasTimeouts reports whether a server overrides any of the global timeouts.
+func hasTimeouts(server models.ObaServer) bool {
+	return server.Timeouts != nil && *server.Timeouts != models.HTTPTimeouts{}
+}
+
+// transport returns the Transport of the requests to server: using its proxy (empty = the
+// proxy of base), trusting the CA certificates of base plus those of its ca_cert_files, and
+// with its connect and TLS timeouts (0 = those of base).
+func (st *serverTransport) transport(server models.ObaServer) (*http.Transport, error) {
+	proxy, caFiles := server.ProxyURL, server.CACertFiles
+	var connect, tlsHandshake time.Duration
+	if server.Timeouts != nil {
+		connect, tlsHandshake = server.Timeouts.Connect.Std(), server.Timeouts.TLSHandshake.Std()
+	}
+	key := fmt.Sprintf("%s\x00%s\x00%s\x00%s", proxy, strings.Join(caFiles, "\x00"), connect, tlsHandshake)
+	st.mu.Lock()
+	defer st.mu.Unlock()
+	if transport, ok := st.transports[key]; ok {
+		return transport, nil
+	}
+
+	transport := st.base.Clone()
+	if proxy != "" {
+		u, err := ParseProxyURL(proxy)
+		if err != nil {
+			return nil, err
+		}
+		transport.Proxy = http.ProxyURL(u)
+	}
+	if connect > 0 {
+		transport.DialContext = (&net.Dialer{Timeout: connect, KeepAlive: 30 * time.Second}).DialContext
+	}
+	if tlsHandshake > 0 {
+		transport.TLSHandshakeTimeout = tlsHandshake
+	}
+	if len(caFiles) > 0 {
+		if transport.TLSClientConfig == nil {
+			transport.TLSClientConfig = &tls.Config{MinVersion: tls.VersionTLS12}
+		}
+		var pool *x509.CertPool
+		if roots := transport.TLSClientConfig.RootCAs; roots != nil {
+			pool = roots.Clone()
+		} else if system, err := x509.SystemCertPool(); err == nil {
+			pool = system
+		} else {
+			pool = x509.NewCertPool()
+		}
+		if err := appendCerts(pool, caFiles); err != nil {
+			return nil, err
+		}
+		transport.TLSClientConfig.RootCAs = pool
+	}
+	st.transports[key] = transport
+	return transport, nil
+}
+
+// SetServers applies the proxy_url, ca_cert_files and timeouts of the servers returned by
+// servers to the requests sent to their hosts. servers is called for every request, so that configuration
+// changes apply right away.
+func (c *Clients) SetServers(servers func() []models.ObaServer) {
+	c.servers.mu.Lock()
+	defer c.servers.mu.Unlock()
+	c.servers.servers = servers
+}
@@ -0,0 +1,174 @@
+package httpclient
+
+import (
+	"context"
+	"math"
+	"net/http"
+	"net/url"
+	"sync"
+	"time"
+
+	"watchdog.onebusaway.org/internal/models"
+)
+
+// requestLimiter is a token bucket allowing rate requests per second, with bursts of up to
+// rate requests (at least one).
+type requestLimiter struct {
+	mu     sync.Mutex
+	rate   float64
+	burst  float64
+	tokens float64
+	last   time.Time
+}
+
+func newRequestLimiter(rate float64) *requestLimiter {
+	burst := math.Max(1, math.Floor(rate))
+	return &requestLimiter{rate: rate, burst: burst, tokens: burst, last: time.Now()}
+}
+
+// wait blocks until a request is allowed, or the context is canceled.
+func (l *requestLimiter) wait(ctx context.Context) error {
+	for {
+		l.mu.Lock()
+		now := time.Now()
+		l.tokens = math.Min(l.burst, l.tokens+now.Sub(l.last).Seconds()*l.rate)
+		l.last = now
+
+		if l.tokens >= 1 {
+			l.tokens--
+			l.mu.Unlock()
+			return nil
+		}
+		delay := time.Duration((1 - l.tokens) / l.rate * float64(time.Second))
+		l.mu.Unlock()
+
+		timer := time.NewTimer(delay)
+		select {
+		case <-ctx.Done():
+			timer.Stop()
+			return ctx.Err()
+		case <-timer.C:
+		}
+	}
+}
+
+// RateLimiter caps the outgoing requests of the watchdog so that it cannot overload the OBA
+// APIs and GTFS-RT feeds of small agencies when many checks run concurrently:
+//   - a per-host limit, the default for every host, overridden by the rate_limit of the
+//     servers whose OBA API or GTFS-RT feeds are on the host (the lowest one if several
+//     servers share a host);
+//   - a global limit shared by all requests.
+//
+// Limits are in requests per second; zero or negative values disable them. Requests wait for
+// their turn, or fail when their context is canceled.
+type RateLimiter struct {
+	global  *requestLimiter
+	perHost float64
+
+	mu      sync.Mutex
+	servers func() []models.ObaServer
+	hosts   map[string]*requestLimiter
+}
+
+// NewRateLimiter creates a RateLimiter from a per-host and a global limit, in requests per
+// second. Per-server limits are taken into account once SetServers is called.
+func NewRateLimiter(perHost, global float64) *RateLimiter {
+	l := &RateLimiter{perHost: perHost, hosts: make(map[string]*requestLimiter)}
+	if global > 0 {
+		l.global = newRequestLimiter(global)
+	}
+	return l
+}
+
+// SetServers sets the function returning the configured servers, whose rate_limit overrides
+// the per-host limit. It is called on every request, so configuration reloads apply at once.
+func (l *RateLimiter) SetServers(servers func() []models.ObaServer) {
+	l.mu.Lock()
+	l.servers = servers
+	l.mu.Unlock()
+}
+
+// hostRate returns the limit of a host: the lowest rate_limit of the servers on the host,
+// or the per-host limit.
+func hostRate(host string, servers []models.ObaServer, perHost float64) float64 {
+	rate := 0.0
+	for _, server := range servers {
+		if server.RateLimit <= 0 {
+			continue
+		}
+		for _, raw := range []string{server.ObaBaseURL, server.TripUpdateUrl, server.VehiclePositionUrl} {
+			if u, err := url.Parse(raw); err == nil && raw != "" && u.Host == host {
+				if rate == 0 || server.RateLimit < rate {
+					rate = server.RateLimit
+				}
+				break
+			}
+		}
+	}
+	if rate == 0 {
+		return perHost
+	}
+	return rate
+}
+
+// hostLimiter returns the limiter of a host, nil if the host is not limited. The limiter is
+// replaced when the limit of the host changes.
+func (l *RateLimiter) hostLimiter(host string) *requestLimiter {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	var servers []models.ObaServer
+	if l.servers != nil {
+		servers = l.servers()
+	}
+	rate := hostRate(host, servers, l.perHost)
+	if rate <= 0 {
+		delete(l.hosts, host)
+		return nil
+	}
+	limiter := l.hosts[host]
+	if limiter == nil || limiter.rate != rate {
+		limiter = newRequestLimiter(rate)
+		l.hosts[host] = limiter
+	}
+	return limiter
+}
+
+// wait blocks until a request to host is allowed by the per-host and global limits.
+func (l *RateLimiter) wait(ctx context.Context, host string) error {
+	start := time.Now()
+	for _, limiter := range []*requestLimiter{l.hostLimiter(host), l.global} {
+		if limiter == nil {
+			continue
+		}
+		if err := limiter.wait(ctx); err != nil {
+			return err
+		}
+	}
+	// Only throttled requests are observed, so that the histogram shows actual waits.
+	if waited := time.Since(start); waited >= time.Millisecond {
+		OutgoingRateLimitWait.WithLabelValues(host).Observe(waited.Seconds())
+	}
+	return nil
+}
+
+// Transport returns next wrapped so that its requests are rate limited.
+func (l *RateLimiter) Transport(next http.RoundTripper) http.RoundTripper {
+	return &rateLimitingRoundTripper{next: next, limiter: l}
+}
+
+// rateLimitingRoundTripper is an HTTP RoundTripper that waits for the RateLimiter before
+// sending each request. It wraps the instrumented transports, so that the latency metrics
+// do not include the time spent waiting.
+type rateLimitingRoundTripper struct {
+	next    http.RoundTripper
+	limiter *RateLimiter
+}
+
+// RoundTrip implements the http.RoundTripper interface.
+func (rt *rateLimitingRoundTripper) RoundTrip(req *http.Request) (*http.Response, error) {
+	if err := rt.limiter.wait(req.Context(), req.URL.Host); err != nil {
+		return nil, err
+	}
+	return rt.next.RoundTrip(req)
+}
@@ -0,0 +1,73 @@
+package httpclient
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"testing"
+	"time"
+
+	"watchdog.onebusaway.org/internal/models"
+)
+
+func TestHostRate(t *testing.T) {
+	servers := []models.ObaServer{
+		{ObaBaseURL: "https://oba.small-agency.org", RateLimit: 2},
+		{VehiclePositionUrl: "https://feeds.example.com/vp.pb", RateLimit: 5},
+		{TripUpdateUrl: "https://feeds.example.com/tu.pb", RateLimit: 1},
+		{ObaBaseURL: "https://oba.large-agency.org"},
+	}
+	for host, want := range map[string]float64{
+		"oba.small-agency.org": 2,
+		"feeds.example.com":    1,
+		"oba.large-agency.org": 10,
+	} {
+		if got := hostRate(host, servers, 10); got != want {
+			t.Errorf("%s: expected a limit of %v, got %v", host, want, got)
+		}
+	}
+}
+
+func TestRateLimiterThrottlesPerHost(t *testing.T) {
+	var requests int
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requests++
+	}))
+	defer server.Close()
+
+	limiter := NewRateLimiter(0, 0)
+	limiter.SetServers(func() []models.ObaServer {
+		return []models.ObaServer{{ObaBaseURL: server.URL, RateLimit: 10}}
+	})
+	client := &http.Client{Transport: limiter.Transport(http.DefaultTransport)}
+
+	// A burst of 10 requests is allowed, the next ones are spaced by 100ms.
+	start := time.Now()
+	for range 12 {
+		resp, err := client.Get(server.URL)
+		if err != nil {
+			t.Fatal(err)
+		}
+		resp.Body.Close()
+	}
+	if elapsed := time.Since(start); elapsed < 150*time.Millisecond {
+		t.Errorf("expected requests beyond the burst to be throttled, took %v", elapsed)
+	}
+	if requests != 12 {
+		t.Errorf("expected 12 requests, got %d", requests)
+	}
+}
+
+func TestRateLimiterCancel(t *testing.T) {
+	limiter := NewRateLimiter(0, 0.1)
+	u, _ := url.Parse("http://oba.example.com")
+	if err := limiter.wait(context.Background(), u.Host); err != nil {
+		t.Fatal(err)
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+	defer cancel()
+	if err := limiter.wait(ctx, u.Host); err == nil {
+		t.Error("expected a request over the global limit to fail when its context is canceled")
+	}
+}
@@ -0,0 +1,32 @@
+package httpclient
+
+import "context"
+
+// Types of targets of outgoing requests, used as the `target_type` label of
+// OutboundRequestDuration.
+const (
+	TargetGTFSStatic = "gtfs_static"
+	TargetGTFSRT     = "gtfs_rt"
+	TargetOBAAPI     = "oba_api"
+)
+
+// target is what an outgoing request is made for: a type of endpoint of a monitored server.
+type target struct {
+	serverID   int
+	targetType string
+}
+
+type targetKey struct{}
+
+// WithTarget returns a context for the requests made to an endpoint of a monitored server, so
+// that their duration is recorded in OutboundRequestDuration. Requests with a context without
+// a target (remote configs, notifications, ...) are not recorded there.
+func WithTarget(ctx context.Context, serverID int, targetType string) context.Context {
+	return context.WithValue(ctx, targetKey{}, target{serverID: serverID, targetType: targetType})
+}
+
+// targetFromContext returns the target set by WithTarget, if any.
+func targetFromContext(ctx context.Context) (target, bool) {
+	t, ok := ctx.Value(targetKey{}).(target)
+	return t, ok
+}
@@ -0,0 +1,59 @@
+package httpclient
+
+import (
+	"context"
+	"io"
+	"net/http"
+)
+
+type noTimeoutKey struct{}
+
+// WithoutTimeout returns a context for requests that are not bounded by the overall timeout of
+// their client, e.g. throttled bundle downloads, which legitimately take longer. They are still
+// bounded by the connect, TLS and response headers timeouts of the transport.
+func WithoutTimeout(ctx context.Context) context.Context {
+	return context.WithValue(ctx, noTimeoutKey{}, true)
+}
+
+// timeoutRoundTripper bounds each request of a client, until its body is closed, with the
+// overall timeout of the client, or that of the server the request is sent to (see
+// Timeouts.ForServer). It stands in for http.Client.Timeout, which is the same for every
+// request.
+type timeoutRoundTripper struct {
+	client  string
+	clients *Clients
+	next    http.RoundTripper
+}
+
+// RoundTrip implements the http.RoundTripper interface.
+func (t *timeoutRoundTripper) RoundTrip(req *http.Request) (*http.Response, error) {
+	if unbounded, _ := req.Context().Value(noTimeoutKey{}).(bool); unbounded {
+		return t.next.RoundTrip(req)
+	}
+	timeouts := t.clients.timeouts
+	if server, ok := t.clients.servers.server(req.URL.Host, hasTimeouts); ok {
+		timeouts = timeouts.ForServer(server)
+	}
+	ctx, cancel := context.WithTimeout(req.Context(), timeouts.overall(t.client))
+	resp, err := t.next.RoundTrip(req.WithContext(ctx))
+	if err != nil {
+		cancel()
+		return nil, err
+	}
+	resp.Body = &cancelOnClose{ReadCloser: resp.Body, cancel: cancel}
+	return resp, nil
+}
+
+// cancelOnClose is the body of a response that releases the timeout of its request when it is
+// closed.
+type cancelOnClose struct {
+	io.ReadCloser
+	cancel context.CancelFunc
+}
+
+// Close implements the io.Closer interface.
+func (b *cancelOnClose) Close() error {
+	err := b.ReadCloser.Close()
+	b.cancel()
+	return err
+}
@@ -0,0 +1,64 @@
+package httpclient
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"watchdog.onebusaway.org/internal/models"
+)
+
+func TestClientTimeouts(t *testing.T) {
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		time.Sleep(200 * time.Millisecond)
+		w.WriteHeader(http.StatusOK)
+	}))
+	defer ts.Close()
+
+	clients := New(Options{Timeouts: Timeouts{API: 50 * time.Millisecond}})
+	if _, err := clients.API.Get(ts.URL); err == nil {
+		t.Fatal("expected the request to time out")
+	}
+
+	req, _ := http.NewRequestWithContext(WithoutTimeout(context.Background()), http.MethodGet, ts.URL, nil)
+	resp, err := clients.API.Do(req)
+	if err != nil {
+		t.Fatalf("expected the request without timeout to succeed: %v", err)
+	}
+	resp.Body.Close()
+
+	clients.SetServers(func() []models.ObaServer {
+		return []models.ObaServer{{ID: 1, ObaBaseURL: ts.URL, Timeouts: &models.HTTPTimeouts{API: models.Duration(time.Second)}}}
+	})
+	resp, err = clients.API.Get(ts.URL + "/api/where/current-time.json")
+	if err != nil {
+		t.Fatalf("expected the timeout of the server to apply: %v", err)
+	}
+	resp.Body.Close()
+}
+
+func TestTimeoutsForServer(t *testing.T) {
+	server := models.ObaServer{Timeouts: &models.HTTPTimeouts{Connect: models.Duration(time.Second), Bundle: models.Duration(time.Hour)}}
+	want := DefaultTimeouts
+	want.Connect, want.Bundle = time.Second, time.Hour
+	if got := DefaultTimeouts.ForServer(server); got != want {
+		t.Errorf("expected %+v, got %+v", want, got)
+	}
+	if got := DefaultTimeouts.ForServer(models.ObaServer{}); got != DefaultTimeouts {
+		t.Errorf("expected the global timeouts, got %+v", got)
+	}
+
+	st := newServerTransport(New(Options{}).servers.base)
+	transport, err := st.transport(server)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if transport.TLSHandshakeTimeout != DefaultTimeouts.TLSHandshake {
+		t.Errorf("expected the global TLS handshake timeout, got %v", transport.TLSHandshakeTimeout)
+	}
+	if again, _ := st.transport(server); again != transport {
+		t.Error("expected the transport to be shared by the servers with the same settings")
+	}
+}
@@ -0,0 +1,66 @@
+// Package i18n translates the user-facing strings of the watchdog (alert notifications and
+// web pages) into the languages used by the agencies it monitors.
+//
+// Messages are looked up by key in the catalog of a locale and formatted with fmt.Sprintf.
+// Locales are matched on their base language, so "es-MX" and "es_419" use the Spanish catalog,
+// and anything unsupported falls back to English.
+package i18n
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+)
+
+// DefaultLocale is used when no locale is configured or the configured one is not supported.
+const DefaultLocale = "en"
+
+// Match returns the supported locale for a locale tag such as "fr", "fr-CA" or "es_MX",
+// or DefaultLocale if its language is not supported.
+func Match(locale string) string {
+	if Supported(locale) {
+		return language(locale)
+	}
+	return DefaultLocale
+}
+
+// Supported reports whether the language of the locale tag has a catalog.
+func Supported(locale string) bool {
+	_, ok := catalogs[language(locale)]
+	return ok
+}
+
+// language returns the lower-cased base language of a locale tag.
+func language(locale string) string {
+	language := strings.ToLower(strings.TrimSpace(locale))
+	if i := strings.IndexAny(language, "-_"); i >= 0 {
+		language = language[:i]
+	}
+	return language
+}
+
+// Locales returns the supported locales, sorted.
+func Locales() []string {
+	locales := make([]string, 0, len(catalogs))
+	for locale := range catalogs {
+		locales = append(locales, locale)
+	}
+	sort.Strings(locales)
+	return locales
+}
+
+// T returns the message for key in the given locale, formatted with args.
+// Messages missing from a catalog fall back to English, and unknown keys are returned as is.
+func T(locale, key string, args ...interface{}) string {
+	message, ok := catalogs[Match(locale)][key]
+	if !ok {
+		message, ok = catalogs[DefaultLocale][key]
+	}
+	if !ok {
+		return key
+	}
+	if len(args) == 0 {
+		return message
+	}
+	return fmt.Sprintf(message, args...)
+}
@@ -0,0 +1,69 @@
+package i18n
+
+import (
+	"regexp"
+	"testing"
+)
+
+var verbPattern = regexp.MustCompile(`%[-+# 0-9.]*[a-zA-Z]`)
+
+func TestCatalogsAreComplete(t *testing.T) {
+	for locale, catalog := range catalogs {
+		for key, reference := range catalogs[DefaultLocale] {
+			message, ok := catalog[key]
+			if !ok {
+				t.Errorf("%s: missing message %q", locale, key)
+				continue
+			}
+			want := verbPattern.FindAllString(reference, -1)
+			got := verbPattern.FindAllString(message, -1)
+			if len(got) != len(want) {
+				t.Errorf("%s: message %q has format verbs %v, want %v", locale, key, got, want)
+				continue
+			}
+			for i := range want {
+				if got[i] != want[i] {
+					t.Errorf("%s: message %q has format verbs %v, want %v", locale, key, got, want)
+					break
+				}
+			}
+		}
+		for key := range catalog {
+			if _, ok := catalogs[DefaultLocale][key]; !ok {
+				t.Errorf("%s: message %q is not in the %s catalog", locale, key, DefaultLocale)
+			}
+		}
+	}
+}
+
+func TestMatch(t *testing.T) {
+	tests := map[string]string{
+		"":       "en",
+		"en":     "en",
+		"es":     "es",
+		"es-MX":  "es",
+		"es_419": "es",
+		"FR-ca":  "fr",
+		"de":     "en",
+	}
+	for locale, want := range tests {
+		if got := Match(locale); got != want {
+			t.Errorf("Match(%q) = %q, want %q", locale, got, want)
+		}
+	}
+	if Supported("de") || !Supported("fr-CA") {
+		t.Error("unexpected result from Supported")
+	}
+}
+
+func TestT(t *testing.T) {
+	if got := T("es-MX", "alert.api_down.description", 3.0); got != "3 pings fallidos consecutivos" {
+		t.Errorf("unexpected Spanish message %q", got)
+	}
+	if got := T("de", "alert.field.server"); got != "Server" {
+		t.Errorf("expected the English fallback, got %q", got)
+	}
+	if got := T("fr", "unknown.key"); got != "unknown.key" {
+		t.Errorf("expected an unknown key to be returned as is, got %q", got)
+	}
+}
@@ -0,0 +1,228 @@
+package i18n
+
+// catalogs maps each supported locale to its messages. English is the reference catalog:
+// every key must exist there, and the other catalogs must use the same format verbs.
+var catalogs = map[string]map[string]string{
+	"en": {
+		"alert.status.firing":   "firing",
+		"alert.status.resolved": "resolved",
+
+		"alert.api_down.title":                "OBA API is down",
+		"alert.api_down.description":          "%.0f consecutive failed pings",
+		"alert.bundle_download.title":         "GTFS bundle download failing",
+		"alert.bundle_download.description":   "%.0f consecutive failed downloads",
+		"alert.bundle_expiration.title":       "GTFS bundle expiring soon",
+		"alert.bundle_expiration.description": "earliest service end date in %.0f days",
+		"alert.bundle_size_drop.title":        "GTFS bundle much smaller than usual",
+		"alert.bundle_size_drop.description":  "the new bundle is %.2f of the median size of the previous ones",
+		"alert.vehicles_dropped.title":        "OBA API is dropping vehicles",
+		"alert.vehicles_dropped.description":  "the OBA API returns %.2f of the vehicles of the GTFS-RT feed",
+		"alert.url_down.title":                "Service is down",
+		"alert.url_down.description":          "%.0f consecutive failed checks",
+
+		"alert.rule.above":          "%s is %s, above %s",
+		"alert.rule.below":          "%s is %s, below %s",
+		"alert.rule.increase_above": "%s increased by %s within %s",
+		"alert.rule.decrease_above": "%s decreased by %s within %s",
+		"alert.rule.rise_percent":   "%s rose by %s within %s",
+		"alert.rule.drop_percent":   "%s dropped by %s within %s",
+		"alert.rule.changed":        "%s changed within %s",
+
+		"alert.field.server":       "Server",
+		"alert.field.check":        "Check",
+		"alert.field.details":      "Details",
+		"alert.field.oba":          "OBA",
+		"alert.field.threshold":    "Threshold",
+		"alert.field.firing_since": "Firing since",
+		"alert.field.vantages":     "Vantage points",
+
+		"alert.email.digest_subject": "%d OneBusAway watchdog alerts",
+		"alert.email.digest_intro":   "The OneBusAway watchdog raised the following alerts since %s:",
+
+		"health.up":       "up",
+		"health.degraded": "degraded",
+		"health.down":     "down",
+		"health.unknown":  "unknown",
+
+		"statuspage.all_operational": "All systems operational",
+		"statuspage.some_problems":   "Some systems are experiencing problems",
+		"statuspage.state.up":        "Operational",
+		"statuspage.state.degraded":  "Degraded performance",
+		"statuspage.state.down":      "Outage",
+		"statuspage.state.unknown":   "No data",
+		"statuspage.uptime":          "%s uptime",
+		"statuspage.days_ago":        "%d days ago",
+		"statuspage.today":           "Today",
+		"statuspage.no_data":         "No data",
+		"statuspage.updated":         "Last updated %s",
+
+		"dashboard.title":             "Watchdog dashboard",
+		"dashboard.server":            "Server",
+		"dashboard.health":            "Health",
+		"dashboard.feed_age":          "Realtime feed age",
+		"dashboard.bundle_expiration": "Bundle expiration",
+		"dashboard.errors":            "Recent errors",
+		"dashboard.none":              "None",
+		"dashboard.never":             "Never",
+		"dashboard.age_seconds":       "%d s ago",
+		"dashboard.age_minutes":       "%d min ago",
+		"dashboard.age_hours":         "%d h ago",
+		"dashboard.expires_in":        "%s (in %d days)",
+		"dashboard.expired":           "%s (expired)",
+		"dashboard.updated":           "Last updated %s",
+		"dashboard.load_error":        "Failed to load the server statuses: %s",
+
+		"digest.subject":  "Data quality report for %s",
+		"digest.intro":    "The OneBusAway watchdog found the following problems in the data of %s since %s:",
+		"digest.finding":  "%s: %s (failed %d times)",
+		"digest.resolved": "(resolved)",
+	},
+	"es": {
+		"alert.status.firing":   "activa",
+		"alert.status.resolved": "resuelta",
+
+		"alert.api_down.title":                "La API de OBA no responde",
+		"alert.api_down.description":          "%.0f pings fallidos consecutivos",
+		"alert.bundle_download.title":         "Falla la descarga del paquete GTFS",
+		"alert.bundle_download.description":   "%.0f descargas fallidas consecutivas",
+		"alert.bundle_expiration.title":       "El paquete GTFS vence pronto",
+		"alert.bundle_expiration.description": "la primera fecha de fin de servicio es en %.0f días",
+		"alert.bundle_size_drop.title":        "Paquete GTFS mucho más pequeño de lo habitual",
+		"alert.bundle_size_drop.description":  "el nuevo paquete mide %.2f del tamaño mediano de los anteriores",
+		"alert.vehicles_dropped.title":        "La API de OBA pierde vehículos",
+		"alert.vehicles_dropped.description":  "la API de OBA devuelve %.2f de los vehículos del feed GTFS-RT",
+		"alert.url_down.title":                "El servicio no responde",
+		"alert.url_down.description":          "%.0f comprobaciones fallidas consecutivas",
+
+		"alert.rule.above":          "%s vale %s, por encima de %s",
+		"alert.rule.below":          "%s vale %s, por debajo de %s",
+		"alert.rule.increase_above": "%s aumentó en %s en %s",
+		"alert.rule.decrease_above": "%s disminuyó en %s en %s",
+		"alert.rule.rise_percent":   "%s subió un %s en %s",
+		"alert.rule.drop_percent":   "%s bajó un %s en %s",
+		"alert.rule.changed":        "%s cambió en %s",
+
+		"alert.field.server":       "Servidor",
+		"alert.field.check":        "Verificación",
+		"alert.field.details":      "Detalles",
+		"alert.field.oba":          "OBA",
+		"alert.field.threshold":    "Umbral",
+		"alert.field.firing_since": "Activa desde",
+		"alert.field.vantages":     "Puntos de observación",
+
+		"alert.email.digest_subject": "%d alertas del watchdog de OneBusAway",
+		"alert.email.digest_intro":   "El watchdog de OneBusAway emitió las siguientes alertas desde %s:",
+
+		"health.up":       "operativo",
+		"health.degraded": "degradado",
+		"health.down":     "caído",
+		"health.unknown":  "desconocido",
+
+		"statuspage.all_operational": "Todos los sistemas funcionan con normalidad",
+		"statuspage.some_problems":   "Algunos sistemas presentan problemas",
+		"statuspage.state.up":        "Operativo",
+		"statuspage.state.degraded":  "Rendimiento degradado",
+		"statuspage.state.down":      "Interrupción",
+		"statuspage.state.unknown":   "Sin datos",
+		"statuspage.uptime":          "%s de disponibilidad",
+		"statuspage.days_ago":        "hace %d días",
+		"statuspage.today":           "Hoy",
+		"statuspage.no_data":         "Sin datos",
+		"statuspage.updated":         "Última actualización: %s",
+
+		"dashboard.title":             "Panel del watchdog",
+		"dashboard.server":            "Servidor",
+		"dashboard.health":            "Estado",
+		"dashboard.feed_age":          "Antigüedad del feed en tiempo real",
+		"dashboard.bundle_expiration": "Vencimiento del bundle",
+		"dashboard.errors":            "Errores recientes",
+		"dashboard.none":              "Ninguno",
+		"dashboard.never":             "Nunca",
+		"dashboard.age_seconds":       "hace %d s",
+		"dashboard.age_minutes":       "hace %d min",
+		"dashboard.age_hours":         "hace %d h",
+		"dashboard.expires_in":        "%s (en %d días)",
+		"dashboard.expired":           "%s (vencido)",
+		"dashboard.updated":           "Última actualización: %s",
+		"dashboard.load_error":        "No se pudo cargar el estado de los servidores: %s",
+
+		"digest.subject":  "Informe de calidad de datos de %s",
+		"digest.intro":    "El watchdog de OneBusAway encontró los siguientes problemas en los datos de %s desde %s:",
+		"digest.finding":  "%s: %s (falló %d veces)",
+		"digest.resolved": "(resuelto)",
+	},
+	"fr": {
+		"alert.status.firing":   "en cours",
+		"alert.status.resolved": "résolue",
+
+		"alert.api_down.title":                "L'API OBA ne répond pas",
+		"alert.api_down.description":          "%.0f pings consécutifs en échec",
+		"alert.bundle_download.title":         "Échec du téléchargement du bundle GTFS",
+		"alert.bundle_download.description":   "%.0f téléchargements consécutifs en échec",
+		"alert.bundle_expiration.title":       "Le bundle GTFS expire bientôt",
+		"alert.bundle_expiration.description": "première date de fin de service dans %.0f jours",
+		"alert.bundle_size_drop.title":        "Paquet GTFS bien plus petit que d'habitude",
+		"alert.bundle_size_drop.description":  "le nouveau paquet fait %.2f de la taille médiane des précédents",
+		"alert.vehicles_dropped.title":        "L'API OBA perd des véhicules",
+		"alert.vehicles_dropped.description":  "l'API OBA renvoie %.2f des véhicules du flux GTFS-RT",
+		"alert.url_down.title":                "Le service ne répond pas",
+		"alert.url_down.description":          "%.0f vérifications consécutives en échec",
+
+		"alert.rule.above":          "%s vaut %s, au-dessus de %s",
+		"alert.rule.below":          "%s vaut %s, en dessous de %s",
+		"alert.rule.increase_above": "%s a augmenté de %s en %s",
+		"alert.rule.decrease_above": "%s a diminué de %s en %s",
+		"alert.rule.rise_percent":   "%s a augmenté de %s en %s",
+		"alert.rule.drop_percent":   "%s a baissé de %s en %s",
+		"alert.rule.changed":        "%s a changé en %s",
+
+		"alert.field.server":       "Serveur",
+		"alert.field.check":        "Vérification",
+		"alert.field.details":      "Détails",
+		"alert.field.oba":          "OBA",
+		"alert.field.threshold":    "Seuil",
+		"alert.field.firing_since": "En cours depuis",
+		"alert.field.vantages":     "Points d'observation",
+
+		"alert.email.digest_subject": "%d alertes du watchdog OneBusAway",
+		"alert.email.digest_intro":   "Le watchdog OneBusAway a émis les alertes suivantes depuis %s :",
+
+		"health.up":       "opérationnel",
+		"health.degraded": "dégradé",
+		"health.down":     "hors service",
+		"health.unknown":  "inconnu",
+
+		"statuspage.all_operational": "Tous les systèmes sont opérationnels",
+		"statuspage.some_problems":   "Certains systèmes rencontrent des problèmes",
+		"statuspage.state.up":        "Opérationnel",
+		"statuspage.state.degraded":  "Performances dégradées",
+		"statuspage.state.down":      "Panne",
+		"statuspage.state.unknown":   "Aucune donnée",
+		"statuspage.uptime":          "%s de disponibilité",
+		"statuspage.days_ago":        "il y a %d jours",
+		"statuspage.today":           "Aujourd'hui",
+		"statuspage.no_data":         "Aucune donnée",
+		"statuspage.updated":         "Dernière mise à jour : %s",
+
+		"dashboard.title":             "Tableau de bord du watchdog",
+		"dashboard.server":            "Serveur",
+		"dashboard.health":            "État",
+		"dashboard.feed_age":          "Âge du flux temps réel",
+		"dashboard.bundle_expiration": "Expiration du bundle",
+		"dashboard.errors":            "Erreurs récentes",
+		"dashboard.none":              "Aucune",
+		"dashboard.never":             "Jamais",
+		"dashboard.age_seconds":       "il y a %d s",
+		"dashboard.age_minutes":       "il y a %d min",
+		"dashboard.age_hours":         "il y a %d h",
+		"dashboard.expires_in":        "%s (dans %d jours)",
+		"dashboard.expired":           "%s (expiré)",
+		"dashboard.updated":           "Dernière mise à jour : %s",
+		"dashboard.load_error":        "Impossible de charger l'état des serveurs : %s",
+
+		"digest.subject":  "Rapport de qualité des données de %s",
+		"digest.intro":    "Le watchdog OneBusAway a trouvé les problèmes suivants dans les données de %s depuis %s :",
+		"digest.finding":  "%s : %s (en échec %d fois)",
+		"digest.resolved": "(résolu)",
+	},
+}
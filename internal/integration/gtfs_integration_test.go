@@ -11,6 +11,7 @@ import (
 
 	"watchdog.onebusaway.org/internal/geo"
 	"watchdog.onebusaway.org/internal/gtfs"
+	"watchdog.onebusaway.org/internal/models"
 )
 
 // TestDownloadGTFSBundles verifies that GTFS bundles can be downloaded successfully
@@ -26,20 +27,15 @@ func TestDownloadGTFSBundles(t *testing.T) {
 	boundingBoxStore := geo.NewBoundingBoxStore()
 	logger := slog.Default()
 	client := &http.Client{}
-	gtfsService := gtfs.NewGtfsService(staticStore,realtimeStore,boundingBoxStore,logger,client)
+	gtfsService := gtfs.NewGtfsService(staticStore,realtimeStore,boundingBoxStore,logger,client,client,gtfs.GtfsServiceOptions{})
 	ctx := context.Background()
 	for _, server := range integrationServers {
 		srv := server
 		t.Run(fmt.Sprintf("ServerID_%d", srv.ID), func(t *testing.T) {
 			t.Parallel()
-			staticBundle,err := gtfsService.DownloadGTFSBundle(ctx,srv.GtfsUrl, srv.ID,20)
-			if err != nil {
-				t.Errorf("failed to download GTFS bundle for server %d : %v", srv.ID, err)
-				return
-			}
-			err = gtfsService.StoreGTFSBundle(staticBundle,server.ID)
-			if err != nil {
-				t.Errorf("failed to store GTFS bundle for server %d : %v", srv.ID, err)
+			gtfsService.DownloadGTFSBundles(ctx, []models.ObaServer{srv}, 20)
+			if _, ok := staticStore.Get(srv.ID); !ok {
+				t.Errorf("failed to download and store GTFS bundle for server %d", srv.ID)
 				return
 			}
 			t.Logf("GTFS bundle downloaded successfully for server %d", srv.ID)
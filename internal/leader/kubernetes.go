@@ -0,0 +1,126 @@
+package leader
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"os"
+	"strings"
+	"time"
+
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/kubernetes"
+	"k8s.io/client-go/rest"
+	"k8s.io/client-go/tools/leaderelection"
+	"k8s.io/client-go/tools/leaderelection/resourcelock"
+	"k8s.io/klog/v2"
+	"watchdog.onebusaway.org/internal/shutdown"
+)
+
+// namespaceFile holds the namespace of the pod, in the service account credentials of a pod.
+const namespaceFile = "/var/run/secrets/kubernetes.io/serviceaccount/namespace"
+
+// NewKubernetesElector returns an Elector holding the coordination.k8s.io/v1 Lease name as
+// identity, with the leader election of client-go used by Kubernetes controllers, which renews
+// the lease before its deadline, tolerates clock skew between the replicas and retries on
+// conflicts. It is authenticated with the service account of the pod it runs in, which needs the
+// get, create and update verbs on leases in namespace (empty = the namespace of the pod).
+func NewKubernetesElector(name, namespace, identity string, ttl time.Duration, logger *slog.Logger) (*Elector, error) {
+	restConfig, err := rest.InClusterConfig()
+	if err != nil {
+		return nil, fmt.Errorf("not running in a Kubernetes pod: %w", err)
+	}
+	if namespace == "" {
+		data, err := os.ReadFile(namespaceFile)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read the namespace of the pod: %w", err)
+		}
+		namespace = strings.TrimSpace(string(data))
+	}
+	clientset, err := kubernetes.NewForConfig(restConfig)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create the Kubernetes client: %w", err)
+	}
+	// client-go logs the progress of the election with klog.
+	klog.SetSlogLogger(logger)
+	lock := &resourcelock.LeaseLock{
+		LeaseMeta:  metav1.ObjectMeta{Name: name, Namespace: namespace},
+		Client:     clientset.CoordinationV1(),
+		LockConfig: resourcelock.ResourceLockConfig{Identity: identity},
+	}
+	return newLeaseElector(lock, identity, ttl, logger)
+}
+
+// newLeaseElector returns an Elector holding lock with the leader election of client-go. The
+// leader renews the lock three times per ttl, and steps down if it could not renew it for two
+// thirds of its ttl, as with a Lock (see Elector.Elect).
+func newLeaseElector(lock resourcelock.Interface, identity string, ttl time.Duration, logger *slog.Logger) (*Elector, error) {
+	e := NewElector(nil, identity, ttl, logger)
+	e.observed = make(chan struct{})
+	e.stopped = make(chan struct{})
+	elector, err := leaderelection.NewLeaderElector(leaderelection.LeaderElectionConfig{
+		Lock:            lock,
+		LeaseDuration:   ttl,
+		RenewDeadline:   ttl * 2 / 3,
+		RetryPeriod:     ttl / 3,
+		ReleaseOnCancel: true,
+		Name:            lock.Describe(),
+		Callbacks: leaderelection.LeaderCallbacks{
+			OnStartedLeading: func(context.Context) { e.setLeader(true) },
+			OnStoppedLeading: func() {
+				if e.leaseCtx.Err() != nil {
+					e.standDown()
+					return
+				}
+				e.setLeader(false)
+			},
+			OnNewLeader: func(holder string) {
+				e.setLeader(holder == identity)
+				e.observeOnce.Do(func() { close(e.observed) })
+			},
+		},
+	})
+	if err != nil {
+		return nil, fmt.Errorf("invalid leader election: %w", err)
+	}
+	e.lease = elector
+	return e, nil
+}
+
+// startLease starts the election of client-go in the background, once, until ctx is canceled
+// or the shutdown begins. The lease is released when it stops.
+func (e *Elector) startLease(ctx context.Context) {
+	e.startOnce.Do(func() {
+		var cancel context.CancelFunc
+		e.leaseCtx, cancel = context.WithCancel(ctx)
+		go func() {
+			defer close(e.stopped)
+			defer cancel()
+			go func() {
+				select {
+				case <-shutdown.Stopping(ctx):
+					cancel()
+				case <-e.leaseCtx.Done():
+				}
+			}()
+			// Run returns when the leader loses the lease; it then stands by for the next term.
+			for e.leaseCtx.Err() == nil {
+				e.lease.Run(e.leaseCtx)
+			}
+		}()
+	})
+}
+
+// electLease starts the election of client-go, and waits until the holder of the lease is known,
+// for at most ttl, so that the first election is held before the checks start.
+func (e *Elector) electLease(ctx context.Context) bool {
+	e.startLease(ctx)
+	timer := time.NewTimer(e.ttl)
+	defer timer.Stop()
+	select {
+	case <-e.observed:
+	case <-timer.C:
+	case <-ctx.Done():
+	}
+	return e.IsLeader()
+}
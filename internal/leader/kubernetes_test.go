@@ -0,0 +1,68 @@
+package leader
+
+import (
+	"context"
+	"io"
+	"log/slog"
+	"testing"
+	"time"
+
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/kubernetes/fake"
+	"k8s.io/client-go/tools/leaderelection/resourcelock"
+)
+
+func TestLeaseElector(t *testing.T) {
+	logger := slog.New(slog.NewTextHandler(io.Discard, nil))
+	client := fake.NewSimpleClientset()
+	newElector := func(identity string) *Elector {
+		lock := &resourcelock.LeaseLock{
+			LeaseMeta:  metav1.ObjectMeta{Name: "watchdog-leader", Namespace: "monitoring"},
+			Client:     client.CoordinationV1(),
+			LockConfig: resourcelock.ResourceLockConfig{Identity: identity},
+		}
+		e, err := newLeaseElector(lock, identity, 3*time.Second, logger)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return e
+	}
+
+	ctxA, cancelA := context.WithCancel(context.Background())
+	defer cancelA()
+	a := newElector("a")
+	if !a.Elect(ctxA) {
+		t.Fatal("expected a to create the lease and lead")
+	}
+	ctxB, cancelB := context.WithCancel(context.Background())
+	defer cancelB()
+	b := newElector("b")
+	if b.Elect(ctxB) {
+		t.Fatal("expected b not to take a lease held by a")
+	}
+	lease, err := client.CoordinationV1().Leases("monitoring").Get(context.Background(), "watchdog-leader", metav1.GetOptions{})
+	if err != nil || lease.Spec.HolderIdentity == nil || *lease.Spec.HolderIdentity != "a" {
+		t.Fatalf("expected a to hold the lease, got %+v, %v", lease, err)
+	}
+
+	// A leader that stops releases the lease, and a standby takes over.
+	stopped := make(chan struct{})
+	go func() {
+		b.Run(ctxB)
+		close(stopped)
+	}()
+	cancelA()
+	a.Run(ctxA)
+	if a.IsLeader() {
+		t.Error("expected a to stand down when its election stops")
+	}
+	deadline := time.Now().Add(5 * time.Second)
+	for !b.IsLeader() && time.Now().Before(deadline) {
+		time.Sleep(50 * time.Millisecond)
+	}
+	if !b.IsLeader() {
+		t.Error("expected b to take over the released lease")
+	}
+	cancelB()
+	<-stopped
+}
@@ -0,0 +1,168 @@
+// Package leader elects a leader among the replicas of a highly available deployment, so that
+// only one of them sends the outbound checks to the monitored servers. The other replicas are
+// standbys: they keep serving /metrics and the status API, with watchdog_leader at 0 to flag
+// their data as stale, and take over when the leader stops renewing its lock.
+//
+// The lock is a Kubernetes Lease, held with the leader election of client-go (see
+// NewKubernetesElector), or a Redis key (see RedisLock).
+package leader
+
+import (
+	"context"
+	"log/slog"
+	"sync"
+	"time"
+
+	"k8s.io/client-go/tools/leaderelection"
+	"watchdog.onebusaway.org/internal/scheduler"
+)
+
+// Lock is a lock held for a limited time, which a replica must renew to keep.
+type Lock interface {
+	// Acquire acquires the lock for identity for ttl, or renews it if identity already holds
+	// it, and reports whether identity holds the lock.
+	Acquire(ctx context.Context, identity string, ttl time.Duration) (bool, error)
+	// Release releases the lock if identity holds it, so that a standby takes over without
+	// waiting for the lock to expire.
+	Release(ctx context.Context, identity string) error
+}
+
+// Elector takes part in the election of the leader with a Lock, or with the leader election of
+// client-go (see NewKubernetesElector). It is safe for concurrent use; a nil Elector is always
+// the leader, as a single replica is.
+type Elector struct {
+	lock     Lock
+	identity string
+	ttl      time.Duration
+	logger   *slog.Logger
+
+	// lease runs the election instead of lock if it is set, under leaseCtx. observed is closed
+	// once the holder of the lease is known, and stopped once the election stopped.
+	lease       *leaderelection.LeaderElector
+	leaseCtx    context.Context
+	startOnce   sync.Once
+	observeOnce sync.Once
+	observed    chan struct{}
+	stopped     chan struct{}
+
+	mu        sync.Mutex
+	leader    bool
+	renewedAt time.Time
+}
+
+// NewElector returns an Elector holding lock as identity for ttl at a time.
+func NewElector(lock Lock, identity string, ttl time.Duration, logger *slog.Logger) *Elector {
+	LeaderGauge.Set(0)
+	return &Elector{lock: lock, identity: identity, ttl: ttl, logger: logger}
+}
+
+// Identity returns the identity of the replica in the election.
+func (e *Elector) Identity() string {
+	if e == nil {
+		return ""
+	}
+	return e.identity
+}
+
+// IsLeader reports whether the replica is the leader.
+func (e *Elector) IsLeader() bool {
+	if e == nil {
+		return true
+	}
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	return e.leader
+}
+
+// Elect makes one attempt to acquire or renew the lock, and reports whether the replica is
+// the leader. A failed attempt only demotes the leader once it could not renew the lock for
+// two thirds of its ttl, so that it steps down before the lock expires and a standby takes
+// over, while riding out a brief outage of the lock's backend.
+//
+// With the leader election of client-go, Elect starts the election, which then runs in the
+// background until ctx is canceled, and waits for its first outcome.
+func (e *Elector) Elect(ctx context.Context) bool {
+	if e == nil {
+		return true
+	}
+	if e.lease != nil {
+		return e.electLease(ctx)
+	}
+	acquired, err := e.lock.Acquire(ctx, e.identity, e.ttl)
+	now := time.Now()
+
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	if err != nil {
+		ElectionErrorsCounter.Inc()
+		e.logger.Warn("Leader election failed", "identity", e.identity, "error", err)
+		acquired = e.leader && now.Sub(e.renewedAt) < e.ttl*2/3
+	} else if acquired {
+		e.renewedAt = now
+	}
+	e.transition(acquired)
+	return e.leader
+}
+
+// setLeader records whether the replica is the leader.
+func (e *Elector) setLeader(leader bool) {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	e.transition(leader)
+}
+
+// transition records whether the replica is the leader, and logs and counts the change if it
+// is one. e.mu must be held.
+func (e *Elector) transition(leader bool) {
+	if leader == e.leader {
+		return
+	}
+	e.leader = leader
+	TransitionsCounter.Inc()
+	if leader {
+		e.logger.Info("Became the leader, running the checks", "identity", e.identity)
+		LeaderGauge.Set(1)
+	} else {
+		e.logger.Warn("Lost the leadership, standing by", "identity", e.identity)
+		LeaderGauge.Set(0)
+	}
+}
+
+// Run takes part in the election, renewing the lock three times per ttl, until ctx is canceled
+// or the shutdown begins, then releases the lock if the replica holds it.
+func (e *Elector) Run(ctx context.Context) {
+	if e == nil {
+		return
+	}
+	if e.lease != nil {
+		// The election of client-go releases the lease itself when it stops.
+		e.startLease(ctx)
+		<-e.stopped
+		e.standDown()
+		return
+	}
+	scheduler.Run(ctx, "leader_election", scheduler.Every(e.ttl/3), func() { e.Elect(ctx) })
+
+	if !e.standDown() {
+		return
+	}
+	// ctx may already be canceled, but the lock is still worth releasing.
+	releaseCtx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	if err := e.lock.Release(releaseCtx, e.identity); err != nil {
+		e.logger.Warn("Failed to release the leader lock, a standby takes over when it expires", "identity", e.identity, "error", err)
+		return
+	}
+	e.logger.Info("Released the leader lock", "identity", e.identity)
+}
+
+// standDown stops the replica from leading when the election stops, which is not a lost
+// leadership, and reports whether it was the leader.
+func (e *Elector) standDown() bool {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	wasLeader := e.leader
+	e.leader = false
+	LeaderGauge.Set(0)
+	return wasLeader
+}
@@ -0,0 +1,88 @@
+package leader
+
+import (
+	"context"
+	"errors"
+	"io"
+	"log/slog"
+	"testing"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus/testutil"
+)
+
+// fakeLock is a Lock held by one identity at a time, which fails while err is set.
+type fakeLock struct {
+	holder   string
+	err      error
+	released bool
+}
+
+func (l *fakeLock) Acquire(ctx context.Context, identity string, ttl time.Duration) (bool, error) {
+	if l.err != nil {
+		return false, l.err
+	}
+	if l.holder == "" {
+		l.holder = identity
+	}
+	return l.holder == identity, nil
+}
+
+func (l *fakeLock) Release(ctx context.Context, identity string) error {
+	if l.holder == identity {
+		l.holder = ""
+		l.released = true
+	}
+	return nil
+}
+
+func TestElector(t *testing.T) {
+	logger := slog.New(slog.NewTextHandler(io.Discard, nil))
+	lock := &fakeLock{}
+	a := NewElector(lock, "a", time.Minute, logger)
+	b := NewElector(lock, "b", time.Minute, logger)
+	ctx := context.Background()
+
+	if !a.Elect(ctx) || b.Elect(ctx) {
+		t.Fatal("expected a to lead and b to stand by")
+	}
+	if got := testutil.ToFloat64(LeaderGauge); got != 1 {
+		t.Errorf("expected watchdog_leader to be 1 after a became the leader, got %v", got)
+	}
+
+	// A brief outage of the backend does not demote the leader.
+	lock.err = errors.New("unreachable")
+	if !a.Elect(ctx) {
+		t.Error("expected the leader to keep the leadership right after a renewal")
+	}
+	a.renewedAt = time.Now().Add(-time.Minute)
+	if a.Elect(ctx) {
+		t.Error("expected the leader to step down when it could not renew the lock in time")
+	}
+
+	lock.err = nil
+	lock.holder = ""
+	if !b.Elect(ctx) || a.Elect(ctx) {
+		t.Error("expected b to take over the free lock")
+	}
+}
+
+func TestElectorRunReleases(t *testing.T) {
+	logger := slog.New(slog.NewTextHandler(io.Discard, nil))
+	lock := &fakeLock{}
+	e := NewElector(lock, "a", 3*time.Second, logger)
+	ctx, cancel := context.WithCancel(context.Background())
+	e.Elect(ctx)
+	cancel()
+	e.Run(ctx)
+	if !lock.released || e.IsLeader() {
+		t.Error("expected the lock to be released when the election stops")
+	}
+}
+
+func TestNilElector(t *testing.T) {
+	var e *Elector
+	if !e.IsLeader() || !e.Elect(context.Background()) {
+		t.Error("expected a nil elector to always lead")
+	}
+}
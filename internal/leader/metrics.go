@@ -0,0 +1,26 @@
+package leader
+
+import (
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promauto"
+)
+
+var (
+	// LeaderGauge is whether the replica is the leader.
+	LeaderGauge = promauto.NewGauge(prometheus.GaugeOpts{
+		Name: "watchdog_leader",
+		Help: "Whether this replica is the leader running the checks (1) or a standby whose check metrics are stale (0); always 1 without leader election",
+	})
+
+	// TransitionsCounter counts the changes of leadership of the replica.
+	TransitionsCounter = promauto.NewCounter(prometheus.CounterOpts{
+		Name: "watchdog_leader_transitions_total",
+		Help: "Times this replica became the leader or lost the leadership",
+	})
+
+	// ElectionErrorsCounter counts the attempts to acquire or renew the lock that failed.
+	ElectionErrorsCounter = promauto.NewCounter(prometheus.CounterOpts{
+		Name: "watchdog_leader_election_errors_total",
+		Help: "Attempts to acquire or renew the leader lock that failed, e.g. because its backend is unreachable",
+	})
+)
@@ -0,0 +1,48 @@
+package leader
+
+import (
+	"context"
+	"time"
+
+	"github.com/redis/go-redis/v9"
+)
+
+// acquireScript sets the key to the identity (ARGV[1]) for ARGV[2] milliseconds if it is free
+// or already holds the identity, and returns 1 if it does.
+var acquireScript = redis.NewScript(`local holder = redis.call('GET', KEYS[1])
+if holder == ARGV[1] then
+  redis.call('PEXPIRE', KEYS[1], ARGV[2])
+  return 1
+end
+if not holder then
+  redis.call('SET', KEYS[1], ARGV[1], 'PX', ARGV[2])
+  return 1
+end
+return 0`)
+
+// releaseScript deletes the key if it holds the identity (ARGV[1]).
+var releaseScript = redis.NewScript(`if redis.call('GET', KEYS[1]) == ARGV[1] then
+  return redis.call('DEL', KEYS[1])
+end
+return 0`)
+
+// RedisLock is a Lock on a Redis key holding the identity of the leader, which expires unless
+// the leader renews it. The scripts checking and setting the key run atomically in Redis.
+type RedisLock struct {
+	Client *redis.Client
+	Key    string
+}
+
+// Acquire implements Lock.
+func (r *RedisLock) Acquire(ctx context.Context, identity string, ttl time.Duration) (bool, error) {
+	acquired, err := acquireScript.Run(ctx, r.Client, []string{r.Key}, identity, max(ttl.Milliseconds(), 1)).Int()
+	if err != nil {
+		return false, err
+	}
+	return acquired == 1, nil
+}
+
+// Release implements Lock.
+func (r *RedisLock) Release(ctx context.Context, identity string) error {
+	return releaseScript.Run(ctx, r.Client, []string{r.Key}, identity).Err()
+}
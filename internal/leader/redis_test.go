@@ -0,0 +1,48 @@
+package leader
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/alicebob/miniredis/v2"
+	"github.com/redis/go-redis/v9"
+)
+
+func TestRedisLock(t *testing.T) {
+	server := miniredis.RunT(t)
+	server.RequireAuth("secret")
+
+	ctx := context.Background()
+	lock := &RedisLock{Client: redis.NewClient(&redis.Options{Addr: server.Addr(), Password: "secret"}), Key: "watchdog-leader"}
+	if ok, err := lock.Acquire(ctx, "a", 10*time.Second); err != nil || !ok {
+		t.Fatalf("expected a to acquire the lock, got %v, %v", ok, err)
+	}
+	if ok, err := lock.Acquire(ctx, "b", 10*time.Second); err != nil || ok {
+		t.Fatalf("expected b not to acquire the lock held by a, got %v, %v", ok, err)
+	}
+	if ttl := server.TTL(lock.Key); ttl != 10*time.Second {
+		t.Errorf("expected the lock to expire in 10s, got %s", ttl)
+	}
+	server.FastForward(11 * time.Second)
+	if ok, err := lock.Acquire(ctx, "b", 10*time.Second); err != nil || !ok {
+		t.Fatalf("expected b to acquire the expired lock, got %v, %v", ok, err)
+	}
+	if err := lock.Release(ctx, "a"); err != nil {
+		t.Fatal(err)
+	}
+	if holder, _ := server.Get(lock.Key); holder != "b" {
+		t.Fatalf("expected a not to release the lock held by b, got %q", holder)
+	}
+	if err := lock.Release(ctx, "b"); err != nil {
+		t.Fatal(err)
+	}
+	if ok, err := lock.Acquire(ctx, "a", 10*time.Second); err != nil || !ok {
+		t.Fatalf("expected a to acquire the released lock, got %v, %v", ok, err)
+	}
+
+	wrong := &RedisLock{Client: redis.NewClient(&redis.Options{Addr: server.Addr(), Password: "wrong"}), Key: lock.Key}
+	if _, err := wrong.Acquire(ctx, "c", 10*time.Second); err == nil {
+		t.Error("expected an error with a wrong password")
+	}
+}
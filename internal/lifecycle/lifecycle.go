@@ -0,0 +1,237 @@
+// Package lifecycle records when the monitored entities were first and last observed: each
+// server (answering pings), the routes of its GTFS bundle and its GTFS-RT feeds (fetched
+// successfully). It answers lifecycle questions such as "when did this route disappear from
+// the bundle?" from the status API, without trawling the history of Prometheus.
+//
+// The observations are kept in a JSON file, if one is given, so that they survive restarts.
+package lifecycle
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sync"
+	"time"
+)
+
+// Retention is how long an entity that is no longer observed, e.g. a route removed from the
+// bundle, is remembered.
+const Retention = 365 * 24 * time.Hour
+
+// Span is when an entity was first and last observed.
+type Span struct {
+	FirstSeen time.Time `json:"first_seen"`
+	LastSeen  time.Time `json:"last_seen"`
+}
+
+// observe extends the span to at.
+func (s *Span) observe(at time.Time) {
+	if s.FirstSeen.IsZero() || at.Before(s.FirstSeen) {
+		s.FirstSeen = at
+	}
+	if at.After(s.LastSeen) {
+		s.LastSeen = at
+	}
+}
+
+// Entities are the observed entities of a server.
+type Entities struct {
+	// Server is when the server answered pings; nil if it never did.
+	Server *Span `json:"server,omitempty"`
+	// Routes are the routes of the GTFS bundles of the server, by route id. A route that is
+	// no longer in the bundle keeps the time it was last seen in it.
+	Routes map[string]Span `json:"routes,omitempty"`
+	// RealtimeFeeds are the GTFS-RT feeds of the server, by feed type (e.g.
+	// vehicle_positions), observed when they are fetched and parsed successfully.
+	RealtimeFeeds map[string]Span `json:"gtfs_realtime_feeds,omitempty"`
+}
+
+// clone returns a deep copy of e.
+func (e *Entities) clone() Entities {
+	c := Entities{Routes: make(map[string]Span, len(e.Routes)), RealtimeFeeds: make(map[string]Span, len(e.RealtimeFeeds))}
+	if e.Server != nil {
+		server := *e.Server
+		c.Server = &server
+	}
+	for id, span := range e.Routes {
+		c.Routes[id] = span
+	}
+	for feed, span := range e.RealtimeFeeds {
+		c.RealtimeFeeds[feed] = span
+	}
+	return c
+}
+
+// file is the content of the file of a Store.
+type file struct {
+	Servers map[int]*Entities `json:"servers"`
+}
+
+// Store keeps the observed entities of every server. It is safe for concurrent use; a nil
+// Store records nothing.
+type Store struct {
+	path string
+
+	mu      sync.Mutex
+	servers map[int]*Entities
+	dirty   bool
+}
+
+// Open returns a Store saved to path (empty = kept in memory only), with the entities
+// previously saved there. A missing file is an empty store.
+func Open(path string) (*Store, error) {
+	s := &Store{path: path, servers: make(map[int]*Entities)}
+	if path == "" {
+		return s, nil
+	}
+	data, err := os.ReadFile(path)
+	if errors.Is(err, os.ErrNotExist) {
+		return s, nil
+	}
+	if err != nil {
+		return s, fmt.Errorf("failed to read lifecycle file: %w", err)
+	}
+	var saved file
+	if err := json.Unmarshal(data, &saved); err != nil {
+		return s, fmt.Errorf("failed to decode lifecycle file %s: %w", path, err)
+	}
+	for id, entities := range saved.Servers {
+		if entities != nil {
+			s.servers[id] = entities
+		}
+	}
+	return s, nil
+}
+
+// entities returns the entities of a server, creating them if needed. s.mu must be held.
+func (s *Store) entities(serverID int) *Entities {
+	e, ok := s.servers[serverID]
+	if !ok {
+		e = &Entities{}
+		s.servers[serverID] = e
+	}
+	if e.Routes == nil {
+		e.Routes = make(map[string]Span)
+	}
+	if e.RealtimeFeeds == nil {
+		e.RealtimeFeeds = make(map[string]Span)
+	}
+	return e
+}
+
+// ObserveServer records that the server answered at at.
+func (s *Store) ObserveServer(serverID int, at time.Time) {
+	if s == nil {
+		return
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	e := s.entities(serverID)
+	if e.Server == nil {
+		e.Server = &Span{}
+	}
+	e.Server.observe(at.UTC())
+	s.dirty = true
+}
+
+// ObserveRoutes records that the routes routeIDs were in the GTFS bundle of the server at at.
+func (s *Store) ObserveRoutes(serverID int, routeIDs []string, at time.Time) {
+	if s == nil || len(routeIDs) == 0 {
+		return
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	e := s.entities(serverID)
+	for _, id := range routeIDs {
+		span := e.Routes[id]
+		span.observe(at.UTC())
+		e.Routes[id] = span
+	}
+	s.dirty = true
+}
+
+// ObserveRealtimeFeed records that the GTFS-RT feed of type feedType of the server was
+// fetched successfully at at.
+func (s *Store) ObserveRealtimeFeed(serverID int, feedType string, at time.Time) {
+	if s == nil {
+		return
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	e := s.entities(serverID)
+	span := e.RealtimeFeeds[feedType]
+	span.observe(at.UTC())
+	e.RealtimeFeeds[feedType] = span
+	s.dirty = true
+}
+
+// Get returns a copy of the observed entities of a server, and whether any was observed.
+func (s *Store) Get(serverID int) (Entities, bool) {
+	if s == nil {
+		return Entities{}, false
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	e, ok := s.servers[serverID]
+	if !ok {
+		return Entities{}, false
+	}
+	return e.clone(), true
+}
+
+// Save writes the entities to the file of the store if they changed since the last save,
+// after forgetting those not observed for Retention. A store without a file is not saved.
+func (s *Store) Save(now time.Time) error {
+	if s == nil || s.path == "" {
+		return nil
+	}
+	s.mu.Lock()
+	if !s.dirty {
+		s.mu.Unlock()
+		return nil
+	}
+	s.prune(now.Add(-Retention))
+	data, err := json.Marshal(file{Servers: s.servers})
+	s.dirty = false
+	s.mu.Unlock()
+	if err != nil {
+		return fmt.Errorf("failed to encode lifecycle file: %w", err)
+	}
+
+	tmp := filepath.Join(filepath.Dir(s.path), "."+filepath.Base(s.path)+".tmp")
+	if err := os.WriteFile(tmp, data, 0o640); err == nil {
+		err = os.Rename(tmp, s.path)
+	}
+	if err != nil {
+		_ = os.Remove(tmp)
+		s.mu.Lock()
+		s.dirty = true
+		s.mu.Unlock()
+		return fmt.Errorf("failed to write lifecycle file: %w", err)
+	}
+	return nil
+}
+
+// prune forgets the entities last observed before cutoff. s.mu must be held.
+func (s *Store) prune(cutoff time.Time) {
+	for id, e := range s.servers {
+		if e.Server != nil && e.Server.LastSeen.Before(cutoff) {
+			e.Server = nil
+		}
+		for route, span := range e.Routes {
+			if span.LastSeen.Before(cutoff) {
+				delete(e.Routes, route)
+			}
+		}
+		for feed, span := range e.RealtimeFeeds {
+			if span.LastSeen.Before(cutoff) {
+				delete(e.RealtimeFeeds, feed)
+			}
+		}
+		if e.Server == nil && len(e.Routes) == 0 && len(e.RealtimeFeeds) == 0 {
+			delete(s.servers, id)
+		}
+	}
+}
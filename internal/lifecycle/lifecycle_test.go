@@ -0,0 +1,117 @@
+package lifecycle
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func TestStoreObserve(t *testing.T) {
+	s, err := Open("")
+	if err != nil {
+		t.Fatal(err)
+	}
+	first := time.Date(2025, 6, 1, 12, 0, 0, 0, time.UTC)
+	later := first.Add(time.Hour)
+
+	s.ObserveServer(1, first)
+	s.ObserveServer(1, later)
+	s.ObserveRoutes(1, []string{"100", "200"}, first)
+	s.ObserveRoutes(1, []string{"100"}, later)
+	s.ObserveRealtimeFeed(1, "vehicle_positions", later)
+
+	e, ok := s.Get(1)
+	if !ok {
+		t.Fatal("expected the entities of server 1")
+	}
+	if e.Server == nil || !e.Server.FirstSeen.Equal(first) || !e.Server.LastSeen.Equal(later) {
+		t.Errorf("unexpected server span %+v", e.Server)
+	}
+	if span := e.Routes["100"]; !span.FirstSeen.Equal(first) || !span.LastSeen.Equal(later) {
+		t.Errorf("unexpected span of route 100 %+v", span)
+	}
+	if span := e.Routes["200"]; !span.LastSeen.Equal(first) {
+		t.Errorf("expected route 200 to be last seen when it left the bundle, got %+v", span)
+	}
+	if span := e.RealtimeFeeds["vehicle_positions"]; !span.FirstSeen.Equal(later) {
+		t.Errorf("unexpected feed span %+v", span)
+	}
+
+	// Get returns a copy.
+	e.Routes["300"] = Span{}
+	if e, _ := s.Get(1); len(e.Routes) != 2 {
+		t.Errorf("expected the store not to change through a copy, got %d routes", len(e.Routes))
+	}
+	if _, ok := s.Get(2); ok {
+		t.Error("expected no entities for an unobserved server")
+	}
+}
+
+func TestStoreSave(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "lifecycle.json")
+	s, err := Open(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	now := time.Date(2025, 6, 1, 12, 0, 0, 0, time.UTC)
+	s.ObserveServer(1, now)
+	s.ObserveRoutes(1, []string{"100"}, now)
+	s.ObserveRoutes(1, []string{"old"}, now.Add(-Retention-time.Hour))
+	s.ObserveServer(2, now.Add(-Retention-time.Hour))
+	if err := s.Save(now); err != nil {
+		t.Fatal(err)
+	}
+
+	reopened, err := Open(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	e, ok := reopened.Get(1)
+	if !ok || e.Server == nil || !e.Server.FirstSeen.Equal(now) || len(e.Routes) != 1 {
+		t.Errorf("expected the saved entities without the expired route, got %+v", e)
+	}
+	if _, ok := reopened.Get(2); ok {
+		t.Error("expected a server not seen for the retention period to be forgotten")
+	}
+
+	// Unchanged entities are not written again.
+	if err := os.Remove(path); err != nil {
+		t.Fatal(err)
+	}
+	if err := s.Save(now); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := os.Stat(path); !os.IsNotExist(err) {
+		t.Errorf("expected an unchanged store not to be saved, got %v", err)
+	}
+}
+
+func TestOpenInvalidFile(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "lifecycle.json")
+	if err := os.WriteFile(path, []byte("not json"), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	s, err := Open(path)
+	if err == nil {
+		t.Error("expected an error for an invalid file")
+	}
+	if s == nil {
+		t.Fatal("expected an empty store along with the error")
+	}
+	s.ObserveServer(1, time.Now())
+	if _, ok := s.Get(1); !ok {
+		t.Error("expected the empty store to record observations")
+	}
+}
+
+func TestNilStore(t *testing.T) {
+	var s *Store
+	s.ObserveServer(1, time.Now())
+	if _, ok := s.Get(1); ok {
+		t.Error("expected a nil store to record nothing")
+	}
+	if err := s.Save(time.Now()); err != nil {
+		t.Error(err)
+	}
+}
@@ -0,0 +1,125 @@
+// Package logbuffer keeps the most recent log records of each server in memory, so they can
+// be attached to diagnostics (see the snapshot endpoint) without access to the log output.
+package logbuffer
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"log/slog"
+	"strconv"
+	"sync"
+)
+
+// ServerIDKey is the attribute identifying the server a log record is about.
+const ServerIDKey = "server_id"
+
+// Buffer keeps the last records logged for each server, formatted as JSON lines.
+// It is safe for concurrent use; a nil *Buffer keeps nothing.
+type Buffer struct {
+	limit int
+
+	mu      sync.Mutex
+	records map[int][][]byte
+}
+
+// New creates a Buffer keeping the last limit records of each server.
+func New(limit int) *Buffer {
+	return &Buffer{limit: limit, records: make(map[int][][]byte)}
+}
+
+func (b *Buffer) add(serverID int, line []byte) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	lines := append(b.records[serverID], line)
+	if len(lines) > b.limit {
+		lines = append([][]byte(nil), lines[len(lines)-b.limit:]...)
+	}
+	b.records[serverID] = lines
+}
+
+// Records returns the kept records of a server as JSON lines, oldest first.
+func (b *Buffer) Records(serverID int) []byte {
+	if b == nil {
+		return nil
+	}
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return bytes.Join(b.records[serverID], nil)
+}
+
+// Handler is a slog.Handler passing records to another handler, and keeping the records with
+// a ServerIDKey attribute, or logged with a logger carrying one, in a Buffer.
+type Handler struct {
+	next   slog.Handler
+	buffer *Buffer
+	// scope replays the calls to WithAttrs and WithGroup on the handler formatting a record.
+	scope    []func(slog.Handler) slog.Handler
+	inGroup  bool
+	serverID string
+}
+
+// NewHandler returns a Handler passing records to next and keeping them in buffer.
+func NewHandler(next slog.Handler, buffer *Buffer) *Handler {
+	return &Handler{next: next, buffer: buffer}
+}
+
+func (h *Handler) Enabled(ctx context.Context, level slog.Level) bool {
+	return h.next.Enabled(ctx, level)
+}
+
+func (h *Handler) Handle(ctx context.Context, record slog.Record) error {
+	if h.buffer != nil {
+		h.keep(record)
+	}
+	return h.next.Handle(ctx, record)
+}
+
+// keep formats the record as a JSON line and adds it to the buffer if it is about a server.
+func (h *Handler) keep(record slog.Record) {
+	serverID := h.serverID
+	record.Attrs(func(attr slog.Attr) bool {
+		if attr.Key == ServerIDKey {
+			serverID = attr.Value.Resolve().String()
+			return false
+		}
+		return true
+	})
+	id, err := strconv.Atoi(serverID)
+	if err != nil {
+		return
+	}
+
+	var line bytes.Buffer
+	var formatter slog.Handler = slog.NewJSONHandler(&line, &slog.HandlerOptions{Level: slog.LevelDebug})
+	for _, apply := range h.scope {
+		formatter = apply(formatter)
+	}
+	if err := formatter.Handle(context.Background(), record); err != nil {
+		line.Reset()
+		fmt.Fprintf(&line, "{\"msg\":%q}\n", record.Message)
+	}
+	h.buffer.add(id, line.Bytes())
+}
+
+func (h *Handler) WithAttrs(attrs []slog.Attr) slog.Handler {
+	clone := *h
+	clone.next = h.next.WithAttrs(attrs)
+	if !h.inGroup {
+		for _, attr := range attrs {
+			if attr.Key == ServerIDKey {
+				clone.serverID = attr.Value.Resolve().String()
+			}
+		}
+	}
+	clone.scope = append(h.scope[:len(h.scope):len(h.scope)], func(f slog.Handler) slog.Handler { return f.WithAttrs(attrs) })
+	return &clone
+}
+
+func (h *Handler) WithGroup(name string) slog.Handler {
+	clone := *h
+	clone.next = h.next.WithGroup(name)
+	clone.inGroup = true
+	clone.scope = append(h.scope[:len(h.scope):len(h.scope)], func(f slog.Handler) slog.Handler { return f.WithGroup(name) })
+	return &clone
+}
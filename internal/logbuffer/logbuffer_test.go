@@ -0,0 +1,47 @@
+package logbuffer
+
+import (
+	"bytes"
+	"io"
+	"log/slog"
+	"strings"
+	"testing"
+)
+
+func TestHandler(t *testing.T) {
+	buffer := New(2)
+	var out bytes.Buffer
+	logger := slog.New(NewHandler(slog.NewTextHandler(&out, nil), buffer))
+
+	logger.Info("Starting")
+	logger.Info("Server ping failed", "server_id", 1)
+	logger.With("server_id", 2).Warn("Bundle expiring", "days", 3)
+	logger.WithGroup("request").Info("Nested", "server_id", 2)
+	logger.Info("Server ping successful", "server_id", "1")
+	logger.Info("Skipping", "server_id", 1)
+
+	if strings.Count(out.String(), "\n") != 6 {
+		t.Errorf("expected every record to reach the next handler, got %q", out.String())
+	}
+
+	records := strings.Split(strings.TrimSpace(string(buffer.Records(1))), "\n")
+	if len(records) != 2 || !strings.Contains(records[0], `"msg":"Server ping successful"`) || !strings.Contains(records[1], `"msg":"Skipping"`) {
+		t.Errorf("expected the last 2 records of server 1, got %q", records)
+	}
+	records = strings.Split(strings.TrimSpace(string(buffer.Records(2))), "\n")
+	if len(records) != 2 || !strings.Contains(records[0], `"server_id":2`) || !strings.Contains(records[0], `"days":3`) {
+		t.Errorf("expected the records of server 2 with their logger attributes, got %q", records)
+	}
+	if !strings.Contains(records[1], `"request":{"server_id":2}`) {
+		t.Errorf("expected grouped attributes to be kept in their group, got %q", records[1])
+	}
+	if got := buffer.Records(3); len(got) != 0 {
+		t.Errorf("expected no records for an unknown server, got %q", got)
+	}
+
+	var nilBuffer *Buffer
+	slog.New(NewHandler(slog.NewTextHandler(io.Discard, nil), nilBuffer)).Info("ignored", "server_id", 1)
+	if got := nilBuffer.Records(1); got != nil {
+		t.Errorf("expected a nil buffer to keep nothing, got %q", got)
+	}
+}
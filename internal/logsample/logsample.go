@@ -0,0 +1,229 @@
+// Package logsample bounds the volume of repetitive warning and error logs: a server that is
+// down for a weekend logs the same error in every collection cycle. The first occurrences of
+// an error are logged as usual, then the following ones are only counted, and a summary is
+// logged once per window ("Suppressed similar log records", with the count).
+package logsample
+
+import (
+	"context"
+	"log/slog"
+	"sync"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promauto"
+	"watchdog.onebusaway.org/internal/logbuffer"
+	"watchdog.onebusaway.org/internal/scheduler"
+)
+
+// SuppressedRecords counts the log records that were not written, by level.
+var SuppressedRecords = promauto.NewCounterVec(
+	prometheus.CounterOpts{
+		Name: "watchdog_log_records_suppressed_total",
+		Help: "Repetitive warning and error log records that were counted in a summary instead of being written, by level",
+	},
+	[]string{"level"},
+)
+
+// maxEntries bounds the number of distinct records tracked; records beyond it are not sampled.
+const maxEntries = 10000
+
+// key identifies similar records: records of the same level and message about the same server.
+type key struct {
+	level    slog.Level
+	message  string
+	serverID string
+}
+
+// entry is the state of a kind of similar records.
+type entry struct {
+	// logged is the number of records written since the records started.
+	logged int
+	// suppressed is the number of records counted since windowStart.
+	suppressed  int
+	windowStart time.Time
+	lastSeen    time.Time
+	// next writes the summary, with the attributes of the logger of the last suppressed record.
+	next slog.Handler
+	// serverIDAttr is whether the server id is an attribute of the records rather than of their
+	// logger, in which case it is added to the summary.
+	serverIDAttr bool
+}
+
+// Sampler keeps track of the warning and error records logged through its Handlers. It is safe
+// for concurrent use.
+type Sampler struct {
+	first  int
+	window time.Duration
+	now    func() time.Time
+
+	mu      sync.Mutex
+	entries map[key]*entry
+}
+
+// New returns a Sampler writing the first records of each kind, then a summary of the similar
+// records of every window. A kind of records is forgotten after a window without any, so that
+// a new outage is logged again.
+func New(first int, window time.Duration) *Sampler {
+	return &Sampler{first: first, window: window, now: time.Now, entries: make(map[key]*entry)}
+}
+
+// summary is a pending summary of suppressed records.
+type summary struct {
+	key   key
+	entry entry
+}
+
+// write logs the summary through the handler of its last suppressed record.
+func (s summary) write(ctx context.Context, at time.Time) {
+	record := slog.NewRecord(at, s.key.level, "Suppressed similar log records", 0)
+	record.AddAttrs(
+		slog.String("message", s.key.message),
+		slog.Int("suppressed", s.entry.suppressed),
+		slog.Duration("period", s.entry.lastSeen.Sub(s.entry.windowStart).Round(time.Second)),
+	)
+	if s.entry.serverIDAttr {
+		record.AddAttrs(slog.String(logbuffer.ServerIDKey, s.key.serverID))
+	}
+	_ = s.entry.next.Handle(ctx, record)
+}
+
+// allow reports whether a record of kind k is written, and returns the summary of the previous
+// window of the kind if it is over.
+func (s *Sampler) allow(k key, next slog.Handler, serverIDAttr bool) (bool, *summary) {
+	now := s.now()
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	e, ok := s.entries[k]
+	if !ok {
+		if len(s.entries) >= maxEntries {
+			return true, nil
+		}
+		e = &entry{windowStart: now}
+		s.entries[k] = e
+	}
+	var pending *summary
+	if now.Sub(e.lastSeen) >= s.window && ok {
+		// The records stopped for a whole window: this is a new occurrence.
+		pending = s.close(k, e, now)
+		*e = entry{windowStart: now}
+	} else if now.Sub(e.windowStart) >= s.window {
+		pending = s.close(k, e, now)
+	}
+	e.lastSeen = now
+	if e.logged < s.first {
+		e.logged++
+		return true, pending
+	}
+	e.suppressed++
+	e.next = next
+	e.serverIDAttr = serverIDAttr
+	return false, pending
+}
+
+// close ends the window of e and returns its summary, or nil if no record was suppressed.
+func (s *Sampler) close(k key, e *entry, now time.Time) *summary {
+	var pending *summary
+	if e.suppressed > 0 {
+		pending = &summary{key: k, entry: *e}
+	}
+	e.suppressed = 0
+	e.windowStart = now
+	return pending
+}
+
+// Flush logs the summaries of the windows that are over, and forgets the kinds of records that
+// were not seen during a whole window. With all, every pending summary is logged, e.g. on
+// shutdown.
+func (s *Sampler) Flush(all bool) {
+	now := s.now()
+	var pending []*summary
+	s.mu.Lock()
+	for k, e := range s.entries {
+		quiet := now.Sub(e.lastSeen) >= s.window
+		if all || quiet || now.Sub(e.windowStart) >= s.window {
+			if summary := s.close(k, e, now); summary != nil {
+				pending = append(pending, summary)
+			}
+		}
+		if quiet {
+			delete(s.entries, k)
+		}
+	}
+	s.mu.Unlock()
+	for _, summary := range pending {
+		summary.write(context.Background(), now)
+	}
+}
+
+// Run logs the summaries of the windows that are over every minute until ctx is canceled, then
+// logs the remaining ones.
+func (s *Sampler) Run(ctx context.Context) {
+	scheduler.Run(ctx, "log_sampler", scheduler.Every(time.Minute), func() { s.Flush(false) })
+	s.Flush(true)
+}
+
+// Handler is a slog.Handler passing records to another handler, except for the warning and
+// error records its Sampler suppresses. Records are similar if they have the same level and
+// message, and the same ServerIDKey attribute, of the record or of the logger.
+type Handler struct {
+	next     slog.Handler
+	sampler  *Sampler
+	inGroup  bool
+	serverID string
+}
+
+// NewHandler returns a Handler passing the records sampler does not suppress to next. A nil
+// sampler passes every record.
+func NewHandler(next slog.Handler, sampler *Sampler) *Handler {
+	return &Handler{next: next, sampler: sampler}
+}
+
+func (h *Handler) Enabled(ctx context.Context, level slog.Level) bool {
+	return h.next.Enabled(ctx, level)
+}
+
+func (h *Handler) Handle(ctx context.Context, record slog.Record) error {
+	if h.sampler == nil || record.Level < slog.LevelWarn {
+		return h.next.Handle(ctx, record)
+	}
+	k := key{level: record.Level, message: record.Message, serverID: h.serverID}
+	serverIDAttr := false
+	record.Attrs(func(attr slog.Attr) bool {
+		if attr.Key == logbuffer.ServerIDKey {
+			k.serverID = attr.Value.Resolve().String()
+			serverIDAttr = true
+			return false
+		}
+		return true
+	})
+	allowed, pending := h.sampler.allow(k, h.next, serverIDAttr)
+	if pending != nil {
+		pending.write(ctx, record.Time)
+	}
+	if !allowed {
+		SuppressedRecords.WithLabelValues(record.Level.String()).Inc()
+		return nil
+	}
+	return h.next.Handle(ctx, record)
+}
+
+func (h *Handler) WithAttrs(attrs []slog.Attr) slog.Handler {
+	clone := *h
+	clone.next = h.next.WithAttrs(attrs)
+	if !h.inGroup {
+		for _, attr := range attrs {
+			if attr.Key == logbuffer.ServerIDKey {
+				clone.serverID = attr.Value.Resolve().String()
+			}
+		}
+	}
+	return &clone
+}
+
+func (h *Handler) WithGroup(name string) slog.Handler {
+	clone := *h
+	clone.next = h.next.WithGroup(name)
+	clone.inGroup = true
+	return &clone
+}
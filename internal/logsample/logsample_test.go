@@ -0,0 +1,73 @@
+package logsample
+
+import (
+	"bytes"
+	"log/slog"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestHandler(t *testing.T) {
+	now := time.Date(2025, 6, 7, 0, 0, 0, 0, time.UTC)
+	sampler := New(2, time.Hour)
+	sampler.now = func() time.Time { return now }
+	var out bytes.Buffer
+	logger := slog.New(NewHandler(slog.NewTextHandler(&out, &slog.HandlerOptions{
+		ReplaceAttr: func(_ []string, attr slog.Attr) slog.Attr {
+			if attr.Key == slog.TimeKey {
+				return slog.Attr{}
+			}
+			return attr
+		},
+	}), sampler))
+	lines := func() []string {
+		defer out.Reset()
+		return strings.Split(strings.TrimSpace(out.String()), "\n")
+	}
+
+	// A weekend outage: server 1 fails every 30 seconds.
+	for i := 0; i < 10; i++ {
+		logger.Error("Server ping failed", "server_id", 1, "attempt", i)
+		logger.Info("Collected metrics", "server_id", 1)
+		now = now.Add(30 * time.Second)
+	}
+	logger.With("server_id", 2).Error("Server ping failed")
+	got := lines()
+	if len(got) != 13 || strings.Count(out.String(), "attempt=2") != 0 {
+		t.Fatalf("expected the first 2 errors, every info record and the error of server 2, got %q", got)
+	}
+
+	// After an hour, the suppressed records are summed up, and still suppressed.
+	for i := 0; i < 120; i++ {
+		logger.Error("Server ping failed", "server_id", 1)
+		now = now.Add(30 * time.Second)
+	}
+	got = lines()
+	if len(got) != 1 || !strings.Contains(got[0], `level=ERROR msg="Suppressed similar log records" message="Server ping failed" suppressed=`) || !strings.Contains(got[0], "server_id=1") {
+		t.Fatalf("expected a summary, got %q", got)
+	}
+
+	// Once the errors stopped for a window, the remaining ones are summed up and the next
+	// outage is logged again.
+	now = now.Add(2 * time.Hour)
+	sampler.Flush(false)
+	if got := lines(); len(got) != 1 || !strings.Contains(got[0], "Suppressed similar log records") {
+		t.Fatalf("expected the last summary, got %q", got)
+	}
+	logger.Error("Server ping failed", "server_id", 1)
+	if got := lines(); len(got) != 1 || !strings.Contains(got[0], `msg="Server ping failed"`) {
+		t.Errorf("expected a new outage to be logged, got %q", got)
+	}
+}
+
+func TestHandlerWithoutSampler(t *testing.T) {
+	var out bytes.Buffer
+	logger := slog.New(NewHandler(slog.NewTextHandler(&out, nil), nil))
+	for i := 0; i < 5; i++ {
+		logger.Error("Server ping failed", "server_id", 1)
+	}
+	if got := strings.Count(out.String(), "\n"); got != 5 {
+		t.Errorf("expected every record without a sampler, got %d", got)
+	}
+}
@@ -4,11 +4,12 @@ import (
 	"context"
 	"fmt"
 	"log/slog"
+	"net/http"
 	"strconv"
 
-	onebusaway "github.com/OneBusAway/go-sdk"
-	"github.com/OneBusAway/go-sdk/option"
+	remoteGtfs "github.com/OneBusAway/go-gtfs"
 	"github.com/getsentry/sentry-go"
+	"github.com/prometheus/client_golang/prometheus"
 	"watchdog.onebusaway.org/internal/gtfs"
 	"watchdog.onebusaway.org/internal/models"
 	"watchdog.onebusaway.org/internal/report"
@@ -20,7 +21,7 @@ import (
 //
 // Returns the agency count if the bundle is present and valid.
 // Returns an error if the bundle is missing, nil, or contains no agencies.
-func checkAgenciesWithCoverage(staticStore *gtfs.StaticStore, server models.ObaServer) (int, error) {
+func checkAgenciesWithCoverage(staticStore gtfs.StaticStore, server models.ObaServer) (int, error) {
 	staticData, ok := staticStore.Get(server.ID)
 	if !ok {
 		err := fmt.Errorf("there is no bundle for server %v", server.ID)
@@ -64,11 +65,15 @@ func checkAgenciesWithCoverage(staticStore *gtfs.StaticStore, server models.ObaS
 //
 // Returns the number of real-time agencies on success.
 // Returns an error if the API call fails or the response is invalid.
-func getAgenciesWithCoverage(server models.ObaServer) (int, error) {
-	client := onebusaway.NewClient(
-		option.WithAPIKey(server.ObaApiKey),
-		option.WithBaseURL(server.ObaBaseURL),
-	)
+func getAgenciesWithCoverage(server models.ObaServer, httpClient *http.Client) (int, error) {
+	agencyIDs, err := listAgenciesWithCoverage(server, httpClient)
+	return len(agencyIDs), err
+}
+
+// listAgenciesWithCoverage returns the IDs of the agencies of the OBA `agencies-with-coverage`
+// endpoint of the given server, and reports their number as getAgenciesWithCoverage does.
+func listAgenciesWithCoverage(server models.ObaServer, httpClient *http.Client) ([]string, error) {
+	client := newObaClient(server, httpClient)
 
 	ctx := context.Background()
 
@@ -81,43 +86,92 @@ func getAgenciesWithCoverage(server models.ObaServer) (int, error) {
 				"oba_base_url": server.ObaBaseURL,
 			},
 		})
-		return 0, err
+		return nil, err
 	}
 
 	if response == nil {
-		return 0, nil
+		return nil, nil
 	}
 
 	AgenciesInCoverageEndpoint.WithLabelValues(
 		strconv.Itoa(server.ID),
 	).Set(float64(len(response.Data.List)))
 
-	return len(response.Data.List), nil
+	agencyIDs := make([]string, len(response.Data.List))
+	for i, agency := range response.Data.List {
+		agencyIDs[i] = agency.AgencyID
+	}
+	return agencyIDs, nil
 }
 
 // checkAgenciesWithCoverageMatch compares the number of agencies in the GTFS static bundle
 // with the number of agencies returned by the real-time `agencies-with-coverage` API for the given server.
-// It sets the AgenciesCoverageMatch Prometheus metric to 1 if the counts match, or 0 if they differ.
+// It sets the AgenciesCoverageMatch Prometheus metric to 1 if the counts match, or 0 if they differ,
+// and breaks the comparison down by agency (see recordAgencyCoverage).
 //
 // Returns an error if reading the static bundle or calling the API fails.
-func checkAgenciesWithCoverageMatch(staticStore *gtfs.StaticStore, logger *slog.Logger, server models.ObaServer) error {
+func checkAgenciesWithCoverageMatch(staticStore gtfs.StaticStore, logger *slog.Logger, server models.ObaServer, client *http.Client) error {
 	staticGtfsAgenciesCount, err := checkAgenciesWithCoverage(staticStore, server)
 	if err != nil {
 		return err
 	}
 
-	coverageAgenciesCount, err := getAgenciesWithCoverage(server)
+	coverageAgencyIDs, err := listAgenciesWithCoverage(server, client)
 
 	if err != nil {
 		return fmt.Errorf("error getting remote agencies with coverage data: %w", err)
 	}
 
 	matchValue := 0
-	if coverageAgenciesCount == staticGtfsAgenciesCount {
+	if len(coverageAgencyIDs) == staticGtfsAgenciesCount {
 		matchValue = 1
 	}
 
 	AgenciesMatch.WithLabelValues(strconv.Itoa(server.ID)).Set(float64(matchValue))
 
+	staticData, _ := staticStore.Get(server.ID)
+	recordAgencyCoverage(server, staticData.Agencies, coverageAgencyIDs)
+
 	return nil
 }
+
+// recordAgencyCoverage sets, for every agency of the static GTFS bundle or the
+// agencies-with-coverage endpoint of a server, whether it is in the bundle, in the endpoint,
+// and in both, so that a multi-agency region shows which agency's data is missing rather
+// than a count mismatch. The agencies that are no longer in either are dropped.
+//
+// OBA agency IDs are the GTFS agency IDs, except for a bundle with a single agency and a
+// configured server.AgencyID, as for routes (see checkRoutesMatch).
+func recordAgencyCoverage(server models.ObaServer, agencies []remoteGtfs.Agency, coverageAgencyIDs []string) {
+	inStatic := make(map[string]bool, len(agencies))
+	for _, agency := range agencies {
+		agencyID := agency.Id
+		if len(agencies) == 1 && server.AgencyID != "" {
+			agencyID = server.AgencyID
+		}
+		inStatic[agencyID] = true
+	}
+	inCoverage := make(map[string]bool, len(coverageAgencyIDs))
+	for _, agencyID := range coverageAgencyIDs {
+		inCoverage[agencyID] = true
+	}
+
+	value := func(present bool) float64 {
+		if present {
+			return 1
+		}
+		return 0
+	}
+	serverID := strconv.Itoa(server.ID)
+	labels := prometheus.Labels{"server_id": serverID}
+	AgencyInStaticGtfs.DeletePartialMatch(labels)
+	AgencyInCoverageEndpoint.DeletePartialMatch(labels)
+	AgencyMatch.DeletePartialMatch(labels)
+	for _, ids := range []map[string]bool{inStatic, inCoverage} {
+		for agencyID := range ids {
+			AgencyInStaticGtfs.WithLabelValues(serverID, agencyID).Set(value(inStatic[agencyID]))
+			AgencyInCoverageEndpoint.WithLabelValues(serverID, agencyID).Set(value(inCoverage[agencyID]))
+			AgencyMatch.WithLabelValues(serverID, agencyID).Set(value(inStatic[agencyID] && inCoverage[agencyID]))
+		}
+	}
+}
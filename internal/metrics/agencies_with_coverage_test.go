@@ -7,6 +7,8 @@ import (
 	"testing"
 
 	remoteGtfs "github.com/OneBusAway/go-gtfs"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/testutil"
 	"watchdog.onebusaway.org/internal/gtfs"
 	"watchdog.onebusaway.org/internal/models"
 )
@@ -31,7 +33,7 @@ func TestCheckAgenciesWithCoverage(t *testing.T) {
 		staticStore := gtfs.NewStaticStore()
 		staticStore.Set(testServer.ID, staticData)
 
-		err = checkAgenciesWithCoverageMatch(staticStore, logger, testServer)
+		err = checkAgenciesWithCoverageMatch(staticStore, logger, testServer, nil)
 		if err != nil {
 			t.Fatalf("CheckAgenciesWithCoverageMatch failed: %v", err)
 		}
@@ -60,7 +62,7 @@ func TestGetAgenciesWithCoverage(t *testing.T) {
 			ObaApiKey:  "test-key",
 		}
 
-		count, err := getAgenciesWithCoverage(server)
+		count, err := getAgenciesWithCoverage(server, nil)
 		if err != nil {
 			t.Fatalf("Expected no error, got %v", err)
 		}
@@ -81,7 +83,7 @@ func TestGetAgenciesWithCoverage(t *testing.T) {
 			ObaApiKey:  "test-key",
 		}
 
-		count, err := getAgenciesWithCoverage(server)
+		count, err := getAgenciesWithCoverage(server, nil)
 		if err != nil {
 			t.Fatalf("Expected no error, got %v", err)
 		}
@@ -102,9 +104,42 @@ func TestGetAgenciesWithCoverage(t *testing.T) {
 			ObaApiKey:  "test-key",
 		}
 
-		_, err := getAgenciesWithCoverage(server)
+		_, err := getAgenciesWithCoverage(server, nil)
 		if err == nil {
 			t.Fatal("Expected an error but got nil")
 		}
 	})
 }
+
+func TestRecordAgencyCoverage(t *testing.T) {
+	server := models.ObaServer{ID: 74}
+	agencies := []remoteGtfs.Agency{{Id: "metro"}, {Id: "ferry"}}
+
+	recordAgencyCoverage(server, agencies, []string{"metro", "old"})
+	recordAgencyCoverage(server, agencies, []string{"metro", "streetcar"})
+
+	for agencyID, want := range map[string][3]float64{
+		"metro":     {1, 1, 1},
+		"ferry":     {1, 0, 0},
+		"streetcar": {0, 1, 0},
+	} {
+		labels := prometheus.Labels{"server_id": "74", "agency_id": agencyID}
+		got := [3]float64{
+			testutil.ToFloat64(AgencyInStaticGtfs.With(labels)),
+			testutil.ToFloat64(AgencyInCoverageEndpoint.With(labels)),
+			testutil.ToFloat64(AgencyMatch.With(labels)),
+		}
+		if got != want {
+			t.Errorf("agency %s: expected in static, in coverage, match = %v, got %v", agencyID, want, got)
+		}
+	}
+	if AgencyMatch.DeleteLabelValues("74", "old") {
+		t.Error("expected the agency no longer in the coverage endpoint to be dropped")
+	}
+
+	// A single-agency bundle is matched with the configured OBA agency ID.
+	recordAgencyCoverage(models.ObaServer{ID: 75, AgencyID: "1"}, []remoteGtfs.Agency{{Id: "40"}}, []string{"1"})
+	if match := testutil.ToFloat64(AgencyMatch.WithLabelValues("75", "1")); match != 1 {
+		t.Errorf("expected the configured agency ID to match, got %v", match)
+	}
+}
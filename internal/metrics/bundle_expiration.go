@@ -2,10 +2,14 @@ package metrics
 
 import (
 	"fmt"
+	"slices"
 	"strconv"
+	"strings"
+	"sync"
 	"time"
 
 	"github.com/getsentry/sentry-go"
+	"github.com/prometheus/client_golang/prometheus"
 	"watchdog.onebusaway.org/internal/gtfs"
 	"watchdog.onebusaway.org/internal/models"
 	"watchdog.onebusaway.org/internal/report"
@@ -28,7 +32,7 @@ import (
 //   - int: days until the earliest service end date.
 //   - int: days until the latest service end date.
 //   - error: any error encountered during processing.
-func checkBundleExpiration(staticStore *gtfs.StaticStore, currentTime time.Time, server models.ObaServer) (int, int, error) {
+func checkBundleExpiration(staticStore gtfs.StaticStore, currentTime time.Time, server models.ObaServer) (int, int, error) {
 	currentTime = currentTime.UTC()
 	staticData, ok := staticStore.Get(server.ID)
 	if !ok {
@@ -65,3 +69,91 @@ func checkBundleExpiration(staticStore *gtfs.StaticStore, currentTime time.Time,
 
 	return daysUntilEarliestExpiration, daysUntilLatestExpiration, nil
 }
+
+// checkFeedInfo exports the validity window and version declared in the feed_info.txt of the
+// GTFS bundle associated with a given server.
+//
+// Unlike checkBundleExpiration, which infers expiration from calendar.txt, this uses the
+// feed_end_date published by the agency. It sets:
+//   - gtfs_feed_end_date_days_remaining: days until feed_end_date (only if the feed declares one).
+//   - gtfs_feed_info: an info metric (always 1) labeled with feed_version and feed_publisher_name.
+//   - gtfs_license_info: an info metric (always 1) labeled with the publisher and the organizations
+//     credited in attribution.txt, joined with ", ".
+//
+// The series are updated in place. An info series is only deleted when its labels change, so a
+// new feed_version does not leave a stale series behind, and the remaining days series is only
+// deleted when the feed no longer declares an end date. A bundle without feed_info.txt is not an
+// error; its series are just removed, and the license info is only exported if it has an
+// attribution.txt.
+//
+// Parameters:
+//   - staticStore: a pointer to StaticStore that holds GTFS data for multiple servers.
+//   - currentTime: the current time used to calculate the days remaining (converted to UTC).
+//   - server: the ObaServer whose feed info should be exported.
+//
+// Returns:
+//   - error: if there is no static data for the server. It is not reported to Sentry, since
+//     checkBundleExpiration already reports a missing bundle every cycle.
+func checkFeedInfo(staticStore gtfs.StaticStore, currentTime time.Time, server models.ObaServer) error {
+	serverID := strconv.Itoa(server.ID)
+	staticData, ok := staticStore.Get(server.ID)
+	if !ok || staticData == nil {
+		return fmt.Errorf("there is no bundle for server %v", server.ID)
+	}
+
+	var licenseLabels []string
+	if license := models.NewLicense(staticData.FeedInfo, staticData.Attributions); !license.IsZero() {
+		organizations := make([]string, len(license.Attributions))
+		for i, attribution := range license.Attributions {
+			organizations[i] = attribution.OrganizationName
+		}
+		licenseLabels = []string{serverID, license.PublisherName, license.PublisherURL, strings.Join(organizations, ", ")}
+	}
+	licenseInfoSeries.set(serverID, licenseLabels)
+
+	feedInfo := staticData.FeedInfo
+	if feedInfo == nil {
+		feedInfoSeries.set(serverID, nil)
+		FeedEndDateDaysRemainingGauge.DeleteLabelValues(serverID)
+		return nil
+	}
+
+	feedInfoSeries.set(serverID, []string{serverID, feedInfo.Version, feedInfo.PublisherName})
+	if feedInfo.EndDate.IsZero() {
+		FeedEndDateDaysRemainingGauge.DeleteLabelValues(serverID)
+		return nil
+	}
+	daysRemaining := int(feedInfo.EndDate.Sub(currentTime.UTC()).Hours() / 24)
+	FeedEndDateDaysRemainingGauge.WithLabelValues(serverID).Set(float64(daysRemaining))
+	return nil
+}
+
+// infoSeries tracks the labels of the info series (always 1) exported for each server, so a
+// series is only deleted when the labels it was exported with change.
+type infoSeries struct {
+	gauge  *prometheus.GaugeVec
+	mu     sync.Mutex
+	labels map[string][]string
+}
+
+var (
+	feedInfoSeries    = &infoSeries{gauge: FeedInfoGauge, labels: make(map[string][]string)}
+	licenseInfoSeries = &infoSeries{gauge: LicenseInfoGauge, labels: make(map[string][]string)}
+)
+
+// set exports the series of a server with the given label values, deleting the one it replaces.
+// Nil label values remove the series of the server.
+func (s *infoSeries) set(serverID string, labels []string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if previous, ok := s.labels[serverID]; ok && !slices.Equal(previous, labels) {
+		s.gauge.DeleteLabelValues(previous...)
+		delete(s.labels, serverID)
+	}
+	if labels == nil {
+		return
+	}
+	s.gauge.WithLabelValues(labels...).Set(1)
+	s.labels[serverID] = labels
+}
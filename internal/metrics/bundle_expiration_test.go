@@ -54,3 +54,138 @@ func TestCheckBundleExpiration(t *testing.T) {
 		t.Errorf("Expected latest expiration metric to be %v, got %v", expectedLatest, latestMetric)
 	}
 }
+
+func TestCheckFeedInfo(t *testing.T) {
+	testServer := createTestServer("www.example.com", "Test Server", 996, "", "www.example.com", "test-api-value", "test-api-key", "1")
+	staticStore := gtfs.NewStaticStore()
+	fixedTime := time.Date(2025, 1, 12, 20, 16, 38, 0, time.UTC)
+
+	if err := checkFeedInfo(staticStore, fixedTime, testServer); err == nil {
+		t.Error("expected an error when there is no bundle for the server")
+	}
+
+	staticStore.Set(testServer.ID, &models.StaticData{
+		FeedInfo: &models.FeedInfo{
+			PublisherName: "Sound Transit",
+			PublisherURL:  "https://www.soundtransit.org",
+			Version:       "SC-Fall-2024.11",
+			EndDate:       time.Date(2025, 3, 28, 0, 0, 0, 0, time.UTC),
+		},
+		Attributions: []models.Attribution{{OrganizationName: "King County Metro"}, {OrganizationName: "Sound Transit"}},
+	})
+	if err := checkFeedInfo(staticStore, fixedTime, testServer); err != nil {
+		t.Fatalf("checkFeedInfo failed: %v", err)
+	}
+
+	expectedDays := float64(int(time.Date(2025, 3, 28, 0, 0, 0, 0, time.UTC).Sub(fixedTime).Hours() / 24))
+	days, err := getMetricValue(FeedEndDateDaysRemainingGauge, map[string]string{"server_id": "996"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if days != expectedDays {
+		t.Errorf("expected %v days remaining, got %v", expectedDays, days)
+	}
+
+	info, err := getMetricValue(FeedInfoGauge, map[string]string{
+		"server_id":           "996",
+		"feed_version":        "SC-Fall-2024.11",
+		"feed_publisher_name": "Sound Transit",
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if info != 1 {
+		t.Errorf("expected gtfs_feed_info to be 1, got %v", info)
+	}
+	license, err := getMetricValue(LicenseInfoGauge, map[string]string{
+		"server_id":      "996",
+		"publisher_name": "Sound Transit",
+		"publisher_url":  "https://www.soundtransit.org",
+		"attributions":   "King County Metro, Sound Transit",
+	})
+	if err != nil || license != 1 {
+		t.Errorf("expected gtfs_license_info to be 1, got %v (%v)", license, err)
+	}
+
+	// A new feed_version replaces the previous series, and the other series are kept.
+	staticStore.Set(testServer.ID, &models.StaticData{
+		FeedInfo: &models.FeedInfo{
+			PublisherName: "Sound Transit",
+			PublisherURL:  "https://www.soundtransit.org",
+			Version:       "SC-Winter-2025.01",
+			EndDate:       time.Date(2025, 3, 28, 0, 0, 0, 0, time.UTC),
+		},
+		Attributions: []models.Attribution{{OrganizationName: "King County Metro"}, {OrganizationName: "Sound Transit"}},
+	})
+	if err := checkFeedInfo(staticStore, fixedTime, testServer); err != nil {
+		t.Fatalf("checkFeedInfo failed: %v", err)
+	}
+	if FeedInfoGauge.DeleteLabelValues("996", "SC-Fall-2024.11", "Sound Transit") {
+		t.Error("expected the gtfs_feed_info series of the previous feed_version to be removed")
+	}
+	info, err = getMetricValue(FeedInfoGauge, map[string]string{
+		"server_id":           "996",
+		"feed_version":        "SC-Winter-2025.01",
+		"feed_publisher_name": "Sound Transit",
+	})
+	if err != nil || info != 1 {
+		t.Errorf("expected gtfs_feed_info of the new feed_version to be 1, got %v (%v)", info, err)
+	}
+	license, err = getMetricValue(LicenseInfoGauge, map[string]string{
+		"server_id":      "996",
+		"publisher_name": "Sound Transit",
+		"publisher_url":  "https://www.soundtransit.org",
+		"attributions":   "King County Metro, Sound Transit",
+	})
+	if err != nil || license != 1 {
+		t.Errorf("expected the unchanged gtfs_license_info to be kept, got %v (%v)", license, err)
+	}
+
+	// A new bundle without feed_info.txt removes the previous series.
+	staticStore.Set(testServer.ID, &models.StaticData{})
+	if err := checkFeedInfo(staticStore, fixedTime, testServer); err != nil {
+		t.Fatalf("checkFeedInfo failed: %v", err)
+	}
+	if removed := FeedInfoGauge.DeletePartialMatch(map[string]string{"server_id": "996"}); removed != 0 {
+		t.Errorf("expected stale gtfs_feed_info series to be removed, found %d", removed)
+	}
+	if removed := LicenseInfoGauge.DeletePartialMatch(map[string]string{"server_id": "996"}); removed != 0 {
+		t.Errorf("expected stale gtfs_license_info series to be removed, found %d", removed)
+	}
+}
+
+// fakeStaticStore is a StaticStore holding the data of a single server.
+type fakeStaticStore struct {
+	serverID int
+	data     *models.StaticData
+}
+
+func (s *fakeStaticStore) Set(serverID int, data *models.StaticData) {
+	s.serverID, s.data = serverID, data
+}
+
+func (s *fakeStaticStore) Get(serverID int) (*models.StaticData, bool) {
+	return s.data, s.data != nil && serverID == s.serverID
+}
+
+func (s *fakeStaticStore) Len() int {
+	if s.data == nil {
+		return 0
+	}
+	return 1
+}
+
+func TestCheckFeedInfoWithOtherStore(t *testing.T) {
+	testServer := createTestServer("www.example.com", "Test Server", 995, "", "www.example.com", "test-api-value", "test-api-key", "1")
+	store := &fakeStaticStore{}
+	fixedTime := time.Date(2025, 1, 12, 20, 16, 38, 0, time.UTC)
+
+	store.Set(testServer.ID, &models.StaticData{FeedInfo: &models.FeedInfo{EndDate: fixedTime.Add(48 * time.Hour)}})
+	if err := checkFeedInfo(store, fixedTime, testServer); err != nil {
+		t.Fatalf("checkFeedInfo failed: %v", err)
+	}
+	days, err := getMetricValue(FeedEndDateDaysRemainingGauge, map[string]string{"server_id": "995"})
+	if err != nil || days != 2 {
+		t.Errorf("expected 2 days remaining, got %v (%v)", days, err)
+	}
+}
@@ -0,0 +1,255 @@
+package metrics
+
+import (
+	"strconv"
+	"sync"
+	"time"
+)
+
+// Names of the checks run for each server in a collection cycle, as recorded in CheckResultStore.
+const (
+	CheckServerPing           = "server_ping"
+	CheckBundleExpiration     = "bundle_expiration"
+	CheckFeedInfo             = "feed_info"
+	CheckAgenciesWithCoverage = "agencies_with_coverage"
+	CheckStopsMatch           = "stops_match"
+	CheckRoutesMatch          = "routes_match"
+	CheckObaAPI               = "oba_api"
+	CheckRealtimeFeed         = "gtfs_rt_feed"
+	CheckVehicleCount         = "vehicle_count"
+	CheckIngestionLag         = "ingestion_lag"
+	CheckTripCoverage         = "trip_coverage"
+	CheckVehicleTelemetry     = "vehicle_telemetry"
+	CheckInvalidVehicles      = "invalid_vehicles"
+	CheckPredictionAccuracy   = "prediction_accuracy"
+	CheckReportProblem        = "report_problem"
+	// The checks of the entries of type "url" (see checkURLTarget).
+	CheckURLStatus  = "url_status"
+	CheckURLKeyword = "url_keyword"
+	CheckURLLatency = "url_latency"
+)
+
+// MeasuredChecks are the checks that measure a value along with their result: the days until
+// the GTFS bundle expires, the age in seconds of the newest vehicle position of the GTFS-RT feed,
+// and the share of the vehicles of the feed returned by the OBA API. The other checks only pass
+// or fail.
+var MeasuredChecks = []string{CheckBundleExpiration, CheckRealtimeFeed, CheckVehicleCount}
+
+// CheckNames lists the checks recorded in CheckResultStore, in the order they run.
+var CheckNames = []string{
+	CheckServerPing,
+	CheckBundleExpiration,
+	CheckFeedInfo,
+	CheckAgenciesWithCoverage,
+	CheckStopsMatch,
+	CheckRoutesMatch,
+	CheckObaAPI,
+	CheckRealtimeFeed,
+	CheckVehicleCount,
+	CheckIngestionLag,
+	CheckTripCoverage,
+	CheckVehicleTelemetry,
+	CheckInvalidVehicles,
+	CheckPredictionAccuracy,
+	CheckReportProblem,
+	CheckURLStatus,
+	CheckURLKeyword,
+	CheckURLLatency,
+}
+
+// DataQualityChecks are the checks whose failures point to problems in the data published by
+// the agency, e.g. a GTFS bundle that does not match what the OBA API serves, rather than in
+// the infrastructure running it. Their findings are sent to the agency contact of the server
+// (see alert.AgencyDigest).
+var DataQualityChecks = []string{
+	CheckStopsMatch,
+	CheckRoutesMatch,
+}
+
+// HistoryRetentionDays is how many days of check history CheckResultStore keeps.
+const HistoryRetentionDays = 90
+
+// FlakinessWindow is the number of most recent runs of a check its flakiness is computed over.
+const FlakinessWindow = 20
+
+// CheckResult is the outcome of the last run of a check for a server.
+type CheckResult struct {
+	OK bool
+	// Error is the error of the last run, if it failed.
+	Error string
+	// At is when the check last ran.
+	At time.Time
+	// LastSuccessAt is when the check last succeeded (zero if it never did).
+	LastSuccessAt time.Time
+	// Streak is the number of consecutive runs, up to and including the last one, with the
+	// same outcome as the last run.
+	Streak int
+	// Flakiness is the fraction of the last FlakinessWindow runs whose outcome differs from
+	// the run before: 0 for a check that always passes or always fails, 1 for a check that
+	// alternates. A flaky check usually has a threshold that needs tuning, whereas an
+	// unstable feed fails in long streaks.
+	Flakiness float64
+}
+
+// DailyCount counts the runs of a check on a day (UTC), and how many of them failed.
+type DailyCount struct {
+	Runs     int
+	Failures int
+}
+
+// CheckResultStore keeps the last result of every check for each server, so the state of a
+// server can be inspected without scraping Prometheus (see the status API), and daily counts
+// of runs and failures of the last HistoryRetentionDays days (see History).
+// History is kept in memory, so it starts over when the watchdog restarts; the status page reads
+// it from the history database instead when there is one.
+// It is safe for concurrent use; a nil store records nothing.
+type CheckResultStore struct {
+	mu      sync.RWMutex
+	results map[int]map[string]CheckResult
+	// history maps server ID → check → day (UTC midnight) → counts.
+	history map[int]map[string]map[time.Time]DailyCount
+	// outcomes maps server ID → check → the outcomes (true for a success) of the last
+	// FlakinessWindow runs, oldest first.
+	outcomes map[int]map[string][]bool
+}
+
+// NewCheckResultStore creates and returns a new, empty CheckResultStore.
+func NewCheckResultStore() *CheckResultStore {
+	return &CheckResultStore{
+		results:  make(map[int]map[string]CheckResult),
+		history:  make(map[int]map[string]map[time.Time]DailyCount),
+		outcomes: make(map[int]map[string][]bool),
+	}
+}
+
+// flakiness returns the fraction of outcomes that differ from the previous one.
+func flakiness(outcomes []bool) float64 {
+	if len(outcomes) < 2 {
+		return 0
+	}
+	changes := 0
+	for i := 1; i < len(outcomes); i++ {
+		if outcomes[i] != outcomes[i-1] {
+			changes++
+		}
+	}
+	return float64(changes) / float64(len(outcomes)-1)
+}
+
+// day returns the UTC midnight of the day of t.
+func day(t time.Time) time.Time {
+	return t.UTC().Truncate(24 * time.Hour)
+}
+
+// Record stores the result of a run of check for the server: a success if err is nil.
+func (s *CheckResultStore) Record(serverID int, check string, err error, at time.Time) {
+	if s == nil {
+		return
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	checks, ok := s.results[serverID]
+	if !ok {
+		checks = make(map[string]CheckResult)
+		s.results[serverID] = checks
+	}
+	result := checks[check]
+	if result.At.IsZero() || result.OK != (err == nil) {
+		result.Streak = 0
+	}
+	result.Streak++
+	result.OK, result.Error, result.At = err == nil, "", at
+	if err != nil {
+		result.Error = err.Error()
+	} else {
+		result.LastSuccessAt = at
+	}
+
+	if s.outcomes[serverID] == nil {
+		s.outcomes[serverID] = make(map[string][]bool)
+	}
+	outcomes := append(s.outcomes[serverID][check], err == nil)
+	if len(outcomes) > FlakinessWindow {
+		outcomes = append([]bool(nil), outcomes[len(outcomes)-FlakinessWindow:]...)
+	}
+	s.outcomes[serverID][check] = outcomes
+	result.Flakiness = flakiness(outcomes)
+	checks[check] = result
+
+	labels := []string{strconv.Itoa(serverID), check}
+	CheckFlakiness.WithLabelValues(labels...).Set(result.Flakiness)
+	streak := float64(result.Streak)
+	if !result.OK {
+		streak = -streak
+	}
+	CheckStreak.WithLabelValues(labels...).Set(streak)
+
+	if s.history[serverID] == nil {
+		s.history[serverID] = make(map[string]map[time.Time]DailyCount)
+	}
+	days := s.history[serverID][check]
+	if days == nil {
+		days = make(map[time.Time]DailyCount)
+		s.history[serverID][check] = days
+	}
+	today := day(at)
+	count := days[today]
+	count.Runs++
+	if err != nil {
+		count.Failures++
+	}
+	days[today] = count
+	// Forget days that are past the retention period.
+	for d := range days {
+		if today.Sub(d) >= HistoryRetentionDays*24*time.Hour {
+			delete(days, d)
+		}
+	}
+}
+
+// History returns the daily counts of a check of a server for the given number of days up to
+// and including the day of now, oldest first. Days without runs have a zero DailyCount.
+func (s *CheckResultStore) History(serverID int, check string, days int, now time.Time) []DailyCount {
+	counts := make([]DailyCount, days)
+	if s == nil {
+		return counts
+	}
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	today := day(now)
+	for i := range counts {
+		counts[i] = s.history[serverID][check][today.AddDate(0, 0, i-days+1)]
+	}
+	return counts
+}
+
+// Get returns a copy of the last results of the checks of a server, indexed by check name.
+func (s *CheckResultStore) Get(serverID int) map[string]CheckResult {
+	if s == nil {
+		return nil
+	}
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	results := make(map[string]CheckResult, len(s.results[serverID]))
+	for check, result := range s.results[serverID] {
+		results[check] = result
+	}
+	return results
+}
+
+// Delete forgets the results of a server, e.g. when it is removed from the configuration.
+func (s *CheckResultStore) Delete(serverID int) {
+	if s == nil {
+		return
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	delete(s.results, serverID)
+	delete(s.history, serverID)
+	delete(s.outcomes, serverID)
+	CheckFlakiness.DeletePartialMatch(map[string]string{"server_id": strconv.Itoa(serverID)})
+	CheckStreak.DeletePartialMatch(map[string]string{"server_id": strconv.Itoa(serverID)})
+}
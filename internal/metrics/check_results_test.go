@@ -0,0 +1,111 @@
+package metrics
+
+import (
+	"errors"
+	"testing"
+	"time"
+)
+
+func TestCheckResultStore(t *testing.T) {
+	store := NewCheckResultStore()
+	first := time.Date(2025, 6, 1, 12, 0, 0, 0, time.UTC)
+	second := first.Add(time.Minute)
+
+	store.Record(1, CheckRealtimeFeed, nil, first)
+	store.Record(1, CheckRealtimeFeed, errors.New("timeout"), second)
+	store.Record(2, CheckServerPing, nil, first)
+
+	result := store.Get(1)[CheckRealtimeFeed]
+	if result.OK || result.Error != "timeout" || !result.At.Equal(second) || !result.LastSuccessAt.Equal(first) {
+		t.Errorf("unexpected result after a failure: %+v", result)
+	}
+
+	store.Record(1, CheckRealtimeFeed, nil, second)
+	if result := store.Get(1)[CheckRealtimeFeed]; !result.OK || result.Error != "" || !result.LastSuccessAt.Equal(second) {
+		t.Errorf("unexpected result after a recovery: %+v", result)
+	}
+
+	store.Delete(1)
+	if results := store.Get(1); len(results) != 0 {
+		t.Errorf("expected no results after Delete, got %v", results)
+	}
+	if results := store.Get(2); len(results) != 1 {
+		t.Errorf("expected other servers to be kept, got %v", results)
+	}
+
+	var nilStore *CheckResultStore
+	nilStore.Record(1, CheckServerPing, nil, first)
+	if results := nilStore.Get(1); results != nil {
+		t.Errorf("expected a nil store to return nothing, got %v", results)
+	}
+}
+
+func TestCheckResultStoreHistory(t *testing.T) {
+	store := NewCheckResultStore()
+	now := time.Date(2025, 6, 10, 12, 0, 0, 0, time.UTC)
+
+	store.Record(1, CheckServerPing, nil, now.AddDate(0, 0, -1))
+	store.Record(1, CheckServerPing, errors.New("timeout"), now.AddDate(0, 0, -1))
+	store.Record(1, CheckServerPing, nil, now)
+	// Past the retention period, so dropped on the next record.
+	store.Record(1, CheckServerPing, nil, now.AddDate(0, 0, -HistoryRetentionDays-1))
+	store.Record(1, CheckServerPing, nil, now)
+
+	history := store.History(1, CheckServerPing, 3, now)
+	want := []DailyCount{{}, {Runs: 2, Failures: 1}, {Runs: 2}}
+	if len(history) != len(want) {
+		t.Fatalf("expected %d days, got %v", len(want), history)
+	}
+	for i := range want {
+		if history[i] != want[i] {
+			t.Errorf("day %d: expected %+v, got %+v", i, want[i], history[i])
+		}
+	}
+	if got := store.History(1, CheckServerPing, HistoryRetentionDays+2, now)[0]; got != (DailyCount{}) {
+		t.Errorf("expected days past the retention period to be dropped, got %+v", got)
+	}
+}
+
+func TestCheckResultStoreFlakiness(t *testing.T) {
+	store := NewCheckResultStore()
+	at := time.Date(2025, 6, 1, 12, 0, 0, 0, time.UTC)
+	failure := errors.New("timeout")
+
+	// A check failing in a streak is unstable but not flaky.
+	for range 3 {
+		store.Record(9005, CheckRealtimeFeed, failure, at)
+	}
+	result := store.Get(9005)[CheckRealtimeFeed]
+	if result.Streak != 3 || result.Flakiness != 0 {
+		t.Errorf("expected a failure streak of 3 and no flakiness, got %+v", result)
+	}
+	labels := map[string]string{"server_id": "9005", "check": CheckRealtimeFeed}
+	if streak, _ := getMetricValue(CheckStreak, labels); streak != -3 {
+		t.Errorf("expected a streak metric of -3, got %v", streak)
+	}
+
+	// Alternating outcomes: 3 changes in the 5 transitions of 6 runs.
+	store.Record(9005, CheckRealtimeFeed, nil, at)
+	store.Record(9005, CheckRealtimeFeed, failure, at)
+	store.Record(9005, CheckRealtimeFeed, nil, at)
+	result = store.Get(9005)[CheckRealtimeFeed]
+	if result.Streak != 1 || result.Flakiness != 3.0/5 {
+		t.Errorf("expected a success streak of 1 and a flakiness of 0.6, got %+v", result)
+	}
+	if flakiness, _ := getMetricValue(CheckFlakiness, labels); flakiness != 3.0/5 {
+		t.Errorf("expected a flakiness metric of 0.6, got %v", flakiness)
+	}
+
+	// Only the last FlakinessWindow runs count.
+	for range FlakinessWindow {
+		store.Record(9005, CheckRealtimeFeed, nil, at)
+	}
+	if result := store.Get(9005)[CheckRealtimeFeed]; result.Streak != FlakinessWindow+1 || result.Flakiness != 0 {
+		t.Errorf("expected older runs to be forgotten, got %+v", result)
+	}
+
+	store.Delete(9005)
+	if CheckFlakiness.DeleteLabelValues("9005", CheckRealtimeFeed) {
+		t.Error("expected Delete to remove the metrics of the server")
+	}
+}
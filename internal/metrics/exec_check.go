@@ -0,0 +1,180 @@
+package metrics
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"os/exec"
+	"strconv"
+	"strings"
+	"time"
+
+	"watchdog.onebusaway.org/internal/models"
+)
+
+// DefaultExecCheckTimeout bounds a run of an exec check that does not set a timeout.
+const DefaultExecCheckTimeout = 30 * time.Second
+
+// maxExecCheckOutput bounds the part of the stdout and stderr of an exec check that is kept.
+const maxExecCheckOutput = 64 << 10
+
+// ExecCheckName returns the name the results of an exec check are recorded under in
+// CheckResultStore, e.g. "exec:fare_zones".
+func ExecCheckName(check models.ExecCheck) string {
+	return "exec:" + check.Name
+}
+
+// execCheckOutput is the JSON object an exec check can print on stdout instead of relying on
+// its exit code.
+type execCheckOutput struct {
+	OK      *bool    `json:"ok"`
+	Message string   `json:"message"`
+	Value   *float64 `json:"value"`
+}
+
+// runExecCheck runs the command of an exec check of server and returns nil if the check passed.
+//
+// The command is contained as far as the watchdog can without privileges, but it is not
+// sandboxed: it runs as the user of the watchdog, which is why exec checks must be enabled by the
+// operator (see config.DisallowExecChecks). Still:
+//   - it runs in a new, empty temporary directory (also its HOME), removed afterwards;
+//   - its environment only holds PATH and the metadata of the server (WATCHDOG_SERVER_ID,
+//     WATCHDOG_OBA_BASE_URL, ...), not the environment of the watchdog and its secrets;
+//   - it runs in its own process group, which is killed as a whole when its timeout elapses
+//     (on Unix), and only the first 64 KiB of its output are kept.
+//
+// If stdout is a JSON object with an "ok" field, e.g. {"ok": false, "message": "3 fare zones
+// missing", "value": 3}, it is the result of the check; the optional value is exported as
+// exec_check_value. Otherwise the command passes if it exits with status 0, and the last line of
+// its stderr (or stdout) is the error of a failure.
+//
+// The command is killed like on a timeout when ctx is canceled, e.g. on shutdown.
+//
+// It sets exec_check_status and exec_check_duration_seconds for the check.
+func runExecCheck(ctx context.Context, server models.ObaServer, check models.ExecCheck) error {
+	serverID := strconv.Itoa(server.ID)
+	name := ExecCheckName(check)
+	start := time.Now()
+	value, err := execCheck(ctx, server, check)
+	ExecCheckDurationSeconds.WithLabelValues(serverID, name).Set(time.Since(start).Seconds())
+	if value != nil {
+		ExecCheckValue.WithLabelValues(serverID, name).Set(*value)
+	}
+	status := 0.0
+	if err == nil {
+		status = 1
+	}
+	ExecCheckStatus.WithLabelValues(serverID, name).Set(status)
+	return err
+}
+
+func execCheck(ctx context.Context, server models.ObaServer, check models.ExecCheck) (*float64, error) {
+	if check.Name == "" || len(check.Command) == 0 {
+		return nil, errors.New("exec checks need a name and a command")
+	}
+	timeout := check.Timeout.Std()
+	if timeout <= 0 {
+		timeout = DefaultExecCheckTimeout
+	}
+	dir, err := os.MkdirTemp("", "watchdog-exec-")
+	if err != nil {
+		return nil, fmt.Errorf("failed to create the working directory of %s: %w", check.Name, err)
+	}
+	defer os.RemoveAll(dir)
+
+	ctx, cancel := context.WithTimeout(ctx, timeout)
+	defer cancel()
+	cmd := exec.CommandContext(ctx, check.Command[0], check.Command[1:]...)
+	cmd.Dir = dir
+	cmd.Env = execCheckEnv(server, check, dir)
+	killProcessGroupOnCancel(cmd)
+	// Children that outlive the command must not keep Wait blocked on its output.
+	cmd.WaitDelay = time.Second
+	var stdout, stderr cappedBuffer
+	cmd.Stdout = &stdout
+	cmd.Stderr = &stderr
+	runErr := cmd.Run()
+	switch ctx.Err() {
+	case context.DeadlineExceeded:
+		return nil, fmt.Errorf("%s timed out after %s", check.Name, timeout)
+	case context.Canceled:
+		return nil, fmt.Errorf("%s was canceled: %w", check.Name, ctx.Err())
+	}
+
+	var output execCheckOutput
+	if trimmed := bytes.TrimSpace(stdout.Bytes()); bytes.HasPrefix(trimmed, []byte("{")) && json.Unmarshal(trimmed, &output) == nil && output.OK != nil {
+		if *output.OK {
+			return output.Value, nil
+		}
+		if output.Message == "" {
+			output.Message = "reported a failure"
+		}
+		return output.Value, fmt.Errorf("%s: %s", check.Name, output.Message)
+	}
+
+	var exitErr *exec.ExitError
+	switch {
+	case runErr == nil:
+		return nil, nil
+	case errors.As(runErr, &exitErr):
+		message := lastLine(stderr.Bytes())
+		if message == "" {
+			message = lastLine(stdout.Bytes())
+		}
+		if message == "" {
+			return nil, fmt.Errorf("%s exited with status %d", check.Name, exitErr.ExitCode())
+		}
+		return nil, fmt.Errorf("%s exited with status %d: %s", check.Name, exitErr.ExitCode(), message)
+	default:
+		return nil, fmt.Errorf("failed to run %s: %w", check.Name, runErr)
+	}
+}
+
+// execCheckEnv returns the environment of the command of an exec check.
+func execCheckEnv(server models.ObaServer, check models.ExecCheck, dir string) []string {
+	path := os.Getenv("PATH")
+	if path == "" {
+		path = "/usr/local/bin:/usr/bin:/bin"
+	}
+	return []string{
+		"PATH=" + path,
+		"HOME=" + dir,
+		"WATCHDOG_CHECK_NAME=" + check.Name,
+		"WATCHDOG_SERVER_ID=" + strconv.Itoa(server.ID),
+		"WATCHDOG_SERVER_NAME=" + server.Name,
+		"WATCHDOG_OBA_BASE_URL=" + server.ObaBaseURL,
+		"WATCHDOG_OBA_API_KEY=" + server.ObaApiKey,
+		"WATCHDOG_GTFS_URL=" + server.GtfsUrl,
+		"WATCHDOG_TRIP_UPDATE_URL=" + server.TripUpdateUrl,
+		"WATCHDOG_VEHICLE_POSITION_URL=" + server.VehiclePositionUrl,
+		"WATCHDOG_GTFS_RT_API_KEY=" + server.GtfsRtApiKey,
+		"WATCHDOG_GTFS_RT_API_VALUE=" + server.GtfsRtApiValue,
+		"WATCHDOG_AGENCY_ID=" + server.AgencyID,
+	}
+}
+
+// lastLine returns the last non-empty line of output.
+func lastLine(output []byte) string {
+	lines := strings.Split(strings.TrimSpace(string(output)), "\n")
+	return strings.TrimSpace(lines[len(lines)-1])
+}
+
+// cappedBuffer keeps the first maxExecCheckOutput bytes written to it and discards the rest,
+// without failing the writes, so a chatty command is not killed by a broken pipe.
+type cappedBuffer struct {
+	buf bytes.Buffer
+}
+
+func (b *cappedBuffer) Write(p []byte) (int, error) {
+	if room := maxExecCheckOutput - b.buf.Len(); room > 0 {
+		b.buf.Write(p[:min(len(p), room)])
+	}
+	return len(p), nil
+}
+
+func (b *cappedBuffer) Bytes() []byte {
+	return b.buf.Bytes()
+}
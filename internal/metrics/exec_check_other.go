@@ -0,0 +1,9 @@
+//go:build !unix
+
+package metrics
+
+import "os/exec"
+
+// killProcessGroupOnCancel leaves cmd as is: without process groups, the cancellation of its
+// context only kills the command itself.
+func killProcessGroupOnCancel(cmd *exec.Cmd) {}
@@ -0,0 +1,94 @@
+package metrics
+
+import (
+	"context"
+	"errors"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	"watchdog.onebusaway.org/internal/models"
+)
+
+func TestRunExecCheck(t *testing.T) {
+	server := models.ObaServer{ID: 950, Name: "Exec Server", ObaBaseURL: "https://oba.example.com", AgencyID: "1"}
+
+	tests := []struct {
+		name    string
+		check   models.ExecCheck
+		wantErr string
+		value   float64
+	}{
+		{name: "exit 0", check: models.ExecCheck{Command: []string{"sh", "-c", "exit 0"}}},
+		{name: "exit 1", check: models.ExecCheck{Command: []string{"sh", "-c", "echo checking; echo 3 fare zones missing >&2; exit 1"}}, wantErr: "exited with status 1: 3 fare zones missing"},
+		{name: "silent failure", check: models.ExecCheck{Command: []string{"false"}}, wantErr: "exited with status 1"},
+		{name: "json pass", check: models.ExecCheck{Command: []string{"sh", "-c", `echo '{"ok": true, "value": 42}'; exit 1`}}, value: 42},
+		{name: "json failure", check: models.ExecCheck{Command: []string{"sh", "-c", `echo '{"ok": false, "message": "2 stops out of bounds", "value": 2}'`}}, wantErr: "2 stops out of bounds", value: 2},
+		{name: "server metadata", check: models.ExecCheck{Command: []string{"sh", "-c", `test "$WATCHDOG_SERVER_ID/$WATCHDOG_OBA_BASE_URL/$WATCHDOG_CHECK_NAME" = "950/https://oba.example.com/server metadata"`}}},
+		{name: "clean environment", check: models.ExecCheck{Command: []string{"sh", "-c", `test -z "$EXEC_CHECK_SECRET"`}}},
+		{name: "timeout", check: models.ExecCheck{Command: []string{"sleep", "5"}, Timeout: models.Duration(100 * time.Millisecond)}, wantErr: "timed out after 100ms"},
+		{name: "missing command", check: models.ExecCheck{Command: []string{"watchdog-no-such-command"}}, wantErr: "failed to run"},
+		{name: "no command", wantErr: "need a name and a command"},
+	}
+	t.Setenv("EXEC_CHECK_SECRET", "secret")
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if tt.check.Command != nil {
+				tt.check.Name = tt.name
+			}
+			err := runExecCheck(context.Background(), server, tt.check)
+			if tt.wantErr == "" && err != nil {
+				t.Fatalf("expected the check to pass, got %v", err)
+			}
+			if tt.wantErr != "" && (err == nil || !strings.Contains(err.Error(), tt.wantErr)) {
+				t.Fatalf("expected an error containing %q, got %v", tt.wantErr, err)
+			}
+
+			status := 1.0
+			if tt.wantErr != "" {
+				status = 0
+			}
+			labels := []string{strconv.Itoa(server.ID), ExecCheckName(tt.check)}
+			if got := testutil.ToFloat64(ExecCheckStatus.WithLabelValues(labels...)); got != status {
+				t.Errorf("expected exec_check_status %v, got %v", status, got)
+			}
+			if tt.value != 0 {
+				if got := testutil.ToFloat64(ExecCheckValue.WithLabelValues(labels...)); got != tt.value {
+					t.Errorf("expected exec_check_value %v, got %v", tt.value, got)
+				}
+			}
+		})
+	}
+}
+
+func TestRunExecCheckKillsProcessGroup(t *testing.T) {
+	server := models.ObaServer{ID: 951, Name: "Exec Server"}
+	// The shell forks sleep, which holds the output pipes: unless it is killed with the shell,
+	// the run only ends after the WaitDelay of the command.
+	check := models.ExecCheck{Name: "group", Command: []string{"sh", "-c", "sleep 5; true"}, Timeout: models.Duration(100 * time.Millisecond)}
+	start := time.Now()
+	err := runExecCheck(context.Background(), server, check)
+	if err == nil || !strings.Contains(err.Error(), "timed out") {
+		t.Fatalf("expected a timeout, got %v", err)
+	}
+	if elapsed := time.Since(start); elapsed > 900*time.Millisecond {
+		t.Errorf("expected the process group to be killed on timeout, the run took %s", elapsed)
+	}
+}
+
+func TestRunExecCheckCanceled(t *testing.T) {
+	server := models.ObaServer{ID: 952, Name: "Exec Server"}
+	check := models.ExecCheck{Name: "canceled", Command: []string{"sleep", "5"}}
+	ctx, cancel := context.WithCancel(context.Background())
+	time.AfterFunc(100*time.Millisecond, cancel)
+	start := time.Now()
+	err := runExecCheck(ctx, server, check)
+	if !errors.Is(err, context.Canceled) {
+		t.Fatalf("expected the check to be canceled, got %v", err)
+	}
+	if elapsed := time.Since(start); elapsed > 900*time.Millisecond {
+		t.Errorf("expected the command to be killed on cancellation, the run took %s", elapsed)
+	}
+}
@@ -0,0 +1,18 @@
+//go:build unix
+
+package metrics
+
+import (
+	"os/exec"
+	"syscall"
+)
+
+// killProcessGroupOnCancel runs cmd in a process group of its own, and makes the cancellation of
+// its context kill the whole group rather than the command only, so that the processes it
+// started (e.g. by a shell script) do not outlive its timeout.
+func killProcessGroupOnCancel(cmd *exec.Cmd) {
+	cmd.SysProcAttr = &syscall.SysProcAttr{Setpgid: true}
+	cmd.Cancel = func() error {
+		return syscall.Kill(-cmd.Process.Pid, syscall.SIGKILL)
+	}
+}
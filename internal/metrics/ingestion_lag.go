@@ -0,0 +1,145 @@
+package metrics
+
+import (
+	"encoding/json"
+	"fmt"
+	"math/rand/v2"
+	"net/http"
+	"net/url"
+	"strconv"
+	"time"
+
+	"watchdog.onebusaway.org/internal/gtfs"
+	"watchdog.onebusaway.org/internal/httpclient"
+	"watchdog.onebusaway.org/internal/models"
+)
+
+// ingestionLagSampleSize is the number of vehicles of the GTFS-RT feed looked up in the OBA API
+// per cycle to measure the ingestion lag.
+const ingestionLagSampleSize = 5
+
+// sampledVehicle is a vehicle of the GTFS-RT feed and the time of its last position.
+type sampledVehicle struct {
+	id        string
+	timestamp time.Time
+}
+
+// sampleVehicles returns up to n vehicles of the GTFS-RT feed that have an ID and a timestamp,
+// picked at random, so that successive cycles cover the whole fleet.
+func sampleVehicles(data *models.RealtimeData, n int) []sampledVehicle {
+	var vehicles []sampledVehicle
+	for _, vehicle := range data.Vehicles {
+		if vehicle.ID == nil || vehicle.ID.ID == "" || vehicle.Timestamp == nil || vehicle.Timestamp.IsZero() {
+			continue
+		}
+		vehicles = append(vehicles, sampledVehicle{id: vehicle.ID.ID, timestamp: *vehicle.Timestamp})
+	}
+	if len(vehicles) <= n {
+		return vehicles
+	}
+	sample := make([]sampledVehicle, 0, n)
+	for _, i := range rand.Perm(len(vehicles))[:n] {
+		sample = append(sample, vehicles[i])
+	}
+	return sample
+}
+
+// checkIngestionLag measures how far behind the GTFS-RT feed the OBA API of a server is: for a
+// sample of the vehicles of the feed, it compares the timestamp of the vehicle in the feed with
+// the `lastUpdateTime` of the vehicle in the OBA `trip-for-vehicle` endpoint. A lag that grows
+// while the feed is fresh points to the realtime pipeline of OBA itself, e.g. a stuck
+// GtfsRealtimeSource, rather than to the agency's feed.
+//
+// OBA vehicle IDs are prefixed with an agency ID: server.AgencyID if configured, otherwise the
+// first agency of the bundle. Vehicles that OBA does not know or that are not serving a trip
+// are skipped. A vehicle updated in OBA after the feed was fetched has no lag.
+//
+// It sets:
+//   - oba_realtime_ingestion_lag_seconds: the lag of each sampled vehicle, as a histogram.
+//   - oba_realtime_ingestion_lag_max_seconds: the largest lag of the sample.
+//
+// Returns an error if there is no GTFS-RT data or if a lookup fails.
+func checkIngestionLag(staticStore gtfs.StaticStore, realtimeStore gtfs.RealtimeStore, server models.ObaServer, client *http.Client, sampleSize int) error {
+	realtimeData := realtimeStore.Get()
+	if realtimeData == nil {
+		return fmt.Errorf("no GTFS-RT data available for server %d", server.ID)
+	}
+	agencyID := server.AgencyID
+	if staticData, ok := staticStore.Get(server.ID); agencyID == "" && ok && staticData != nil && len(staticData.Agencies) > 0 {
+		agencyID = staticData.Agencies[0].Id
+	}
+	if client == nil {
+		client = httpclient.Default().API
+	}
+
+	serverID := strconv.Itoa(server.ID)
+	var maxLag time.Duration
+	for _, vehicle := range sampleVehicles(realtimeData, sampleSize) {
+		vehicleID := vehicle.id
+		if agencyID != "" {
+			vehicleID = agencyID + "_" + vehicle.id
+		}
+		lastUpdate, found, err := obaVehicleLastUpdate(server, vehicleID, client)
+		if err != nil {
+			return err
+		}
+		if !found {
+			continue
+		}
+		lag := max(vehicle.timestamp.Sub(lastUpdate), 0)
+		maxLag = max(maxLag, lag)
+		IngestionLagSeconds.WithLabelValues(serverID).Observe(lag.Seconds())
+	}
+	IngestionLagMaxSeconds.WithLabelValues(serverID).Set(maxLag.Seconds())
+	return nil
+}
+
+// obaTripForVehicleResponse is the subset of the OBA `trip-for-vehicle` response used to
+// measure the ingestion lag.
+type obaTripForVehicleResponse struct {
+	Code int    `json:"code"`
+	Text string `json:"text"`
+	Data *struct {
+		Entry struct {
+			Status *struct {
+				// LastUpdateTime is in milliseconds since the epoch, or 0 without realtime data.
+				LastUpdateTime int64 `json:"lastUpdateTime"`
+			} `json:"status"`
+		} `json:"entry"`
+	} `json:"data"`
+}
+
+// obaVehicleLastUpdate returns the time of the last realtime update of a vehicle in the OBA API
+// of a server, and whether OBA has one. OBA answers unknown vehicles, and vehicles not serving
+// a trip, with 404, either as the HTTP status or as the code of the response body.
+func obaVehicleLastUpdate(server models.ObaServer, vehicleID string, client *http.Client) (time.Time, bool, error) {
+	endpoint := fmt.Sprintf("%s/api/where/trip-for-vehicle/%s.json?%s", server.ObaBaseURL, url.PathEscape(vehicleID), url.Values{"key": {server.ObaApiKey}}.Encode())
+
+	resp, err := obaGet(client, server.ID, endpoint)
+	if err != nil {
+		return time.Time{}, false, fmt.Errorf("failed to fetch trip for vehicle %s: %w", vehicleID, err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode == http.StatusNotFound {
+		return time.Time{}, false, nil
+	}
+	if resp.StatusCode != http.StatusOK {
+		return time.Time{}, false, fmt.Errorf("unexpected status code %d fetching trip for vehicle %s", resp.StatusCode, vehicleID)
+	}
+
+	var body obaTripForVehicleResponse
+	if err := json.NewDecoder(resp.Body).Decode(&body); err != nil {
+		return time.Time{}, false, fmt.Errorf("failed to decode trip for vehicle %s: %w", vehicleID, err)
+	}
+	switch body.Code {
+	case 0, http.StatusOK:
+	case http.StatusNotFound:
+		return time.Time{}, false, nil
+	default:
+		return time.Time{}, false, fmt.Errorf("OBA API error fetching trip for vehicle %s: %d %s", vehicleID, body.Code, body.Text)
+	}
+	if body.Data == nil || body.Data.Entry.Status == nil || body.Data.Entry.Status.LastUpdateTime <= 0 {
+		return time.Time{}, false, nil
+	}
+	return time.UnixMilli(body.Data.Entry.Status.LastUpdateTime), true, nil
+}
@@ -0,0 +1,83 @@
+package metrics
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	remoteGtfs "github.com/OneBusAway/go-gtfs"
+	"watchdog.onebusaway.org/internal/gtfs"
+	"watchdog.onebusaway.org/internal/models"
+)
+
+func TestCheckIngestionLag(t *testing.T) {
+	feedTime := time.Date(2025, 3, 10, 8, 0, 0, 0, time.UTC)
+	millis := func(t time.Time) string { return strconv.FormatInt(t.UnixMilli(), 10) }
+	obaServer := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/api/where/trip-for-vehicle/1_bus-1.json":
+			// OBA last saw the vehicle 40 seconds before the feed.
+			_, _ = w.Write([]byte(`{"code":200,"data":{"entry":{"status":{"lastUpdateTime":` + millis(feedTime.Add(-40*time.Second)) + `}}}}`))
+		case "/api/where/trip-for-vehicle/1_bus-2.json":
+			// Updated in OBA after the feed was fetched: no lag.
+			_, _ = w.Write([]byte(`{"code":200,"data":{"entry":{"status":{"lastUpdateTime":` + millis(feedTime.Add(5*time.Second)) + `}}}}`))
+		case "/api/where/trip-for-vehicle/1_bus-3.json":
+			// Not serving a trip.
+			_, _ = w.Write([]byte(`{"code":404,"text":"resource not found"}`))
+		case "/api/where/trip-for-vehicle/1_bus-500.json":
+			w.WriteHeader(http.StatusInternalServerError)
+		default:
+			w.WriteHeader(http.StatusNotFound)
+		}
+	}))
+	defer obaServer.Close()
+
+	server := models.ObaServer{ID: 9004, ObaBaseURL: obaServer.URL, ObaApiKey: "test-key", AgencyID: "1"}
+	staticStore := gtfs.NewStaticStore()
+	realtimeStore := gtfs.NewRealtimeStore()
+	if err := checkIngestionLag(staticStore, realtimeStore, server, obaServer.Client(), 10); err == nil {
+		t.Error("expected an error without GTFS-RT data")
+	}
+
+	vehicle := func(id string, timestamp *time.Time) remoteGtfs.Vehicle {
+		return remoteGtfs.Vehicle{ID: &remoteGtfs.VehicleID{ID: id}, Timestamp: timestamp}
+	}
+	realtimeStore.Set(&models.RealtimeData{Vehicles: []remoteGtfs.Vehicle{
+		vehicle("bus-1", &feedTime),
+		vehicle("bus-2", &feedTime),
+		vehicle("bus-3", &feedTime),
+		vehicle("bus-4", nil),
+	}})
+	if err := checkIngestionLag(staticStore, realtimeStore, server, obaServer.Client(), 10); err != nil {
+		t.Fatalf("checkIngestionLag failed: %v", err)
+	}
+	if count, sum := histogramSample(t, IngestionLagSeconds.WithLabelValues("9004")); count != 2 || sum != 40 {
+		t.Errorf("expected lags of 40s and 0s, got %d samples summing to %v", count, sum)
+	}
+	if value, err := getMetricValue(IngestionLagMaxSeconds, map[string]string{"server_id": "9004"}); err != nil || value != 40 {
+		t.Errorf("expected a max lag of 40s, got %v (%v)", value, err)
+	}
+
+	realtimeStore.Set(&models.RealtimeData{Vehicles: []remoteGtfs.Vehicle{vehicle("bus-500", &feedTime)}})
+	if err := checkIngestionLag(staticStore, realtimeStore, server, obaServer.Client(), 10); err == nil || !strings.Contains(err.Error(), "status code 500") {
+		t.Errorf("expected the failed lookup to be an error, got %v", err)
+	}
+}
+
+func TestSampleVehicles(t *testing.T) {
+	now := time.Now()
+	data := &models.RealtimeData{}
+	for i := range 20 {
+		data.Vehicles = append(data.Vehicles, remoteGtfs.Vehicle{ID: &remoteGtfs.VehicleID{ID: strings.Repeat("v", i+1)}, Timestamp: &now})
+	}
+	data.Vehicles = append(data.Vehicles, remoteGtfs.Vehicle{Timestamp: &now})
+	if sample := sampleVehicles(data, 5); len(sample) != 5 {
+		t.Errorf("expected 5 vehicles, got %d", len(sample))
+	}
+	if sample := sampleVehicles(data, 50); len(sample) != 20 {
+		t.Errorf("expected the 20 vehicles with an ID, got %d", len(sample))
+	}
+}
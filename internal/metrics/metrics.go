@@ -14,6 +14,14 @@ var (
 		},
 		[]string{"server_id", "server_url"},
 	)
+
+	ObaReportProblemStatus = promauto.NewGaugeVec(
+		prometheus.GaugeOpts{
+			Name: "oba_report_problem_status",
+			Help: "Whether the last test problem report submitted to the OneBusAway API was accepted (0 = rejected or failed, 1 = accepted)",
+		},
+		[]string{"server_id"},
+	)
 )
 
 var (
@@ -26,6 +34,21 @@ var (
 		Name: "gtfs_bundle_days_until_latest_expiration",
 		Help: "Number of days until the latest GTFS bundle expiration",
 	}, []string{"server_id"})
+
+	FeedEndDateDaysRemainingGauge = promauto.NewGaugeVec(prometheus.GaugeOpts{
+		Name: "gtfs_feed_end_date_days_remaining",
+		Help: "Number of days until the feed_end_date declared in the GTFS bundle's feed_info.txt",
+	}, []string{"server_id"})
+
+	FeedInfoGauge = promauto.NewGaugeVec(prometheus.GaugeOpts{
+		Name: "gtfs_feed_info",
+		Help: "Information from the GTFS bundle's feed_info.txt, always 1",
+	}, []string{"server_id", "feed_version", "feed_publisher_name"})
+
+	LicenseInfoGauge = promauto.NewGaugeVec(prometheus.GaugeOpts{
+		Name: "gtfs_license_info",
+		Help: "Publisher (feed_info.txt) and attributed organizations (attribution.txt) of the GTFS bundle, always 1",
+	}, []string{"server_id", "publisher_name", "publisher_url", "attributions"})
 )
 
 var (
@@ -43,6 +66,101 @@ var (
 		Name: "oba_agencies_match",
 		Help: "Whether the number of agencies in the static GTFS file matches the agencies-with-coverage endpoint (1 = match, 0 = no match)",
 	}, []string{"server_id"})
+
+	AgencyInStaticGtfs = promauto.NewGaugeVec(prometheus.GaugeOpts{
+		Name: "oba_agency_in_static_gtfs",
+		Help: "Whether an agency is in the static GTFS file (1) or only in the agencies-with-coverage endpoint (0)",
+	}, []string{"server_id", "agency_id"})
+
+	AgencyInCoverageEndpoint = promauto.NewGaugeVec(prometheus.GaugeOpts{
+		Name: "oba_agency_in_coverage_endpoint",
+		Help: "Whether an agency is in the agencies-with-coverage endpoint (1) or only in the static GTFS file (0)",
+	}, []string{"server_id", "agency_id"})
+
+	AgencyMatch = promauto.NewGaugeVec(prometheus.GaugeOpts{
+		Name: "oba_agency_match",
+		Help: "Whether an agency is both in the static GTFS file and the agencies-with-coverage endpoint (1 = match, 0 = in only one of them)",
+	}, []string{"server_id", "agency_id"})
+
+	StaticRoutesMissing = promauto.NewGaugeVec(prometheus.GaugeOpts{
+		Name: "gtfs_static_routes_missing",
+		Help: "Number of routes of the static GTFS file that the OBA routes-for-agency endpoint does not return",
+	}, []string{"server_id", "agency_id"})
+
+	StaticRoutesExtra = promauto.NewGaugeVec(prometheus.GaugeOpts{
+		Name: "gtfs_static_routes_extra",
+		Help: "Number of routes returned by the OBA routes-for-agency endpoint that are not in the static GTFS file",
+	}, []string{"server_id", "agency_id"})
+
+	RealtimeTripsScheduledActive = promauto.NewGaugeVec(prometheus.GaugeOpts{
+		Name: "realtime_trips_scheduled_active",
+		Help: "Number of trips of the static GTFS file scheduled to be running",
+	}, []string{"server_id"})
+
+	RealtimeTripCoverageRatio = promauto.NewGaugeVec(prometheus.GaugeOpts{
+		Name: "realtime_trip_coverage_ratio",
+		Help: "Fraction of the trips scheduled to be running that have a vehicle or trip update in the GTFS-RT feed",
+	}, []string{"server_id"})
+
+	RealtimeRouteTripCoverageRatio = promauto.NewGaugeVec(prometheus.GaugeOpts{
+		Name: "realtime_route_trip_coverage_ratio",
+		Help: "Fraction of the trips of a route scheduled to be running that have a vehicle or trip update in the GTFS-RT feed",
+	}, []string{"server_id", "route_id"})
+
+	CheckFlakiness = promauto.NewGaugeVec(prometheus.GaugeOpts{
+		Name: "watchdog_check_flakiness",
+		Help: "Fraction of the last runs of a check whose outcome differs from the run before (0: stable, 1: alternating)",
+	}, []string{"server_id", "check"})
+
+	CheckStreak = promauto.NewGaugeVec(prometheus.GaugeOpts{
+		Name: "watchdog_check_streak",
+		Help: "Number of consecutive runs of a check with the same outcome, positive for successes and negative for failures",
+	}, []string{"server_id", "check"})
+
+	CircuitBreakerState = promauto.NewGaugeVec(prometheus.GaugeOpts{
+		Name: "watchdog_circuit_breaker_state",
+		Help: "State of the circuit breaker of a server (0 = closed, 1 = half-open, 2 = open)",
+	}, []string{"server_id"})
+
+	ServerInfo = promauto.NewGaugeVec(prometheus.GaugeOpts{
+		Name: "oba_server_info",
+		Help: "Human-readable settings of a configured OBA server, the feed_version of its GTFS bundle and whether it is in a maintenance window (true or false), always 1",
+	}, []string{"server_id", "name", "region", "oba_base_url", "gtfs_feed_version", "maintenance"})
+
+	DuplicateServers = promauto.NewGaugeVec(prometheus.GaugeOpts{
+		Name: "watchdog_server_duplicates",
+		Help: "Number of other configured servers with the same OBA base URL or GTFS URL (field) as the server",
+	}, []string{"server_id", "field"})
+
+	ServersTotalGauge = promauto.NewGaugeVec(prometheus.GaugeOpts{
+		Name: "watchdog_servers_total",
+		Help: "Number of configured servers, by region (empty for servers without one)",
+	}, []string{"region"})
+
+	ServersUnhealthyGauge = promauto.NewGaugeVec(prometheus.GaugeOpts{
+		Name: "watchdog_servers_unhealthy",
+		Help: "Number of servers whose last run of a check failed (health degraded or down), by region",
+	}, []string{"region"})
+
+	FeedsStaleGauge = promauto.NewGaugeVec(prometheus.GaugeOpts{
+		Name: "watchdog_feeds_stale",
+		Help: "Number of servers whose GTFS-RT feed last failed to be fetched or was last fetched longer ago than --feed-stale-after, by region",
+	}, []string{"region"})
+
+	ChecksInProgress = promauto.NewGaugeVec(prometheus.GaugeOpts{
+		Name: "watchdog_checks_in_progress",
+		Help: "Number of checks running, by kind (server, url_target, exec); a value stuck above 0 means a check is hanging",
+	}, []string{"kind"})
+
+	StaticStopsMatched = promauto.NewGaugeVec(prometheus.GaugeOpts{
+		Name: "gtfs_static_stops_matched",
+		Help: "Number of stops sampled from the static GTFS file that the OBA stop endpoint returns",
+	}, []string{"server_id"})
+
+	StaticStopsMissing = promauto.NewGaugeVec(prometheus.GaugeOpts{
+		Name: "gtfs_static_stops_missing",
+		Help: "Number of stops sampled from the static GTFS file that the OBA stop endpoint does not know",
+	}, []string{"server_id"})
 )
 
 var (
@@ -61,6 +179,16 @@ var (
 		Help: "Whether the number of vehicles in the API response matches the number of vehicles in the static GTFS-RT file (1 = match, 0 = no match)",
 	}, []string{"agency_id", "server_id"})
 
+	VehicleCountMatchRatio = promauto.NewGaugeVec(prometheus.GaugeOpts{
+		Name: "vehicle_count_match_ratio",
+		Help: "Number of vehicles in the API response divided by the number of vehicles in the GTFS-RT feed (1 when the feed has no vehicles)",
+	}, []string{"agency_id", "server_id"})
+
+	VehicleCountDifference = promauto.NewGaugeVec(prometheus.GaugeOpts{
+		Name: "vehicle_count_difference",
+		Help: "Absolute difference between the number of vehicles in the GTFS-RT feed and in the API response",
+	}, []string{"agency_id", "server_id"})
+
 	VehicleReportInterval = promauto.NewGaugeVec(prometheus.GaugeOpts{
 		Name: "vehicle_position_report_interval_seconds",
 		Help: "Time in seconds since each vehicle last reported a GTFS-RT position",
@@ -205,12 +333,83 @@ var (
 )
 
 var (
-	OutgoingLatency = promauto.NewHistogramVec(
+	PredictionErrorSeconds = promauto.NewHistogramVec(
 		prometheus.HistogramOpts{
-			Name:    "http_outgoing_request_duration_seconds",
-			Help:    "Duration of outgoing HTTP requests to external APIs (in seconds)",
-			Buckets: prometheus.DefBuckets,
+			Name:    "oba_prediction_error_seconds",
+			Help:    "Error of predicted arrival times at the sampled stops (predicted - actual, in seconds), by route and how long before the arrival the prediction was made",
+			Buckets: []float64{-600, -300, -180, -120, -60, -30, 0, 30, 60, 120, 180, 300, 600},
+		},
+		[]string{"server_id", "route_id", "horizon"},
+	)
+)
+
+var (
+	IngestionLagSeconds = promauto.NewHistogramVec(
+		prometheus.HistogramOpts{
+			Name:    "oba_realtime_ingestion_lag_seconds",
+			Help:    "Time between the timestamp of sampled vehicles in the GTFS-RT feed and their last update in the OBA API, in seconds",
+			Buckets: []float64{0, 5, 10, 15, 30, 60, 120, 300, 600, 1800},
+		},
+		[]string{"server_id"},
+	)
+
+	IngestionLagMaxSeconds = promauto.NewGaugeVec(
+		prometheus.GaugeOpts{
+			Name: "oba_realtime_ingestion_lag_max_seconds",
+			Help: "Largest ingestion lag of the vehicles sampled in the last collection cycle, in seconds",
+		},
+		[]string{"server_id"},
+	)
+)
+
+var (
+	URLCheckUp = promauto.NewGaugeVec(
+		prometheus.GaugeOpts{
+			Name: "url_check_up",
+			Help: "Whether the last uptime checks of a URL target passed (0 = failing, 1 = passing)",
+		},
+		[]string{"server_id", "url"},
+	)
+
+	URLCheckStatusCode = promauto.NewGaugeVec(
+		prometheus.GaugeOpts{
+			Name: "url_check_status_code",
+			Help: "HTTP status code of the last request to a URL target (0 = no response)",
+		},
+		[]string{"server_id"},
+	)
+
+	URLCheckDurationSeconds = promauto.NewGaugeVec(
+		prometheus.GaugeOpts{
+			Name: "url_check_duration_seconds",
+			Help: "Time until the response headers of the last request to a URL target were received, in seconds",
+		},
+		[]string{"server_id"},
+	)
+)
+
+var (
+	ExecCheckStatus = promauto.NewGaugeVec(
+		prometheus.GaugeOpts{
+			Name: "exec_check_status",
+			Help: "Whether the last run of a custom exec check of a server passed (0 = failed, 1 = passed)",
+		},
+		[]string{"server_id", "check"},
+	)
+
+	ExecCheckValue = promauto.NewGaugeVec(
+		prometheus.GaugeOpts{
+			Name: "exec_check_value",
+			Help: "Value reported in the JSON output of the last run of a custom exec check",
+		},
+		[]string{"server_id", "check"},
+	)
+
+	ExecCheckDurationSeconds = promauto.NewGaugeVec(
+		prometheus.GaugeOpts{
+			Name: "exec_check_duration_seconds",
+			Help: "Duration of the last run of a custom exec check, in seconds",
 		},
-		[]string{"url", "method", "status_code"},
+		[]string{"server_id", "check"},
 	)
 )
@@ -1,6 +1,7 @@
 package metrics
 
 import (
+	"context"
 	"log/slog"
 	"net/http"
 	"time"
@@ -11,43 +12,83 @@ import (
 )
 
 type MetricsService struct {
-	StaticStore      *gtfs.StaticStore
-	RealtimeStore    *gtfs.RealtimeStore
-	BoundingBoxStore *geo.BoundingBoxStore
+	StaticStore      gtfs.StaticStore
+	RealtimeStore    gtfs.RealtimeStore
+	BoundingBoxStore geo.BoundingBoxStore
 	VehicleLastSeen  *VehicleLastSeen
+	CheckResults     *CheckResultStore
+	Predictions      *PredictionTracker
 	Logger           *slog.Logger
 	Client           *http.Client
 }
 
-func NewMetricsService(static *gtfs.StaticStore, realtime *gtfs.RealtimeStore, bbox *geo.BoundingBoxStore, vehicleLastSeen *VehicleLastSeen, logger *slog.Logger, client *http.Client) *MetricsService {
+func NewMetricsService(static gtfs.StaticStore, realtime gtfs.RealtimeStore, bbox geo.BoundingBoxStore, vehicleLastSeen *VehicleLastSeen, checkResults *CheckResultStore, predictions *PredictionTracker, logger *slog.Logger, client *http.Client) *MetricsService {
 	return &MetricsService{
 		StaticStore:      static,
 		RealtimeStore:    realtime,
 		BoundingBoxStore: bbox,
 		VehicleLastSeen:  vehicleLastSeen,
+		CheckResults:     checkResults,
+		Predictions:      predictions,
 		Logger:           logger,
 		Client:           client,
 	}
 }
 
-func (ms *MetricsService) CheckVehicleCountMatch(server models.ObaServer) error {
-	return checkVehicleCountMatch(server, ms.RealtimeStore)
+func (ms *MetricsService) CheckVehicleCountMatch(server models.ObaServer) (float64, error) {
+	return checkVehicleCountMatch(server, ms.RealtimeStore, ms.Client)
+}
+
+// RunExecCheck runs a custom check of the server as an external command (see runExecCheck).
+func (ms *MetricsService) RunExecCheck(ctx context.Context, server models.ObaServer, check models.ExecCheck) error {
+	return runExecCheck(ctx, server, check)
+}
+
+// CheckURLTarget runs the uptime checks of an entry of type "url" (see checkURLTarget).
+func (ms *MetricsService) CheckURLTarget(target models.ObaServer) URLCheckResult {
+	return checkURLTarget(target, ms.Client)
+}
+
+// CheckIngestionLag measures how far behind the GTFS-RT feed the OBA API of a server is (see checkIngestionLag).
+func (ms *MetricsService) CheckIngestionLag(server models.ObaServer) error {
+	return checkIngestionLag(ms.StaticStore, ms.RealtimeStore, server, ms.Client, ingestionLagSampleSize)
+}
+
+func (ms *MetricsService) CheckTripCoverage(currentTime time.Time, server models.ObaServer) error {
+	return checkTripCoverage(ms.StaticStore, ms.RealtimeStore, server, currentTime)
 }
 
 func (ms *MetricsService) CheckAgenciesWithCoverageMatch(server models.ObaServer) error {
-	if err := checkAgenciesWithCoverageMatch(ms.StaticStore, ms.Logger, server); err != nil {
+	if err := checkAgenciesWithCoverageMatch(ms.StaticStore, ms.Logger, server, ms.Client); err != nil {
 		return err
 	}
 	return nil
 
 }
 
+func (ms *MetricsService) CheckStopsMatch(server models.ObaServer) error {
+	return checkStopsMatch(ms.StaticStore, server, ms.Client, stopSampleSize)
+}
+
+func (ms *MetricsService) CheckRoutesMatch(server models.ObaServer, threshold float64) error {
+	return checkRoutesMatch(ms.StaticStore, server, ms.Client, threshold)
+}
+
 func (ms *MetricsService) CheckBundleExpiration(currentTime time.Time, server models.ObaServer) (int, int, error) {
 	return checkBundleExpiration(ms.StaticStore, currentTime, server)
 }
 
+func (ms *MetricsService) CheckFeedInfo(currentTime time.Time, server models.ObaServer) error {
+	return checkFeedInfo(ms.StaticStore, currentTime, server)
+}
+
+// CheckReportProblem submits a test problem report to the OBA API of a server (see checkReportProblem).
+func (ms *MetricsService) CheckReportProblem(server models.ObaServer) error {
+	return checkReportProblem(server, ms.Client)
+}
+
 func (ms *MetricsService) ServerPing(server models.ObaServer) bool {
-	return serverPing(server)
+	return serverPing(server, ms.Client)
 }
 
 func (ms *MetricsService) FetchObaAPIMetrics(slugID string, serverID int, serverBaseUrl string, apiKey string) error {
@@ -61,3 +102,7 @@ func (ms *MetricsService) TrackVehicleTelemetry(server models.ObaServer) error {
 func (ms *MetricsService) TrackInvalidVehiclesAndStoppedOutOfBounds(server models.ObaServer) error {
 	return trackInvalidVehiclesAndStoppedOutOfBounds(server, ms.BoundingBoxStore, ms.RealtimeStore)
 }
+
+func (ms *MetricsService) CheckPredictionAccuracy(currentTime time.Time, server models.ObaServer) error {
+	return checkPredictionAccuracy(server, ms.Predictions, ms.Client, currentTime)
+}
@@ -0,0 +1,39 @@
+package metrics
+
+import (
+	"context"
+	"net/http"
+
+	onebusaway "github.com/OneBusAway/go-sdk"
+	"github.com/OneBusAway/go-sdk/option"
+	"watchdog.onebusaway.org/internal/httpclient"
+	"watchdog.onebusaway.org/internal/models"
+)
+
+// newObaClient returns an OBA SDK client for the API of a server, making its requests with
+// client (nil = httpclient.Default().API). Its requests are recorded as oba_api requests of
+// the server (see httpclient.WithTarget).
+func newObaClient(server models.ObaServer, client *http.Client) *onebusaway.Client {
+	if client == nil {
+		client = httpclient.Default().API
+	}
+	return onebusaway.NewClient(
+		option.WithAPIKey(server.ObaApiKey),
+		option.WithBaseURL(server.ObaBaseURL),
+		option.WithHTTPClient(client),
+		option.WithMiddleware(func(req *http.Request, next option.MiddlewareNext) (*http.Response, error) {
+			return next(req.WithContext(httpclient.WithTarget(req.Context(), server.ID, httpclient.TargetOBAAPI)))
+		}),
+	)
+}
+
+// obaGet issues a GET request to an endpoint of the OBA API of a server, recorded as an
+// oba_api request of the server (see httpclient.WithTarget).
+func obaGet(client *http.Client, serverID int, endpoint string) (*http.Response, error) {
+	ctx := httpclient.WithTarget(context.Background(), serverID, httpclient.TargetOBAAPI)
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, endpoint, nil)
+	if err != nil {
+		return nil, err
+	}
+	return client.Do(req)
+}
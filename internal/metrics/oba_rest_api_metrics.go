@@ -4,9 +4,9 @@ import (
 	"encoding/json"
 	"fmt"
 	"net/http"
-	"time"
 
 	"watchdog.onebusaway.org/internal/gtfs"
+	"watchdog.onebusaway.org/internal/httpclient"
 	"watchdog.onebusaway.org/internal/report"
 	"watchdog.onebusaway.org/internal/utils"
 )
@@ -56,18 +56,16 @@ type OBAMetrics struct {
 // Returns:
 //   - error: any error encountered during request, decoding, or Prometheus reporting.
 
-func fetchObaAPIMetrics(slugID string, serverID int, serverBaseUrl string, apiKey string, client *http.Client, staticStore *gtfs.StaticStore) error {
+func fetchObaAPIMetrics(slugID string, serverID int, serverBaseUrl string, apiKey string, client *http.Client, staticStore gtfs.StaticStore) error {
 	if client == nil {
-		client = &http.Client{
-			Timeout: 10 * time.Second,
-		}
+		client = httpclient.Default().API
 	}
 
 	url := fmt.Sprintf("%s/api/where/metrics.json?key=%s", serverBaseUrl, apiKey)
 
 	fmt.Printf("Fetching metrics from %s\n", url)
 
-	resp, err := client.Get(url)
+	resp, err := obaGet(client, serverID, url)
 	if err != nil {
 		err = fmt.Errorf("failed to fetch metrics from %s: %v", url, err)
 		report.ReportErrorWithSentryOptions(err, report.SentryReportOptions{
@@ -0,0 +1,246 @@
+package metrics
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strconv"
+	"sync"
+	"time"
+
+	"watchdog.onebusaway.org/internal/httpclient"
+	"watchdog.onebusaway.org/internal/models"
+)
+
+const (
+	// predictionWindowBefore and predictionWindowAfter are the minutes before and after now
+	// of the arrivals requested from arrivals-and-departures-for-stop. Departed arrivals stay
+	// in the window long enough to be seen departing in the next cycles.
+	predictionWindowBefore = 10
+	predictionWindowAfter  = 30
+	// predictionTTL is how long an arrival that was never seen departing is kept.
+	predictionTTL = time.Hour
+)
+
+// predictionHorizons are the lead times (time between a prediction and the predicted arrival)
+// that prediction errors are grouped by, as upper bounds and the label of the group.
+var predictionHorizons = []struct {
+	max   time.Duration
+	label string
+}{
+	{5 * time.Minute, "0-5m"},
+	{10 * time.Minute, "5-10m"},
+	{20 * time.Minute, "10-20m"},
+	{30 * time.Minute, "20-30m"},
+}
+
+// predictionHorizon returns the label of the horizon of a lead time.
+func predictionHorizon(lead time.Duration) string {
+	for _, horizon := range predictionHorizons {
+		if lead < horizon.max {
+			return horizon.label
+		}
+	}
+	return "30m+"
+}
+
+// arrivalsAndDeparturesResponse is the subset of the OBA arrivals-and-departures-for-stop
+// response used to measure prediction accuracy.
+type arrivalsAndDeparturesResponse struct {
+	Code int    `json:"code"`
+	Text string `json:"text"`
+	Data struct {
+		Entry struct {
+			ArrivalsAndDepartures []arrivalAndDeparture `json:"arrivalsAndDepartures"`
+		} `json:"entry"`
+	} `json:"data"`
+}
+
+type arrivalAndDeparture struct {
+	TripID       string `json:"tripId"`
+	RouteID      string `json:"routeId"`
+	StopID       string `json:"stopId"`
+	StopSequence int    `json:"stopSequence"`
+	ServiceDate  int64  `json:"serviceDate"`
+	Predicted    bool   `json:"predicted"`
+	// PredictedArrivalTime is in milliseconds since the epoch, or 0 without a prediction.
+	PredictedArrivalTime int64 `json:"predictedArrivalTime"`
+	// NumberOfStopsAway is negative once the vehicle has passed the stop.
+	NumberOfStopsAway int `json:"numberOfStopsAway"`
+}
+
+// predictionKey identifies an arrival of a trip at a stop.
+type predictionKey struct {
+	serverID     int
+	stopID       string
+	tripID       string
+	serviceDate  int64
+	stopSequence int
+}
+
+// trackedArrival holds the predictions made for an upcoming arrival: the first one seen in each horizon.
+type trackedArrival struct {
+	routeID  string
+	samples  map[string]time.Time
+	lastSeen time.Time
+}
+
+// PredictionTracker follows the predicted arrival times of the stops sampled for prediction
+// accuracy (`prediction_stops` of a server) from one collection cycle to the next.
+//
+// While a vehicle approaches a stop, the first prediction seen in each horizon (see
+// predictionHorizons) is kept. Once the vehicle has passed the stop, the last predicted time,
+// which OBA then bases on the vehicle's actual position, is taken as the actual arrival, and the
+// error of each kept prediction is observed in PredictionErrorSeconds. Arrivals that are never
+// seen departing (e.g. a vehicle that stopped reporting) are dropped without being measured.
+//
+// It is safe for concurrent use.
+type PredictionTracker struct {
+	mu       sync.Mutex
+	arrivals map[predictionKey]*trackedArrival
+	// measured holds the arrivals already measured, until they leave the sampling window.
+	measured map[predictionKey]time.Time
+}
+
+// NewPredictionTracker creates and returns a new, empty PredictionTracker.
+func NewPredictionTracker() *PredictionTracker {
+	return &PredictionTracker{
+		arrivals: make(map[predictionKey]*trackedArrival),
+		measured: make(map[predictionKey]time.Time),
+	}
+}
+
+// observe processes an arrival of a stop sampled at now.
+func (p *PredictionTracker) observe(server models.ObaServer, arrival arrivalAndDeparture, now time.Time) {
+	if !arrival.Predicted || arrival.PredictedArrivalTime == 0 {
+		return
+	}
+	key := predictionKey{
+		serverID:     server.ID,
+		stopID:       arrival.StopID,
+		tripID:       arrival.TripID,
+		serviceDate:  arrival.ServiceDate,
+		stopSequence: arrival.StopSequence,
+	}
+	predicted := time.UnixMilli(arrival.PredictedArrivalTime)
+
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	if _, ok := p.measured[key]; ok {
+		p.measured[key] = now
+		return
+	}
+	tracked, ok := p.arrivals[key]
+
+	if arrival.NumberOfStopsAway < 0 {
+		if !ok {
+			return
+		}
+		for horizon, prediction := range tracked.samples {
+			PredictionErrorSeconds.WithLabelValues(strconv.Itoa(server.ID), tracked.routeID, horizon).Observe(prediction.Sub(predicted).Seconds())
+		}
+		delete(p.arrivals, key)
+		p.measured[key] = now
+		return
+	}
+
+	lead := predicted.Sub(now)
+	if lead < 0 {
+		return
+	}
+	if !ok {
+		tracked = &trackedArrival{routeID: arrival.RouteID, samples: make(map[string]time.Time)}
+		p.arrivals[key] = tracked
+	}
+	tracked.lastSeen = now
+	if horizon := predictionHorizon(lead); tracked.samples[horizon].IsZero() {
+		tracked.samples[horizon] = predicted
+	}
+}
+
+// prune drops the arrivals of a server that have not been seen for predictionTTL.
+func (p *PredictionTracker) prune(serverID int, now time.Time) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	for key, tracked := range p.arrivals {
+		if key.serverID == serverID && now.Sub(tracked.lastSeen) > predictionTTL {
+			delete(p.arrivals, key)
+		}
+	}
+	for key, seen := range p.measured {
+		if key.serverID == serverID && now.Sub(seen) > predictionTTL {
+			delete(p.measured, key)
+		}
+	}
+}
+
+// Delete forgets the arrivals of a server, e.g. when it is removed from the configuration.
+func (p *PredictionTracker) Delete(serverID int) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	for key := range p.arrivals {
+		if key.serverID == serverID {
+			delete(p.arrivals, key)
+		}
+	}
+	for key := range p.measured {
+		if key.serverID == serverID {
+			delete(p.measured, key)
+		}
+	}
+}
+
+// checkPredictionAccuracy samples the arrivals and departures of the prediction stops of a
+// server and passes them to the tracker. Stops that fail are reported in the returned error,
+// and do not prevent the others from being sampled.
+func checkPredictionAccuracy(server models.ObaServer, tracker *PredictionTracker, client *http.Client, now time.Time) error {
+	if client == nil {
+		client = httpclient.Default().API
+	}
+
+	var errs []error
+	for _, stopID := range server.PredictionStops {
+		arrivals, err := fetchArrivalsAndDepartures(server, stopID, client)
+		if err != nil {
+			errs = append(errs, err)
+			continue
+		}
+		for _, arrival := range arrivals {
+			if arrival.StopID == "" {
+				arrival.StopID = stopID
+			}
+			tracker.observe(server, arrival, now)
+		}
+	}
+	tracker.prune(server.ID, now)
+	return errors.Join(errs...)
+}
+
+func fetchArrivalsAndDepartures(server models.ObaServer, stopID string, client *http.Client) ([]arrivalAndDeparture, error) {
+	query := url.Values{
+		"key":           {server.ObaApiKey},
+		"minutesBefore": {strconv.Itoa(predictionWindowBefore)},
+		"minutesAfter":  {strconv.Itoa(predictionWindowAfter)},
+	}
+	endpoint := fmt.Sprintf("%s/api/where/arrivals-and-departures-for-stop/%s.json?%s", server.ObaBaseURL, url.PathEscape(stopID), query.Encode())
+
+	resp, err := obaGet(client, server.ID, endpoint)
+	if err != nil {
+		return nil, fmt.Errorf("failed to fetch arrivals for stop %s: %w", stopID, err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("unexpected status code %d fetching arrivals for stop %s", resp.StatusCode, stopID)
+	}
+
+	var body arrivalsAndDeparturesResponse
+	if err := json.NewDecoder(resp.Body).Decode(&body); err != nil {
+		return nil, fmt.Errorf("failed to decode arrivals for stop %s: %w", stopID, err)
+	}
+	if body.Code != 0 && body.Code != http.StatusOK {
+		return nil, fmt.Errorf("OBA API error fetching arrivals for stop %s: %d %s", stopID, body.Code, body.Text)
+	}
+	return body.Data.Entry.ArrivalsAndDepartures, nil
+}
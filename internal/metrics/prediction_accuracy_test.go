@@ -0,0 +1,99 @@
+package metrics
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	dto "github.com/prometheus/client_model/go"
+	"watchdog.onebusaway.org/internal/models"
+)
+
+// histogramSample returns the sample count and sum of a histogram.
+func histogramSample(t *testing.T, metric prometheus.Observer) (uint64, float64) {
+	t.Helper()
+	pb := &dto.Metric{}
+	if err := metric.(prometheus.Metric).Write(pb); err != nil {
+		t.Fatal(err)
+	}
+	return pb.Histogram.GetSampleCount(), pb.Histogram.GetSampleSum()
+}
+
+func TestCheckPredictionAccuracy(t *testing.T) {
+	// The arrival of trip t1 at stop 1_100, as seen in each cycle.
+	var arrival arrivalAndDeparture
+	obaServer := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if !strings.HasSuffix(r.URL.Path, "/arrivals-and-departures-for-stop/1_100.json") || r.URL.Query().Get("key") != "test-key" {
+			w.WriteHeader(http.StatusNotFound)
+			return
+		}
+		var body arrivalsAndDeparturesResponse
+		body.Code = http.StatusOK
+		body.Data.Entry.ArrivalsAndDepartures = []arrivalAndDeparture{arrival}
+		_ = json.NewEncoder(w).Encode(body)
+	}))
+	defer obaServer.Close()
+
+	server := models.ObaServer{ID: 9001, ObaBaseURL: obaServer.URL, ObaApiKey: "test-key", PredictionStops: []string{"1_100"}}
+	tracker := NewPredictionTracker()
+	start := time.Date(2025, 6, 1, 8, 0, 0, 0, time.UTC)
+	actual := start.Add(15 * time.Minute)
+	arrival = arrivalAndDeparture{TripID: "t1", RouteID: "r1", StopID: "1_100", StopSequence: 4, ServiceDate: 1748736000000, Predicted: true, NumberOfStopsAway: 8}
+
+	// 15 minutes before: predicted 2 minutes late.
+	arrival.PredictedArrivalTime = actual.Add(2 * time.Minute).UnixMilli()
+	if err := checkPredictionAccuracy(server, tracker, obaServer.Client(), start); err != nil {
+		t.Fatal(err)
+	}
+	// 12 minutes before, same horizon: not sampled again.
+	arrival.PredictedArrivalTime = actual.Add(5 * time.Minute).UnixMilli()
+	if err := checkPredictionAccuracy(server, tracker, obaServer.Client(), start.Add(3*time.Minute)); err != nil {
+		t.Fatal(err)
+	}
+	// 3 minutes before: predicted 30 seconds early.
+	arrival.PredictedArrivalTime = actual.Add(-30 * time.Second).UnixMilli()
+	arrival.NumberOfStopsAway = 1
+	if err := checkPredictionAccuracy(server, tracker, obaServer.Client(), start.Add(12*time.Minute)); err != nil {
+		t.Fatal(err)
+	}
+	// The vehicle has passed the stop: the predictions are measured, once.
+	arrival.PredictedArrivalTime = actual.UnixMilli()
+	arrival.NumberOfStopsAway = -1
+	for _, at := range []time.Duration{16 * time.Minute, 17 * time.Minute} {
+		if err := checkPredictionAccuracy(server, tracker, obaServer.Client(), start.Add(at)); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	if count, sum := histogramSample(t, PredictionErrorSeconds.WithLabelValues("9001", "r1", "10-20m")); count != 1 || sum != 120 {
+		t.Errorf("expected one error of 120s 10-20 minutes ahead, got %d samples summing to %v", count, sum)
+	}
+	if count, sum := histogramSample(t, PredictionErrorSeconds.WithLabelValues("9001", "r1", "0-5m")); count != 1 || sum != -30 {
+		t.Errorf("expected one error of -30s 0-5 minutes ahead, got %d samples summing to %v", count, sum)
+	}
+
+	server.PredictionStops = append(server.PredictionStops, "1_404")
+	if err := checkPredictionAccuracy(server, tracker, obaServer.Client(), start.Add(18*time.Minute)); err == nil || !strings.Contains(err.Error(), "1_404") {
+		t.Errorf("expected an error for the failing stop, got %v", err)
+	}
+}
+
+func TestPredictionHorizon(t *testing.T) {
+	tests := map[time.Duration]string{
+		0:                "0-5m",
+		4 * time.Minute:  "0-5m",
+		5 * time.Minute:  "5-10m",
+		19 * time.Minute: "10-20m",
+		29 * time.Minute: "20-30m",
+		45 * time.Minute: "30m+",
+	}
+	for lead, want := range tests {
+		if got := predictionHorizon(lead); got != want {
+			t.Errorf("predictionHorizon(%v) = %q, want %q", lead, got, want)
+		}
+	}
+}
@@ -0,0 +1,44 @@
+package metrics
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"strconv"
+
+	onebusaway "github.com/OneBusAway/go-sdk"
+	"watchdog.onebusaway.org/internal/models"
+)
+
+// ReportProblemComment is the comment of the problem reports submitted by checkReportProblem,
+// so that the agency staff reviewing rider feedback can recognize and discard them.
+const ReportProblemComment = "[TEST] Synthetic report from the OneBusAway watchdog to verify that problem reports are accepted. Please ignore."
+
+// checkReportProblem submits a test problem report for the server's ReportProblemStopID through
+// the report-problem-with-stop endpoint of its OBA API, with the "other" code and
+// ReportProblemComment, and verifies that OBA accepts it. Riders report wrong stop names or
+// missing routes through this endpoint, and nothing else notices when it breaks.
+//
+// It sets oba_report_problem_status to 1 if the report was accepted and 0 otherwise.
+// Returns an error if the request failed or OBA did not answer with code 200.
+func checkReportProblem(server models.ObaServer, client *http.Client) error {
+	if server.ReportProblemStopID == "" {
+		return fmt.Errorf("server %d has no report_problem_stop_id", server.ID)
+	}
+	obaClient := newObaClient(server, client)
+	response, err := obaClient.ReportProblemWithStop.Get(context.Background(), server.ReportProblemStopID, onebusaway.ReportProblemWithStopGetParams{
+		Code:        onebusaway.F(onebusaway.ReportProblemWithStopGetParamsCodeOther),
+		UserComment: onebusaway.F(ReportProblemComment),
+	})
+	status := ObaReportProblemStatus.WithLabelValues(strconv.Itoa(server.ID))
+	if err != nil {
+		status.Set(0)
+		return fmt.Errorf("failed to report a problem with stop %s: %w", server.ReportProblemStopID, err)
+	}
+	if response.Code != http.StatusOK {
+		status.Set(0)
+		return fmt.Errorf("problem report for stop %s was not accepted: code %d (%s)", server.ReportProblemStopID, response.Code, response.Text)
+	}
+	status.Set(1)
+	return nil
+}
@@ -0,0 +1,49 @@
+package metrics
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"strings"
+	"testing"
+
+	"watchdog.onebusaway.org/internal/models"
+)
+
+func TestCheckReportProblem(t *testing.T) {
+	code := 200
+	var query string
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/api/where/report-problem-with-stop/1_75403.json" {
+			t.Errorf("unexpected path %s", r.URL.Path)
+		}
+		query = r.URL.RawQuery
+		w.Header().Set("Content-Type", "application/json")
+		_, _ = w.Write([]byte(`{"code":` + strconv.Itoa(code) + `,"currentTime":1,"text":"OK","version":2}`))
+	}))
+	defer ts.Close()
+
+	server := models.ObaServer{ID: 9301, ObaBaseURL: ts.URL, ObaApiKey: "test-key", ReportProblemStopID: "1_75403"}
+	if err := checkReportProblem(server, nil); err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(query, "code=other") || !strings.Contains(query, "userComment=%5BTEST%5D") {
+		t.Errorf("expected a test report with the other code, got query %s", query)
+	}
+	if value, _ := getMetricValue(ObaReportProblemStatus, map[string]string{"server_id": "9301"}); value != 1 {
+		t.Errorf("expected oba_report_problem_status 1, got %v", value)
+	}
+
+	code = 404
+	if err := checkReportProblem(server, nil); err == nil {
+		t.Error("expected an error when OBA does not accept the report")
+	}
+	if value, _ := getMetricValue(ObaReportProblemStatus, map[string]string{"server_id": "9301"}); value != 0 {
+		t.Errorf("expected oba_report_problem_status 0, got %v", value)
+	}
+
+	server.ReportProblemStopID = ""
+	if err := checkReportProblem(server, nil); err == nil {
+		t.Error("expected an error without a stop to report")
+	}
+}
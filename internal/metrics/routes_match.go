@@ -0,0 +1,152 @@
+package metrics
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+	"sort"
+	"strconv"
+
+	"github.com/getsentry/sentry-go"
+	"watchdog.onebusaway.org/internal/gtfs"
+	"watchdog.onebusaway.org/internal/httpclient"
+	"watchdog.onebusaway.org/internal/models"
+	"watchdog.onebusaway.org/internal/report"
+)
+
+// maxReportedRouteIDs caps the route IDs attached to a Sentry report of a route discrepancy.
+const maxReportedRouteIDs = 20
+
+// routesForAgencyResponse is the subset of the OBA routes-for-agency response used to compare routes.
+type routesForAgencyResponse struct {
+	Code int    `json:"code"`
+	Text string `json:"text"`
+	Data struct {
+		List []struct {
+			ID string `json:"id"`
+		} `json:"list"`
+	} `json:"data"`
+}
+
+// checkRoutesMatch compares, for each agency of the static GTFS bundle of a server, the routes
+// of the bundle with the routes returned by the OBA `routes-for-agency` endpoint.
+//
+// OBA route IDs are the GTFS route IDs prefixed with the agency ID. For a bundle with a single
+// agency, server.AgencyID (if configured) is used as the OBA agency ID, as for stops (see
+// checkStopsMatch); routes without an agency belong to the first agency.
+//
+// It sets, per agency:
+//   - gtfs_static_routes_missing: routes of the bundle that the OBA API does not return.
+//   - gtfs_static_routes_extra: routes returned by the OBA API that are not in the bundle.
+//
+// When the discrepancy of an agency ((missing + extra) / routes of the bundle) exceeds
+// threshold, it is reported to Sentry with the route IDs, and the returned error lists the
+// agencies concerned. Agencies whose routes cannot be fetched are also reported in the error.
+func checkRoutesMatch(staticStore gtfs.StaticStore, server models.ObaServer, client *http.Client, threshold float64) error {
+	staticData, ok := staticStore.Get(server.ID)
+	if !ok || staticData == nil {
+		return fmt.Errorf("there is no bundle for server %v", server.ID)
+	}
+	if len(staticData.Agencies) == 0 {
+		return fmt.Errorf("no agencies found in GTFS bundle for server %v", server.ID)
+	}
+	if client == nil {
+		client = httpclient.Default().API
+	}
+
+	// Static route IDs by GTFS agency ID.
+	staticRoutes := make(map[string][]string)
+	for _, route := range staticData.Routes {
+		agencyID := staticData.Agencies[0].Id
+		if route.Agency != nil {
+			agencyID = route.Agency.Id
+		}
+		staticRoutes[agencyID] = append(staticRoutes[agencyID], route.Id)
+	}
+
+	serverID := strconv.Itoa(server.ID)
+	var errs []error
+	for _, agency := range staticData.Agencies {
+		obaAgencyID := agency.Id
+		if len(staticData.Agencies) == 1 && server.AgencyID != "" {
+			obaAgencyID = server.AgencyID
+		}
+
+		obaRoutes, err := fetchRoutesForAgency(server, obaAgencyID, client)
+		if err != nil {
+			errs = append(errs, err)
+			continue
+		}
+
+		var missing []string
+		for _, routeID := range staticRoutes[agency.Id] {
+			id := obaAgencyID + "_" + routeID
+			if obaRoutes[id] {
+				delete(obaRoutes, id)
+			} else {
+				missing = append(missing, id)
+			}
+		}
+		extra := make([]string, 0, len(obaRoutes))
+		for id := range obaRoutes {
+			extra = append(extra, id)
+		}
+		sort.Strings(extra)
+
+		StaticRoutesMissing.WithLabelValues(serverID, obaAgencyID).Set(float64(len(missing)))
+		StaticRoutesExtra.WithLabelValues(serverID, obaAgencyID).Set(float64(len(extra)))
+
+		total := len(staticRoutes[agency.Id])
+		discrepancy := float64(len(missing) + len(extra))
+		if total > 0 {
+			discrepancy /= float64(total)
+		}
+		if discrepancy > threshold {
+			err := fmt.Errorf("routes of agency %s differ between the GTFS bundle and the OBA API: %d missing, %d extra", obaAgencyID, len(missing), len(extra))
+			report.ReportErrorWithSentryOptions(err, report.SentryReportOptions{
+				Tags: map[string]string{
+					"server_id": serverID,
+					"agency_id": obaAgencyID,
+				},
+				ExtraContext: map[string]interface{}{
+					"missing_routes": missing[:min(len(missing), maxReportedRouteIDs)],
+					"extra_routes":   extra[:min(len(extra), maxReportedRouteIDs)],
+					"discrepancy":    discrepancy,
+				},
+				Level: sentry.LevelWarning,
+			})
+			errs = append(errs, err)
+		}
+	}
+	return errors.Join(errs...)
+}
+
+// fetchRoutesForAgency returns the set of route IDs the OBA API of a server has for an agency.
+func fetchRoutesForAgency(server models.ObaServer, agencyID string, client *http.Client) (map[string]bool, error) {
+	endpoint := fmt.Sprintf("%s/api/where/routes-for-agency/%s.json?%s", server.ObaBaseURL, url.PathEscape(agencyID), url.Values{"key": {server.ObaApiKey}}.Encode())
+
+	resp, err := obaGet(client, server.ID, endpoint)
+	if err != nil {
+		return nil, fmt.Errorf("failed to fetch routes of agency %s: %w", agencyID, err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("unexpected status code %d fetching routes of agency %s", resp.StatusCode, agencyID)
+	}
+
+	var body routesForAgencyResponse
+	if err := json.NewDecoder(resp.Body).Decode(&body); err != nil {
+		return nil, fmt.Errorf("failed to decode routes of agency %s: %w", agencyID, err)
+	}
+	if body.Code != 0 && body.Code != http.StatusOK {
+		return nil, fmt.Errorf("OBA API error fetching routes of agency %s: %d %s", agencyID, body.Code, body.Text)
+	}
+
+	routes := make(map[string]bool, len(body.Data.List))
+	for _, route := range body.Data.List {
+		routes[route.ID] = true
+	}
+	return routes, nil
+}
@@ -0,0 +1,66 @@
+package metrics
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	remoteGtfs "github.com/OneBusAway/go-gtfs"
+	"watchdog.onebusaway.org/internal/gtfs"
+	"watchdog.onebusaway.org/internal/models"
+)
+
+func TestCheckRoutesMatch(t *testing.T) {
+	obaServer := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/api/where/routes-for-agency/1.json":
+			_, _ = w.Write([]byte(`{"code":200,"data":{"list":[{"id":"1_10"},{"id":"1_20"},{"id":"1_99"}]}}`))
+		case "/api/where/routes-for-agency/40.json":
+			_, _ = w.Write([]byte(`{"code":200,"data":{"list":[{"id":"40_1"}]}}`))
+		default:
+			w.WriteHeader(http.StatusNotFound)
+		}
+	}))
+	defer obaServer.Close()
+
+	server := models.ObaServer{ID: 9003, ObaBaseURL: obaServer.URL, ObaApiKey: "test-key"}
+	staticStore := gtfs.NewStaticStore()
+	if err := checkRoutesMatch(staticStore, server, obaServer.Client(), 0.05); err == nil {
+		t.Error("expected an error when there is no bundle for the server")
+	}
+
+	metro := &remoteGtfs.Agency{Id: "1"}
+	sound := &remoteGtfs.Agency{Id: "40"}
+	staticStore.Set(server.ID, &models.StaticData{
+		Agencies: []remoteGtfs.Agency{*metro, *sound},
+		Routes: []remoteGtfs.Route{
+			{Id: "10", Agency: metro}, {Id: "20", Agency: metro}, {Id: "30", Agency: metro},
+			{Id: "1", Agency: sound},
+		},
+	})
+	err := checkRoutesMatch(staticStore, server, obaServer.Client(), 0.05)
+	if err == nil || !strings.Contains(err.Error(), "agency 1 ") || strings.Contains(err.Error(), "agency 40") {
+		t.Errorf("expected a discrepancy for agency 1 only, got %v", err)
+	}
+	for agency, want := range map[string][2]float64{"1": {1, 1}, "40": {0, 0}} {
+		labels := map[string]string{"server_id": "9003", "agency_id": agency}
+		missing, _ := getMetricValue(StaticRoutesMissing, labels)
+		extra, _ := getMetricValue(StaticRoutesExtra, labels)
+		if missing != want[0] || extra != want[1] {
+			t.Errorf("agency %s: expected %v missing and %v extra routes, got %v and %v", agency, want[0], want[1], missing, extra)
+		}
+	}
+
+	// Under the threshold, the discrepancy is only exported.
+	if err := checkRoutesMatch(staticStore, server, obaServer.Client(), 1); err != nil {
+		t.Errorf("expected no error under the threshold, got %v", err)
+	}
+
+	// A single agency is looked up with the configured agency ID; unknown agencies fail.
+	server.AgencyID = "unknown"
+	staticStore.Set(server.ID, &models.StaticData{Agencies: []remoteGtfs.Agency{*metro}, Routes: []remoteGtfs.Route{{Id: "10"}}})
+	if err := checkRoutesMatch(staticStore, server, obaServer.Client(), 1); err == nil || !strings.Contains(err.Error(), "status code 404") {
+		t.Errorf("expected the failed lookup to be an error, got %v", err)
+	}
+}
@@ -3,10 +3,9 @@ package metrics
 import (
 	"context"
 	"fmt"
+	"net/http"
 	"strconv"
 
-	onebusaway "github.com/OneBusAway/go-sdk"
-	"github.com/OneBusAway/go-sdk/option"
 	"watchdog.onebusaway.org/internal/models"
 	"watchdog.onebusaway.org/internal/report"
 	"watchdog.onebusaway.org/internal/utils"
@@ -24,14 +23,11 @@ import (
 //
 // Returns:
 //   - None (side effects include reporting to Prometheus and Sentry).
-func serverPing(server models.ObaServer) bool {
-	client := onebusaway.NewClient(
-		option.WithAPIKey(server.ObaApiKey),
-		option.WithBaseURL(server.ObaBaseURL),
-	)
+func serverPing(server models.ObaServer, client *http.Client) bool {
+	obaClient := newObaClient(server, client)
 
 	ctx := context.Background()
-	response, err := client.CurrentTime.Get(ctx)
+	response, err := obaClient.CurrentTime.Get(ctx)
 
 	if err != nil {
 		err := fmt.Errorf("failed to ping OBA server %s: %v", server.ObaBaseURL, err)
@@ -0,0 +1,46 @@
+package metrics
+
+import (
+	"time"
+
+	"watchdog.onebusaway.org/internal/models"
+)
+
+// ServiceCoverageDays is the number of days covered by the service coverage of the status API.
+const ServiceCoverageDays = 60
+
+// ServiceDay is the number of services of a bundle active on a day.
+type ServiceDay struct {
+	// Date is the day, as YYYY-MM-DD in the time zone of the bundle.
+	Date           string `json:"date"`
+	ActiveServices int    `json:"active_services"`
+}
+
+// ServiceCoverage returns the number of services of a bundle active, according to its calendar,
+// on each of the given number of days starting with the day of from in the time zone of the
+// bundle. Days without any service, within the bundle or past its end, stand out as gaps.
+func ServiceCoverage(staticData *models.StaticData, from time.Time, days int) []ServiceDay {
+	from = from.In(bundleLocation(staticData))
+	first := time.Date(from.Year(), from.Month(), from.Day(), 0, 0, 0, 0, from.Location())
+	coverage := make([]ServiceDay, days)
+	for i := range coverage {
+		day := first.AddDate(0, 0, i)
+		coverage[i].Date = day.Format(time.DateOnly)
+		for _, service := range staticData.Services {
+			if serviceRunsOn(service, day) {
+				coverage[i].ActiveServices++
+			}
+		}
+	}
+	return coverage
+}
+
+// bundleLocation returns the time zone of the first agency of a bundle, or UTC if it has none.
+func bundleLocation(staticData *models.StaticData) *time.Location {
+	if len(staticData.Agencies) > 0 {
+		if location, err := time.LoadLocation(staticData.Agencies[0].Timezone); err == nil {
+			return location
+		}
+	}
+	return time.UTC
+}
@@ -0,0 +1,39 @@
+package metrics
+
+import (
+	"testing"
+	"time"
+
+	remoteGtfs "github.com/OneBusAway/go-gtfs"
+	"watchdog.onebusaway.org/internal/models"
+)
+
+func TestServiceCoverage(t *testing.T) {
+	date := func(day int) time.Time { return time.Date(2025, 6, day, 0, 0, 0, 0, time.UTC) }
+	staticData := &models.StaticData{
+		Agencies: []remoteGtfs.Agency{{Timezone: "America/Los_Angeles"}},
+		Services: []remoteGtfs.Service{
+			// Weekdays until Friday June 6, except June 4.
+			{Id: "weekday", Monday: true, Tuesday: true, Wednesday: true, Thursday: true, Friday: true, StartDate: date(1), EndDate: date(6), RemovedDates: []time.Time{date(4)}},
+			// Every day until June 5.
+			{Id: "daily", Monday: true, Tuesday: true, Wednesday: true, Thursday: true, Friday: true, Saturday: true, Sunday: true, StartDate: date(1), EndDate: date(5)},
+			// A special event on Sunday June 8.
+			{Id: "event", AddedDates: []time.Time{date(8)}},
+		},
+	}
+
+	// 2025-06-02 03:00 UTC is still June 1 in Los Angeles.
+	coverage := ServiceCoverage(staticData, time.Date(2025, 6, 2, 3, 0, 0, 0, time.UTC), 9)
+	expected := []int{1, 2, 2, 1, 2, 1, 0, 1, 0}
+	if len(coverage) != len(expected) {
+		t.Fatalf("expected %d days, got %d", len(expected), len(coverage))
+	}
+	for i, want := range expected {
+		if coverage[i].ActiveServices != want {
+			t.Errorf("%s: expected %d active services, got %d", coverage[i].Date, want, coverage[i].ActiveServices)
+		}
+	}
+	if coverage[0].Date != "2025-06-01" || coverage[8].Date != "2025-06-09" {
+		t.Errorf("expected June 1 to June 9, got %s to %s", coverage[0].Date, coverage[8].Date)
+	}
+}
@@ -0,0 +1,122 @@
+package metrics
+
+import (
+	"encoding/json"
+	"fmt"
+	"math/rand/v2"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+
+	remoteGtfs "github.com/OneBusAway/go-gtfs"
+	"watchdog.onebusaway.org/internal/gtfs"
+	"watchdog.onebusaway.org/internal/httpclient"
+	"watchdog.onebusaway.org/internal/models"
+)
+
+// stopSampleSize is the number of stops of the static bundle looked up in the OBA API per cycle.
+const stopSampleSize = 10
+
+// sampleStops returns up to n stops picked at random, so that successive cycles cover the whole bundle.
+func sampleStops(stops []remoteGtfs.Stop, n int) []remoteGtfs.Stop {
+	if len(stops) <= n {
+		return stops
+	}
+	sample := make([]remoteGtfs.Stop, 0, n)
+	for _, i := range rand.Perm(len(stops))[:n] {
+		sample = append(sample, stops[i])
+	}
+	return sample
+}
+
+// checkStopsMatch looks up a sample of the stops of the static GTFS bundle of a server in the
+// OBA `stop` endpoint, to catch bundles that OBA failed to ingest (e.g. a bundle that is served
+// by the agency but was never built into OBA). It is the stop-level counterpart of
+// checkAgenciesWithCoverageMatch.
+//
+// OBA stop IDs are prefixed with an agency ID: server.AgencyID if configured, otherwise the
+// first agency of the bundle.
+//
+// It sets:
+//   - gtfs_static_stops_matched: the number of sampled stops found by the OBA API.
+//   - gtfs_static_stops_missing: the number of sampled stops the OBA API does not know.
+//
+// Returns an error if there is no bundle, if a lookup fails, or if any sampled stop is missing.
+func checkStopsMatch(staticStore gtfs.StaticStore, server models.ObaServer, client *http.Client, sampleSize int) error {
+	staticData, ok := staticStore.Get(server.ID)
+	if !ok || staticData == nil {
+		return fmt.Errorf("there is no bundle for server %v", server.ID)
+	}
+	agencyID := server.AgencyID
+	if agencyID == "" && len(staticData.Agencies) > 0 {
+		agencyID = staticData.Agencies[0].Id
+	}
+	if client == nil {
+		client = httpclient.Default().API
+	}
+
+	var missing []string
+	matched := 0
+	for _, stop := range sampleStops(staticData.Stops, sampleSize) {
+		stopID := stop.Id
+		if agencyID != "" {
+			stopID = agencyID + "_" + stop.Id
+		}
+		found, err := obaStopExists(server, stopID, client)
+		if err != nil {
+			return err
+		}
+		if found {
+			matched++
+		} else {
+			missing = append(missing, stopID)
+		}
+	}
+
+	serverID := strconv.Itoa(server.ID)
+	StaticStopsMatched.WithLabelValues(serverID).Set(float64(matched))
+	StaticStopsMissing.WithLabelValues(serverID).Set(float64(len(missing)))
+
+	if len(missing) > 0 {
+		return fmt.Errorf("%d of %d sampled stops of the GTFS bundle are missing from the OBA API: %s", len(missing), matched+len(missing), strings.Join(missing, ", "))
+	}
+	return nil
+}
+
+// obaStopResponse is the subset of the OBA `stop` response used to tell whether a stop exists.
+type obaStopResponse struct {
+	Code int    `json:"code"`
+	Text string `json:"text"`
+}
+
+// obaStopExists reports whether the OBA API of a server knows a stop. OBA answers unknown
+// stops with 404, either as the HTTP status or as the code of the response body.
+func obaStopExists(server models.ObaServer, stopID string, client *http.Client) (bool, error) {
+	endpoint := fmt.Sprintf("%s/api/where/stop/%s.json?%s", server.ObaBaseURL, url.PathEscape(stopID), url.Values{"key": {server.ObaApiKey}}.Encode())
+
+	resp, err := obaGet(client, server.ID, endpoint)
+	if err != nil {
+		return false, fmt.Errorf("failed to fetch stop %s: %w", stopID, err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode == http.StatusNotFound {
+		return false, nil
+	}
+	if resp.StatusCode != http.StatusOK {
+		return false, fmt.Errorf("unexpected status code %d fetching stop %s", resp.StatusCode, stopID)
+	}
+
+	var body obaStopResponse
+	if err := json.NewDecoder(resp.Body).Decode(&body); err != nil {
+		return false, fmt.Errorf("failed to decode stop %s: %w", stopID, err)
+	}
+	switch body.Code {
+	case 0, http.StatusOK:
+		return true, nil
+	case http.StatusNotFound:
+		return false, nil
+	default:
+		return false, fmt.Errorf("OBA API error fetching stop %s: %d %s", stopID, body.Code, body.Text)
+	}
+}
@@ -0,0 +1,84 @@
+package metrics
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	remoteGtfs "github.com/OneBusAway/go-gtfs"
+	"watchdog.onebusaway.org/internal/gtfs"
+	"watchdog.onebusaway.org/internal/models"
+)
+
+func TestCheckStopsMatch(t *testing.T) {
+	obaServer := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/api/where/stop/1_100.json":
+			_, _ = w.Write([]byte(`{"code":200,"text":"OK","data":{"entry":{"id":"1_100"}}}`))
+		case "/api/where/stop/1_200.json":
+			// Some OBA versions answer unknown stops with 200 and the error code in the body.
+			_, _ = w.Write([]byte(`{"code":404,"text":"resource not found"}`))
+		case "/api/where/stop/1_500.json":
+			w.WriteHeader(http.StatusInternalServerError)
+		default:
+			w.WriteHeader(http.StatusNotFound)
+		}
+	}))
+	defer obaServer.Close()
+
+	server := models.ObaServer{ID: 9002, ObaBaseURL: obaServer.URL, ObaApiKey: "test-key"}
+	staticStore := gtfs.NewStaticStore()
+	if err := checkStopsMatch(staticStore, server, obaServer.Client(), stopSampleSize); err == nil {
+		t.Error("expected an error when there is no bundle for the server")
+	}
+
+	staticStore.Set(server.ID, &models.StaticData{
+		Agencies: []remoteGtfs.Agency{{Id: "1"}},
+		Stops:    []remoteGtfs.Stop{{Id: "100"}, {Id: "200"}, {Id: "300"}},
+	})
+	err := checkStopsMatch(staticStore, server, obaServer.Client(), stopSampleSize)
+	if err == nil || !strings.Contains(err.Error(), "2 of 3") || !strings.Contains(err.Error(), "1_200, 1_300") {
+		t.Errorf("expected the missing stops in the error, got %v", err)
+	}
+	labels := map[string]string{"server_id": "9002"}
+	if value, err := getMetricValue(StaticStopsMatched, labels); err != nil || value != 1 {
+		t.Errorf("expected 1 matched stop, got %v (%v)", value, err)
+	}
+	if value, err := getMetricValue(StaticStopsMissing, labels); err != nil || value != 2 {
+		t.Errorf("expected 2 missing stops, got %v (%v)", value, err)
+	}
+
+	// The configured agency ID takes precedence over the bundle's.
+	server.AgencyID = "1"
+	staticStore.Set(server.ID, &models.StaticData{
+		Agencies: []remoteGtfs.Agency{{Id: "40"}},
+		Stops:    []remoteGtfs.Stop{{Id: "100"}},
+	})
+	if err := checkStopsMatch(staticStore, server, obaServer.Client(), stopSampleSize); err != nil {
+		t.Errorf("expected all stops to match, got %v", err)
+	}
+
+	staticStore.Set(server.ID, &models.StaticData{Stops: []remoteGtfs.Stop{{Id: "500"}}})
+	if err := checkStopsMatch(staticStore, server, obaServer.Client(), stopSampleSize); err == nil || !strings.Contains(err.Error(), "status code 500") {
+		t.Errorf("expected the failed lookup to be an error, got %v", err)
+	}
+}
+
+func TestSampleStops(t *testing.T) {
+	stops := make([]remoteGtfs.Stop, 50)
+	for i := range stops {
+		stops[i].Id = strings.Repeat("s", i+1)
+	}
+	sample := sampleStops(stops, 10)
+	seen := make(map[string]bool)
+	for _, stop := range sample {
+		seen[stop.Id] = true
+	}
+	if len(sample) != 10 || len(seen) != 10 {
+		t.Errorf("expected 10 distinct stops, got %d (%d distinct)", len(sample), len(seen))
+	}
+	if got := sampleStops(stops[:3], 10); len(got) != 3 {
+		t.Errorf("expected all 3 stops, got %d", len(got))
+	}
+}
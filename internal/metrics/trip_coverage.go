@@ -0,0 +1,135 @@
+package metrics
+
+import (
+	"fmt"
+	"strconv"
+	"time"
+
+	remoteGtfs "github.com/OneBusAway/go-gtfs"
+	"watchdog.onebusaway.org/internal/gtfs"
+	"watchdog.onebusaway.org/internal/models"
+)
+
+// checkTripCoverage computes the fraction of the trips scheduled to be running at currentTime,
+// according to the calendars and stop times of the static GTFS bundle of a server, that have a
+// vehicle or a trip update in the GTFS-RT feed. A low coverage means that buses run without
+// realtime data, which riders see as scheduled-only arrivals.
+//
+// Trips are considered active from their first departure to their last arrival, in the
+// timezone of the first agency of the bundle. Trips of the previous service day are included,
+// as GTFS times may exceed 24:00:00.
+//
+// It sets:
+//   - realtime_trips_scheduled_active: the number of trips scheduled to be running.
+//   - realtime_trip_coverage_ratio: the fraction of them that are in the GTFS-RT feed.
+//   - realtime_route_trip_coverage_ratio: the same fraction, per route.
+//
+// The ratios are not set when no trip is scheduled to be running, e.g. at night.
+// Returns an error if there is no bundle or no GTFS-RT data for the server.
+func checkTripCoverage(staticStore gtfs.StaticStore, realtimeStore gtfs.RealtimeStore, server models.ObaServer, currentTime time.Time) error {
+	staticData, ok := staticStore.Get(server.ID)
+	if !ok || staticData == nil {
+		return fmt.Errorf("there is no bundle for server %v", server.ID)
+	}
+	realtimeData := realtimeStore.Get()
+	if realtimeData == nil {
+		return fmt.Errorf("there is no GTFS-RT data for server %v", server.ID)
+	}
+
+	realtimeTrips := make(map[string]bool, len(realtimeData.Trips)+len(realtimeData.Vehicles))
+	for _, trip := range realtimeData.Trips {
+		realtimeTrips[trip.ID.ID] = true
+	}
+	for _, vehicle := range realtimeData.Vehicles {
+		if vehicle.Trip != nil {
+			realtimeTrips[vehicle.Trip.ID.ID] = true
+		}
+	}
+
+	type coverage struct{ active, covered int }
+	var total coverage
+	routes := make(map[string]*coverage)
+	for _, trip := range activeTrips(staticData, currentTime.In(bundleLocation(staticData))) {
+		route := routes[trip.RouteID]
+		if route == nil {
+			route = &coverage{}
+			routes[trip.RouteID] = route
+		}
+		total.active++
+		route.active++
+		if realtimeTrips[trip.TripID] {
+			total.covered++
+			route.covered++
+		}
+	}
+
+	serverID := strconv.Itoa(server.ID)
+	RealtimeTripsScheduledActive.WithLabelValues(serverID).Set(float64(total.active))
+	// Routes without running trips are dropped rather than left at their last value.
+	RealtimeRouteTripCoverageRatio.DeletePartialMatch(map[string]string{"server_id": serverID})
+	if total.active == 0 {
+		RealtimeTripCoverageRatio.DeleteLabelValues(serverID)
+		return nil
+	}
+	RealtimeTripCoverageRatio.WithLabelValues(serverID).Set(float64(total.covered) / float64(total.active))
+	for routeID, route := range routes {
+		RealtimeRouteTripCoverageRatio.WithLabelValues(serverID, routeID).Set(float64(route.covered) / float64(route.active))
+	}
+	return nil
+}
+
+// activeTrips returns the trips of a bundle scheduled to be running at now, which must be in
+// the timezone of the bundle.
+func activeTrips(staticData *models.StaticData, now time.Time) []models.TripSpan {
+	services := make(map[string]remoteGtfs.Service, len(staticData.Services))
+	for _, service := range staticData.Services {
+		services[service.Id] = service
+	}
+
+	today := time.Date(now.Year(), now.Month(), now.Day(), 0, 0, 0, 0, now.Location())
+	serviceDays := []time.Time{today, today.AddDate(0, 0, -1)}
+
+	var active []models.TripSpan
+	for _, trip := range staticData.TripSpans {
+		service, ok := services[trip.ServiceID]
+		if !ok {
+			continue
+		}
+		for _, day := range serviceDays {
+			elapsed := now.Sub(day)
+			if elapsed >= trip.Start && elapsed <= trip.End && serviceRunsOn(service, day) {
+				active = append(active, trip)
+				break
+			}
+		}
+	}
+	return active
+}
+
+// serviceRunsOn reports whether a service runs on a date, according to its calendar.txt
+// weekdays and date range and its calendar_dates.txt exceptions.
+func serviceRunsOn(service remoteGtfs.Service, date time.Time) bool {
+	day := date.Format("20060102")
+	for _, removed := range service.RemovedDates {
+		if removed.Format("20060102") == day {
+			return false
+		}
+	}
+	for _, added := range service.AddedDates {
+		if added.Format("20060102") == day {
+			return true
+		}
+	}
+	if service.StartDate.IsZero() || day < service.StartDate.Format("20060102") || day > service.EndDate.Format("20060102") {
+		return false
+	}
+	return [...]bool{
+		time.Sunday:    service.Sunday,
+		time.Monday:    service.Monday,
+		time.Tuesday:   service.Tuesday,
+		time.Wednesday: service.Wednesday,
+		time.Thursday:  service.Thursday,
+		time.Friday:    service.Friday,
+		time.Saturday:  service.Saturday,
+	}[date.Weekday()]
+}
@@ -0,0 +1,105 @@
+package metrics
+
+import (
+	"testing"
+	"time"
+
+	remoteGtfs "github.com/OneBusAway/go-gtfs"
+	"watchdog.onebusaway.org/internal/gtfs"
+	"watchdog.onebusaway.org/internal/models"
+)
+
+func TestCheckTripCoverage(t *testing.T) {
+	server := models.ObaServer{ID: 9004}
+	staticStore := gtfs.NewStaticStore()
+	realtimeStore := gtfs.NewRealtimeStore()
+	// Wednesday 2025-01-15 at 08:00 in Los Angeles.
+	location, err := time.LoadLocation("America/Los_Angeles")
+	if err != nil {
+		t.Fatal(err)
+	}
+	now := time.Date(2025, 1, 15, 8, 0, 0, 0, location)
+
+	if err := checkTripCoverage(staticStore, realtimeStore, server, now); err == nil {
+		t.Error("expected an error when there is no bundle for the server")
+	}
+
+	date := func(day int) time.Time { return time.Date(2025, 1, day, 0, 0, 0, 0, time.UTC) }
+	staticStore.Set(server.ID, &models.StaticData{
+		Agencies: []remoteGtfs.Agency{{Id: "1", Timezone: "America/Los_Angeles"}},
+		Services: []remoteGtfs.Service{
+			{Id: "weekday", Monday: true, Tuesday: true, Wednesday: true, Thursday: true, Friday: true, StartDate: date(1), EndDate: date(31)},
+			{Id: "weekend", Saturday: true, Sunday: true, StartDate: date(1), EndDate: date(31), AddedDates: []time.Time{date(15)}},
+			{Id: "holiday", Wednesday: true, StartDate: date(1), EndDate: date(31), RemovedDates: []time.Time{date(15)}},
+		},
+		TripSpans: []models.TripSpan{
+			{TripID: "a1", RouteID: "A", ServiceID: "weekday", Start: 7 * time.Hour, End: 9 * time.Hour},
+			{TripID: "a2", RouteID: "A", ServiceID: "weekday", Start: 7 * time.Hour, End: 9 * time.Hour},
+			{TripID: "b1", RouteID: "B", ServiceID: "weekend", Start: 7 * time.Hour, End: 9 * time.Hour},
+			// Not running: later today, removed today, and a night trip of yesterday's service.
+			{TripID: "a3", RouteID: "A", ServiceID: "weekday", Start: 10 * time.Hour, End: 11 * time.Hour},
+			{TripID: "c1", RouteID: "C", ServiceID: "holiday", Start: 7 * time.Hour, End: 9 * time.Hour},
+			{TripID: "a4", RouteID: "A", ServiceID: "weekday", Start: 25 * time.Hour, End: 26 * time.Hour},
+			// A trip of yesterday's service running past midnight.
+			{TripID: "b2", RouteID: "B", ServiceID: "weekday", Start: 31 * time.Hour, End: 33 * time.Hour},
+		},
+	})
+	if err := checkTripCoverage(staticStore, realtimeStore, server, now); err == nil {
+		t.Error("expected an error when there is no GTFS-RT data")
+	}
+
+	realtimeStore.Set(&models.RealtimeData{
+		Vehicles: []remoteGtfs.Vehicle{{Trip: &remoteGtfs.Trip{ID: remoteGtfs.TripID{ID: "a1"}}}, {}},
+		Trips:    []remoteGtfs.Trip{{ID: remoteGtfs.TripID{ID: "b2"}}, {ID: remoteGtfs.TripID{ID: "c1"}}},
+	})
+	if err := checkTripCoverage(staticStore, realtimeStore, server, now); err != nil {
+		t.Fatal(err)
+	}
+
+	if active, _ := getMetricValue(RealtimeTripsScheduledActive, map[string]string{"server_id": "9004"}); active != 4 {
+		t.Errorf("expected 4 active trips, got %v", active)
+	}
+	if ratio, _ := getMetricValue(RealtimeTripCoverageRatio, map[string]string{"server_id": "9004"}); ratio != 0.5 {
+		t.Errorf("expected a coverage ratio of 0.5, got %v", ratio)
+	}
+	for route, want := range map[string]float64{"A": 0.5, "B": 0.5} {
+		if ratio, _ := getMetricValue(RealtimeRouteTripCoverageRatio, map[string]string{"server_id": "9004", "route_id": route}); ratio != want {
+			t.Errorf("route %s: expected a coverage ratio of %v, got %v", route, want, ratio)
+		}
+	}
+	// getMetricValue would create the series; deleting reports whether it exists.
+	if RealtimeRouteTripCoverageRatio.DeleteLabelValues("9004", "C") {
+		t.Error("expected no coverage ratio for a route without running trips")
+	}
+
+	// At night, no trip is scheduled and the ratios are removed.
+	if err := checkTripCoverage(staticStore, realtimeStore, server, now.Add(-5*time.Hour)); err != nil {
+		t.Fatal(err)
+	}
+	if RealtimeTripCoverageRatio.DeleteLabelValues("9004") {
+		t.Error("expected no coverage ratio without running trips")
+	}
+}
+
+func TestNewStaticDataTripSpans(t *testing.T) {
+	route := &remoteGtfs.Route{Id: "A"}
+	service := &remoteGtfs.Service{Id: "weekday"}
+	data := models.NewStaticData(&remoteGtfs.Static{Trips: []remoteGtfs.ScheduledTrip{
+		{ID: "t1", Route: route, Service: service, StopTimes: []remoteGtfs.ScheduledStopTime{
+			{ArrivalTime: 8 * time.Hour, DepartureTime: 8 * time.Hour},
+			{ArrivalTime: 9 * time.Hour, DepartureTime: 9 * time.Hour},
+		}},
+		{ID: "t2", Route: route, Service: service, StopTimes: []remoteGtfs.ScheduledStopTime{
+			{ArrivalTime: 6 * time.Hour, DepartureTime: 6 * time.Hour},
+			{ArrivalTime: 6*time.Hour + 30*time.Minute, DepartureTime: 6*time.Hour + 30*time.Minute},
+		}, Frequencies: []remoteGtfs.Frequency{{StartTime: 6 * time.Hour, EndTime: 10 * time.Hour}}},
+		{ID: "t3", Route: route, Service: service},
+	}})
+	want := []models.TripSpan{
+		{TripID: "t1", RouteID: "A", ServiceID: "weekday", Start: 8 * time.Hour, End: 9 * time.Hour},
+		{TripID: "t2", RouteID: "A", ServiceID: "weekday", Start: 6 * time.Hour, End: 10*time.Hour + 30*time.Minute},
+	}
+	if len(data.TripSpans) != len(want) || data.TripSpans[0] != want[0] || data.TripSpans[1] != want[1] {
+		t.Errorf("expected trip spans %+v, got %+v", want, data.TripSpans)
+	}
+}
@@ -0,0 +1,94 @@
+package metrics
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"io"
+	"net/http"
+	"strconv"
+	"time"
+
+	"watchdog.onebusaway.org/internal/models"
+)
+
+// maxURLCheckBodySize bounds the part of a response body searched for the keyword of a URL target.
+const maxURLCheckBodySize = 1 << 20
+
+// URLCheckResult is the outcome of the uptime checks of an entry of type "url" (see
+// checkURLTarget). Each error is nil if the check passed; KeywordErr and LatencyErr are also
+// nil if the check is not configured or the request failed.
+type URLCheckResult struct {
+	StatusCode int
+	// Latency is the time until the response headers were received.
+	Latency    time.Duration
+	StatusErr  error
+	KeywordErr error
+	LatencyErr error
+}
+
+// OK reports whether every check of the target passed.
+func (r URLCheckResult) OK() bool {
+	return r.StatusErr == nil && r.KeywordErr == nil && r.LatencyErr == nil
+}
+
+// checkURLTarget requests the URL of an entry of type "url" and checks the response:
+//   - url_status: the request succeeds with the expected_status of the target, or any 2xx status.
+//   - url_keyword: the body (its first MiB) contains the keyword of the target, if any.
+//   - url_latency: the response headers arrive within the max_latency_ms of the target, if any.
+//
+// It sets url_check_up, url_check_status_code and url_check_duration_seconds for the target.
+func checkURLTarget(target models.ObaServer, client *http.Client) URLCheckResult {
+	serverID := strconv.Itoa(target.ID)
+	result := checkURL(target, client)
+	up := 0.0
+	if result.OK() {
+		up = 1
+	}
+	URLCheckUp.WithLabelValues(serverID, target.URL).Set(up)
+	URLCheckStatusCode.WithLabelValues(serverID).Set(float64(result.StatusCode))
+	if result.StatusCode != 0 {
+		URLCheckDurationSeconds.WithLabelValues(serverID).Set(result.Latency.Seconds())
+	}
+	return result
+}
+
+func checkURL(target models.ObaServer, client *http.Client) URLCheckResult {
+	var result URLCheckResult
+	if target.URL == "" {
+		result.StatusErr = fmt.Errorf("url is required for entries of type %q", models.ServerTypeURL)
+		return result
+	}
+	req, err := http.NewRequestWithContext(context.Background(), http.MethodGet, target.URL, nil)
+	if err != nil {
+		result.StatusErr = fmt.Errorf("failed to create request for %s: %w", target.URL, err)
+		return result
+	}
+	start := time.Now()
+	resp, err := client.Do(req)
+	if err != nil {
+		result.StatusErr = fmt.Errorf("failed to request %s: %w", target.URL, err)
+		return result
+	}
+	defer resp.Body.Close()
+	result.Latency = time.Since(start)
+	result.StatusCode = resp.StatusCode
+
+	if target.ExpectedStatus != 0 && resp.StatusCode != target.ExpectedStatus {
+		result.StatusErr = fmt.Errorf("%s returned status %d, expected %d", target.URL, resp.StatusCode, target.ExpectedStatus)
+	} else if target.ExpectedStatus == 0 && (resp.StatusCode < 200 || resp.StatusCode > 299) {
+		result.StatusErr = fmt.Errorf("%s returned status: %d", target.URL, resp.StatusCode)
+	}
+	if maxLatency := time.Duration(target.MaxLatencyMs) * time.Millisecond; maxLatency > 0 && result.Latency > maxLatency {
+		result.LatencyErr = fmt.Errorf("%s answered in %dms, more than %dms", target.URL, result.Latency.Milliseconds(), target.MaxLatencyMs)
+	}
+	if target.Keyword != "" {
+		body, err := io.ReadAll(io.LimitReader(resp.Body, maxURLCheckBodySize))
+		if err != nil {
+			result.KeywordErr = fmt.Errorf("failed to read the response of %s: %w", target.URL, err)
+		} else if !bytes.Contains(body, []byte(target.Keyword)) {
+			result.KeywordErr = fmt.Errorf("the response of %s does not contain %q", target.URL, target.Keyword)
+		}
+	}
+	return result
+}
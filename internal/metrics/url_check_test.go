@@ -0,0 +1,74 @@
+package metrics
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	"watchdog.onebusaway.org/internal/models"
+)
+
+func TestCheckURLTarget(t *testing.T) {
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/health":
+			_, _ = w.Write([]byte(`{"status": "ok"}`))
+		case "/slow":
+			time.Sleep(50 * time.Millisecond)
+			_, _ = w.Write([]byte("ok"))
+		default:
+			http.NotFound(w, r)
+		}
+	}))
+	defer ts.Close()
+
+	tests := []struct {
+		name                              string
+		target                            models.ObaServer
+		statusErr, keywordErr, latencyErr string
+	}{
+		{name: "healthy", target: models.ObaServer{URL: ts.URL + "/health", Keyword: `"ok"`, MaxLatencyMs: 5000}},
+		{name: "unexpected status", target: models.ObaServer{URL: ts.URL + "/missing"}, statusErr: "returned status: 404"},
+		{name: "expected status", target: models.ObaServer{URL: ts.URL + "/missing", ExpectedStatus: http.StatusNotFound}},
+		{name: "other expected status", target: models.ObaServer{URL: ts.URL + "/health", ExpectedStatus: http.StatusNoContent}, statusErr: "expected 204"},
+		{name: "missing keyword", target: models.ObaServer{URL: ts.URL + "/health", Keyword: "trip planner"}, keywordErr: "does not contain"},
+		{name: "slow", target: models.ObaServer{URL: ts.URL + "/slow", MaxLatencyMs: 10}, latencyErr: "more than 10ms"},
+		{name: "unreachable", target: models.ObaServer{URL: "http://127.0.0.1:1/health", Keyword: "ok"}, statusErr: "failed to request"},
+		{name: "no url", target: models.ObaServer{}, statusErr: "url is required"},
+	}
+	for i, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tt.target.ID = 900 + i
+			tt.target.Type = models.ServerTypeURL
+			result := checkURLTarget(tt.target, &http.Client{Timeout: 5 * time.Second})
+			for _, check := range []struct {
+				name string
+				err  error
+				want string
+			}{
+				{"status", result.StatusErr, tt.statusErr},
+				{"keyword", result.KeywordErr, tt.keywordErr},
+				{"latency", result.LatencyErr, tt.latencyErr},
+			} {
+				if check.want == "" && check.err != nil {
+					t.Errorf("expected the %s check to pass, got %v", check.name, check.err)
+				}
+				if check.want != "" && (check.err == nil || !strings.Contains(check.err.Error(), check.want)) {
+					t.Errorf("expected the %s check to fail with %q, got %v", check.name, check.want, check.err)
+				}
+			}
+
+			up := 0.0
+			if tt.statusErr == "" && tt.keywordErr == "" && tt.latencyErr == "" {
+				up = 1
+			}
+			if got := testutil.ToFloat64(URLCheckUp.WithLabelValues(strconv.Itoa(tt.target.ID), tt.target.URL)); got != up {
+				t.Errorf("expected url_check_up %v, got %v", up, got)
+			}
+		})
+	}
+}
@@ -4,11 +4,11 @@ import (
 	"context"
 	"fmt"
 	"math"
+	"net/http"
 	"strconv"
 	"time"
 
 	onebusaway "github.com/OneBusAway/go-sdk"
-	"github.com/OneBusAway/go-sdk/option"
 	"watchdog.onebusaway.org/internal/geo"
 	"watchdog.onebusaway.org/internal/gtfs"
 	"watchdog.onebusaway.org/internal/models"
@@ -30,7 +30,7 @@ import (
 //   - int: the number of vehicle positions found in the GTFS-RT feed.
 //   - error: if the realtimeStore is nil or the data is missing.
 
-func countVehiclePositions(server models.ObaServer, realtimeStore *gtfs.RealtimeStore) (int, error) {
+func countVehiclePositions(server models.ObaServer, realtimeStore gtfs.RealtimeStore) (int, error) {
 	if realtimeStore == nil {
 		err := fmt.Errorf("realtimeStore is nil for server %d", server.ID)
 		report.ReportErrorWithSentryOptions(err, report.SentryReportOptions{
@@ -73,12 +73,9 @@ func countVehiclePositions(server models.ObaServer, realtimeStore *gtfs.Realtime
 // Returns:
 //   - int: the number of vehicles returned by the API.
 //   - error: if the API call fails or returns an invalid response.
-func vehiclesForAgencyAPI(server models.ObaServer) (int, error) {
+func vehiclesForAgencyAPI(server models.ObaServer, httpClient *http.Client) (int, error) {
 
-	client := onebusaway.NewClient(
-		option.WithAPIKey(server.ObaApiKey),
-		option.WithBaseURL(server.ObaBaseURL),
-	)
+	client := newObaClient(server, httpClient)
 
 	ctx := context.Background()
 
@@ -106,42 +103,61 @@ func vehiclesForAgencyAPI(server models.ObaServer) (int, error) {
 // checkVehicleCountMatch compares the number of vehicles in the GTFS-RT feed with
 // the number reported by the VehiclesForAgency API for the given server.
 //
-// It sets the VehicleCountMatch Prometheus metric to 1 if the counts match, or 0 otherwise.
-// Used to detect inconsistencies between real-time GTFS-RT data and the OBA API.
+// It sets the VehicleCountMatch Prometheus metric to 1 if the counts match, or 0 otherwise,
+// VehicleCountMatchRatio to the API count divided by the GTFS-RT count, and
+// VehicleCountDifference to the absolute difference between the counts.
+// Used to detect inconsistencies between real-time GTFS-RT data and the OBA API, in
+// particular vehicles of the feed that OBA drops.
 //
 // Parameters:
 //   - server: the ObaServer for which the comparison is made.
 //   - realtimeStore: a pointer to the RealtimeStore holding GTFS-RT data.
+//   - client: the HTTP client the OBA API is called with (nil = httpclient.Default().API).
 //
 // Returns:
+//   - float64: the match ratio, 1 when the GTFS-RT feed has no vehicles.
 //   - error: if counting vehicles from either source fails.
-func checkVehicleCountMatch(server models.ObaServer, realtimeStore *gtfs.RealtimeStore) error {
+func checkVehicleCountMatch(server models.ObaServer, realtimeStore gtfs.RealtimeStore, client *http.Client) (float64, error) {
 	gtfsRtVehicleCount, err := countVehiclePositions(server, realtimeStore)
 	if err != nil {
 		err := fmt.Errorf("failed to count vehicle positions from GTFS-RT: %v", err)
 		report.ReportErrorWithSentryOptions(err, report.SentryReportOptions{
 			Tags: utils.MakeMap("server_id", strconv.Itoa(server.ID)),
 		})
-		return err
+		return 0, err
 	}
 
-	apiVehicleCount, err := vehiclesForAgencyAPI(server)
+	apiVehicleCount, err := vehiclesForAgencyAPI(server, client)
 	if err != nil {
 		err := fmt.Errorf("failed to count vehicle positions from API: %v", err)
 		report.ReportErrorWithSentryOptions(err, report.SentryReportOptions{
 			Tags: utils.MakeMap("server_id", strconv.Itoa(server.ID)),
 		})
-		return err
+		return 0, err
 	}
 
 	match := 0
 	if gtfsRtVehicleCount == apiVehicleCount {
 		match = 1
 	}
+	ratio := vehicleCountMatchRatio(gtfsRtVehicleCount, apiVehicleCount)
 
-	VehicleCountMatch.WithLabelValues(server.AgencyID, strconv.Itoa(server.ID)).Set(float64(match))
+	labels := []string{server.AgencyID, strconv.Itoa(server.ID)}
+	VehicleCountMatch.WithLabelValues(labels...).Set(float64(match))
+	VehicleCountMatchRatio.WithLabelValues(labels...).Set(ratio)
+	VehicleCountDifference.WithLabelValues(labels...).Set(math.Abs(float64(gtfsRtVehicleCount - apiVehicleCount)))
 
-	return nil
+	return ratio, nil
+}
+
+// vehicleCountMatchRatio returns the number of vehicles of the API divided by the number of
+// vehicles of the GTFS-RT feed. It is below 1 when OBA drops vehicles of the feed, and above 1
+// when the API returns vehicles that are not in the feed, e.g. vehicles that stopped reporting.
+func vehicleCountMatchRatio(gtfsRtCount, apiCount int) float64 {
+	if gtfsRtCount == 0 {
+		return 1
+	}
+	return float64(apiCount) / float64(gtfsRtCount)
 }
 
 // trackVehicleTelemetry collects and reports various telemetry metrics for vehicles in a GTFS-RT feed.
@@ -166,7 +182,7 @@ func checkVehicleCountMatch(server models.ObaServer, realtimeStore *gtfs.Realtim
 //
 // Returns:
 //   - An error if the feed cannot be fetched or parsed, otherwise nil.
-func trackVehicleTelemetry(server models.ObaServer, vehicleLastSeen *VehicleLastSeen, realtimeStore *gtfs.RealtimeStore) error {
+func trackVehicleTelemetry(server models.ObaServer, vehicleLastSeen *VehicleLastSeen, realtimeStore gtfs.RealtimeStore) error {
 	serverID := server.ID
 	agencyID := server.AgencyID
 	now := time.Now().UTC()
@@ -272,7 +288,7 @@ const VehicleStatusStoppedAtStop = 1
 // The results are exposed via Prometheus metrics:
 // - InvalidVehicleCoordinatesGauge: for invalid or missing coordinates
 // - StoppedOutOfBoundsVehiclesGauge: for vehicles stopped outside the bounding box
-func trackInvalidVehiclesAndStoppedOutOfBounds(server models.ObaServer, boundingBoxStore *geo.BoundingBoxStore, realtimeStore *gtfs.RealtimeStore) error {
+func trackInvalidVehiclesAndStoppedOutOfBounds(server models.ObaServer, boundingBoxStore geo.BoundingBoxStore, realtimeStore gtfs.RealtimeStore) error {
 	realtimeData := realtimeStore.Get()
 	if realtimeData == nil {
 		err := fmt.Errorf("no GTFS-RT data available for server %d", server.ID)
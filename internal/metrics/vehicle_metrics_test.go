@@ -13,7 +13,7 @@ import (
 	"watchdog.onebusaway.org/internal/models"
 )
 
-var realtimeStore *gtfs.RealtimeStore
+var realtimeStore gtfs.RealtimeStore
 
 func TestMain(m *testing.M) {
 	realtimeStore = gtfs.NewRealtimeStore()
@@ -73,7 +73,7 @@ func TestVehiclesForAgencyAPI(t *testing.T) {
 			AgencyID:   "test-agency",
 		}
 
-		count, err := vehiclesForAgencyAPI(server)
+		count, err := vehiclesForAgencyAPI(server, nil)
 		if err != nil {
 			t.Fatalf("Expected no error, got %v", err)
 		}
@@ -95,7 +95,7 @@ func TestVehiclesForAgencyAPI(t *testing.T) {
 			AgencyID:   "test-agency",
 		}
 
-		count, err := vehiclesForAgencyAPI(server)
+		count, err := vehiclesForAgencyAPI(server, nil)
 		if err != nil {
 			t.Fatalf("Expected no error, got %v", err)
 		}
@@ -117,7 +117,7 @@ func TestVehiclesForAgencyAPI(t *testing.T) {
 			AgencyID:   "test-agency",
 		}
 
-		_, err := vehiclesForAgencyAPI(server)
+		_, err := vehiclesForAgencyAPI(server, nil)
 		if err == nil {
 			t.Fatal("Expected an error but got nil")
 		}
@@ -132,7 +132,7 @@ func TestCheckVehicleCountMatch(t *testing.T) {
 
 		testServer := createTestServer(obaServer.URL, "Test Server", 999, "test-key", "GTFS-Rt Server URL 1", "test-api-value", "test-api-key", "1")
 
-		err := checkVehicleCountMatch(testServer, realtimeStore)
+		ratio, err := checkVehicleCountMatch(testServer, realtimeStore, nil)
 		if err != nil {
 			t.Fatalf("CheckVehicleCountMatch failed: %v", err)
 		}
@@ -143,6 +143,18 @@ func TestCheckVehicleCountMatch(t *testing.T) {
 		}
 
 		t.Log("Number of vehicles in GTFS-RT feed:", len(realtimeData.Vehicles))
+
+		gtfsRtCount := float64(len(realtimeData.Vehicles))
+		if ratio != 1/gtfsRtCount {
+			t.Errorf("expected a match ratio of %v, got %v", 1/gtfsRtCount, ratio)
+		}
+		labels := map[string]string{"agency_id": "1", "server_id": "999"}
+		if value, err := getMetricValue(VehicleCountMatchRatio, labels); err != nil || value != ratio {
+			t.Errorf("expected vehicle_count_match_ratio %v, got %v (%v)", ratio, value, err)
+		}
+		if value, err := getMetricValue(VehicleCountDifference, labels); err != nil || value != gtfsRtCount-1 {
+			t.Errorf("expected vehicle_count_difference %v, got %v (%v)", gtfsRtCount-1, value, err)
+		}
 	})
 	t.Run("OBA API Error", func(t *testing.T) {
 		obaServer := setupObaServer(t, `{}`, http.StatusInternalServerError)
@@ -150,7 +162,7 @@ func TestCheckVehicleCountMatch(t *testing.T) {
 
 		testServer := createTestServer(obaServer.URL, "Test Server", 999, "test-key", "GTFS-Rt Server URL 1", "test-api-value", "test-api-key", "1")
 
-		err := checkVehicleCountMatch(testServer, realtimeStore)
+		_, err := checkVehicleCountMatch(testServer, realtimeStore, nil)
 		if err == nil {
 			t.Fatal("Expected an error but got nil")
 		}
@@ -158,6 +170,23 @@ func TestCheckVehicleCountMatch(t *testing.T) {
 	})
 }
 
+func TestVehicleCountMatchRatio(t *testing.T) {
+	tests := []struct {
+		gtfsRt, api int
+		want        float64
+	}{
+		{gtfsRt: 10, api: 10, want: 1},
+		{gtfsRt: 10, api: 7, want: 0.7},
+		{gtfsRt: 4, api: 5, want: 1.25},
+		{gtfsRt: 0, api: 3, want: 1},
+	}
+	for _, tt := range tests {
+		if got := vehicleCountMatchRatio(tt.gtfsRt, tt.api); got != tt.want {
+			t.Errorf("vehicleCountMatchRatio(%d, %d) = %v, want %v", tt.gtfsRt, tt.api, got, tt.want)
+		}
+	}
+}
+
 func TestTrackInvalidVehiclesAndStoppedOutOfBounds(t *testing.T) {
 	boundingBoxStore := geo.NewBoundingBoxStore()
 	boundingBoxStore.Set(1, geo.BoundingBox{
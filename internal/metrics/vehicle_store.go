@@ -4,6 +4,8 @@ import (
 	"context"
 	"sync"
 	"time"
+
+	"watchdog.onebusaway.org/internal/scheduler"
 )
 
 // LastSeen stores timestamp & coordinates for speed computation
@@ -85,20 +87,12 @@ func (v *VehicleLastSeen) Count(serverID int) int {
 // whose LastSeen timestamps exceed the given threshold.
 //
 // ctx: Context for canceling the routine.
-// timeInterval: Interval at which cleanup checks are performed.
+// schedule: When cleanup checks are performed (see scheduler.Parse).
 // threshold: Duration after which a vehicle entry is considered stale and removed.
-func (vehicleLastSeen *VehicleLastSeen) ClearRoutine(ctx context.Context, timeInterval, threshold time.Duration) {
-	ticker := time.NewTicker(timeInterval)
-	defer ticker.Stop()
-
-	for {
-		select {
-		case <-ticker.C:
-			vehicleLastSeen.clear(threshold)
-		case <-ctx.Done():
-			return
-		}
-	}
+func (vehicleLastSeen *VehicleLastSeen) ClearRoutine(ctx context.Context, schedule scheduler.Schedule, threshold time.Duration) {
+	scheduler.Run(ctx, "vehicle_cleanup", schedule, func() {
+		vehicleLastSeen.clear(threshold)
+	})
 }
 
 // clear removes stale vehicle entries from the store that have not been
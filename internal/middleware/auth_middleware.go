@@ -0,0 +1,90 @@
+package middleware
+
+import (
+	"encoding/json"
+	"log/slog"
+	"net/http"
+
+	"watchdog.onebusaway.org/internal/auth"
+)
+
+// statusRecorder captures the status code written by a handler, for the audit log.
+type statusRecorder struct {
+	http.ResponseWriter
+	status int
+}
+
+func (r *statusRecorder) WriteHeader(status int) {
+	r.status = status
+	r.ResponseWriter.WriteHeader(status)
+}
+
+// Unwrap returns the wrapped ResponseWriter, e.g. to hijack the connection of a WebSocket.
+func (r *statusRecorder) Unwrap() http.ResponseWriter {
+	return r.ResponseWriter
+}
+
+// RequireRole is an HTTP middleware that only lets callers with at least the required role
+// reach next.
+//
+// Callers are identified by authenticator:
+//   - Requests without valid credentials get 401 Unauthorized.
+//   - Callers whose role is too low get 403 Forbidden.
+//   - Otherwise the caller is stored in the request context (see auth.PrincipalFromContext)
+//     and the request is passed to next.
+//
+// Every request, allowed or not, is written to the audit logger with the caller, its role,
+// the required role and the response status.
+func RequireRole(authenticator auth.Authenticator, required auth.Role, audit *slog.Logger, next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		attrs := []any{
+			"method", r.Method,
+			"path", r.URL.Path,
+			"remote_addr", r.RemoteAddr,
+			"required_role", required.String(),
+		}
+
+		principal, err := authenticator.Authenticate(r)
+		if err != nil || principal == nil {
+			w.Header().Set("WWW-Authenticate", `Bearer realm="watchdog"`)
+			writeJSONError(w, http.StatusUnauthorized, "authentication required")
+			audit.Warn("Admin request denied", append(attrs, "decision", "unauthenticated", "status", http.StatusUnauthorized)...)
+			return
+		}
+
+		attrs = append(attrs, "principal", principal.Name, "role", principal.Role.String(), "auth_method", principal.Method)
+		if !principal.Role.Allows(required) {
+			writeJSONError(w, http.StatusForbidden, "insufficient role")
+			audit.Warn("Admin request denied", append(attrs, "decision", "forbidden", "status", http.StatusForbidden)...)
+			return
+		}
+
+		recorder := &statusRecorder{ResponseWriter: w, status: http.StatusOK}
+		next.ServeHTTP(recorder, r.WithContext(auth.WithPrincipal(r.Context(), principal)))
+		audit.Info("Admin request", append(attrs, "decision", "allowed", "status", recorder.status)...)
+	})
+}
+
+func writeJSONError(w http.ResponseWriter, status int, message string) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	_ = json.NewEncoder(w).Encode(map[string]string{"error": message})
+}
+
+// RequireAuthentication is an HTTP middleware that only lets callers authenticated by
+// authenticator reach next, whatever their role. Requests without valid credentials get
+// 401 Unauthorized with the WWW-Authenticate header challenge.
+//
+// Unlike RequireRole, requests are not audited: the routes it protects, like /metrics, are
+// read-only and polled frequently.
+func RequireAuthentication(authenticator auth.Authenticator, challenge string, next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		principal, err := authenticator.Authenticate(r)
+		if err != nil || principal == nil {
+			w.Header().Set("WWW-Authenticate", challenge)
+			writeJSONError(w, http.StatusUnauthorized, "authentication required")
+			return
+		}
+		next.ServeHTTP(w, r.WithContext(auth.WithPrincipal(r.Context(), principal)))
+	})
+}
@@ -0,0 +1,91 @@
+package models
+
+// AlertConfig holds the per-server alerting settings (the `alerts` object of a server in config.json).
+type AlertConfig struct {
+	// Disabled turns off all alert notifications for the server. Checks and metrics still run.
+	Disabled bool `json:"disabled"`
+	// SlackWebhookURL overrides the global Slack webhook, e.g. to route a server to its agency's channel.
+	SlackWebhookURL string `json:"slack_webhook_url"`
+	// PagerDutyRoutingKey overrides the global PagerDuty routing key, e.g. to page the agency's own service.
+	PagerDutyRoutingKey string `json:"pagerduty_routing_key"`
+	// WebhookURL overrides the global alert webhook URL.
+	WebhookURL string `json:"webhook_url"`
+	// EmailTo overrides the global alert email recipients (--alert-email-to).
+	EmailTo []string `json:"email_to"`
+	// Locale is the language of the server's notifications, e.g. "es" or "fr-CA" (default: the global alert locale).
+	Locale string `json:"locale"`
+	// Checks holds per-check overrides, keyed by check name (e.g. "api_down").
+	Checks map[string]AlertCheckConfig `json:"checks"`
+}
+
+// AgencyContact is the contact of the agency producing the data of a server. Data-quality
+// findings (e.g. stops missing from the OBA API or invalid vehicle positions), as opposed to
+// infrastructure alerts, are sent to it in a digest, so the agency can fix its feeds directly.
+type AgencyContact struct {
+	// Email is the address digests are sent to (requires --smtp-addr).
+	Email string `json:"email,omitempty"`
+	// SlackWebhookURL posts digests to the agency's Slack channel.
+	SlackWebhookURL string `json:"slack_webhook_url,omitempty"`
+}
+
+// AlertCheckConfig overrides the alerting defaults of one check for one server.
+type AlertCheckConfig struct {
+	// Disabled turns off notifications for this check.
+	Disabled bool `json:"disabled"`
+	// Threshold overrides the check's default threshold. Its meaning depends on the check,
+	// e.g. a number of consecutive failures or a number of days.
+	Threshold *float64 `json:"threshold"`
+	// Cooldown overrides the minimum time between two notifications for this check.
+	Cooldown *Duration `json:"cooldown"`
+}
+
+// Check returns the override for the named check, or the zero value if there is none.
+// It is safe to call on a nil *AlertConfig.
+func (c *AlertConfig) Check(name string) AlertCheckConfig {
+	if c == nil {
+		return AlertCheckConfig{}
+	}
+	return c.Checks[name]
+}
+
+// AlertRule is a user-defined alert on a Prometheus metric exported by the watchdog, or on the
+// results of a check, evaluated after every collection cycle over their recent values (see
+// alert.RuleEvaluator). Rules are read from the file given with --alert-rules-file.
+type AlertRule struct {
+	// Name identifies the rule in notifications, metrics and `alerts.checks` overrides.
+	Name string `json:"name"`
+	// Title is the summary of the rule's notifications (default: Name).
+	Title string `json:"title,omitempty"`
+	// Metric is the name of the metric, e.g. "realtime_vehicle_positions_count_gtfs_rt".
+	// Each of its series is evaluated separately.
+	Metric string `json:"metric,omitempty"`
+	// Check is the name of a check (see metrics.CheckNames) evaluated instead of a metric, with
+	// one series per server: the value the check measured (see metrics.MeasuredChecks), e.g. the
+	// age in seconds of the GTFS-RT feed for "gtfs_rt_feed", or, for the checks that measure
+	// none, 1 when it failed and 0 when it passed.
+	Check string `json:"check,omitempty"`
+	// Labels restricts the rule to the series with these label values.
+	Labels map[string]string `json:"labels,omitempty"`
+	// Condition is how the values of a series are compared to Threshold, e.g. "drop_percent"
+	// (see alert.RuleConditions).
+	Condition string `json:"condition"`
+	// Threshold is the value the condition compares to; its meaning depends on the condition.
+	Threshold float64 `json:"threshold"`
+	// Window is how far back rate-of-change conditions look, e.g. "10m".
+	Window Duration `json:"window"`
+	// For is how long the condition must hold before the rule fires, e.g. "10m" (default: it
+	// fires as soon as the condition holds).
+	For Duration `json:"for,omitempty"`
+	// Severity is the severity of the alert in paging systems: "critical", "error" or "warning" (default).
+	Severity string `json:"severity,omitempty"`
+	// Aggregate combines the series of the metric across all servers into one value per cycle
+	// before the condition is applied: "sum", "avg", "min", "max" or "count" (see alert.RuleAggregates).
+	// Such rules raise a single alert, attributed to no server.
+	Aggregate string `json:"aggregate,omitempty"`
+	// Alerts routes and localizes the alerts of an aggregate rule like the `alerts` of a server,
+	// e.g. to an infrastructure channel rather than the agencies' ones.
+	Alerts *AlertConfig `json:"alerts,omitempty"`
+	// ServiceDependent rules, e.g. on vehicle counts, are not evaluated for a server during
+	// the planned service reductions of its `reduced_service_calendar_url`.
+	ServiceDependent bool `json:"service_dependent,omitempty"`
+}
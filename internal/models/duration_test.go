@@ -0,0 +1,38 @@
+package models
+
+import (
+	"encoding/json"
+	"testing"
+	"time"
+)
+
+func TestDurationUnmarshalJSON(t *testing.T) {
+	tests := []struct {
+		input   string
+		want    time.Duration
+		wantErr bool
+	}{
+		{input: `"30m"`, want: 30 * time.Minute},
+		{input: `"1h30m"`, want: 90 * time.Minute},
+		{input: `90`, want: 90 * time.Second},
+		{input: `"soon"`, wantErr: true},
+		{input: `true`, wantErr: true},
+	}
+	for _, tt := range tests {
+		var d Duration
+		err := json.Unmarshal([]byte(tt.input), &d)
+		if tt.wantErr {
+			if err == nil {
+				t.Errorf("%s: expected an error", tt.input)
+			}
+			continue
+		}
+		if err != nil {
+			t.Errorf("%s: unexpected error: %v", tt.input, err)
+			continue
+		}
+		if d.Std() != tt.want {
+			t.Errorf("%s: got %v, want %v", tt.input, d.Std(), tt.want)
+		}
+	}
+}
@@ -0,0 +1,14 @@
+package models
+
+// ExecCheck is a custom check of a server (an entry of `exec_checks` in config.json): an
+// external command run on a schedule, whose exit code or JSON output is the result of the
+// check. It is an escape hatch for agency-specific validations that the watchdog does not
+// implement.
+type ExecCheck struct {
+	// Name identifies the check; its results are recorded as the check "exec:<name>".
+	Name string `json:"name"`
+	// Command is the program to run and its arguments. It is not run through a shell.
+	Command []string `json:"command"`
+	// Timeout bounds a run of the command, which is killed when it elapses (0 = 30s).
+	Timeout Duration `json:"timeout,omitempty"`
+}
@@ -1,12 +1,14 @@
 package models
 
 import (
+	"time"
+
 	remoteGtfs "github.com/OneBusAway/go-gtfs"
 )
 
 // StaticData represents the static GTFS data structure.
 // It contains parts we uses from GTFS Static bundels
-// which are stops, agencies, and services.
+// which are stops, routes, agencies, services and the time spans of trips.
 //
 // IMPORTANT:
 // In the future, we may need to extend this structure
@@ -14,31 +16,139 @@ import (
 // Don't forget to include them here
 type StaticData struct {
 	Stops    []remoteGtfs.Stop
+	Routes   []remoteGtfs.Route
 	Agencies []remoteGtfs.Agency
 	Services []remoteGtfs.Service
+	// TripSpans are kept instead of the trips and their stop_times, which are by far the
+	// largest part of a bundle.
+	TripSpans []TripSpan
+	// FeedInfo is parsed separately from feed_info.txt, which the GTFS library does not support.
+	// It is nil if the bundle has no feed_info.txt.
+	FeedInfo *FeedInfo
+	// Attributions are the records of attribution.txt, parsed separately like FeedInfo.
+	Attributions []Attribution
+}
+
+// TripSpan is the time span of a scheduled trip, from its first departure to its last arrival
+// (or the end of its last frequency window), relative to the start of its service day.
+type TripSpan struct {
+	TripID    string
+	RouteID   string
+	ServiceID string
+	Start     time.Duration
+	End       time.Duration
+}
+
+// newTripSpan returns the span of a trip, and false for a trip without stop times.
+func newTripSpan(trip remoteGtfs.ScheduledTrip) (TripSpan, bool) {
+	if len(trip.StopTimes) == 0 {
+		return TripSpan{}, false
+	}
+	span := TripSpan{TripID: trip.ID, Start: trip.StopTimes[0].DepartureTime, End: trip.StopTimes[0].ArrivalTime}
+	if trip.Route != nil {
+		span.RouteID = trip.Route.Id
+	}
+	if trip.Service != nil {
+		span.ServiceID = trip.Service.Id
+	}
+	for _, stopTime := range trip.StopTimes {
+		span.Start = min(span.Start, stopTime.DepartureTime)
+		span.End = max(span.End, stopTime.ArrivalTime)
+	}
+	// A frequency-based trip runs its stop times again every headway until the end of the window.
+	duration := span.End - span.Start
+	for i, frequency := range trip.Frequencies {
+		if i == 0 {
+			span.Start, span.End = frequency.StartTime, frequency.EndTime+duration
+		}
+		span.Start = min(span.Start, frequency.StartTime)
+		span.End = max(span.End, frequency.EndTime+duration)
+	}
+	return span, true
+}
+
+// FeedInfo holds the fields of a bundle's feed_info.txt used for monitoring.
+// StartDate and EndDate are zero if the feed does not declare them.
+type FeedInfo struct {
+	PublisherName string
+	PublisherURL  string
+	Lang          string
+	StartDate     time.Time
+	EndDate       time.Time
+	Version       string
+	ContactEmail  string
+	ContactURL    string
+}
+
+// Attribution is a record of a bundle's attribution.txt: an organization credited for the
+// data, and its roles (producer, operator, authority).
+type Attribution struct {
+	OrganizationName string   `json:"organization_name"`
+	Roles            []string `json:"roles,omitempty"`
+	URL              string   `json:"url,omitempty"`
+	Email            string   `json:"email,omitempty"`
+	Phone            string   `json:"phone,omitempty"`
+}
+
+// License holds the publisher fields of feed_info.txt and the attributions of attribution.txt,
+// which data-usage terms usually require crediting.
+type License struct {
+	PublisherName string        `json:"publisher_name,omitempty"`
+	PublisherURL  string        `json:"publisher_url,omitempty"`
+	ContactEmail  string        `json:"contact_email,omitempty"`
+	ContactURL    string        `json:"contact_url,omitempty"`
+	Attributions  []Attribution `json:"attributions,omitempty"`
+}
+
+// NewLicense combines the publisher fields of feedInfo (which may be nil) with the attributions.
+func NewLicense(feedInfo *FeedInfo, attributions []Attribution) License {
+	license := License{Attributions: attributions}
+	if feedInfo != nil {
+		license.PublisherName = feedInfo.PublisherName
+		license.PublisherURL = feedInfo.PublisherURL
+		license.ContactEmail = feedInfo.ContactEmail
+		license.ContactURL = feedInfo.ContactURL
+	}
+	return license
+}
+
+// IsZero reports whether the bundle declares no publisher nor attribution.
+func (l License) IsZero() bool {
+	return l.PublisherName == "" && l.PublisherURL == "" && l.ContactEmail == "" && l.ContactURL == "" && len(l.Attributions) == 0
 }
 
 func NewStaticData(GtfsStaticBundle *remoteGtfs.Static) *StaticData {
-	return &StaticData{
+	staticData := &StaticData{
 		Stops:    append([]remoteGtfs.Stop(nil), GtfsStaticBundle.Stops...),
+		Routes:   append([]remoteGtfs.Route(nil), GtfsStaticBundle.Routes...),
 		Agencies: append([]remoteGtfs.Agency(nil), GtfsStaticBundle.Agencies...),
 		Services: append([]remoteGtfs.Service(nil), GtfsStaticBundle.Services...),
 	}
+	staticData.TripSpans = make([]TripSpan, 0, len(GtfsStaticBundle.Trips))
+	for _, trip := range GtfsStaticBundle.Trips {
+		if span, ok := newTripSpan(trip); ok {
+			staticData.TripSpans = append(staticData.TripSpans, span)
+		}
+	}
+	return staticData
 }
 
 // RealtimeData represents the realtime GTFS data structure.
 // It contains parts we uses from GTFS Realtime bundels
-// which are vehicles.
+// which are vehicles and trips.
 // IMPORTANT:
 // In the future, we may need to extend this structure
 // to include more fields from the GTFS Realtime bundle.
 // Don't forget to include them here
 type RealtimeData struct {
 	Vehicles []remoteGtfs.Vehicle
+	// Trips are the trips of the feed's trip updates and of its vehicles.
+	Trips []remoteGtfs.Trip
 }
 
 func NewRealtimeData(GtfsRealtimeBundle *remoteGtfs.Realtime) *RealtimeData {
 	return &RealtimeData{
 		Vehicles: append([]remoteGtfs.Vehicle(nil), GtfsRealtimeBundle.Vehicles...),
+		Trips:    append([]remoteGtfs.Trip(nil), GtfsRealtimeBundle.Trips...),
 	}
 }
@@ -0,0 +1,15 @@
+package models
+
+// HTTPTimeouts are the timeouts of the requests to the hosts of a server (its OBA API, GTFS
+// bundles, GTFS-RT feeds and data sources). Unset (0) timeouts are the global ones.
+type HTTPTimeouts struct {
+	// Connect bounds establishing a TCP connection.
+	Connect Duration `json:"connect,omitempty"`
+	// TLSHandshake bounds the TLS handshake of a connection.
+	TLSHandshake Duration `json:"tls_handshake,omitempty"`
+	// API, Realtime and Bundle are the overall timeouts of OBA API calls, GTFS-RT feed polls
+	// and GTFS bundle downloads.
+	API      Duration `json:"api,omitempty"`
+	Realtime Duration `json:"realtime,omitempty"`
+	Bundle   Duration `json:"bundle,omitempty"`
+}
@@ -0,0 +1,19 @@
+package models
+
+import "time"
+
+// MaintenanceWindow is a period of planned maintenance of a server (an entry of
+// `maintenance_windows` in config.json, or one declared with the admin API), e.g. an upgrade of
+// OBA. During the window the checks still run, but alerts and Sentry reports of the server are
+// suppressed.
+type MaintenanceWindow struct {
+	Start time.Time `json:"start"`
+	End   time.Time `json:"end"`
+	// Reason explains the maintenance, e.g. "OBA 2.6 upgrade".
+	Reason string `json:"reason,omitempty"`
+}
+
+// Contains reports whether at is in the window, from Start, included, to End, excluded.
+func (w MaintenanceWindow) Contains(at time.Time) bool {
+	return !at.Before(w.Start) && at.Before(w.End)
+}
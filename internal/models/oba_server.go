@@ -1,18 +1,88 @@
 package models
 
+import (
+	"slices"
+	"sort"
+)
+
+// ServerTypeURL is the Type of the configuration entries that are plain HTTP services rather
+// than OBA servers.
+const ServerTypeURL = "url"
+
 // ObaServer represents a OneBusAway server configuration
 // TODO: Some server have multiple Agencies, so we should have a list of Agencies
 type ObaServer struct {
-	Name               string `json:"name"`
-	ID                 int    `json:"id"`
-	ObaBaseURL         string `json:"oba_base_url"`
-	ObaApiKey          string `json:"oba_api_key"`
-	GtfsUrl            string `json:"gtfs_url"`
-	TripUpdateUrl      string `json:"trip_update_url"`
-	VehiclePositionUrl string `json:"vehicle_position_url"`
-	GtfsRtApiKey       string `json:"gtfs_rt_api_key"`
-	GtfsRtApiValue     string `json:"gtfs_rt_api_value"`
-	AgencyID           string `json:"agency_id"`
+	Name string `json:"name"`
+	ID   int    `json:"id"`
+	// Type is empty (or "oba") for an OBA server, or ServerTypeURL for a plain HTTP service of
+	// the transit stack, e.g. a trip planner or a tile server, that is only checked for uptime
+	// with URL, ExpectedStatus, Keyword and MaxLatencyMs. The OBA and GTFS settings of such an
+	// entry are ignored.
+	Type string `json:"type,omitempty"`
+	// URL is the URL requested by the uptime checks of an entry of type "url".
+	URL string `json:"url,omitempty"`
+	// ExpectedStatus is the status code URL must answer with (0 = any 2xx status).
+	ExpectedStatus int `json:"expected_status,omitempty"`
+	// Keyword is a string the response body must contain (empty = not checked).
+	Keyword string `json:"keyword,omitempty"`
+	// MaxLatencyMs is the longest acceptable response time, in milliseconds (0 = not checked).
+	MaxLatencyMs int    `json:"max_latency_ms,omitempty"`
+	ObaBaseURL   string `json:"oba_base_url"`
+	ObaApiKey    string `json:"oba_api_key"`
+	GtfsUrl      string `json:"gtfs_url"`
+	// GtfsUrls are further GTFS static bundles of the server, for deployments that merge the
+	// bundles of several agencies. Their stops, routes, agencies, services and trips are
+	// merged with those of GtfsUrl into the static data of the server.
+	GtfsUrls           []string `json:"gtfs_urls,omitempty"`
+	TripUpdateUrl      string   `json:"trip_update_url"`
+	VehiclePositionUrl string   `json:"vehicle_position_url"`
+	GtfsRtApiKey       string   `json:"gtfs_rt_api_key"`
+	GtfsRtApiValue     string   `json:"gtfs_rt_api_value"`
+	AgencyID           string   `json:"agency_id"`
+	// Region is a human-readable area the server covers, e.g. "Puget Sound", exported with its
+	// name in oba_server_info for dashboards.
+	Region string `json:"region,omitempty"`
+	// PriorityTier orders servers on cold start and in each collection cycle:
+	// tier 1 is handled first, then tier 2, and so on. Unset (0) is treated as tier 1.
+	PriorityTier int `json:"priority_tier"`
+	// DataSourcesURL points to the data-sources.xml of the OBA instance. When set, empty feed
+	// settings (GTFS, GTFS-RT URLs, agency, GTFS-RT API key) are derived from it.
+	DataSourcesURL string `json:"oba_data_sources_url"`
+	// PredictionStops are the stops whose arrivals are sampled to measure the accuracy of
+	// arrival predictions (empty = not measured).
+	PredictionStops []string `json:"prediction_stops,omitempty"`
+	// ReportProblemStopID is the stop test problem reports are submitted for, to check that the
+	// report-problem endpoints of the OBA API accept rider feedback (empty = not checked).
+	ReportProblemStopID string `json:"report_problem_stop_id,omitempty"`
+	// RateLimit caps the requests per second sent to the hosts of the OBA API and GTFS-RT feeds
+	// of the server, overriding the default per-host limit (0 = default).
+	RateLimit float64 `json:"rate_limit,omitempty"`
+	// ReducedServiceCalendarURL points to an iCalendar or JSON calendar of the planned service
+	// reductions of the agency (school breaks, snow days), during which the checks expecting
+	// scheduled service do not raise alerts (empty = none).
+	ReducedServiceCalendarURL string `json:"reduced_service_calendar_url,omitempty"`
+	// MaintenanceWindows are the planned maintenance periods of the server, during which its
+	// alerts and Sentry reports are suppressed.
+	MaintenanceWindows []MaintenanceWindow `json:"maintenance_windows,omitempty"`
+	// ProxyURL is the HTTP(S) proxy requests to the hosts of the server go through, overriding
+	// the global proxy (empty = global proxy).
+	ProxyURL string `json:"proxy_url,omitempty"`
+	// CACertFiles are PEM bundles of CA certificates trusted, besides the global ones, for the
+	// hosts of the server, e.g. an internal CA.
+	CACertFiles []string `json:"ca_cert_files,omitempty"`
+	// Timeouts overrides the global timeouts of the requests to the hosts of the server, e.g.
+	// for a slow agency server; nil uses the global timeouts.
+	Timeouts *HTTPTimeouts `json:"timeouts,omitempty"`
+	// ExecChecks are custom checks run as external commands (see ExecCheck).
+	ExecChecks []ExecCheck `json:"exec_checks,omitempty"`
+	// Alerts holds per-server alerting settings; nil uses the global defaults.
+	Alerts *AlertConfig `json:"alerts,omitempty"`
+	// AgencyContact receives the data-quality findings of the server in a periodic digest;
+	// nil sends them to nobody.
+	AgencyContact *AgencyContact `json:"agency_contact,omitempty"`
+	// SecretRefs are the secret references (e.g. "vault://secret/data/oba#api_key") the settings
+	// were resolved from, by JSON field name, so that rotated secrets can be resolved again.
+	SecretRefs map[string]string `json:"-"`
 }
 
 // NewObaServer creates a new ObaServer instance with the provided configuration
@@ -30,3 +100,53 @@ func NewObaServer(name string, id int, baseURL, apiKey, gtfsURL, tripUpdateURL,
 		AgencyID:           agencyID,
 	}
 }
+
+// IsURLTarget reports whether the entry is a plain HTTP service rather than an OBA server.
+func (s ObaServer) IsURLTarget() bool {
+	return s.Type == ServerTypeURL
+}
+
+// GtfsFeedURLs returns the URLs of the GTFS static bundles of the server: GtfsUrl followed by
+// GtfsUrls, without empty or repeated URLs.
+func (s ObaServer) GtfsFeedURLs() []string {
+	var urls []string
+	for _, u := range append([]string{s.GtfsUrl}, s.GtfsUrls...) {
+		if u != "" && !slices.Contains(urls, u) {
+			urls = append(urls, u)
+		}
+	}
+	return urls
+}
+
+// Tier returns the server's priority tier, treating an unset tier as tier 1.
+func (s ObaServer) Tier() int {
+	if s.PriorityTier < 1 {
+		return 1
+	}
+	return s.PriorityTier
+}
+
+// GroupServersByPriorityTier splits servers into groups of equal priority tier,
+// ordered from the highest priority (tier 1) to the lowest.
+// Servers keep their configured order within a tier.
+func GroupServersByPriorityTier(servers []ObaServer) [][]ObaServer {
+	sorted := SortServersByPriorityTier(servers)
+	var groups [][]ObaServer
+	for i, server := range sorted {
+		if i == 0 || server.Tier() != sorted[i-1].Tier() {
+			groups = append(groups, nil)
+		}
+		groups[len(groups)-1] = append(groups[len(groups)-1], server)
+	}
+	return groups
+}
+
+// SortServersByPriorityTier returns a copy of servers ordered by priority tier (tier 1 first).
+// Servers keep their configured order within a tier.
+func SortServersByPriorityTier(servers []ObaServer) []ObaServer {
+	sorted := append([]ObaServer(nil), servers...)
+	sort.SliceStable(sorted, func(i, j int) bool {
+		return sorted[i].Tier() < sorted[j].Tier()
+	})
+	return sorted
+}
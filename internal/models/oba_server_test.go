@@ -52,3 +52,44 @@ func TestNewObaServer(t *testing.T) {
 		t.Errorf("NewObaServer() ID = %v, want %v", server.ID, id)
 	}
 }
+
+func TestGroupServersByPriorityTier(t *testing.T) {
+	servers := []ObaServer{
+		{ID: 1, PriorityTier: 3},
+		{ID: 2},
+		{ID: 3, PriorityTier: 2},
+		{ID: 4, PriorityTier: 1},
+		{ID: 5, PriorityTier: 3},
+	}
+
+	groups := GroupServersByPriorityTier(servers)
+	expected := [][]int{{2, 4}, {3}, {1, 5}}
+	if len(groups) != len(expected) {
+		t.Fatalf("expected %d tiers, got %d", len(expected), len(groups))
+	}
+	for i, group := range groups {
+		if len(group) != len(expected[i]) {
+			t.Fatalf("tier %d: expected %d servers, got %d", i+1, len(expected[i]), len(group))
+		}
+		for j, server := range group {
+			if server.ID != expected[i][j] {
+				t.Errorf("tier %d position %d: expected server %d, got %d", i+1, j, expected[i][j], server.ID)
+			}
+		}
+	}
+
+	if servers[0].ID != 1 {
+		t.Error("GroupServersByPriorityTier must not reorder the input slice")
+	}
+	if groups := GroupServersByPriorityTier(nil); len(groups) != 0 {
+		t.Errorf("expected no tiers for no servers, got %d", len(groups))
+	}
+}
+
+func TestGtfsFeedURLs(t *testing.T) {
+	server := ObaServer{GtfsUrl: "https://a", GtfsUrls: []string{"", "https://b", "https://a"}}
+	urls := server.GtfsFeedURLs()
+	if len(urls) != 2 || urls[0] != "https://a" || urls[1] != "https://b" {
+		t.Errorf("expected the primary bundle then the others without duplicates, got %v", urls)
+	}
+}
@@ -0,0 +1,28 @@
+// Package redisclient sets up the Redis client of the leader lock (see leader.RedisLock) and the
+// shared stores (see sharedstore.Redis).
+package redisclient
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/redis/go-redis/v9"
+)
+
+// New returns a client of the Redis server at addr, which is either "host:port" or a URL:
+// redis://[user@]host:port[/db], or rediss:// to connect over TLS. password, if not empty,
+// authenticates the connections (it overrides the password of a URL). The client keeps a pool of
+// connections, authenticated once each, shared by the goroutines using it.
+func New(addr, password string) (*redis.Client, error) {
+	options := &redis.Options{Addr: addr}
+	if strings.Contains(addr, "://") {
+		var err error
+		if options, err = redis.ParseURL(addr); err != nil {
+			return nil, fmt.Errorf("invalid Redis URL: %w", err)
+		}
+	}
+	if password != "" {
+		options.Password = password
+	}
+	return redis.NewClient(options), nil
+}
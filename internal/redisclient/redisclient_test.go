@@ -0,0 +1,37 @@
+package redisclient
+
+import "testing"
+
+func TestNew(t *testing.T) {
+	tests := []struct {
+		addr, password string
+		wantAddr       string
+		wantPassword   string
+		wantTLS        bool
+		wantErr        bool
+	}{
+		{addr: "localhost:6379", password: "secret", wantAddr: "localhost:6379", wantPassword: "secret"},
+		{addr: "redis://:url-secret@redis.example.com:6380/1", wantAddr: "redis.example.com:6380", wantPassword: "url-secret"},
+		{addr: "rediss://redis.example.com:6380", password: "secret", wantAddr: "redis.example.com:6380", wantPassword: "secret", wantTLS: true},
+		{addr: "http://redis.example.com", wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.addr, func(t *testing.T) {
+			client, err := New(tt.addr, tt.password)
+			if tt.wantErr {
+				if err == nil {
+					t.Fatal("expected an error")
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			defer client.Close()
+			options := client.Options()
+			if options.Addr != tt.wantAddr || options.Password != tt.wantPassword || (options.TLSConfig != nil) != tt.wantTLS {
+				t.Errorf("unexpected options: addr %q, password %q, TLS %v", options.Addr, options.Password, options.TLSConfig != nil)
+			}
+		})
+	}
+}
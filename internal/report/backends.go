@@ -0,0 +1,70 @@
+package report
+
+import (
+	"context"
+	"log/slog"
+	"time"
+
+	"github.com/getsentry/sentry-go"
+)
+
+// LogReporter is the Reporter writing errors to a logger instead of sending them anywhere, for
+// deployments that cannot ship data to an error tracking service.
+type LogReporter struct {
+	logger *slog.Logger
+}
+
+// NewLogReporter creates a LogReporter writing to logger.
+func NewLogReporter(logger *slog.Logger) *LogReporter {
+	return &LogReporter{logger: logger}
+}
+
+// Name implements Reporter.
+func (r *LogReporter) Name() string {
+	return "log"
+}
+
+// Report implements Reporter: the error is logged at its level, with its tags and extra
+// context as attributes.
+func (r *LogReporter) Report(event Event) {
+	attrs := []slog.Attr{slog.String("error", event.Err.Error())}
+	for k, v := range event.Tags {
+		attrs = append(attrs, slog.String(k, v))
+	}
+	if len(event.Extra) > 0 {
+		attrs = append(attrs, slog.Any("extra", event.Extra))
+	}
+	r.logger.LogAttrs(context.Background(), logLevel(event.Level), "Reported error", attrs...)
+}
+
+// Flush implements Reporter; logged errors are already written.
+func (r *LogReporter) Flush(time.Duration) {}
+
+// logLevel returns the log level of the errors of a level.
+func logLevel(level sentry.Level) slog.Level {
+	switch level {
+	case sentry.LevelDebug:
+		return slog.LevelDebug
+	case sentry.LevelInfo:
+		return slog.LevelInfo
+	case sentry.LevelWarning:
+		return slog.LevelWarn
+	default:
+		return slog.LevelError
+	}
+}
+
+// NoopReporter is the Reporter dropping errors. They are still counted in
+// watchdog_report_errors_total.
+type NoopReporter struct{}
+
+// Name implements Reporter.
+func (NoopReporter) Name() string {
+	return "none"
+}
+
+// Report implements Reporter.
+func (NoopReporter) Report(Event) {}
+
+// Flush implements Reporter.
+func (NoopReporter) Flush(time.Duration) {}
@@ -0,0 +1,34 @@
+package report
+
+import (
+	"bytes"
+	"errors"
+	"log/slog"
+	"strings"
+	"testing"
+
+	"github.com/getsentry/sentry-go"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+)
+
+func TestLogReporter(t *testing.T) {
+	var logs bytes.Buffer
+	SetReporter(NewLogReporter(slog.New(slog.NewTextHandler(&logs, nil))))
+	defer SetReporter(nil)
+
+	ReportErrorWithSentryOptions(errors.New("bundle expired"), SentryReportOptions{Tags: map[string]string{"server_id": "4"}, Level: sentry.LevelWarning})
+	if got := logs.String(); !strings.Contains(got, "level=WARN") || !strings.Contains(got, `error="bundle expired"`) || !strings.Contains(got, "server_id=4") {
+		t.Errorf("unexpected log: %s", got)
+	}
+}
+
+func TestNoopReporter(t *testing.T) {
+	SetReporter(NoopReporter{})
+	defer SetReporter(nil)
+
+	before := testutil.ToFloat64(ReportedErrors.WithLabelValues("error"))
+	ReportError(errors.New("dropped"))
+	if got := testutil.ToFloat64(ReportedErrors.WithLabelValues("error")) - before; got != 1 {
+		t.Errorf("expected the dropped error to be counted, got %v", got)
+	}
+}
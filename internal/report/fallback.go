@@ -0,0 +1,220 @@
+package report
+
+import (
+	"bytes"
+	"context"
+	"io"
+	"log"
+	"net/http"
+	"sync"
+	"time"
+
+	"watchdog.onebusaway.org/internal/scheduler"
+)
+
+// Limits of the fallback buffer: when either is exceeded, the oldest events are dropped.
+const (
+	maxBufferedEvents = 100
+	maxBufferedBytes  = 5 << 20
+)
+
+// retryTimeout bounds each attempt to send a buffered event again.
+const retryTimeout = 10 * time.Second
+
+// bufferedRequest is a request of the Sentry SDK that failed, kept to be sent again.
+type bufferedRequest struct {
+	id     uint64
+	method string
+	url    string
+	header http.Header
+	body   []byte
+}
+
+// fallbackTransport is the HTTP transport of the Sentry SDK, and of the other reporters sending
+// errors over HTTP (see RollbarReporter). The SDK only writes delivery errors
+// to its debug log, so events sent while Sentry is unreachable were lost without a trace:
+// fallbackTransport counts each failure in watchdog_report_failures_total and keeps the events
+// that may succeed later (network errors, 5xx and 429 responses) in a bounded buffer, which
+// retry sends again. Events that Sentry rejected (other 4xx, e.g. a revoked DSN) are not kept.
+type fallbackTransport struct {
+	next http.RoundTripper
+
+	mu          sync.Mutex
+	buffer      []bufferedRequest
+	size        int
+	nextID      uint64
+	unreachable bool // whether the last attempt failed, to log the outage once
+}
+
+// fallback is the transport installed by SetupSentry and NewRollbarReporter.
+var fallback = &fallbackTransport{next: http.DefaultTransport}
+
+// SendThrough sends the requests of the reporters through next, e.g. the shared transport of the
+// HTTP clients with their proxy and CA certificates, and returns next unchanged, so that it can be
+// passed to httpclient.Clients.WrapTransport.
+func SendThrough(next http.RoundTripper) http.RoundTripper {
+	fallback.mu.Lock()
+	defer fallback.mu.Unlock()
+	fallback.next = next
+	return next
+}
+
+// transport returns the transport the requests are sent through.
+func (t *fallbackTransport) transport() http.RoundTripper {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	return t.next
+}
+
+func (t *fallbackTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	var body []byte
+	if req.Body != nil {
+		var err error
+		if body, err = io.ReadAll(req.Body); err != nil {
+			return nil, err
+		}
+		req.Body.Close()
+		req.Body = io.NopCloser(bytes.NewReader(body))
+	}
+	resp, err := t.transport().RoundTrip(req)
+	reason := failureReason(resp, err)
+	switch reason {
+	case "":
+		t.recovered()
+	case "rejected":
+		ReportFailures.WithLabelValues(reason).Inc()
+		log.Printf("%s: event rejected with status %d, it is not sent again", CurrentReporter().Name(), resp.StatusCode)
+	default:
+		ReportFailures.WithLabelValues(reason).Inc()
+		t.add(bufferedRequest{method: req.Method, url: req.URL.String(), header: req.Header.Clone(), body: body}, reason)
+	}
+	return resp, err
+}
+
+// failureReason classifies the outcome of a request to Sentry; "" is a success.
+func failureReason(resp *http.Response, err error) string {
+	switch {
+	case err != nil:
+		return "unreachable"
+	case resp.StatusCode == http.StatusTooManyRequests:
+		return "rate_limited"
+	case resp.StatusCode >= 500:
+		return "server_error"
+	case resp.StatusCode >= 400:
+		return "rejected"
+	}
+	return ""
+}
+
+// add appends request to the buffer, dropping the oldest events beyond the limits.
+func (t *fallbackTransport) add(request bufferedRequest, reason string) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	if !t.unreachable {
+		log.Printf("%s: failed to send an event (%s), buffering events until it is available", CurrentReporter().Name(), reason)
+		t.unreachable = true
+	}
+	t.nextID++
+	request.id = t.nextID
+	t.buffer = append(t.buffer, request)
+	t.size += len(request.body)
+	for len(t.buffer) > maxBufferedEvents || (t.size > maxBufferedBytes && len(t.buffer) > 1) {
+		t.size -= len(t.buffer[0].body)
+		t.buffer = t.buffer[1:]
+		ReportDroppedEvents.Inc()
+	}
+	ReportBufferedEvents.Set(float64(len(t.buffer)))
+}
+
+func (t *fallbackTransport) recovered() {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	if t.unreachable {
+		log.Printf("%s: events are delivered again, %d buffered events left to resend", CurrentReporter().Name(), len(t.buffer))
+		t.unreachable = false
+	}
+}
+
+// buffered returns the number of events waiting to be sent again.
+func (t *fallbackTransport) buffered() int {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	return len(t.buffer)
+}
+
+// retry sends the buffered events again, oldest first, until one fails for a reason worth
+// another retry: the rest stay buffered for the next call.
+func (t *fallbackTransport) retry(ctx context.Context) {
+	for {
+		t.mu.Lock()
+		if len(t.buffer) == 0 {
+			t.mu.Unlock()
+			return
+		}
+		request := t.buffer[0]
+		t.mu.Unlock()
+
+		reason := t.resend(ctx, request)
+		if reason != "" {
+			ReportFailures.WithLabelValues(reason).Inc()
+		}
+		if reason != "" && reason != "rejected" {
+			return
+		}
+
+		t.mu.Lock()
+		// The buffer may have been trimmed while the request was in flight.
+		if len(t.buffer) > 0 && t.buffer[0].id == request.id {
+			t.size -= len(request.body)
+			t.buffer = t.buffer[1:]
+		}
+		ReportBufferedEvents.Set(float64(len(t.buffer)))
+		t.mu.Unlock()
+		if reason == "" {
+			t.recovered()
+		}
+	}
+}
+
+// resend sends request and returns the reason it failed, or "".
+func (t *fallbackTransport) resend(ctx context.Context, request bufferedRequest) string {
+	ctx, cancel := context.WithTimeout(ctx, retryTimeout)
+	defer cancel()
+	req, err := http.NewRequestWithContext(ctx, request.method, request.url, bytes.NewReader(request.body))
+	if err != nil {
+		return "rejected"
+	}
+	req.Header = request.header.Clone()
+	resp, err := t.transport().RoundTrip(req)
+	if resp != nil {
+		_, _ = io.Copy(io.Discard, io.LimitReader(resp.Body, 1<<16))
+		resp.Body.Close()
+	}
+	return failureReason(resp, err)
+}
+
+// RetryBufferedEvents sends the events buffered while Sentry was unavailable again at every
+// activation of schedule, until ctx is canceled.
+func RetryBufferedEvents(ctx context.Context, schedule scheduler.Schedule) {
+	scheduler.Run(ctx, "report_retry", schedule, func() {
+		fallback.retry(ctx)
+	})
+}
+
+// BufferedEvents returns the number of events waiting to be sent to Sentry again.
+func BufferedEvents() int {
+	return fallback.buffered()
+}
+
+// dsnError records why SENTRY_DSN was not used, if it is invalid.
+var dsnError error
+
+// reportUndeliverable handles an error reported while SENTRY_DSN is invalid: the SDK has no
+// client to send it with, so it is only logged.
+func reportUndeliverable(err error) {
+	if dsnError == nil {
+		return
+	}
+	ReportFailures.WithLabelValues("invalid_dsn").Inc()
+	log.Printf("sentry: not reported (invalid SENTRY_DSN): %v", err)
+}
@@ -0,0 +1,99 @@
+package report
+
+import (
+	"context"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync/atomic"
+	"testing"
+
+	"github.com/prometheus/client_golang/prometheus/testutil"
+)
+
+func TestFallbackTransport(t *testing.T) {
+	var status atomic.Int32
+	status.Store(http.StatusServiceUnavailable)
+	var received []string
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := io.ReadAll(r.Body)
+		if code := int(status.Load()); code != http.StatusOK {
+			w.WriteHeader(code)
+			return
+		}
+		received = append(received, string(body)+" "+r.Header.Get("X-Sentry-Auth"))
+	}))
+	defer ts.Close()
+
+	transport := &fallbackTransport{next: http.DefaultTransport}
+	client := &http.Client{Transport: transport}
+	send := func(body string) {
+		t.Helper()
+		req, _ := http.NewRequest(http.MethodPost, ts.URL, strings.NewReader(body))
+		req.Header.Set("X-Sentry-Auth", "key")
+		resp, err := client.Do(req)
+		if err != nil {
+			t.Fatal(err)
+		}
+		resp.Body.Close()
+	}
+
+	failures := testutil.ToFloat64(ReportFailures.WithLabelValues("server_error"))
+	send("event 1")
+	send("event 2")
+	if got := transport.buffered(); got != 2 {
+		t.Fatalf("expected 2 buffered events, got %d", got)
+	}
+	if got := testutil.ToFloat64(ReportFailures.WithLabelValues("server_error")) - failures; got != 2 {
+		t.Errorf("expected 2 server_error failures, got %v", got)
+	}
+
+	// Still down: nothing is lost.
+	transport.retry(context.Background())
+	if got := transport.buffered(); got != 2 {
+		t.Fatalf("expected the events to stay buffered, got %d", got)
+	}
+
+	status.Store(http.StatusOK)
+	transport.retry(context.Background())
+	if got := transport.buffered(); got != 0 {
+		t.Fatalf("expected the buffer to be empty, got %d", got)
+	}
+	if len(received) != 2 || received[0] != "event 1 key" || received[1] != "event 2 key" {
+		t.Errorf("expected the events to be sent again in order with their headers, got %q", received)
+	}
+
+	// Rejected events would be rejected again: they are not buffered.
+	status.Store(http.StatusUnauthorized)
+	send("event 3")
+	if got := transport.buffered(); got != 0 {
+		t.Errorf("expected a rejected event not to be buffered, got %d", got)
+	}
+}
+
+func TestFallbackTransportLimit(t *testing.T) {
+	transport := &fallbackTransport{}
+	dropped := testutil.ToFloat64(ReportDroppedEvents)
+	for i := 0; i < maxBufferedEvents+5; i++ {
+		transport.add(bufferedRequest{body: []byte("event")}, "unreachable")
+	}
+	if got := transport.buffered(); got != maxBufferedEvents {
+		t.Errorf("expected the buffer to be capped at %d events, got %d", maxBufferedEvents, got)
+	}
+	if got := testutil.ToFloat64(ReportDroppedEvents) - dropped; got != 5 {
+		t.Errorf("expected 5 dropped events, got %v", got)
+	}
+	if transport.buffer[0].id != 6 {
+		t.Errorf("expected the oldest events to be dropped, first is %d", transport.buffer[0].id)
+	}
+
+	transport = &fallbackTransport{}
+	large := make([]byte, maxBufferedBytes/2+1)
+	for i := 0; i < 3; i++ {
+		transport.add(bufferedRequest{body: large}, "unreachable")
+	}
+	if got := transport.buffered(); got != 1 {
+		t.Errorf("expected the buffer to be capped at %d bytes, got %d events", maxBufferedBytes, got)
+	}
+}
@@ -0,0 +1,46 @@
+package report
+
+import (
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promauto"
+)
+
+var (
+	ReportedErrors = promauto.NewCounterVec(
+		prometheus.CounterOpts{
+			Name: "watchdog_report_errors_total",
+			Help: "Errors reported, by level (error, warning, ...); counted whatever the --error-reporter, even when Sentry is not configured",
+		},
+		[]string{"level"},
+	)
+
+	ReportFailures = promauto.NewCounterVec(
+		prometheus.CounterOpts{
+			Name: "watchdog_report_failures_total",
+			Help: "Events that could not be delivered to Sentry or Rollbar, by reason (unreachable, server_error, rate_limited, rejected, invalid_dsn); retries of buffered events are counted again",
+		},
+		[]string{"reason"},
+	)
+
+	ReportBufferedEvents = promauto.NewGauge(
+		prometheus.GaugeOpts{
+			Name: "watchdog_report_buffered_events",
+			Help: "Events waiting in the fallback buffer to be sent to Sentry or Rollbar again",
+		},
+	)
+
+	ReportSuppressed = promauto.NewCounterVec(
+		prometheus.CounterOpts{
+			Name: "watchdog_report_suppressed_total",
+			Help: "Errors that were not reported, by reason (maintenance: the server is in a maintenance window, throttled: the same error of the same server was reported too often)",
+		},
+		[]string{"reason"},
+	)
+
+	ReportDroppedEvents = promauto.NewCounter(
+		prometheus.CounterOpts{
+			Name: "watchdog_report_dropped_events_total",
+			Help: "Events removed from the full fallback buffer before they could be sent to Sentry or Rollbar",
+		},
+	)
+)
@@ -3,6 +3,8 @@ package report
 import (
 	"os"
 	"runtime"
+	"sync/atomic"
+	"time"
 
 	"github.com/getsentry/sentry-go"
 )
@@ -30,22 +32,62 @@ func getHostname() string {
 	return hostname
 }
 
-// ReportError reports the error to Sentry with the given severity level
-// If no level is provided, it defaults to sentry.LevelError.
-func ReportError(err error, levels ...sentry.Level) {
-	if err == nil {
+// Reporter sends the reported errors to an error tracking service, or elsewhere. The reporter in
+// use is chosen with SetReporter; Sentry is the default. Implementations must be safe for
+// concurrent use, and should not block the caller while sending.
+type Reporter interface {
+	// Name identifies the reporter, e.g. "sentry", in logs and the readiness probe.
+	Name() string
+	// Report sends a reported error.
+	Report(event Event)
+	// Flush waits up to timeout for the reports in flight to be sent.
+	Flush(timeout time.Duration)
+}
+
+// Event is a reported error, with its level, tags (e.g. server_id) and extra context.
+type Event struct {
+	Err   error
+	Level sentry.Level
+	Tags  map[string]string
+	Extra map[string]interface{}
+}
+
+// reporter is the Reporter set with SetReporter; nil is Sentry.
+var reporter atomic.Pointer[Reporter]
+
+// SetReporter sets the reporter the errors are sent to (nil = Sentry). Throttling (see
+// SetThrottle), suppression (see SuppressServers) and the metrics of reported errors apply
+// whatever the reporter.
+func SetReporter(r Reporter) {
+	if r == nil {
+		reporter.Store(nil)
 		return
 	}
+	reporter.Store(&r)
+}
+
+// CurrentReporter returns the reporter the errors are sent to.
+func CurrentReporter() Reporter {
+	if r := reporter.Load(); r != nil {
+		return *r
+	}
+	return SentryReporter{}
+}
 
+// Flush waits up to 2 seconds for the reports in flight to be sent, e.g. before exiting.
+func Flush() {
+	CurrentReporter().Flush(2 * time.Second)
+}
+
+// ReportError reports the error with the given severity level.
+// If no level is provided, it defaults to sentry.LevelError.
+// Repeated errors are throttled (see SetThrottle).
+func ReportError(err error, levels ...sentry.Level) {
 	level := sentry.LevelError
 	if len(levels) > 0 {
 		level = levels[0]
 	}
-
-	sentry.WithScope(func(scope *sentry.Scope) {
-		scope.SetLevel(level)
-		sentry.CaptureException(err)
-	})
+	ReportErrorWithSentryOptions(err, SentryReportOptions{Level: level})
 }
 
 // SentryReportOptions provides optional data for reporting.
@@ -55,24 +97,40 @@ type SentryReportOptions struct {
 	Level        sentry.Level
 }
 
-// ReportErrorWithSentryOptions reports the error with additional options (tags, context, level).
+// suppressedServer reports whether the errors of a server are left out of the reports (see
+// SuppressServers); nil reports them all.
+var suppressedServer atomic.Pointer[func(serverID string) bool]
+
+// SuppressServers sets which servers have their errors left out of the reports, e.g. during their
+// maintenance windows: errors reported with a server_id tag for which suppressed returns true
+// are only counted in watchdog_report_suppressed_total with reason="maintenance".
+func SuppressServers(suppressed func(serverID string) bool) {
+	suppressedServer.Store(&suppressed)
+}
+
+// ReportErrorWithSentryOptions reports the error with additional options (tags, context, level)
+// to the current reporter (see SetReporter).
+// Errors of a suppressed server are dropped (see SuppressServers), and repeated errors of the
+// same server are throttled (see SetThrottle).
 func ReportErrorWithSentryOptions(err error, opts SentryReportOptions) {
 	if err == nil {
 		return
 	}
-
-	sentry.WithScope(func(scope *sentry.Scope) {
-		if opts.ExtraContext != nil {
-			scope.SetContext("extra", opts.ExtraContext)
+	serverID, hasServer := opts.Tags["server_id"]
+	if suppressed := suppressedServer.Load(); suppressed != nil && *suppressed != nil {
+		if hasServer && (*suppressed)(serverID) {
+			ReportSuppressed.WithLabelValues("maintenance").Inc()
+			return
 		}
-		if opts.Tags != nil {
-			for k, v := range opts.Tags {
-				scope.SetTag(k, v)
-			}
-		}
-		if opts.Level != "" {
-			scope.SetLevel(opts.Level)
-		}
-		sentry.CaptureException(err)
-	})
+	}
+	level := opts.Level
+	if level == "" {
+		level = sentry.LevelError
+	}
+	if level != sentry.LevelFatal && throttled(err, serverID, time.Now()) {
+		ReportSuppressed.WithLabelValues("throttled").Inc()
+		return
+	}
+	ReportedErrors.WithLabelValues(string(level)).Inc()
+	CurrentReporter().Report(Event{Err: err, Level: level, Tags: opts.Tags, Extra: opts.ExtraContext})
 }
@@ -0,0 +1,153 @@
+package report
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"log"
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/getsentry/sentry-go"
+)
+
+// RollbarEndpoint is the Rollbar API errors are sent to by default.
+const RollbarEndpoint = "https://api.rollbar.com/api/1/item/"
+
+// rollbarTimeout bounds each request to Rollbar.
+const rollbarTimeout = 10 * time.Second
+
+// RollbarOptions configures a RollbarReporter.
+type RollbarOptions struct {
+	// AccessToken is a post_server_item access token of the Rollbar project.
+	AccessToken string
+	// Environment and CodeVersion are sent with every item, e.g. "production" and the version
+	// of the watchdog.
+	Environment string
+	CodeVersion string
+	// Endpoint is the Rollbar API items are sent to (empty = RollbarEndpoint).
+	Endpoint string
+}
+
+// RollbarReporter is the Reporter sending errors to Rollbar. Items are sent in the background
+// through the same fallback buffer as Sentry events, so that those sent while Rollbar is
+// unavailable are sent again by RetryBufferedEvents.
+type RollbarReporter struct {
+	opts   RollbarOptions
+	client *http.Client
+	host   string
+
+	wg sync.WaitGroup
+}
+
+// NewRollbarReporter creates a RollbarReporter. Its requests are sent through the transport
+// set with SendThrough, http.DefaultTransport by default.
+func NewRollbarReporter(opts RollbarOptions) *RollbarReporter {
+	if opts.Endpoint == "" {
+		opts.Endpoint = RollbarEndpoint
+	}
+	return &RollbarReporter{opts: opts, client: &http.Client{Transport: fallback, Timeout: rollbarTimeout}, host: getHostname()}
+}
+
+// Name implements Reporter.
+func (r *RollbarReporter) Name() string {
+	return "rollbar"
+}
+
+// rollbarItem is the body of a request to the Rollbar items API.
+type rollbarItem struct {
+	Data rollbarData `json:"data"`
+}
+
+type rollbarData struct {
+	Environment string                 `json:"environment"`
+	Level       string                 `json:"level"`
+	Timestamp   int64                  `json:"timestamp"`
+	CodeVersion string                 `json:"code_version,omitempty"`
+	Platform    string                 `json:"platform"`
+	Language    string                 `json:"language"`
+	Server      map[string]string      `json:"server"`
+	Custom      map[string]interface{} `json:"custom,omitempty"`
+	Body        rollbarBody            `json:"body"`
+}
+
+type rollbarBody struct {
+	Message rollbarMessage `json:"message"`
+}
+
+type rollbarMessage struct {
+	Body  string `json:"body"`
+	Class string `json:"class"`
+}
+
+// Report implements Reporter: the error is sent as a message item, with its tags and extra
+// context as custom data.
+func (r *RollbarReporter) Report(event Event) {
+	custom := make(map[string]interface{}, len(event.Tags)+1)
+	for k, v := range event.Tags {
+		custom[k] = v
+	}
+	if len(event.Extra) > 0 {
+		custom["extra"] = event.Extra
+	}
+	item := rollbarItem{Data: rollbarData{
+		Environment: r.opts.Environment,
+		Level:       rollbarLevel(event.Level),
+		Timestamp:   time.Now().Unix(),
+		CodeVersion: r.opts.CodeVersion,
+		Platform:    "go",
+		Language:    "go",
+		Server:      map[string]string{"host": r.host},
+		Custom:      custom,
+		Body:        rollbarBody{Message: rollbarMessage{Body: event.Err.Error(), Class: fmt.Sprintf("%T", event.Err)}},
+	}}
+	body, err := json.Marshal(item)
+	if err != nil {
+		log.Printf("rollbar: cannot encode the item: %v", err)
+		return
+	}
+
+	r.wg.Add(1)
+	go func() {
+		defer r.wg.Done()
+		req, err := http.NewRequest(http.MethodPost, r.opts.Endpoint, bytes.NewReader(body))
+		if err != nil {
+			log.Printf("rollbar: %v", err)
+			return
+		}
+		req.Header.Set("Content-Type", "application/json")
+		req.Header.Set("X-Rollbar-Access-Token", r.opts.AccessToken)
+		// Failures are counted, and buffered to be sent again, by the fallback transport.
+		if resp, err := r.client.Do(req); err == nil {
+			resp.Body.Close()
+		}
+	}()
+}
+
+// Flush implements Reporter: it waits up to timeout for the items in flight, then up to timeout
+// for the buffered items to be sent again.
+func (r *RollbarReporter) Flush(timeout time.Duration) {
+	done := make(chan struct{})
+	go func() {
+		r.wg.Wait()
+		close(done)
+	}()
+	select {
+	case <-done:
+	case <-time.After(timeout):
+	}
+	flushBuffered(timeout)
+}
+
+// rollbarLevel returns the Rollbar level of the errors of a level.
+func rollbarLevel(level sentry.Level) string {
+	switch level {
+	case sentry.LevelDebug, sentry.LevelInfo, sentry.LevelWarning, sentry.LevelError:
+		return string(level)
+	case sentry.LevelFatal:
+		return "critical"
+	default:
+		return "error"
+	}
+}
@@ -0,0 +1,103 @@
+package report
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/getsentry/sentry-go"
+)
+
+func TestRollbarReporter(t *testing.T) {
+	var (
+		mu       sync.Mutex
+		received []rollbarItem
+		tokens   []string
+	)
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var item rollbarItem
+		if err := json.NewDecoder(r.Body).Decode(&item); err != nil {
+			t.Errorf("invalid item: %v", err)
+		}
+		mu.Lock()
+		received = append(received, item)
+		tokens = append(tokens, r.Header.Get("X-Rollbar-Access-Token"))
+		mu.Unlock()
+	}))
+	defer ts.Close()
+
+	rollbar := NewRollbarReporter(RollbarOptions{AccessToken: "token", Environment: "production", CodeVersion: "1.2.3", Endpoint: ts.URL})
+	SetReporter(rollbar)
+	defer SetReporter(nil)
+	if CurrentReporter().Name() != "rollbar" {
+		t.Fatalf("expected the rollbar reporter, got %s", CurrentReporter().Name())
+	}
+
+	ReportErrorWithSentryOptions(errors.New("feed unavailable"), SentryReportOptions{
+		Tags:         map[string]string{"server_id": "3"},
+		ExtraContext: map[string]interface{}{"url": "https://feed.example.com"},
+		Level:        sentry.LevelWarning,
+	})
+	ReportError(errors.New("shutting down"), sentry.LevelFatal)
+	rollbar.Flush(time.Second)
+
+	mu.Lock()
+	defer mu.Unlock()
+	if len(received) != 2 {
+		t.Fatalf("expected 2 items, got %d", len(received))
+	}
+	levels := map[string]rollbarData{}
+	for i, item := range received {
+		levels[item.Data.Level] = item.Data
+		if tokens[i] != "token" {
+			t.Errorf("expected the access token, got %q", tokens[i])
+		}
+	}
+	warning, ok := levels["warning"]
+	if !ok || warning.Body.Message.Body != "feed unavailable" || warning.Custom["server_id"] != "3" || warning.Environment != "production" || warning.CodeVersion != "1.2.3" {
+		t.Errorf("unexpected warning item: %+v", warning)
+	}
+	if _, ok := levels["critical"]; !ok {
+		t.Errorf("expected the fatal error as a critical item, got %v", levels)
+	}
+}
+
+// countingTransport counts the requests sent through it.
+type countingTransport struct {
+	mu       sync.Mutex
+	requests int
+}
+
+func (c *countingTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	c.mu.Lock()
+	c.requests++
+	c.mu.Unlock()
+	return http.DefaultTransport.RoundTrip(req)
+}
+
+func TestRollbarReporterSendThrough(t *testing.T) {
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+	defer ts.Close()
+
+	transport := &countingTransport{}
+	if next := SendThrough(transport); next != transport {
+		t.Fatal("expected SendThrough to return the transport unchanged")
+	}
+	defer SendThrough(http.DefaultTransport)
+
+	rollbar := NewRollbarReporter(RollbarOptions{AccessToken: "token", Endpoint: ts.URL})
+	SetReporter(rollbar)
+	defer SetReporter(nil)
+	ReportError(errors.New("sent through the shared transport"), sentry.LevelFatal)
+	rollbar.Flush(time.Second)
+
+	transport.mu.Lock()
+	defer transport.mu.Unlock()
+	if transport.requests != 1 {
+		t.Errorf("expected the item to be sent through the transport, got %d requests", transport.requests)
+	}
+}
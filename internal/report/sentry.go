@@ -1,6 +1,7 @@
 package report
 
 import (
+	"context"
 	"log"
 	"os"
 	"time"
@@ -11,12 +12,26 @@ import (
 // SetupSentry initializes the Sentry client using environment configuration.
 // It enables tracing and debugging, sets the sample rate to 100%, and captures
 // a startup message indicating that the Watchdog has started.
+//
+// Events that cannot be delivered are buffered and sent again by RetryBufferedEvents. An invalid
+// SENTRY_DSN does not stop the watchdog: the client is not initialized, and reported errors are
+// only logged and counted in watchdog_report_failures_total.
 func SetupSentry() {
+	dsn := os.Getenv("SENTRY_DSN")
+	if dsn != "" {
+		if _, err := sentry.NewDsn(dsn); err != nil {
+			dsnError = err
+			log.Printf("sentry: invalid SENTRY_DSN, errors will only be logged: %s", err)
+			return
+		}
+	}
+	dsnError = nil
 	if err := sentry.Init(sentry.ClientOptions{
-		Dsn:              os.Getenv("SENTRY_DSN"),
+		Dsn:              dsn,
 		EnableTracing:    true,
 		Debug:            true,
 		TracesSampleRate: 1.0,
+		HTTPTransport:    fallback,
 	}); err != nil {
 		log.Fatalf("sentry.Init: %s", err)
 	}
@@ -24,7 +39,69 @@ func SetupSentry() {
 }
 
 // FlushSentry flushes any buffered Sentry events.
-// It waits up to 2 seconds for all events to be delivered before shutting down.
+// It waits up to 2 seconds for all events to be delivered, then makes a last attempt to send the
+// events buffered while Sentry was unavailable, before shutting down.
 func FlushSentry() {
-	sentry.Flush(2 * time.Second)
+	flushSentry(2 * time.Second)
+}
+
+// flushSentry waits up to timeout for the events to be delivered, then up to timeout for the
+// buffered events to be sent again.
+func flushSentry(timeout time.Duration) {
+	sentry.Flush(timeout)
+	flushBuffered(timeout)
+}
+
+// flushBuffered makes a last attempt, for up to timeout, to send the events buffered while the
+// reporter was unavailable.
+func flushBuffered(timeout time.Duration) {
+	if fallback.buffered() > 0 {
+		ctx, cancel := context.WithTimeout(context.Background(), timeout)
+		defer cancel()
+		fallback.retry(ctx)
+	}
+}
+
+// SentryInitialized reports whether SetupSentry has initialized the Sentry client, and whether
+// it has a DSN to send events to.
+func SentryInitialized() (initialized bool, hasDSN bool) {
+	client := sentry.CurrentHub().Client()
+	if client == nil {
+		return false, false
+	}
+	return true, client.Options().Dsn != ""
+}
+
+// InvalidDSN returns why SENTRY_DSN was ignored by SetupSentry, or nil if it is valid or unset.
+func InvalidDSN() error {
+	return dsnError
+}
+
+// SentryReporter is the Reporter sending errors to Sentry, the default. It only sends them once
+// SetupSentry has initialized the Sentry client.
+type SentryReporter struct{}
+
+// Name implements Reporter.
+func (SentryReporter) Name() string {
+	return "sentry"
+}
+
+// Report implements Reporter.
+func (SentryReporter) Report(event Event) {
+	reportUndeliverable(event.Err)
+	sentry.WithScope(func(scope *sentry.Scope) {
+		if event.Extra != nil {
+			scope.SetContext("extra", event.Extra)
+		}
+		for k, v := range event.Tags {
+			scope.SetTag(k, v)
+		}
+		scope.SetLevel(event.Level)
+		sentry.CaptureException(event.Err)
+	})
+}
+
+// Flush implements Reporter: see FlushSentry.
+func (SentryReporter) Flush(timeout time.Duration) {
+	flushSentry(timeout)
 }
@@ -1,9 +1,12 @@
 package report_test
 
 import (
+	"errors"
 	"os"
 	"testing"
 
+	"github.com/getsentry/sentry-go"
+	"github.com/prometheus/client_golang/prometheus/testutil"
 	"watchdog.onebusaway.org/internal/report"
 )
 
@@ -15,4 +18,42 @@ func TestSetupSentry(t *testing.T) {
 		report.SetupSentry()
 		report.FlushSentry()
 	})
+
+	t.Run("Invalid DSN", func(t *testing.T) {
+		os.Setenv("SENTRY_DSN", "not a dsn")
+		defer os.Unsetenv("SENTRY_DSN")
+
+		report.SetupSentry()
+		if report.InvalidDSN() == nil {
+			t.Error("expected the DSN to be reported as invalid")
+		}
+		report.ReportError(errors.New("test error"))
+		report.FlushSentry()
+	})
+}
+
+func TestReportedErrorsCounted(t *testing.T) {
+	before := testutil.ToFloat64(report.ReportedErrors.WithLabelValues("warning"))
+	report.ReportError(errors.New("test warning"), sentry.LevelWarning)
+	report.ReportErrorWithSentryOptions(errors.New("test warning"), report.SentryReportOptions{Level: sentry.LevelWarning})
+	report.ReportError(nil, sentry.LevelWarning)
+	if got := testutil.ToFloat64(report.ReportedErrors.WithLabelValues("warning")) - before; got != 2 {
+		t.Errorf("expected 2 reported warnings, got %v", got)
+	}
+}
+
+func TestSuppressServers(t *testing.T) {
+	report.SuppressServers(func(serverID string) bool { return serverID == "2" })
+	defer report.SuppressServers(nil)
+
+	reported := testutil.ToFloat64(report.ReportedErrors.WithLabelValues("error"))
+	suppressed := testutil.ToFloat64(report.ReportSuppressed.WithLabelValues("maintenance"))
+	report.ReportErrorWithSentryOptions(errors.New("in maintenance"), report.SentryReportOptions{Tags: map[string]string{"server_id": "2"}})
+	report.ReportErrorWithSentryOptions(errors.New("not in maintenance"), report.SentryReportOptions{Tags: map[string]string{"server_id": "1"}})
+	if got := testutil.ToFloat64(report.ReportedErrors.WithLabelValues("error")) - reported; got != 1 {
+		t.Errorf("expected 1 reported error, got %v", got)
+	}
+	if got := testutil.ToFloat64(report.ReportSuppressed.WithLabelValues("maintenance")) - suppressed; got != 1 {
+		t.Errorf("expected 1 suppressed error, got %v", got)
+	}
 }
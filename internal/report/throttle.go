@@ -0,0 +1,108 @@
+package report
+
+import (
+	"fmt"
+	"regexp"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// limiter throttles the reports of the same error (see SetThrottle); nil reports them all.
+var limiter atomic.Pointer[throttle]
+
+// SetThrottle limits the reports of the same error of the same server to burst reports at once,
+// then one per minInterval: during an outage, every failed refresh of every server would
+// otherwise send a fresh event. Errors are the same if they have the same type and message,
+// numbers aside (see fingerprint). The reports left out are counted in
+// watchdog_report_suppressed_total with reason="throttled". A minInterval of 0 disables the
+// throttling.
+func SetThrottle(minInterval time.Duration, burst int) {
+	if minInterval <= 0 || burst < 1 {
+		limiter.Store(nil)
+		return
+	}
+	limiter.Store(&throttle{interval: minInterval, burst: burst, buckets: make(map[throttleKey]*bucket)})
+}
+
+// throttled reports whether a report of err by the server is left out, taking one of the tokens
+// of the error and server otherwise.
+func throttled(err error, serverID string, now time.Time) bool {
+	t := limiter.Load()
+	if t == nil {
+		return false
+	}
+	return !t.allow(throttleKey{fingerprint: fingerprint(err), serverID: serverID}, now)
+}
+
+// throttleKey identifies the reports throttled together: the same error of the same server.
+type throttleKey struct {
+	fingerprint string
+	serverID    string
+}
+
+// throttle is a token bucket per throttleKey: each bucket holds up to burst tokens, one is taken
+// by each report, and one is added back every interval.
+type throttle struct {
+	interval time.Duration
+	burst    int
+
+	mu      sync.Mutex
+	buckets map[throttleKey]*bucket
+	// prunedAt is when the full buckets were last removed (see prune).
+	prunedAt time.Time
+}
+
+type bucket struct {
+	tokens float64
+	at     time.Time
+}
+
+// allow takes a token of the bucket of key, and reports whether there was one.
+func (t *throttle) allow(key throttleKey, now time.Time) bool {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	t.prune(now)
+
+	b, ok := t.buckets[key]
+	if !ok {
+		b = &bucket{tokens: float64(t.burst), at: now}
+		t.buckets[key] = b
+	}
+	b.refill(now, t.interval, t.burst)
+	if b.tokens < 1 {
+		return false
+	}
+	b.tokens--
+	return true
+}
+
+// refill adds the tokens earned since the last report, up to burst.
+func (b *bucket) refill(now time.Time, interval time.Duration, burst int) {
+	if elapsed := now.Sub(b.at); elapsed > 0 {
+		b.tokens = min(b.tokens+float64(elapsed)/float64(interval), float64(burst))
+	}
+	b.at = now
+}
+
+// prune removes, at most once per interval, the buckets that are full again: a new bucket would
+// be the same, and errors whose message holds e.g. a URL would otherwise pile up.
+func (t *throttle) prune(now time.Time) {
+	if now.Sub(t.prunedAt) < t.interval {
+		return
+	}
+	t.prunedAt = now
+	for key, b := range t.buckets {
+		if b.refill(now, t.interval, t.burst); b.tokens >= float64(t.burst) {
+			delete(t.buckets, key)
+		}
+	}
+}
+
+var digits = regexp.MustCompile(`[0-9]+`)
+
+// fingerprint identifies the errors that are the same failure: their type and message, with the
+// numbers in it, like durations, counts or addresses, left out.
+func fingerprint(err error) string {
+	return fmt.Sprintf("%T:%s", err, digits.ReplaceAllString(err.Error(), "#"))
+}
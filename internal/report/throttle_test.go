@@ -0,0 +1,63 @@
+package report
+
+import (
+	"errors"
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus/testutil"
+)
+
+func TestThrottle(t *testing.T) {
+	SetThrottle(time.Minute, 2)
+	defer SetThrottle(0, 0)
+
+	now := time.Date(2026, 5, 1, 12, 0, 0, 0, time.UTC)
+	timeout := func(seconds int) error { return fmt.Errorf("fetch failed after %ds", seconds) }
+	allowed := func(err error, serverID string, at time.Time) bool {
+		t.Helper()
+		return !throttled(err, serverID, at)
+	}
+
+	if !allowed(timeout(10), "1", now) || !allowed(timeout(12), "1", now) {
+		t.Fatal("expected the burst to be reported")
+	}
+	if allowed(timeout(15), "1", now.Add(30*time.Second)) {
+		t.Error("expected the same error, numbers aside, to be throttled")
+	}
+	if !allowed(timeout(10), "2", now) {
+		t.Error("expected the same error of another server to be reported")
+	}
+	if !allowed(errors.New("no such host"), "1", now) {
+		t.Error("expected another error of the server to be reported")
+	}
+	if !allowed(timeout(10), "1", now.Add(time.Minute)) {
+		t.Error("expected a report after the min interval")
+	}
+	if allowed(timeout(10), "1", now.Add(90*time.Second)) {
+		t.Error("expected one report per min interval after the burst")
+	}
+
+	limiter.Load().prune(now.Add(time.Hour))
+	if n := len(limiter.Load().buckets); n != 0 {
+		t.Errorf("expected the full buckets to be pruned, %d left", n)
+	}
+}
+
+func TestReportErrorThrottled(t *testing.T) {
+	SetThrottle(time.Hour, 1)
+	defer SetThrottle(0, 0)
+
+	reported := testutil.ToFloat64(ReportedErrors.WithLabelValues("error"))
+	suppressed := testutil.ToFloat64(ReportSuppressed.WithLabelValues("throttled"))
+	for range 3 {
+		ReportErrorWithSentryOptions(errors.New("feed unavailable"), SentryReportOptions{Tags: map[string]string{"server_id": "7"}})
+	}
+	if got := testutil.ToFloat64(ReportedErrors.WithLabelValues("error")) - reported; got != 1 {
+		t.Errorf("expected 1 reported error, got %v", got)
+	}
+	if got := testutil.ToFloat64(ReportSuppressed.WithLabelValues("throttled")) - suppressed; got != 2 {
+		t.Errorf("expected 2 throttled errors, got %v", got)
+	}
+}
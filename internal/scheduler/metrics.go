@@ -0,0 +1,36 @@
+package scheduler
+
+import (
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promauto"
+)
+
+var (
+	// TickLagHistogram is how late the runs of each task start after their scheduled activation.
+	TickLagHistogram = promauto.NewHistogramVec(
+		prometheus.HistogramOpts{
+			Name:    "watchdog_scheduler_tick_lag_seconds",
+			Help:    "Delay between the scheduled activation of a background task and the start of its run, by task",
+			Buckets: []float64{0.001, 0.01, 0.1, 0.5, 1, 5, 30, 120},
+		},
+		[]string{"task"},
+	)
+
+	// TaskRunningGauge is whether a run of each task is in progress.
+	TaskRunningGauge = promauto.NewGaugeVec(
+		prometheus.GaugeOpts{
+			Name: "watchdog_scheduler_task_running",
+			Help: "Whether a run of a background task is in progress (1) or not (0), by task",
+		},
+		[]string{"task"},
+	)
+
+	// TaskLastCompletedGauge is when the last run of each task finished.
+	TaskLastCompletedGauge = promauto.NewGaugeVec(
+		prometheus.GaugeOpts{
+			Name: "watchdog_scheduler_task_last_completed_timestamp",
+			Help: "Unix time at which the last run of a background task finished, by task",
+		},
+		[]string{"task"},
+	)
+)
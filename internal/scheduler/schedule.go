@@ -0,0 +1,267 @@
+package scheduler
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Schedule describes when a recurring task runs.
+type Schedule interface {
+	// Next returns the first activation time strictly after t,
+	// or the zero time if the schedule never fires again.
+	Next(t time.Time) time.Time
+}
+
+// intervalSchedule fires at a fixed interval.
+type intervalSchedule struct {
+	interval time.Duration
+}
+
+// Every returns a Schedule that fires every d, measured from the previous activation.
+// It is the replacement for a plain time.Ticker.
+func Every(d time.Duration) Schedule {
+	return intervalSchedule{interval: d}
+}
+
+func (s intervalSchedule) Next(t time.Time) time.Time {
+	return t.Add(s.interval)
+}
+
+func (s intervalSchedule) String() string {
+	return "@every " + s.interval.String()
+}
+
+// cronSchedule is a parsed five-field cron expression evaluated in a fixed time zone.
+// Each field is a bit set of the allowed values.
+type cronSchedule struct {
+	spec     string
+	minute   uint64
+	hour     uint64
+	dom      uint64
+	month    uint64
+	dow      uint64
+	domStar  bool
+	dowStar  bool
+	location *time.Location
+}
+
+func (s *cronSchedule) String() string {
+	return s.spec
+}
+
+// cronField describes the valid range and optional names of one cron field.
+type cronField struct {
+	name     string
+	min, max int
+	names    map[string]int
+}
+
+var (
+	minuteField = cronField{name: "minute", min: 0, max: 59}
+	hourField   = cronField{name: "hour", min: 0, max: 23}
+	domField    = cronField{name: "day of month", min: 1, max: 31}
+	monthField  = cronField{name: "month", min: 1, max: 12, names: map[string]int{
+		"jan": 1, "feb": 2, "mar": 3, "apr": 4, "may": 5, "jun": 6,
+		"jul": 7, "aug": 8, "sep": 9, "oct": 10, "nov": 11, "dec": 12,
+	}}
+	// Day of week accepts 7 as an alias for Sunday, as most cron implementations do.
+	dowField = cronField{name: "day of week", min: 0, max: 7, names: map[string]int{
+		"sun": 0, "mon": 1, "tue": 2, "wed": 3, "thu": 4, "fri": 5, "sat": 6,
+	}}
+)
+
+// cronMacros maps the common shorthand schedules to their cron expressions.
+var cronMacros = map[string]string{
+	"@yearly":   "0 0 1 1 *",
+	"@annually": "0 0 1 1 *",
+	"@monthly":  "0 0 1 * *",
+	"@weekly":   "0 0 * * 0",
+	"@daily":    "0 0 * * *",
+	"@midnight": "0 0 * * *",
+	"@hourly":   "0 * * * *",
+}
+
+// Parse parses a schedule specification. Supported forms are:
+//
+//   - "@every <duration>" (or a bare Go duration such as "30s"): a fixed interval.
+//   - A standard five-field cron expression "<minute> <hour> <day-of-month> <month> <day-of-week>",
+//     where each field accepts `*`, values, ranges (`1-5`), lists (`1,15`), steps (`*/10`, `0-30/5`),
+//     and month / weekday names (`jan`, `mon`).
+//   - The macros @yearly, @annually, @monthly, @weekly, @daily, @midnight and @hourly.
+//
+// Cron expressions and macros are evaluated in the local time zone unless prefixed with
+// `CRON_TZ=<IANA zone>` (or `TZ=<IANA zone>`), e.g. "CRON_TZ=America/Los_Angeles 0 3 * * *"
+// runs daily at 03:00 in the agency's local time.
+func Parse(spec string) (Schedule, error) {
+	spec = strings.TrimSpace(spec)
+	if spec == "" {
+		return nil, fmt.Errorf("empty schedule")
+	}
+
+	if rest, ok := strings.CutPrefix(spec, "@every "); ok {
+		return parseInterval(strings.TrimSpace(rest))
+	}
+	if d, err := time.ParseDuration(spec); err == nil {
+		return parseInterval(d.String())
+	}
+
+	location := time.Local
+	expr := spec
+	if strings.HasPrefix(expr, "CRON_TZ=") || strings.HasPrefix(expr, "TZ=") {
+		tzSpec, rest, _ := strings.Cut(expr, " ")
+		_, zone, _ := strings.Cut(tzSpec, "=")
+		loc, err := time.LoadLocation(zone)
+		if err != nil {
+			return nil, fmt.Errorf("invalid time zone in schedule %q: %w", spec, err)
+		}
+		location = loc
+		expr = strings.TrimSpace(rest)
+	}
+	if macro, ok := cronMacros[expr]; ok {
+		expr = macro
+	}
+
+	fields := strings.Fields(expr)
+	if len(fields) != 5 {
+		return nil, fmt.Errorf("invalid schedule %q: expected 5 cron fields, got %d", spec, len(fields))
+	}
+
+	s := &cronSchedule{spec: spec, location: location}
+	var err error
+	if s.minute, _, err = parseCronField(fields[0], minuteField); err != nil {
+		return nil, fmt.Errorf("invalid schedule %q: %w", spec, err)
+	}
+	if s.hour, _, err = parseCronField(fields[1], hourField); err != nil {
+		return nil, fmt.Errorf("invalid schedule %q: %w", spec, err)
+	}
+	if s.dom, s.domStar, err = parseCronField(fields[2], domField); err != nil {
+		return nil, fmt.Errorf("invalid schedule %q: %w", spec, err)
+	}
+	if s.month, _, err = parseCronField(fields[3], monthField); err != nil {
+		return nil, fmt.Errorf("invalid schedule %q: %w", spec, err)
+	}
+	if s.dow, s.dowStar, err = parseCronField(fields[4], dowField); err != nil {
+		return nil, fmt.Errorf("invalid schedule %q: %w", spec, err)
+	}
+	// Fold Sunday=7 onto Sunday=0.
+	if s.dow&(1<<7) != 0 {
+		s.dow |= 1
+	}
+	return s, nil
+}
+
+func parseInterval(spec string) (Schedule, error) {
+	d, err := time.ParseDuration(spec)
+	if err != nil {
+		return nil, fmt.Errorf("invalid interval %q: %w", spec, err)
+	}
+	if d <= 0 {
+		return nil, fmt.Errorf("invalid interval %q: must be positive", spec)
+	}
+	return Every(d), nil
+}
+
+// parseCronField parses one comma-separated cron field into a bit set.
+// The second return value reports whether the field is an unrestricted `*`.
+func parseCronField(field string, f cronField) (uint64, bool, error) {
+	var bits uint64
+	for _, part := range strings.Split(field, ",") {
+		rangeSpec, stepSpec, hasStep := strings.Cut(part, "/")
+		step := 1
+		if hasStep {
+			var err error
+			step, err = strconv.Atoi(stepSpec)
+			if err != nil || step <= 0 {
+				return 0, false, fmt.Errorf("invalid step %q in %s field", stepSpec, f.name)
+			}
+		}
+
+		var lo, hi int
+		switch {
+		case rangeSpec == "*":
+			lo, hi = f.min, f.max
+		case strings.Contains(rangeSpec, "-"):
+			loSpec, hiSpec, _ := strings.Cut(rangeSpec, "-")
+			var err error
+			if lo, err = parseCronValue(loSpec, f); err != nil {
+				return 0, false, err
+			}
+			if hi, err = parseCronValue(hiSpec, f); err != nil {
+				return 0, false, err
+			}
+			if lo > hi {
+				return 0, false, fmt.Errorf("invalid range %q in %s field", rangeSpec, f.name)
+			}
+		default:
+			v, err := parseCronValue(rangeSpec, f)
+			if err != nil {
+				return 0, false, err
+			}
+			lo, hi = v, v
+			if hasStep {
+				// "5/15" means "starting at 5, every 15".
+				hi = f.max
+			}
+		}
+
+		for v := lo; v <= hi; v += step {
+			bits |= 1 << uint(v)
+		}
+	}
+	return bits, field == "*" || field == "?", nil
+}
+
+func parseCronValue(s string, f cronField) (int, error) {
+	if v, ok := f.names[strings.ToLower(s)]; ok {
+		return v, nil
+	}
+	v, err := strconv.Atoi(s)
+	if err != nil || v < f.min || v > f.max {
+		return 0, fmt.Errorf("invalid value %q in %s field (allowed %d-%d)", s, f.name, f.min, f.max)
+	}
+	return v, nil
+}
+
+// Next returns the next minute after t matching the cron expression, in the schedule's time zone.
+//
+// The search advances field by field (month, day, hour, minute), resetting the smaller fields
+// whenever a larger one moves, so it needs at most a few hundred iterations. Expressions that
+// can never match (e.g. "0 0 30 2 *") return the zero time after searching five years ahead.
+func (s *cronSchedule) Next(t time.Time) time.Time {
+	t = t.In(s.location).Truncate(time.Minute).Add(time.Minute)
+	limit := t.AddDate(5, 0, 0)
+
+	for t.Before(limit) {
+		if s.month&(1<<uint(t.Month())) == 0 {
+			t = time.Date(t.Year(), t.Month()+1, 1, 0, 0, 0, 0, s.location)
+			continue
+		}
+		if !s.dayMatches(t) {
+			t = time.Date(t.Year(), t.Month(), t.Day()+1, 0, 0, 0, 0, s.location)
+			continue
+		}
+		if s.hour&(1<<uint(t.Hour())) == 0 {
+			t = time.Date(t.Year(), t.Month(), t.Day(), t.Hour()+1, 0, 0, 0, s.location)
+			continue
+		}
+		if s.minute&(1<<uint(t.Minute())) == 0 {
+			t = t.Truncate(time.Minute).Add(time.Minute)
+			continue
+		}
+		return t
+	}
+	return time.Time{}
+}
+
+// dayMatches applies the usual cron rule: when both day-of-month and day-of-week are
+// restricted, a day matches if either of them matches.
+func (s *cronSchedule) dayMatches(t time.Time) bool {
+	domMatch := s.dom&(1<<uint(t.Day())) != 0
+	dowMatch := s.dow&(1<<uint(t.Weekday())) != 0
+	if s.domStar || s.dowStar {
+		return domMatch && dowMatch
+	}
+	return domMatch || dowMatch
+}
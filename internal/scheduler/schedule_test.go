@@ -0,0 +1,150 @@
+package scheduler
+
+import (
+	"context"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	"watchdog.onebusaway.org/internal/shutdown"
+)
+
+func TestParseInvalid(t *testing.T) {
+	specs := []string{
+		"",
+		"@every -1s",
+		"@every nonsense",
+		"* * * *",
+		"60 * * * *",
+		"* 24 * * *",
+		"* * 0 * *",
+		"* * * 13 *",
+		"* * * * 8",
+		"*/0 * * * *",
+		"5-1 * * * *",
+		"CRON_TZ=Not/AZone 0 3 * * *",
+	}
+	for _, spec := range specs {
+		if _, err := Parse(spec); err == nil {
+			t.Errorf("Parse(%q) succeeded, expected an error", spec)
+		}
+	}
+}
+
+func TestScheduleNext(t *testing.T) {
+	utc := time.UTC
+	losAngeles, err := time.LoadLocation("America/Los_Angeles")
+	if err != nil {
+		t.Skipf("time zone data not available: %v", err)
+	}
+
+	// Wednesday 2025-01-15 10:30:20 UTC
+	from := time.Date(2025, time.January, 15, 10, 30, 20, 0, utc)
+
+	tests := []struct {
+		spec     string
+		expected time.Time
+	}{
+		{"@every 30s", from.Add(30 * time.Second)},
+		{"5m", from.Add(5 * time.Minute)},
+		{"CRON_TZ=UTC */15 * * * *", time.Date(2025, time.January, 15, 10, 45, 0, 0, utc)},
+		{"CRON_TZ=UTC 0 3 * * *", time.Date(2025, time.January, 16, 3, 0, 0, 0, utc)},
+		{"CRON_TZ=UTC @hourly", time.Date(2025, time.January, 15, 11, 0, 0, 0, utc)},
+		{"CRON_TZ=UTC 0 9 * * mon", time.Date(2025, time.January, 20, 9, 0, 0, 0, utc)},
+		{"CRON_TZ=UTC 0 9 * * 1-5", time.Date(2025, time.January, 16, 9, 0, 0, 0, utc)},
+		{"CRON_TZ=UTC 0 0 1 feb *", time.Date(2025, time.February, 1, 0, 0, 0, 0, utc)},
+		{"CRON_TZ=UTC 0 0 29 2 *", time.Date(2028, time.February, 29, 0, 0, 0, 0, utc)},
+		// Day of month OR day of week when both are restricted: the 20th or any Sunday.
+		{"CRON_TZ=UTC 0 0 20 * sun", time.Date(2025, time.January, 19, 0, 0, 0, 0, utc)},
+		// 10:30 UTC is 02:30 in Los Angeles, so 03:00 local time is still ahead on the same day.
+		{"CRON_TZ=America/Los_Angeles 0 3 * * *", time.Date(2025, time.January, 15, 3, 0, 0, 0, losAngeles)},
+	}
+
+	for _, tt := range tests {
+		schedule, err := Parse(tt.spec)
+		if err != nil {
+			t.Errorf("Parse(%q) failed: %v", tt.spec, err)
+			continue
+		}
+		if got := schedule.Next(from); !got.Equal(tt.expected) {
+			t.Errorf("Parse(%q).Next(%v) = %v, want %v", tt.spec, from, got, tt.expected)
+		}
+	}
+}
+
+func TestScheduleNeverMatches(t *testing.T) {
+	schedule, err := Parse("0 0 30 2 *")
+	if err != nil {
+		t.Fatalf("Parse failed: %v", err)
+	}
+	if next := schedule.Next(time.Now()); !next.IsZero() {
+		t.Errorf("expected zero time for a schedule that never fires, got %v", next)
+	}
+}
+
+func TestRun(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	var runs atomic.Int32
+	done := make(chan struct{})
+	go func() {
+		Run(ctx, "test", Every(10*time.Millisecond), func() { runs.Add(1) })
+		close(done)
+	}()
+
+	time.Sleep(55 * time.Millisecond)
+	cancel()
+	select {
+	case <-done:
+	case <-time.After(time.Second):
+		t.Fatal("Run did not return after the context was canceled")
+	}
+	if n := runs.Load(); n < 3 {
+		t.Errorf("expected at least 3 runs in 55ms with a 10ms interval, got %d", n)
+	}
+	if running := testutil.ToFloat64(TaskRunningGauge.WithLabelValues("test")); running != 0 {
+		t.Errorf("expected no run in progress, got %v", running)
+	}
+	if completed := testutil.ToFloat64(TaskLastCompletedGauge.WithLabelValues("test")); completed == 0 {
+		t.Error("expected the completion time of the last run to be recorded")
+	}
+	if n := testutil.CollectAndCount(TickLagHistogram); n == 0 {
+		t.Error("expected the tick lag to be observed")
+	}
+}
+
+func TestRunShutdown(t *testing.T) {
+	coordinator, ctx := shutdown.New(context.Background())
+	started := make(chan struct{})
+	release := make(chan struct{})
+	var finished atomic.Bool
+	done := make(chan struct{})
+	go func() {
+		Run(ctx, "test_shutdown", Every(5*time.Millisecond), func() {
+			select {
+			case started <- struct{}{}:
+				<-release
+				finished.Store(true)
+			default:
+			}
+		})
+		close(done)
+	}()
+
+	<-started
+	go func() {
+		time.Sleep(20 * time.Millisecond)
+		close(release)
+	}()
+	if !coordinator.Shutdown(time.Second) {
+		t.Fatal("expected the run in progress to be drained")
+	}
+	if !finished.Load() {
+		t.Error("expected the shutdown to wait for the run in progress")
+	}
+	select {
+	case <-done:
+	case <-time.After(time.Second):
+		t.Fatal("Run did not return after the shutdown")
+	}
+}
@@ -0,0 +1,56 @@
+package scheduler
+
+import (
+	"context"
+	"time"
+
+	"watchdog.onebusaway.org/internal/shutdown"
+)
+
+// Run executes task at every activation of schedule until ctx is canceled. name identifies the
+// task in the self-monitoring metrics of the scheduler (e.g. watchdog_scheduler_task_running).
+//
+// It replaces the `time.NewTicker` + `select` loops used by the background routines:
+//   - Activations are computed from the previous scheduled time, not from when the task
+//     finished, so interval schedules do not drift (like a ticker).
+//   - Runs of the same task never overlap. If a run takes longer than the gap to the next
+//     activation, the missed activations are skipped and the schedule resumes from now.
+//   - Run returns when ctx is canceled or the schedule has no further activations.
+//   - With a shutdown.Coordinator in ctx, Run also returns when the shutdown begins, and runs
+//     in progress are waited for before ctx is canceled.
+//
+// A task whose run never finishes is visible as watchdog_scheduler_task_running stuck at 1 and
+// a watchdog_scheduler_task_last_completed_timestamp that stops advancing.
+//
+// The task is not run immediately; the first run happens at the first activation after Run is called.
+func Run(ctx context.Context, name string, schedule Schedule, task func()) {
+	next := schedule.Next(time.Now())
+	for !next.IsZero() {
+		timer := time.NewTimer(time.Until(next))
+		select {
+		case <-ctx.Done():
+			timer.Stop()
+			return
+		case <-shutdown.Stopping(ctx):
+			timer.Stop()
+			return
+		case <-timer.C:
+		}
+		TickLagHistogram.WithLabelValues(name).Observe(max(time.Since(next), 0).Seconds())
+
+		done, ok := shutdown.Begin(ctx)
+		if !ok {
+			return
+		}
+		TaskRunningGauge.WithLabelValues(name).Set(1)
+		task()
+		TaskRunningGauge.WithLabelValues(name).Set(0)
+		TaskLastCompletedGauge.WithLabelValues(name).SetToCurrentTime()
+		done()
+
+		next = schedule.Next(next)
+		if now := time.Now(); !next.After(now) {
+			next = schedule.Next(now)
+		}
+	}
+}
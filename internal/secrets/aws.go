@@ -0,0 +1,72 @@
+package secrets
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"strings"
+	"sync"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/secretsmanager"
+)
+
+// AWS reads secrets from AWS Secrets Manager. References are awssm://<secret id>[#<key>],
+// where the secret id is the name or the ARN of the secret, and key selects a field of a secret
+// holding a JSON object, e.g. awssm://prod/watchdog#oba_api_key.
+type AWS struct {
+	// Client makes the requests (see AWSConfig).
+	Client *http.Client
+
+	// mu serializes the creation of secretsManager, the client of Secrets Manager.
+	mu             sync.Mutex
+	secretsManager *secretsmanager.Client
+}
+
+// AWSFromEnv returns an AWS provider authenticated with the default credential chain of the AWS
+// SDK (see AWSConfig). AWS_ENDPOINT_URL_SECRETS_MANAGER (or AWS_ENDPOINT_URL) overrides the
+// endpoint of Secrets Manager.
+func AWSFromEnv(client *http.Client) *AWS {
+	return &AWS{Client: client}
+}
+
+// client returns the client of Secrets Manager, created on first use.
+func (a *AWS) client(ctx context.Context) (*secretsmanager.Client, error) {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	if a.secretsManager != nil {
+		return a.secretsManager, nil
+	}
+	cfg, err := AWSConfig(ctx, a.Client)
+	if err != nil {
+		return nil, err
+	}
+	a.secretsManager = secretsmanager.NewFromConfig(cfg)
+	return a.secretsManager, nil
+}
+
+// Resolve returns the current version of the secret id, or its field key.
+func (a *AWS) Resolve(ctx context.Context, id, key string) (string, error) {
+	client, err := a.client(ctx)
+	if err != nil {
+		return "", err
+	}
+	region := client.Options().Region
+	// arn:aws:secretsmanager:<region>:<account>:secret:<name>
+	if parts := strings.SplitN(id, ":", 6); len(parts) == 6 && parts[0] == "arn" {
+		region = parts[3]
+	}
+	if region == "" {
+		return "", errors.New("AWS_REGION must be set, or the secret id must be an ARN")
+	}
+	secret, err := client.GetSecretValue(ctx, &secretsmanager.GetSecretValueInput{SecretId: aws.String(id)},
+		func(o *secretsmanager.Options) { o.Region = region })
+	if err != nil {
+		return "", fmt.Errorf("reading %s failed: %w", id, err)
+	}
+	if secret.SecretString == nil {
+		return "", fmt.Errorf("secret %s is binary, only string secrets are supported", id)
+	}
+	return selectKey(*secret.SecretString, key)
+}
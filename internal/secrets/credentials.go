@@ -0,0 +1,87 @@
+package secrets
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"os"
+	"sync"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	awsconfig "github.com/aws/aws-sdk-go-v2/config"
+	"golang.org/x/oauth2"
+	"golang.org/x/oauth2/google"
+	"watchdog.onebusaway.org/internal/ttlcache"
+)
+
+// AWSConfig loads the configuration of the AWS SDK with its default credential chain: the
+// environment (AWS_ACCESS_KEY_ID, AWS_SECRET_ACCESS_KEY, AWS_SESSION_TOKEN), web identity tokens
+// (IAM roles for service accounts on EKS), the shared ~/.aws config and credentials files
+// (AWS_PROFILE), and the roles of ECS tasks and EC2 instances. The region is read from AWS_REGION
+// or the profile, and the endpoints can be overridden with AWS_ENDPOINT_URL_<SERVICE> or
+// AWS_ENDPOINT_URL. Requests are made with client, unless AWS_CA_BUNDLE is set: the SDK can only
+// add its certificates to a client of its own.
+func AWSConfig(ctx context.Context, client *http.Client) (aws.Config, error) {
+	var options []func(*awsconfig.LoadOptions) error
+	if os.Getenv("AWS_CA_BUNDLE") == "" {
+		options = append(options, awsconfig.WithHTTPClient(client))
+	}
+	cfg, err := awsconfig.LoadDefaultConfig(ctx, options...)
+	if err != nil {
+		return aws.Config{}, fmt.Errorf("failed to load the AWS configuration: %w", err)
+	}
+	return cfg, nil
+}
+
+// GoogleClient returns a client authenticated for scopes with the Application Default Credentials
+// of Google Cloud: the JSON file of GOOGLE_APPLICATION_CREDENTIALS (a service account key or an
+// external account for workload identity federation), the credentials of gcloud, or the service
+// account of the instance (GKE workload identity, Cloud Run, GCE). GOOGLE_OAUTH_ACCESS_TOKEN, if
+// set, is used as is instead. Requests, including those for tokens, are made with the transport
+// of client, and tokens are cached until a minute before they expire.
+func GoogleClient(ctx context.Context, client *http.Client, scopes ...string) (*http.Client, error) {
+	// Tokens are refreshed later on, after the request that created the client is over.
+	ctx = context.WithValue(context.WithoutCancel(ctx), oauth2.HTTPClient, client)
+	if token := os.Getenv("GOOGLE_OAUTH_ACCESS_TOKEN"); token != "" {
+		return oauth2.NewClient(ctx, oauth2.StaticTokenSource(&oauth2.Token{AccessToken: token})), nil
+	}
+	credentials, err := google.FindDefaultCredentials(ctx, scopes...)
+	if err != nil {
+		return nil, fmt.Errorf("no Google Cloud credentials: %w", err)
+	}
+	tokens := &cachedTokenSource{
+		source: credentials.TokenSource,
+		tokens: ttlcache.New[string, *oauth2.Token]("gcp_access_token", 1, 0),
+	}
+	return oauth2.NewClient(ctx, tokens), nil
+}
+
+// cachedTokenSource caches the tokens of source until a minute before they expire.
+type cachedTokenSource struct {
+	source oauth2.TokenSource
+
+	// mu serializes the requests for a token, cached in tokens.
+	mu     sync.Mutex
+	tokens *ttlcache.Cache[string, *oauth2.Token]
+}
+
+// Token returns the cached token, or else a new token of the source.
+func (s *cachedTokenSource) Token() (*oauth2.Token, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.tokens.GetOrLoad("", func() (*oauth2.Token, time.Duration, error) {
+		token, err := s.source.Token()
+		if err != nil {
+			return nil, 0, err
+		}
+		if token.Expiry.IsZero() {
+			return token, 0, nil
+		}
+		ttl := time.Until(token.Expiry) - time.Minute
+		if ttl <= 0 {
+			ttl = -1
+		}
+		return token, ttl, nil
+	})
+}
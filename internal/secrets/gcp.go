@@ -0,0 +1,76 @@
+package secrets
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"strings"
+	"sync"
+
+	secretmanager "cloud.google.com/go/secretmanager/apiv1"
+	"cloud.google.com/go/secretmanager/apiv1/secretmanagerpb"
+	"google.golang.org/api/option"
+)
+
+// GCP reads secrets from Google Cloud Secret Manager. References are
+// gcpsm://projects/<project>/secrets/<secret>[/versions/<version>][#<key>]: the latest version
+// is read unless one is given, and key selects a field of a secret holding a JSON object.
+type GCP struct {
+	// Endpoint overrides the endpoint of Secret Manager (empty = the global endpoint).
+	Endpoint string
+	// Client makes the requests (see GoogleClient).
+	Client *http.Client
+
+	// mu serializes the creation of secretManager, the client of Secret Manager.
+	mu            sync.Mutex
+	secretManager *secretmanager.Client
+}
+
+// GCPFromEnv returns a GCP provider authenticated with the Application Default Credentials of
+// Google Cloud, or with GOOGLE_OAUTH_ACCESS_TOKEN if it is set (see GoogleClient).
+func GCPFromEnv(client *http.Client) *GCP {
+	return &GCP{Client: client}
+}
+
+// client returns the client of Secret Manager, created on first use.
+func (g *GCP) client(ctx context.Context) (*secretmanager.Client, error) {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	if g.secretManager != nil {
+		return g.secretManager, nil
+	}
+	httpClient, err := GoogleClient(ctx, g.Client, secretmanager.DefaultAuthScopes()...)
+	if err != nil {
+		return nil, err
+	}
+	options := []option.ClientOption{option.WithHTTPClient(httpClient)}
+	if g.Endpoint != "" {
+		options = append(options, option.WithEndpoint(g.Endpoint))
+	}
+	// The REST client is used, as for the other requests of the watchdog, rather than gRPC.
+	client, err := secretmanager.NewRESTClient(context.WithoutCancel(ctx), options...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create the Secret Manager client: %w", err)
+	}
+	g.secretManager = client
+	return client, nil
+}
+
+// Resolve returns the secret version at name, or its field key.
+func (g *GCP) Resolve(ctx context.Context, name, key string) (string, error) {
+	if !strings.HasPrefix(name, "projects/") || !strings.Contains(name, "/secrets/") {
+		return "", fmt.Errorf("invalid secret name %q, expected projects/<project>/secrets/<secret>", name)
+	}
+	if !strings.Contains(name, "/versions/") {
+		name += "/versions/latest"
+	}
+	client, err := g.client(ctx)
+	if err != nil {
+		return "", err
+	}
+	version, err := client.AccessSecretVersion(ctx, &secretmanagerpb.AccessSecretVersionRequest{Name: name})
+	if err != nil {
+		return "", fmt.Errorf("reading %s failed: %w", name, err)
+	}
+	return selectKey(string(version.GetPayload().GetData()), key)
+}
@@ -0,0 +1,114 @@
+// Package secrets resolves references to the secrets of external secret managers, so that the
+// API keys of the configuration can be kept in a secrets backend rather than in the
+// configuration itself.
+//
+// A reference is a URI whose scheme names the backend, with an optional #key selecting a field
+// of a secret holding a JSON object (or, for Vault, of the key/value secret):
+//
+//	vault://secret/data/watchdog#oba_api_key
+//	awssm://prod/watchdog#oba_api_key
+//	gcpsm://projects/my-project/secrets/watchdog-oba-key
+package secrets
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"sort"
+	"strings"
+)
+
+// Provider reads secrets from a secrets backend.
+type Provider interface {
+	// Resolve returns the value of the secret at path, or of its field key if key is not empty.
+	Resolve(ctx context.Context, path, key string) (string, error)
+}
+
+// Resolver resolves secret references with the Provider registered for their scheme.
+type Resolver struct {
+	providers map[string]Provider
+}
+
+// NewResolver returns a Resolver with the Vault ("vault://"), AWS Secrets Manager ("awssm://")
+// and GCP Secret Manager ("gcpsm://") providers, configured from the environment (see
+// VaultFromEnv, AWSFromEnv and GCPFromEnv) and making their requests with client.
+func NewResolver(client *http.Client) *Resolver {
+	r := &Resolver{providers: make(map[string]Provider)}
+	r.Register("vault", VaultFromEnv(client))
+	r.Register("awssm", AWSFromEnv(client))
+	r.Register("gcpsm", GCPFromEnv(client))
+	return r
+}
+
+// Register makes r resolve the references with the given scheme with provider.
+func (r *Resolver) Register(scheme string, provider Provider) {
+	r.providers[scheme] = provider
+}
+
+// Schemes returns the schemes of the registered providers, sorted.
+func (r *Resolver) Schemes() []string {
+	schemes := make([]string, 0, len(r.providers))
+	for scheme := range r.providers {
+		schemes = append(schemes, scheme)
+	}
+	sort.Strings(schemes)
+	return schemes
+}
+
+// IsReference reports whether value is a reference to a secret of a registered provider, rather
+// than a literal value. A nil Resolver has no providers.
+func (r *Resolver) IsReference(value string) bool {
+	if r == nil {
+		return false
+	}
+	scheme, _, ok := strings.Cut(value, "://")
+	_, registered := r.providers[scheme]
+	return ok && registered
+}
+
+// Resolve returns the value of the secret referenced by ref.
+func (r *Resolver) Resolve(ctx context.Context, ref string) (string, error) {
+	if !r.IsReference(ref) {
+		return "", fmt.Errorf("not a secret reference (expected one of %s)", strings.Join(r.Schemes(), "://, ")+"://")
+	}
+	scheme, rest, _ := strings.Cut(ref, "://")
+	// The path is not parsed as a URL: AWS secret ARNs contain colons.
+	path, key, _ := strings.Cut(rest, "#")
+	if path == "" {
+		return "", fmt.Errorf("secret reference %s has no path", ref)
+	}
+	value, err := r.providers[scheme].Resolve(ctx, path, key)
+	if err != nil {
+		return "", fmt.Errorf("%s: %w", scheme, err)
+	}
+	return value, nil
+}
+
+// selectKey returns the field key of the JSON object secret, or secret itself if key is empty.
+func selectKey(secret, key string) (string, error) {
+	if key == "" {
+		return secret, nil
+	}
+	var fields map[string]any
+	if err := json.Unmarshal([]byte(secret), &fields); err != nil {
+		return "", fmt.Errorf("secret is not a JSON object, cannot select key %q", key)
+	}
+	return fieldValue(fields, key)
+}
+
+// fieldValue returns the field key of a secret as a string.
+func fieldValue(fields map[string]any, key string) (string, error) {
+	value, ok := fields[key]
+	if !ok {
+		return "", fmt.Errorf("secret has no key %q", key)
+	}
+	switch value := value.(type) {
+	case string:
+		return value, nil
+	case float64, bool:
+		return fmt.Sprint(value), nil
+	default:
+		return "", fmt.Errorf("key %q of the secret is not a string", key)
+	}
+}
@@ -0,0 +1,183 @@
+package secrets
+
+import (
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"strings"
+	"sync/atomic"
+	"testing"
+)
+
+func TestResolver(t *testing.T) {
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("X-Vault-Token") != "token" {
+			w.WriteHeader(http.StatusForbidden)
+			return
+		}
+		switch r.URL.Path {
+		case "/v1/secret/data/watchdog":
+			_, _ = io.WriteString(w, `{"data": {"data": {"oba_api_key": "v2-key"}, "metadata": {"version": 3}}}`)
+		case "/v1/kv/watchdog":
+			_, _ = io.WriteString(w, `{"data": {"oba_api_key": "v1-key", "data": "not nested"}}`)
+		default:
+			w.WriteHeader(http.StatusNotFound)
+		}
+	}))
+	defer ts.Close()
+
+	t.Setenv("VAULT_ADDR", ts.URL)
+	t.Setenv("VAULT_TOKEN", "token")
+	t.Setenv("VAULT_NAMESPACE", "")
+	t.Setenv("VAULT_MAX_RETRIES", "0")
+	r := &Resolver{providers: map[string]Provider{}}
+	r.Register("vault", VaultFromEnv(ts.Client()))
+
+	tests := []struct {
+		ref     string
+		want    string
+		wantErr string
+	}{
+		{ref: "vault://secret/data/watchdog#oba_api_key", want: "v2-key"},
+		{ref: "vault://kv/watchdog#oba_api_key", want: "v1-key"},
+		{ref: "vault://kv/watchdog#data", want: "not nested"},
+		{ref: "vault://kv/watchdog#missing", wantErr: `vault: secret has no key "missing"`},
+		{ref: "vault://kv/watchdog", wantErr: "a key is required"},
+		{ref: "vault://kv/other#key", wantErr: "secret kv/other not found"},
+		{ref: "vault://#key", wantErr: "has no path"},
+		{ref: "s3://bucket/key", wantErr: "not a secret reference (expected one of vault://)"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.ref, func(t *testing.T) {
+			got, err := r.Resolve(context.Background(), tt.ref)
+			if tt.wantErr != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+					t.Fatalf("expected an error containing %q, got %q, %v", tt.wantErr, got, err)
+				}
+				return
+			}
+			if err != nil || got != tt.want {
+				t.Fatalf("expected %q, got %q, %v", tt.want, got, err)
+			}
+		})
+	}
+
+	if !r.IsReference("vault://a#b") || r.IsReference("plain-api-key") || r.IsReference("https://example.com") {
+		t.Error("unexpected IsReference results")
+	}
+	var none *Resolver
+	if none.IsReference("vault://a#b") {
+		t.Error("expected a nil Resolver to have no references")
+	}
+}
+
+// isolateAWS keeps the AWS SDK from reading the AWS configuration of the machine running the tests.
+func isolateAWS(t *testing.T) {
+	dir := t.TempDir()
+	t.Setenv("AWS_CONFIG_FILE", filepath.Join(dir, "config"))
+	t.Setenv("AWS_SHARED_CREDENTIALS_FILE", filepath.Join(dir, "credentials"))
+	t.Setenv("AWS_EC2_METADATA_DISABLED", "true")
+	t.Setenv("AWS_PROFILE", "")
+}
+
+func TestAWS(t *testing.T) {
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var body struct{ SecretId string }
+		_ = json.NewDecoder(r.Body).Decode(&body)
+		if r.Header.Get("X-Amz-Target") != "secretsmanager.GetSecretValue" ||
+			!strings.HasPrefix(r.Header.Get("Authorization"), "AWS4-HMAC-SHA256 Credential=AKID/") ||
+			r.Header.Get("X-Amz-Security-Token") != "session" {
+			w.WriteHeader(http.StatusForbidden)
+			return
+		}
+		switch body.SecretId {
+		case "prod/watchdog":
+			_, _ = io.WriteString(w, `{"Name": "prod/watchdog", "SecretString": "{\"oba_api_key\": \"aws-key\"}"}`)
+		case "arn:aws:secretsmanager:eu-west-1:123456789012:secret:plain":
+			if !strings.Contains(r.Header.Get("Authorization"), "/eu-west-1/secretsmanager/") {
+				w.WriteHeader(http.StatusForbidden)
+				return
+			}
+			_, _ = io.WriteString(w, `{"SecretString": "plain-key"}`)
+		default:
+			w.WriteHeader(http.StatusBadRequest)
+			_, _ = io.WriteString(w, `{"__type": "ResourceNotFoundException", "message": "Secrets Manager can't find the specified secret."}`)
+		}
+	}))
+	defer ts.Close()
+
+	isolateAWS(t)
+	t.Setenv("AWS_REGION", "us-east-1")
+	t.Setenv("AWS_ENDPOINT_URL_SECRETS_MANAGER", ts.URL)
+	t.Setenv("AWS_ACCESS_KEY_ID", "AKID")
+	t.Setenv("AWS_SECRET_ACCESS_KEY", "secret")
+	t.Setenv("AWS_SESSION_TOKEN", "session")
+	aws := AWSFromEnv(ts.Client())
+	if got, err := aws.Resolve(context.Background(), "prod/watchdog", "oba_api_key"); err != nil || got != "aws-key" {
+		t.Errorf("expected aws-key, got %q, %v", got, err)
+	}
+	if got, err := aws.Resolve(context.Background(), "arn:aws:secretsmanager:eu-west-1:123456789012:secret:plain", ""); err != nil || got != "plain-key" {
+		t.Errorf("expected the region of the ARN to be used, got %q, %v", got, err)
+	}
+	if _, err := aws.Resolve(context.Background(), "missing", ""); err == nil || !strings.Contains(err.Error(), "ResourceNotFoundException") {
+		t.Errorf("expected the error of Secrets Manager, got %v", err)
+	}
+	t.Setenv("AWS_REGION", "")
+	if _, err := AWSFromEnv(ts.Client()).Resolve(context.Background(), "prod/watchdog", ""); err == nil || !strings.Contains(err.Error(), "AWS_REGION") {
+		t.Errorf("expected an error without a region, got %v", err)
+	}
+}
+
+func TestGCP(t *testing.T) {
+	var tokens atomic.Int32
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/computeMetadata/v1/instance/service-accounts/default/token" {
+			if r.Header.Get("Metadata-Flavor") != "Google" {
+				w.WriteHeader(http.StatusForbidden)
+				return
+			}
+			tokens.Add(1)
+			_, _ = io.WriteString(w, `{"access_token": "instance-token", "expires_in": 3599}`)
+			return
+		}
+		if r.Header.Get("Authorization") != "Bearer instance-token" {
+			w.WriteHeader(http.StatusUnauthorized)
+			return
+		}
+		switch r.URL.Path {
+		case "/v1/projects/p/secrets/oba-key/versions/latest:access":
+			payload := base64.StdEncoding.EncodeToString([]byte("gcp-key"))
+			_, _ = io.WriteString(w, `{"payload": {"data": "`+payload+`"}}`)
+		case "/v1/projects/p/secrets/keys/versions/2:access":
+			payload := base64.StdEncoding.EncodeToString([]byte(`{"gtfs_rt_api_value": "rt-key"}`))
+			_, _ = io.WriteString(w, `{"payload": {"data": "`+payload+`"}}`)
+		default:
+			w.WriteHeader(http.StatusNotFound)
+		}
+	}))
+	defer ts.Close()
+
+	// Without other credentials, the service account of the instance is used.
+	t.Setenv("GOOGLE_APPLICATION_CREDENTIALS", "")
+	t.Setenv("GOOGLE_OAUTH_ACCESS_TOKEN", "")
+	t.Setenv("CLOUDSDK_CONFIG", t.TempDir())
+	t.Setenv("GCE_METADATA_HOST", strings.TrimPrefix(ts.URL, "http://"))
+	gcp := GCPFromEnv(ts.Client())
+	gcp.Endpoint = ts.URL
+	if got, err := gcp.Resolve(context.Background(), "projects/p/secrets/oba-key", ""); err != nil || got != "gcp-key" {
+		t.Errorf("expected gcp-key, got %q, %v", got, err)
+	}
+	if got, err := gcp.Resolve(context.Background(), "projects/p/secrets/keys/versions/2", "gtfs_rt_api_value"); err != nil || got != "rt-key" {
+		t.Errorf("expected rt-key, got %q, %v", got, err)
+	}
+	if n := tokens.Load(); n != 1 {
+		t.Errorf("expected the access token to be cached, got %d token requests", n)
+	}
+	if _, err := gcp.Resolve(context.Background(), "my-secret", ""); err == nil {
+		t.Error("expected an error for a name that is not a secret resource name")
+	}
+}
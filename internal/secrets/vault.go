@@ -0,0 +1,104 @@
+package secrets
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"os"
+	"sync"
+
+	vault "github.com/hashicorp/vault/api"
+)
+
+// Vault reads secrets from the key/value secrets engine of HashiCorp Vault, version 1 or 2.
+// References are vault://<path>#<key>, where path is the API path of the secret without the
+// /v1/ prefix, e.g. vault://secret/data/watchdog#oba_api_key for the secret "watchdog" of a
+// version 2 engine mounted at secret/.
+type Vault struct {
+	// Client makes the requests, unless the TLS or proxy settings of Vault are set in the
+	// environment.
+	Client *http.Client
+
+	// mu serializes the creation of vault, the client of Vault.
+	mu    sync.Mutex
+	vault *vault.Client
+}
+
+// VaultFromEnv returns a Vault provider configured with the environment variables of the Vault
+// CLI: VAULT_ADDR, VAULT_TOKEN, VAULT_NAMESPACE, and VAULT_CACERT, VAULT_CLIENT_CERT and the
+// other TLS and proxy settings.
+func VaultFromEnv(client *http.Client) *Vault {
+	return &Vault{Client: client}
+}
+
+// vaultTransportVariables are the environment variables of the TLS and proxy settings of Vault,
+// which the client of Vault can only apply to a transport of its own.
+var vaultTransportVariables = []string{
+	vault.EnvVaultCACert, vault.EnvVaultCACertBytes, vault.EnvVaultCAPath, vault.EnvVaultClientCert,
+	vault.EnvVaultClientKey, vault.EnvVaultInsecure, vault.EnvVaultTLSServerName,
+	vault.EnvVaultProxyAddr, vault.EnvHTTPProxy,
+}
+
+// client returns the client of Vault, created on first use.
+func (v *Vault) client() (*vault.Client, error) {
+	v.mu.Lock()
+	defer v.mu.Unlock()
+	if v.vault != nil {
+		return v.vault, nil
+	}
+	if os.Getenv(vault.EnvVaultAddress) == "" || os.Getenv(vault.EnvVaultToken) == "" {
+		return nil, errors.New("VAULT_ADDR and VAULT_TOKEN must be set")
+	}
+	config := vault.DefaultConfig()
+	if config.Error != nil {
+		return nil, fmt.Errorf("invalid Vault configuration: %w", config.Error)
+	}
+	if v.Client != nil && firstEnv(vaultTransportVariables...) == "" {
+		// The client of Vault changes the redirect policy of its HTTP client.
+		httpClient := *v.Client
+		config.HttpClient = &httpClient
+	}
+	client, err := vault.NewClient(config)
+	if err != nil {
+		return nil, fmt.Errorf("invalid Vault configuration: %w", err)
+	}
+	v.vault = client
+	return client, nil
+}
+
+// Resolve returns the field key of the secret at path.
+func (v *Vault) Resolve(ctx context.Context, path, key string) (string, error) {
+	if key == "" {
+		return "", errors.New("a key is required, e.g. vault://" + path + "#api_key")
+	}
+	client, err := v.client()
+	if err != nil {
+		return "", err
+	}
+	secret, err := client.Logical().ReadWithContext(ctx, path)
+	if err != nil {
+		return "", fmt.Errorf("reading %s failed: %w", path, err)
+	}
+	if secret == nil || secret.Data == nil {
+		return "", fmt.Errorf("secret %s not found", path)
+	}
+	fields := secret.Data
+	// Version 2 engines nest the fields of the secret, next to its metadata.
+	if nested, ok := fields["data"].(map[string]any); ok {
+		if _, ok := fields["metadata"]; ok {
+			fields = nested
+		}
+	}
+	return fieldValue(fields, key)
+}
+
+// firstEnv returns the value of the first of the environment variables that is set.
+func firstEnv(names ...string) string {
+	for _, name := range names {
+		if value := os.Getenv(name); value != "" {
+			return value
+		}
+	}
+	return ""
+}
@@ -0,0 +1,20 @@
+package sharedstore
+
+import (
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promauto"
+)
+
+var (
+	// SharedStoreSyncedCounter counts the entries published by other instances and synced.
+	SharedStoreSyncedCounter = promauto.NewCounterVec(prometheus.CounterOpts{
+		Name: "watchdog_shared_store_synced_total",
+		Help: "Entries of a shared store published by another instance and synced by this one, by store",
+	}, []string{"store"})
+
+	// SharedStoreErrorsCounter counts the failed syncs and writes of the shared stores.
+	SharedStoreErrorsCounter = promauto.NewCounterVec(prometheus.CounterOpts{
+		Name: "watchdog_shared_store_errors_total",
+		Help: "Failed syncs (sync) and writes (publish) of a shared store, by store and operation",
+	}, []string{"store", "operation"})
+)
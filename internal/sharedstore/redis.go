@@ -0,0 +1,52 @@
+package sharedstore
+
+import (
+	"context"
+	"errors"
+
+	"github.com/redis/go-redis/v9"
+)
+
+// Redis is a Backend keeping each entry in a hash under Prefix, with the fields "value" and
+// "version". Both are written by one HSET and read by one HMGET, so a reader never sees the value
+// of one write labelled with the version of another.
+type Redis struct {
+	Client *redis.Client
+	// Prefix namespaces the keys, e.g. "watchdog:" to share a Redis server with other uses.
+	Prefix string
+}
+
+// Put implements Backend.
+func (r *Redis) Put(ctx context.Context, key string, value []byte, version string) error {
+	return r.Client.HSet(ctx, r.Prefix+key, "value", value, "version", version).Err()
+}
+
+// Get implements Backend.
+func (r *Redis) Get(ctx context.Context, key, version string) ([]byte, string, bool, error) {
+	current, err := r.Client.HGet(ctx, r.Prefix+key, "version").Result()
+	if err != nil && !errors.Is(err, redis.Nil) {
+		return nil, "", false, err
+	}
+	if current == version {
+		return nil, version, false, nil
+	}
+	if current == "" {
+		return nil, "", true, nil
+	}
+	fields, err := r.Client.HMGet(ctx, r.Prefix+key, "value", "version").Result()
+	if err != nil {
+		return nil, "", false, err
+	}
+	value, _ := fields[0].(string)
+	// The entry may have been deleted or written again since its version was read.
+	current, _ = fields[1].(string)
+	if current == "" {
+		return nil, "", current != version, nil
+	}
+	return []byte(value), current, true, nil
+}
+
+// Delete implements Backend.
+func (r *Redis) Delete(ctx context.Context, key string) error {
+	return r.Client.Del(ctx, r.Prefix+key).Err()
+}
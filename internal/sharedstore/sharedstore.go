@@ -0,0 +1,162 @@
+// Package sharedstore shares the in-memory stores of the watchdog (GTFS static data, bounding
+// boxes, bundle validators, backoffs, raw GTFS-RT feeds) between the instances of a
+// horizontally scaled deployment, through a Backend such as Redis.
+//
+// The stores stay in memory, and each instance reads them locally: an instance publishes the
+// entries it writes to the backend (see Replica.Publish), and picks up those published by the
+// other instances when it syncs (see Replica.Sync). An instance that syncs the bundle of a
+// server downloaded by another instance, along with its validators, only sends conditional
+// requests for it, so a bundle is downloaded once for all instances.
+package sharedstore
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"sync"
+	"time"
+)
+
+// publishTimeout bounds the time a write to a store waits for the backend.
+const publishTimeout = 10 * time.Second
+
+// Backend holds the shared entries, each with a version that changes whenever it is written.
+type Backend interface {
+	// Put writes the value and version of an entry.
+	Put(ctx context.Context, key string, value []byte, version string) error
+	// Get returns the value and version of an entry if its version differs from version, and
+	// reports whether it does. A missing entry has the empty version and a nil value.
+	Get(ctx context.Context, key, version string) ([]byte, string, bool, error)
+	// Delete removes an entry.
+	Delete(ctx context.Context, key string) error
+}
+
+// Shareable is implemented by the in-memory stores whose entries can be shared through a Backend.
+type Shareable interface {
+	// Share publishes the entries stored from now on to backend. It must be called before the
+	// store is used.
+	Share(backend Backend)
+	// Sync stores the entries of the given servers published by the other instances since the
+	// last sync.
+	Sync(ctx context.Context, serverIDs []int) error
+}
+
+// Replica shares the entries of a store, by server ID, through a Backend, encoded as JSON. It
+// is safe for concurrent use; a nil Replica shares nothing.
+type Replica[V any] struct {
+	store   string
+	backend Backend
+
+	mu sync.Mutex
+	// versions are the versions of the entries last published or synced by this instance.
+	versions map[int]string
+}
+
+// NewReplica returns a Replica of the entries of store (e.g. "gtfs_static"), or nil if
+// backend is nil.
+func NewReplica[V any](store string, backend Backend) *Replica[V] {
+	if backend == nil {
+		return nil
+	}
+	return &Replica[V]{store: store, backend: backend, versions: make(map[int]string)}
+}
+
+func (r *Replica[V]) key(serverID int) string {
+	return fmt.Sprintf("%s:%d", r.store, serverID)
+}
+
+func (r *Replica[V]) setVersion(serverID int, version string) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if version == "" {
+		delete(r.versions, serverID)
+	} else {
+		r.versions[serverID] = version
+	}
+}
+
+// Publish writes the entry of a server to the backend. A failed write is counted in
+// watchdog_shared_store_errors_total; the entry is published again at its next write.
+func (r *Replica[V]) Publish(serverID int, value V) {
+	if r == nil {
+		return
+	}
+	data, err := json.Marshal(value)
+	if err != nil {
+		SharedStoreErrorsCounter.WithLabelValues(r.store, "publish").Inc()
+		return
+	}
+	version := newVersion()
+	ctx, cancel := context.WithTimeout(context.Background(), publishTimeout)
+	defer cancel()
+	if err := r.backend.Put(ctx, r.key(serverID), data, version); err != nil {
+		SharedStoreErrorsCounter.WithLabelValues(r.store, "publish").Inc()
+		return
+	}
+	r.setVersion(serverID, version)
+}
+
+// Unpublish removes the entry of a server from the backend.
+func (r *Replica[V]) Unpublish(serverID int) {
+	if r == nil {
+		return
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), publishTimeout)
+	defer cancel()
+	if err := r.backend.Delete(ctx, r.key(serverID)); err != nil {
+		SharedStoreErrorsCounter.WithLabelValues(r.store, "publish").Inc()
+		return
+	}
+	r.setVersion(serverID, "")
+}
+
+// Sync picks up the entries of the servers published or removed by other instances since this
+// instance last published or synced them: set is called with each entry published, and
+// remove with each server whose entry was removed. It returns the errors of the servers that
+// could not be synced, which are tried again at the next sync.
+func (r *Replica[V]) Sync(ctx context.Context, serverIDs []int, set func(serverID int, value V), remove func(serverID int)) error {
+	if r == nil {
+		return nil
+	}
+	var errs []error
+	for _, serverID := range serverIDs {
+		r.mu.Lock()
+		known := r.versions[serverID]
+		r.mu.Unlock()
+
+		data, version, changed, err := r.backend.Get(ctx, r.key(serverID), known)
+		if err != nil {
+			SharedStoreErrorsCounter.WithLabelValues(r.store, "sync").Inc()
+			errs = append(errs, fmt.Errorf("failed to sync %s of server %d: %w", r.store, serverID, err))
+			continue
+		}
+		if !changed {
+			continue
+		}
+		if version == "" {
+			remove(serverID)
+			r.setVersion(serverID, "")
+			continue
+		}
+		var value V
+		if err := json.Unmarshal(data, &value); err != nil {
+			SharedStoreErrorsCounter.WithLabelValues(r.store, "sync").Inc()
+			errs = append(errs, fmt.Errorf("failed to decode %s of server %d: %w", r.store, serverID, err))
+			continue
+		}
+		set(serverID, value)
+		r.setVersion(serverID, version)
+		SharedStoreSyncedCounter.WithLabelValues(r.store).Inc()
+	}
+	return errors.Join(errs...)
+}
+
+// newVersion returns a random version, unique across instances.
+func newVersion() string {
+	b := make([]byte, 8)
+	_, _ = rand.Read(b)
+	return hex.EncodeToString(b)
+}
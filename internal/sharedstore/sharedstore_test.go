@@ -0,0 +1,129 @@
+package sharedstore
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/alicebob/miniredis/v2"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	"github.com/redis/go-redis/v9"
+)
+
+type entry struct {
+	Name  string
+	Count int
+}
+
+func TestReplicaSync(t *testing.T) {
+	server := miniredis.RunT(t)
+	server.RequireAuth("secret")
+	backend := &Redis{Client: redis.NewClient(&redis.Options{Addr: server.Addr(), Password: "secret"}), Prefix: "watchdog:"}
+	publisher := NewReplica[entry]("test_entries", backend)
+	subscriber := NewReplica[entry]("test_entries", backend)
+	ctx := context.Background()
+
+	synced := make(map[int]entry)
+	set := func(serverID int, e entry) { synced[serverID] = e }
+	remove := func(serverID int) { delete(synced, serverID) }
+
+	publisher.Publish(1, entry{Name: "first", Count: 1})
+	if !server.Exists("watchdog:test_entries:1") {
+		t.Fatal("expected the entry to be written under the prefix")
+	}
+	if err := subscriber.Sync(ctx, []int{1, 2}, set, remove); err != nil {
+		t.Fatal(err)
+	}
+	if synced[1] != (entry{Name: "first", Count: 1}) || len(synced) != 1 {
+		t.Errorf("expected the published entry to be synced, got %+v", synced)
+	}
+
+	// An unchanged entry is not synced again, and the publisher does not sync its own entries.
+	delete(synced, 1)
+	if err := subscriber.Sync(ctx, []int{1}, set, remove); err != nil {
+		t.Fatal(err)
+	}
+	if err := publisher.Sync(ctx, []int{1}, set, remove); err != nil {
+		t.Fatal(err)
+	}
+	if len(synced) != 0 {
+		t.Errorf("expected no changes to sync, got %+v", synced)
+	}
+
+	publisher.Publish(1, entry{Name: "second", Count: 2})
+	if err := subscriber.Sync(ctx, []int{1}, set, remove); err != nil {
+		t.Fatal(err)
+	}
+	if synced[1].Name != "second" {
+		t.Errorf("expected the new entry to be synced, got %+v", synced)
+	}
+
+	publisher.Unpublish(1)
+	if err := subscriber.Sync(ctx, []int{1}, set, remove); err != nil {
+		t.Fatal(err)
+	}
+	if _, ok := synced[1]; ok {
+		t.Error("expected the removed entry to be removed")
+	}
+}
+
+type failingBackend struct{}
+
+func (failingBackend) Put(context.Context, string, []byte, string) error { return errors.New("down") }
+
+func (failingBackend) Get(context.Context, string, string) ([]byte, string, bool, error) {
+	return nil, "", false, errors.New("down")
+}
+
+func (failingBackend) Delete(context.Context, string) error { return errors.New("down") }
+
+func TestReplicaErrors(t *testing.T) {
+	replica := NewReplica[entry]("failing_entries", failingBackend{})
+	replica.Publish(1, entry{})
+	if err := replica.Sync(context.Background(), []int{1}, func(int, entry) {}, func(int) {}); err == nil {
+		t.Error("expected the sync to fail")
+	}
+	if got := testutil.ToFloat64(SharedStoreErrorsCounter.WithLabelValues("failing_entries", "publish")); got != 1 {
+		t.Errorf("expected 1 publish error, got %v", got)
+	}
+	if got := testutil.ToFloat64(SharedStoreErrorsCounter.WithLabelValues("failing_entries", "sync")); got != 1 {
+		t.Errorf("expected 1 sync error, got %v", got)
+	}
+}
+
+func TestNilReplica(t *testing.T) {
+	replica := NewReplica[entry]("none", nil)
+	if replica != nil {
+		t.Fatal("expected no replica without a backend")
+	}
+	replica.Publish(1, entry{})
+	replica.Unpublish(1)
+	if err := replica.Sync(context.Background(), []int{1}, func(int, entry) { t.Error("unexpected entry") }, func(int) {}); err != nil {
+		t.Error(err)
+	}
+}
+
+func TestRedisPutIsAtomic(t *testing.T) {
+	server := miniredis.RunT(t)
+	backend := &Redis{Client: redis.NewClient(&redis.Options{Addr: server.Addr()}), Prefix: "watchdog:"}
+	ctx := context.Background()
+
+	if err := backend.Put(ctx, "entry", []byte("value"), "v1"); err != nil {
+		t.Fatal(err)
+	}
+	if value, version := server.HGet("watchdog:entry", "value"), server.HGet("watchdog:entry", "version"); value != "value" || version != "v1" {
+		t.Fatalf("expected the value and version in one hash, got %q, %q", value, version)
+	}
+	if value, version, changed, err := backend.Get(ctx, "entry", ""); err != nil || !changed || string(value) != "value" || version != "v1" {
+		t.Errorf("expected the new entry, got %q, %q, %v, %v", value, version, changed, err)
+	}
+	if _, _, changed, err := backend.Get(ctx, "entry", "v1"); err != nil || changed {
+		t.Errorf("expected the entry unchanged, got %v, %v", changed, err)
+	}
+	if err := backend.Delete(ctx, "entry"); err != nil {
+		t.Fatal(err)
+	}
+	if value, version, changed, err := backend.Get(ctx, "entry", "v1"); err != nil || !changed || value != nil || version != "" {
+		t.Errorf("expected the entry removed, got %q, %q, %v, %v", value, version, changed, err)
+	}
+}
@@ -0,0 +1,100 @@
+// Package shutdown drains the background work of the watchdog when it is stopped.
+//
+// On shutdown, canceling the application context right away would cut GTFS downloads and
+// checks in the middle. Instead, the Coordinator first stops scheduled routines from starting
+// new runs (see scheduler.Run) and waits, for a bounded time, for the runs and background tasks
+// in flight to finish. Only then is the application context canceled.
+package shutdown
+
+import (
+	"context"
+	"sync"
+	"time"
+)
+
+// Coordinator tracks the work in flight under the application context.
+type Coordinator struct {
+	cancel context.CancelFunc
+
+	mu       sync.Mutex
+	stopped  bool
+	stopping chan struct{}
+	inFlight sync.WaitGroup
+}
+
+type coordinatorKey struct{}
+
+// New returns a Coordinator and the application context derived from parent, which carries the
+// Coordinator and is canceled by Shutdown once the work in flight is drained.
+func New(parent context.Context) (*Coordinator, context.Context) {
+	ctx, cancel := context.WithCancel(parent)
+	c := &Coordinator{cancel: cancel, stopping: make(chan struct{})}
+	return c, context.WithValue(ctx, coordinatorKey{}, c)
+}
+
+// Stopping returns a channel closed when the shutdown of the Coordinator of ctx begins, or nil
+// (never closed) if ctx has no Coordinator.
+func Stopping(ctx context.Context) <-chan struct{} {
+	if c, ok := ctx.Value(coordinatorKey{}).(*Coordinator); ok {
+		return c.stopping
+	}
+	return nil
+}
+
+// Begin registers a unit of work in flight with the Coordinator of ctx, to be waited for on
+// shutdown; done must be called when it finishes. ok is false if the shutdown has begun, in
+// which case the work should not be started. Without a Coordinator, work is not tracked.
+func Begin(ctx context.Context) (done func(), ok bool) {
+	c, hasCoordinator := ctx.Value(coordinatorKey{}).(*Coordinator)
+	if !hasCoordinator {
+		return func() {}, true
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if c.stopped {
+		return nil, false
+	}
+	c.inFlight.Add(1)
+	return c.inFlight.Done, true
+}
+
+// Go runs task in a goroutine tracked by the Coordinator of ctx (see Begin), and reports
+// whether it was started.
+func Go(ctx context.Context, task func()) bool {
+	done, ok := Begin(ctx)
+	if !ok {
+		return false
+	}
+	go func() {
+		defer done()
+		task()
+	}()
+	return true
+}
+
+// Shutdown stops scheduled routines from starting new runs, waits up to timeout for the work
+// in flight to finish, then cancels the application context, cutting the work left. It reports
+// whether all the work finished in time.
+func (c *Coordinator) Shutdown(timeout time.Duration) bool {
+	c.mu.Lock()
+	if !c.stopped {
+		c.stopped = true
+		close(c.stopping)
+	}
+	c.mu.Unlock()
+	defer c.cancel()
+
+	drained := make(chan struct{})
+	go func() {
+		c.inFlight.Wait()
+		close(drained)
+	}()
+	timer := time.NewTimer(timeout)
+	defer timer.Stop()
+	select {
+	case <-drained:
+		return true
+	case <-timer.C:
+		return false
+	}
+}
@@ -0,0 +1,81 @@
+package shutdown
+
+import (
+	"context"
+	"testing"
+	"time"
+)
+
+func TestShutdownDrains(t *testing.T) {
+	c, ctx := New(context.Background())
+	release := make(chan struct{})
+	var finished bool
+	if !Go(ctx, func() {
+		<-release
+		finished = true
+	}) {
+		t.Fatal("expected the task to start before the shutdown")
+	}
+
+	select {
+	case <-Stopping(ctx):
+		t.Fatal("expected Stopping to block before the shutdown")
+	default:
+	}
+	go func() {
+		time.Sleep(20 * time.Millisecond)
+		close(release)
+	}()
+	if !c.Shutdown(time.Second) {
+		t.Fatal("expected the task to be drained")
+	}
+	if !finished {
+		t.Error("expected Shutdown to wait for the task")
+	}
+	if ctx.Err() == nil {
+		t.Error("expected the context to be canceled after the shutdown")
+	}
+	select {
+	case <-Stopping(ctx):
+	default:
+		t.Error("expected Stopping to be closed after the shutdown")
+	}
+	if _, ok := Begin(ctx); ok {
+		t.Error("expected no work to begin after the shutdown")
+	}
+	if Go(ctx, func() {}) {
+		t.Error("expected no task to start after the shutdown")
+	}
+}
+
+func TestShutdownTimeout(t *testing.T) {
+	c, ctx := New(context.Background())
+	done, ok := Begin(ctx)
+	if !ok {
+		t.Fatal("expected the work to begin before the shutdown")
+	}
+	defer done()
+
+	start := time.Now()
+	if c.Shutdown(30 * time.Millisecond) {
+		t.Error("expected the shutdown to time out")
+	}
+	if elapsed := time.Since(start); elapsed > time.Second {
+		t.Errorf("expected the shutdown to be bounded by its timeout, took %v", elapsed)
+	}
+	if ctx.Err() == nil {
+		t.Error("expected the context to be canceled when the timeout is reached")
+	}
+}
+
+func TestWithoutCoordinator(t *testing.T) {
+	ctx := context.Background()
+	if Stopping(ctx) != nil {
+		t.Error("expected no Stopping channel without a coordinator")
+	}
+	done, ok := Begin(ctx)
+	if !ok {
+		t.Fatal("expected work to begin without a coordinator")
+	}
+	done()
+}
@@ -0,0 +1,205 @@
+package telemetry
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log/slog"
+	"net/http"
+	"net/url"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"watchdog.onebusaway.org/internal/scheduler"
+)
+
+// Exporter publishes the watchdog's metrics and HTTP client spans to an OpenTelemetry
+// collector using OTLP over HTTP with JSON encoding.
+//
+// Metrics are not instrumented twice: on every export the Prometheus registry is gathered
+// and converted to OTLP (see convertMetricFamilies), so every existing gauge, counter and
+// histogram is published under its Prometheus name and labels.
+//
+// Spans are recorded by the RoundTripper returned from Transport and queued until the next export.
+type Exporter struct {
+	endpoint  string
+	headers   map[string]string
+	resource  otlpResource
+	gatherer  prometheus.Gatherer
+	client    *http.Client
+	startTime time.Time
+	logger    *slog.Logger
+
+	mu           sync.Mutex
+	spans        []otlpSpan
+	droppedSpans int
+}
+
+// NewExporter creates an exporter sending to the OTLP/HTTP endpoint of a collector
+// (e.g. "http://otel-collector:4318"); metrics are posted to <endpoint>/v1/metrics
+// and spans to <endpoint>/v1/traces.
+//
+// Parameters:
+//   - client: Client the exports are sent with, usually the API client of httpclient.Clients, so
+//     that they use the proxy, CA certificates and connection pool of the other requests.
+//   - endpoint: Base URL of the collector's OTLP/HTTP receiver.
+//   - headers: Extra headers sent with every export, e.g. for authentication (may be nil).
+//   - resource: Resource attributes identifying this watchdog (service.name, service.version, ...).
+//   - gatherer: Registry whose metrics are exported, usually prometheus.DefaultGatherer.
+//   - logger: Logger for export failures.
+//
+// Exports are never traced themselves, even when client goes through Transport.
+func NewExporter(client *http.Client, endpoint string, headers map[string]string, resource map[string]string, gatherer prometheus.Gatherer, logger *slog.Logger) *Exporter {
+	return &Exporter{
+		endpoint:  strings.TrimSuffix(endpoint, "/"),
+		headers:   headers,
+		resource:  otlpResource{Attributes: attributesFromMap(resource)},
+		gatherer:  gatherer,
+		client:    client,
+		startTime: time.Now(),
+		logger:    logger,
+	}
+}
+
+// Transport wraps next so that every request made through it emits a client span.
+func (e *Exporter) Transport(next http.RoundTripper) http.RoundTripper {
+	if next == nil {
+		next = http.DefaultTransport
+	}
+	return &tracingRoundTripper{next: next, exporter: e}
+}
+
+func (e *Exporter) queueSpan(span otlpSpan) {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	if len(e.spans) >= maxQueuedSpans {
+		e.droppedSpans++
+		return
+	}
+	e.spans = append(e.spans, span)
+}
+
+// Run exports metrics and spans at every interval until ctx is canceled,
+// then flushes once more so the last collection cycle is not lost.
+func (e *Exporter) Run(ctx context.Context, interval time.Duration) {
+	scheduler.Run(ctx, "telemetry_export", scheduler.Every(interval), func() {
+		e.exportAndLog(ctx)
+	})
+
+	flushCtx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	e.exportAndLog(flushCtx)
+}
+
+func (e *Exporter) exportAndLog(ctx context.Context) {
+	if err := e.Export(ctx); err != nil {
+		e.logger.Error("Failed to export OpenTelemetry data", "endpoint", e.endpoint, "error", err)
+	}
+}
+
+// Export sends the current metrics and all queued spans to the collector.
+// Spans that fail to export are dropped; metrics are cumulative, so the next export catches up.
+func (e *Exporter) Export(ctx context.Context) error {
+	var errs []error
+	if err := e.exportMetrics(ctx); err != nil {
+		errs = append(errs, err)
+	}
+	if err := e.exportSpans(ctx); err != nil {
+		errs = append(errs, err)
+	}
+	return errors.Join(errs...)
+}
+
+func (e *Exporter) exportMetrics(ctx context.Context) error {
+	families, err := e.gatherer.Gather()
+	if err != nil {
+		// Gather returns whatever it could collect alongside the error.
+		e.logger.Warn("Failed to gather some metrics for OpenTelemetry export", "error", err)
+	}
+	metrics := convertMetricFamilies(families, e.startTime, time.Now())
+	if len(metrics) == 0 {
+		return nil
+	}
+
+	return e.post(ctx, "/v1/metrics", otlpMetricsRequest{
+		ResourceMetrics: []otlpResourceMetrics{{
+			Resource:     e.resource,
+			ScopeMetrics: []otlpScopeMetrics{{Scope: otlpScope{Name: scopeName}, Metrics: metrics}},
+		}},
+	})
+}
+
+func (e *Exporter) exportSpans(ctx context.Context) error {
+	e.mu.Lock()
+	spans, dropped := e.spans, e.droppedSpans
+	e.spans, e.droppedSpans = nil, 0
+	e.mu.Unlock()
+
+	if dropped > 0 {
+		e.logger.Warn("Dropped OpenTelemetry spans, the span queue was full", "dropped", dropped)
+	}
+	if len(spans) == 0 {
+		return nil
+	}
+
+	return e.post(ctx, "/v1/traces", otlpTracesRequest{
+		ResourceSpans: []otlpResourceSpans{{
+			Resource:   e.resource,
+			ScopeSpans: []otlpScopeSpans{{Scope: otlpScope{Name: scopeName}, Spans: spans}},
+		}},
+	})
+}
+
+func (e *Exporter) post(ctx context.Context, path string, payload any) error {
+	body, err := json.Marshal(payload)
+	if err != nil {
+		return fmt.Errorf("failed to encode OTLP payload for %s: %w", path, err)
+	}
+	req, err := http.NewRequestWithContext(withoutTracing(ctx), http.MethodPost, e.endpoint+path, bytes.NewReader(body))
+	if err != nil {
+		return fmt.Errorf("failed to create OTLP request for %s: %w", path, err)
+	}
+	req.Header.Set("Content-Type", "application/json")
+	for k, v := range e.headers {
+		req.Header.Set(k, v)
+	}
+
+	resp, err := e.client.Do(req)
+	if err != nil {
+		return fmt.Errorf("failed to send %s: %w", path, err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		msg, _ := io.ReadAll(io.LimitReader(resp.Body, 1024))
+		return fmt.Errorf("collector rejected %s with status %d: %s", path, resp.StatusCode, strings.TrimSpace(string(msg)))
+	}
+	return nil
+}
+
+// ParseHeaders parses headers in the format of the standard OTEL_EXPORTER_OTLP_HEADERS
+// environment variable: comma-separated key=value pairs with URL-encoded values,
+// e.g. "Authorization=Bearer%20token,X-Scope-OrgID=transit".
+func ParseHeaders(s string) (map[string]string, error) {
+	headers := make(map[string]string)
+	for _, pair := range strings.Split(s, ",") {
+		pair = strings.TrimSpace(pair)
+		if pair == "" {
+			continue
+		}
+		key, value, ok := strings.Cut(pair, "=")
+		if !ok || strings.TrimSpace(key) == "" {
+			return nil, fmt.Errorf("invalid OTLP header %q: expected key=value", pair)
+		}
+		decoded, err := url.PathUnescape(strings.TrimSpace(value))
+		if err != nil {
+			return nil, fmt.Errorf("invalid OTLP header value for %q: %w", key, err)
+		}
+		headers[strings.TrimSpace(key)] = decoded
+	}
+	return headers, nil
+}
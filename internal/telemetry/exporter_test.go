@@ -0,0 +1,184 @@
+package telemetry
+
+import (
+	"context"
+	"encoding/json"
+	"io"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+// collector is a fake OTLP/HTTP receiver that records the decoded payloads it receives.
+type collector struct {
+	mu      sync.Mutex
+	metrics []otlpMetricsRequest
+	traces  []otlpTracesRequest
+	headers http.Header
+}
+
+func newCollector(t *testing.T) (*collector, *httptest.Server) {
+	t.Helper()
+	c := &collector{}
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		c.mu.Lock()
+		defer c.mu.Unlock()
+		c.headers = r.Header.Clone()
+		var err error
+		switch r.URL.Path {
+		case "/v1/metrics":
+			var req otlpMetricsRequest
+			err = json.NewDecoder(r.Body).Decode(&req)
+			c.metrics = append(c.metrics, req)
+		case "/v1/traces":
+			var req otlpTracesRequest
+			err = json.NewDecoder(r.Body).Decode(&req)
+			c.traces = append(c.traces, req)
+		default:
+			w.WriteHeader(http.StatusNotFound)
+			return
+		}
+		if err != nil {
+			t.Errorf("failed to decode %s payload: %v", r.URL.Path, err)
+		}
+	}))
+	t.Cleanup(server.Close)
+	return c, server
+}
+
+func TestExportMetrics(t *testing.T) {
+	registry := prometheus.NewRegistry()
+	gauge := prometheus.NewGaugeVec(prometheus.GaugeOpts{Name: "oba_api_status", Help: "status"}, []string{"server_id"})
+	counter := prometheus.NewCounter(prometheus.CounterOpts{Name: "vehicle_report_total", Help: "reports"})
+	histogram := prometheus.NewHistogram(prometheus.HistogramOpts{Name: "latency_seconds", Help: "latency", Buckets: []float64{0.1, 1}})
+	registry.MustRegister(gauge, counter, histogram)
+	gauge.WithLabelValues("1").Set(1)
+	counter.Add(3)
+	histogram.Observe(0.05)
+	histogram.Observe(0.5)
+	histogram.Observe(5)
+
+	c, server := newCollector(t)
+	logger := slog.New(slog.NewTextHandler(io.Discard, nil))
+	exporter := NewExporter(&http.Client{Timeout: time.Second}, server.URL, map[string]string{"Authorization": "Bearer token"}, map[string]string{"service.name": "watchdog"}, registry, logger)
+
+	if err := exporter.Export(context.Background()); err != nil {
+		t.Fatalf("Export failed: %v", err)
+	}
+	if got := c.headers.Get("Authorization"); got != "Bearer token" {
+		t.Errorf("expected the configured header to be sent, got %q", got)
+	}
+	if len(c.metrics) != 1 || len(c.traces) != 0 {
+		t.Fatalf("expected one metrics export and no traces, got %d and %d", len(c.metrics), len(c.traces))
+	}
+
+	resource := c.metrics[0].ResourceMetrics[0]
+	if attr := resource.Resource.Attributes; len(attr) != 1 || attr[0].Key != "service.name" || *attr[0].Value.StringValue != "watchdog" {
+		t.Errorf("unexpected resource attributes: %+v", attr)
+	}
+	byName := make(map[string]otlpMetric)
+	for _, m := range resource.ScopeMetrics[0].Metrics {
+		byName[m.Name] = m
+	}
+
+	status := byName["oba_api_status"]
+	if status.Gauge == nil || len(status.Gauge.DataPoints) != 1 || status.Gauge.DataPoints[0].AsDouble != 1 {
+		t.Errorf("unexpected gauge: %+v", status)
+	} else if attr := status.Gauge.DataPoints[0].Attributes; len(attr) != 1 || attr[0].Key != "server_id" {
+		t.Errorf("expected the server_id label as an attribute, got %+v", attr)
+	}
+
+	reports := byName["vehicle_report_total"]
+	if reports.Sum == nil || !reports.Sum.IsMonotonic || reports.Sum.AggregationTemporality != aggregationTemporalityCumulative || reports.Sum.DataPoints[0].AsDouble != 3 {
+		t.Errorf("unexpected counter: %+v", reports)
+	}
+
+	latency := byName["latency_seconds"]
+	if latency.Histogram == nil {
+		t.Fatalf("expected a histogram, got %+v", latency)
+	}
+	point := latency.Histogram.DataPoints[0]
+	if point.Count != "3" || strings.Join(point.BucketCounts, ",") != "1,1,1" || len(point.ExplicitBounds) != 2 {
+		t.Errorf("unexpected histogram data point: %+v", point)
+	}
+}
+
+func TestTransportRecordsSpans(t *testing.T) {
+	var traceparent string
+	target := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		traceparent = r.Header.Get("traceparent")
+		if r.URL.Path == "/missing" {
+			w.WriteHeader(http.StatusNotFound)
+		}
+	}))
+	defer target.Close()
+
+	c, server := newCollector(t)
+	logger := slog.New(slog.NewTextHandler(io.Discard, nil))
+	// The exports are sent with the traced client, as in the watchdog.
+	client := &http.Client{Timeout: time.Second}
+	exporter := NewExporter(client, server.URL, nil, nil, prometheus.NewRegistry(), logger)
+	client.Transport = exporter.Transport(http.DefaultTransport)
+
+	for _, path := range []string{"/api/where/current-time.json?key=secret", "/missing"} {
+		resp, err := client.Get(target.URL + path)
+		if err != nil {
+			t.Fatalf("GET %s failed: %v", path, err)
+		}
+		resp.Body.Close()
+	}
+	if traceID, spanID := parseTraceparent(traceparent); traceID == "" || spanID == "" {
+		t.Errorf("expected a valid traceparent header, got %q", traceparent)
+	}
+
+	if err := exporter.Export(context.Background()); err != nil {
+		t.Fatalf("Export failed: %v", err)
+	}
+	if len(c.traces) != 1 {
+		t.Fatalf("expected one traces export, got %d", len(c.traces))
+	}
+	spans := c.traces[0].ResourceSpans[0].ScopeSpans[0].Spans
+	if len(spans) != 2 {
+		t.Fatalf("expected 2 spans, got %d", len(spans))
+	}
+
+	for _, attr := range spans[0].Attributes {
+		if attr.Key == "url.full" && strings.Contains(*attr.Value.StringValue, "secret") {
+			t.Errorf("url.full must not contain the query string, got %q", *attr.Value.StringValue)
+		}
+	}
+	if spans[0].Kind != spanKindClient || spans[0].Name != http.MethodGet || spans[0].Status.Code != statusCodeOK {
+		t.Errorf("unexpected first span: %+v", spans[0])
+	}
+	if spans[1].Status.Code != statusCodeError {
+		t.Errorf("expected a 404 to mark the span as an error, got %+v", spans[1].Status)
+	}
+
+	// The queue is emptied by a successful export, which is not traced itself.
+	if err := exporter.Export(context.Background()); err != nil {
+		t.Fatalf("second Export failed: %v", err)
+	}
+	if len(c.traces) != 1 {
+		t.Errorf("expected no new traces export without new spans, got %d", len(c.traces))
+	}
+}
+
+func TestParseHeaders(t *testing.T) {
+	headers, err := ParseHeaders("Authorization=Bearer%20a+b, X-Scope-OrgID=transit,")
+	if err != nil {
+		t.Fatalf("ParseHeaders failed: %v", err)
+	}
+	if headers["Authorization"] != "Bearer a+b" || headers["X-Scope-OrgID"] != "transit" || len(headers) != 2 {
+		t.Errorf("unexpected headers: %v", headers)
+	}
+
+	if _, err := ParseHeaders("missing-separator"); err == nil {
+		t.Error("expected an error for a header without '='")
+	}
+}
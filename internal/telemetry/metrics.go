@@ -0,0 +1,134 @@
+package telemetry
+
+import (
+	"math"
+	"strconv"
+	"time"
+
+	dto "github.com/prometheus/client_model/go"
+)
+
+// convertMetricFamilies converts gathered Prometheus metric families to OTLP metrics.
+//
+// Gauges and untyped metrics become OTLP gauges, counters become cumulative monotonic sums,
+// and histograms and summaries keep their type. Prometheus histogram buckets are cumulative
+// while OTLP bucket counts are per bucket, so counts are de-accumulated, with the implicit
+// +Inf bucket appended last. Labels become data point attributes.
+//
+// Samples that are NaN or ±Inf cannot be encoded in JSON and are skipped.
+// startTime is the start of the cumulative series, i.e. when the watchdog started.
+func convertMetricFamilies(families []*dto.MetricFamily, startTime, now time.Time) []otlpMetric {
+	start, timestamp := unixNano(startTime), unixNano(now)
+	metrics := make([]otlpMetric, 0, len(families))
+
+	for _, family := range families {
+		metric := otlpMetric{Name: family.GetName(), Description: family.GetHelp()}
+
+		switch family.GetType() {
+		case dto.MetricType_COUNTER:
+			sum := &otlpSum{AggregationTemporality: aggregationTemporalityCumulative, IsMonotonic: true}
+			for _, m := range family.GetMetric() {
+				if value := m.GetCounter().GetValue(); finite(value) {
+					sum.DataPoints = append(sum.DataPoints, otlpNumberDataPoint{
+						Attributes:        labelAttributes(m),
+						StartTimeUnixNano: start,
+						TimeUnixNano:      timestamp,
+						AsDouble:          value,
+					})
+				}
+			}
+			metric.Sum = sum
+
+		case dto.MetricType_GAUGE, dto.MetricType_UNTYPED:
+			gauge := &otlpGauge{}
+			for _, m := range family.GetMetric() {
+				value := m.GetGauge().GetValue()
+				if family.GetType() == dto.MetricType_UNTYPED {
+					value = m.GetUntyped().GetValue()
+				}
+				if finite(value) {
+					gauge.DataPoints = append(gauge.DataPoints, otlpNumberDataPoint{
+						Attributes:   labelAttributes(m),
+						TimeUnixNano: timestamp,
+						AsDouble:     value,
+					})
+				}
+			}
+			metric.Gauge = gauge
+
+		case dto.MetricType_HISTOGRAM, dto.MetricType_GAUGE_HISTOGRAM:
+			histogram := &otlpHistogram{AggregationTemporality: aggregationTemporalityCumulative}
+			for _, m := range family.GetMetric() {
+				h := m.GetHistogram()
+				if !finite(h.GetSampleSum()) {
+					continue
+				}
+				point := otlpHistogramDataPoint{
+					Attributes:        labelAttributes(m),
+					StartTimeUnixNano: start,
+					TimeUnixNano:      timestamp,
+					Count:             strconv.FormatUint(h.GetSampleCount(), 10),
+					Sum:               h.GetSampleSum(),
+					BucketCounts:      []string{},
+					ExplicitBounds:    []float64{},
+				}
+				var previous uint64
+				for _, bucket := range h.GetBucket() {
+					if math.IsInf(bucket.GetUpperBound(), +1) {
+						continue
+					}
+					point.ExplicitBounds = append(point.ExplicitBounds, bucket.GetUpperBound())
+					point.BucketCounts = append(point.BucketCounts, strconv.FormatUint(bucket.GetCumulativeCount()-previous, 10))
+					previous = bucket.GetCumulativeCount()
+				}
+				point.BucketCounts = append(point.BucketCounts, strconv.FormatUint(h.GetSampleCount()-previous, 10))
+				histogram.DataPoints = append(histogram.DataPoints, point)
+			}
+			metric.Histogram = histogram
+
+		case dto.MetricType_SUMMARY:
+			summary := &otlpSummary{}
+			for _, m := range family.GetMetric() {
+				s := m.GetSummary()
+				if !finite(s.GetSampleSum()) {
+					continue
+				}
+				point := otlpSummaryDataPoint{
+					Attributes:        labelAttributes(m),
+					StartTimeUnixNano: start,
+					TimeUnixNano:      timestamp,
+					Count:             strconv.FormatUint(s.GetSampleCount(), 10),
+					Sum:               s.GetSampleSum(),
+					QuantileValues:    []otlpQuantile{},
+				}
+				for _, q := range s.GetQuantile() {
+					if finite(q.GetValue()) {
+						point.QuantileValues = append(point.QuantileValues, otlpQuantile{Quantile: q.GetQuantile(), Value: q.GetValue()})
+					}
+				}
+				summary.DataPoints = append(summary.DataPoints, point)
+			}
+			metric.Summary = summary
+
+		default:
+			continue
+		}
+		metrics = append(metrics, metric)
+	}
+	return metrics
+}
+
+func labelAttributes(m *dto.Metric) []otlpKeyValue {
+	if len(m.GetLabel()) == 0 {
+		return nil
+	}
+	attributes := make([]otlpKeyValue, 0, len(m.GetLabel()))
+	for _, label := range m.GetLabel() {
+		attributes = append(attributes, stringAttribute(label.GetName(), label.GetValue()))
+	}
+	return attributes
+}
+
+func finite(v float64) bool {
+	return !math.IsNaN(v) && !math.IsInf(v, 0)
+}
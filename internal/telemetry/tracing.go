@@ -0,0 +1,116 @@
+package telemetry
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"fmt"
+	"net/http"
+	"strings"
+	"time"
+)
+
+// maxQueuedSpans bounds the number of finished spans kept between two exports.
+// If the collector is unreachable, newer spans are dropped instead of growing memory.
+const maxQueuedSpans = 4096
+
+// tracingRoundTripper is an HTTP RoundTripper that records a client span for every outgoing request
+// (OBA API calls, GTFS bundle and GTFS-RT downloads, remote config) and queues it on the exporter.
+//
+// Spans follow the OpenTelemetry HTTP client semantic conventions. The URL is recorded without
+// its query string because OBA API keys are passed as `?key=` parameters.
+//
+// A W3C `traceparent` header is added to each request so that instrumented servers can join the trace.
+// If the request already carries one, the span becomes its child.
+type tracingRoundTripper struct {
+	next     http.RoundTripper
+	exporter *Exporter
+}
+
+func (rt *tracingRoundTripper) RoundTrip(req *http.Request) (*http.Response, error) {
+	if req.Context().Value(untracedKey{}) != nil {
+		return rt.next.RoundTrip(req)
+	}
+	traceID, parentSpanID := parseTraceparent(req.Header.Get("traceparent"))
+	if traceID == "" {
+		traceID = randomHex(16)
+	}
+	spanID := randomHex(8)
+
+	// A RoundTripper must not modify the caller's request.
+	req = req.Clone(req.Context())
+	req.Header.Set("traceparent", fmt.Sprintf("00-%s-%s-01", traceID, spanID))
+
+	start := time.Now()
+	resp, err := rt.next.RoundTrip(req)
+	end := time.Now()
+
+	attributes := []otlpKeyValue{
+		stringAttribute("http.request.method", req.Method),
+		stringAttribute("url.full", req.URL.Scheme+"://"+req.URL.Host+req.URL.Path),
+		stringAttribute("server.address", req.URL.Hostname()),
+	}
+	if port := req.URL.Port(); port != "" {
+		attributes = append(attributes, stringAttribute("server.port", port))
+	}
+
+	status := otlpStatus{Code: statusCodeOK}
+	switch {
+	case err != nil:
+		status = otlpStatus{Code: statusCodeError, Message: err.Error()}
+		attributes = append(attributes, stringAttribute("error.type", fmt.Sprintf("%T", err)))
+	case resp.StatusCode >= 400:
+		status = otlpStatus{Code: statusCodeError}
+		attributes = append(attributes,
+			intAttribute("http.response.status_code", int64(resp.StatusCode)),
+			stringAttribute("error.type", fmt.Sprintf("%d", resp.StatusCode)),
+		)
+	default:
+		attributes = append(attributes, intAttribute("http.response.status_code", int64(resp.StatusCode)))
+	}
+
+	rt.exporter.queueSpan(otlpSpan{
+		TraceID:           traceID,
+		SpanID:            spanID,
+		ParentSpanID:      parentSpanID,
+		Name:              req.Method,
+		Kind:              spanKindClient,
+		StartTimeUnixNano: unixNano(start),
+		EndTimeUnixNano:   unixNano(end),
+		Attributes:        attributes,
+		Status:            status,
+	})
+	return resp, err
+}
+
+// untracedKey marks the context of requests that must not be traced.
+type untracedKey struct{}
+
+// withoutTracing returns a context whose requests are not traced, e.g. for the exports of the
+// spans, which would otherwise queue a span per export.
+func withoutTracing(ctx context.Context) context.Context {
+	return context.WithValue(ctx, untracedKey{}, true)
+}
+
+// parseTraceparent extracts the trace ID and parent span ID from a W3C traceparent header
+// ("00-<32 hex trace id>-<16 hex span id>-<flags>"). It returns empty strings if the header is invalid.
+func parseTraceparent(header string) (traceID, spanID string) {
+	parts := strings.Split(header, "-")
+	if len(parts) != 4 || len(parts[1]) != 32 || len(parts[2]) != 16 {
+		return "", ""
+	}
+	if _, err := hex.DecodeString(parts[1]); err != nil {
+		return "", ""
+	}
+	if _, err := hex.DecodeString(parts[2]); err != nil {
+		return "", ""
+	}
+	return parts[1], parts[2]
+}
+
+func randomHex(n int) string {
+	b := make([]byte, n)
+	// crypto/rand.Read never returns an error on supported platforms.
+	_, _ = rand.Read(b)
+	return hex.EncodeToString(b)
+}
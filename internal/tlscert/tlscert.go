@@ -0,0 +1,101 @@
+// Package tlscert provides the TLS certificate of the embedded HTTP server, so it can serve
+// HTTPS without a reverse proxy, and reloads it when its files are rotated (e.g. by
+// cert-manager or certbot) without restarting the watchdog.
+package tlscert
+
+import (
+	"context"
+	"crypto/tls"
+	"fmt"
+	"log/slog"
+	"os"
+	"sync"
+	"time"
+
+	"watchdog.onebusaway.org/internal/scheduler"
+)
+
+// Reloader holds the certificate of a PEM certificate and key file pair.
+type Reloader struct {
+	certFile string
+	keyFile  string
+
+	mu   sync.RWMutex
+	cert *tls.Certificate
+	// modTimes are the modification times of the files when they were loaded, to only reload
+	// them when they change.
+	certModTime time.Time
+	keyModTime  time.Time
+}
+
+// New loads the certificate of certFile (which may include intermediate certificates) and its
+// private key keyFile.
+func New(certFile, keyFile string) (*Reloader, error) {
+	r := &Reloader{certFile: certFile, keyFile: keyFile}
+	if _, err := r.Reload(); err != nil {
+		return nil, err
+	}
+	return r, nil
+}
+
+// Reload loads the certificate again if one of its files was modified since it was loaded,
+// and reports whether it did. On error, the previous certificate is kept.
+func (r *Reloader) Reload() (bool, error) {
+	certInfo, err := os.Stat(r.certFile)
+	if err != nil {
+		return false, fmt.Errorf("failed to read TLS certificate: %w", err)
+	}
+	keyInfo, err := os.Stat(r.keyFile)
+	if err != nil {
+		return false, fmt.Errorf("failed to read TLS key: %w", err)
+	}
+
+	r.mu.RLock()
+	unchanged := r.cert != nil && certInfo.ModTime().Equal(r.certModTime) && keyInfo.ModTime().Equal(r.keyModTime)
+	r.mu.RUnlock()
+	if unchanged {
+		return false, nil
+	}
+
+	cert, err := tls.LoadX509KeyPair(r.certFile, r.keyFile)
+	if err != nil {
+		return false, fmt.Errorf("failed to load TLS certificate: %w", err)
+	}
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.cert = &cert
+	r.certModTime, r.keyModTime = certInfo.ModTime(), keyInfo.ModTime()
+	return true, nil
+}
+
+// GetCertificate returns the current certificate, for tls.Config.GetCertificate.
+func (r *Reloader) GetCertificate(*tls.ClientHelloInfo) (*tls.Certificate, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	return r.cert, nil
+}
+
+// TLSConfig returns the TLS configuration of a server presenting the current certificate.
+// HTTP/2 is negotiated by http.Server.ServeTLS.
+func (r *Reloader) TLSConfig() *tls.Config {
+	return &tls.Config{
+		MinVersion:     tls.VersionTLS12,
+		GetCertificate: r.GetCertificate,
+	}
+}
+
+// Watch reloads the certificate at every activation of schedule, until ctx is canceled.
+// Failed reloads are logged, and the previous certificate is kept, so that a rotation writing
+// the certificate before the key does not break the server.
+func (r *Reloader) Watch(ctx context.Context, schedule scheduler.Schedule, logger *slog.Logger) {
+	scheduler.Run(ctx, "tls_certificate_reload", schedule, func() {
+		reloaded, err := r.Reload()
+		if err != nil {
+			logger.Warn("Failed to reload TLS certificate, keeping the previous one", "cert_file", r.certFile, "error", err)
+			return
+		}
+		if reloaded {
+			logger.Info("Reloaded TLS certificate", "cert_file", r.certFile)
+		}
+	})
+}
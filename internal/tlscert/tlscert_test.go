@@ -0,0 +1,106 @@
+package tlscert
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/pem"
+	"math/big"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+// writeCert writes a self-signed certificate for commonName and its key to certFile and
+// keyFile, with the given modification time.
+func writeCert(t *testing.T, certFile, keyFile, commonName string, modTime time.Time) {
+	t.Helper()
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	template := &x509.Certificate{
+		SerialNumber: big.NewInt(1),
+		Subject:      pkix.Name{CommonName: commonName},
+		NotBefore:    time.Now().Add(-time.Hour),
+		NotAfter:     time.Now().Add(time.Hour),
+	}
+	der, err := x509.CreateCertificate(rand.Reader, template, template, &key.PublicKey, key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	keyDER, err := x509.MarshalECPrivateKey(key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(certFile, pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: der}), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(keyFile, pem.EncodeToMemory(&pem.Block{Type: "EC PRIVATE KEY", Bytes: keyDER}), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	for _, file := range []string{certFile, keyFile} {
+		if err := os.Chtimes(file, modTime, modTime); err != nil {
+			t.Fatal(err)
+		}
+	}
+}
+
+func commonName(t *testing.T, r *Reloader) string {
+	t.Helper()
+	cert, err := r.GetCertificate(&tls.ClientHelloInfo{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	leaf, err := x509.ParseCertificate(cert.Certificate[0])
+	if err != nil {
+		t.Fatal(err)
+	}
+	return leaf.Subject.CommonName
+}
+
+func TestReloader(t *testing.T) {
+	dir := t.TempDir()
+	certFile, keyFile := filepath.Join(dir, "tls.crt"), filepath.Join(dir, "tls.key")
+	start := time.Now().Add(-time.Hour)
+	writeCert(t, certFile, keyFile, "first", start)
+
+	r, err := New(certFile, keyFile)
+	if err != nil {
+		t.Fatalf("New failed: %v", err)
+	}
+	if name := commonName(t, r); name != "first" {
+		t.Errorf("expected the first certificate, got %q", name)
+	}
+	if reloaded, err := r.Reload(); reloaded || err != nil {
+		t.Errorf("expected no reload of unchanged files, got %v, %v", reloaded, err)
+	}
+
+	// A rotation that has written the certificate but not the key yet keeps the previous one.
+	writeCert(t, certFile, filepath.Join(dir, "next.key"), "second", start.Add(time.Minute))
+	if reloaded, err := r.Reload(); reloaded || err == nil {
+		t.Errorf("expected a failed reload of a mismatched key pair, got %v, %v", reloaded, err)
+	}
+	if name := commonName(t, r); name != "first" {
+		t.Errorf("expected the first certificate to be kept, got %q", name)
+	}
+
+	writeCert(t, certFile, keyFile, "second", start.Add(2*time.Minute))
+	if reloaded, err := r.Reload(); !reloaded || err != nil {
+		t.Errorf("expected the rotated certificate to be reloaded, got %v, %v", reloaded, err)
+	}
+	if name := commonName(t, r); name != "second" {
+		t.Errorf("expected the second certificate, got %q", name)
+	}
+}
+
+func TestNewInvalid(t *testing.T) {
+	dir := t.TempDir()
+	if _, err := New(filepath.Join(dir, "missing.crt"), filepath.Join(dir, "missing.key")); err == nil {
+		t.Error("expected an error for missing files")
+	}
+}
@@ -0,0 +1,204 @@
+// Package ttlcache provides small in-memory caches whose entries expire after a time to live,
+// for the subsystems that keep the results of remote lookups for a while (access tokens,
+// responses of OBA servers, validators of remote configurations, ...). A cache holds a bounded
+// number of entries, and its hits, misses, evictions and size are exported as Prometheus
+// metrics labelled with the name of the cache.
+package ttlcache
+
+import (
+	"sync"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promauto"
+)
+
+var (
+	// Lookups counts the lookups of the caches, by result (hit, miss).
+	Lookups = promauto.NewCounterVec(
+		prometheus.CounterOpts{
+			Name: "watchdog_cache_lookups_total",
+			Help: "Lookups of the internal caches, by cache and result (hit, miss)",
+		},
+		[]string{"cache", "result"},
+	)
+
+	// Evictions counts the entries dropped from the caches, by reason (expired, capacity).
+	Evictions = promauto.NewCounterVec(
+		prometheus.CounterOpts{
+			Name: "watchdog_cache_evictions_total",
+			Help: "Entries dropped from the internal caches, by cache and reason (expired, capacity)",
+		},
+		[]string{"cache", "reason"},
+	)
+
+	// Entries is the number of entries held by the caches, expired entries not yet dropped
+	// included.
+	Entries = promauto.NewGaugeVec(
+		prometheus.GaugeOpts{
+			Name: "watchdog_cache_entries",
+			Help: "Number of entries held by the internal caches, by cache",
+		},
+		[]string{"cache"},
+	)
+)
+
+// entry is a cached value and when it expires.
+type entry[V any] struct {
+	value     V
+	expiresAt time.Time
+}
+
+// Cache is a cache of values of type V by key K, holding at most a fixed number of entries that
+// each expire after a time to live. It is safe for concurrent use. A nil Cache caches nothing:
+// every lookup is a miss.
+//
+// Expired entries are dropped when they are looked up, or when room is needed for a new entry;
+// when the cache is full of unexpired entries, the one closest to expiring is dropped. Finding
+// it takes a pass over the entries, which is meant for the small caches of the watchdog rather
+// than for caches of thousands of entries.
+type Cache[K comparable, V any] struct {
+	name       string
+	maxEntries int
+	ttl        time.Duration
+	now        func() time.Time
+
+	mu      sync.Mutex
+	entries map[K]entry[V]
+}
+
+// New returns an empty cache, named name in the metrics, of at most maxEntries entries (0 =
+// unbounded) that expire ttl after they are set (0 = never, unless set with another TTL).
+func New[K comparable, V any](name string, maxEntries int, ttl time.Duration) *Cache[K, V] {
+	Entries.WithLabelValues(name).Set(0)
+	return &Cache[K, V]{name: name, maxEntries: maxEntries, ttl: ttl, now: time.Now, entries: make(map[K]entry[V])}
+}
+
+// Get returns the value cached for key, and whether there is one that has not expired.
+func (c *Cache[K, V]) Get(key K) (V, bool) {
+	var zero V
+	if c == nil {
+		return zero, false
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	e, ok := c.entries[key]
+	if ok && c.expired(e, c.now()) {
+		c.remove(key, "expired")
+		ok = false
+	}
+	if !ok {
+		Lookups.WithLabelValues(c.name, "miss").Inc()
+		return zero, false
+	}
+	Lookups.WithLabelValues(c.name, "hit").Inc()
+	return e.value, true
+}
+
+// Set caches value for key with the TTL of the cache.
+func (c *Cache[K, V]) Set(key K, value V) {
+	if c == nil {
+		return
+	}
+	c.SetWithTTL(key, value, c.ttl)
+}
+
+// SetWithTTL caches value for key until ttl from now (0 = never expires), e.g. for values that
+// come with their own lifetime such as access tokens. A ttl below 0 removes the key instead.
+func (c *Cache[K, V]) SetWithTTL(key K, value V, ttl time.Duration) {
+	if c == nil {
+		return
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if ttl < 0 {
+		if _, ok := c.entries[key]; ok {
+			c.remove(key, "expired")
+		}
+		return
+	}
+	now := c.now()
+	if _, ok := c.entries[key]; !ok && c.maxEntries > 0 && len(c.entries) >= c.maxEntries {
+		c.makeRoom(now)
+	}
+	e := entry[V]{value: value}
+	if ttl > 0 {
+		e.expiresAt = now.Add(ttl)
+	}
+	c.entries[key] = e
+	Entries.WithLabelValues(c.name).Set(float64(len(c.entries)))
+}
+
+// GetOrLoad returns the value cached for key, or else the value returned by load, which is
+// cached for the TTL it returns (see SetWithTTL). Errors of load are returned and not cached.
+//
+// The cache is not locked while load runs, so concurrent misses of the same key may all load
+// it; callers for which that matters serialize the calls themselves.
+func (c *Cache[K, V]) GetOrLoad(key K, load func() (V, time.Duration, error)) (V, error) {
+	if value, ok := c.Get(key); ok {
+		return value, nil
+	}
+	value, ttl, err := load()
+	if err != nil {
+		return value, err
+	}
+	c.SetWithTTL(key, value, ttl)
+	return value, nil
+}
+
+// Delete removes the value cached for key, if any.
+func (c *Cache[K, V]) Delete(key K) {
+	if c == nil {
+		return
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	delete(c.entries, key)
+	Entries.WithLabelValues(c.name).Set(float64(len(c.entries)))
+}
+
+// Len returns the number of entries of the cache, expired entries not yet dropped included.
+func (c *Cache[K, V]) Len() int {
+	if c == nil {
+		return 0
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return len(c.entries)
+}
+
+// expired reports whether e expired at now.
+func (c *Cache[K, V]) expired(e entry[V], now time.Time) bool {
+	return !e.expiresAt.IsZero() && !now.Before(e.expiresAt)
+}
+
+// makeRoom drops the expired entries, or else the entry closest to expiring (entries that
+// never expire last). c.mu must be held.
+func (c *Cache[K, V]) makeRoom(now time.Time) {
+	var (
+		victim    K
+		victimAt  time.Time
+		hasVictim bool
+		dropped   bool
+	)
+	for key, e := range c.entries {
+		if c.expired(e, now) {
+			c.remove(key, "expired")
+			dropped = true
+			continue
+		}
+		if !hasVictim || (!e.expiresAt.IsZero() && (victimAt.IsZero() || e.expiresAt.Before(victimAt))) {
+			victim, victimAt, hasVictim = key, e.expiresAt, true
+		}
+	}
+	if !dropped && hasVictim {
+		c.remove(victim, "capacity")
+	}
+}
+
+// remove drops the entry of key for reason. c.mu must be held.
+func (c *Cache[K, V]) remove(key K, reason string) {
+	delete(c.entries, key)
+	Evictions.WithLabelValues(c.name, reason).Inc()
+	Entries.WithLabelValues(c.name).Set(float64(len(c.entries)))
+}
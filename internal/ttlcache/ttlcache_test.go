@@ -0,0 +1,110 @@
+package ttlcache
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus/testutil"
+)
+
+func TestCacheExpiry(t *testing.T) {
+	now := time.Date(2025, 1, 1, 12, 0, 0, 0, time.UTC)
+	c := New[string, int]("test_expiry", 0, time.Minute)
+	c.now = func() time.Time { return now }
+
+	c.Set("a", 1)
+	c.SetWithTTL("b", 2, 0)
+	if value, ok := c.Get("a"); !ok || value != 1 {
+		t.Fatalf("expected a cached value, got %v %v", value, ok)
+	}
+
+	now = now.Add(time.Minute)
+	if _, ok := c.Get("a"); ok {
+		t.Error("expected the entry to expire after its TTL")
+	}
+	if _, ok := c.Get("b"); !ok {
+		t.Error("expected an entry without a TTL not to expire")
+	}
+	if c.Len() != 1 {
+		t.Errorf("expected the expired entry to be dropped, got %d entries", c.Len())
+	}
+
+	if hits := testutil.ToFloat64(Lookups.WithLabelValues("test_expiry", "hit")); hits != 2 {
+		t.Errorf("expected 2 hits, got %v", hits)
+	}
+	if misses := testutil.ToFloat64(Lookups.WithLabelValues("test_expiry", "miss")); misses != 1 {
+		t.Errorf("expected 1 miss, got %v", misses)
+	}
+	if expired := testutil.ToFloat64(Evictions.WithLabelValues("test_expiry", "expired")); expired != 1 {
+		t.Errorf("expected 1 expired entry, got %v", expired)
+	}
+}
+
+func TestCacheCapacity(t *testing.T) {
+	now := time.Date(2025, 1, 1, 12, 0, 0, 0, time.UTC)
+	c := New[int, string]("test_capacity", 2, time.Hour)
+	c.now = func() time.Time { return now }
+
+	c.SetWithTTL(1, "one", 2*time.Hour)
+	c.Set(2, "two")
+	c.Set(3, "three")
+	if _, ok := c.Get(2); ok {
+		t.Error("expected the entry closest to expiring to make room")
+	}
+	if _, ok := c.Get(1); !ok {
+		t.Error("expected the other entry to be kept")
+	}
+	if c.Len() != 2 {
+		t.Errorf("expected the cache to stay within its capacity, got %d entries", c.Len())
+	}
+	if entries := testutil.ToFloat64(Entries.WithLabelValues("test_capacity")); entries != 2 {
+		t.Errorf("expected the entries gauge to be 2, got %v", entries)
+	}
+	if evicted := testutil.ToFloat64(Evictions.WithLabelValues("test_capacity", "capacity")); evicted != 1 {
+		t.Errorf("expected 1 entry evicted for capacity, got %v", evicted)
+	}
+
+	// Expired entries make room before unexpired ones are evicted.
+	now = now.Add(90 * time.Minute)
+	c.Set(4, "four")
+	if _, ok := c.Get(1); !ok {
+		t.Error("expected the unexpired entry to be kept while an expired one could be dropped")
+	}
+}
+
+func TestCacheGetOrLoad(t *testing.T) {
+	c := New[string, string]("test_load", 0, 0)
+	loads := 0
+	load := func() (string, time.Duration, error) {
+		loads++
+		return "token", time.Hour, nil
+	}
+	for i := 0; i < 2; i++ {
+		if value, err := c.GetOrLoad("key", load); err != nil || value != "token" {
+			t.Fatalf("expected the loaded value, got %q %v", value, err)
+		}
+	}
+	if loads != 1 {
+		t.Errorf("expected the value to be loaded once, got %d loads", loads)
+	}
+
+	failure := errors.New("unavailable")
+	if _, err := c.GetOrLoad("other", func() (string, time.Duration, error) { return "", 0, failure }); !errors.Is(err, failure) {
+		t.Errorf("expected the load error, got %v", err)
+	}
+	if _, ok := c.Get("other"); ok {
+		t.Error("expected a failed load not to be cached")
+	}
+}
+
+func TestNilCache(t *testing.T) {
+	var c *Cache[string, int]
+	c.Set("a", 1)
+	if _, ok := c.Get("a"); ok || c.Len() != 0 {
+		t.Error("expected a nil cache to cache nothing")
+	}
+	if value, err := c.GetOrLoad("a", func() (int, time.Duration, error) { return 2, 0, nil }); err != nil || value != 2 {
+		t.Errorf("expected a nil cache to load every time, got %v %v", value, err)
+	}
+}
@@ -0,0 +1,20 @@
+package updatecheck
+
+import (
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promauto"
+)
+
+var (
+	// UpdateAvailableGauge is whether a newer release than the running version is available.
+	UpdateAvailableGauge = promauto.NewGaugeVec(prometheus.GaugeOpts{
+		Name: "watchdog_update_available",
+		Help: "Whether a release newer than the running version of the watchdog is available (1) or not (0), with the running and latest versions; only set with --update-check",
+	}, []string{"current_version", "latest_version"})
+
+	// CheckFailuresCounter counts the update checks that failed.
+	CheckFailuresCounter = promauto.NewCounter(prometheus.CounterOpts{
+		Name: "watchdog_update_check_failures_total",
+		Help: "Update checks against the releases API that failed, e.g. because it is unreachable or rate limited",
+	})
+)
@@ -0,0 +1,157 @@
+// Package updatecheck checks the releases of the watchdog on GitHub for a version newer than the
+// running one, so that operators of a fleet of watchdogs notice the instances lagging behind
+// released bug fixes.
+package updatecheck
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"watchdog.onebusaway.org/internal/scheduler"
+)
+
+// DefaultRepo is the GitHub repository whose releases are checked by default.
+const DefaultRepo = "OneBusAway/watchdog"
+
+// DefaultAPIURL is the GitHub API the releases are read from by default.
+const DefaultAPIURL = "https://api.github.com"
+
+// maxReleaseSize bounds the size of the release read from the API.
+const maxReleaseSize = 1 << 20
+
+// Release is a published release of the watchdog.
+type Release struct {
+	// Version is the tag of the release, e.g. "v1.2.0".
+	Version string `json:"tag_name"`
+	URL     string `json:"html_url"`
+}
+
+// Checker checks the latest release of Repo against the running version.
+type Checker struct {
+	// Repo is the GitHub repository, as "owner/name".
+	Repo string
+	// Current is the running version, e.g. "1.0.0".
+	Current string
+	// APIURL is the GitHub API (empty = DefaultAPIURL).
+	APIURL string
+	Client *http.Client
+	Logger *slog.Logger
+}
+
+// Latest returns the latest release of the repository, which GitHub defines as the most recent
+// release that is neither a draft nor a prerelease.
+func (c *Checker) Latest(ctx context.Context) (Release, error) {
+	apiURL := c.APIURL
+	if apiURL == "" {
+		apiURL = DefaultAPIURL
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, strings.TrimSuffix(apiURL, "/")+"/repos/"+c.Repo+"/releases/latest", nil)
+	if err != nil {
+		return Release{}, err
+	}
+	req.Header.Set("Accept", "application/vnd.github+json")
+	resp, err := c.Client.Do(req)
+	if err != nil {
+		return Release{}, fmt.Errorf("failed to get the latest release of %s: %w", c.Repo, err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return Release{}, fmt.Errorf("failed to get the latest release of %s: status %d", c.Repo, resp.StatusCode)
+	}
+	var release Release
+	if err := json.NewDecoder(http.MaxBytesReader(nil, resp.Body, maxReleaseSize)).Decode(&release); err != nil {
+		return Release{}, fmt.Errorf("invalid release of %s: %w", c.Repo, err)
+	}
+	if release.Version == "" {
+		return Release{}, fmt.Errorf("invalid release of %s: no tag", c.Repo)
+	}
+	return release, nil
+}
+
+// Check compares the latest release with the running version, exports the result as
+// watchdog_update_available, and logs the newer version, if any. Failures are logged and
+// counted in watchdog_update_check_failures_total; the metric keeps the result of the last
+// successful check.
+func (c *Checker) Check(ctx context.Context) {
+	release, err := c.Latest(ctx)
+	if err == nil {
+		var newer bool
+		if newer, err = Newer(release.Version, c.Current); err == nil {
+			UpdateAvailableGauge.Reset()
+			UpdateAvailableGauge.WithLabelValues(c.Current, release.Version).Set(boolToFloat(newer))
+			if newer {
+				c.Logger.Info("A newer version of the watchdog is available", "current_version", c.Current, "latest_version", release.Version, "url", release.URL)
+			}
+			return
+		}
+	}
+	CheckFailuresCounter.Inc()
+	c.Logger.Warn("Update check failed", "repo", c.Repo, "error", err)
+}
+
+// Run checks for updates right away, then at every activation of schedule, until ctx is
+// canceled.
+func (c *Checker) Run(ctx context.Context, schedule scheduler.Schedule) {
+	c.Check(ctx)
+	scheduler.Run(ctx, "update_check", schedule, func() {
+		c.Check(ctx)
+	})
+}
+
+// Newer reports whether version is newer than current. Versions are compared as dotted
+// numbers, e.g. "v1.10.0" > "1.9.2"; a "v" prefix and prerelease or build suffixes ("-rc.1",
+// "+build") are ignored.
+func Newer(version, current string) (bool, error) {
+	v, err := parseVersion(version)
+	if err != nil {
+		return false, err
+	}
+	c, err := parseVersion(current)
+	if err != nil {
+		return false, err
+	}
+	for i := range max(len(v), len(c)) {
+		a, b := part(v, i), part(c, i)
+		if a != b {
+			return a > b, nil
+		}
+	}
+	return false, nil
+}
+
+// parseVersion returns the numbers of a version, e.g. [1 2 0] for "v1.2.0-rc.1".
+func parseVersion(version string) ([]int, error) {
+	s := strings.TrimPrefix(strings.TrimSpace(version), "v")
+	if i := strings.IndexAny(s, "-+"); i >= 0 {
+		s = s[:i]
+	}
+	var parts []int
+	for _, field := range strings.Split(s, ".") {
+		n, err := strconv.Atoi(field)
+		if err != nil || n < 0 {
+			return nil, fmt.Errorf("invalid version %q", version)
+		}
+		parts = append(parts, n)
+	}
+	return parts, nil
+}
+
+// part returns the i-th number of a version, 0 if it has fewer.
+func part(version []int, i int) int {
+	if i < len(version) {
+		return version[i]
+	}
+	return 0
+}
+
+func boolToFloat(b bool) float64 {
+	if b {
+		return 1
+	}
+	return 0
+}
@@ -0,0 +1,75 @@
+package updatecheck
+
+import (
+	"bytes"
+	"context"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/prometheus/client_golang/prometheus/testutil"
+)
+
+func TestNewer(t *testing.T) {
+	for _, tt := range []struct {
+		version, current string
+		want             bool
+	}{
+		{"v1.2.0", "1.0.0", true},
+		{"v1.10.0", "1.9.2", true},
+		{"1.0.0", "1.0.0", false},
+		{"v1.0", "1.0.0", false},
+		{"v0.9.9", "1.0.0", false},
+		{"v2.0.0-rc.1", "1.5.0", true},
+	} {
+		if got, err := Newer(tt.version, tt.current); err != nil || got != tt.want {
+			t.Errorf("Newer(%q, %q) = %v, %v, want %v", tt.version, tt.current, got, err, tt.want)
+		}
+	}
+	if _, err := Newer("latest", "1.0.0"); err == nil {
+		t.Error("expected an error for an invalid version")
+	}
+}
+
+func TestCheck(t *testing.T) {
+	status, body := http.StatusOK, `{"tag_name": "v1.3.0", "html_url": "https://github.com/OneBusAway/watchdog/releases/tag/v1.3.0"}`
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/repos/OneBusAway/watchdog/releases/latest" {
+			t.Errorf("unexpected path %s", r.URL.Path)
+		}
+		w.WriteHeader(status)
+		_, _ = w.Write([]byte(body))
+	}))
+	defer ts.Close()
+
+	var logs bytes.Buffer
+	checker := &Checker{Repo: DefaultRepo, Current: "1.2.1", APIURL: ts.URL, Client: ts.Client(), Logger: slog.New(slog.NewTextHandler(&logs, nil))}
+	checker.Check(context.Background())
+	if got := testutil.ToFloat64(UpdateAvailableGauge.WithLabelValues("1.2.1", "v1.3.0")); got != 1 {
+		t.Errorf("expected an update to be available, got %v", got)
+	}
+	if !strings.Contains(logs.String(), "latest_version=v1.3.0") {
+		t.Errorf("expected the available version to be logged, got %s", logs.String())
+	}
+
+	status = http.StatusForbidden
+	failures := testutil.ToFloat64(CheckFailuresCounter)
+	checker.Check(context.Background())
+	if got := testutil.ToFloat64(CheckFailuresCounter) - failures; got != 1 {
+		t.Errorf("expected 1 failed check, got %v", got)
+	}
+	if got := testutil.ToFloat64(UpdateAvailableGauge.WithLabelValues("1.2.1", "v1.3.0")); got != 1 {
+		t.Errorf("expected the result of the last successful check to be kept, got %v", got)
+	}
+
+	status, body = http.StatusOK, `{"tag_name": "v1.2.1"}`
+	checker.Check(context.Background())
+	if got := testutil.CollectAndCount(UpdateAvailableGauge); got != 1 {
+		t.Errorf("expected only the latest versions to be exported, got %d series", got)
+	}
+	if got := testutil.ToFloat64(UpdateAvailableGauge.WithLabelValues("1.2.1", "v1.2.1")); got != 0 {
+		t.Errorf("expected no update to be available, got %v", got)
+	}
+}
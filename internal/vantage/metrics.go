@@ -0,0 +1,26 @@
+package vantage
+
+import (
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promauto"
+)
+
+var (
+	// StatusByVantageGauge is the status of each server as pinged by each agent.
+	StatusByVantageGauge = promauto.NewGaugeVec(prometheus.GaugeOpts{
+		Name: "oba_api_status_by_vantage",
+		Help: "Status of the OneBusAway API server as pinged from a secondary probe agent (0 = not working, 1 = working), by vantage point",
+	}, []string{"server_id", "vantage"})
+
+	// UnreachableFromPrimaryGauge is whether a server answers agents but not the primary.
+	UnreachableFromPrimaryGauge = promauto.NewGaugeVec(prometheus.GaugeOpts{
+		Name: "oba_api_unreachable_from_primary",
+		Help: "Whether the OneBusAway API server answers secondary probe agents but not the primary watchdog (1), pointing to the network of the primary rather than the server",
+	}, []string{"server_id"})
+
+	// ReportsCounter counts the reports received from each agent.
+	ReportsCounter = promauto.NewCounterVec(prometheus.CounterOpts{
+		Name: "watchdog_probe_reports_total",
+		Help: "Reports of ping results received from secondary probe agents, by vantage point",
+	}, []string{"vantage"})
+)
@@ -0,0 +1,209 @@
+// Package vantage aggregates the pings of the monitored servers made from several vantage
+// points: secondary probe agents, lightweight watchdog instances run in other regions, push the
+// results of their pings to the primary watchdog, which compares them with its own. A server
+// that only the primary cannot reach points to the network of the primary (e.g. its data
+// center) rather than to the server of the agency.
+//
+// The agents match their results to the servers of the primary by server ID, so they are meant
+// to run with the same configuration, e.g. the same --config-url.
+package vantage
+
+import (
+	"bytes"
+	"context"
+	"crypto/subtle"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"sort"
+	"strconv"
+	"sync"
+	"time"
+)
+
+// Path is the path of the internal API of the primary that agents push their results to.
+const Path = "/v1/probes/results"
+
+// maxReportSize bounds the size of a report pushed by an agent.
+const maxReportSize = 1 << 20
+
+// Diagnoses of a server, comparing the ping of the primary with those of the agents (see Diagnose).
+const (
+	// DiagnosisUp: the server answers the primary and every agent.
+	DiagnosisUp = "up"
+	// DiagnosisServerDown: the server answers neither the primary nor any agent.
+	DiagnosisServerDown = "server_down"
+	// DiagnosisPrimaryUnreachable: the server answers some agents but not the primary, so the
+	// primary's network is the likely cause.
+	DiagnosisPrimaryUnreachable = "unreachable_from_primary"
+	// DiagnosisPartial: the server answers the primary but not every agent.
+	DiagnosisPartial = "partial"
+)
+
+// Result is the outcome of the ping of a server from a vantage point.
+type Result struct {
+	ServerID int       `json:"server_id"`
+	OK       bool      `json:"ok"`
+	Error    string    `json:"error,omitempty"`
+	At       time.Time `json:"at"`
+}
+
+// Report is what an agent pushes to the primary after each collection cycle: the results of
+// its pings, and the name of its vantage point (e.g. its region).
+type Report struct {
+	Vantage string   `json:"vantage"`
+	Results []Result `json:"results"`
+}
+
+// Store keeps the latest result of each agent for each server, and forgets results older than
+// its maximum age, so that an agent that stops reporting is no longer taken into account.
+// It is safe for concurrent use; a nil Store records nothing.
+type Store struct {
+	maxAge time.Duration
+
+	mu sync.Mutex
+	// results maps server ID → vantage point → latest result.
+	results map[int]map[string]Result
+}
+
+// NewStore returns an empty Store keeping results for maxAge.
+func NewStore(maxAge time.Duration) *Store {
+	return &Store{maxAge: maxAge, results: make(map[int]map[string]Result)}
+}
+
+// Record stores the results of a report, and exports them as oba_api_status_by_vantage.
+func (s *Store) Record(report Report) {
+	if s == nil {
+		return
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	for _, result := range report.Results {
+		if s.results[result.ServerID] == nil {
+			s.results[result.ServerID] = make(map[string]Result)
+		}
+		s.results[result.ServerID][report.Vantage] = result
+		StatusByVantageGauge.WithLabelValues(strconv.Itoa(result.ServerID), report.Vantage).Set(boolToFloat(result.OK))
+	}
+	ReportsCounter.WithLabelValues(report.Vantage).Inc()
+}
+
+// Get returns the results of the agents for a server that are not older than the maximum
+// age of the store at now, by vantage point.
+func (s *Store) Get(serverID int, now time.Time) map[string]Result {
+	if s == nil {
+		return nil
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	results := make(map[string]Result, len(s.results[serverID]))
+	for vantage, result := range s.results[serverID] {
+		if now.Sub(result.At) > s.maxAge {
+			delete(s.results[serverID], vantage)
+			StatusByVantageGauge.DeleteLabelValues(strconv.Itoa(serverID), vantage)
+			continue
+		}
+		results[vantage] = result
+	}
+	return results
+}
+
+// Delete forgets the results of a server, e.g. when it is removed from the configuration.
+func (s *Store) Delete(serverID int) {
+	if s == nil {
+		return
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	for vantage := range s.results[serverID] {
+		StatusByVantageGauge.DeleteLabelValues(strconv.Itoa(serverID), vantage)
+	}
+	delete(s.results, serverID)
+}
+
+// Diagnose compares the ping of the primary with the results of the agents, and returns one
+// of the Diagnosis constants; it returns "" without results from agents to compare with.
+func Diagnose(primaryOK bool, remote map[string]Result) string {
+	if len(remote) == 0 {
+		return ""
+	}
+	reachable := 0
+	for _, result := range remote {
+		if result.OK {
+			reachable++
+		}
+	}
+	switch {
+	case primaryOK && reachable == len(remote):
+		return DiagnosisUp
+	case primaryOK:
+		return DiagnosisPartial
+	case reachable > 0:
+		return DiagnosisPrimaryUnreachable
+	default:
+		return DiagnosisServerDown
+	}
+}
+
+// Vantages returns the vantage points of results, sorted.
+func Vantages(results map[string]Result) []string {
+	vantages := make([]string, 0, len(results))
+	for vantage := range results {
+		vantages = append(vantages, vantage)
+	}
+	sort.Strings(vantages)
+	return vantages
+}
+
+// Handler returns the handler of Path on the primary, which records the reports of agents
+// authenticated with the bearer token.
+func (s *Store) Handler(token string) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if subtle.ConstantTimeCompare([]byte(r.Header.Get("Authorization")), []byte("Bearer "+token)) != 1 {
+			http.Error(w, "invalid probe token", http.StatusUnauthorized)
+			return
+		}
+		var report Report
+		if err := json.NewDecoder(io.LimitReader(r.Body, maxReportSize)).Decode(&report); err != nil {
+			http.Error(w, "invalid report: "+err.Error(), http.StatusBadRequest)
+			return
+		}
+		if report.Vantage == "" {
+			http.Error(w, "invalid report: missing vantage", http.StatusBadRequest)
+			return
+		}
+		s.Record(report)
+		w.WriteHeader(http.StatusNoContent)
+	})
+}
+
+// Push sends a report to the primary watchdog at primaryURL, authenticated with the token.
+func Push(ctx context.Context, client *http.Client, primaryURL, token string, report Report) error {
+	body, err := json.Marshal(report)
+	if err != nil {
+		return fmt.Errorf("failed to encode probe report: %w", err)
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, primaryURL+Path, bytes.NewReader(body))
+	if err != nil {
+		return fmt.Errorf("invalid primary URL: %w", err)
+	}
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("Authorization", "Bearer "+token)
+	resp, err := client.Do(req)
+	if err != nil {
+		return fmt.Errorf("failed to push probe report: %w", err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode/100 != 2 {
+		return fmt.Errorf("the primary rejected the probe report with status: %d", resp.StatusCode)
+	}
+	return nil
+}
+
+func boolToFloat(b bool) float64 {
+	if b {
+		return 1
+	}
+	return 0
+}
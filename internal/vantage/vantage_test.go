@@ -0,0 +1,83 @@
+package vantage
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus/testutil"
+)
+
+func TestDiagnose(t *testing.T) {
+	up := Result{OK: true}
+	down := Result{OK: false}
+	tests := []struct {
+		name      string
+		primaryOK bool
+		remote    map[string]Result
+		want      string
+	}{
+		{"no agents", false, nil, ""},
+		{"up everywhere", true, map[string]Result{"eu": up, "us": up}, DiagnosisUp},
+		{"down everywhere", false, map[string]Result{"eu": down, "us": down}, DiagnosisServerDown},
+		{"only the primary fails", false, map[string]Result{"eu": up, "us": down}, DiagnosisPrimaryUnreachable},
+		{"an agent fails", true, map[string]Result{"eu": up, "us": down}, DiagnosisPartial},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := Diagnose(tt.primaryOK, tt.remote); got != tt.want {
+				t.Errorf("expected %q, got %q", tt.want, got)
+			}
+		})
+	}
+}
+
+func TestStoreMaxAge(t *testing.T) {
+	s := NewStore(time.Minute)
+	now := time.Now()
+	s.Record(Report{Vantage: "eu", Results: []Result{{ServerID: 1, OK: true, At: now}}})
+	s.Record(Report{Vantage: "us", Results: []Result{{ServerID: 1, OK: false, At: now.Add(-2 * time.Minute)}}})
+
+	results := s.Get(1, now)
+	if len(results) != 1 || !results["eu"].OK {
+		t.Fatalf("expected only the recent result of eu, got %+v", results)
+	}
+	if got := testutil.ToFloat64(StatusByVantageGauge.WithLabelValues("1", "eu")); got != 1 {
+		t.Errorf("expected oba_api_status_by_vantage to be 1, got %v", got)
+	}
+
+	s.Delete(1)
+	if results := s.Get(1, now); len(results) != 0 {
+		t.Errorf("expected no results after Delete, got %+v", results)
+	}
+	var nilStore *Store
+	nilStore.Record(Report{Vantage: "eu"})
+	if results := nilStore.Get(1, now); results != nil {
+		t.Errorf("expected a nil store to record nothing, got %+v", results)
+	}
+}
+
+func TestPushToHandler(t *testing.T) {
+	s := NewStore(time.Minute)
+	mux := http.NewServeMux()
+	mux.Handle(Path, s.Handler("secret"))
+	ts := httptest.NewServer(mux)
+	defer ts.Close()
+
+	now := time.Now().UTC()
+	report := Report{Vantage: "eu", Results: []Result{{ServerID: 1, OK: false, Error: "timeout", At: now}}}
+	if err := Push(context.Background(), ts.Client(), ts.URL, "wrong", report); err == nil {
+		t.Error("expected a report with an invalid token to be rejected")
+	}
+	if err := Push(context.Background(), ts.Client(), ts.URL, "secret", Report{}); err == nil {
+		t.Error("expected a report without vantage to be rejected")
+	}
+	if err := Push(context.Background(), ts.Client(), ts.URL, "secret", report); err != nil {
+		t.Fatal(err)
+	}
+	if result, ok := s.Get(1, now)["eu"]; !ok || result.OK || result.Error != "timeout" {
+		t.Errorf("expected the pushed result to be recorded, got %+v", result)
+	}
+}